@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"ctoz/backend/internal/config"
 	"ctoz/backend/internal/handlers"
+	"ctoz/backend/internal/i18n"
+	"ctoz/backend/internal/logging"
 	"ctoz/backend/internal/middleware"
 	"ctoz/backend/internal/services"
+	"ctoz/backend/internal/storage"
 	"ctoz/backend/internal/websocket"
 )
 
@@ -15,44 +24,133 @@ func main() {
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 
+	// 加载配置
+	cfg := config.Load()
+	i18n.SetDefault(cfg.Language)
+	if err := logging.Install(cfg.LogLevel, cfg.LogHTTPDumps); err != nil {
+		log.Printf("[WARNING] %v, using debug", err)
+		logging.SetLevel(logging.LevelDebug)
+	}
+	// 配置中的密码和令牌不出现在日志中
+	logging.RegisterSecret("config", cfg.SMTP.Password, cfg.TelegramBotToken, cfg.DiscordWebhookURL, cfg.Auth.OIDC.ClientSecret)
+
 	// 创建Gin引擎
 	r := gin.New()
 
 	// 添加中间件
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
-	r.Use(middleware.CORS())
+	// 启用登录时只允许PublicURL和CTOZ_ALLOWED_ORIGINS跨域携带会话访问
+	corsOrigins := cfg.CORSOrigins()
+	r.Use(middleware.CORS(corsOrigins))
 	r.Use(middleware.RequestID())
+	r.Use(middleware.Language())
 	r.Use(middleware.Security())
 	r.Use(middleware.ErrorHandler())
-	r.Use(middleware.Timeout(30 * time.Second))
+	// 长时间的导出下载、上传、日志跟踪和逐个统计应用大小的估算不受请求超时限制
+	r.Use(middleware.Timeout(30*time.Second,
+		"/ws",
+		"/api/export-download",
+		"/api/data-export",
+		"/api/estimate",
+		"/api/tasks/:id/download/:appName",
+		"/api/data-import-upload",
+		"/api/uploads/:id",
+		"/api/tasks/:id/logs",
+		"/api/tasks/:id/promote",
+	))
 	r.Use(middleware.NoCacheForHTML())
 
 	// 创建WebSocket管理器
 	wsManager := websocket.NewManager()
+	wsManager.SetOriginCheck(func(r *http.Request) bool {
+		return middleware.OriginAllowed(r, corsOrigins)
+	})
 	go wsManager.Run()
 
+	// 创建共享的存储，连接、任务和日志保存在同一处
+	store := storage.NewMemoryStore()
+	if cfg.StateFile != "" {
+		if err := store.EnablePersistence(cfg.StateFile); err != nil {
+			log.Printf("[ERROR] Task persistence disabled: %v", err)
+		}
+	}
+
 	// 创建服务
-	connService := services.NewConnectionService()
-	taskService := services.NewTaskService(wsManager)
-	migrationService := services.NewMigrationService(connService, taskService)
+	connService := services.NewConnectionService(cfg, store)
+	taskService := services.NewTaskService(store, wsManager)
+	notificationService := services.NewNotificationService(cfg)
+	migrationService := services.NewMigrationService(cfg, connService, taskService, notificationService)
+
+	janitorService := services.NewJanitorService(cfg, taskService)
+	backupService := services.NewBackupService(connService, migrationService, taskService)
+	presetService := services.NewPresetService(taskService)
+	pathRuleService := services.NewPathRuleService(taskService)
+	imageRuleService := services.NewImageRuleService(taskService)
+	authService := services.NewAuthService(cfg)
+	uploadService := services.NewUploadService(cfg)
+
+	// 上次运行时未结束的任务标记为已中断
+	taskService.RecoverInterruptedTasks()
+
+	// 定期清理残留的临时文件
+	janitorService.Start()
+
+	// 按计划执行定时备份
+	backupService.Start()
 
 	// 创建处理器
-	handler := handlers.NewHandler(connService, migrationService, taskService, wsManager)
+	handler := handlers.NewHandler(cfg, connService, migrationService, taskService, janitorService, backupService, presetService, pathRuleService, imageRuleService, authService, uploadService, wsManager)
 
 	// 健康检查
 	r.GET("/health", handler.HealthCheck)
 	r.GET("/info", handler.GetSystemInfo)
 
 	// API路由组
-	api := r.Group("/api")
+	// 登录接口不需要会话
+	auth := r.Group("/api/auth")
+	{
+		auth.GET("/status", handler.AuthStatus)
+		auth.GET("/oidc/login", handler.OIDCLogin)
+		auth.GET("/oidc/callback", handler.OIDCCallback)
+		auth.POST("/ldap/login", handler.LDAPLogin)
+		auth.POST("/logout", handler.Logout)
+	}
+
+	// 登录会话管理，撤销会话在只读模式下也允许
+	sessions := r.Group("/api/sessions", middleware.Auth(authService))
+	{
+		sessions.GET("", handler.ListSessions)
+		sessions.DELETE("", handler.RevokeOtherSessions)
+		sessions.DELETE("/:id", handler.RevokeSession)
+	}
+
+	// 配置了OIDC或LDAP时其余接口都需要登录，只读模式下拒绝修改操作
+	api := r.Group("/api", middleware.Auth(authService), middleware.ReadOnly(cfg.ReadOnly))
 	{
 		// 连接测试
 		api.POST("/test-connection", handler.TestConnection)
 
+		// 保存的连接
+		api.GET("/connections", handler.ListConnections)
+		api.PUT("/connections/:id", handler.UpdateConnection)
+		api.DELETE("/connections/:id", handler.DeleteConnection)
+
 		// 在线迁移
 		api.POST("/online-migration", handler.StartOnlineMigration)
 
+		// 迁移前检查
+		api.POST("/preflight", handler.Preflight)
+
+		// 迁移预估
+		api.POST("/estimate", handler.EstimateMigration)
+
+		// 源系统应用列表
+		api.POST("/source/apps", handler.ListSourceApps)
+
+		// 目标系统存储卷列表
+		api.POST("/target/storage", handler.ListTargetStorage)
+
 		// 数据导出
 		api.POST("/data-export", handler.StartDataExport)
 		
@@ -64,6 +162,16 @@ func main() {
 		
 		// 文件上传导入
 		api.POST("/data-import-upload", handler.DataImportUpload)
+
+		// 断点续传上传（tus协议），完成后启动导入
+		uploads := api.Group("/uploads")
+		{
+			uploads.POST("", handler.CreateUpload)
+			uploads.HEAD("/:id", handler.GetUploadOffset)
+			uploads.PATCH("/:id", handler.WriteUploadChunk)
+			uploads.DELETE("/:id", handler.DeleteUpload)
+			uploads.POST("/:id/import", handler.ImportUpload)
+		}
 		
 		// WebSocket测试端点
 		api.POST("/test-websocket/:taskId", handler.TestWebSocket)
@@ -76,18 +184,97 @@ func main() {
 		{
 			tasks.GET("", handler.ListTasks)
 			tasks.GET("/:id", handler.GetTaskStatus)
+			// 导出任务历史（CSV或NDJSON）
+			tasks.GET("/export", handler.ExportTasks)
+			// 批量删除、取消或重试任务
+			tasks.POST("/batch", handler.BatchTasks)
 		tasks.DELETE("/:id", handler.DeleteTask)
+			// 恢复中断的任务
+			tasks.POST("/:id/resume", handler.ResumeTask)
+			// 取消正在执行的任务
+			tasks.POST("/:id/cancel", handler.CancelTask)
+			// 提升蓝绿导入的应用
+			tasks.POST("/:id/promote", handler.PromoteStagedApps)
 		// 获取任务日志
 		tasks.GET("/:id/logs", handler.GetTaskLogs)
+		// 下载任务日志文件
+		tasks.GET("/:id/logs/download", handler.DownloadTaskLogs)
 		// 获取导入状态
 		tasks.GET("/:id/import-status", handler.GetImportStatus)
+			// 下载任务总结报告
+			tasks.GET("/:id/report", handler.DownloadTaskReport)
+			// 获取任务事件历史
+			tasks.GET("/:id/events", handler.GetTaskEvents)
+			// 下载任务诊断包
+			tasks.GET("/:id/diagnostics", handler.DownloadTaskDiagnostics)
+			// 获取任务步骤清单
+			tasks.GET("/:id/steps", handler.GetTaskSteps)
+			// 预览bind挂载路径改写结果
+			tasks.GET("/:id/bind-mounts", handler.GetTaskBindMounts)
 			// 下载应用压缩包
 			tasks.GET("/:id/download/:appName", handler.DownloadAppPackage)
 		}
+
+		// 迁移选项预设
+		presets := api.Group("/presets")
+		{
+			presets.GET("", handler.ListPresets)
+			presets.POST("", handler.CreatePreset)
+			presets.GET("/:name", handler.GetPreset)
+			presets.PUT("/:name", handler.UpdatePreset)
+			presets.DELETE("/:name", handler.DeletePreset)
+		}
+
+		// bind挂载路径改写规则
+		pathRules := api.Group("/path-rules")
+		{
+			pathRules.GET("", handler.ListPathRules)
+			pathRules.POST("", handler.CreatePathRule)
+			pathRules.GET("/:id", handler.GetPathRule)
+			pathRules.PUT("/:id", handler.UpdatePathRule)
+			pathRules.DELETE("/:id", handler.DeletePathRule)
+		}
+
+		// 镜像引用改写规则
+		imageRules := api.Group("/image-rules")
+		{
+			imageRules.GET("", handler.ListImageRules)
+			imageRules.POST("", handler.CreateImageRule)
+			imageRules.GET("/:id", handler.GetImageRule)
+			imageRules.PUT("/:id", handler.UpdateImageRule)
+			imageRules.DELETE("/:id", handler.DeleteImageRule)
+		}
+
+		// 定时备份
+		backupJobs := api.Group("/backup-jobs")
+		{
+			backupJobs.GET("", handler.ListBackupJobs)
+			backupJobs.POST("", handler.CreateBackupJob)
+			backupJobs.GET("/:id", handler.GetBackupJob)
+			backupJobs.PUT("/:id", handler.UpdateBackupJob)
+			backupJobs.DELETE("/:id", handler.DeleteBackupJob)
+			backupJobs.POST("/:id/run", handler.RunBackupJob)
+		}
+
+		// 维护
+		api.POST("/maintenance/cleanup", handler.CleanupTempFiles)
+
+		// 统计信息
+		api.GET("/stats", handler.GetStats)
+
+		// 版本和构建信息
+		api.GET("/version", handler.GetVersion)
+
+		// 服务日志级别
+		api.GET("/admin/loglevel", handler.GetLogLevel)
+		api.POST("/admin/loglevel", handler.SetLogLevel)
+
+		// 清除全部数据
+		api.POST("/admin/purge", handler.PurgeAllData)
 	}
 
 	// WebSocket路由
-	r.GET("/ws", handler.HandleWebSocket)
+	r.GET("/ws", middleware.Auth(authService), handler.HandleWebSocket)
 
 	// 静态文件服务（前端）
 	r.Static("/assets", "./dist/assets")
@@ -100,5 +287,31 @@ func main() {
 	log.Println("CasaOS to ZimaOS Migration Tool 服务器启动在端口 :8080")
 	log.Println("访问 http://localhost:8080 查看Web界面")
 	log.Println("API文档: http://localhost:8080/info")
-	log.Fatal(r.Run(":8080"))
+	if cfg.ReadOnly {
+		log.Println("[INFO] Read-only mode enabled, migrations, imports and deletions are disabled")
+	}
+
+	// 收到SIGINT/SIGTERM时先取消运行中的任务并保存状态（之后可以恢复），再停止HTTP服务
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("[INFO] Shutting down, cancelling running tasks")
+	taskService.Shutdown(cfg.ShutdownTimeout)
+
+	// 任务的最终状态发出后通知WebSocket客户端并关闭连接，HTTP服务的Shutdown不会关闭已升级的连接
+	wsManager.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[WARNING] HTTP server shutdown: %v", err)
+	}
 }
\ No newline at end of file
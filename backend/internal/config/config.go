@@ -0,0 +1,432 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config 应用配置，从环境变量加载
+type Config struct {
+	// PublicURL 工具对外访问地址，用于通知中的任务链接
+	PublicURL string
+
+	// AllowedOrigins 启用登录时除PublicURL外允许跨域携带会话Cookie访问接口的来源
+	AllowedOrigins []string
+
+	// StateFile 任务状态持久化文件，为空时任务仅保存在内存中
+	StateFile string
+
+	// Language 默认语言（en/zh），请求未指定Accept-Language时使用
+	Language string
+
+	// ReadOnly 只读模式，禁止迁移、导入、删除等修改操作，只保留查看任务、日志和下载
+	ReadOnly bool
+
+	// DemoMode 演示模式，直接导出连接源系统失败时生成标记为演示的模拟数据
+	DemoMode bool
+
+	// SMTP 邮件通知配置
+	SMTP SMTPConfig
+
+	// Telegram 机器人通知配置
+	TelegramBotToken string
+	TelegramChatID   string
+
+	// DiscordWebhookURL Discord Webhook通知地址
+	DiscordWebhookURL string
+
+	// ZimaOSDataRoot ZimaOS数据根目录，其它系统的共享文件夹路径映射到该目录下
+	ZimaOSDataRoot string
+
+	// Extract 解压限制，防止压缩炸弹
+	Extract ExtractLimits
+
+	// MaxUploadBytes 导入文件上传的最大字节数，0表示不限制
+	MaxUploadBytes int64
+
+	// LargeAppBytes 应用AppData超过该大小时需要确认后才能开始迁移，0表示不检查
+	LargeAppBytes int64
+
+	// CompressWorkers 创建ZIP压缩包时并行压缩的worker数，默认为CPU核数
+	CompressWorkers int
+
+	// Cleanup 临时文件自动清理
+	Cleanup CleanupConfig
+
+	// Dirs 本地工作目录
+	Dirs WorkDirs
+
+	// SSHKeyDir SSH私钥目录，连接的key_file只能指向该目录中的文件
+	SSHKeyDir string
+
+	// Retry CasaOS/ZimaOS接口调用的重试策略
+	Retry RetryConfig
+
+	// Timeouts 按操作类型区分的HTTP超时时间
+	Timeouts HTTPTimeouts
+
+	// ShutdownTimeout 关闭服务时等待请求结束和任务保存状态的最长时间
+	ShutdownTimeout time.Duration
+
+	// TargetBreaker 目标连续失败时的熔断设置
+	TargetBreaker BreakerConfig
+
+	// RateLimit 迁移时对每个CasaOS/ZimaOS主机的接口调用限流
+	RateLimit RateLimitConfig
+
+	// Auth 登录设置，配置了OIDC或LDAP时所有接口都需要登录
+	Auth AuthConfig
+
+	// LogLevel 服务日志的最低级别：debug/info/warn/error，运行时可通过接口修改
+	LogLevel string
+
+	// LogHTTPDumps 是否在服务日志中输出上传、删除等接口调用的请求和响应（仅在debug级别有效）
+	LogHTTPDumps bool
+}
+
+// CORSOrigins 返回允许跨域访问接口和WebSocket的来源，未启用登录时返回nil表示不限制
+// 启用登录后只允许PublicURL和AllowedOrigins，防止同一主机上其它端口的页面借用会话Cookie
+func (c *Config) CORSOrigins() []string {
+	if !c.Auth.Enabled() {
+		return nil
+	}
+	origins := []string{}
+	for _, value := range append([]string{c.PublicURL}, c.AllowedOrigins...) {
+		if origin := normalizeOrigin(value); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// normalizeOrigin 将URL转换为 scheme://host[:port] 形式的小写来源，无效时返回空字符串
+func normalizeOrigin(value string) string {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// AuthConfig 外部身份提供方登录设置
+type AuthConfig struct {
+	OIDC       OIDCConfig
+	LDAP       LDAPConfig
+	SessionTTL time.Duration // 登录会话的有效期
+	// SessionIdleTimeout 会话空闲超过该时间后失效，0表示不限制
+	SessionIdleTimeout time.Duration
+}
+
+// Enabled 是否配置了任一登录方式
+func (c AuthConfig) Enabled() bool {
+	return c.OIDC.Enabled() || c.LDAP.Enabled()
+}
+
+// OIDCConfig 通用OIDC登录配置（Authelia、Authentik、Keycloak等）
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string   // 作为用户名的声明，默认preferred_username
+	AllowedGroups []string // 为空时允许所有通过认证的用户
+}
+
+// Enabled OIDC配置是否完整可用
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// LDAPConfig LDAP简单绑定登录配置
+type LDAPConfig struct {
+	URL                string // ldap://host:389 或 ldaps://host:636
+	UserDN             string // 用户DN模板，%s替换为用户名，如 uid=%s,ou=people,dc=example,dc=org
+	InsecureSkipVerify bool   // ldaps不校验证书
+	// AllowedGroups 允许登录的组（组DN或cn，以分号分隔），为空时允许所有能绑定的用户，按用户条目的memberOf属性判断
+	AllowedGroups []string
+}
+
+// Enabled LDAP配置是否完整可用
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != "" && c.UserDN != ""
+}
+
+// WorkDirs 本地工作目录，默认都位于 CTOZ_WORK_DIR 之下
+type WorkDirs struct {
+	Download string // 从源系统下载的备份
+	Upload   string // 离线导入上传的文件
+	Extract  string // 解压或拉取后的源数据
+	Compress string // 上传到目标系统前的临时压缩包
+	Export   string // 导出文件
+	Package  string // 单个应用的下载包
+	Archive  string // 开启keep_source_archive时保留的源系统备份，不参与临时文件清理
+}
+
+// All 返回去重后的全部工作目录
+func (d WorkDirs) All() []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range []string{d.Download, d.Upload, d.Extract, d.Compress, d.Export, d.Package} {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// CleanupConfig 临时文件清理配置
+type CleanupConfig struct {
+	MaxAge   time.Duration // 未被任务引用的文件超过该时长后删除，0表示不自动清理
+	Interval time.Duration // 自动清理的间隔
+}
+
+// HTTPTimeouts 各类CasaOS/ZimaOS请求的超时时间（包括读取响应体），0表示不限制
+// 下载和上传默认不限制总时长，只限制建立连接和等待响应头的时间
+type HTTPTimeouts struct {
+	Connect        time.Duration // 连接测试和登录
+	Download       time.Duration // 从源系统下载备份和文件
+	Upload         time.Duration // 上传压缩包到目标系统
+	Compose        time.Duration // 导入compose（目标可能在请求中拉取镜像）
+	FileOps        time.Duration // 文件管理、应用状态等其它接口调用
+	Dial           time.Duration // 建立TCP连接
+	ResponseHeader time.Duration // 下载和上传时发出请求后等待响应头
+}
+
+// RetryConfig HTTP请求重试策略，等待时间从BaseDelay开始每次翻倍，不超过MaxDelay
+type RetryConfig struct {
+	MaxRetries int // 最多重试次数，0表示不重试
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// BreakerConfig 熔断设置：连续失败Threshold次后暂停任务，每隔ProbeInterval探测目标，
+// 超过MaxWait仍不可用时放弃（0表示一直等待）
+type BreakerConfig struct {
+	Threshold     int // 0表示不启用
+	ProbeInterval time.Duration
+	MaxWait       time.Duration
+}
+
+// RateLimitConfig 接口调用限流：同一主机同时等待响应的请求不超过MaxInFlight个，
+// 相邻两次请求的发起时间至少间隔Interval，0表示不限制
+type RateLimitConfig struct {
+	MaxInFlight int
+	Interval    time.Duration
+}
+
+// ExtractLimits 解压大小和条目数限制，0表示不限制
+type ExtractLimits struct {
+	MaxTotalBytes int64 // 解压后总字节数
+	MaxFileBytes  int64 // 单个文件解压后字节数
+	MaxEntries    int   // 条目数
+	MaxRatio      int   // 解压后总大小与压缩包大小之比
+}
+
+// SMTPConfig SMTP邮件配置
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Enabled SMTP配置是否完整可用
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != "" && c.From != "" && len(c.To) > 0
+}
+
+// Load 从环境变量加载配置
+func Load() *Config {
+	workDir := getEnv("CTOZ_WORK_DIR", ".")
+
+	return &Config{
+		PublicURL:      strings.TrimRight(getEnv("CTOZ_PUBLIC_URL", "http://localhost:8080"), "/"),
+		AllowedOrigins: getEnvList("CTOZ_ALLOWED_ORIGINS"),
+		StateFile:      getEnv("CTOZ_STATE_FILE", "./data/state.json"),
+		Language:       getEnv("CTOZ_LANGUAGE", "en"),
+		ReadOnly:       getEnvBool("CTOZ_READ_ONLY", false),
+		DemoMode:       getEnvBool("CTOZ_DEMO_MODE", false),
+		SMTP: SMTPConfig{
+			Host:     getEnv("CTOZ_SMTP_HOST", ""),
+			Port:     getEnvInt("CTOZ_SMTP_PORT", 587),
+			Username: getEnv("CTOZ_SMTP_USERNAME", ""),
+			Password: getEnv("CTOZ_SMTP_PASSWORD", ""),
+			From:     getEnv("CTOZ_SMTP_FROM", ""),
+			To:       getEnvList("CTOZ_SMTP_TO"),
+		},
+		TelegramBotToken:  getEnv("CTOZ_TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:    getEnv("CTOZ_TELEGRAM_CHAT_ID", ""),
+		DiscordWebhookURL: getEnv("CTOZ_DISCORD_WEBHOOK_URL", ""),
+		ZimaOSDataRoot:    strings.TrimRight(getEnv("CTOZ_ZIMAOS_DATA_ROOT", "/DATA"), "/"),
+		Extract: ExtractLimits{
+			MaxTotalBytes: getEnvInt64("CTOZ_MAX_EXTRACT_BYTES", 500<<30),
+			MaxFileBytes:  getEnvInt64("CTOZ_MAX_EXTRACT_FILE_BYTES", 100<<30),
+			MaxEntries:    getEnvInt("CTOZ_MAX_EXTRACT_ENTRIES", 2000000),
+			MaxRatio:      getEnvInt("CTOZ_MAX_EXTRACT_RATIO", 1000),
+		},
+		MaxUploadBytes:  getEnvLimit("CTOZ_MAX_UPLOAD_BYTES", 100<<30),
+		LargeAppBytes:   getEnvLimit("CTOZ_LARGE_APP_BYTES", 50<<30),
+		CompressWorkers: getEnvInt("CTOZ_COMPRESS_WORKERS", runtime.NumCPU()),
+		Cleanup: CleanupConfig{
+			MaxAge:   getEnvDuration("CTOZ_CLEANUP_MAX_AGE", 24*time.Hour),
+			Interval: getEnvDuration("CTOZ_CLEANUP_INTERVAL", time.Hour),
+		},
+		ShutdownTimeout: getEnvDuration("CTOZ_SHUTDOWN_TIMEOUT", 30*time.Second),
+		LogLevel:        getEnv("CTOZ_LOG_LEVEL", "debug"),
+		LogHTTPDumps:    getEnvBool("CTOZ_LOG_HTTP_DUMPS", true),
+		Timeouts: HTTPTimeouts{
+			Connect:        getEnvDuration("CTOZ_TIMEOUT_CONNECT", 10*time.Second),
+			Download:       getEnvDuration("CTOZ_TIMEOUT_DOWNLOAD", 0),
+			Upload:         getEnvDuration("CTOZ_TIMEOUT_UPLOAD", 0),
+			Compose:        getEnvDuration("CTOZ_TIMEOUT_COMPOSE", 10*time.Minute),
+			FileOps:        getEnvDuration("CTOZ_TIMEOUT_FILE_OPS", 5*time.Minute),
+			Dial:           getEnvDuration("CTOZ_TIMEOUT_DIAL", 30*time.Second),
+			ResponseHeader: getEnvDuration("CTOZ_TIMEOUT_RESPONSE_HEADER", 5*time.Minute),
+		},
+		Retry: RetryConfig{
+			MaxRetries: getEnvInt("CTOZ_HTTP_RETRIES", 3),
+			BaseDelay:  getEnvDuration("CTOZ_HTTP_RETRY_DELAY", time.Second),
+			MaxDelay:   getEnvDuration("CTOZ_HTTP_RETRY_MAX_DELAY", 30*time.Second),
+		},
+		TargetBreaker: BreakerConfig{
+			Threshold:     getEnvInt("CTOZ_TARGET_FAILURE_THRESHOLD", 3),
+			ProbeInterval: getEnvDuration("CTOZ_TARGET_PROBE_INTERVAL", 30*time.Second),
+			MaxWait:       getEnvDuration("CTOZ_TARGET_MAX_WAIT", time.Hour),
+		},
+		RateLimit: RateLimitConfig{
+			MaxInFlight: getEnvInt("CTOZ_API_MAX_INFLIGHT", 4),
+			Interval:    getEnvDuration("CTOZ_API_REQUEST_INTERVAL", 50*time.Millisecond),
+		},
+		Auth: AuthConfig{
+			OIDC: OIDCConfig{
+				Issuer:        strings.TrimRight(getEnv("CTOZ_OIDC_ISSUER", ""), "/"),
+				ClientID:      getEnv("CTOZ_OIDC_CLIENT_ID", ""),
+				ClientSecret:  getEnv("CTOZ_OIDC_CLIENT_SECRET", ""),
+				RedirectURL:   getEnv("CTOZ_OIDC_REDIRECT_URL", ""),
+				Scopes:        getEnvList("CTOZ_OIDC_SCOPES"),
+				UsernameClaim: getEnv("CTOZ_OIDC_USERNAME_CLAIM", "preferred_username"),
+				AllowedGroups: getEnvList("CTOZ_OIDC_ALLOWED_GROUPS"),
+			},
+			LDAP: LDAPConfig{
+				URL:                getEnv("CTOZ_LDAP_URL", ""),
+				UserDN:             getEnv("CTOZ_LDAP_USER_DN", ""),
+				InsecureSkipVerify: getEnvBool("CTOZ_LDAP_INSECURE_SKIP_VERIFY", false),
+				AllowedGroups:      getEnvSplit("CTOZ_LDAP_ALLOWED_GROUPS", ";"),
+			},
+			SessionTTL:         getEnvDuration("CTOZ_SESSION_TTL", 24*time.Hour),
+			SessionIdleTimeout: getEnvDuration("CTOZ_SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		},
+		SSHKeyDir: getEnv("CTOZ_SSH_KEY_DIR", filepath.Join(workDir, "ssh")),
+		Dirs: WorkDirs{
+			Download: getEnv("CTOZ_DOWNLOAD_DIR", filepath.Join(workDir, "download")),
+			Upload:   getEnv("CTOZ_UPLOAD_DIR", filepath.Join(workDir, "uploads")),
+			Extract:  getEnv("CTOZ_EXTRACT_DIR", filepath.Join(workDir, "extract")),
+			Compress: getEnv("CTOZ_COMPRESS_DIR", filepath.Join(workDir, "compress")),
+			Export:   getEnv("CTOZ_EXPORT_DIR", filepath.Join(workDir, "exports")),
+			Package:  getEnv("CTOZ_PACKAGE_DIR", filepath.Join(workDir, "packages")),
+			Archive:  getEnv("CTOZ_ARCHIVE_DIR", filepath.Join(workDir, "archives")),
+		},
+	}
+}
+
+// 辅助函数
+
+// getEnv 读取字符串环境变量
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return defaultValue
+}
+
+// getEnvInt 读取整数环境变量
+func getEnvInt(key string, defaultValue int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+// getEnvInt64 读取64位整数环境变量
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+// getEnvLimit 读取字节数上限，0或unlimited表示不限制
+func getEnvLimit(key string, defaultValue int64) int64 {
+	if strings.EqualFold(getEnv(key, ""), "unlimited") {
+		return 0
+	}
+	if limit := getEnvInt64(key, defaultValue); limit >= 0 {
+		return limit
+	}
+	return defaultValue
+}
+
+// getEnvBool 读取布尔环境变量（true/false、1/0）
+func getEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvDuration 读取时长环境变量（如 30m、24h）
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvList 读取逗号分隔的列表环境变量
+func getEnvList(key string) []string {
+	return getEnvSplit(key, ",")
+}
+
+// getEnvSplit 读取以sep分隔的列表环境变量，用于本身含有逗号的值（如LDAP DN）
+func getEnvSplit(key, sep string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return nil
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
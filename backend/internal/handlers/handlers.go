@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,18 +23,31 @@ import (
 	"sync"
 	"time"
 
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/i18n"
+	"ctoz/backend/internal/logging"
+	"ctoz/backend/internal/middleware"
 	"ctoz/backend/internal/models"
 	"ctoz/backend/internal/services"
 	"ctoz/backend/internal/websocket"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Handler 处理器结构体
 type Handler struct {
+	cfg              *config.Config
 	connService      *services.ConnectionService
 	migrationService *services.MigrationService
 	taskService      *services.TaskService
+	janitorService   *services.JanitorService
+	backupService    *services.BackupService
+	presetService    *services.PresetService
+	pathRuleService  *services.PathRuleService
+	imageRuleService *services.ImageRuleService
+	authService      *services.AuthService
+	uploadService    *services.UploadService
 	wsManager        *websocket.Manager
 
 	// 缓存相关
@@ -38,15 +59,31 @@ type Handler struct {
 
 // NewHandler 创建新的处理器
 func NewHandler(
+	cfg *config.Config,
 	connService *services.ConnectionService,
 	migrationService *services.MigrationService,
 	taskService *services.TaskService,
+	janitorService *services.JanitorService,
+	backupService *services.BackupService,
+	presetService *services.PresetService,
+	pathRuleService *services.PathRuleService,
+	imageRuleService *services.ImageRuleService,
+	authService *services.AuthService,
+	uploadService *services.UploadService,
 	wsManager *websocket.Manager,
 ) *Handler {
 	handler := &Handler{
+		cfg:               cfg,
 		connService:       connService,
 		migrationService:  migrationService,
 		taskService:       taskService,
+		janitorService:    janitorService,
+		backupService:     backupService,
+		presetService:     presetService,
+		pathRuleService:   pathRuleService,
+		imageRuleService:  imageRuleService,
+		authService:       authService,
+		uploadService:     uploadService,
 		wsManager:         wsManager,
 		importStatusCache: make(map[string]models.ImportStatusResponse),
 		cacheExpiry:       make(map[string]time.Time),
@@ -66,6 +103,17 @@ func NewHandler(
 	return handler
 }
 
+// requestLanguage 返回语言中间件为请求选择的语言
+func requestLanguage(c *gin.Context) string {
+	return c.GetString("Language")
+}
+
+// respond 按请求语言翻译响应消息后返回JSON
+func (h *Handler) respond(c *gin.Context, status int, resp models.APIResponse) {
+	resp.Message = i18n.T(requestLanguage(c), resp.Message)
+	c.JSON(status, resp)
+}
+
 // 缓存相关方法
 
 // getCachedImportStatus 获取缓存的导入状态
@@ -117,7 +165,7 @@ func (h *Handler) clearExpiredCache() {
 func (h *Handler) TestConnection(c *gin.Context) {
 	var req models.ConnectionTestRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid request: " + err.Error(),
 		})
@@ -125,14 +173,14 @@ func (h *Handler) TestConnection(c *gin.Context) {
 	}
 
 	// 调试日志：记录接收到的请求
-	log.Printf("[TestConnection DEBUG] received request: %+v", req)
+	log.Printf("[DEBUG] TestConnection received request: %+v", req)
 
 	// 测试连接
-	resp, err := h.connService.TestConnection(&req.Connection)
+	resp, err := h.connService.TestConnection(c.Request.Context(), &req.Connection)
 	if err != nil {
 		// 调试日志：记录连接服务错误
-		log.Printf("[TestConnection DEBUG] connService.TestConnection error: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		log.Printf("[DEBUG] TestConnection connService.TestConnection error: %v", err)
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Connection test failed: " + err.Error(),
 		})
@@ -140,7 +188,9 @@ func (h *Handler) TestConnection(c *gin.Context) {
 	}
 
 	// 调试日志：记录连接服务返回的完整响应
-	log.Printf("[TestConnection DEBUG] connService.TestConnection response: %+v", resp)
+	log.Printf("[DEBUG] TestConnection connService.TestConnection response: %+v", resp)
+
+	resp.Message = i18n.T(requestLanguage(c), resp.Message)
 
 	// 构建最终响应
 	finalResponse := models.APIResponse{
@@ -150,9 +200,93 @@ func (h *Handler) TestConnection(c *gin.Context) {
 	}
 
 	// 调试日志：记录最终发送给前端的响应
-	log.Printf("[TestConnection DEBUG] final APIResponse: %+v", finalResponse)
+	log.Printf("[DEBUG] TestConnection final APIResponse: %+v", finalResponse)
+
+	h.respond(c, http.StatusOK, finalResponse)
+}
+
+// ListConnections 列出测试通过后保存的连接，不返回密码和令牌
+func (h *Handler) ListConnections(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Connections retrieved successfully",
+		Data:    h.connService.ListConnections(),
+	})
+}
+
+// UpdateConnection 修改保存的连接
+func (h *Handler) UpdateConnection(c *gin.Context) {
+	var req models.SystemConnection
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	conn, err := h.connService.UpdateConnection(c.Param("id"), &req)
+	if err != nil {
+		h.respondConnectionError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Connection updated",
+		Data:    conn,
+	})
+}
+
+// DeleteConnection 删除保存的连接
+func (h *Handler) DeleteConnection(c *gin.Context) {
+	if err := h.connService.DeleteConnection(c.Param("id")); err != nil {
+		h.respondConnectionError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Connection deleted",
+	})
+}
+
+// respondConnectionError 连接不存在时返回404，其余返回400
+func (h *Handler) respondConnectionError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	message := err.Error()
+	if err == models.ErrConnectionNotFound {
+		status = http.StatusNotFound
+		message = "Connection not found"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// Preflight 迁移前检查源和目标系统是否就绪
+func (h *Handler) Preflight(c *gin.Context) {
+	var req models.PreflightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	report := h.migrationService.Preflight(c.Request.Context(), &req)
+	lang := requestLanguage(c)
+	for i := range report.Checks {
+		report.Checks[i].Message = i18n.T(lang, report.Checks[i].Message)
+	}
 
-	c.JSON(http.StatusOK, finalResponse)
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Preflight check completed",
+		Data:    report,
+	})
 }
 
 // StartOnlineMigration 开始在线迁移
@@ -169,7 +303,7 @@ func (h *Handler) StartOnlineMigration(c *gin.Context) {
 	var req models.OnlineMigrationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("[ERROR] Failed to parse request body: %v", err)
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid request: " + err.Error(),
 		})
@@ -180,10 +314,31 @@ func (h *Handler) StartOnlineMigration(c *gin.Context) {
 		req.Source.Host, req.Source.Port, req.Target.Host, req.Target.Port)
 
 	// 开始迁移
-	task, err := h.migrationService.StartOnlineMigration(&req)
+	req.Language = requestLanguage(c)
+	task, err := h.migrationService.StartOnlineMigration(c.Request.Context(), &req)
 	if err != nil {
 		log.Printf("[ERROR] Failed to start online migration: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		// AppData很大的应用需要确认后再迁移
+		var largeErr *models.LargeAppsError
+		if errors.As(err, &largeErr) {
+			h.respond(c, http.StatusConflict, models.APIResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    largeErr,
+			})
+			return
+		}
+		// 镜像没有目标CPU架构版本的应用需要确认后再迁移
+		var archErr *models.ArchMismatchError
+		if errors.As(err, &archErr) {
+			h.respond(c, http.StatusConflict, models.APIResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    archErr,
+			})
+			return
+		}
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to start online migration: " + err.Error(),
 		})
@@ -192,7 +347,7 @@ func (h *Handler) StartOnlineMigration(c *gin.Context) {
 
 	log.Printf("[DEBUG] Online migration task created: %s", task.ID)
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Online migration started",
 		Data: map[string]interface{}{
@@ -202,69 +357,230 @@ func (h *Handler) StartOnlineMigration(c *gin.Context) {
 	})
 }
 
-// StartDataExport 开始数据导出 - 直接下载
-func (h *Handler) StartDataExport(c *gin.Context) {
-	var req models.DataExportRequest
+// EstimateMigration 预估迁移数据量和耗时
+func (h *Handler) EstimateMigration(c *gin.Context) {
+	var req models.EstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.connService.ValidateConnectionConfig(&req.Source); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid source connection configuration: " + err.Error(),
+		})
+		return
+	}
+
+	estimate, err := h.migrationService.EstimateMigration(c.Request.Context(), &req.Source)
+	if err != nil {
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to estimate migration: " + err.Error(),
+		})
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Estimate completed",
+		Data:    estimate,
+	})
+}
+
+// ListSourceApps 获取源系统已安装应用列表
+func (h *Handler) ListSourceApps(c *gin.Context) {
+	var req models.SourceAppsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Message: "请求参数无效: " + err.Error(),
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.connService.ValidateConnectionConfig(&req.Source); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid source connection configuration: " + err.Error(),
 		})
 		return
 	}
 
-	// 直接生成并返回压缩包
-	filePath, err := h.migrationService.CreateDirectExport(&req.Source)
+	apps, err := h.migrationService.ListSourceApps(c.Request.Context(), &req.Source)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "生成导出文件失败: " + err.Error(),
+			Message: "Failed to fetch source apps: " + err.Error(),
 		})
 		return
 	}
 
-	// 设置响应头
-	c.Header("Content-Type", "application/gzip")
-	c.Header("Content-Disposition", "attachment; filename=\"casaos-export.tar.gz\"")
-	c.Header("Content-Transfer-Encoding", "binary")
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d apps", len(apps)),
+		Data:    apps,
+	})
+}
 
-	// 发送文件
-	c.File(filePath)
+// ListTargetStorage 列出ZimaOS目标上可用于存放AppData的存储卷
+func (h *Handler) ListTargetStorage(c *gin.Context) {
+	var req models.TargetStorageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
 
-	// 清理临时文件
-	go func() {
-		time.Sleep(5 * time.Second)
-		os.Remove(filePath)
-	}()
+	if err := h.connService.ValidateConnectionConfig(&req.Target); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid target connection configuration: " + err.Error(),
+		})
+		return
+	}
+
+	volumes, err := h.migrationService.ListTargetStorage(c.Request.Context(), &req.Target)
+	if err != nil {
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to fetch target storage: " + err.Error(),
+		})
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d storage volumes", len(volumes)),
+		Data:    volumes,
+	})
+}
+
+// StartDataExport 开始数据导出 - 直接下载
+func (h *Handler) StartDataExport(c *gin.Context) {
+	var req models.DataExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	// 设置了推送目标时以后台任务方式导出并推送，否则直接下载
+	if _, ok := req.ExportOptions["destination"]; ok {
+		req.Language = requestLanguage(c)
+		task, err := h.migrationService.StartDataExport(&req)
+		if err != nil {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Failed to start data export: " + err.Error(),
+			})
+			return
+		}
+
+		h.respond(c, http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Data export started",
+			Data: map[string]interface{}{
+				"task_id": task.ID,
+				"status":  task.Status,
+			},
+		})
+		return
+	}
+
+	format, _ := req.ExportOptions["format"].(string)
+	h.sendExport(c, &req.Source, format, req.ExportOptions)
 }
 
 // ExportDownload 直接导出并下载压缩包
 func (h *Handler) ExportDownload(c *gin.Context) {
 	var req struct {
 		SourceConnection models.SystemConnection `json:"source_connection"`
+		Format           string                  `json:"format"` // casaos（默认）/portainer
+		models.ExportFilter
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	options := map[string]interface{}{
+		"include": req.Include,
+		"exclude": req.Exclude,
+	}
+	h.sendExport(c, &req.SourceConnection, req.Format, options)
+}
+
+// sendExport 按导出格式生成压缩包并作为附件返回，options中的include和exclude用于筛选CasaOS格式的导出内容
+func (h *Handler) sendExport(c *gin.Context, source *models.SystemConnection, format string, options map[string]interface{}) {
+	if err := services.ValidateExportFilter(options); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Message: "请求参数无效: " + err.Error(),
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// 直接生成并返回压缩包
-	filePath, err := h.migrationService.CreateDirectExport(&req.SourceConnection)
+	var filePath string
+	var err error
+
+	switch format {
+	case "", services.ExportFormatCasaOS:
+		// 直接生成并返回压缩包
+		filePath, err = h.migrationService.CreateDirectExport(c.Request.Context(), source, options)
+	case services.ExportFormatPortainer:
+		filePath, err = h.migrationService.CreatePortainerExport(c.Request.Context(), source)
+	default:
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("Unsupported export format: %s", format),
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		// 源系统连接失败时返回连接测试的详细结果
+		var connErr *models.SourceConnectionError
+		if errors.As(err, &connErr) {
+			connErr.Test.Message = i18n.T(requestLanguage(c), connErr.Test.Message)
+			h.respond(c, http.StatusBadGateway, models.APIResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    connErr.Test,
+			})
+			return
+		}
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "生成导出文件失败: " + err.Error(),
+			Message: "Failed to generate export file: " + err.Error(),
 		})
 		return
 	}
 
 	// 设置响应头
-	c.Header("Content-Type", "application/gzip")
-	c.Header("Content-Disposition", "attachment; filename=\"casaos-export.tar.gz\"")
+	if format == services.ExportFormatPortainer {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", "attachment; filename=\"portainer-stacks.zip\"")
+	} else if services.IsDemoExport(filePath) {
+		// 演示数据使用不同的文件名，并通过响应头标记
+		c.Header("Content-Type", "application/gzip")
+		c.Header("Content-Disposition", "attachment; filename=\"casaos-export-demo.tar.gz\"")
+		c.Header("X-CTOZ-Demo", "true")
+	} else {
+		c.Header("Content-Type", "application/gzip")
+		c.Header("Content-Disposition", "attachment; filename=\"casaos-export.tar.gz\"")
+	}
 	c.Header("Content-Transfer-Encoding", "binary")
 
 	// 发送文件
@@ -282,7 +598,7 @@ func (h *Handler) StartDataImport(c *gin.Context) {
 	var req models.DataImportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("[ERROR] StartDataImport - failed to bind request: %v", err)
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid request: " + err.Error(),
 		})
@@ -301,10 +617,11 @@ func (h *Handler) StartDataImport(c *gin.Context) {
 	}
 
 	// 开始导入
+	req.Language = requestLanguage(c)
 	task, err := h.migrationService.StartDataImport(&req)
 	if err != nil {
 		log.Printf("[ERROR] StartDataImport - failed to start: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to start data import: " + err.Error(),
 		})
@@ -312,7 +629,7 @@ func (h *Handler) StartDataImport(c *gin.Context) {
 	}
 
 	log.Printf("[INFO] StartDataImport - task started, TaskID: %s", task.ID)
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Data import started",
 		Data: map[string]interface{}{
@@ -326,7 +643,7 @@ func (h *Handler) StartDataImport(c *gin.Context) {
 func (h *Handler) GetTaskStatus(c *gin.Context) {
 	taskID := c.Param("id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Task ID is required",
 		})
@@ -336,32 +653,18 @@ func (h *Handler) GetTaskStatus(c *gin.Context) {
 	// 获取任务
 	task, err := h.taskService.GetTask(taskID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+		h.respond(c, http.StatusNotFound, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// 创建任务副本，不返回敏感信息
-	taskCopy := *task
-	if taskCopy.Source != nil {
-		sourceCopy := *taskCopy.Source
-		sourceCopy.Password = "" // 不返回密码
-		sourceCopy.Token = ""    // 不返回令牌
-		taskCopy.Source = &sourceCopy
-	}
-	if taskCopy.Target != nil {
-		targetCopy := *taskCopy.Target
-		targetCopy.Password = "" // 不返回密码
-		targetCopy.Token = ""    // 不返回令牌
-		taskCopy.Target = &targetCopy
-	}
-
-	c.JSON(http.StatusOK, models.APIResponse{
+	// 返回任务副本，不返回敏感信息
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Task status retrieved",
-		Data:    &taskCopy,
+		Data:    services.RedactTask(task),
 	})
 }
 
@@ -372,6 +675,9 @@ func (h *Handler) ListTasks(c *gin.Context) {
 	offsetStr := c.DefaultQuery("offset", "0")
 	status := c.Query("status")
 	taskType := c.Query("type")
+	name := strings.ToLower(c.Query("name"))
+	search := strings.ToLower(c.Query("q"))
+	labels := c.QueryArray("label")
 
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 {
@@ -400,6 +706,18 @@ func (h *Handler) ListTasks(c *gin.Context) {
 		if taskType != "" && task.Type != taskType {
 			continue
 		}
+		// 名称过滤（不区分大小写的包含匹配）
+		if name != "" && !strings.Contains(strings.ToLower(task.Name), name) {
+			continue
+		}
+		// 名称或备注关键字过滤
+		if search != "" && !strings.Contains(strings.ToLower(task.Name), search) && !strings.Contains(strings.ToLower(task.Notes), search) {
+			continue
+		}
+		// 标签过滤，所有条件都需满足
+		if !matchTaskLabels(task.Labels, labels) {
+			continue
+		}
 		filteredTasks = append(filteredTasks, task)
 	}
 
@@ -414,14 +732,13 @@ func (h *Handler) ListTasks(c *gin.Context) {
 		end = total
 	}
 
-	var pagedTasks []*models.MigrationTask
-	if start < end {
-		pagedTasks = filteredTasks[start:end]
-	} else {
-		pagedTasks = []*models.MigrationTask{}
+	// 列表同样不返回密码、令牌和密钥
+	pagedTasks := make([]*models.MigrationTask, 0, end-start)
+	for _, task := range filteredTasks[start:end] {
+		pagedTasks = append(pagedTasks, services.RedactTask(task))
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Task list retrieved",
 		Data: map[string]interface{}{
@@ -433,143 +750,1398 @@ func (h *Handler) ListTasks(c *gin.Context) {
 	})
 }
 
+// ExportTasks 以CSV或NDJSON文件下载全部任务历史（含摘要和耗时，不含密码和令牌），
+// format默认为csv，可以按status和type过滤
+func (h *Handler) ExportTasks(c *gin.Context) {
+	format := c.DefaultQuery("format", services.TaskExportCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case services.TaskExportCSV:
+	case services.TaskExportNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid export format %s: must be csv or ndjson", format),
+		})
+		return
+	}
+
+	records := h.migrationService.TaskHistory(c.Query("status"), c.Query("type"))
+	fileName := fmt.Sprintf("tasks_%s.%s", time.Now().Format("20060102_150405"), format)
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	if err := services.WriteTaskHistory(c.Writer, format, records); err != nil {
+		log.Printf("[ERROR] Failed to export task history: %v", err)
+	}
+}
+
+// matchTaskLabels 判断任务标签是否满足所有选择条件，条件格式为key=value或key（仅要求存在）
+func matchTaskLabels(taskLabels map[string]string, selectors []string) bool {
+	for _, selector := range selectors {
+		key, value, hasValue := strings.Cut(selector, "=")
+		actual, ok := taskLabels[strings.TrimSpace(key)]
+		if !ok || (hasValue && actual != strings.TrimSpace(value)) {
+			return false
+		}
+	}
+	return true
+}
+
 // DeleteTask 删除任务
 func (h *Handler) DeleteTask(c *gin.Context) {
 	taskID := c.Param("id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Task ID is required",
 		})
 		return
 	}
 
-	// 检查任务是否存在
-	task, err := h.taskService.GetTask(taskID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+	// 删除任务，运行中的任务不能删除
+	if status, err := h.deleteTask(taskID); err != nil {
+		h.respond(c, status, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// 检查任务状态，运行中的任务不能删除
-	if task.Status == string(models.TaskStatusRunning) {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Message: "Running tasks cannot be deleted",
-		})
-		return
-	}
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Task deleted successfully",
+	})
+}
+
+// ResumeTask 恢复因服务重启而中断的任务
+func (h *Handler) ResumeTask(c *gin.Context) {
+	taskID := c.Param("id")
 
-	// 删除任务
-	if err := h.taskService.DeleteTask(taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+	task, err := h.migrationService.ResumeTask(taskID)
+	if err != nil {
+		status := http.StatusBadRequest
+		message := err.Error()
+		switch err {
+		case models.ErrTaskNotFound:
+			status = http.StatusNotFound
+		case models.ErrInvalidTaskStatus:
+			message = "Only interrupted or waiting tasks can be resumed"
+		}
+		h.respond(c, status, models.APIResponse{
 			Success: false,
-			Message: "Failed to delete task: " + err.Error(),
+			Message: message,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Task deleted successfully",
+		Message: "Task resumed",
+		Data:    services.RedactTask(task),
 	})
 }
 
-// GetTaskLogs 获取任务日志
-func (h *Handler) GetTaskLogs(c *gin.Context) {
+// PromoteStagedApps 用蓝绿导入的临时应用替换目标上的原应用
+func (h *Handler) PromoteStagedApps(c *gin.Context) {
 	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Message: "Task ID is required",
-		})
-		return
+
+	var req models.PromoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
 	}
 
-	// 检查任务是否存在
-	if _, err := h.taskService.GetTask(taskID); err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+	results, err := h.migrationService.PromoteStagedApps(c.Request.Context(), taskID, req.Apps)
+	if err != nil {
+		status := http.StatusBadRequest
+		message := err.Error()
+		switch err {
+		case models.ErrTaskNotFound:
+			status = http.StatusNotFound
+		case models.ErrInvalidTaskStatus:
+			message = "Only completed tasks can be promoted"
+		}
+		h.respond(c, status, models.APIResponse{
 			Success: false,
-			Message: "Task not found",
+			Message: message,
 		})
 		return
 	}
 
-	// 获取任务日志
-	logs, err := h.taskService.GetTaskLogs(taskID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+	success := true
+	for _, result := range results {
+		if result.Status == models.AppStatusFailed {
+			success = false
+		}
+	}
+	message := "Staged apps promoted"
+	if !success {
+		message = "Some staged apps could not be promoted"
+	}
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: success,
+		Message: message,
+		Data:    results,
+	})
+}
+
+// CancelTask 取消正在执行的任务，进行中的下载、上传和外部命令随之中止
+func (h *Handler) CancelTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if err := h.taskService.CancelTask(taskID); err != nil {
+		status := http.StatusBadRequest
+		message := err.Error()
+		switch err {
+		case models.ErrTaskNotFound:
+			status = http.StatusNotFound
+		case models.ErrInvalidTaskStatus:
+			message = "Only running tasks can be cancelled"
+		}
+		h.respond(c, status, models.APIResponse{
 			Success: false,
-			Message: "Failed to get task logs: " + err.Error(),
+			Message: message,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Task logs retrieved",
-		Data:    logs,
+		Message: "Task cancellation requested",
 	})
 }
 
-// HandleWebSocket 处理WebSocket连接
-func (h *Handler) HandleWebSocket(c *gin.Context) {
-	taskID := c.Query("task_id")
-	if taskID == "" {
-		c.AbortWithStatus(http.StatusBadRequest)
+// maxBatchTasks 单次批量操作的最多任务数
+const maxBatchTasks = 1000
+
+// BatchTasks 对多个任务执行删除、取消或重试，逐个处理并返回每个任务的结果，
+// 单个任务失败不影响其他任务
+func (h *Handler) BatchTasks(c *gin.Context) {
+	var req models.BatchTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
 		return
 	}
 
-	// 检查任务是否存在
-	_, err := h.taskService.GetTask(taskID)
-	if err != nil {
-		c.AbortWithStatus(http.StatusNotFound)
+	var apply func(taskID string) error
+	switch req.Action {
+	case models.BatchActionDelete:
+		apply = func(taskID string) error {
+			_, err := h.deleteTask(taskID)
+			return err
+		}
+	case models.BatchActionCancel:
+		apply = func(taskID string) error {
+			err := h.taskService.CancelTask(taskID)
+			if err == models.ErrInvalidTaskStatus {
+				return fmt.Errorf("Only running tasks can be cancelled")
+			}
+			return err
+		}
+	case models.BatchActionRetry:
+		apply = func(taskID string) error {
+			_, err := h.migrationService.RetryTask(taskID)
+			if err == models.ErrInvalidTaskStatus {
+				return fmt.Errorf("Only failed, interrupted or waiting tasks can be retried")
+			}
+			return err
+		}
+	default:
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid action %s: must be delete, cancel or retry", req.Action),
+		})
+		return
+	}
+	if len(req.IDs) > maxBatchTasks {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("Too many tasks in one request: at most %d", maxBatchTasks),
+		})
 		return
 	}
 
-	// 处理WebSocket连接
+	lang := requestLanguage(c)
+	resp := models.BatchTaskResponse{Action: req.Action, Results: []models.BatchTaskResult{}}
+	seen := make(map[string]bool)
+	for _, taskID := range req.IDs {
+		if taskID == "" || seen[taskID] {
+			continue
+		}
+		seen[taskID] = true
+
+		result := models.BatchTaskResult{TaskID: taskID, Success: true}
+		if err := apply(taskID); err != nil {
+			message := err.Error()
+			if err == models.ErrTaskNotFound {
+				message = "Task not found"
+			}
+			result.Success = false
+			result.Error = i18n.T(lang, message)
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	log.Printf("[INFO] Batch %s: %d succeeded, %d failed", req.Action, resp.Succeeded, resp.Failed)
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: resp.Failed == 0,
+		Message: fmt.Sprintf("Batch %s: %d succeeded, %d failed", req.Action, resp.Succeeded, resp.Failed),
+		Data:    resp,
+	})
+}
+
+// deleteTask 删除任务，DeleteTask和批量删除共用，返回失败时的HTTP状态码和错误信息
+func (h *Handler) deleteTask(taskID string) (int, error) {
+	switch err := h.taskService.DeleteTask(taskID); err {
+	case nil:
+		return http.StatusOK, nil
+	case models.ErrTaskNotFound:
+		return http.StatusNotFound, err
+	case models.ErrInvalidTaskStatus:
+		return http.StatusBadRequest, fmt.Errorf("Running tasks cannot be deleted")
+	default:
+		return http.StatusInternalServerError, fmt.Errorf("Failed to delete task: %v", err)
+	}
+}
+
+// GetTaskLogs 获取任务日志
+// 支持 offset/limit 分页、since(RFC3339) 时间过滤，follow=true 时以NDJSON分块流式输出实时日志
+func (h *Handler) GetTaskLogs(c *gin.Context) {
+	taskID := c.Param("id")
+	if taskID == "" {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Task ID is required",
+		})
+		return
+	}
+
+	// 检查任务是否存在
+	if _, err := h.taskService.GetTask(taskID); err != nil {
+		h.respond(c, http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Task not found",
+		})
+		return
+	}
+
+	query, err := parseLogQuery(c)
+	if err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid log query: " + err.Error(),
+		})
+		return
+	}
+
+	// 实时跟踪模式
+	if c.Query("follow") == "true" {
+		h.followTaskLogs(c, taskID, query)
+		return
+	}
+
+	// 获取任务日志
+	paged := c.Query("offset") != "" || c.Query("limit") != "" || c.Query("since") != ""
+	logs, total, err := h.taskService.QueryTaskLogs(taskID, query)
+	if err != nil {
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get task logs: " + err.Error(),
+		})
+		return
+	}
+
+	// 未指定分页和时间参数时与之前一样直接返回日志数组，保持兼容
+	if !paged {
+		h.respond(c, http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Task logs retrieved",
+			Data:    logs,
+		})
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Task logs retrieved",
+		Data: map[string]interface{}{
+			"logs":        logs,
+			"total":       total,
+			"offset":      query.Offset,
+			"limit":       query.Limit,
+			"next_offset": query.Offset + len(logs),
+		},
+	})
+}
+
+// GetTaskEvents 获取任务发出过的WebSocket消息（状态、步骤、进度、日志），since为上次获取到的最大序号
+func (h *Handler) GetTaskEvents(c *gin.Context) {
+	var since int64
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		value, err := strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || value < 0 {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid since: must be a non-negative integer",
+			})
+			return
+		}
+		since = value
+	}
+
+	page, err := h.taskService.GetTaskEvents(c.Param("id"), since)
+	if err != nil {
+		h.respond(c, http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Task not found",
+		})
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Task events retrieved",
+		Data:    page,
+	})
+}
+
+// parseLogQuery 解析日志查询参数
+func parseLogQuery(c *gin.Context) (models.LogQuery, error) {
+	var query models.LogQuery
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return query, fmt.Errorf("offset must be a non-negative integer")
+		}
+		query.Offset = offset
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return query, fmt.Errorf("limit must be a non-negative integer")
+		}
+		if limit > 1000 {
+			limit = 1000 // 限制单页最大返回数量
+		}
+		query.Limit = limit
+	}
+
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			return query, fmt.Errorf("since must be an RFC3339 timestamp")
+		}
+		query.Since = since
+	}
+
+	return query, nil
+}
+
+// followTaskLogs 以NDJSON分块方式持续输出任务日志，直到任务结束或客户端断开
+func (h *Handler) followTaskLogs(c *gin.Context, taskID string, query models.LogQuery) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// 跟踪模式下由客户端断开决定结束，不做单次数量限制
+	query.Limit = 0
+	encoder := json.NewEncoder(c.Writer)
+	first := true
+
+	c.Stream(func(w io.Writer) bool {
+		if !first {
+			time.Sleep(time.Second)
+		}
+		first = false
+
+		logs, _, err := h.taskService.QueryTaskLogs(taskID, query)
+		if err != nil {
+			return false
+		}
+		for _, l := range logs {
+			if err := encoder.Encode(l); err != nil {
+				return false
+			}
+		}
+		query.Offset += len(logs)
+
+		// 任务已结束且没有新日志时停止输出
+		task, err := h.taskService.GetTask(taskID)
+		if err != nil {
+			return false
+		}
+		return !(models.IsTerminalStatus(task.Status) && len(logs) == 0)
+	})
+}
+
+// DownloadTaskLogs 以文件附件形式下载完整任务日志
+// format=text（默认）输出纯文本，format=ndjson 输出每行一个JSON对象
+func (h *Handler) DownloadTaskLogs(c *gin.Context) {
+	taskID := c.Param("id")
+	if taskID == "" {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Task ID is required",
+		})
+		return
+	}
+
+	// 检查任务是否存在
+	if _, err := h.taskService.GetTask(taskID); err != nil {
+		h.respond(c, http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Task not found",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "text")
+	if format != "text" && format != "ndjson" {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Unsupported log format, use text or ndjson",
+		})
+		return
+	}
+
+	logs, _, err := h.taskService.QueryTaskLogs(taskID, models.LogQuery{})
+	if err != nil {
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get task logs: " + err.Error(),
+		})
+		return
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	var buf strings.Builder
+	var fileName, contentType string
+
+	if format == "ndjson" {
+		fileName = fmt.Sprintf("task_%s_logs_%s.ndjson", taskID, timestamp)
+		contentType = "application/x-ndjson"
+		encoder := json.NewEncoder(&buf)
+		for _, l := range logs {
+			encoder.Encode(l)
+		}
+	} else {
+		fileName = fmt.Sprintf("task_%s_logs_%s.log", taskID, timestamp)
+		contentType = "text/plain; charset=utf-8"
+		for _, l := range logs {
+			fmt.Fprintf(&buf, "%s [%s] %s\n", l.Timestamp.Format(time.RFC3339Nano), strings.ToUpper(l.Level), l.Message)
+		}
+	}
+
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, contentType, []byte(buf.String()))
+}
+
+// HandleWebSocket 处理WebSocket连接
+func (h *Handler) HandleWebSocket(c *gin.Context) {
+	// 上传导入文件时订阅上传的临时频道，此时任务还没有创建
+	if uploadID := c.Query("upload_id"); uploadID != "" {
+		if _, err := uuid.Parse(uploadID); err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		h.wsManager.HandleChannel(c, websocket.UploadChannel(uploadID))
+		return
+	}
+
+	taskID := c.Query("task_id")
+	if taskID == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	// 检查任务是否存在
+	_, err := h.taskService.GetTask(taskID)
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	// 处理WebSocket连接
 	h.wsManager.HandleWebSocket(c)
 
-	// 移除不必要的测试消息发送逻辑
-	// 当WebSocket连接建立时，不需要发送测试消息
-	// 任务状态和日志会通过正常的业务流程发送
+	// 移除不必要的测试消息发送逻辑
+	// 当WebSocket连接建立时，不需要发送测试消息
+	// 任务状态和日志会通过正常的业务流程发送
+}
+
+// GetSystemInfo 获取系统信息
+func (h *Handler) GetSystemInfo(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "System info",
+		Data: map[string]interface{}{
+			"name":        "CasaOS to ZimaOS Migration Tool",
+			"version":     services.ToolVersion,
+			"description": "A tool for migrating from CasaOS to ZimaOS",
+			"read_only":   h.cfg.ReadOnly,
+			"demo_mode":   h.cfg.DemoMode,
+			"started_at":  services.StartedAt().UTC().Format(time.RFC3339),
+			// 服务已运行的秒数
+			"uptime_seconds": int64(services.Uptime().Seconds()),
+			// 导入文件上传的最大字节数，0表示不限制
+			"max_upload_bytes": h.cfg.MaxUploadBytes,
+			"features": []string{
+				"Online migration",
+				"Offline export/import",
+				"Live status updates",
+				"Web UI",
+			},
+			"supported_systems": []string{
+				"CasaOS",
+				"ZimaOS",
+			},
+		},
+	})
+}
+
+// ListBackupJobs 列出定时备份任务
+func (h *Handler) ListBackupJobs(c *gin.Context) {
+	jobs := h.backupService.ListJobs()
+	redacted := make([]*models.BackupJob, 0, len(jobs))
+	for _, job := range jobs {
+		redacted = append(redacted, services.RedactBackupJob(job))
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backup jobs retrieved successfully",
+		Data:    redacted,
+	})
+}
+
+// GetBackupJob 获取定时备份任务
+func (h *Handler) GetBackupJob(c *gin.Context) {
+	job, err := h.backupService.GetJob(c.Param("id"))
+	if err != nil {
+		h.respondBackupJobError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backup job retrieved successfully",
+		Data:    services.RedactBackupJob(job),
+	})
+}
+
+// CreateBackupJob 创建定时备份任务
+func (h *Handler) CreateBackupJob(c *gin.Context) {
+	var req models.BackupJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.Interval == "" {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Backup interval is required",
+		})
+		return
+	}
+
+	job, err := h.backupService.CreateJob(&req)
+	if err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.respond(c, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Backup job created",
+		Data:    services.RedactBackupJob(job),
+	})
+}
+
+// UpdateBackupJob 更新定时备份任务
+func (h *Handler) UpdateBackupJob(c *gin.Context) {
+	var req models.BackupJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	job, err := h.backupService.UpdateJob(c.Param("id"), &req)
+	if err != nil {
+		h.respondBackupJobError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backup job updated",
+		Data:    services.RedactBackupJob(job),
+	})
+}
+
+// DeleteBackupJob 删除定时备份任务
+func (h *Handler) DeleteBackupJob(c *gin.Context) {
+	if err := h.backupService.DeleteJob(c.Param("id")); err != nil {
+		h.respondBackupJobError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backup job deleted",
+	})
+}
+
+// RunBackupJob 立即执行一次定时备份任务
+func (h *Handler) RunBackupJob(c *gin.Context) {
+	task, err := h.backupService.RunJob(c.Param("id"))
+	if err != nil {
+		h.respondBackupJobError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Backup started",
+		Data: map[string]interface{}{
+			"task_id": task.ID,
+			"status":  task.Status,
+		},
+	})
+}
+
+// respondBackupJobError 备份任务不存在时返回404，其余返回400
+func (h *Handler) respondBackupJobError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	message := err.Error()
+	if err == models.ErrBackupJobNotFound {
+		status = http.StatusNotFound
+		message = "Backup job not found"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// ListPresets 列出迁移选项预设
+func (h *Handler) ListPresets(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Presets retrieved successfully",
+		Data:    h.presetService.ListPresets(),
+	})
+}
+
+// GetPreset 获取迁移选项预设
+func (h *Handler) GetPreset(c *gin.Context) {
+	preset, err := h.presetService.GetPreset(c.Param("name"))
+	if err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Preset retrieved successfully",
+		Data:    preset,
+	})
+}
+
+// CreatePreset 创建迁移选项预设
+func (h *Handler) CreatePreset(c *gin.Context) {
+	var req models.OptionPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	preset, err := h.presetService.CreatePreset(&req)
+	if err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Preset created",
+		Data:    preset,
+	})
+}
+
+// UpdatePreset 更新迁移选项预设
+func (h *Handler) UpdatePreset(c *gin.Context) {
+	var req models.OptionPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	preset, err := h.presetService.UpdatePreset(c.Param("name"), &req)
+	if err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Preset updated",
+		Data:    preset,
+	})
+}
+
+// DeletePreset 删除迁移选项预设
+func (h *Handler) DeletePreset(c *gin.Context) {
+	if err := h.presetService.DeletePreset(c.Param("name")); err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Preset deleted",
+	})
+}
+
+// respondPresetError 预设不存在返回404，名称冲突返回409，其余返回400
+func (h *Handler) respondPresetError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	message := err.Error()
+	switch err {
+	case models.ErrPresetNotFound:
+		status = http.StatusNotFound
+		message = "Preset not found"
+	case models.ErrPresetExists:
+		status = http.StatusConflict
+		message = "Preset already exists"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// ListPathRules 按匹配顺序列出bind挂载路径改写规则
+func (h *Handler) ListPathRules(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Path rules retrieved successfully",
+		Data:    h.pathRuleService.ListPathRules(),
+	})
+}
+
+// GetPathRule 获取路径改写规则
+func (h *Handler) GetPathRule(c *gin.Context) {
+	rule, err := h.pathRuleService.GetPathRule(c.Param("id"))
+	if err != nil {
+		h.respondPathRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Path rule retrieved successfully",
+		Data:    rule,
+	})
+}
+
+// CreatePathRule 创建路径改写规则
+func (h *Handler) CreatePathRule(c *gin.Context) {
+	var req models.PathRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := h.pathRuleService.CreatePathRule(&req)
+	if err != nil {
+		h.respondPathRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Path rule created",
+		Data:    rule,
+	})
+}
+
+// UpdatePathRule 更新路径改写规则
+func (h *Handler) UpdatePathRule(c *gin.Context) {
+	var req models.PathRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := h.pathRuleService.UpdatePathRule(c.Param("id"), &req)
+	if err != nil {
+		h.respondPathRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Path rule updated",
+		Data:    rule,
+	})
+}
+
+// DeletePathRule 删除路径改写规则
+func (h *Handler) DeletePathRule(c *gin.Context) {
+	if err := h.pathRuleService.DeletePathRule(c.Param("id")); err != nil {
+		h.respondPathRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Path rule deleted",
+	})
+}
+
+// respondPathRuleError 规则不存在返回404，其余返回400
+func (h *Handler) respondPathRuleError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	message := err.Error()
+	if err == models.ErrPathRuleNotFound {
+		status = http.StatusNotFound
+		message = "Path rule not found"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// ListImageRules 按匹配顺序列出镜像改写规则
+func (h *Handler) ListImageRules(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image rules retrieved successfully",
+		Data:    h.imageRuleService.ListImageRules(),
+	})
+}
+
+// GetImageRule 获取镜像改写规则
+func (h *Handler) GetImageRule(c *gin.Context) {
+	rule, err := h.imageRuleService.GetImageRule(c.Param("id"))
+	if err != nil {
+		h.respondImageRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image rule retrieved successfully",
+		Data:    rule,
+	})
+}
+
+// CreateImageRule 创建镜像改写规则
+func (h *Handler) CreateImageRule(c *gin.Context) {
+	var req models.ImageRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := h.imageRuleService.CreateImageRule(&req)
+	if err != nil {
+		h.respondImageRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Image rule created",
+		Data:    rule,
+	})
 }
 
-// GetSystemInfo 获取系统信息
-func (h *Handler) GetSystemInfo(c *gin.Context) {
-	c.JSON(http.StatusOK, models.APIResponse{
+// UpdateImageRule 更新镜像改写规则
+func (h *Handler) UpdateImageRule(c *gin.Context) {
+	var req models.ImageRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	rule, err := h.imageRuleService.UpdateImageRule(c.Param("id"), &req)
+	if err != nil {
+		h.respondImageRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "System info",
-		Data: map[string]interface{}{
-			"name":        "CasaOS to ZimaOS Migration Tool",
-			"version":     "1.0.0",
-			"description": "A tool for migrating from CasaOS to ZimaOS",
-			"features": []string{
-				"Online migration",
-				"Offline export/import",
-				"Live status updates",
-				"Web UI",
-			},
-			"supported_systems": []string{
-				"CasaOS",
-				"ZimaOS",
-			},
-		},
+		Message: "Image rule updated",
+		Data:    rule,
+	})
+}
+
+// DeleteImageRule 删除镜像改写规则
+func (h *Handler) DeleteImageRule(c *gin.Context) {
+	if err := h.imageRuleService.DeleteImageRule(c.Param("id")); err != nil {
+		h.respondImageRuleError(c, err)
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image rule deleted",
+	})
+}
+
+// respondImageRuleError 规则不存在返回404，其余返回400
+func (h *Handler) respondImageRuleError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	message := err.Error()
+	if err == models.ErrImageRuleNotFound {
+		status = http.StatusNotFound
+		message = "Image rule not found"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// GetTaskBindMounts 按当前规则预览任务中每个bind挂载改写前后的路径
+func (h *Handler) GetTaskBindMounts(c *gin.Context) {
+	mounts, err := h.pathRuleService.PreviewTaskBindMounts(c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := err.Error()
+		if err == models.ErrTaskNotFound {
+			status = http.StatusNotFound
+			message = "Task not found"
+		}
+		h.respond(c, status, models.APIResponse{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Bind mounts retrieved successfully",
+		Data:    mounts,
+	})
+}
+
+// GetTaskSteps 获取任务的步骤清单及每个步骤的状态、耗时和错误信息
+func (h *Handler) GetTaskSteps(c *gin.Context) {
+	steps, err := h.taskService.GetTaskSteps(c.Param("id"))
+	if err != nil {
+		h.respond(c, http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Task not found",
+		})
+		return
+	}
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Task steps retrieved successfully",
+		Data:    steps,
+	})
+}
+
+// DownloadTaskReport 下载任务的HTML总结报告
+func (h *Handler) DownloadTaskReport(c *gin.Context) {
+	taskID := c.Param("id")
+	report, err := h.migrationService.BuildTaskReport(taskID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := err.Error()
+		if err == models.ErrTaskNotFound {
+			status = http.StatusNotFound
+			message = "Task not found"
+		}
+		h.respond(c, status, models.APIResponse{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("task_%s_report_%s.html", taskID, time.Now().Format("20060102_150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", report)
+}
+
+// DownloadTaskDiagnostics 下载任务的诊断包，包含任务记录、日志、步骤时间线和失败的请求摘录
+func (h *Handler) DownloadTaskDiagnostics(c *gin.Context) {
+	taskID := c.Param("id")
+	bundle, err := h.migrationService.BuildTaskDiagnostics(taskID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := err.Error()
+		if err == models.ErrTaskNotFound {
+			status = http.StatusNotFound
+			message = "Task not found"
+		}
+		h.respond(c, status, models.APIResponse{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("task_%s_diagnostics_%s.zip", taskID, time.Now().Format("20060102_150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, "application/zip", bundle)
+}
+
+// AuthStatus 返回是否需要登录、可用的登录方式和当前用户
+func (h *Handler) AuthStatus(c *gin.Context) {
+	data := gin.H{
+		"enabled":   h.authService.Enabled(),
+		"providers": h.authService.Providers(),
+	}
+	if user, ok := h.authService.ValidateSession(middleware.SessionToken(c)); ok {
+		data["user"] = user
+	}
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Auth status retrieved successfully",
+		Data:    data,
+	})
+}
+
+// OIDCLogin 跳转到OIDC身份提供方登录，return_to为登录后返回的页面
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	authURL, err := h.authService.StartOIDCLogin(c.Query("return_to"))
+	if err != nil {
+		h.respondAuthError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 处理OIDC身份提供方的回调，登录成功后设置会话Cookie并返回原页面
+// 失败时跳转到登录页并通过auth_error查询参数显示原因
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		log.Printf("[WARNING] OIDC login failed: %s %s", errCode, c.Query("error_description"))
+		c.Redirect(http.StatusFound, "/login?auth_error="+url.QueryEscape(errCode))
+		return
+	}
+
+	session, returnTo, err := h.authService.FinishOIDCLogin(c.Query("state"), c.Query("code"))
+	if err != nil {
+		log.Printf("[WARNING] OIDC login failed: %v", err)
+		c.Redirect(http.StatusFound, "/login?auth_error="+url.QueryEscape(i18n.T(requestLanguage(c), err.Error())))
+		return
+	}
+	h.setSessionCookie(c, session)
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+// LDAPLogin 使用用户名和密码通过LDAP登录
+func (h *Handler) LDAPLogin(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	session, err := h.authService.LDAPLogin(req.Username, req.Password)
+	if err != nil {
+		log.Printf("[WARNING] LDAP login failed for %s: %v", req.Username, err)
+		h.respondAuthError(c, err)
+		return
+	}
+	h.setSessionCookie(c, session)
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Signed in successfully",
+		Data:    session,
+	})
+}
+
+// Logout 退出登录并清除会话Cookie
+func (h *Handler) Logout(c *gin.Context) {
+	h.authService.Logout(middleware.SessionToken(c))
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(middleware.SessionCookieName, "", -1, "/", "", h.secureCookies(), true)
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Signed out successfully",
+	})
+}
+
+// ListSessions 列出当前用户有效的登录会话，发起请求的会话标记为current
+func (h *Handler) ListSessions(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(middleware.SessionToken(c))
+	if err != nil {
+		h.respondAuthError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// RevokeSession 撤销当前用户的指定会话，例如在共用电脑上忘记退出的浏览器
+func (h *Handler) RevokeSession(c *gin.Context) {
+	if err := h.authService.RevokeSession(middleware.SessionToken(c), c.Param("id")); err != nil {
+		h.respondAuthError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Session revoked",
+	})
+}
+
+// RevokeOtherSessions 撤销当前用户除当前会话以外的全部会话
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	count, err := h.authService.RevokeOtherSessions(middleware.SessionToken(c))
+	if err != nil {
+		h.respondAuthError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Revoked %d sessions", count),
+		Data:    gin.H{"revoked": count},
+	})
+}
+
+// setSessionCookie 设置会话Cookie，SameSite=Lax防止其它站点携带Cookie调用接口
+func (h *Handler) setSessionCookie(c *gin.Context, session *models.AuthSession) {
+	maxAge := int(time.Until(session.ExpiresAt).Seconds())
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(middleware.SessionCookieName, session.Token, maxAge, "/", "", h.secureCookies(), true)
+}
+
+// secureCookies 对外地址为HTTPS时只通过HTTPS发送会话Cookie
+func (h *Handler) secureCookies() bool {
+	return strings.HasPrefix(h.cfg.PublicURL, "https://")
+}
+
+// respondAuthError 返回登录错误
+func (h *Handler) respondAuthError(c *gin.Context, err error) {
+	status := http.StatusBadGateway
+	message := err.Error()
+	switch err {
+	case models.ErrAuthProviderDisabled:
+		status = http.StatusNotFound
+		message = "Login provider is not enabled"
+	case models.ErrSessionNotFound:
+		status = http.StatusNotFound
+		message = "Session not found"
+	case models.ErrInvalidCredentials:
+		status = http.StatusUnauthorized
+		message = "Invalid username or password"
+	case models.ErrGroupNotAllowed:
+		status = http.StatusForbidden
+		message = "User is not a member of an allowed group"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// CleanupTempFiles 手动清理工作目录中未被任务引用的过期临时文件
+// 可通过 max_age（如 30m、12h）指定过期时长，默认使用 CTOZ_CLEANUP_MAX_AGE
+func (h *Handler) CleanupTempFiles(c *gin.Context) {
+	maxAge := h.janitorService.DefaultMaxAge()
+	if value := c.Query("max_age"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid max_age: %s", value),
+			})
+			return
+		}
+		maxAge = d
+	}
+
+	report := h.janitorService.Cleanup(maxAge)
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Removed %d temporary entries", len(report.Removed)),
+		Data:    report,
+	})
+}
+
+// GetVersion 返回程序的版本、git提交、构建时间和Go运行环境
+func (h *Handler) GetVersion(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    services.CurrentBuild(),
+	})
+}
+
+// GetStats 返回任务数量（按状态和类型）、日志总数、工作目录磁盘占用和WebSocket连接数，用于仪表盘
+func (h *Handler) GetStats(c *gin.Context) {
+	stats := models.Stats{
+		StoreStats: h.taskService.GetStats(),
+		WorkDirs:   h.janitorService.DiskUsage(),
+	}
+	for _, dir := range stats.WorkDirs {
+		stats.WorkDirBytes += dir.Bytes
+	}
+	stats.WebSocketClients, stats.WebSocketTasks = h.wsManager.Stats()
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+// PurgeAllData 删除全部任务、日志、保存的连接、定时备份任务和工作目录中的文件，请求体中confirm必须为true
+func (h *Handler) PurgeAllData(c *gin.Context) {
+	var req models.PurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !req.Confirm {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Purging all data requires {\"confirm\": true}",
+		})
+		return
+	}
+
+	report, err := h.janitorService.PurgeAll()
+	if errors.Is(err, models.ErrTasksRunning) {
+		h.respond(c, http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to purge data: " + err.Error(),
+		})
+		return
+	}
+
+	// 已删除任务的导入状态缓存一并清除
+	h.cacheMutex.Lock()
+	h.importStatusCache = make(map[string]models.ImportStatusResponse)
+	h.cacheExpiry = make(map[string]time.Time)
+	h.cacheMutex.Unlock()
+
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Purged %d tasks, %d connections and %d files", report.Tasks, report.Connections, len(report.RemovedFiles)),
+		Data:    report,
+	})
+}
+
+// GetLogLevel 返回当前的服务日志设置
+func (h *Handler) GetLogLevel(c *gin.Context) {
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    models.LogSettings{Level: logging.Level(), HTTPDumps: logging.HTTPDumps()},
+	})
+}
+
+// SetLogLevel 运行时修改服务日志级别和接口调用请求响应的输出，不需要重启
+func (h *Handler) SetLogLevel(c *gin.Context) {
+	var req models.LogSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.Level != nil {
+		if err := logging.SetLevel(*req.Level); err != nil {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	if req.HTTPDumps != nil {
+		logging.SetHTTPDumps(*req.HTTPDumps)
+	}
+
+	settings := models.LogSettings{Level: logging.Level(), HTTPDumps: logging.HTTPDumps()}
+	log.Printf("[INFO] Log level set to %s, HTTP dumps: %t", settings.Level, settings.HTTPDumps)
+	h.respond(c, http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Log level set to %s", settings.Level),
+		Data:    settings,
 	})
 }
 
 // HealthCheck 健康检查
 func (h *Handler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Service is healthy",
 		Data: map[string]interface{}{
-			"status":    "healthy",
-			"timestamp": "2024-01-01T00:00:00Z",
-			"uptime":    "running",
+			"status":         "healthy",
+			"timestamp":      time.Now().UTC().Format(time.RFC3339),
+			"started_at":     services.StartedAt().UTC().Format(time.RFC3339),
+			"uptime":         services.Uptime().Round(time.Second).String(),
+			"uptime_seconds": int64(services.Uptime().Seconds()),
+			"version":        services.ToolVersion,
 		},
 	})
 }
@@ -578,7 +2150,7 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 func (h *Handler) TestWebSocket(c *gin.Context) {
 	taskID := c.Param("taskId")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Task ID is required",
 		})
@@ -591,7 +2163,7 @@ func (h *Handler) TestWebSocket(c *gin.Context) {
 	h.taskService.AddTaskLog(taskID, models.LogLevelError, "This is an error level test message")
 	h.taskService.AddTaskLog(taskID, models.LogLevelWarning, "This is a warning level test message")
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "WebSocket test message sent",
 		Data: map[string]interface{}{
@@ -606,6 +2178,8 @@ func (h *Handler) CreateTestTask(c *gin.Context) {
 	// 创建一个测试任务
 	task := h.taskService.CreateTask(
 		models.TaskTypeTest,
+		requestLanguage(c),
+		models.TaskMeta{Name: "WebSocket test"},
 		&models.SystemConnection{Host: "test-source", Port: 22, Username: "test"},
 		&models.SystemConnection{Host: "test-target", Port: 22, Username: "test"},
 		map[string]interface{}{"test": true},
@@ -615,7 +2189,7 @@ func (h *Handler) CreateTestTask(c *gin.Context) {
 	h.taskService.AddTaskLog(task.ID, models.LogLevelInfo, "Test task created")
 	h.taskService.AddTaskLog(task.ID, models.LogLevelInfo, "Preparing for WebSocket test")
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Test task created successfully",
 		Data: map[string]interface{}{
@@ -630,7 +2204,7 @@ func (h *Handler) CreateTestTask(c *gin.Context) {
 func (h *Handler) GetImportStatus(c *gin.Context) {
 	taskID := c.Param("id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Task ID is required",
 		})
@@ -640,7 +2214,7 @@ func (h *Handler) GetImportStatus(c *gin.Context) {
 	// 获取任务
 	task, err := h.taskService.GetTask(taskID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+		h.respond(c, http.StatusNotFound, models.APIResponse{
 			Success: false,
 			Message: err.Error(),
 		})
@@ -649,15 +2223,15 @@ func (h *Handler) GetImportStatus(c *gin.Context) {
 
 	// 检查任务类型是否为导入相关
 	if task.Type != models.TaskTypeImport && task.Type != models.TaskTypeOnline && task.Type != models.TaskTypeOfflineImport {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Task type does not support import status query"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.T(requestLanguage(c), "Task type does not support import status query")})
 		return
 	}
 
 	// 仅当任务不在运行中时才使用缓存
-	if task.Status != string(models.TaskStatusRunning) {
+	if task.Status != string(models.TaskStatusRunning) && task.Status != string(models.TaskStatusWaiting) {
 		if cachedResponse, ok := h.getCachedImportStatus(taskID); ok {
 			log.Printf("[DEBUG] GetImportStatus - Using cached data, TaskID: %s", taskID)
-			c.JSON(http.StatusOK, models.APIResponse{
+			h.respond(c, http.StatusOK, models.APIResponse{
 				Success: true,
 				Message: "Import status retrieved (cached)",
 				Data:    cachedResponse,
@@ -696,7 +2270,39 @@ func (h *Handler) GetImportStatus(c *gin.Context) {
 							ComposeStatus: getString(appMap, "compose_status"),
 							OverallStatus: getString(appMap, "overall_status"),
 							ErrorMessage:  getString(appMap, "error_message"),
+
+							RuntimeStatus:  getString(appMap, "runtime_status"),
+							RuntimeMessage: getString(appMap, "runtime_message"),
+						}
+						if warnings, ok := appMap["warnings"].([]interface{}); ok {
+							for _, w := range warnings {
+								app.Warnings = append(app.Warnings, fmt.Sprint(w))
+							}
+						}
+						if findings, ok := appMap["security_findings"].([]interface{}); ok {
+							for _, f := range findings {
+								if fm, ok := f.(map[string]interface{}); ok {
+									app.SecurityFindings = append(app.SecurityFindings, models.SecurityFinding{
+										Service: getString(fm, "service"),
+										Kind:    getString(fm, "kind"),
+										Detail:  getString(fm, "detail"),
+									})
+								}
+							}
 						}
+						if mounts, ok := appMap["bind_mounts"].([]interface{}); ok {
+							for _, m := range mounts {
+								if mm, ok := m.(map[string]interface{}); ok {
+									app.BindMounts = append(app.BindMounts, models.BindMount{
+										Service:   getString(mm, "service"),
+										Source:    getString(mm, "source"),
+										Rewritten: getString(mm, "rewritten"),
+										RuleID:    getString(mm, "rule_id"),
+									})
+								}
+							}
+						}
+						app.Metrics = getMetrics(appMap, "metrics")
 						apps = append(apps, app)
 					}
 				}
@@ -710,7 +2316,13 @@ func (h *Handler) GetImportStatus(c *gin.Context) {
 					TotalApps:   getInt(summaryMap, "total_apps"),
 					SuccessApps: getInt(summaryMap, "success_apps"),
 					FailedApps:  getInt(summaryMap, "failed_apps"),
+					SkippedApps: getInt(summaryMap, "skipped_apps"),
+					WarningApps: getInt(summaryMap, "warning_apps"),
+					FlaggedApps: getInt(summaryMap, "flagged_apps"),
+					Metrics:     getMetrics(summaryMap, "metrics"),
 				}
+			} else if typed, ok := summaryData.(models.ImportSummary); ok {
+				summary = typed
 			}
 		}
 	}
@@ -729,11 +2341,11 @@ func (h *Handler) GetImportStatus(c *gin.Context) {
 	}
 
 	// 仅当任务不在运行中时才缓存结果
-	if task.Status != string(models.TaskStatusRunning) {
+	if task.Status != string(models.TaskStatusRunning) && task.Status != string(models.TaskStatusWaiting) {
 		h.cacheImportStatus(taskID, response)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Import status retrieved",
 		Data:    response,
@@ -773,6 +2385,34 @@ func getInt(m map[string]interface{}, key string) int {
 	return 0
 }
 
+// 辅助函数：安全地从map中获取64位整数值
+func getInt64(m map[string]interface{}, key string) int64 {
+	if val, ok := m[key]; ok {
+		if i, ok := val.(float64); ok {
+			return int64(i)
+		}
+		if i, ok := val.(int64); ok {
+			return i
+		}
+	}
+	return 0
+}
+
+// 辅助函数：从map中获取应用迁移指标，不存在时返回nil
+func getMetrics(m map[string]interface{}, key string) *models.AppMetrics {
+	mm, ok := m[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return &models.AppMetrics{
+		BytesTransferred: getInt64(mm, "bytes_transferred"),
+		CompressMs:       getInt64(mm, "compress_ms"),
+		UploadMs:         getInt64(mm, "upload_ms"),
+		DecompressMs:     getInt64(mm, "decompress_ms"),
+		ComposeImportMs:  getInt64(mm, "compose_import_ms"),
+	}
+}
+
 // 辅助函数：获取map的所有键
 func getMapKeys(m map[string]interface{}) []string {
 	keys := make([]string, 0, len(m))
@@ -788,7 +2428,7 @@ func (h *Handler) DownloadAppPackage(c *gin.Context) {
 	appName := c.Param("appName")
 
 	if taskID == "" || appName == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Task ID and app name are required",
 		})
@@ -798,7 +2438,7 @@ func (h *Handler) DownloadAppPackage(c *gin.Context) {
 	// 创建应用压缩包
 	packagePath, err := h.migrationService.CreateAppPackage(taskID, appName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create app package: %v", err),
 		})
@@ -807,7 +2447,7 @@ func (h *Handler) DownloadAppPackage(c *gin.Context) {
 
 	// 检查文件是否存在
 	if _, err := os.Stat(packagePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+		h.respond(c, http.StatusNotFound, models.APIResponse{
 			Success: false,
 			Message: "Package file not found",
 		})
@@ -831,15 +2471,48 @@ func (h *Handler) DownloadAppPackage(c *gin.Context) {
 	// }()
 }
 
+const (
+	// multipartMemory 解析上传表单时保存在内存中的最大字节数
+	multipartMemory = 32 << 20
+	// multipartOverhead 上传表单中文件以外的部分（边界、连接信息等）允许的大小
+	multipartOverhead = 1 << 20
+)
+
 // DataImportUpload 处理文件上传并启动数据导入
 func (h *Handler) DataImportUpload(c *gin.Context) {
 	log.Printf("[DEBUG] Received file upload import request")
 
-	// 解析multipart form
-	err := c.Request.ParseMultipartForm(500 << 20) // 500MB
+	// 可选的upload_id由客户端生成，上传和校验进度发送到该ID的临时WebSocket频道
+	uploadID := c.Query("upload_id")
+	if uploadID != "" {
+		if _, err := uuid.Parse(uploadID); err != nil {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid upload ID",
+			})
+			return
+		}
+		c.Request.Body = h.newUploadProgressReader(c.Request.Body, uploadID, 0, c.Request.ContentLength)
+		defer h.reportUploadFailure(c, uploadID)
+	}
+
+	// 超过上限的请求在读取前拒绝，分块传输的请求在读取时截断
+	if limit := h.uploadService.MaxSize(); limit > 0 {
+		if c.Request.ContentLength > limit+multipartOverhead {
+			h.respond(c, http.StatusRequestEntityTooLarge, models.APIResponse{
+				Success: false,
+				Message: h.uploadService.CheckSize(c.Request.ContentLength).Error(),
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+multipartOverhead)
+	}
+
+	// 解析multipart form，超过内存上限的部分写入临时文件
+	err := c.Request.ParseMultipartForm(multipartMemory)
 	if err != nil {
 		log.Printf("[ERROR] Failed to parse multipart form: %v", err)
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Failed to parse upload data: " + err.Error(),
 		})
@@ -850,7 +2523,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		log.Printf("[ERROR] Failed to get uploaded file: %v", err)
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Failed to get uploaded file: " + err.Error(),
 		})
@@ -861,20 +2534,19 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	log.Printf("[DEBUG] Uploaded file info: Filename=%s, Size=%d", header.Filename, header.Size)
 
 	// 验证文件类型
-	fileName := strings.ToLower(header.Filename)
-	if !strings.HasSuffix(fileName, ".tar.gz") && !strings.HasSuffix(fileName, ".zip") {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+	if !services.IsSupportedImportFile(header.Filename) {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Message: "Unsupported file format, please upload .tar.gz or .zip files",
+			Message: "Unsupported file format, please upload .tar.gz, .tar.xz, .tar, .zip or .7z files",
 		})
 		return
 	}
 
-	// 验证文件大小（500MB限制）
-	if header.Size > 500*1024*1024 {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+	// 验证文件大小
+	if err := h.uploadService.CheckSize(header.Size); err != nil {
+		h.respond(c, http.StatusRequestEntityTooLarge, models.APIResponse{
 			Success: false,
-			Message: "File size exceeds limit (500MB)",
+			Message: err.Error(),
 		})
 		return
 	}
@@ -882,7 +2554,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	// 获取目标连接信息
 	targetConnectionStr := c.Request.FormValue("target_connection")
 	if targetConnectionStr == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Missing target connection information",
 		})
@@ -892,7 +2564,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	var targetConnection models.SystemConnection
 	if err := json.Unmarshal([]byte(targetConnectionStr), &targetConnection); err != nil {
 		log.Printf("[ERROR] Failed to parse target connection information: %v", err)
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Failed to parse target connection information: " + err.Error(),
 		})
@@ -901,11 +2573,23 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 
 	log.Printf("[DEBUG] Target connection info: %s:%d", targetConnection.Host, targetConnection.Port)
 
+	// 可选的任务标签，JSON对象格式
+	var labels map[string]string
+	if labelsStr := c.Request.FormValue("labels"); labelsStr != "" {
+		if err := json.Unmarshal([]byte(labelsStr), &labels); err != nil {
+			h.respond(c, http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid labels: " + err.Error(),
+			})
+			return
+		}
+	}
+
 	// 创建临时目录保存上传的文件
-	uploadDir := "./uploads"
+	uploadDir := h.cfg.Dirs.Upload
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		log.Printf("[ERROR] Failed to create upload directory: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to create upload directory: " + err.Error(),
 		})
@@ -922,7 +2606,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	dstFile, err := os.Create(savedFilePath)
 	if err != nil {
 		log.Printf("[ERROR] Failed to create target file: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to save uploaded file: " + err.Error(),
 		})
@@ -930,12 +2614,13 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	}
 	defer dstFile.Close()
 
-	// 复制文件内容
-	copiedBytes, err := io.Copy(dstFile, file)
+	// 复制文件内容，同时计算SHA-256用于检测重复导入
+	hasher := sha256.New()
+	copiedBytes, err := io.Copy(io.MultiWriter(dstFile, hasher), file)
 	if err != nil {
 		log.Printf("[ERROR] Failed to copy file content: %v", err)
 		os.Remove(savedFilePath) // 清理失败的文件
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to save file content: " + err.Error(),
 		})
@@ -946,7 +2631,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	if err := dstFile.Sync(); err != nil {
 		log.Printf("[ERROR] Failed to flush file buffer: %v", err)
 		os.Remove(savedFilePath)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to save file: " + err.Error(),
 		})
@@ -961,7 +2646,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	if err != nil {
 		log.Printf("[ERROR] Failed to get saved file info: %v", err)
 		os.Remove(savedFilePath)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to verify saved file: " + err.Error(),
 		})
@@ -971,7 +2656,7 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	if savedFileInfo.Size() != copiedBytes || savedFileInfo.Size() != header.Size {
 		log.Printf("[ERROR] File size mismatch: Original=%d, Copied=%d, Saved=%d", header.Size, copiedBytes, savedFileInfo.Size())
 		os.Remove(savedFilePath)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "File save incomplete, please re-upload",
 		})
@@ -981,60 +2666,52 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	log.Printf("[DEBUG] File saved successfully: %s, Size verified: %d bytes", savedFilePath, savedFileInfo.Size())
 
 	// 验证上传的文件格式（根据文件内容而非扩展名）
-	actualFormat, err := detectFileFormat(savedFilePath)
-	if err != nil {
-		log.Printf("[ERROR] Failed to detect file format: %v", err)
-		os.Remove(savedFilePath) // 清理无效文件
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Message: "Failed to detect file format: " + err.Error(),
-		})
-		return
-	}
-
-	log.Printf("[DEBUG] Detected file format: %s", actualFormat)
-
-	// 验证文件格式是否支持
-	if actualFormat != "gzip" && actualFormat != "zip" {
-		log.Printf("[ERROR] Unsupported file format: %s", actualFormat)
+	h.sendUploadPhase(uploadID, websocket.UploadPhaseValidating, nil)
+	if err := validateImportArchive(savedFilePath); err != nil {
 		os.Remove(savedFilePath) // 清理无效文件
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Message: fmt.Sprintf("Unsupported file format: %s, please upload gzip or zip format files", actualFormat),
-		})
+		h.respondInvalidArchive(c, err)
 		return
 	}
 
-	log.Printf("[DEBUG] File format verified: %s", actualFormat)
-
-	// 如果是gzip文件，进行额外的完整性验证
-	if actualFormat == "gzip" {
-		if err := validateGzipFile(savedFilePath); err != nil {
-			log.Printf("[ERROR] gzip file validation failed: %v", err)
-			os.Remove(savedFilePath)
-			c.JSON(http.StatusBadRequest, models.APIResponse{
-				Success: false,
-				Message: "Uploaded gzip file is corrupted or incomplete: " + err.Error(),
-			})
-			return
-		}
-		log.Printf("[DEBUG] gzip file integrity verified")
-	}
-
 	// 创建数据导入请求
 	importRequest := &models.DataImportRequest{
 		Target: targetConnection,
 		ImportOptions: map[string]interface{}{
 			"import_file": savedFilePath,
 		},
+		Preset:   c.Request.FormValue("preset"),
+		Language: requestLanguage(c),
+		TaskMeta: models.TaskMeta{
+			Name:     c.Request.FormValue("name"),
+			Notes:    c.Request.FormValue("notes"),
+			Labels:   labels,
+			LogLevel: c.Request.FormValue("log_level"),
+		},
+	}
+	if allow, _ := strconv.ParseBool(c.Request.FormValue("allow_duplicate")); allow {
+		importRequest.ImportOptions["allow_duplicate"] = true
 	}
 
+	// 同一压缩包已导入到该目标时需要确认
+	if err := h.migrationService.CheckDuplicateImport(importRequest, hex.EncodeToString(hasher.Sum(nil))); err != nil {
+		os.Remove(savedFilePath)
+		h.respondDuplicateImport(c, err)
+		return
+	}
+
+	h.startUploadedImport(c, importRequest, savedFilePath, uploadID)
+}
+
+// startUploadedImport 以上传的文件启动数据导入任务，任务结束后删除上传的文件
+// uploadID不为空时在上传的临时频道通知创建的任务
+func (h *Handler) startUploadedImport(c *gin.Context, importRequest *models.DataImportRequest, savedFilePath, uploadID string) {
 	// 启动数据导入任务
+	h.sendUploadPhase(uploadID, websocket.UploadPhaseStarting, nil)
 	task, err := h.migrationService.StartDataImport(importRequest)
 	if err != nil {
 		log.Printf("[ERROR] Failed to start data import task: %v", err)
 		os.Remove(savedFilePath) // 清理上传的文件
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.respond(c, http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to start data import task: " + err.Error(),
 		})
@@ -1042,9 +2719,10 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Data import task created: %s", task.ID)
+	h.sendUploadPhase(uploadID, websocket.UploadPhaseCompleted, map[string]interface{}{"task_id": task.ID})
 
 	// 返回成功响应
-	c.JSON(http.StatusOK, models.APIResponse{
+	h.respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "File uploaded successfully, data import task started",
 		Data: map[string]interface{}{
@@ -1055,23 +2733,414 @@ func (h *Handler) DataImportUpload(c *gin.Context) {
 
 	// 异步清理上传的文件（任务完成后）
 	go func() {
-		// 等待任务完成或失败后清理文件
+		// 等待任务结束后清理文件
 		for {
 			time.Sleep(30 * time.Second)
 			currentTask, err := h.taskService.GetTask(task.ID)
 			if err != nil {
 				break
 			}
-			if currentTask.Status == string(models.TaskStatusCompleted) ||
-				currentTask.Status == string(models.TaskStatusFailed) {
-				os.Remove(savedFilePath)
-				log.Printf("[DEBUG] Cleaning up uploaded file: %s", savedFilePath)
+			if !models.IsTerminalStatus(currentTask.Status) {
+				continue
+			}
+			// 可恢复的中断任务还需要导入文件，交给定时清理在任务不再需要后删除
+			if currentTask.Resumable {
+				log.Printf("[DEBUG] Keeping uploaded file for resume: %s", savedFilePath)
 				break
 			}
+			os.Remove(savedFilePath)
+			log.Printf("[DEBUG] Cleaning up uploaded file: %s", savedFilePath)
+			break
 		}
 	}()
 }
 
+// tusVersion 支持的tus协议版本
+const tusVersion = "1.0.0"
+
+// checkTusResumable 设置tus响应头并检查请求的协议版本，不支持时返回412
+func checkTusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// parseTusMetadata 解析Upload-Metadata头，格式为逗号分隔的"键 base64值"
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Upload-Metadata value for %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// CreateUpload 创建断点续传上传（tus creation扩展），Upload-Metadata中的filename为文件名
+func (h *Handler) CreateUpload(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid Upload-Length header",
+		})
+		return
+	}
+	if err := h.uploadService.CheckSize(length); err != nil {
+		h.respond(c, http.StatusRequestEntityTooLarge, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	metadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !services.IsSupportedImportFile(metadata["filename"]) {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Unsupported file format, please upload .tar.gz, .tar.xz, .tar, .zip or .7z files",
+		})
+		return
+	}
+
+	upload, err := h.uploadService.CreateUpload(metadata["filename"], length, metadata)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+
+	log.Printf("[INFO] Upload %s created: %s (%d bytes)", upload.ID, upload.Filename, upload.Length)
+	c.Header("Location", "/api/uploads/"+upload.ID)
+	h.respond(c, http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Upload created",
+		Data:    upload,
+	})
+}
+
+// GetUploadOffset 返回上传已接收的字节数，客户端据此继续上传
+func (h *Handler) GetUploadOffset(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	upload, err := h.uploadService.GetUpload(c.Param("id"))
+	if err != nil {
+		if err == models.ErrUploadNotFound {
+			c.Status(http.StatusNotFound)
+		} else {
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Status(http.StatusOK)
+}
+
+// WriteUploadChunk 从Upload-Offset处追加上传数据
+func (h *Handler) WriteUploadChunk(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		h.respond(c, http.StatusUnsupportedMediaType, models.APIResponse{
+			Success: false,
+			Message: "Content-Type must be application/offset+octet-stream",
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid Upload-Offset header",
+		})
+		return
+	}
+
+	upload, err := h.uploadService.GetUpload(c.Param("id"))
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	body := h.newUploadProgressReader(c.Request.Body, upload.ID, offset, upload.Length)
+	newOffset, err := h.uploadService.WriteChunk(upload.ID, offset, body)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload 取消上传并删除已接收的数据（tus termination扩展）
+func (h *Handler) DeleteUpload(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	if err := h.uploadService.DeleteUpload(c.Param("id")); err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ImportUpload 以接收完成的上传启动数据导入，请求体与 /api/data-import 相同，import_file 由上传决定
+func (h *Handler) ImportUpload(c *gin.Context) {
+	var req models.DataImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	// 在移动上传的文件之前检查重复导入，确认后可以再次请求，不需要重新上传
+	checksum, err := h.uploadService.Checksum(c.Param("id"))
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	if err := h.migrationService.CheckDuplicateImport(&req, checksum); err != nil {
+		h.respondDuplicateImport(c, err)
+		return
+	}
+
+	savedFilePath, upload, err := h.uploadService.CompleteUpload(c.Param("id"))
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	log.Printf("[INFO] Upload %s completed: %s (%d bytes)", upload.ID, upload.Filename, upload.Length)
+	defer h.reportUploadFailure(c, upload.ID)
+
+	h.sendUploadPhase(upload.ID, websocket.UploadPhaseValidating, nil)
+	if err := validateImportArchive(savedFilePath); err != nil {
+		os.Remove(savedFilePath)
+		h.respondInvalidArchive(c, err)
+		return
+	}
+
+	if req.ImportOptions == nil {
+		req.ImportOptions = make(map[string]interface{})
+	}
+	req.ImportOptions["import_file"] = savedFilePath
+	req.S3 = nil
+	req.Language = requestLanguage(c)
+	h.startUploadedImport(c, &req, savedFilePath, upload.ID)
+}
+
+// uploadProgressInterval 发送上传接收进度的最小间隔
+const uploadProgressInterval = 500 * time.Millisecond
+
+// uploadProgressReader 统计接收的字节数，定期在上传的临时频道发送接收进度
+type uploadProgressReader struct {
+	io.ReadCloser
+	wsManager *websocket.Manager
+	uploadID  string
+	received  int64
+	total     int64
+	lastSent  time.Time
+}
+
+// newUploadProgressReader 包装请求体，offset为此前已接收的字节数，total为文件总大小（未知时为0）
+func (h *Handler) newUploadProgressReader(body io.ReadCloser, uploadID string, offset, total int64) io.ReadCloser {
+	if total < 0 {
+		total = 0
+	}
+	return &uploadProgressReader{
+		ReadCloser: body,
+		wsManager:  h.wsManager,
+		uploadID:   uploadID,
+		received:   offset,
+		total:      total,
+	}
+}
+
+func (r *uploadProgressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.received += int64(n)
+	if err == io.EOF || time.Since(r.lastSent) >= uploadProgressInterval {
+		r.lastSent = time.Now()
+		r.wsManager.SendUploadProgress(r.uploadID, r.received, r.total)
+	}
+	return n, err
+}
+
+// sendUploadPhase 在上传的临时频道发送处理阶段，uploadID为空时不发送
+func (h *Handler) sendUploadPhase(uploadID, phase string, data map[string]interface{}) {
+	if uploadID == "" {
+		return
+	}
+	h.wsManager.SendUploadPhase(uploadID, phase, data)
+}
+
+// reportUploadFailure 请求以错误结束时在上传的临时频道通知失败，需在返回响应后调用（defer）
+func (h *Handler) reportUploadFailure(c *gin.Context, uploadID string) {
+	if c.Writer.Status() >= http.StatusBadRequest {
+		h.sendUploadPhase(uploadID, websocket.UploadPhaseFailed, map[string]interface{}{"status": c.Writer.Status()})
+	}
+}
+
+// respondUploadError 按上传错误类型返回对应的状态码
+func (h *Handler) respondUploadError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	switch err {
+	case models.ErrUploadNotFound:
+		status = http.StatusNotFound
+		message = "Upload not found"
+	case models.ErrUploadOffsetMismatch:
+		status = http.StatusConflict
+		message = "Upload offset does not match"
+	case models.ErrUploadBusy:
+		status = http.StatusLocked
+		message = "Upload is being written by another request"
+	case models.ErrUploadTooLarge:
+		status = http.StatusRequestEntityTooLarge
+		message = "Upload exceeds its declared length"
+	case models.ErrUploadIncomplete:
+		status = http.StatusConflict
+		message = "Upload is not complete"
+	}
+	h.respond(c, status, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// validateImportArchive 根据文件内容检查上传的导入文件是否为完整的gzip、xz、tar、zip或7z文件，以及目录结构能否导入
+func validateImportArchive(savedFilePath string) error {
+	actualFormat, err := detectFileFormat(savedFilePath)
+	if err != nil {
+		log.Printf("[ERROR] Failed to detect file format: %v", err)
+		return fmt.Errorf("Failed to detect file format: %v", err)
+	}
+
+	log.Printf("[DEBUG] Detected file format: %s", actualFormat)
+
+	// 验证文件格式是否支持
+	if actualFormat == "unknown" {
+		log.Printf("[ERROR] Unsupported file format: %s", actualFormat)
+		return fmt.Errorf("Unsupported file format: %s, please upload gzip, xz, tar, zip or 7z format files", actualFormat)
+	}
+
+	log.Printf("[DEBUG] File format verified: %s", actualFormat)
+
+	// 按格式进行额外的完整性验证
+	switch actualFormat {
+	case "gzip":
+		if err := validateGzipFile(savedFilePath); err != nil {
+			log.Printf("[ERROR] gzip file validation failed: %v", err)
+			return fmt.Errorf("Uploaded gzip file is corrupted or incomplete: %v", err)
+		}
+		log.Printf("[DEBUG] gzip file integrity verified")
+		// 单个压缩文件（如 .sql.gz）也是gzip格式，但不能作为导入包
+		if err := services.CheckTarStream(savedFilePath, actualFormat); err != nil {
+			log.Printf("[ERROR] gzip file is not a tar archive: %v", err)
+			return err
+		}
+	case "xz":
+		if err := validateXzFile(savedFilePath); err != nil {
+			log.Printf("[ERROR] xz file validation failed: %v", err)
+			return fmt.Errorf("Uploaded xz file is corrupted or incomplete: %v", err)
+		}
+		log.Printf("[DEBUG] xz file header verified")
+		if err := services.CheckTarStream(savedFilePath, actualFormat); err != nil {
+			log.Printf("[ERROR] xz file is not a tar archive: %v", err)
+			return err
+		}
+	case "zip":
+		if err := services.CheckZipArchive(savedFilePath); err != nil {
+			log.Printf("[ERROR] ZIP file validation failed: %v", err)
+			return fmt.Errorf("Uploaded ZIP file is corrupted or incomplete: %v", err)
+		}
+		log.Printf("[DEBUG] ZIP central directory verified")
+	case "tar":
+		if err := validateTarFile(savedFilePath); err != nil {
+			log.Printf("[ERROR] tar file validation failed: %v", err)
+			return fmt.Errorf("Uploaded tar file is corrupted or incomplete: %v", err)
+		}
+		log.Printf("[DEBUG] tar file header verified")
+	case "7z":
+		if err := validateSevenZipFile(savedFilePath); err != nil {
+			log.Printf("[ERROR] 7z file validation failed: %v", err)
+			return fmt.Errorf("Uploaded 7z file is corrupted or incomplete: %v", err)
+		}
+		log.Printf("[DEBUG] 7z file header verified")
+	}
+
+	// 检查目录结构，缺少应用定义的压缩包在创建任务前拒绝
+	layout, err := services.InspectImportLayout(savedFilePath)
+	if err != nil {
+		log.Printf("[ERROR] Import archive layout is invalid: %v", err)
+		return err
+	}
+	log.Printf("[DEBUG] Import archive layout verified: apps=%v, appdata=%v, synology=%v, incremental=%v", layout.HasApps, layout.HasAppData, layout.Synology, layout.Incremental)
+	return nil
+}
+
+// respondDuplicateImport 压缩包已导入过同一目标时返回409和之前的导入任务
+func (h *Handler) respondDuplicateImport(c *gin.Context, err error) {
+	var dupErr *models.DuplicateImportError
+	if errors.As(err, &dupErr) {
+		h.respond(c, http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+			Data:    dupErr.Duplicate,
+		})
+		return
+	}
+	h.respond(c, http.StatusBadRequest, models.APIResponse{
+		Success: false,
+		Message: err.Error(),
+	})
+}
+
+// respondInvalidArchive 返回上传的导入文件校验失败，目录结构无效时附带找到的结构
+func (h *Handler) respondInvalidArchive(c *gin.Context, err error) {
+	var layoutErr *models.ArchiveLayoutError
+	if errors.As(err, &layoutErr) {
+		h.respond(c, http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+			Data:    layoutErr.Layout,
+		})
+		return
+	}
+	h.respond(c, http.StatusBadRequest, models.APIResponse{
+		Success: false,
+		Message: err.Error(),
+	})
+}
+
 // detectFileFormat 根据文件魔数检测文件格式
 func detectFileFormat(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -1080,27 +3149,83 @@ func detectFileFormat(filePath string) (string, error) {
 	}
 	defer file.Close()
 
-	// 读取文件前10个字节用于格式检测
-	buf := make([]byte, 10)
-	n, err := file.Read(buf)
-	if err != nil {
+	// 读取文件头用于格式检测，tar的标识位于第257字节
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
 		return "", fmt.Errorf("Failed to read file header: %v", err)
 	}
 
-	log.Printf("[DEBUG] File first %d bytes: %v", n, buf[:n])
+	head := buf[:n]
+	if len(head) > 10 {
+		head = head[:10]
+	}
+	log.Printf("[DEBUG] File first %d bytes: %v", len(head), head)
+
+	return services.DetectArchiveFormat(buf[:n]), nil
+}
+
+// validateXzFile 验证xz流头部：魔数、标志和标志的CRC32
+// 完整解压需要读取整个文件，这里只检查头部，数据错误在解压时报告
+func validateXzFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return fmt.Errorf("File too small, not a valid xz file")
+	}
+	if crc32.ChecksumIEEE(header[6:8]) != binary.LittleEndian.Uint32(header[8:12]) {
+		return fmt.Errorf("Invalid xz stream header checksum")
+	}
+	return nil
+}
+
+// validateSevenZipFile 验证7z签名头：起始头的CRC32，以及起始头指向的结尾头是否在文件范围内
+// 上传不完整时结尾头缺失，这里可以提前发现
+func validateSevenZipFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to get file info: %v", err)
+	}
 
-	// 检测ZIP格式 (PK signature: 0x504B)
-	if n >= 2 && buf[0] == 0x50 && buf[1] == 0x4B {
-		return "zip", nil
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return fmt.Errorf("File too small, not a valid 7z file")
+	}
+	if crc32.ChecksumIEEE(header[12:32]) != binary.LittleEndian.Uint32(header[8:12]) {
+		return fmt.Errorf("Invalid 7z start header checksum")
+	}
+	nextHeaderOffset := binary.LittleEndian.Uint64(header[12:20])
+	nextHeaderSize := binary.LittleEndian.Uint64(header[20:28])
+	size := uint64(fileInfo.Size())
+	if nextHeaderOffset > size || nextHeaderSize > size || 32+nextHeaderOffset+nextHeaderSize > size {
+		return fmt.Errorf("File is truncated: 7z header is beyond the end of the %d byte file", size)
 	}
+	return nil
+}
 
-	// 检测GZIP格式 (magic number: 0x1F8B)
-	if n >= 2 && buf[0] == 0x1F && buf[1] == 0x8B {
-		return "gzip", nil
+// validateTarFile 验证文件是否为可读取的tar文件
+func validateTarFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %v", err)
 	}
+	defer file.Close()
 
-	// 如果都不匹配，返回未知格式
-	return "unknown", nil
+	if _, err := tar.NewReader(file).Next(); err != nil {
+		return fmt.Errorf("Failed to read tar entry: %v", err)
+	}
+	return nil
 }
 
 // validateGzipFile 验证文件是否为有效的gzip格式
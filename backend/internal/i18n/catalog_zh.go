@@ -0,0 +1,555 @@
+package i18n
+
+// zhMessages 中文消息目录
+var zhMessages = map[string]string{
+	// 任务状态与步骤
+	"Task started":                     "任务已开始",
+	"Task completed":                   "任务已完成",
+	"Task failed":                      "任务失败",
+	"Step started":                     "步骤开始",
+	"Step completed":                   "步骤完成",
+	"Step failed":                      "步骤失败",
+	"Step started: %s":                 "步骤开始: %s",
+	"Step completed: %s":               "步骤完成: %s",
+	"Step failed: %s - %v":             "步骤失败: %s - %v",
+	"Test source system connection":    "测试源系统连接",
+	"Test target system connection":    "测试目标系统连接",
+	"Download and process source data": "下载并处理源数据",
+	"Scan app configuration":           "扫描应用配置",
+	"Import application configuration": "导入应用配置",
+	"Start imported apps":              "启动导入的应用",
+	"Merge AppData directory":          "合并AppData目录",
+	"Cleanup local temporary files":    "清理本地临时文件",
+	"Export system data":               "导出系统数据",
+	"Upload export file":               "推送导出文件",
+	"Download import file":             "下载导入文件",
+	"Parse import file":                "解析导入文件",
+
+	// 任务日志
+	"Online migration completed":                                                                      "在线迁移完成",
+	"Offline import completed":                                                                        "离线导入完成",
+	"Data export completed":                                                                           "数据导出完成",
+	"Export file uploaded to %s":                                                                      "导出文件已推送到 %s",
+	"Downloading import file from %s":                                                                 "正在从 %s 下载导入文件",
+	"Layering %d incremental exports over base %s":                                                    "正在将 %d 个增量导出叠加到基础导出 %s 上",
+	"Incremental backup: %d of %d apps changed since %s":                                              "增量备份: 自 %[3]s 以来 %[1]d/%[2]d 个应用有变化",
+	"Full backup of %d apps":                                                                          "完整备份 %d 个应用",
+	"Export filter: %d app files and %d data files included, %d files excluded":                       "导出过滤: 包含 %d 个应用文件和 %d 个数据文件，排除 %d 个文件",
+	"Critical error occurred during online migration; task failed":                                    "在线迁移过程中发生严重错误，任务失败",
+	"Critical error occurred during offline import; task failed":                                      "离线导入过程中发生严重错误，任务失败",
+	"Critical error occurred during data export; task failed":                                         "数据导出过程中发生严重错误，任务失败",
+	"Migration panic: %v":                                                                             "迁移发生异常: %v",
+	"Panic occurred during export: %v":                                                                "导出过程中发生异常: %v",
+	"Panic occurred during import: %v":                                                                "导入过程中发生异常: %v",
+	"Stack trace:\n%s":                                                                                "堆栈跟踪：\n%s",
+	"Failed to encode %s: %v":                                                                         "无法编码 %s: %v",
+	"Failed to create diagnostics archive: %v":                                                        "无法创建诊断包: %v",
+	"Not enough free space on the target for %s: %s required, %s available":                           "目标上 %s 的可用空间不足：需要 %s，可用 %s",
+	"Transferring container images requires ssh_port on the source connection, skipping":              "迁移容器镜像需要在源连接上配置ssh_port，已跳过",
+	"Could not list source containers, images were not saved: %v":                                     "无法列出源系统上的容器，未保存镜像：%v",
+	"App %s: failed to save container images, the target will pull them: %v":                          "应用 %s：保存容器镜像失败，目标将从镜像仓库拉取：%v",
+	"App %s: saved %d container images":                                                               "应用 %s：已保存 %d 个容器镜像",
+	"Load container images":                                                                           "加载容器镜像",
+	"Loading container images of %s (%d/%d)...":                                                       "正在加载 %s 的容器镜像 (%d/%d)...",
+	"Container images could not be loaded on the target, they will be pulled from the registry: %v":   "无法在目标上加载容器镜像，将从镜像仓库拉取：%v",
+	"App %s: failed to load container images: %v":                                                     "应用 %s：加载容器镜像失败：%v",
+	"App %s: container images loaded ✓":                                                               "应用 %s：容器镜像已加载 ✓",
+	"Container images loaded":                                                                         "容器镜像已加载",
+	"Failed to load container images: %v":                                                             "加载容器镜像失败：%v",
+	"Failed to create images directory: %v":                                                           "创建镜像目录失败: %v",
+	"Failed to move container images of app %s: %v":                                                   "移动应用 %s 的容器镜像失败: %v",
+	"Failed to open image archive: %v":                                                                "打开镜像包失败: %v",
+	"Failed to remove container images of app %s: %v":                                                 "删除应用 %s 的容器镜像失败: %v",
+	"Prefix rules need a replacement prefix":                                                          "前缀规则需要替换前缀",
+	"Invalid rule type: %s (expected %s, %s or %s)":                                                   "无效的规则类型：%s（应为 %s、%s 或 %s）",
+	"Pinning images to digests requires ssh_port on the source connection, images will not be pinned": "将镜像固定到摘要需要源连接设置ssh_port，镜像不会被固定",
+	"Could not read source image digests, images will not be pinned: %v":                              "无法读取源镜像摘要，镜像不会被固定: %v",
+	"Read digests of %d source images":                                                                "已读取 %d 个源镜像的摘要",
+	"App %s: service %s image %s was not pinned, no digest was read from the source":                  "应用 %s：服务 %s 的镜像 %s 未固定，未从源读取到摘要",
+	"App %s: service %s image %s rewritten to %s":                                                     "应用 %s：服务 %s 的镜像 %s 已改写为 %s",
+	"App %s: failed to apply image rules: %v":                                                         "应用 %s：应用镜像规则失败: %v",
+	"Image rules retrieved successfully":                                                              "镜像规则获取成功",
+	"Image rule retrieved successfully":                                                               "镜像规则获取成功",
+	"Image rule created":                                                                              "镜像规则已创建",
+	"Image rule updated":                                                                              "镜像规则已更新",
+	"Image rule deleted":                                                                              "镜像规则已删除",
+	"Image rule not found":                                                                            "镜像规则不存在",
+	"Failed to fetch app list: %v":                                                                    "获取应用列表失败: %v",
+	"Failed to scan app configuration: %v, continuing with next steps":                                "扫描应用配置失败: %v，继续执行后续步骤",
+	"Failed to import application configuration: %v, continuing with next steps":                      "导入应用配置失败: %v，继续执行后续步骤",
+	"Failed to merge AppData directory: %v, continuing with next steps":                               "合并AppData目录失败: %v，继续执行后续步骤",
+	"Cleanup local temporary files failed: %v":                                                        "清理本地临时文件失败: %v",
+	"Start importing app: %s":                                                                         "开始导入应用: %s",
+	"App %s compose import succeeded ✓":                                                               "应用 %s compose导入成功 ✓",
+	"App %s compose import failed: %v":                                                                "应用 %s compose导入失败: %v",
+	"App %s failed to start: %v":                                                                      "应用 %s 启动失败: %v",
+	"Could not list source apps, apps were not stopped: %v":                                           "无法列出源系统应用，未停止任何应用: %v",
+	"App %s is not a compose app and was not stopped":                                                 "应用 %s 不是compose应用，未停止",
+	"Failed to stop app %s on source: %v":                                                             "停止源系统应用 %s 失败: %v",
+	"App %s stopped on source":                                                                        "已停止源系统应用 %s",
+	"Failed to restart app %s on source: %v":                                                          "重新启动源系统应用 %s 失败: %v",
+	"App %s restarted on source":                                                                      "已重新启动源系统应用 %s",
+	"App %s: compose normalized: %s":                                                                  "应用 %s: compose已规范化: %s",
+	"Invalid resolve_ip: %s":                                                                          "无效的resolve_ip：%s",
+	"Invalid IPv6 address: %s":                                                                        "无效的IPv6地址：%s",
+	"Invalid username: must not start with '-' or contain spaces or control characters":               "无效的用户名：不能以“-”开头，也不能包含空白或控制字符",
+	"Invalid host: must not start with '-' or contain spaces or control characters":                   "无效的主机地址：不能以“-”开头，也不能包含空白或控制字符",
+	"Invalid key_file %s: must be a file in the SSH key directory":                                    "无效的key_file %s：必须是SSH密钥目录中的文件",
+	"SSH key directory is not available: %v":                                                          "SSH密钥目录不可用: %v",
+	"Invalid proxy URL: %s":                                                                           "无效的代理地址：%s",
+	"Unsupported proxy scheme: %s (expected http, https or socks5)":                                   "不支持的代理协议：%s（应为 http、https 或 socks5）",
+	"Target unavailable, operation skipped":                                                           "目标不可用，已跳过操作",
+	"Target unavailable after %d consecutive failures; pausing until it recovers (checking every %s)": "目标连续失败 %d 次后不可用，任务暂停直到目标恢复（每 %s 检查一次）",
+	"Checking target availability now":                                                                "正在检查目标是否可用",
+	"Target available again after %s, continuing":                                                     "目标已在 %s 后恢复，继续执行",
+	"Target still unavailable after %s; remaining operations will fail":                               "目标在 %s 后仍不可用，剩余操作将失败",
+	"Target unavailable, waiting for it to recover":                                                   "目标不可用，等待恢复",
+	"Only interrupted or waiting tasks can be resumed":                                                "只能恢复已中断或正在等待的任务",
+	"Request to %s failed (%s), retrying in %s (attempt %d of %d)":                                    "请求 %s 失败（%s），%s 后重试（第 %d 次，共 %d 次）",
+	"Request timed out after %s: %v":                                                                  "请求在 %s 后超时：%v",
+	"Task cancelled":                                                                                  "任务已取消",
+	"Server is shutting down":                                                                         "服务正在关闭",
+	"Cancelling task":                                                                                 "正在取消任务",
+	"Task interrupted by server shutdown":                                                             "任务因服务关闭而中断",
+	"Task interrupted by server shutdown; it can be resumed":                                          "任务因服务关闭而中断，可以恢复",
+	"Only running tasks can be cancelled":                                                             "只能取消运行中的任务",
+	"Task cancellation requested":                                                                     "已请求取消任务",
+	"Failed to rewind request body for retry: %v":                                                     "重试时无法重新读取请求体：%v",
+	"Failed to create archive directory: %v":                                                          "创建归档目录失败：%v",
+	"Source archive kept at %s":                                                                       "源系统备份已保留在 %s",
+	"Failed to keep source archive, leaving it at %s: %v":                                             "保留源系统备份失败，文件仍位于 %s：%v",
+	"App %s: service %s bind mount %s rewritten to %s":                                                "应用 %s：服务 %s 的绑定挂载 %s 已改写为 %s",
+	"App %s: failed to apply path rules: %v":                                                          "应用 %s：应用路径改写规则失败：%v",
+	"Prefix rules must map an absolute path to an absolute path":                                      "前缀规则必须将绝对路径映射为绝对路径",
+	"Invalid regular expression: %v":                                                                  "无效的正则表达式：%v",
+	"Invalid rule type: %s (expected %s or %s)":                                                       "无效的规则类型：%s（应为 %s 或 %s）",
+	"Failed to read task result: %v":                                                                  "读取任务结果失败：%v",
+	"Path rules retrieved successfully":                                                               "路径改写规则获取成功",
+	"Path rule retrieved successfully":                                                                "路径改写规则获取成功",
+	"Path rule created":                                                                               "路径改写规则已创建",
+	"Path rule updated":                                                                               "路径改写规则已更新",
+	"Path rule deleted":                                                                               "路径改写规则已删除",
+	"Path rule not found":                                                                             "路径改写规则不存在",
+	"Bind mounts retrieved successfully":                                                              "绑定挂载获取成功",
+	"Task steps retrieved successfully":                                                               "任务步骤获取成功",
+	"Auth status retrieved successfully":                                                              "登录状态获取成功",
+	"Authentication required":                                                                         "需要登录",
+	"Server is in read-only mode":                                                                     "服务器处于只读模式",
+	"Signed in successfully":                                                                          "登录成功",
+	"Signed out successfully":                                                                         "已退出登录",
+	"Login provider is not enabled":                                                                   "未启用该登录方式",
+	"Invalid username or password":                                                                    "用户名或密码错误",
+	"Login request expired or unknown, please sign in again":                                          "登录请求已过期或无效，请重新登录",
+	"User is not a member of an allowed group":                                                        "用户不属于允许登录的用户组",
+	"Sessions retrieved successfully":                                                                 "已获取登录会话",
+	"Session revoked":                                                                                 "会话已撤销",
+	"Session not found":                                                                               "会话不存在",
+	"Revoked %d sessions":                                                                             "已撤销 %d 个会话",
+	"App uses %s. Review its compose file and set %s to migrate it.":                                  "应用使用了 %s。请检查其compose文件，并设置 %s 以迁移该应用。",
+	"Security report: %d apps use privileged or host-level access: %s":                                "安全报告: %d 个应用使用了特权或主机级访问: %s",
+	"Migrating flagged apps because %s is set":                                                        "已设置 %s，迁移被标记的应用",
+	"Flagged apps are not migrated until %s is set":                                                   "设置 %s 之前不会迁移被标记的应用",
+	"App %s: could not verify devices on the target (%s): %v":                                         "应用 %s: 无法在目标上确认设备 (%s): %v",
+	"Service %s requests an NVIDIA GPU, but no NVIDIA driver was found on the target. Install the GPU driver or remove the GPU reservation, then import the app again.": "服务 %s 需要NVIDIA GPU，但目标上未找到NVIDIA驱动。请安装GPU驱动或移除GPU预留后重新导入应用。",
+	"Device %s used by service %s does not exist on the target. Attach the hardware or remove the mapping from the compose file, then import the app again.":            "服务 %[2]s 使用的设备 %[1]s 在目标上不存在。请连接硬件或从compose文件中移除该映射后重新导入应用。",
+	"App %s: required devices found on target: %s": "应用 %s: 目标上已找到所需设备: %s",
+	"App %s: %s": "应用 %s: %s",
+	"App %s: importing despite missing devices (%s is set)":                   "应用 %s: 已设置 %s，缺少设备仍继续导入",
+	"App %s: compose not imported because required devices are missing":       "应用 %s: 缺少所需设备，未导入compose",
+	"App %s: uploaded %d config files: %s":                                    "应用 %s: 已上传 %d 个配置文件: %s",
+	"App %s: failed to upload config files: %v":                               "应用 %s: 上传配置文件失败: %v",
+	"Failed to upload files to %s: %v":                                        "上传文件到 %s 失败: %v",
+	"Failed to copy app directory: %v":                                        "复制应用目录失败: %v",
+	"App %s: .env file uploaded with %d variables":                            "应用 %s: 已上传.env文件，包含 %d 个变量",
+	"App %s: failed to upload .env file, interpolating instead: %v":           "应用 %s: 上传.env文件失败，改为插值: %v",
+	"App %s: interpolated %d variables from .env file":                        "应用 %s: 已从.env文件插值 %d 个变量",
+	"App %s: compose references undefined variables: %s":                      "应用 %s: compose引用了未定义的变量: %s",
+	"Database dumps require ssh_port on the source connection, skipping":      "数据库导出需要源连接配置ssh_port，已跳过",
+	"Could not list source containers, databases were not dumped: %v":         "无法列出源系统容器，未导出数据库: %v",
+	"App %s: failed to dump %s database in service %s, copying raw files: %v": "应用 %s: %s 数据库（服务 %s）导出失败，将复制原始文件: %v",
+	"App %s: %s data in service %s flushed to disk":                           "应用 %s: %s 数据（服务 %s）已写入磁盘",
+	"App %s: %s database in service %s dumped":                                "应用 %s: 已导出 %s 数据库（服务 %s）",
+	"Restore database dumps":                                                  "恢复数据库转储",
+	"Restoring databases of %s (%d/%d)...":                                    "正在恢复 %s 的数据库 (%d/%d)...",
+	"Database restore completed":                                              "数据库恢复完成",
+	"App %s: database restore failed, dumps are kept in %s: %v":               "应用 %s: 数据库恢复失败，转储文件保留在 %s 中: %v",
+	"Failed to restore database dumps: %v":                                    "恢复数据库转储失败: %v",
+	"App %s: %s database in service %s restored ✓":                            "应用 %s: %s 数据库（服务 %s）已恢复 ✓",
+	"Database restore failed: %v":                                             "数据库恢复失败: %v",
+	"Failed to start app: %v":                                                 "启动应用失败: %v",
+	"Failed to restore %s database in service %s: %v":                         "恢复 %s 数据库（服务 %s）失败: %v",
+	"%s database in service %s not ready after %s: %v":                        "%s 数据库（服务 %s）在 %s 后仍未就绪: %v",
+	"ssh_port is not configured for the target":                               "目标连接未配置ssh_port",
+	"App %s is %s ✓":                                                                 "应用 %s 状态为 %s ✓",
+	"Failed to start imported apps: %v":                                              "启动导入的应用失败: %v",
+	"App %s AppData merge succeeded ✓":                                               "应用 %s AppData合并成功 ✓",
+	"App %s AppData already migrated, skipping":                                      "应用 %s 的AppData已迁移，跳过",
+	"App %s compose already imported, skipping":                                      "应用 %s 的compose已导入，跳过",
+	"Reusing source data from %s":                                                    "复用已下载的源数据: %s",
+	"Resuming interrupted task":                                                      "正在恢复中断的任务",
+	"Task interrupted by server restart":                                             "任务因服务重启而中断",
+	"Task interrupted by server restart; it can be resumed":                          "任务因服务重启而中断，可以恢复执行",
+	"App %s AppData merge failed: %v":                                                "应用 %s AppData合并失败: %v",
+	"App %s data merge succeeded ✓ (%d/%d)":                                          "应用 %s 数据合并成功 ✓ (%d/%d)",
+	"App %s data upload failed: %v":                                                  "应用 %s 数据上传失败: %v",
+	"App %s skipped: %s":                                                             "应用 %s 已跳过: %s",
+	"App %s: Sending import request...":                                              "应用 %s: 正在发送导入请求...",
+	"App %s: Import succeeded ✓":                                                     "应用 %s: 导入成功 ✓",
+	"App %s: Compose written to %s ✓":                                                "应用 %s: Compose已写入 %s ✓",
+	"App %s: AppData synced to %s":                                                   "应用 %s: AppData已同步到 %s",
+	"App %s: restored ownership of %d files":                                         "应用 %s: 已恢复 %d 个文件的属主",
+	"App %s: %v":                                                                     "应用 %s: %v",
+	"App %s: Import failed (status code: %d): %s":                                    "应用 %s: 导入失败（状态码: %d）: %s",
+	"ZimaOS %s detected, using %s file API":                                          "检测到 ZimaOS %s，使用 %s 文件接口",
+	"ZimaOS version unknown, using %s file API":                                      "ZimaOS 版本未知，使用 %s 文件接口",
+	"ZimaOS %s has no decompression API, configure ssh_port to extract over SSH":     "ZimaOS %s 没有解压接口，请配置 ssh_port 以通过SSH解压",
+	"Decompression over SSH failed: %v %s":                                           "通过SSH解压失败: %v %s",
+	"App %s: file ownership not restored (no ssh_port configured), see %s/%s":        "应用 %s: 未恢复文件属主（未配置ssh_port），参见 %s/%s",
+	"Data directory for app %s already exists, skipping merge ⚠️":                    "应用 %s 的数据目录已存在，跳过合并 ⚠️",
+	"Failed to check app %s data directory: %v":                                      "检查应用 %s 数据目录失败: %v",
+	"Found %d application data directories, starting merge":                          "发现 %d 个应用数据目录，开始合并",
+	"Runtipi app %s converted":                                                       "Runtipi应用 %s 已转换",
+	"Runtipi app %s skipped: %v":                                                     "Runtipi应用 %s 已跳过: %v",
+	"TrueNAS app %s converted":                                                       "TrueNAS应用 %s 已转换",
+	"TrueNAS apps found at %s (%s)":                                                  "在 %s 发现TrueNAS应用 (%s)",
+	"Synology project %s converted":                                                  "Synology项目 %s 已转换",
+	"Synology project %s skipped: %v":                                                "Synology项目 %s 已跳过: %v",
+	"App %s will be imported as %s":                                                  "应用 %s 将以 %s 的名称导入",
+	"App %s in %s was not found":                                                     "%[2]s 中的应用 %[1]s 未找到",
+	"Apps were already renamed in the extracted data":                                "解压数据中的应用已重命名",
+	"App %s: failed to update compose file for new name %s: %v":                      "应用 %s: 更新compose文件为新名称 %s 失败: %v",
+	"Failed to update ownership manifest for renamed apps: %v":                       "更新重命名应用的属主清单失败: %v",
+	"Apps %s and %s would both be renamed to %s":                                     "应用 %s 和 %s 将被重命名为同一名称 %s",
+	"Cannot rename app %s to %s: an app with that name is already in the import":     "无法将应用 %s 重命名为 %s: 导入中已有同名应用",
+	"Project %s mounts shared folder %s; make sure its contents exist on the target": "项目 %s 挂载了共享文件夹 %s，请确认目标系统上存在其内容",
+
+	// 进度消息
+	"All application compose imports completed":         "所有应用compose导入完成",
+	"AppData directory is empty, skipping merge":        "AppData目录为空，跳过合并",
+	"AppData directory merge completed":                 "AppData目录合并完成",
+	"AppData directory not found, skipping merge":       "未找到AppData目录，跳过合并",
+	"Cleaning up local temporary files...":              "正在清理本地临时文件...",
+	"Cleanup completed":                                 "清理完成",
+	"Converting Runtipi apps":                           "正在转换Runtipi应用",
+	"Converting Synology Container Manager projects...": "正在转换Synology Container Manager项目...",
+	"Converting TrueNAS apps":                           "正在转换TrueNAS应用",
+	"Data acquisition completed":                        "数据获取完成",
+	"Download succeeded":                                "下载成功",
+	"Download completed":                                "下载完成",
+	"Downloading file":                                  "正在下载文件",
+	"Export application data":                           "导出应用数据",
+	"Export system settings":                            "导出系统设置",
+	"Export user data":                                  "导出用户数据",
+	"Export file uploaded":                              "导出文件推送完成",
+	"Extract import file...":                            "解压导入文件...",
+	"Extracting":                                        "正在解压",
+	"Extracting file":                                   "正在解压文件",
+	"Extraction completed":                              "解压完成",
+	"Extraction succeeded":                              "解压成功",
+	"Fetching app list":                                 "正在获取应用列表",
+	"Fetching system settings":                          "正在获取系统设置",
+	"Fetching user data":                                "正在获取用户数据",
+	"Generate export file":                              "生成导出文件",
+	"Uploading export file to %s":                       "正在推送导出文件到 %s",
+	"Import file parsing completed":                     "导入文件解析完成",
+	"Initializing application status...":                "正在初始化应用状态...",
+	"No application configuration files found":          "未找到应用配置文件",
+	"Parsing CasaOS structure...":                       "正在解析CasaOS结构...",
+	"Scanning app configuration...":                     "正在扫描应用配置...",
+	"Start download":                                    "开始下载",
+	"Start downloading":                                 "开始下载",
+	"Start merging AppData directory...":                "开始合并AppData目录...",
+	"Start parsing import file...":                      "开始解析导入文件...",
+	"Starting to extract file":                          "开始解压文件",
+	"Syncing Runtipi apps":                              "正在同步Runtipi应用",
+	"Syncing TrueNAS apps":                              "正在同步TrueNAS应用",
+	"Converting: %s":                                    "正在转换: %s",
+	"Download completed, file size: %d bytes":           "下载完成，文件大小: %d 字节",
+	"Downloading source data using the %s method":       "使用 %s 方式下载源数据",
+	"Download method %s failed: %v":                     "下载方式 %s 失败: %v",
+	"All download methods failed: %s":                   "所有下载方式均失败: %s",
+	"Downloaded %d files (%d bytes)":                    "已下载 %d 个文件（%d 字节）",
+	"Download completed: %d files, %d bytes":            "下载完成: %d 个文件，%d 字节",
+	"No imported apps to start":                         "没有需要启动的已导入应用",
+	"Starting %s (%d/%d)...":                            "正在启动 %s (%d/%d)...",
+	"Imported apps started":                             "已启动导入的应用",
+	"Failed to query decompression task %s: %v":         "查询解压任务 %s 失败: %v",
+	"Decompression task %s %s: %s":                      "解压任务 %s %s: %s",
+	"Timed out waiting for decompression task %s":       "等待解压任务 %s 超时",
+	"Syncing %s over SSH...":                            "正在通过SSH同步 %s...",
+	"Downloading: %d bytes":                             "正在下载: %d 字节",
+	"Downloading: %d/%d bytes (%d%%)":                   "正在下载: %d/%d 字节 (%d%%)",
+	"Extracting: %s":                                    "正在解压: %s",
+	"Extracting %s (%d/%d)...":                          "正在解压 %s (%d/%d)...",
+	"Found %d apps":                                     "发现 %d 个应用",
+	"Found %d storage volumes":                          "发现 %d 个存储卷",
+	"Import %s compose configuration (%d/%d)...":        "导入 %s 的compose配置 (%d/%d)...",
+	"Merging %s AppData (%d/%d)...":                     "正在合并 %s 的AppData (%d/%d)...",
+	"Processing app data: %s (%d/%d)":                   "正在处理应用数据: %s (%d/%d)",
+	"Synced %s":                                         "已同步 %s",
+
+	// 接口响应
+	"Internal server error":                       "服务器内部错误",
+	"Connection test completed":                   "连接测试完成",
+	"Connection test failed: %v":                  "连接测试失败: %v",
+	"Connections retrieved successfully":          "已获取连接列表",
+	"Connection deleted":                          "连接已删除",
+	"Connection not found":                        "连接不存在",
+	"Connection updated":                          "连接已更新",
+	"Online migration started":                    "在线迁移已开始",
+	"Data import started":                         "数据导入已开始",
+	"Data export started":                         "数据导出已开始",
+	"Estimate completed":                          "预估完成",
+	"Invalid request: %v":                         "请求参数无效: %v",
+	"Invalid source connection configuration: %v": "源连接配置无效: %v",
+	"Invalid labels: %v":                          "标签格式无效: %v",
+	"Invalid export destination: %v":              "导出目标设置无效: %v",
+	"Invalid export filter: %v":                   "导出过滤设置无效: %v",
+	"Invalid export filter: include and exclude must be lists of patterns": "导出过滤设置无效: include和exclude必须是模式列表",
+	"Invalid export pattern: pattern is empty":                             "导出模式无效: 模式为空",
+	"Invalid export pattern %s: %v":                                        "导出模式 %s 无效: %v",
+	"Invalid S3 import source: %v":                                         "S3导入来源设置无效: %v",
+	"Invalid log query: %v":                                                "日志查询参数无效: %v",
+	"Invalid max_age: %s":                                                  "无效的max_age: %s",
+	"Invalid backup interval: %s":                                          "无效的备份间隔: %s",
+	"Backup interval is required":                                          "需要设置备份间隔",
+	"Backup interval must be at least %s":                                  "备份间隔不能小于 %s",
+	"Backup jobs only support CasaOS sources":                              "定时备份只支持CasaOS源系统",
+	"Backup retention values cannot be negative":                           "备份保留规则的值不能为负数",
+	"Connected in %d ms":                                                   "连接耗时 %d 毫秒",
+	"Could not determine version":                                          "无法确定版本",
+	"Version %s":                                                           "版本 %s",
+	"Could not determine source data size: %v":                             "无法统计源数据量: %v",
+	"Source data: %s":                                                      "源数据量: %s",
+	"Could not determine free space: %v":                                   "无法获取可用空间: %v",
+	"Large app check is disabled":                                          "大应用检查已关闭",
+	"Could not determine app sizes: %v":                                    "无法获取应用大小: %v",
+	"No app has more than %s of AppData":                                   "没有应用的AppData超过 %s",
+	"%d apps with more than %s of AppData are confirmed":                   "%d 个AppData超过 %s 的应用已确认",
+	"Apps with more than %s of AppData need confirmation: %s":              "AppData超过 %s 的应用需要确认: %s",
+	"Apps with very large AppData need confirmation: %s":                   "AppData非常大的应用需要确认: %s",
+	"Failed to list AppData directory: %v":                                 "列出AppData目录失败: %v",
+	"%s free of %s":                                                        "可用 %s，共 %s",
+	"Not enough free space: %s required, %s free":                          "可用空间不足: 需要 %s，可用 %s",
+	"Free space is tight: %s recommended, %s free":                         "可用空间紧张: 建议 %s，可用 %s",
+	"Skipped because the connection failed":                                "连接失败，已跳过",
+	"full_every cannot be negative":                                        "full_every不能为负数",
+	"Invalid preset name: %s":                                              "无效的预设名称: %s",
+	"Option %s cannot be stored in a preset":                               "选项 %s 不能保存在预设中",
+	"Preset %s not found":                                                  "预设 %s 不存在",
+	"Either connection_id or source is required":                           "需要提供connection_id或source",
+	"Connection %s not found; test the connection first":                   "连接 %s 不存在，请先测试连接",
+	"Backup job %s is already running":                                     "备份任务 %s 正在运行",
+	"Failed to start online migration: %v":                                 "启动在线迁移失败: %v",
+	"Failed to start data import: %v":                                      "启动数据导入失败: %v",
+	"Failed to start data import task: %v":                                 "启动数据导入任务失败: %v",
+	"Failed to start data export: %v":                                      "启动数据导出失败: %v",
+	"Failed to estimate migration: %v":                                     "迁移预估失败: %v",
+	"Failed to fetch source apps: %v":                                      "获取源系统应用失败: %v",
+	"Failed to fetch target storage: %v":                                   "获取目标存储失败: %v",
+	"Storage selection is only supported for ZimaOS targets":               "仅ZimaOS目标支持选择存储",
+	"Failed to list target storage: %v":                                    "获取目标存储列表失败: %v",
+	"Target volume %s not found on ZimaOS":                                 "ZimaOS上未找到存储卷 %s",
+	"Target AppData directory must be an absolute path: %s":                "目标AppData目录必须是绝对路径: %s",
+	"Target AppData directory cannot be the root directory":                "目标AppData目录不能是根目录",
+	"Target AppData directory must be under /media or /DATA: %s":           "目标AppData目录必须位于 /media 或 /DATA 下: %s",
+	"Failed to check target AppData directory %s: %v":                      "检查目标AppData目录 %s 失败: %v",
+	"Target AppData directory %s does not exist on ZimaOS":                 "ZimaOS上不存在目标AppData目录 %s",
+	"Target AppData directory %s is not writable: %v":                      "目标AppData目录 %s 不可写: %v",
+	"Invalid target connection configuration: %v":                          "目标连接配置无效: %v",
+	"Failed to generate export file: %v":                                   "生成导出文件失败: %v",
+	"Source connection failed: %s":                                         "源系统连接失败: %s",
+	"Import file contains demo data generated without a source connection, not a real export": "导入文件是未连接源系统时生成的演示数据，不是真实的导出",
+	"Failed to create app package: %v":                                                     "创建应用包失败: %v",
+	"Failed to create upload directory: %v":                                                "创建上传目录失败: %v",
+	"Failed to delete task: %v":                                                            "删除任务失败: %v",
+	"Failed to detect file format: %v":                                                     "检测文件格式失败: %v",
+	"Failed to get task logs: %v":                                                          "获取任务日志失败: %v",
+	"Failed to get uploaded file: %v":                                                      "获取上传文件失败: %v",
+	"Failed to parse target connection information: %v":                                    "解析目标连接信息失败: %v",
+	"Failed to parse upload data: %v":                                                      "解析上传数据失败: %v",
+	"Failed to save file content: %v":                                                      "保存文件内容失败: %v",
+	"Failed to save file: %v":                                                              "保存文件失败: %v",
+	"Failed to save uploaded file: %v":                                                     "保存上传文件失败: %v",
+	"Failed to verify saved file: %v":                                                      "校验已保存文件失败: %v",
+	"Uploaded gzip file is corrupted or incomplete: %v":                                    "上传的gzip文件已损坏或不完整: %v",
+	"Uploaded xz file is corrupted or incomplete: %v":                                      "上传的xz文件已损坏或不完整: %v",
+	"Uploaded tar file is corrupted or incomplete: %v":                                     "上传的tar文件已损坏或不完整: %v",
+	"Uploaded 7z file is corrupted or incomplete: %v":                                      "上传的7z文件已损坏或不完整: %v",
+	"Uploaded ZIP file is corrupted or incomplete: %v":                                     "上传的ZIP文件已损坏或不完整: %v",
+	"Archive is %s-compressed but not a tar archive":                                       "压缩包是%s压缩格式，但内容不是tar包",
+	"CasaOS directories were found under %s/, but they must be at the root of the archive": "在%s/下找到了CasaOS目录，但它们必须位于压缩包的根目录",
+	"Archive contains %s but no app definitions in %s":                                     "压缩包包含%s，但%s中没有应用定义",
+	"Archive is empty":                                                                     "压缩包为空",
+	"Archive does not contain %s or %s, found: %s":                                         "压缩包中没有%s或%s，找到的内容: %s",
+	"This archive was already imported to the same target by task %s":                      "该压缩包已由任务%s导入到同一目标",
+	"File save incomplete, please re-upload":                                               "文件保存不完整，请重新上传",
+	"File size exceeds limit (%s)":                                                         "文件大小超过限制（%s）",
+	"File uploaded successfully, data import task started":                                 "文件上传成功，数据导入任务已开始",
+	"Upload created":                                       "上传已创建",
+	"Invalid Upload-Length header":                         "Upload-Length请求头无效",
+	"Invalid Upload-Offset header":                         "Upload-Offset请求头无效",
+	"Invalid Upload-Metadata value for %s":                 "Upload-Metadata中%s的值无效",
+	"Content-Type must be application/offset+octet-stream": "Content-Type必须为application/offset+octet-stream",
+	"Upload not found":                                     "上传不存在",
+	"Upload offset does not match":                         "上传偏移不匹配",
+	"Upload is being written by another request":           "上传正在被另一个请求写入",
+	"Upload exceeds its declared length":                   "上传数据超过了声明的大小",
+	"Upload is not complete":                               "上传尚未完成",
+	"Invalid upload ID":                                    "上传ID无效",
+	"Import status retrieved":                              "已获取导入状态",
+	"Import status retrieved (cached)":                     "已获取导入状态（缓存）",
+	"Backup job created":                                   "备份任务已创建",
+	"Backup job deleted":                                   "备份任务已删除",
+	"Backup job not found":                                 "备份任务不存在",
+	"Backup job retrieved successfully":                    "已获取备份任务",
+	"Backup job updated":                                   "备份任务已更新",
+	"Backup jobs retrieved successfully":                   "已获取备份任务列表",
+	"Backup started":                                       "备份已开始",
+	"Missing target connection information":                "缺少目标连接信息",
+	"Package file not found":                               "未找到应用包文件",
+	"Preflight check completed":                            "迁移前检查完成",
+	"Preset already exists":                                "预设已存在",
+	"Preset created":                                       "预设已创建",
+	"Preset deleted":                                       "预设已删除",
+	"Preset not found":                                     "预设不存在",
+	"Preset retrieved successfully":                        "已获取预设",
+	"Preset updated":                                       "预设已更新",
+	"Presets retrieved successfully":                       "已获取预设列表",
+	"Removed %d temporary entries":                         "已删除 %d 个临时文件或目录",
+	"Running tasks cannot be deleted":                      "运行中的任务无法删除",
+	"Service is healthy":                                   "服务运行正常",
+	"System info":                                          "系统信息",
+	"Task ID and app name are required":                    "需要任务ID和应用名称",
+	"Task ID is required":                                  "需要任务ID",
+	"Task deleted successfully":                            "任务已删除",
+	"Task list retrieved":                                  "已获取任务列表",
+	"Task logs retrieved":                                  "已获取任务日志",
+	"Task resumed":                                         "任务已恢复执行",
+	"Task not found":                                       "任务不存在",
+	"Task status retrieved":                                "已获取任务状态",
+	"Test task created successfully":                       "测试任务创建成功",
+	"Unsupported export format: %s":                        "不支持的导出格式: %s",
+	"Unsupported file format, please upload .tar.gz, .tar.xz, .tar, .zip or .7z files": "不支持的文件格式，请上传.tar.gz、.tar.xz、.tar、.zip或.7z文件",
+	"Unsupported file format: %s, please upload gzip, xz, tar, zip or 7z format files": "不支持的文件格式: %s，请上传gzip、xz、tar、zip或7z格式的文件",
+	"Unsupported log format, use text or ndjson":                                       "不支持的日志格式，请使用text或ndjson",
+	"WebSocket test message sent":                                                      "WebSocket测试消息已发送",
+	"CasaOS login successful":                                                          "CasaOS登录成功",
+	"ZimaOS login successful":                                                          "ZimaOS登录成功",
+	"Docker host connection successful":                                                "Docker主机连接成功",
+	"Runtipi connection successful":                                                    "Runtipi连接成功",
+	"TrueNAS connection successful":                                                    "TrueNAS连接成功",
+	"CasaOS login failed: invalid username or password":                                "CasaOS登录失败: 用户名或密码错误",
+	"ZimaOS login failed: invalid username or password":                                "ZimaOS登录失败: 用户名或密码错误",
+	"CasaOS login failed: %s":                                                          "CasaOS登录失败: %s",
+	"ZimaOS login failed: %s":                                                          "ZimaOS登录失败: %s",
+	"Connection information is required":                                               "连接信息不能为空",
+	"Host is required":                                                                 "主机地址不能为空",
+	"Port must be between 1 and 65535":                                                 "端口号必须在1-65535之间",
+	"Username is required":                                                             "用户名不能为空",
+	"Password is required":                                                             "密码不能为空",
+	"Unsupported system type: %s":                                                      "不支持的系统类型: %s",
+	"No files found for app %s":                                                        "未找到应用 %s 的相关文件",
+	"Task type does not support import status query":                                   "该任务类型不支持查询导入状态",
+
+	// 任务日志级别
+	"Invalid log_level %s: must be debug, info or warn": "无效的log_level %s：必须为debug、info或warn",
+
+	// 服务日志级别
+	"Invalid log level %s: must be debug, info, warn or error": "无效的日志级别 %s：必须为debug、info、warn或error",
+	"Log level set to %s": "日志级别已设置为 %s",
+
+	// 清除全部数据
+	"Purging all data requires {\"confirm\": true}":            "清除全部数据需要提供 {\"confirm\": true}",
+	"Tasks are still running, cancel them before purging data": "仍有任务在运行，请先取消任务再清除数据",
+	"Failed to purge data: %s":                                 "清除数据失败: %s",
+	"Purged %d tasks, %d connections and %d files":             "已清除 %d 个任务、%d 个连接和 %d 个文件",
+
+	// 任务事件
+	"Invalid since: must be a non-negative integer": "since参数无效：必须为非负整数",
+	"Task events retrieved":                         "已获取任务事件",
+
+	// 批量任务操作
+	"Invalid action %s: must be delete, cancel or retry":       "无效的操作 %s：必须为delete、cancel或retry",
+	"Only failed, interrupted or waiting tasks can be retried": "只能重试失败、已中断或正在等待的任务",
+	"Import file of task %s no longer exists":                  "任务 %s 的导入文件已不存在",
+	"Task type %s cannot be retried":                           "任务类型 %s 不支持重试",
+	"Retrying failed task":                                     "正在重新执行失败的任务",
+	"Too many tasks in one request: at most %d":                "单次请求的任务过多：最多 %d 个",
+	"Batch %s: %d succeeded, %d failed":                        "批量%s：%d 个成功，%d 个失败",
+
+	// 导出任务历史
+	"Invalid export format %s: must be csv or ndjson": "无效的导出格式 %s：必须为csv或ndjson",
+
+	// 心跳
+	"Still decompressing data of app %s on the target (%s elapsed, status: %s)":                    "目标上仍在解压应用 %s 的数据（已等待 %s，状态：%s）",
+	"Still importing compose of app %s, the target may be pulling images (%s elapsed, status: %s)": "仍在导入应用 %s 的compose，目标可能正在拉取镜像（已等待 %s，状态：%s）",
+
+	// CPU架构检查
+	"Could not determine source CPU architecture: %v":  "无法获取源系统CPU架构: %v",
+	"Could not determine target CPU architecture: %v":  "无法获取目标系统CPU架构: %v",
+	"Source and target are both %s":                    "源系统和目标系统均为 %s",
+	"Could not check app images for %s: %v":            "无法检查应用镜像是否支持 %s: %v",
+	"All app images are available for %s":              "所有应用镜像均有 %s 版本",
+	"%d apps without images for %s are confirmed":      "%d 个没有 %s 镜像的应用已确认",
+	"Apps without images for %s need confirmation: %s": "没有 %s 镜像的应用需要确认: %s",
+
+	// 应用依赖
+	"Invalid %s: must be an object mapping app names to the apps they depend on": "%s 无效: 必须是应用名到其依赖应用列表的映射",
+	"Invalid %s: dependencies of %s must be a list of app names":                 "%s 无效: %s 的依赖必须是应用名列表",
+	"App %s depends on %s (detected from compose)":                               "应用 %s 依赖 %s（从compose检测）",
+	"Dependencies declared for unknown app %s are ignored":                       "为未知应用 %s 声明的依赖已忽略",
+	"App %s depends on %s, which is not part of this migration":                  "应用 %s 依赖 %s，但该应用不在本次迁移中",
+	"Apps %s depend on each other and are imported in name order":                "应用 %s 相互依赖，按名称顺序导入",
+	"App import order: %s": "应用导入顺序: %s",
+
+	// 蓝绿导入
+	"Only completed tasks can be promoted":                             "只能提升已完成任务的应用",
+	"Staged apps promoted":                                             "临时应用已提升",
+	"Some staged apps could not be promoted":                           "部分临时应用未能提升",
+	"Task has no staged apps to promote":                               "任务没有可提升的临时应用",
+	"Promoting staged apps is only supported on ZimaOS targets":        "只有ZimaOS目标支持提升临时应用",
+	"Promoting staged apps requires ssh_port on the target connection": "提升临时应用需要在目标连接中配置ssh_port",
+	"App is not staged by this task":                                   "该应用不是本任务导入的临时应用",
+	"Compose of the staged app was not imported":                       "临时应用的compose未导入",
+	"Failed to promote app %s to %s: %v":                               "将应用 %s 提升为 %s 失败: %v",
+	"App %s promoted to %s ✓":                                          "应用 %s 已提升为 %s ✓",
+	"Failed to read compose of %s: %v":                                 "读取 %s 的compose失败: %v",
+	"Failed to check app %s: %v":                                       "检查应用 %s 失败: %v",
+	"Failed to move app data of %s: %v":                                "移动 %s 的应用数据失败: %v",
+	"Import of %s failed (status code: %d): %s":                        "导入 %s 失败（状态码: %d）: %s",
+	"Failed to uninstall app %s, status code: %d, response: %s":        "卸载应用 %s 失败，状态码: %d，响应: %s",
+
+	// 任务总结报告
+	"Migration report":   "迁移报告",
+	"Task ID":            "任务ID",
+	"Type":               "类型",
+	"Status":             "状态",
+	"Source":             "源系统",
+	"Target":             "目标系统",
+	"Started":            "开始时间",
+	"Finished":           "结束时间",
+	"Total duration":     "总耗时",
+	"Notes":              "备注",
+	"Summary":            "摘要",
+	"Total apps":         "应用总数",
+	"Succeeded":          "成功",
+	"Warnings":           "警告",
+	"Failed":             "失败",
+	"Skipped":            "跳过",
+	"Apps":               "应用",
+	"App":                "应用",
+	"Runtime":            "运行状态",
+	"Message":            "信息",
+	"Rewritten paths":    "改写的路径",
+	"Service":            "服务",
+	"Source path":        "源路径",
+	"Target path":        "目标路径",
+	"Failures":           "失败原因",
+	"Steps":              "步骤",
+	"Step":               "步骤",
+	"Duration":           "耗时",
+	"Transferred":        "传输量",
+	"Generated at":       "生成时间",
+	"No reason recorded": "未记录原因",
+}
@@ -0,0 +1,204 @@
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 支持的语言
+const (
+	English = "en"
+	Chinese = "zh"
+)
+
+// catalogs 各语言的消息目录，键为英文原文（可含格式化动词），值为译文
+// 英文是源语言，没有目录
+var catalogs = map[string]map[string]string{
+	Chinese: zhMessages,
+}
+
+var (
+	defaultLang = English
+	mu          sync.RWMutex
+
+	patternsOnce sync.Once
+	patterns     map[string][]messagePattern
+)
+
+// messagePattern 由带格式化动词的消息编译出的匹配规则
+type messagePattern struct {
+	source      string
+	re          *regexp.Regexp
+	verbs       []byte // 每个捕获组对应的动词（s/v/d）
+	translation string
+	literalLen  int
+}
+
+// verbPattern 匹配消息中的格式化动词
+var verbPattern = regexp.MustCompile(`%%|%[sdv]`)
+
+// SetDefault 设置默认语言，不支持的语言忽略
+func SetDefault(lang string) {
+	if lang = Normalize(lang); lang == "" {
+		return
+	}
+	mu.Lock()
+	defaultLang = lang
+	mu.Unlock()
+}
+
+// Default 返回默认语言
+func Default() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLang
+}
+
+// Normalize 将语言标签（如zh-CN、en_US）规范为支持的语言，不支持时返回空字符串
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch lang {
+	case English, Chinese:
+		return lang
+	}
+	return ""
+}
+
+// ParseAcceptLanguage 按Accept-Language的权重选择第一个支持的语言，没有时返回默认语言
+func ParseAcceptLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := Normalize(fields[0])
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default()
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// T 将英文消息翻译为指定语言，lang为空时使用默认语言
+// 消息可以是目录中的原文，也可以是按目录中格式串格式化后的结果；没有译文时原样返回
+func T(lang, message string) string {
+	if lang == "" {
+		lang = Default()
+	}
+	catalog, ok := catalogs[lang]
+	if !ok || message == "" {
+		return message
+	}
+	if translated, ok := catalog[message]; ok {
+		return translated
+	}
+
+	patternsOnce.Do(compilePatterns)
+	for _, p := range patterns[lang] {
+		groups := p.re.FindStringSubmatch(message)
+		if groups == nil {
+			continue
+		}
+		args := make([]interface{}, len(p.verbs))
+		for i, verb := range p.verbs {
+			value := groups[i+1]
+			if verb == 'd' {
+				n, _ := strconv.ParseInt(value, 10, 64)
+				args[i] = n
+				continue
+			}
+			// 参数本身是目录中的消息时一并翻译（如步骤名称）
+			if translated, ok := catalog[value]; ok {
+				value = translated
+			}
+			args[i] = value
+		}
+		return fmt.Sprintf(p.translation, args...)
+	}
+	return message
+}
+
+// Tf 格式化并翻译消息
+func Tf(lang, format string, args ...interface{}) string {
+	return T(lang, fmt.Sprintf(format, args...))
+}
+
+// compilePatterns 将目录中带格式化动词的消息编译为正则，字面部分越长越优先匹配
+func compilePatterns() {
+	patterns = make(map[string][]messagePattern)
+	for lang, catalog := range catalogs {
+		for source, translation := range catalog {
+			if !verbPattern.MatchString(source) {
+				continue
+			}
+
+			var expr strings.Builder
+			var verbs []byte
+			literalLen := 0
+			last := 0
+			expr.WriteString("(?s)^")
+			for _, loc := range verbPattern.FindAllStringIndex(source, -1) {
+				literal := source[last:loc[0]]
+				expr.WriteString(regexp.QuoteMeta(literal))
+				literalLen += len(literal)
+				switch verb := source[loc[0]+1]; verb {
+				case '%':
+					expr.WriteString("%")
+					literalLen++
+				case 'd':
+					expr.WriteString(`(-?\d+)`)
+					verbs = append(verbs, verb)
+				default:
+					expr.WriteString(`(.*?)`)
+					verbs = append(verbs, verb)
+				}
+				last = loc[1]
+			}
+			expr.WriteString(regexp.QuoteMeta(source[last:]))
+			literalLen += len(source) - last
+			expr.WriteString("$")
+
+			patterns[lang] = append(patterns[lang], messagePattern{
+				source:      source,
+				re:          regexp.MustCompile(expr.String()),
+				verbs:       verbs,
+				translation: translation,
+				literalLen:  literalLen,
+			})
+		}
+		list := patterns[lang]
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].literalLen != list[j].literalLen {
+				return list[i].literalLen > list[j].literalLen
+			}
+			return list[i].source < list[j].source
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// 服务日志级别，按详细程度从高到低排列
+const (
+	LevelDebug   = "debug"
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// levels 日志级别的先后
+var levels = []string{LevelDebug, LevelInfo, LevelWarning, LevelError}
+
+// lineTags 日志行中的级别标记
+var lineTags = map[string]int{
+	"[DEBUG]":   0,
+	"[INFO]":    1,
+	"[WARNING]": 2,
+	"[WARN]":    2,
+	"[ERROR]":   3,
+}
+
+var (
+	// minLevel 输出的最低级别在levels中的下标
+	minLevel int32
+	// httpDumps 是否在服务日志中输出上传、删除等接口调用的请求和响应
+	httpDumps int32 = 1
+)
+
+// ParseLevel 解析日志级别，warn等同于warning
+func ParseLevel(level string) (string, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "warn" {
+		level = LevelWarning
+	}
+	for _, l := range levels {
+		if l == level {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid log level %s: must be debug, info, warn or error", level)
+}
+
+// Install 替换标准log包的输出，按当前级别过滤日志行并隐藏密码和令牌
+func Install(level string, dumps bool) error {
+	log.SetOutput(&filterWriter{out: log.Writer()})
+	SetHTTPDumps(dumps)
+	return SetLevel(level)
+}
+
+// SetLevel 修改服务日志级别，立即生效
+func SetLevel(level string) error {
+	level, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	for i, l := range levels {
+		if l == level {
+			atomic.StoreInt32(&minLevel, int32(i))
+		}
+	}
+	return nil
+}
+
+// Level 返回当前的服务日志级别
+func Level() string {
+	return levels[atomic.LoadInt32(&minLevel)]
+}
+
+// SetHTTPDumps 开启或关闭接口调用请求和响应的输出
+func SetHTTPDumps(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&httpDumps, v)
+}
+
+// HTTPDumps 是否输出接口调用的请求和响应，服务日志级别高于debug时不输出
+func HTTPDumps() bool {
+	return atomic.LoadInt32(&httpDumps) == 1 && atomic.LoadInt32(&minLevel) == 0
+}
+
+// filterWriter 丢弃低于当前级别的日志行并隐藏其中的密码和令牌，没有级别标记的行始终输出
+type filterWriter struct {
+	out io.Writer
+}
+
+// Write log包每次调用写入一整行，按其中第一个级别标记过滤
+func (w *filterWriter) Write(p []byte) (int, error) {
+	if i := bytes.IndexByte(p, '['); i >= 0 {
+		if j := bytes.IndexByte(p[i:], ']'); j > 0 {
+			if rank, ok := lineTags[string(p[i:i+j+1])]; ok && int32(rank) < atomic.LoadInt32(&minLevel) {
+				return len(p), nil
+			}
+		}
+	}
+	if _, err := io.WriteString(w.out, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Redacted 替换敏感信息的文本
+const Redacted = "REDACTED"
+
+// minSecretLength 登记的密码和令牌短于该长度时不替换，避免误替换普通文本
+// 只由字母组成的值（如changeme）同样不替换，这类值多半也是普通单词
+const minSecretLength = 8
+
+var (
+	// sensitiveNamePattern 视为敏感信息的字段名、参数名和HTTP头名
+	sensitiveNamePattern = regexp.MustCompile(`(?i)pass|token|secret|key|auth|cookie|session|credential`)
+	// jsonSecretPattern JSON中敏感字段的字符串值
+	jsonSecretPattern = regexp.MustCompile(`(?i)("[^"]*(?:pass|token|secret|key|auth|cookie|session|credential)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// formSecretPattern 表单和查询字符串中敏感参数的值
+	formSecretPattern = regexp.MustCompile(`(?i)((?:^|[&?\s])[^=&\s]*(?:pass|token|secret|key|auth|cookie|session|credential)[^=&\s]*=)[^&\s]*`)
+	// fieldSecretPattern %+v输出的结构体和map中敏感字段的值，如 Password:xxx、access_token:xxx
+	fieldSecretPattern = regexp.MustCompile(`(?i)((?:^|[\s{\[(])[A-Za-z_]*(?:pass|token|secret|cookie|session|credential|authorization)[A-Za-z_]*:)[^\s}\]),]+`)
+	// bearerPattern 正文中出现的Bearer令牌
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	// jwtPattern CasaOS/ZimaOS登录返回的JWT令牌，请求头中不带Bearer前缀
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// secrets 已知的密码和令牌，按归属（任务、连接或配置）登记，出现在任何位置都会被替换
+var secrets struct {
+	sync.RWMutex
+	owners   map[string]map[string]bool
+	replacer *strings.Replacer
+}
+
+// RegisterSecret 为owner登记密码或令牌，之后写入的服务日志、任务日志和诊断包中都不会出现该值
+// 过短或只由字母组成的值不登记，仍由字段名和参数名规则隐藏
+func RegisterSecret(owner string, values ...string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	changed := false
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !registrable(value) || secrets.owners[owner][value] {
+			continue
+		}
+		if secrets.owners == nil {
+			secrets.owners = make(map[string]map[string]bool)
+		}
+		if secrets.owners[owner] == nil {
+			secrets.owners[owner] = make(map[string]bool)
+		}
+		secrets.owners[owner][value] = true
+		changed = true
+	}
+	if changed {
+		rebuildReplacer()
+	}
+}
+
+// ReleaseSecrets 移除owner登记的值，其他归属仍登记的相同值继续被替换
+func ReleaseSecrets(owner string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	if _, ok := secrets.owners[owner]; !ok {
+		return
+	}
+	delete(secrets.owners, owner)
+	rebuildReplacer()
+}
+
+// registrable 判断值是否足够长且不只由字母组成，可以在任意位置替换
+func registrable(value string) bool {
+	if len(value) < minSecretLength {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildReplacer 按所有归属登记的值重建替换器，较长的值优先匹配，调用方持有写锁
+func rebuildReplacer() {
+	seen := make(map[string]bool)
+	var values []string
+	for _, owned := range secrets.owners {
+		for value := range owned {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	if len(values) == 0 {
+		secrets.replacer = nil
+		return
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		pairs = append(pairs, value, Redacted)
+	}
+	secrets.replacer = strings.NewReplacer(pairs...)
+}
+
+// SensitiveName 字段名、参数名或HTTP头名是否表示敏感信息
+func SensitiveName(name string) bool {
+	return sensitiveNamePattern.MatchString(name)
+}
+
+// Redact 隐藏文本中的密码和令牌：已登记的值、敏感字段和参数的值、Bearer令牌和JWT
+func Redact(text string) string {
+	secrets.RLock()
+	replacer := secrets.replacer
+	secrets.RUnlock()
+	if replacer != nil {
+		text = replacer.Replace(text)
+	}
+	text = jsonSecretPattern.ReplaceAllString(text, `${1}"`+Redacted+`"`)
+	text = formSecretPattern.ReplaceAllString(text, `${1}`+Redacted)
+	text = fieldSecretPattern.ReplaceAllString(text, `${1}`+Redacted)
+	text = bearerPattern.ReplaceAllString(text, `${1}`+Redacted)
+	text = jwtPattern.ReplaceAllString(text, Redacted)
+	return text
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"ctoz/backend/internal/i18n"
+	"ctoz/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookieName 登录会话的Cookie名称
+const SessionCookieName = "ctoz_session"
+
+// SessionValidator 校验登录会话
+type SessionValidator interface {
+	Enabled() bool
+	ValidateSession(token string) (string, bool)
+	// TouchSession 记录会话的最近访问时间，用于空闲超时和会话列表
+	TouchSession(token, clientIP, userAgent string)
+}
+
+// Auth 登录中间件，启用登录时要求有效的会话，未启用时直接放行
+// 会话令牌从Cookie读取，脚本调用也可以使用 Authorization: Bearer <token>
+func Auth(sessions SessionValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !sessions.Enabled() {
+			c.Next()
+			return
+		}
+
+		token := SessionToken(c)
+		user, ok := sessions.ValidateSession(token)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Message: i18n.T(c.GetString("Language"), "Authentication required"),
+			})
+			return
+		}
+		sessions.TouchSession(token, c.ClientIP(), c.Request.UserAgent())
+		c.Set("User", user)
+		c.Next()
+	}
+}
+
+// SessionToken 返回请求携带的会话令牌
+func SessionToken(c *gin.Context) string {
+	if token, err := c.Cookie(SessionCookieName); err == nil && token != "" {
+		return token
+	}
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return ""
+}
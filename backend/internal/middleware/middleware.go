@@ -6,9 +6,12 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"ctoz/backend/internal/i18n"
 	"ctoz/backend/internal/models"
 )
 
@@ -35,22 +38,33 @@ func Recovery() gin.HandlerFunc {
 		log.Printf("Panic recovered: %v", recovered)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "服务器内部错误",
+			Message: i18n.T(c.GetString("Language"), "Internal server error"),
 		})
 	})
 }
 
-// CORS 跨域中间件
-func CORS() gin.HandlerFunc {
+// CORS 跨域中间件，allowedOrigins为nil时允许任何来源，否则只对列表中的来源返回跨域头
+func CORS(allowedOrigins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		method := c.Request.Method
 		origin := c.Request.Header.Get("Origin")
 
+		if origin != "" && !OriginAllowed(c.Request, allowedOrigins) {
+			// 不返回跨域头，浏览器不会把响应交给其它来源的页面
+			if method == "OPTIONS" {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 设置CORS头
 		c.Header("Access-Control-Allow-Origin", origin)
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization, Cache-Control, Pragma")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type")
+		c.Header("Vary", "Origin")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization, Cache-Control, Pragma, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type, Content-Disposition, X-CTOZ-Demo, Location, Tus-Resumable, Tus-Version, Upload-Offset, Upload-Length")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		// 处理预检请求
@@ -63,6 +77,29 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// OriginAllowed 判断请求来源是否允许：allowedOrigins为nil时允许任何来源，
+// 没有Origin头（非浏览器客户端）或与请求主机相同的来源（同源页面）总是允许
+func OriginAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || allowedOrigins == nil {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	for _, allowed := range allowedOrigins {
+		if normalized == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 // RequestID 请求ID中间件
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -76,6 +113,19 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
+// Language 语言中间件，按lang查询参数或Accept-Language选择响应语言
+func Language() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Normalize(c.Query("lang"))
+		if lang == "" {
+			lang = i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+		}
+		c.Header("Content-Language", lang)
+		c.Set("Language", lang)
+		c.Next()
+	}
+}
+
 // RateLimiter 简单的速率限制中间件
 func RateLimiter() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -113,7 +163,7 @@ func ErrorHandler() gin.HandlerFunc {
 			case gin.ErrorTypeBind:
 				c.JSON(http.StatusBadRequest, models.APIResponse{
 					Success: false,
-					Message: "请求参数错误: " + err.Error(),
+					Message: i18n.T(c.GetString("Language"), "Invalid request: "+err.Error()),
 				})
 			case gin.ErrorTypePublic:
 				c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -123,16 +173,27 @@ func ErrorHandler() gin.HandlerFunc {
 			default:
 				c.JSON(http.StatusInternalServerError, models.APIResponse{
 					Success: false,
-					Message: "服务器内部错误",
+					Message: i18n.T(c.GetString("Language"), "Internal server error"),
 				})
 			}
 		}
 	}
 }
 
-// Timeout 超时中间件
-func Timeout(timeout time.Duration) gin.HandlerFunc {
+// Timeout 超时中间件，超时后取消请求上下文，处理器中进行的请求和传输随之中止
+// streamingRoutes 中的路由（长时间的下载、上传和推送）不限制时长，客户端断开时仍会取消
+func Timeout(timeout time.Duration, streamingRoutes ...string) gin.HandlerFunc {
+	streaming := make(map[string]bool, len(streamingRoutes))
+	for _, route := range streamingRoutes {
+		streaming[route] = true
+	}
+
 	return func(c *gin.Context) {
+		if streaming[c.FullPath()] {
+			c.Next()
+			return
+		}
+
 		// 设置超时上下文
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ctoz/backend/internal/i18n"
+	"ctoz/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnly 只读模式中间件，启用时只放行GET/HEAD请求
+// 预检、估算等接口虽然不修改数据，但会用提交的凭据连接源或目标系统，只读模式下同样拒绝
+func ReadOnly(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: i18n.T(c.GetString("Language"), "Server is in read-only mode"),
+		})
+	}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -16,21 +17,214 @@ var (
 	ErrMigrationFailed              = errors.New("migration failed")
 	ErrExportFailed                 = errors.New("export failed")
 	ErrImportFailed                 = errors.New("import failed")
+	ErrBackupJobNotFound            = errors.New("backup job not found")
+	ErrTasksRunning                 = errors.New("Tasks are still running, cancel them before purging data")
+	ErrPresetNotFound               = errors.New("preset not found")
+	ErrPresetExists                 = errors.New("preset already exists")
+	ErrPathRuleNotFound             = errors.New("path rule not found")
+	ErrImageRuleNotFound            = errors.New("image rule not found")
+	ErrAuthProviderDisabled         = errors.New("login provider is not enabled")
+	ErrInvalidCredentials           = errors.New("invalid username or password")
+	ErrSessionNotFound              = errors.New("session not found")
+	ErrGroupNotAllowed              = errors.New("user is not a member of an allowed group")
+	ErrUploadNotFound               = errors.New("upload not found")
+	ErrUploadOffsetMismatch         = errors.New("upload offset does not match")
+	ErrUploadBusy                   = errors.New("upload is being written by another request")
+	ErrUploadTooLarge               = errors.New("upload exceeds its declared length")
+	ErrUploadIncomplete             = errors.New("upload is not complete")
 )
 
+// SourceConnectionError 导出前源系统连接测试失败，携带连接测试的结果
+type SourceConnectionError struct {
+	Test *ConnectionTestResponse
+}
+
+func (e *SourceConnectionError) Error() string {
+	return "Source connection failed: " + e.Test.Message
+}
+
+// ArchiveLayout 导入压缩包的目录结构，只根据条目名称判断，不解压文件内容
+type ArchiveLayout struct {
+	Format      string   `json:"format"`
+	HasApps     bool     `json:"has_apps"`              // 根目录下有var/lib/casaos/apps/<app>/docker-compose.yml
+	HasAppData  bool     `json:"has_app_data"`          // 根目录下有DATA/AppData
+	Synology    bool     `json:"synology,omitempty"`    // 有Synology Container Manager项目的compose文件
+	Incremental bool     `json:"incremental,omitempty"` // CTOZ增量导出，结构在与之前的导出叠加后才完整
+	Prefix      string   `json:"prefix,omitempty"`      // CasaOS目录不在根目录时所在的子目录
+	TopLevel    []string `json:"top_level"`             // 根目录下的条目
+	Expected    []string `json:"expected,omitempty"`    // 结构无效时期望的目录
+}
+
+// ArchiveLayoutError 导入压缩包的目录结构无效
+type ArchiveLayoutError struct {
+	Layout *ArchiveLayout
+	Reason string
+}
+
+func (e *ArchiveLayoutError) Error() string {
+	return e.Reason
+}
+
+// DuplicateImport 之前将相同压缩包导入到同一目标的任务
+type DuplicateImport struct {
+	TaskID     string    `json:"task_id"`
+	TaskName   string    `json:"task_name,omitempty"`
+	Status     string    `json:"status"`
+	SHA256     string    `json:"sha256"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// DuplicateImportError 压缩包已导入过同一目标，需要确认后才能再次导入
+type DuplicateImportError struct {
+	Duplicate *DuplicateImport
+}
+
+func (e *DuplicateImportError) Error() string {
+	return "This archive was already imported to the same target by task " + e.Duplicate.TaskID
+}
+
+// ArchMismatchImage 没有目标CPU架构版本的镜像及镜像仓库中已有的架构
+type ArchMismatchImage struct {
+	Image         string   `json:"image"`
+	Architectures []string `json:"architectures"`
+}
+
+// ArchMismatchApp 有镜像没有目标CPU架构版本的应用
+type ArchMismatchApp struct {
+	AppName string              `json:"app_name"`
+	Images  []ArchMismatchImage `json:"images"`
+}
+
+// ArchMismatchError 源和目标CPU架构不同且有应用的镜像没有目标架构的版本，需要确认后才能开始迁移
+type ArchMismatchError struct {
+	SourceArch string            `json:"source_arch"`
+	TargetArch string            `json:"target_arch"`
+	Apps       []ArchMismatchApp `json:"apps"`
+}
+
+func (e *ArchMismatchError) Error() string {
+	names := make([]string, 0, len(e.Apps))
+	for _, app := range e.Apps {
+		names = append(names, app.AppName)
+	}
+	return "Apps without images for " + e.TargetArch + " need confirmation: " + strings.Join(names, ", ")
+}
+
+// LargeApp AppData超过大小阈值的应用
+type LargeApp struct {
+	AppName      string `json:"app_name"`
+	AppDataBytes int64  `json:"appdata_bytes"`
+}
+
+// LargeAppsError 有应用的AppData超过大小阈值，需要确认后才能开始迁移
+type LargeAppsError struct {
+	Apps           []LargeApp `json:"apps"`
+	ThresholdBytes int64      `json:"threshold_bytes"`
+}
+
+func (e *LargeAppsError) Error() string {
+	names := make([]string, 0, len(e.Apps))
+	for _, app := range e.Apps {
+		names = append(names, app.AppName)
+	}
+	return "Apps with very large AppData need confirmation: " + strings.Join(names, ", ")
+}
+
 // MigrationTask 迁移任务结构
 type MigrationTask struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"`   // online/offline-export/offline-import
-	Status    string                 `json:"status"` // pending/running/completed/failed
-	Progress  int                    `json:"progress"`
-	Source    *SystemConnection      `json:"source,omitempty"`
-	Target    *SystemConnection      `json:"target,omitempty"`
-	Options   map[string]interface{} `json:"options"`
-	Logs      []MigrationLog         `json:"logs"`
-	Result    map[string]interface{} `json:"result,omitempty"`
-	CreatedAt time.Time              `json:"created_at" time_format:"2006-01-02T15:04:05Z07:00"`
-	UpdatedAt time.Time              `json:"updated_at" time_format:"2006-01-02T15:04:05Z07:00"`
+	TaskMeta
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`   // online/offline-export/offline-import
+	Status   string                 `json:"status"` // pending/running/completed/failed
+	Progress int                    `json:"progress"`
+	Source   *SystemConnection      `json:"source,omitempty"`
+	Target   *SystemConnection      `json:"target,omitempty"`
+	Options  map[string]interface{} `json:"options"`
+	Logs     []MigrationLog         `json:"logs"`
+	Result   map[string]interface{} `json:"result,omitempty"`
+	Language string                 `json:"language,omitempty"` // 任务日志和推送消息使用的语言
+	// Checkpoint 任务工作目录等断点信息，服务重启后用于恢复任务
+	Checkpoint *TaskCheckpoint `json:"checkpoint,omitempty"`
+	Resumable  bool            `json:"resumable,omitempty"`
+	// Steps 已开始的步骤，按开始顺序排列
+	Steps []StepRecord `json:"steps,omitempty"`
+	// FailedCalls 最近失败的CasaOS/ZimaOS接口调用摘录，写入诊断包
+	FailedCalls []FailedCall `json:"failed_calls,omitempty"`
+	// ImageDigests 从源系统读取的镜像引用到摘要的映射，供pin镜像规则使用
+	ImageDigests map[string]string `json:"image_digests,omitempty"`
+	// StagedApps 蓝绿导入时目标上的临时应用名到正式应用名的映射，提升后移除
+	StagedApps map[string]string `json:"staged_apps,omitempty"`
+	// Build 创建任务的程序的版本信息，用于把旧任务的结果与产生它的程序对应起来
+	Build     *BuildInfo `json:"build,omitempty"`
+	CreatedAt time.Time  `json:"created_at" time_format:"2006-01-02T15:04:05Z07:00"`
+	UpdatedAt time.Time  `json:"updated_at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// BuildInfo 程序的版本、构建信息和Go运行环境
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// TaskCheckpoint 任务断点，记录已下载和解压的源数据位置
+// 每个应用的处理结果保存在任务结果的apps中
+type TaskCheckpoint struct {
+	DownloadPath  string            `json:"download_path,omitempty"`
+	ExtractedPath string            `json:"extracted_path,omitempty"`
+	SkippedApps   map[string]string `json:"skipped_apps,omitempty"`
+	// Apps 每个应用已完成的步骤，AppData合并和compose导入成功后立即写入状态文件
+	Apps map[string]*AppCheckpoint `json:"apps,omitempty"`
+}
+
+// AppCheckpoint 应用的断点记录
+type AppCheckpoint struct {
+	AppData   bool      `json:"appdata,omitempty"`
+	Compose   bool      `json:"compose,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 任务步骤状态
+const (
+	StepStatusPending = "pending"
+	StepStatusRunning = "running"
+	StepStatusDone    = "done"
+	StepStatusFailed  = "failed"
+	StepStatusSkipped = "skipped"
+)
+
+// StepRecord 任务步骤的执行记录
+type StepRecord struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// FailedCall 失败的接口调用摘录，URL和请求、响应内容中的密码和令牌已隐藏
+type FailedCall struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Request    string    `json:"request,omitempty"`  // 请求体开头
+	Response   string    `json:"response,omitempty"` // 响应体开头
+}
+
+// TaskMeta 创建任务时附加的名称、备注、标签和日志级别，用于区分和筛选任务
+type TaskMeta struct {
+	Name   string            `json:"name,omitempty"`
+	Notes  string            `json:"notes,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// LogLevel 任务日志记录的最低级别：debug/info/warning（可写作warn），默认info
+	// debug任务额外记录上传、删除等接口调用的请求和响应
+	LogLevel string `json:"log_level,omitempty"`
 }
 
 // SystemConnection 系统连接信息
@@ -41,8 +235,81 @@ type SystemConnection struct {
 	Username string `json:"username"`
 	Password string `json:"password,omitempty"` // 不返回给前端
 	Token    string `json:"token,omitempty"`
-	Type     string `json:"type"` // casaos/zimaos
+	Type     string `json:"type"` // casaos/zimaos/docker/runtipi/truenas
 	Verified bool   `json:"verified"`
+
+	// Version 连接测试时检测到的CasaOS/ZimaOS版本，未能检测时为空
+	Version string `json:"version,omitempty"`
+
+	// LastTestedAt 最近一次连接测试通过的时间，只对保存的连接有效
+	LastTestedAt *time.Time `json:"last_tested_at,omitempty"`
+
+	// 基于SSH的系统（type=docker/runtipi/truenas）使用的设置
+	KeyFile    string `json:"key_file,omitempty"`    // SSH私钥路径
+	ComposeDir string `json:"compose_dir,omitempty"` // compose文件写入目录
+	AppDataDir string `json:"appdata_dir,omitempty"` // 应用数据目录
+	RootDir    string `json:"root_dir,omitempty"`    // 源系统安装目录（runtipi默认为~/runtipi，truenas默认自动探测）
+
+	// CasaOS/ZimaOS可选的SSH端口：ZimaOS目标用于解压后恢复文件属主，CasaOS源在打包下载不可用时通过SSH获取数据
+	SSHPort int `json:"ssh_port,omitempty"`
+
+	// Proxy 访问该系统HTTP接口使用的代理（http://、https:// 或 socks5://），为空时使用HTTP_PROXY等环境变量
+	Proxy string `json:"proxy,omitempty"`
+
+	// ResolveIP 主机名解析到的固定地址（类似curl --resolve），用于只能通过tailnet或分离DNS访问的系统
+	ResolveIP string `json:"resolve_ip,omitempty"`
+}
+
+// ExportFilter 导出内容的包含和排除模式，在导出选项的include和exclude中设置
+// 模式匹配压缩包中应用目录（var/lib/casaos/apps）和DATA目录下的路径，如 DATA/AppData/*/cache
+type ExportFilter struct {
+	Include []string `json:"include,omitempty"` // 设置时只导出匹配的条目
+	Exclude []string `json:"exclude,omitempty"` // 匹配的条目不导出，优先于include
+}
+
+// ExportDestination 导出文件的推送目标，在导出选项的destination中设置
+// 未设置时导出文件只保存在本地导出目录
+type ExportDestination struct {
+	Type      string     `json:"type"` // s3/smb/nfs
+	S3        *S3Config  `json:"s3,omitempty"`
+	SMB       *SMBConfig `json:"smb,omitempty"`
+	NFS       *NFSConfig `json:"nfs,omitempty"`
+	KeepLocal bool       `json:"keep_local,omitempty"` // 推送成功后保留本地文件
+}
+
+// S3Config S3兼容对象存储设置
+type S3Config struct {
+	Endpoint        string `json:"endpoint,omitempty"` // 为空时使用AWS S3
+	Region          string `json:"region,omitempty"`   // 默认us-east-1
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix,omitempty"` // 对象键前缀
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	PathStyle       bool   `json:"path_style,omitempty"` // 使用路径形式访问（MinIO等自建服务通常需要）
+}
+
+// S3Object S3兼容存储中的单个对象
+type S3Object struct {
+	S3Config
+	Key string `json:"key"`
+}
+
+// SMBConfig SMB共享设置
+type SMBConfig struct {
+	Server   string `json:"server"`
+	Share    string `json:"share"`
+	Path     string `json:"path,omitempty"` // 共享内已存在的目录
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+}
+
+// NFSConfig NFS导出设置
+type NFSConfig struct {
+	Server  string `json:"server"`
+	Export  string `json:"export"`            // 服务器上的导出路径
+	Path    string `json:"path,omitempty"`    // 导出内的子目录
+	Options string `json:"options,omitempty"` // mount -o 选项
 }
 
 // MigrationLog 迁移日志
@@ -52,6 +319,13 @@ type MigrationLog struct {
 	Timestamp time.Time `json:"timestamp" time_format:"2006-01-02T15:04:05Z07:00"`
 }
 
+// LogQuery 任务日志查询条件
+type LogQuery struct {
+	Offset int       // 跳过的日志条数
+	Limit  int       // 返回的最大条数，0表示不限制
+	Since  time.Time // 仅返回该时间之后的日志
+}
+
 // WSMessage WebSocket消息结构
 type WSMessage struct {
 	Type      string                 `json:"type"` // step_start/step_progress/step_complete/step_error/console_output
@@ -64,6 +338,20 @@ type WSMessage struct {
 	Data      map[string]interface{} `json:"data,omitempty"`
 }
 
+// TaskEvent 任务事件，记录发给任务的每条WebSocket消息，Seq在任务内从1开始递增
+type TaskEvent struct {
+	Seq int64 `json:"seq"`
+	WSMessage
+}
+
+// TaskEventPage 任务事件查询结果
+type TaskEventPage struct {
+	TaskID    string       `json:"task_id"`
+	Events    []*TaskEvent `json:"events"`
+	LastSeq   int64        `json:"last_seq"`  // 任务最新事件的序号，下次查询作为since
+	Truncated bool         `json:"truncated"` // since之后的部分旧事件已因数量上限被丢弃
+}
+
 // DownloadInstructions 下载指引信息
 type DownloadInstructions struct {
 	CasaOSHost    string   `json:"casaos_host"`
@@ -88,7 +376,7 @@ type TestConnectionRequest struct {
 	Host       string `json:"host" binding:"required"`
 	Username   string `json:"username" binding:"required"`
 	Password   string `json:"password" binding:"required"`
-	SystemType string `json:"system_type" binding:"required,oneof=casaos zimaos"`
+	SystemType string `json:"system_type" binding:"required,oneof=casaos zimaos docker runtipi truenas"`
 }
 
 // ConnectionTestRequest 连接测试请求（包装结构）
@@ -98,29 +386,124 @@ type ConnectionTestRequest struct {
 
 // OnlineMigrationRequest 在线迁移请求
 type OnlineMigrationRequest struct {
+	TaskMeta
 	Source           SystemConnection       `json:"source" binding:"required"`
 	Target           SystemConnection       `json:"target" binding:"required"`
 	MigrationOptions map[string]interface{} `json:"migrationOptions"`
+	Preset           string                 `json:"preset,omitempty"` // 以该预设的选项为默认值，migrationOptions中的同名选项优先
+	Language         string                 `json:"-"`                // 由请求的Accept-Language决定
 }
 
 // DataExportRequest 数据导出请求
 type DataExportRequest struct {
+	TaskMeta
 	Source        SystemConnection       `json:"source" binding:"required"`
 	ExportOptions map[string]interface{} `json:"export_options" binding:"required"`
+	Language      string                 `json:"-"` // 由请求的Accept-Language决定
 }
 
 // DataImportRequest 数据导入请求
 type DataImportRequest struct {
+	TaskMeta
 	Target        SystemConnection       `json:"target" binding:"required"`
 	ImportOptions map[string]interface{} `json:"import_options"`
+	Preset        string                 `json:"preset,omitempty"` // 以该预设的选项为默认值，import_options中的同名选项优先
+	S3            *S3Object              `json:"s3,omitempty"`     // 从S3兼容存储下载导入文件，代替import_file
+	Language      string                 `json:"-"`                // 由请求的Accept-Language决定
 	// PackageFile 通过multipart/form-data上传
 }
 
+// EstimateRequest 迁移预估请求
+type EstimateRequest struct {
+	Source SystemConnection `json:"source" binding:"required"`
+}
+
+// AppSizeEstimate 单个应用的数据量
+type AppSizeEstimate struct {
+	AppName      string `json:"app_name"`
+	ComposeBytes int64  `json:"compose_bytes"`
+	AppDataBytes int64  `json:"appdata_bytes"`
+	TotalBytes   int64  `json:"total_bytes"`
+}
+
+// EstimateResponse 迁移预估结果
+type EstimateResponse struct {
+	Apps             []AppSizeEstimate `json:"apps"`
+	TotalBytes       int64             `json:"total_bytes"`
+	ThroughputBps    int64             `json:"throughput_bytes_per_sec"` // 实测源系统下载速率
+	EstimatedSeconds int64             `json:"estimated_seconds"`        // 按实测速率估算的下载+上传时间
+}
+
+// SourceAppsRequest 源系统应用列表请求
+type SourceAppsRequest struct {
+	Source SystemConnection `json:"source" binding:"required"`
+}
+
+// SourceApp 源系统已安装的应用
+type SourceApp struct {
+	Name     string   `json:"name"`
+	Title    string   `json:"title"`
+	Icon     string   `json:"icon,omitempty"`
+	Status   string   `json:"status,omitempty"`
+	AppType  string   `json:"app_type,omitempty"`
+	WebPort  string   `json:"web_port,omitempty"`
+	Ports    []string `json:"ports"`
+	DataSize int64    `json:"data_size"` // AppData目录大小（字节）
+}
+
 // ConnectionTestResponse 连接测试响应
 type ConnectionTestResponse struct {
 	Success    bool                   `json:"success"`
 	Message    string                 `json:"message"`
 	SystemInfo map[string]interface{} `json:"system_info,omitempty"`
+	// ConnectionID 测试通过后保存的连接ID，可用于 /api/connections 和 connection_id
+	ConnectionID string `json:"connection_id,omitempty"`
+}
+
+// TargetStorageRequest 目标系统存储列表请求
+type TargetStorageRequest struct {
+	Target SystemConnection `json:"target" binding:"required"`
+}
+
+// TargetVolume 目标系统上可用于存放AppData的存储卷
+type TargetVolume struct {
+	Name       string `json:"name"`
+	MountPoint string `json:"mount_point"`
+	Type       string `json:"type,omitempty"`
+	Size       int64  `json:"size"`  // 总空间（字节），未知时为0
+	Avail      int64  `json:"avail"` // 可用空间（字节），未知时为0
+	AppDataDir string `json:"app_data_dir"`
+	Default    bool   `json:"default"`
+}
+
+// PreflightRequest 迁移前检查请求
+type PreflightRequest struct {
+	Source  SystemConnection       `json:"source" binding:"required"`
+	Target  SystemConnection       `json:"target" binding:"required"`
+	Options map[string]interface{} `json:"options,omitempty"` // 迁移选项，用于确定目标存储位置
+}
+
+// 检查项结果
+const (
+	PreflightPass    = "pass"
+	PreflightWarning = "warning"
+	PreflightFail    = "fail"
+	PreflightSkipped = "skipped"
+)
+
+// PreflightCheck 单个检查项
+type PreflightCheck struct {
+	Name    string                 `json:"name"`   // 如 source_connection、target_disk
+	Status  string                 `json:"status"` // pass/warning/fail/skipped
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// PreflightReport 迁移前检查结果，没有失败项时Ready为true
+type PreflightReport struct {
+	Ready       bool             `json:"ready"`
+	Checks      []PreflightCheck `json:"checks"`
+	SourceBytes int64            `json:"source_bytes,omitempty"` // 源系统待迁移数据量
 }
 
 // TaskResponse 任务响应
@@ -135,6 +518,195 @@ type ExportDataResponse struct {
 	DownloadInstructions *DownloadInstructions `json:"download_instructions"`
 }
 
+// BackupJob 定时备份任务，按固定间隔导出CasaOS源系统的完整数据
+type BackupJob struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Source      *SystemConnection  `json:"source"`
+	Interval    string             `json:"interval"` // Go时长格式，如24h
+	Destination *ExportDestination `json:"destination,omitempty"`
+	Retention   *BackupRetention   `json:"retention,omitempty"`  // 未设置时使用默认保留规则
+	Incremental bool               `json:"incremental"`          // 只导出与上次备份相比有变化的应用
+	FullEvery   int                `json:"full_every,omitempty"` // 连续增量备份达到该次数后重新做完整备份
+	Filter      *ExportFilter      `json:"filter,omitempty"`     // 备份内容的包含和排除模式
+	Enabled     bool               `json:"enabled"`
+	NextRunAt   time.Time          `json:"next_run_at"`
+	LastRunAt   *time.Time         `json:"last_run_at,omitempty"`
+	LastTaskID  string             `json:"last_task_id,omitempty"`
+	LastStatus  string             `json:"last_status,omitempty"`
+	// 增量备份状态：上次备份的压缩包、各应用内容哈希和自上次完整备份以来的增量次数
+	LastArchive      string            `json:"last_archive,omitempty"`
+	AppHashes        map[string]string `json:"app_hashes,omitempty"`
+	IncrementalCount int               `json:"incremental_count,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// BackupJobRequest 创建或更新定时备份任务的请求
+type BackupJobRequest struct {
+	Name         string             `json:"name"`
+	ConnectionID string             `json:"connection_id,omitempty"` // 已测试通过的连接
+	Source       *SystemConnection  `json:"source,omitempty"`        // 或直接提供连接信息
+	Interval     string             `json:"interval"`
+	StartAt      *time.Time         `json:"start_at,omitempty"` // 首次执行时间，默认为创建后一个间隔
+	Destination  *ExportDestination `json:"destination,omitempty"`
+	Retention    *BackupRetention   `json:"retention,omitempty"`
+	Incremental  *bool              `json:"incremental,omitempty"`
+	FullEvery    *int               `json:"full_every,omitempty"`
+	Filter       *ExportFilter      `json:"filter,omitempty"`
+	Enabled      *bool              `json:"enabled,omitempty"`
+}
+
+// OptionPreset 保存的迁移选项预设，启动在线迁移或导入时可通过preset按名称引用
+type OptionPreset struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Options     map[string]interface{} `json:"options"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// OptionPresetRequest 创建或更新迁移选项预设的请求
+type OptionPresetRequest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Options     map[string]interface{} `json:"options" binding:"required"`
+}
+
+// 路径改写规则类型
+const (
+	PathRulePrefix = "prefix"
+	PathRuleRegex  = "regex"
+)
+
+// PathRule 导入时改写compose中bind挂载源路径的规则，按创建顺序匹配，第一个匹配的规则生效
+type PathRule struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`  // prefix/regex
+	Match       string    `json:"match"` // 路径前缀或正则表达式
+	Replace     string    `json:"replace"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PathRuleRequest 创建或更新路径改写规则的请求
+type PathRuleRequest struct {
+	Type        string `json:"type" binding:"required"`
+	Match       string `json:"match" binding:"required"`
+	Replace     string `json:"replace"`
+	Description string `json:"description"`
+}
+
+// 镜像改写规则类型
+const (
+	ImageRulePrefix = "prefix"
+	ImageRuleRegex  = "regex"
+	ImageRulePin    = "pin"
+)
+
+// ImageRule 导入时改写compose中镜像引用的规则，匹配完整的镜像引用（如docker.io/library/nginx:latest）
+// pin规则把latest标签固定为源系统上的摘要，其余规则按创建顺序匹配，第一个匹配的规则生效
+type ImageRule struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`  // prefix/regex/pin
+	Match       string    `json:"match"` // 镜像引用前缀或正则表达式
+	Replace     string    `json:"replace"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ImageRuleRequest 创建或更新镜像改写规则的请求
+type ImageRuleRequest struct {
+	Type        string `json:"type" binding:"required"`
+	Match       string `json:"match" binding:"required"`
+	Replace     string `json:"replace"`
+	Description string `json:"description"`
+}
+
+// BindMount 应用服务的bind挂载源路径及按当前改写规则得到的路径
+type BindMount struct {
+	Service   string `json:"service"`
+	Source    string `json:"source"`
+	Rewritten string `json:"rewritten"`
+	RuleID    string `json:"rule_id,omitempty"` // 生效的规则，未改写时为空
+}
+
+// AppBindMounts 任务中单个应用的bind挂载预览
+type AppBindMounts struct {
+	AppName string      `json:"app_name"`
+	Mounts  []BindMount `json:"mounts"`
+}
+
+// BackupRetention 导出目录中备份压缩包的保留规则，规则之间取并集，最新的备份始终保留
+// 所有值为0时保留全部备份
+type BackupRetention struct {
+	KeepLast   int `json:"keep_last"`   // 保留最近N个备份
+	KeepDaily  int `json:"keep_daily"`  // 最近N天内每天保留最新的一个
+	KeepWeekly int `json:"keep_weekly"` // 最近N周内每周保留最新的一个
+}
+
+// CleanupReport 临时文件清理结果
+type CleanupReport struct {
+	Removed    []string `json:"removed"`
+	FreedBytes int64    `json:"freed_bytes"`
+	Kept       int      `json:"kept"`                     // 仍被任务引用或未到期而保留的条目数
+	Pruned     []string `json:"pruned_backups,omitempty"` // 按保留规则删除的备份压缩包
+}
+
+// StoreStats 存储中的任务、连接和日志数量
+type StoreStats struct {
+	Tasks                int            `json:"tasks"`
+	TasksByStatus        map[string]int `json:"tasks_by_status"`
+	TasksByType          map[string]int `json:"tasks_by_type"`
+	Connections          int            `json:"connections"`
+	TotalLogs            int            `json:"total_logs"`
+	DownloadInstructions int            `json:"download_instructions"`
+}
+
+// WorkDirUsage 工作目录的磁盘占用，嵌套在其中的其它工作目录不计入
+type WorkDirUsage struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Bytes   int64  `json:"bytes"`
+	Entries int    `json:"entries"`
+}
+
+// Stats 统计接口返回的任务、存储、工作目录和WebSocket统计信息
+type Stats struct {
+	StoreStats
+	WorkDirs         []WorkDirUsage `json:"work_dirs"`
+	WorkDirBytes     int64          `json:"work_dir_bytes"`
+	WebSocketClients int            `json:"websocket_clients"`
+	WebSocketTasks   int            `json:"websocket_tasks"` // 有客户端连接的任务数
+}
+
+// PurgeRequest 清除全部数据的请求，confirm必须为true
+type PurgeRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
+// PurgeReport 清除全部数据的结果
+type PurgeReport struct {
+	Tasks        int      `json:"tasks"`
+	Logs         int      `json:"logs"`
+	Connections  int      `json:"connections"`
+	BackupJobs   int      `json:"backup_jobs"`
+	RemovedFiles []string `json:"removed_files"`
+	FreedBytes   int64    `json:"freed_bytes"`
+}
+
+// Upload 断点续传（tus协议）的导入文件上传
+type Upload struct {
+	ID        string            `json:"id"`
+	Filename  string            `json:"filename"`
+	Length    int64             `json:"length"` // 文件总大小
+	Offset    int64             `json:"offset"` // 已接收的字节数，以数据文件的大小为准
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
 // TaskStatus 任务状态类型
 type TaskStatus string
 
@@ -144,8 +716,22 @@ const (
 	TaskStatusRunning   TaskStatus = "running"
 	TaskStatusCompleted TaskStatus = "completed"
 	TaskStatusFailed    TaskStatus = "failed"
+	// TaskStatusInterrupted 服务重启时仍在执行的任务
+	TaskStatusInterrupted TaskStatus = "interrupted"
+	// TaskStatusWaiting 目标连续失败后暂停，等待目标恢复
+	TaskStatusWaiting TaskStatus = "waiting"
 )
 
+// IsTerminalStatus 任务是否已结束：completed、failed和interrupted不会再自行变化，
+// interrupted只能通过resume接口恢复；waiting的任务在目标恢复后会继续执行，不算结束
+func IsTerminalStatus(status string) bool {
+	switch TaskStatus(status) {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusInterrupted:
+		return true
+	}
+	return false
+}
+
 // 任务类型常量
 const (
 	TaskTypeOnline        = "online"
@@ -156,10 +742,20 @@ const (
 	TaskTypeTest          = "test"
 )
 
+// 导出目标类型常量
+const (
+	ExportDestinationS3  = "s3"
+	ExportDestinationSMB = "smb"
+	ExportDestinationNFS = "nfs"
+)
+
 // 系统类型常量
 const (
-	SystemTypeCasaOS = "casaos"
-	SystemTypeZimaOS = "zimaos"
+	SystemTypeCasaOS  = "casaos"
+	SystemTypeZimaOS  = "zimaos"
+	SystemTypeDocker  = "docker"
+	SystemTypeRuntipi = "runtipi"
+	SystemTypeTrueNAS = "truenas"
 )
 
 // WebSocket消息类型常量
@@ -169,24 +765,84 @@ const (
 	WSMsgTypeStepComplete  = "step_complete"
 	WSMsgTypeStepError     = "step_error"
 	WSMsgTypeConsoleOutput = "console_output"
+	// WSMsgTypeAppProgress 单个应用的传输进度，不影响任务整体进度
+	WSMsgTypeAppProgress = "app_progress"
+	// WSMsgTypeServerRestarting 服务即将关闭或重启，随后连接以1012关闭帧结束
+	WSMsgTypeServerRestarting = "server_restarting"
 )
 
 // 日志级别常量
 const (
+	LogLevelDebug   = "debug"
 	LogLevelInfo    = "info"
 	LogLevelWarning = "warning"
 	LogLevelError   = "error"
 )
 
+// LogSettings 服务日志设置
+type LogSettings struct {
+	Level     string `json:"level"`      // debug/info/warning/error
+	HTTPDumps bool   `json:"http_dumps"` // 是否输出接口调用的请求和响应
+}
+
+// LogSettingsRequest 修改服务日志设置的请求，未提供的字段保持不变
+type LogSettingsRequest struct {
+	Level     *string `json:"level"`
+	HTTPDumps *bool   `json:"http_dumps"`
+}
+
 // AppImportStatus 应用导入状态
 type AppImportStatus struct {
 	AppName       string `json:"app_name"`
 	HasAppData    bool   `json:"has_app_data"`
 	AppDataStatus string `json:"app_data_status"` // success/failed/skipped
 	ComposeStatus string `json:"compose_status"`  // success/failed
-	OverallStatus string `json:"overall_status"`  // success/failed
+	OverallStatus string `json:"overall_status"`  // success/failed/skipped/warning
 	ErrorMessage  string `json:"error_message,omitempty"`
+	ErrorCode     string `json:"error_code,omitempty"` // 已知失败原因的代码，如TARGET_DISK_FULL
 	DownloadURL   string `json:"download_url,omitempty"`
+
+	// Warnings 需要用户处理的问题及处理建议，例如目标上缺少应用需要的设备
+	Warnings []string `json:"warnings,omitempty"`
+	// SecurityFindings 应用使用的特权模式、主机网络等高权限配置
+	SecurityFindings []SecurityFinding `json:"security_findings,omitempty"`
+	// BindMounts 扫描时记录的bind挂载，用于预览路径改写
+	BindMounts []BindMount `json:"bind_mounts,omitempty"`
+
+	// 开启auto_start时导入后容器的运行状态
+	RuntimeStatus  string `json:"runtime_status,omitempty"` // running/healthy/unhealthy/starting/exited
+	RuntimeMessage string `json:"runtime_message,omitempty"`
+
+	// Metrics 迁移各阶段的耗时和传输量
+	Metrics *AppMetrics `json:"metrics,omitempty"`
+}
+
+// AppMetrics 应用迁移各阶段的耗时（毫秒）和传输的字节数，用于找出迁移慢的应用
+type AppMetrics struct {
+	BytesTransferred int64 `json:"bytes_transferred"`
+	CompressMs       int64 `json:"compress_ms"`
+	UploadMs         int64 `json:"upload_ms"`
+	DecompressMs     int64 `json:"decompress_ms"`
+	ComposeImportMs  int64 `json:"compose_import_ms"`
+}
+
+// Add 累加另一个应用的指标，用于计算汇总
+func (m *AppMetrics) Add(other *AppMetrics) {
+	if other == nil {
+		return
+	}
+	m.BytesTransferred += other.BytesTransferred
+	m.CompressMs += other.CompressMs
+	m.UploadMs += other.UploadMs
+	m.DecompressMs += other.DecompressMs
+	m.ComposeImportMs += other.ComposeImportMs
+}
+
+// SecurityFinding compose中需要确认后才迁移的高权限配置
+type SecurityFinding struct {
+	Service string `json:"service"`
+	Kind    string `json:"kind"` // privileged/host_network/cap_add/docker_socket
+	Detail  string `json:"detail,omitempty"`
 }
 
 // ImportStatusResponse 导入状态响应
@@ -198,11 +854,77 @@ type ImportStatusResponse struct {
 	Summary  ImportSummary     `json:"summary"`
 }
 
+// PromoteRequest 提升蓝绿导入的应用，Apps为空时提升全部
+type PromoteRequest struct {
+	Apps []string `json:"apps"`
+}
+
+// PromoteResult 单个应用的提升结果
+type PromoteResult struct {
+	AppName    string `json:"app_name"`
+	StagedName string `json:"staged_name"`
+	Status     string `json:"status"` // success/failed/skipped
+	Error      string `json:"error,omitempty"`
+}
+
+// 批量任务操作
+const (
+	BatchActionDelete = "delete" // 删除未在运行的任务
+	BatchActionCancel = "cancel" // 取消运行中的任务
+	BatchActionRetry  = "retry"  // 重新执行失败的任务，恢复中断的任务，或让等待目标恢复的任务立即重试
+)
+
+// BatchTaskRequest 对多个任务执行同一操作
+type BatchTaskRequest struct {
+	IDs    []string `json:"ids" binding:"required"`
+	Action string   `json:"action" binding:"required"` // delete/cancel/retry
+}
+
+// BatchTaskResult 单个任务的操作结果
+type BatchTaskResult struct {
+	TaskID  string `json:"task_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchTaskResponse 批量任务操作的结果，每个任务单独成功或失败
+type BatchTaskResponse struct {
+	Action    string            `json:"action"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchTaskResult `json:"results"`
+}
+
+// TaskHistoryRecord 导出的任务历史记录，连接和选项中的密码、令牌和密钥已去掉
+type TaskHistoryRecord struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name,omitempty"`
+	Type       string                 `json:"type"`
+	Status     string                 `json:"status"`
+	Progress   int                    `json:"progress"`
+	Labels     map[string]string      `json:"labels,omitempty"`
+	Notes      string                 `json:"notes,omitempty"`
+	Source     *SystemConnection      `json:"source,omitempty"`
+	Target     *SystemConnection      `json:"target,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty"`
+	Summary    ImportSummary          `json:"summary"`
+	Error      string                 `json:"error,omitempty"` // 失败步骤的错误信息
+	Version    string                 `json:"version,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
 // ImportSummary 导入摘要
 type ImportSummary struct {
 	TotalApps   int `json:"total_apps"`
 	SuccessApps int `json:"success_apps"`
 	FailedApps  int `json:"failed_apps"`
+	SkippedApps int `json:"skipped_apps"`
+	WarningApps int `json:"warning_apps"`
+	FlaggedApps int `json:"flagged_apps"` // 使用高权限配置的应用数
+	// Metrics 所有应用迁移指标的合计
+	Metrics *AppMetrics `json:"metrics,omitempty"`
 }
 
 // 应用状态常量
@@ -210,4 +932,37 @@ const (
 	AppStatusSuccess = "success"
 	AppStatusFailed  = "failed"
 	AppStatusSkipped = "skipped"
+	// AppStatusWarning 应用未导入或导入后可能无法运行，需要用户处理Warnings中的问题
+	AppStatusWarning = "warning"
+)
+
+// 应用失败原因代码，便于界面区分处理
+const (
+	// AppErrorTargetDiskFull 目标存储卷的可用空间不足以容纳应用数据，上传前即失败
+	AppErrorTargetDiskFull = "TARGET_DISK_FULL"
 )
+
+// 应用运行状态常量
+const (
+	AppRuntimeRunning   = "running"
+	AppRuntimeHealthy   = "healthy"
+	AppRuntimeUnhealthy = "unhealthy"
+	AppRuntimeStarting  = "starting"
+	AppRuntimeExited    = "exited"
+)
+
+// AuthSession 通过外部身份提供方登录后的会话
+type AuthSession struct {
+	Token     string    `json:"-"`
+	ID        string    `json:"id"` // 会话列表和撤销使用的标识，不能用于登录
+	User      string    `json:"user"`
+	Provider  string    `json:"provider"` // oidc/ldap
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// LastSeen 最近一次通过该会话访问接口的时间，超过空闲时间后会话失效
+	LastSeen  time.Time `json:"last_seen"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// Current 是否为发起请求的会话，只在会话列表中设置
+	Current bool `json:"current,omitempty"`
+}
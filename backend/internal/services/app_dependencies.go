@@ -0,0 +1,308 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+
+	"gopkg.in/yaml.v2"
+)
+
+// appDependenciesOption 迁移选项：声明应用之间的依赖，如 {"nextcloud": ["mariadb"]}，被依赖的应用先导入和启动
+const appDependenciesOption = "app_dependencies"
+
+// hostTokenPattern 拆分环境变量值中的主机名，如 mysql://user@mariadb:3306/db 中的mariadb
+var hostTokenPattern = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// appDependencyOptions 从迁移选项中读取声明的应用依赖
+func appDependencyOptions(options map[string]interface{}) (map[string][]string, error) {
+	deps := make(map[string][]string)
+	value, ok := options[appDependenciesOption]
+	if !ok || value == nil {
+		return deps, nil
+	}
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid %s: must be an object mapping app names to the apps they depend on", appDependenciesOption)
+	}
+	for app, list := range raw {
+		items, ok := list.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Invalid %s: dependencies of %s must be a list of app names", appDependenciesOption, app)
+		}
+		for _, item := range items {
+			name, ok := item.(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("Invalid %s: dependencies of %s must be a list of app names", appDependenciesOption, app)
+			}
+			deps[app] = append(deps[app], name)
+		}
+	}
+	return deps, nil
+}
+
+// validateAppDependencyOptions 创建任务前检查应用依赖选项
+func validateAppDependencyOptions(options map[string]interface{}) error {
+	_, err := appDependencyOptions(options)
+	return err
+}
+
+// composeLinks 应用对外提供的名称（容器名、主机名、网络）和引用的其他应用的名称
+type composeLinks struct {
+	containers []string
+	networks   []string
+	refs       []string
+	netRefs    []string
+}
+
+// parseComposeLinks 读取compose中可能跨应用的名称：
+// 容器名和主机名、自己创建的网络，以及external_links、network_mode: container:、外部网络和环境变量中出现的主机名
+func parseComposeLinks(appName, content string) composeLinks {
+	var links composeLinks
+	doc, err := parseCompose(content)
+	if err != nil {
+		return links
+	}
+
+	for _, svc := range doc.Services() {
+		for _, key := range []string{"container_name", "hostname"} {
+			if value, ok := mapGet(svc.Config, key); ok {
+				if name, ok := value.(string); ok && name != "" {
+					links.containers = append(links.containers, name)
+				}
+			}
+		}
+		if value, ok := mapGet(svc.Config, "external_links"); ok {
+			if list, ok := value.([]interface{}); ok {
+				for _, item := range list {
+					links.refs = append(links.refs, strings.SplitN(fmt.Sprint(item), ":", 2)[0])
+				}
+			}
+		}
+		if value, ok := mapGet(svc.Config, "network_mode"); ok {
+			if mode, ok := value.(string); ok && strings.HasPrefix(mode, "container:") {
+				links.refs = append(links.refs, strings.TrimPrefix(mode, "container:"))
+			}
+		}
+		if value, ok := mapGet(svc.Config, "environment"); ok {
+			var values []string
+			switch env := value.(type) {
+			case []interface{}:
+				for _, item := range env {
+					if parts := strings.SplitN(fmt.Sprint(item), "=", 2); len(parts) == 2 {
+						values = append(values, parts[1])
+					}
+				}
+			case yaml.MapSlice:
+				for _, item := range env {
+					if item.Value != nil {
+						values = append(values, fmt.Sprint(item.Value))
+					}
+				}
+			}
+			for _, v := range values {
+				for _, token := range hostTokenPattern.Split(v, -1) {
+					if token != "" {
+						links.refs = append(links.refs, token)
+					}
+				}
+			}
+		}
+	}
+
+	// 外部网络引用其他应用创建的网络；未写name的网络按compose规则名为 <项目名>_<键>
+	value, _ := mapGet(doc.root, "networks")
+	networks, _ := value.(yaml.MapSlice)
+	for _, item := range networks {
+		key := fmt.Sprint(item.Key)
+		config, _ := item.Value.(yaml.MapSlice)
+		name := ""
+		if value, ok := mapGet(config, "name"); ok {
+			name, _ = value.(string)
+		}
+		external := false
+		if value, ok := mapGet(config, "external"); ok {
+			switch ext := value.(type) {
+			case bool:
+				external = ext
+			case yaml.MapSlice:
+				// 旧格式 external: {name: ...}
+				external = true
+				if value, ok := mapGet(ext, "name"); ok {
+					name, _ = value.(string)
+				}
+			}
+		}
+		if external {
+			if name == "" {
+				name = key
+			}
+			links.netRefs = append(links.netRefs, name)
+			continue
+		}
+		if name == "" {
+			name = appName + "_" + key
+		}
+		links.networks = append(links.networks, name)
+	}
+	return links
+}
+
+// detectAppDependencies 按compose中跨应用的容器名和网络引用检测应用之间的依赖
+func detectAppDependencies(composeFiles map[string]string) map[string][]string {
+	links := make(map[string]composeLinks, len(composeFiles))
+	containerOwner := make(map[string]string)
+	networkOwner := make(map[string]string)
+	for appName, content := range composeFiles {
+		l := parseComposeLinks(appName, content)
+		links[appName] = l
+		for _, name := range l.containers {
+			containerOwner[name] = appName
+		}
+		for _, name := range l.networks {
+			networkOwner[name] = appName
+		}
+	}
+
+	deps := make(map[string][]string)
+	for appName, l := range links {
+		seen := make(map[string]bool)
+		add := func(owner string) {
+			if owner != "" && owner != appName && !seen[owner] {
+				seen[owner] = true
+				deps[appName] = append(deps[appName], owner)
+			}
+		}
+		for _, ref := range l.refs {
+			add(containerOwner[ref])
+		}
+		for _, ref := range l.netRefs {
+			add(networkOwner[ref])
+		}
+		sort.Strings(deps[appName])
+	}
+	return deps
+}
+
+// orderApps 按依赖对应用拓扑排序，被依赖的应用在前，没有依赖关系的应用按名称排列
+// 循环依赖中的应用按名称排在最后并作为cyclic返回
+func orderApps(apps []string, deps map[string][]string) (order, cyclic []string) {
+	known := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		known[app] = true
+	}
+	pending := make(map[string]int, len(apps))
+	dependents := make(map[string][]string)
+	for _, app := range apps {
+		for _, dep := range deps[app] {
+			if known[dep] && dep != app {
+				pending[app]++
+				dependents[dep] = append(dependents[dep], app)
+			}
+		}
+	}
+
+	var ready []string
+	for _, app := range apps {
+		if pending[app] == 0 {
+			ready = append(ready, app)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		app := ready[0]
+		ready = ready[1:]
+		order = append(order, app)
+		for _, dependent := range dependents[app] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(apps) {
+		ordered := make(map[string]bool, len(order))
+		for _, app := range order {
+			ordered[app] = true
+		}
+		for _, app := range apps {
+			if !ordered[app] {
+				cyclic = append(cyclic, app)
+			}
+		}
+		sort.Strings(cyclic)
+		order = append(order, cyclic...)
+	}
+	return order, cyclic
+}
+
+// appImportOrder 合并声明和检测到的依赖，返回应用的导入和启动顺序，依赖和顺序记录到任务日志
+// 声明的应用名可以是重命名前的源应用名
+func (s *MigrationService) appImportOrder(task *models.MigrationTask, composeFiles map[string]string) []string {
+	apps := make([]string, 0, len(composeFiles))
+	for appName := range composeFiles {
+		apps = append(apps, appName)
+	}
+	sort.Strings(apps)
+
+	deps := detectAppDependencies(composeFiles)
+	for _, appName := range apps {
+		if list := deps[appName]; len(list) > 0 {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s depends on %s (detected from compose)", appName, strings.Join(list, ", ")))
+		}
+	}
+
+	declared, _ := appDependencyOptions(task.Options)
+	names, prefix, _ := appRenameOptions(task.Options)
+	resolve := func(name string) string {
+		if _, ok := composeFiles[name]; ok {
+			return name
+		}
+		if renamed, ok := names[name]; ok {
+			return renamed
+		}
+		return prefix + name
+	}
+	for app, list := range declared {
+		app = resolve(app)
+		if _, ok := composeFiles[app]; !ok {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Dependencies declared for unknown app %s are ignored", app))
+			continue
+		}
+		for _, dep := range list {
+			dep = resolve(dep)
+			if _, ok := composeFiles[dep]; !ok {
+				s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s depends on %s, which is not part of this migration", app, dep))
+				continue
+			}
+			if !containsString(deps[app], dep) {
+				deps[app] = append(deps[app], dep)
+			}
+		}
+	}
+
+	order, cyclic := orderApps(apps, deps)
+	if len(cyclic) > 0 {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Apps %s depend on each other and are imported in name order", strings.Join(cyclic, ", ")))
+	}
+	if len(deps) > 0 {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App import order: %s", strings.Join(order, ", ")))
+	}
+	return order
+}
+
+// orderedComposeApps 返回扫描时确定的应用导入顺序，没有时按名称排列
+func orderedComposeApps(sourceData map[string]interface{}, composeFiles map[string]string) []string {
+	if order, ok := sourceData["appOrder"].([]string); ok && len(order) == len(composeFiles) {
+		return order
+	}
+	apps := make([]string, 0, len(composeFiles))
+	for appName := range composeFiles {
+		apps = append(apps, appName)
+	}
+	sort.Strings(apps)
+	return apps
+}
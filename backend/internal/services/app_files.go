@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// composeFileName 应用目录中的主compose文件
+const composeFileName = "docker-compose.yml"
+
+// composeOverrideFileNames docker compose默认加载的覆盖文件，读取时合并到主compose中
+var composeOverrideFileNames = []string{"docker-compose.override.yml", "docker-compose.override.yaml"}
+
+// prepareAppFiles 将应用目录中compose之外的文件（.env、自定义配置等）上传到目标，并按迁移选项处理.env，返回要导入的compose内容
+// 上传失败只记录警告，不影响compose导入
+func (s *MigrationService) prepareAppFiles(ctx context.Context, task *models.MigrationTask, target TargetAdapter, appName, composeContent string, sourceData map[string]interface{}) string {
+	extractedPath, _ := sourceData["extractedPath"].(string)
+	appDir := filepath.Join(extractedPath, "var/lib/casaos/apps", appName)
+
+	files, err := listAppFiles(appDir)
+	if err == nil && len(files) > 0 {
+		err = target.UploadAppFiles(ctx, appName, appDir, files, task.ID)
+		if err == nil {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s: uploaded %d config files: %s", appName, len(files), strings.Join(files, ", ")))
+		} else {
+			log.Printf("[WARNING] App %s: failed to upload config files: %v", appName, err)
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to upload config files: %v", appName, err))
+		}
+	}
+
+	return s.applyAppEnvFile(task, appName, composeContent, appDir, err)
+}
+
+// listAppFiles 列出应用目录中除compose和覆盖文件之外的普通文件，返回以/分隔的相对路径
+func listAppFiles(appDir string) ([]string, error) {
+	skip := map[string]bool{composeFileName: true}
+	for _, name := range composeOverrideFileNames {
+		skip[name] = true
+	}
+
+	var files []string
+	err := filepath.WalkDir(appDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(appDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !skip[rel] {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergeComposeOverride 将应用目录中的覆盖文件合并到compose内容中，没有覆盖文件时原样返回
+// ZimaOS应用管理和按文件导入的目标只使用单个compose文件
+func mergeComposeOverride(appDir, composeContent string) (string, error) {
+	for _, name := range composeOverrideFileNames {
+		override, err := os.ReadFile(filepath.Join(appDir, name))
+		if err != nil {
+			continue
+		}
+
+		doc, err := parseCompose(composeContent)
+		if err != nil {
+			return composeContent, err
+		}
+		overrideDoc, err := parseCompose(string(override))
+		if err != nil {
+			return composeContent, fmt.Errorf("Failed to parse %s: %v", name, err)
+		}
+		doc.Merge(overrideDoc)
+		if composeContent, err = doc.String(); err != nil {
+			return "", err
+		}
+		log.Printf("[INFO] Merged %s into compose file of app %s", name, filepath.Base(appDir))
+	}
+	return composeContent, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// rsyncBytesSentPattern 匹配 rsync --stats 输出中的发送字节数
+var rsyncBytesSentPattern = regexp.MustCompile(`Total bytes sent:\s*([\d,.]+)`)
+
+// appMetricsKey 指标在appMetrics中的键
+func appMetricsKey(taskID, appName string) string {
+	return taskID + "/" + appName
+}
+
+// recordAppMetrics 在目标适配器上传应用数据时记录各阶段的指标
+// 指标暂存在服务中，由collectAppMetrics写入应用状态
+func (s *MigrationService) recordAppMetrics(taskID, appName string, update func(m *models.AppMetrics)) {
+	value, _ := s.appMetrics.LoadOrStore(appMetricsKey(taskID, appName), &models.AppMetrics{})
+	update(value.(*models.AppMetrics))
+}
+
+// collectAppMetrics 将暂存的指标合并到应用状态中
+func (s *MigrationService) collectAppMetrics(taskID string, status *models.AppImportStatus) {
+	value, ok := s.appMetrics.LoadAndDelete(appMetricsKey(taskID, status.AppName))
+	if !ok {
+		return
+	}
+	recorded := value.(*models.AppMetrics)
+	if status.Metrics == nil {
+		status.Metrics = &models.AppMetrics{}
+	}
+	// 重新上传时替换上次的数据传输指标
+	status.Metrics.BytesTransferred = recorded.BytesTransferred
+	status.Metrics.CompressMs = recorded.CompressMs
+	status.Metrics.UploadMs = recorded.UploadMs
+	status.Metrics.DecompressMs = recorded.DecompressMs
+}
+
+// recordComposeImportTime 在应用状态中记录compose导入的耗时
+func recordComposeImportTime(appStatuses []models.AppImportStatus, appName string, start time.Time) {
+	for i := range appStatuses {
+		if appStatuses[i].AppName != appName {
+			continue
+		}
+		if appStatuses[i].Metrics == nil {
+			appStatuses[i].Metrics = &models.AppMetrics{}
+		}
+		appStatuses[i].Metrics.ComposeImportMs = time.Since(start).Milliseconds()
+		return
+	}
+}
+
+// sumAppMetrics 合计所有应用的指标，没有任何指标时返回nil
+func sumAppMetrics(appStatuses []models.AppImportStatus) *models.AppMetrics {
+	var total *models.AppMetrics
+	for _, app := range appStatuses {
+		if app.Metrics == nil {
+			continue
+		}
+		if total == nil {
+			total = &models.AppMetrics{}
+		}
+		total.Add(app.Metrics)
+	}
+	return total
+}
+
+// parseRsyncBytesSent 从 rsync --stats 的输出中解析发送的字节数，解析失败时返回0
+func parseRsyncBytesSent(output string) int64 {
+	groups := rsyncBytesSentPattern.FindStringSubmatch(output)
+	if groups == nil {
+		return 0
+	}
+	digits := strings.NewReplacer(",", "", ".", "").Replace(groups[1])
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
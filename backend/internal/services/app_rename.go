@@ -0,0 +1,281 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// appNameMapOption 迁移选项：源应用名到目标应用名的映射，如 {"jellyfin": "jellyfin-casaos"}
+	appNameMapOption = "app_name_map"
+	// appNamePrefixOption 迁移选项：未在映射中的应用统一加上的前缀，如 "casa-"
+	appNamePrefixOption = "app_name_prefix"
+
+	// appRenameMarker 解压目录中记录已完成重命名的文件，恢复任务时不会重复加前缀
+	appRenameMarker = ".ctoz-app-names.json"
+)
+
+// appNamePattern 目标应用名（compose项目名）允许的格式
+var appNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// appRenameOptions 从迁移选项中读取应用重命名设置
+func appRenameOptions(options map[string]interface{}) (map[string]string, string, error) {
+	names := make(map[string]string)
+	if value, ok := options[appNameMapOption]; ok && value != nil {
+		raw, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("Invalid %s: must be an object mapping source app names to target names", appNameMapOption)
+		}
+		for from, to := range raw {
+			name, ok := to.(string)
+			if !ok || !appNamePattern.MatchString(name) {
+				return nil, "", fmt.Errorf("Invalid %s: target name for %s must use lowercase letters, digits, '-' and '_'", appNameMapOption, from)
+			}
+			names[from] = name
+		}
+	}
+
+	prefix, _ := options[appNamePrefixOption].(string)
+	if prefix != "" && !appNamePattern.MatchString(prefix) {
+		return nil, "", fmt.Errorf("Invalid %s: must use lowercase letters, digits, '-' and '_'", appNamePrefixOption)
+	}
+	return names, prefix, nil
+}
+
+// validateAppRenameOptions 创建任务前检查应用重命名选项
+func validateAppRenameOptions(options map[string]interface{}) error {
+	_, _, err := appRenameOptions(options)
+	return err
+}
+
+// renameApps 按迁移选项重命名解压目录中的应用，之后的步骤都使用新名称；蓝绿导入时所有应用再加上临时后缀
+// 同时移动应用目录和AppData目录，改写compose的项目名、容器名和指向AppData的bind挂载，以及属主清单中的路径
+func (s *MigrationService) renameApps(task *models.MigrationTask, extractedPath string) error {
+	names, prefix, err := appRenameOptions(task.Options)
+	suffix := ""
+	if blueGreen(task.Options) {
+		suffix = blueGreenSuffix
+	}
+	if err != nil || (len(names) == 0 && prefix == "" && suffix == "") {
+		return err
+	}
+	markerPath := filepath.Join(extractedPath, appRenameMarker)
+	if _, err := os.Stat(markerPath); err == nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, "Apps were already renamed in the extracted data")
+		return nil
+	}
+
+	appsDir := filepath.Join(extractedPath, "var/lib/casaos/apps")
+	entries, err := os.ReadDir(appsDir)
+	if err != nil {
+		return fmt.Errorf("Failed to read apps directory: %v", err)
+	}
+
+	// 先确定全部新名称并检查冲突，避免重命名到一半失败
+	existing := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			existing[entry.Name()] = true
+		}
+	}
+	renames := make(map[string]string)
+	taken := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		from := entry.Name()
+		to, ok := names[from]
+		if !ok {
+			to = prefix + from
+		}
+		to += suffix
+		if other, ok := taken[to]; ok {
+			return fmt.Errorf("Apps %s and %s would both be renamed to %s", other, from, to)
+		}
+		taken[to] = from
+		if to != from {
+			renames[from] = to
+		}
+	}
+	for from, to := range renames {
+		if existing[to] {
+			if _, renamed := renames[to]; !renamed {
+				return fmt.Errorf("Cannot rename app %s to %s: an app with that name is already in the import", from, to)
+			}
+		}
+	}
+	for from := range names {
+		if !existing[from] {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s in %s was not found", from, appNameMapOption))
+		}
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	// 经由临时名称移动，支持互换名称的映射
+	appDataDir := filepath.Join(extractedPath, "DATA/AppData")
+	sources := make([]string, 0, len(renames))
+	for from := range renames {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+	for _, dir := range []string{appsDir, appDataDir} {
+		for _, from := range sources {
+			if err := renameIfExists(filepath.Join(dir, from), filepath.Join(dir, ".ctoz-rename-"+from)); err != nil {
+				return err
+			}
+		}
+		for _, from := range sources {
+			if err := renameIfExists(filepath.Join(dir, ".ctoz-rename-"+from), filepath.Join(dir, renames[from])); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 镜像包按应用名命名，同样经由临时名称移动
+	imagesDir := filepath.Join(extractedPath, exportImagesDir)
+	for _, from := range sources {
+		if err := renameIfExists(filepath.Join(imagesDir, from+".tar"), filepath.Join(imagesDir, ".ctoz-rename-"+from+".tar")); err != nil {
+			return err
+		}
+	}
+	for _, from := range sources {
+		if err := renameIfExists(filepath.Join(imagesDir, ".ctoz-rename-"+from+".tar"), filepath.Join(imagesDir, renames[from]+".tar")); err != nil {
+			return err
+		}
+	}
+
+	for _, from := range sources {
+		to := renames[from]
+		if err := renameComposeApp(filepath.Join(appsDir, to, composeFileName), from, to); err != nil {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to update compose file for new name %s: %v", from, to, err))
+		}
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s will be imported as %s", from, to))
+	}
+
+	if err := renameOwnershipPaths(extractedPath, renames); err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to update ownership manifest for renamed apps: %v", err))
+	}
+
+	if suffix != "" {
+		staged := make(map[string]string, len(renames))
+		for _, to := range renames {
+			staged[to] = strings.TrimSuffix(to, suffix)
+		}
+		s.recordStagedApps(task.ID, staged)
+	}
+
+	data, err := json.Marshal(renames)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize renamed apps: %v", err)
+	}
+	return os.WriteFile(markerPath, data, 0644)
+}
+
+// renameIfExists 移动文件或目录，源不存在时忽略
+func renameIfExists(from, to string) error {
+	if _, err := os.Lstat(from); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("Failed to rename %s: %v", filepath.Base(from), err)
+	}
+	return nil
+}
+
+// renameComposeApp 改写重命名应用的compose：项目名、与应用同名的容器名和指向AppData的bind挂载
+func renameComposeApp(composePath, from, to string) error {
+	content, err := os.ReadFile(composePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rewritten, err := renameComposeContent(string(content), from, to, "/DATA/AppData")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(composePath, []byte(rewritten), 0644)
+}
+
+// renameComposeContent 改写compose内容中的应用名，appDataDir为bind挂载中AppData的基础目录
+func renameComposeContent(content, from, to, appDataDir string) (string, error) {
+	doc, err := parseCompose(content)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := mapGet(doc.root, "name"); ok {
+		mapSet(&doc.root, "name", to)
+	}
+	for _, svc := range doc.Services() {
+		if name, _ := mapGet(svc.Config, "container_name"); name == from {
+			mapSet(&svc.Config, "container_name", to)
+			doc.SetService(svc.Name, svc.Config)
+		}
+	}
+
+	oldDir := path.Join(appDataDir, from)
+	doc.RewriteBindSources(func(service, source string) string {
+		if source == oldDir || strings.HasPrefix(source, oldDir+"/") {
+			return path.Join(appDataDir, to) + strings.TrimPrefix(source, oldDir)
+		}
+		return source
+	})
+	return doc.String()
+}
+
+// renameOwnershipPaths 将属主清单中重命名应用的AppData路径改为新名称
+func renameOwnershipPaths(extractedPath string, renames map[string]string) error {
+	manifestPath := filepath.Join(extractedPath, ownershipManifestName)
+	data, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []fileAttr
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].Path = renamedAppPath(entries[i].Path, renames)
+	}
+
+	data, err = json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath, data, 0600)
+}
+
+// renamedAppPath 返回重命名后应用目录或AppData目录中条目的路径，其他路径原样返回
+func renamedAppPath(relPath string, renames map[string]string) string {
+	for _, dir := range []string{"DATA/AppData/", "var/lib/casaos/apps/"} {
+		if !strings.HasPrefix(relPath, dir) {
+			continue
+		}
+		rest := strings.TrimPrefix(relPath, dir)
+		name, tail := rest, ""
+		if i := strings.Index(rest, "/"); i >= 0 {
+			name, tail = rest[:i], rest[i:]
+		}
+		if to, ok := renames[name]; ok {
+			return dir + to + tail
+		}
+	}
+	return relPath
+}
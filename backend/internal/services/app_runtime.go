@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// autoStartOption 迁移选项：导入后启动应用并等待容器运行
+	autoStartOption = "auto_start"
+	// appStartTimeout 等待应用容器运行或健康的最长时间
+	appStartTimeout = 5 * time.Minute
+	// appStartPollInterval 查询容器状态的间隔
+	appStartPollInterval = 5 * time.Second
+)
+
+// appContainer 应用容器的运行状态
+type appContainer struct {
+	Name   string
+	State  string // created/running/restarting/exited/dead...
+	Health string // healthy/unhealthy/starting，未配置健康检查时为空
+}
+
+// startImportedApps 开启auto_start时按导入顺序启动compose导入成功的应用，被依赖的应用先启动并等待运行，并将每个应用的运行状态写入任务结果
+// 启动失败只记录在应用状态中，不影响导入结果
+func (s *MigrationService) startImportedApps(ctx context.Context, task *models.MigrationTask, target TargetAdapter, appStatuses []models.AppImportStatus) {
+	if autoStart, ok := task.Options[autoStartOption].(bool); !ok || !autoStart {
+		return
+	}
+
+	err := s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Start imported apps", func(ctx context.Context, progressCallback func(int, string)) error {
+		var imported []int
+		for i := range appStatuses {
+			if appStatuses[i].ComposeStatus == models.AppStatusSuccess {
+				imported = append(imported, i)
+			}
+		}
+		if len(imported) == 0 {
+			progressCallback(100, "No imported apps to start")
+			return nil
+		}
+
+		for n, i := range imported {
+			appName := appStatuses[i].AppName
+			progressCallback(100*n/len(imported), fmt.Sprintf("Starting %s (%d/%d)...", appName, n+1, len(imported)))
+
+			status, err := target.StartApp(ctx, appName, task.ID)
+			appStatuses[i].RuntimeStatus = status
+			appStatuses[i].RuntimeMessage = ""
+			if err != nil {
+				log.Printf("[WARNING] App %s failed to start: %v", appName, err)
+				appStatuses[i].RuntimeMessage = err.Error()
+				s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s failed to start: %v", appName, err))
+			} else {
+				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s is %s ✓", appName, status))
+			}
+			s.saveAppImportStatuses(task.ID, appStatuses)
+		}
+
+		progressCallback(100, "Imported apps started")
+		return nil
+	})
+	if err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to start imported apps: %v", err))
+	}
+}
+
+// waitForAppRunning 轮询容器状态，直到全部运行（配置了健康检查时为健康）、有容器退出、超时或ctx取消
+func waitForAppRunning(ctx context.Context, listContainers func() ([]appContainer, error)) (string, error) {
+	deadline := time.Now().Add(appStartTimeout)
+	status := models.AppRuntimeStarting
+	for {
+		containers, err := listContainers()
+		if err == nil {
+			var done bool
+			status, done = summarizeContainers(containers)
+			if done {
+				if status == models.AppRuntimeExited {
+					return status, fmt.Errorf("Containers exited after start: %s", describeContainers(containers))
+				}
+				return status, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return status, fmt.Errorf("Failed to get container status: %v", err)
+			}
+			return status, fmt.Errorf("Containers not ready after %s: %s", appStartTimeout, describeContainers(containers))
+		}
+		if !sleepContext(ctx, appStartPollInterval) {
+			return status, ctx.Err()
+		}
+	}
+}
+
+// summarizeContainers 汇总应用的容器状态，done表示不需要继续等待
+func summarizeContainers(containers []appContainer) (status string, done bool) {
+	if len(containers) == 0 {
+		return models.AppRuntimeStarting, false
+	}
+
+	hasHealth, starting, unhealthy := false, false, false
+	for _, c := range containers {
+		switch strings.ToLower(c.State) {
+		case "running":
+		case "exited", "dead":
+			return models.AppRuntimeExited, true
+		default:
+			starting = true
+		}
+		switch strings.ToLower(c.Health) {
+		case "healthy":
+			hasHealth = true
+		case "unhealthy":
+			hasHealth, unhealthy = true, true
+		case "starting":
+			hasHealth, starting = true, true
+		}
+	}
+
+	switch {
+	case starting:
+		return models.AppRuntimeStarting, false
+	case unhealthy:
+		// 健康检查可能在启动期之后恢复，超时前继续等待
+		return models.AppRuntimeUnhealthy, false
+	case hasHealth:
+		return models.AppRuntimeHealthy, true
+	}
+	return models.AppRuntimeRunning, true
+}
+
+// describeContainers 生成容器状态说明，用于日志和错误信息
+func describeContainers(containers []appContainer) string {
+	if len(containers) == 0 {
+		return "no containers"
+	}
+	parts := make([]string, 0, len(containers))
+	for _, c := range containers {
+		state := c.State
+		if c.Health != "" {
+			state += "/" + c.Health
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", c.Name, state))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// healthFromStatus 从Docker状态描述（如 "Up 5 minutes (healthy)"）中解析健康状态
+func healthFromStatus(status string) string {
+	status = strings.ToLower(status)
+	switch {
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "health: starting"):
+		return "starting"
+	}
+	return ""
+}
+
+// parseComposePS 解析 docker compose ps --format json 的输出
+// 新版本每行一个JSON对象，旧版本输出一个JSON数组
+func parseComposePS(output []byte) ([]appContainer, error) {
+	type psEntry struct {
+		Name   string `json:"Name"`
+		State  string `json:"State"`
+		Health string `json:"Health"`
+		Status string `json:"Status"`
+	}
+
+	var entries []psEntry
+	trimmed := strings.TrimSpace(string(output))
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal([]byte(trimmed), &entries); err != nil {
+			return nil, fmt.Errorf("Failed to parse docker compose ps output: %v", err)
+		}
+	} else {
+		for _, line := range strings.Split(trimmed, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			var entry psEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				return nil, fmt.Errorf("Failed to parse docker compose ps output: %v", err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	containers := make([]appContainer, 0, len(entries))
+	for _, entry := range entries {
+		health := entry.Health
+		if health == "" {
+			health = healthFromStatus(entry.Status)
+		}
+		containers = append(containers, appContainer{Name: entry.Name, State: entry.State, Health: health})
+	}
+	return containers, nil
+}
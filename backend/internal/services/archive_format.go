@@ -0,0 +1,245 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// tarBlockSize tar头部块的大小
+const tarBlockSize = 512
+
+// importFileSuffixes 支持导入的文件扩展名，复合扩展名排在前面
+var importFileSuffixes = []string{".tar.gz", ".tar.xz", ".txz", ".tar", ".zip", ".7z"}
+
+// IsSupportedImportFile 根据文件名判断是否为支持导入的压缩包
+func IsSupportedImportFile(filename string) bool {
+	name := strings.ToLower(filename)
+	for _, suffix := range importFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// openTarStream 打开tar格式的压缩包，返回解压后的tar数据流
+// format为detectFileFormat返回的格式：gzip、xz或未压缩的tar
+func openTarStream(src, format string) (io.ReadCloser, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open source file: %v", err)
+	}
+
+	switch format {
+	case "tar":
+		return file, nil
+	case "gzip":
+		gzReader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("Failed to create gzip reader: %v", err)
+		}
+		return &tarStream{Reader: gzReader, closers: []io.Closer{gzReader, file}}, nil
+	case "xz":
+		xz, err := newXzReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &tarStream{Reader: xz, closers: []io.Closer{xz, file}}, nil
+	}
+	file.Close()
+	return nil, fmt.Errorf("Unsupported tar compression: %s", format)
+}
+
+// tarStream 解压后的数据流，关闭时依次关闭解压器和源文件
+type tarStream struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close 关闭解压器和源文件
+func (t *tarStream) Close() error {
+	var firstErr error
+	for _, c := range t.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// xzReader 通过xz命令解压数据，标准库没有xz解码器
+type xzReader struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+	done   bool
+	err    error
+}
+
+// newXzReader 启动xz进程解压r中的数据
+func newXzReader(r io.Reader) (*xzReader, error) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		return nil, fmt.Errorf("xz command not found, it is required to import .tar.xz archives")
+	}
+
+	x := &xzReader{cmd: exec.Command("xz", "-dc")}
+	x.cmd.Stdin = r
+	x.cmd.Stderr = &x.stderr
+	stdout, err := x.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create xz output pipe: %v", err)
+	}
+	x.stdout = stdout
+	if err := x.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Failed to start xz: %v", err)
+	}
+	return x, nil
+}
+
+// Read 读取解压后的数据，xz异常退出时在数据结束处返回其错误
+func (x *xzReader) Read(p []byte) (int, error) {
+	n, err := x.stdout.Read(p)
+	if err == io.EOF {
+		if waitErr := x.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close 结束xz进程，未读完时直接终止
+func (x *xzReader) Close() error {
+	if !x.done && x.cmd.Process != nil {
+		x.cmd.Process.Kill()
+		x.wait()
+		return nil
+	}
+	return x.err
+}
+
+// wait 等待xz进程退出并记录错误
+func (x *xzReader) wait() error {
+	if x.done {
+		return x.err
+	}
+	x.done = true
+	if err := x.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(x.stderr.String()); msg != "" {
+			x.err = fmt.Errorf("Failed to decompress xz data: %s", msg)
+		} else {
+			x.err = fmt.Errorf("Failed to decompress xz data: %v", err)
+		}
+	}
+	return x.err
+}
+
+// DetectArchiveFormat 根据文件头判断压缩包格式，无法识别时返回unknown
+func DetectArchiveFormat(header []byte) string {
+	switch {
+	case len(header) >= 2 && header[0] == 0x50 && header[1] == 0x4B:
+		return "zip"
+	case len(header) >= 2 && header[0] == 0x1F && header[1] == 0x8B:
+		return "gzip"
+	case bytes.HasPrefix(header, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}):
+		return "xz"
+	case bytes.HasPrefix(header, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}):
+		return "7z"
+	case len(header) >= 262 && string(header[257:262]) == "ustar", isTarHeader(header):
+		// POSIX和GNU格式的tar在第257字节处都有ustar标识，更早的格式只能通过头部校验和识别
+		return "tar"
+	}
+	return "unknown"
+}
+
+// tarFormatName 日志和错误信息中使用的tar压缩包名称
+func tarFormatName(format string) string {
+	switch format {
+	case "gzip":
+		return "tar.gz"
+	case "xz":
+		return "tar.xz"
+	}
+	return "tar"
+}
+
+// isTarHeader 根据头部校验和判断数据块是否为tar头部
+// 校验和为头部所有字节之和，计算时校验和字段按空格计
+func isTarHeader(block []byte) bool {
+	if len(block) < tarBlockSize {
+		return false
+	}
+	field := strings.Trim(string(block[148:156]), " \x00")
+	expected, err := strconv.ParseInt(field, 8, 64)
+	if err != nil {
+		return false
+	}
+
+	// 历史实现中有按有符号字节计算的，两种都接受
+	var unsigned, signed int64
+	for i, b := range block[:tarBlockSize] {
+		if i >= 148 && i < 156 {
+			b = ' '
+		}
+		unsigned += int64(b)
+		signed += int64(int8(b))
+	}
+	return expected == unsigned || expected == signed
+}
+
+// peekTarHeader 检查解压后的数据是否以tar头部开始，r的读取位置不变
+// 例如单个 .sql.gz 文件是合法的gzip，但不是tar包，直接解压会得到难以理解的错误
+func peekTarHeader(r *bufio.Reader, format string) error {
+	block, err := r.Peek(tarBlockSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return fmt.Errorf("Failed to read %s data: %v", format, err)
+	}
+	if !isTarHeader(block) {
+		return fmt.Errorf("Archive is %s-compressed but not a tar archive", format)
+	}
+	return nil
+}
+
+// CheckTarStream 检查gzip或xz压缩包解压后是否为tar包，只读取第一个头部
+func CheckTarStream(src, format string) error {
+	stream, err := openTarStream(src, format)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return peekTarHeader(bufio.NewReaderSize(stream, tarBlockSize), format)
+}
+
+// CheckZipArchive 检查ZIP中央目录是否完整，以及每个条目的数据是否在文件范围内
+// 上传中断的ZIP文件缺少位于末尾的中央目录，无法解压
+func CheckZipArchive(src string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("Failed to get file info: %v", err)
+	}
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("ZIP central directory is missing or corrupted: %v", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return fmt.Errorf("ZIP entry %s has an invalid local header: %v", f.Name, err)
+		}
+		if uint64(offset)+f.CompressedSize64 > uint64(info.Size()) {
+			return fmt.Errorf("ZIP entry %s extends beyond the end of the file", f.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// layoutAppsDir 导入包中CasaOS应用定义所在目录
+	layoutAppsDir = "var/lib/casaos/apps"
+	// layoutAppDataDir 导入包中应用数据所在目录
+	layoutAppDataDir = "DATA/AppData"
+	// layoutMaxTopLevel 结构检查结果中最多列出的根目录条目数
+	layoutMaxTopLevel = 20
+)
+
+// layoutScanner 根据条目名称统计压缩包的目录结构
+type layoutScanner struct {
+	layout   models.ArchiveLayout
+	topLevel map[string]bool
+}
+
+// InspectImportLayout 检查导入压缩包的目录结构，结构无效时返回 *models.ArchiveLayoutError
+// tar类压缩包需要顺序读取，找到应用定义和AppData后即停止，不会读完整个文件
+func InspectImportLayout(src string) (*models.ArchiveLayout, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open file: %v", err)
+	}
+	header := make([]byte, tarBlockSize)
+	n, err := io.ReadFull(file, header)
+	file.Close()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("Failed to read file header: %v", err)
+	}
+
+	scanner := &layoutScanner{topLevel: make(map[string]bool)}
+	scanner.layout.Format = DetectArchiveFormat(header[:n])
+
+	switch scanner.layout.Format {
+	case "zip":
+		err = scanner.scanZip(src)
+	case "gzip", "xz", "tar":
+		err = scanner.scanTar(src, scanner.layout.Format)
+	case "7z":
+		err = scanner.scanSevenZip(src)
+	default:
+		return nil, fmt.Errorf("Unsupported file format: %s", scanner.layout.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &scanner.layout
+	return layout, checkImportLayout(layout)
+}
+
+// scanZip 读取ZIP中央目录中的条目名称，增量导出的结构要与之前的导出叠加后才能判断
+func (l *layoutScanner) scanZip(src string) error {
+	manifest, err := readExportManifest(src)
+	if err != nil {
+		return err
+	}
+	if manifest != nil && manifest.Type == exportTypeIncremental {
+		l.layout.Incremental = true
+	}
+
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("Failed to open ZIP file: %v", err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if l.add(f.Name) {
+			break
+		}
+	}
+	return nil
+}
+
+// scanTar 顺序读取tar头部，条目内容由tar reader跳过
+func (l *layoutScanner) scanTar(src, format string) error {
+	stream, err := openTarStream(src, format)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	tarReader := tar.NewReader(stream)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read tar entry: %v", err)
+		}
+		if l.add(header.Name) {
+			return nil
+		}
+	}
+}
+
+// scanSevenZip 通过7-Zip列出条目名称
+func (l *layoutScanner) scanSevenZip(src string) error {
+	bin, err := findSevenZip()
+	if err != nil {
+		return err
+	}
+	entries, err := listSevenZip(bin, src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if l.add(entry.Path) {
+			break
+		}
+	}
+	return nil
+}
+
+// add 统计一个条目，已找到应用定义和AppData时返回true
+func (l *layoutScanner) add(name string) bool {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if name == "" {
+		return false
+	}
+
+	top := strings.SplitN(name, "/", 2)[0]
+	if !l.topLevel[top] && len(l.layout.TopLevel) < layoutMaxTopLevel {
+		l.topLevel[top] = true
+		l.layout.TopLevel = append(l.layout.TopLevel, top)
+	}
+
+	// 应用定义：<前缀>var/lib/casaos/apps/<app>/docker-compose.yml
+	if idx := strings.Index("/"+name, "/"+layoutAppsDir+"/"); idx >= 0 {
+		prefix := strings.TrimSuffix(name[:idx], "/")
+		rest := strings.Split(name[idx+len(layoutAppsDir)+1:], "/")
+		if len(rest) == 2 && rest[1] == composeFileName {
+			if prefix == "" {
+				l.layout.HasApps = true
+			} else if l.layout.Prefix == "" {
+				l.layout.Prefix = prefix
+			}
+		}
+	} else if isSynologyComposeName(path.Base(name)) {
+		l.layout.Synology = true
+	}
+
+	if name == layoutAppDataDir || strings.HasPrefix(name, layoutAppDataDir+"/") {
+		l.layout.HasAppData = true
+	}
+	return l.layout.HasApps && l.layout.HasAppData
+}
+
+// isSynologyComposeName 判断文件名是否为Synology项目的compose文件名
+func isSynologyComposeName(name string) bool {
+	for _, composeName := range synologyComposeNames {
+		if name == composeName {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImportLayout 判断目录结构能否导入，无效时说明找到的和期望的结构
+func checkImportLayout(layout *models.ArchiveLayout) error {
+	if layout.HasApps || layout.Synology || layout.Incremental {
+		return nil
+	}
+
+	layout.Expected = []string{layoutAppsDir + "/<app>/" + composeFileName, layoutAppDataDir + "/<app>"}
+	var reason string
+	switch {
+	case layout.Prefix != "":
+		reason = fmt.Sprintf("CasaOS directories were found under %s/, but they must be at the root of the archive", layout.Prefix)
+	case layout.HasAppData:
+		reason = fmt.Sprintf("Archive contains %s but no app definitions in %s", layoutAppDataDir, layoutAppsDir)
+	case len(layout.TopLevel) == 0:
+		reason = "Archive is empty"
+	default:
+		reason = fmt.Sprintf("Archive does not contain %s or %s, found: %s", layoutAppsDir, layoutAppDataDir, strings.Join(layout.TopLevel, ", "))
+	}
+	return &models.ArchiveLayoutError{Layout: layout, Reason: reason}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// AuthProviderOIDC 通过OIDC登录
+	AuthProviderOIDC = "oidc"
+	// AuthProviderLDAP 通过LDAP绑定登录
+	AuthProviderLDAP = "ldap"
+
+	// oidcLoginTimeout 从跳转到身份提供方到回调的最长时间
+	oidcLoginTimeout = 10 * time.Minute
+)
+
+// AuthService 登录服务，将登录委托给外部OIDC或LDAP，并管理登录会话
+// 未配置任何登录方式时不启用，所有接口保持开放
+type AuthService struct {
+	cfg         config.AuthConfig
+	oidc        *oidcProvider
+	ldap        *ldapAuthenticator
+	mu          sync.Mutex
+	sessions    map[string]*models.AuthSession // 会话令牌 -> 会话
+	oidcPending map[string]*oidcLogin          // state -> 进行中的OIDC登录
+}
+
+// NewAuthService 创建登录服务
+func NewAuthService(cfg *config.Config) *AuthService {
+	s := &AuthService{
+		cfg:         cfg.Auth,
+		sessions:    make(map[string]*models.AuthSession),
+		oidcPending: make(map[string]*oidcLogin),
+	}
+	if cfg.Auth.OIDC.Enabled() {
+		s.oidc = newOIDCProvider(cfg.Auth.OIDC, cfg.PublicURL, newRetryClient(cfg.Timeouts.Connect, cfg.Retry))
+		log.Printf("[INFO] OIDC login enabled (issuer: %s)", cfg.Auth.OIDC.Issuer)
+	}
+	if cfg.Auth.LDAP.Enabled() {
+		s.ldap = &ldapAuthenticator{cfg: cfg.Auth.LDAP, timeout: cfg.Timeouts.Connect}
+		log.Printf("[INFO] LDAP login enabled (%s)", cfg.Auth.LDAP.URL)
+	}
+	return s
+}
+
+// Enabled 是否需要登录
+func (s *AuthService) Enabled() bool {
+	return s.oidc != nil || s.ldap != nil
+}
+
+// Providers 已启用的登录方式
+func (s *AuthService) Providers() []string {
+	providers := make([]string, 0, 2)
+	if s.oidc != nil {
+		providers = append(providers, AuthProviderOIDC)
+	}
+	if s.ldap != nil {
+		providers = append(providers, AuthProviderLDAP)
+	}
+	return providers
+}
+
+// StartOIDCLogin 开始OIDC登录，返回身份提供方的授权地址
+// returnTo为登录完成后返回的页面，只接受本站的相对路径
+func (s *AuthService) StartOIDCLogin(returnTo string) (string, error) {
+	if s.oidc == nil {
+		return "", models.ErrAuthProviderDisabled
+	}
+
+	login := &oidcLogin{
+		nonce:    randomToken(16),
+		verifier: randomToken(32),
+		returnTo: safeReturnPath(returnTo),
+		expires:  time.Now().Add(oidcLoginTimeout),
+	}
+	state := randomToken(16)
+	authURL, err := s.oidc.authCodeURL(state, login.nonce, login.verifier)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.oidcPending[state] = login
+	return authURL, nil
+}
+
+// FinishOIDCLogin 处理身份提供方的回调，校验ID令牌后创建会话，返回会话和登录后返回的页面
+func (s *AuthService) FinishOIDCLogin(state, code string) (*models.AuthSession, string, error) {
+	if s.oidc == nil {
+		return nil, "", models.ErrAuthProviderDisabled
+	}
+
+	s.mu.Lock()
+	login, ok := s.oidcPending[state]
+	delete(s.oidcPending, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, "", fmt.Errorf("Login request expired or unknown, please sign in again")
+	}
+
+	user, err := s.oidc.exchange(code, login.nonce, login.verifier)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.createSession(user, AuthProviderOIDC), login.returnTo, nil
+}
+
+// LDAPLogin 使用用户名和密码在LDAP服务器上绑定，成功时创建会话
+func (s *AuthService) LDAPLogin(username, password string) (*models.AuthSession, error) {
+	if s.ldap == nil {
+		return nil, models.ErrAuthProviderDisabled
+	}
+	if err := s.ldap.authenticate(username, password); err != nil {
+		return nil, err
+	}
+	return s.createSession(username, AuthProviderLDAP), nil
+}
+
+// ValidateSession 校验会话令牌，返回会话对应的用户名；会话过期或空闲超时时删除会话
+func (s *AuthService) ValidateSession(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return "", false
+	}
+	if s.expiredLocked(session, time.Now()) {
+		delete(s.sessions, token)
+		log.Printf("[INFO] Session %s of user %s expired", session.ID, session.User)
+		return "", false
+	}
+	return session.User, true
+}
+
+// TouchSession 记录会话的最近访问时间和客户端，由登录中间件在每个通过校验的请求上调用
+func (s *AuthService) TouchSession(token, clientIP, userAgent string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[token]; ok {
+		session.LastSeen = time.Now()
+		session.ClientIP = clientIP
+		session.UserAgent = userAgent
+	}
+}
+
+// ListSessions 按登录时间列出与currentToken同一用户的有效会话，currentToken对应的会话标记为current
+func (s *AuthService) ListSessions(currentToken string) ([]models.AuthSession, error) {
+	if !s.Enabled() {
+		return nil, models.ErrAuthProviderDisabled
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+
+	owner, ok := s.sessions[currentToken]
+	if !ok {
+		return nil, models.ErrSessionNotFound
+	}
+	sessions := make([]models.AuthSession, 0, len(s.sessions))
+	for token, session := range s.sessions {
+		if !sameUser(session, owner) {
+			continue
+		}
+		entry := *session
+		entry.Current = token == currentToken
+		sessions = append(sessions, entry)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession 按会话标识删除currentToken所属用户的会话，被撤销的浏览器下次请求时需要重新登录
+// 其它用户的会话按不存在处理，不暴露其是否存在
+func (s *AuthService) RevokeSession(currentToken, id string) error {
+	if !s.Enabled() {
+		return models.ErrAuthProviderDisabled
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.sessions[currentToken]
+	if !ok {
+		return models.ErrSessionNotFound
+	}
+	for token, session := range s.sessions {
+		if session.ID == id && sameUser(session, owner) {
+			delete(s.sessions, token)
+			log.Printf("[INFO] Session %s of user %s revoked", session.ID, session.User)
+			return nil
+		}
+	}
+	return models.ErrSessionNotFound
+}
+
+// RevokeOtherSessions 删除currentToken所属用户的其它会话，返回删除的数量
+func (s *AuthService) RevokeOtherSessions(currentToken string) (int, error) {
+	if !s.Enabled() {
+		return 0, models.ErrAuthProviderDisabled
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.sessions[currentToken]
+	if !ok {
+		return 0, models.ErrSessionNotFound
+	}
+	count := 0
+	for token, session := range s.sessions {
+		if token != currentToken && sameUser(session, owner) {
+			delete(s.sessions, token)
+			count++
+		}
+	}
+	log.Printf("[INFO] Revoked %d other sessions of user %s", count, owner.User)
+	return count, nil
+}
+
+// sameUser 判断两个会话是否属于同一用户，不同登录方式的同名用户视为不同用户
+func sameUser(a, b *models.AuthSession) bool {
+	return a.User == b.User && a.Provider == b.Provider
+}
+
+// Logout 删除会话
+func (s *AuthService) Logout(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+}
+
+// createSession 为登录成功的用户创建会话
+func (s *AuthService) createSession(user, provider string) *models.AuthSession {
+	now := time.Now()
+	session := &models.AuthSession{
+		Token:     randomToken(32),
+		ID:        randomToken(8),
+		User:      user,
+		Provider:  provider,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.SessionTTL),
+		LastSeen:  now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.sessions[session.Token] = session
+	log.Printf("[INFO] User %s signed in via %s", user, provider)
+	return session
+}
+
+// expiredLocked 会话是否已过期或空闲超时，调用时需持有锁
+func (s *AuthService) expiredLocked(session *models.AuthSession, now time.Time) bool {
+	if now.After(session.ExpiresAt) {
+		return true
+	}
+	return s.cfg.SessionIdleTimeout > 0 && now.Sub(session.LastSeen) > s.cfg.SessionIdleTimeout
+}
+
+// pruneLocked 删除过期或空闲超时的会话和未完成的OIDC登录，调用时需持有锁
+func (s *AuthService) pruneLocked() {
+	now := time.Now()
+	for token, session := range s.sessions {
+		if s.expiredLocked(session, now) {
+			delete(s.sessions, token)
+		}
+	}
+	for state, login := range s.oidcPending {
+		if now.After(login.expires) {
+			delete(s.oidcPending, state)
+		}
+	}
+}
+
+// randomToken 生成n字节的随机十六进制字符串
+func randomToken(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// safeReturnPath 只允许以单个/开头的站内路径，防止登录后跳转到其它站点
+func safeReturnPath(p string) string {
+	if len(p) == 0 || p[0] != '/' || (len(p) > 1 && (p[1] == '/' || p[1] == '\\')) {
+		return "/"
+	}
+	return p
+}
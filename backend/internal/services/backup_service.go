@@ -0,0 +1,514 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// backupJobOption 导出任务选项中记录所属定时备份任务的键
+	backupJobOption = "backup_job"
+	// minBackupInterval 定时备份的最小间隔
+	minBackupInterval = 15 * time.Minute
+	// backupCheckInterval 检查到期备份的间隔
+	backupCheckInterval = time.Minute
+	// backupFilePrefix 备份压缩包文件名前缀
+	backupFilePrefix = "backup_"
+	// backupTimeLayout 备份压缩包文件名中的时间格式
+	backupTimeLayout = "20060102_150405"
+)
+
+// defaultBackupRetention 未设置保留规则时使用：保留7天内每天和4周内每周的最新备份
+var defaultBackupRetention = models.BackupRetention{KeepDaily: 7, KeepWeekly: 4}
+
+// BackupService 定时备份服务，按间隔为CasaOS源系统创建导出任务
+type BackupService struct {
+	connService      *ConnectionService
+	migrationService *MigrationService
+	taskService      *TaskService
+	mutex            sync.Mutex
+}
+
+// NewBackupService 创建定时备份服务，备份任务与任务一起保存在状态文件中
+func NewBackupService(connService *ConnectionService, migrationService *MigrationService, taskService *TaskService) *BackupService {
+	return &BackupService{
+		connService:      connService,
+		migrationService: migrationService,
+		taskService:      taskService,
+	}
+}
+
+// Start 在后台定期执行到期的备份任务
+func (s *BackupService) Start() {
+	go func() {
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.runDueJobs()
+		}
+	}()
+}
+
+// ListJobs 列出定时备份任务，按创建时间排序
+func (s *BackupService) ListJobs() []*models.BackupJob {
+	jobs := s.taskService.store.GetAllBackupJobs()
+	for _, job := range jobs {
+		s.refreshLastStatus(job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// GetJob 获取定时备份任务
+func (s *BackupService) GetJob(jobID string) (*models.BackupJob, error) {
+	job, err := s.taskService.store.GetBackupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshLastStatus(job)
+	return job, nil
+}
+
+// CreateJob 创建定时备份任务
+func (s *BackupService) CreateJob(req *models.BackupJobRequest) (*models.BackupJob, error) {
+	source, err := s.resolveSource(req)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, fmt.Errorf("Either connection_id or source is required")
+	}
+	interval, err := parseBackupInterval(req.Interval)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBackupDestination(req.Destination); err != nil {
+		return nil, err
+	}
+	if err := validateBackupRetention(req.Retention); err != nil {
+		return nil, err
+	}
+	if req.FullEvery != nil && *req.FullEvery < 0 {
+		return nil, fmt.Errorf("full_every cannot be negative")
+	}
+	if _, err := newExportFilter(req.Filter); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.BackupJob{
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(req.Name),
+		Source:      source,
+		Interval:    req.Interval,
+		Destination: req.Destination,
+		Retention:   req.Retention,
+		Incremental: req.Incremental != nil && *req.Incremental,
+		Filter:      req.Filter,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		NextRunAt:   now.Add(interval),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.StartAt != nil {
+		job.NextRunAt = *req.StartAt
+	}
+	if req.FullEvery != nil {
+		job.FullEvery = *req.FullEvery
+	}
+	if job.Name == "" {
+		job.Name = fmt.Sprintf("Backup of %s", source.Host)
+	}
+
+	s.taskService.store.SaveBackupJob(job)
+	log.Printf("[INFO] Backup job %s created for %s (every %s)", job.ID, source.Host, job.Interval)
+	return job, nil
+}
+
+// UpdateJob 更新定时备份任务，未提供的字段保持不变
+// 推送目标中未提供的密钥和密码沿用原设置
+func (s *BackupService) UpdateJob(jobID string, req *models.BackupJobRequest) (*models.BackupJob, error) {
+	existing, err := s.taskService.store.GetBackupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.resolveSource(req)
+	if err != nil {
+		return nil, err
+	}
+	var interval time.Duration
+	if req.Interval != "" {
+		if interval, err = parseBackupInterval(req.Interval); err != nil {
+			return nil, err
+		}
+	}
+	dest := req.Destination
+	if dest != nil {
+		dest = mergeDestinationSecrets(dest, existing.Destination)
+		if err := validateBackupDestination(dest); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateBackupRetention(req.Retention); err != nil {
+		return nil, err
+	}
+	if req.FullEvery != nil && *req.FullEvery < 0 {
+		return nil, fmt.Errorf("full_every cannot be negative")
+	}
+	if _, err := newExportFilter(req.Filter); err != nil {
+		return nil, err
+	}
+
+	err = s.taskService.store.UpdateBackupJob(jobID, func(job *models.BackupJob) {
+		if name := strings.TrimSpace(req.Name); name != "" {
+			job.Name = name
+		}
+		if source != nil {
+			// 更换源系统后下一次备份重新做完整备份
+			if job.Source == nil || job.Source.Host != source.Host || job.Source.Port != source.Port {
+				job.LastArchive = ""
+				job.AppHashes = nil
+				job.IncrementalCount = 0
+			}
+			job.Source = source
+		}
+		if req.Interval != "" {
+			job.Interval = req.Interval
+			job.NextRunAt = time.Now().Add(interval)
+		}
+		if req.StartAt != nil {
+			job.NextRunAt = *req.StartAt
+		}
+		if dest != nil {
+			job.Destination = dest
+		}
+		if req.Retention != nil {
+			job.Retention = req.Retention
+		}
+		if req.Incremental != nil {
+			job.Incremental = *req.Incremental
+		}
+		if req.FullEvery != nil {
+			job.FullEvery = *req.FullEvery
+		}
+		if req.Filter != nil {
+			// 过滤模式改变后内容哈希不再可比，下一次备份重新做完整备份
+			job.Filter = req.Filter
+			job.LastArchive = ""
+			job.AppHashes = nil
+			job.IncrementalCount = 0
+		}
+		if req.Enabled != nil {
+			job.Enabled = *req.Enabled
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetJob(jobID)
+}
+
+// DeleteJob 删除定时备份任务，已生成的备份文件保留
+func (s *BackupService) DeleteJob(jobID string) error {
+	return s.taskService.store.DeleteBackupJob(jobID)
+}
+
+// RunJob 立即执行一次定时备份任务
+func (s *BackupService) RunJob(jobID string) (*models.MigrationTask, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, err := s.taskService.store.GetBackupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if s.jobRunning(job) {
+		return nil, fmt.Errorf("Backup job %s is already running", job.Name)
+	}
+	return s.startJob(job)
+}
+
+// runDueJobs 执行到期的备份任务
+func (s *BackupService) runDueJobs() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for _, job := range s.taskService.store.GetAllBackupJobs() {
+		if !job.Enabled || now.Before(job.NextRunAt) {
+			continue
+		}
+		if s.jobRunning(job) {
+			log.Printf("[WARNING] Backup job %s is still running; skipping this run", job.ID)
+			s.scheduleNext(job, now)
+			continue
+		}
+		if _, err := s.startJob(job); err != nil {
+			log.Printf("[ERROR] Failed to start backup job %s: %v", job.ID, err)
+			s.scheduleNext(job, now)
+		}
+	}
+}
+
+// startJob 为备份任务创建导出任务
+func (s *BackupService) startJob(job *models.BackupJob) (*models.MigrationTask, error) {
+	options := map[string]interface{}{
+		"export_apps":     true,
+		"export_settings": true,
+		"export_data":     true,
+		backupJobOption:   job.ID,
+	}
+	if job.Destination != nil {
+		options["destination"] = job.Destination
+	}
+	if job.Filter != nil {
+		options["include"] = job.Filter.Include
+		options["exclude"] = job.Filter.Exclude
+	}
+
+	source := *job.Source
+	task, err := s.migrationService.StartDataExport(&models.DataExportRequest{
+		TaskMeta: models.TaskMeta{
+			Name:   job.Name,
+			Labels: map[string]string{backupJobOption: job.ID},
+		},
+		Source:        source,
+		ExportOptions: options,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.taskService.store.UpdateBackupJob(job.ID, func(j *models.BackupJob) {
+		j.LastRunAt = &now
+		j.LastTaskID = task.ID
+		j.LastStatus = task.Status
+	})
+	s.scheduleNext(job, now)
+	log.Printf("[INFO] Backup job %s started task %s", job.ID, task.ID)
+	return task, nil
+}
+
+// scheduleNext 计算下次执行时间，错过的执行不补跑
+func (s *BackupService) scheduleNext(job *models.BackupJob, now time.Time) {
+	interval, err := parseBackupInterval(job.Interval)
+	if err != nil {
+		return
+	}
+	s.taskService.store.UpdateBackupJob(job.ID, func(j *models.BackupJob) {
+		next := j.NextRunAt
+		for !next.After(now) {
+			next = next.Add(interval)
+		}
+		j.NextRunAt = next
+	})
+}
+
+// jobRunning 判断备份任务上次创建的导出任务是否仍在运行
+func (s *BackupService) jobRunning(job *models.BackupJob) bool {
+	if job.LastTaskID == "" {
+		return false
+	}
+	task, err := s.taskService.GetTask(job.LastTaskID)
+	if err != nil {
+		return false
+	}
+	return task.Status == string(models.TaskStatusRunning) || task.Status == string(models.TaskStatusPending) || task.Status == string(models.TaskStatusWaiting)
+}
+
+// refreshLastStatus 同步上次导出任务的状态
+func (s *BackupService) refreshLastStatus(job *models.BackupJob) {
+	if job.LastTaskID == "" {
+		return
+	}
+	task, err := s.taskService.GetTask(job.LastTaskID)
+	if err != nil || task.Status == job.LastStatus {
+		return
+	}
+	s.taskService.store.UpdateBackupJob(job.ID, func(j *models.BackupJob) {
+		j.LastStatus = task.Status
+	})
+}
+
+// resolveSource 根据请求获取备份源连接，只支持CasaOS
+func (s *BackupService) resolveSource(req *models.BackupJobRequest) (*models.SystemConnection, error) {
+	var source *models.SystemConnection
+	if req.ConnectionID != "" {
+		conn, err := s.connService.GetConnection(req.ConnectionID)
+		if err != nil {
+			return nil, fmt.Errorf("Connection %s not found; test the connection first", req.ConnectionID)
+		}
+		copied := *conn
+		source = &copied
+	} else if req.Source != nil {
+		copied := *req.Source
+		source = &copied
+	}
+	if source == nil {
+		return nil, nil
+	}
+
+	if source.Type == "" {
+		source.Type = models.SystemTypeCasaOS
+	}
+	if source.Type != models.SystemTypeCasaOS {
+		return nil, fmt.Errorf("Backup jobs only support CasaOS sources")
+	}
+	if err := s.connService.ValidateConnectionConfig(source); err != nil {
+		return nil, fmt.Errorf("Invalid source connection configuration: %v", err)
+	}
+	return source, nil
+}
+
+// parseBackupInterval 解析并校验备份间隔
+func parseBackupInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid backup interval: %s", value)
+	}
+	if interval < minBackupInterval {
+		return 0, fmt.Errorf("Backup interval must be at least %s", minBackupInterval)
+	}
+	return interval, nil
+}
+
+// validateBackupDestination 校验备份推送目标
+func validateBackupDestination(dest *models.ExportDestination) error {
+	if dest == nil {
+		return nil
+	}
+	if _, err := newExportSink(dest); err != nil {
+		return fmt.Errorf("Invalid export destination: %v", err)
+	}
+	return nil
+}
+
+// validateBackupRetention 校验保留规则
+func validateBackupRetention(retention *models.BackupRetention) error {
+	if retention == nil {
+		return nil
+	}
+	if retention.KeepLast < 0 || retention.KeepDaily < 0 || retention.KeepWeekly < 0 {
+		return fmt.Errorf("Backup retention values cannot be negative")
+	}
+	return nil
+}
+
+// mergeDestinationSecrets 更新推送目标时沿用未提供的密钥和密码
+func mergeDestinationSecrets(dest, previous *models.ExportDestination) *models.ExportDestination {
+	if previous == nil {
+		return dest
+	}
+	merged := *dest
+	if merged.S3 != nil && previous.S3 != nil && merged.S3.SecretAccessKey == "" {
+		s3 := *merged.S3
+		s3.SecretAccessKey = previous.S3.SecretAccessKey
+		merged.S3 = &s3
+	}
+	if merged.SMB != nil && previous.SMB != nil && merged.SMB.Password == "" {
+		smb := *merged.SMB
+		smb.Password = previous.SMB.Password
+		merged.SMB = &smb
+	}
+	return &merged
+}
+
+// RedactBackupJob 返回去掉连接密码和推送目标密钥的备份任务副本
+func RedactBackupJob(job *models.BackupJob) *models.BackupJob {
+	redacted := *job
+	if job.Source != nil {
+		redacted.Source = RedactConnection(job.Source)
+	}
+	if job.Destination != nil {
+		if dest, ok := RedactTaskOptions(map[string]interface{}{"destination": job.Destination})["destination"].(*models.ExportDestination); ok {
+			redacted.Destination = dest
+		}
+	}
+	return &redacted
+}
+
+// backupArchiveName 备份压缩包文件名：backup_<任务ID>_<时间>.zip
+func backupArchiveName(jobID string, t time.Time) string {
+	return fmt.Sprintf("%s%s_%s.zip", backupFilePrefix, jobID, t.Format(backupTimeLayout))
+}
+
+// backupArchive 导出目录中的备份压缩包
+type backupArchive struct {
+	path      string
+	createdAt time.Time
+}
+
+// listBackupArchives 列出导出目录中指定备份任务的压缩包，按创建时间从新到旧排序
+func listBackupArchives(exportDir, jobID string) []backupArchive {
+	prefix := backupFilePrefix + jobID + "_"
+	entries, err := os.ReadDir(exportDir)
+	if err != nil {
+		return nil
+	}
+
+	var archives []backupArchive
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		createdAt, err := time.ParseInLocation(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".zip"), time.Local)
+		if err != nil {
+			continue
+		}
+		archives = append(archives, backupArchive{path: filepath.Join(exportDir, name), createdAt: createdAt})
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].createdAt.After(archives[j].createdAt) })
+	return archives
+}
+
+// expiredBackupArchives 按保留规则选出需要删除的压缩包，archives需按从新到旧排序
+// 每天/每周保留该时间段内最新的一个，最新的备份始终保留
+func expiredBackupArchives(archives []backupArchive, retention models.BackupRetention, now time.Time) []backupArchive {
+	if retention.KeepLast == 0 && retention.KeepDaily == 0 && retention.KeepWeekly == 0 {
+		return nil
+	}
+
+	dailyCutoff := now.AddDate(0, 0, -retention.KeepDaily)
+	weeklyCutoff := now.AddDate(0, 0, -7*retention.KeepWeekly)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+
+	var expired []backupArchive
+	for i, archive := range archives {
+		keep := i == 0 || i < retention.KeepLast
+
+		if retention.KeepDaily > 0 && archive.createdAt.After(dailyCutoff) {
+			day := archive.createdAt.Format("2006-01-02")
+			if !days[day] {
+				days[day] = true
+				keep = true
+			}
+		}
+		if retention.KeepWeekly > 0 && archive.createdAt.After(weeklyCutoff) {
+			year, week := archive.createdAt.ISOWeek()
+			key := fmt.Sprintf("%d-%d", year, week)
+			if !weeks[key] {
+				weeks[key] = true
+				keep = true
+			}
+		}
+
+		if !keep {
+			expired = append(expired, archive)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// blueGreenOption 迁移选项：所有应用以带后缀的临时名称导入，与目标上的原应用并存，验证后通过promote接口替换原应用
+	blueGreenOption = "blue_green"
+	// blueGreenSuffix 蓝绿导入时应用名和AppData目录的后缀
+	blueGreenSuffix = "-migrated"
+)
+
+// blueGreen 迁移选项是否开启了blue_green
+func blueGreen(options map[string]interface{}) bool {
+	enabled, ok := options[blueGreenOption].(bool)
+	return ok && enabled
+}
+
+// recordStagedApps 记录蓝绿导入的临时应用名，恢复任务时重复记录不影响结果
+func (s *MigrationService) recordStagedApps(taskID string, staged map[string]string) {
+	s.taskService.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		if task.StagedApps == nil {
+			task.StagedApps = make(map[string]string)
+		}
+		for stagedName, appName := range staged {
+			task.StagedApps[stagedName] = appName
+		}
+	})
+}
+
+// PromoteStagedApps 用蓝绿导入的应用替换目标上的原应用：卸载原应用并删除其AppData，
+// 临时应用的AppData和应用目录移到正式名称下，再以正式名称重新导入compose
+// apps为空时提升全部compose导入成功的应用，可以使用临时名称或正式名称；需要目标连接配置ssh_port
+func (s *MigrationService) PromoteStagedApps(ctx context.Context, taskID string, apps []string) ([]models.PromoteResult, error) {
+	task, err := s.taskService.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != string(models.TaskStatusCompleted) {
+		return nil, models.ErrInvalidTaskStatus
+	}
+	if len(task.StagedApps) == 0 {
+		return nil, fmt.Errorf("Task has no staged apps to promote")
+	}
+	if task.Target == nil || task.Target.Type != models.SystemTypeZimaOS {
+		return nil, fmt.Errorf("Promoting staged apps is only supported on ZimaOS targets")
+	}
+	if task.Target.SSHPort <= 0 {
+		return nil, fmt.Errorf("Promoting staged apps requires ssh_port on the target connection")
+	}
+
+	adapter, err := s.targetAdapter(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := adapter.(*zimaOSTarget)
+	if !ok {
+		return nil, fmt.Errorf("Promoting staged apps is only supported on ZimaOS targets")
+	}
+
+	imported := make(map[string]bool)
+	for _, status := range previousAppStatuses(task) {
+		if status.ComposeStatus == models.AppStatusSuccess {
+			imported[status.AppName] = true
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range apps {
+		selected[name] = true
+	}
+	stagedNames := make([]string, 0, len(task.StagedApps))
+	for stagedName := range task.StagedApps {
+		stagedNames = append(stagedNames, stagedName)
+	}
+	sort.Strings(stagedNames)
+
+	// 请求中不属于本任务的应用名记录为跳过
+	results := []models.PromoteResult{}
+	for _, name := range apps {
+		if !isStagedApp(task.StagedApps, name) {
+			results = append(results, models.PromoteResult{AppName: name, Status: models.AppStatusSkipped, Error: "App is not staged by this task"})
+		}
+	}
+
+	for _, stagedName := range stagedNames {
+		appName := task.StagedApps[stagedName]
+		if len(selected) > 0 && !selected[stagedName] && !selected[appName] {
+			continue
+		}
+		result := models.PromoteResult{AppName: appName, StagedName: stagedName}
+		if !imported[stagedName] {
+			result.Status = models.AppStatusSkipped
+			result.Error = "Compose of the staged app was not imported"
+			results = append(results, result)
+			continue
+		}
+
+		if err := s.promoteApp(ctx, target, stagedName, appName); err != nil {
+			log.Printf("[ERROR] Failed to promote app %s: %v", stagedName, err)
+			result.Status = models.AppStatusFailed
+			result.Error = err.Error()
+			s.taskService.AddTaskLog(taskID, models.LogLevelError, fmt.Sprintf("Failed to promote app %s to %s: %v", stagedName, appName, err))
+		} else {
+			result.Status = models.AppStatusSuccess
+			s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s promoted to %s ✓", stagedName, appName))
+			s.taskService.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+				delete(task.StagedApps, stagedName)
+			})
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// promoteApp 将单个临时应用提升为正式应用
+func (s *MigrationService) promoteApp(ctx context.Context, target *zimaOSTarget, stagedName, appName string) error {
+	sshConn := *target.conn
+	sshConn.Port = target.conn.SSHPort
+	client := s.zimaOSClientFor(target.conn)
+
+	stagedAppDir := path.Join(casaOSAppsDir, stagedName)
+	appDir := path.Join(casaOSAppsDir, appName)
+	content, err := runSSH(ctx, &sshConn, nil, "cat "+shellQuote(path.Join(stagedAppDir, composeFileName)))
+	if err != nil {
+		return fmt.Errorf("Failed to read compose of %s: %v", stagedName, err)
+	}
+	composeContent, err := renameComposeContent(string(content), stagedName, appName, target.appDataDir)
+	if err != nil {
+		return err
+	}
+
+	// 原应用不存在时（如首次迁移）直接提升
+	exists, err := s.zimaOSPathExists(ctx, target.conn, appDir)
+	if err != nil {
+		return fmt.Errorf("Failed to check app %s: %v", appName, err)
+	}
+	if exists {
+		if err := client.UninstallCompose(ctx, appName, true); err != nil {
+			return err
+		}
+	}
+	// 保留临时应用目录中的.env等文件，随目录一起移到正式名称下
+	if err := client.UninstallCompose(ctx, stagedName, false); err != nil {
+		return err
+	}
+
+	stagedData := path.Join(target.appDataDir, stagedName)
+	appData := path.Join(target.appDataDir, appName)
+	script := fmt.Sprintf("set -e; rm -rf %[1]s; if [ -e %[2]s ]; then mv %[2]s %[1]s; fi; rm -rf %[3]s; if [ -e %[4]s ]; then mv %[4]s %[3]s; rm -f %[3]s/%[5]s; fi",
+		shellQuote(appData), shellQuote(stagedData), shellQuote(appDir), shellQuote(stagedAppDir), composeFileName)
+	if _, err := runSSH(ctx, &sshConn, nil, script); err != nil {
+		return fmt.Errorf("Failed to move app data of %s: %v", stagedName, err)
+	}
+
+	statusCode, body, err := client.ImportCompose(ctx, composeContent, true)
+	if err != nil {
+		return err
+	}
+	if statusCode != 200 {
+		return fmt.Errorf("Import of %s failed (status code: %d): %s", appName, statusCode, string(body))
+	}
+	return nil
+}
+
+// isStagedApp 判断名称是否为蓝绿导入的临时名称或对应的正式名称
+func isStagedApp(staged map[string]string, name string) bool {
+	if _, ok := staged[name]; ok {
+		return true
+	}
+	for _, appName := range staged {
+		if appName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// 构建信息，构建时通过 -ldflags "-X ctoz/backend/internal/services.<变量>=<值>" 注入
+var (
+	// ToolVersion 工具版本，在健康检查、系统信息、版本接口和诊断包中返回，未注入时为dev
+	ToolVersion = "dev"
+	// GitCommit 构建时的git提交，未注入时使用Go记录的vcs.revision
+	GitCommit = ""
+	// BuildDate 构建时间（RFC3339），未注入时使用Go记录的vcs.time
+	BuildDate = ""
+)
+
+// startedAt 进程启动时间
+var startedAt = time.Now()
+
+// StartedAt 返回服务进程的启动时间
+func StartedAt() time.Time {
+	return startedAt
+}
+
+// Uptime 返回服务已运行的时长
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
+
+// CurrentBuild 返回程序的版本、构建信息和Go运行环境
+func CurrentBuild() *models.BuildInfo {
+	info := &models.BuildInfo{
+		Version:   ToolVersion,
+		Commit:    GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
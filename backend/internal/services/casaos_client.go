@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// CasaOS源系统目录
+const (
+	casaOSAppsDir = "/var/lib/casaos/apps"
+)
+
+// casaOSFileEntry CasaOS文件API返回的目录项
+type casaOSFileEntry struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	IsDir bool   `json:"is_dir"`
+}
+
+// casaOSBatchURL 返回打包下载apps和AppData目录的URL
+func casaOSBatchURL(conn *models.SystemConnection) string {
+	host := (&url.URL{Scheme: "http", Host: urlHost(conn.Host)}).String()
+	return fmt.Sprintf("%s/v1/batch?token=%s&files=%s,%s", host, conn.Token, casaOSAppsDir, casaOSAppDataDir)
+}
+
+// casaOSGet 调用CasaOS API并将响应中的data字段解析到out
+func (s *MigrationService) casaOSGet(ctx context.Context, conn *models.SystemConnection, apiPath string, query url.Values, out interface{}) error {
+	apiURL := connBaseURL(conn) + apiPath
+	if len(query) > 0 {
+		apiURL += "?" + query.Encode()
+	}
+
+	req, err := newConnRequest(ctx, conn, "GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", conn.Token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Request to %s failed: %v", apiPath, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Request to %s failed, status code: %d, response: %s", apiPath, resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("Failed to parse response: %v", err)
+	}
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("Failed to parse response data: %v", err)
+	}
+	return nil
+}
+
+// listCasaOSFolder 列出CasaOS目录内容
+func (s *MigrationService) listCasaOSFolder(ctx context.Context, conn *models.SystemConnection, dirPath string) ([]casaOSFileEntry, error) {
+	var raw json.RawMessage
+	if err := s.casaOSGet(ctx, conn, "/v1/folder", url.Values{"path": {dirPath}}, &raw); err != nil {
+		return nil, err
+	}
+
+	// 不同版本返回数组或 {content: [...]} 结构
+	var entries []casaOSFileEntry
+	if err := json.Unmarshal(raw, &entries); err == nil {
+		return entries, nil
+	}
+	var list struct {
+		Content []casaOSFileEntry `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("Unexpected folder listing for %s: %v", dirPath, err)
+	}
+	return list.Content, nil
+}
+
+// getCasaOSFolderSize 获取CasaOS目录的总大小（字节）
+func (s *MigrationService) getCasaOSFolderSize(ctx context.Context, conn *models.SystemConnection, dirPath string) (int64, error) {
+	var size int64
+	if err := s.casaOSGet(ctx, conn, "/v1/folder/size", url.Values{"path": {dirPath}}, &size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// setComposeStatus 通过应用管理接口设置compose应用状态（running/stopped/restarting），CasaOS和ZimaOS通用
+func (s *MigrationService) setComposeStatus(ctx context.Context, conn *models.SystemConnection, appName, status string) error {
+	apiURL := fmt.Sprintf("%s/v2/app_management/compose/%s/status", connBaseURL(conn), url.PathEscape(appName))
+	req, err := newConnRequest(ctx, conn, "PUT", apiURL, strings.NewReader(fmt.Sprintf("%q", status)))
+	if err != nil {
+		return fmt.Errorf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", conn.Token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Status code: %d, response: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// CasaOS源数据的获取方式
+const (
+	casaOSDownloadBatch   = "batch"
+	casaOSDownloadSSH     = "ssh"
+	casaOSDownloadPerFile = "per-file"
+
+	// casaOSBatchMinVersion 提供 /v1/batch 打包下载接口的最低CasaOS版本
+	casaOSBatchMinVersion = "0.4.4"
+	// perFileReportEvery 逐个文件下载时每下载多少个文件报告一次进度
+	perFileReportEvery = 100
+)
+
+// downloadCasaOSFiles 下载CasaOS的apps和AppData目录，返回与 /v1/batch 结构相同的ZIP文件
+// 按版本选择获取方式，失败时依次尝试下一种，所用方式记录在任务日志中
+func (s *MigrationService) downloadCasaOSFiles(ctx context.Context, conn *models.SystemConnection, progressCallback func(int, string)) (string, error) {
+	var failures []string
+	for _, method := range casaOSDownloadMethods(conn) {
+		progressCallback(10, fmt.Sprintf("Downloading source data using the %s method", method))
+
+		var filePath string
+		var err error
+		switch method {
+		case casaOSDownloadBatch:
+			filePath, err = s.downloadCasaOSBatch(ctx, conn, progressCallback)
+		case casaOSDownloadSSH:
+			filePath, err = s.downloadCasaOSOverSSH(ctx, conn, progressCallback)
+		default:
+			filePath, err = s.downloadCasaOSPerFile(ctx, conn, progressCallback)
+		}
+		if err == nil {
+			return filePath, nil
+		}
+
+		log.Printf("[WARNING] CasaOS %s download from %s failed: %v", method, conn.Host, err)
+		progressCallback(10, fmt.Sprintf("Download method %s failed: %v", method, err))
+		failures = append(failures, fmt.Sprintf("%s: %v", method, err))
+	}
+	return "", fmt.Errorf("All download methods failed: %s", strings.Join(failures, "; "))
+}
+
+// casaOSDownloadMethods 返回按优先级排列的获取方式
+// 不提供打包下载的旧版本跳过batch，配置了ssh_port时可通过SSH获取
+func casaOSDownloadMethods(conn *models.SystemConnection) []string {
+	var methods []string
+	if conn.Version == "" || compareVersions(conn.Version, casaOSBatchMinVersion) >= 0 {
+		methods = append(methods, casaOSDownloadBatch)
+	}
+	if conn.SSHPort > 0 {
+		methods = append(methods, casaOSDownloadSSH)
+	}
+	return append(methods, casaOSDownloadPerFile)
+}
+
+// casaOSDownloadPath 生成下载文件路径
+func casaOSDownloadPath(downloadDir string) string {
+	return filepath.Join(downloadDir, fmt.Sprintf("casaos_backup_%s.zip", time.Now().Format("20060102_150405")))
+}
+
+// downloadCasaOSBatch 通过 /v1/batch 接口一次打包下载apps和AppData目录
+func (s *MigrationService) downloadCasaOSBatch(ctx context.Context, conn *models.SystemConnection, progressCallback func(int, string)) (string, error) {
+	// 构建下载URL
+	downloadURL := casaOSBatchURL(conn)
+
+	// 创建HTTP请求
+	req, err := newConnRequest(ctx, conn, "GET", downloadURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create download request: %v", err)
+	}
+
+	// 发送请求
+	resp, err := s.downloadClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Failed to send download request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Download failed, status code: %d", resp.StatusCode)
+	}
+
+	progressCallback(20, "Downloading file")
+
+	// 创建下载目录
+	downloadDir := s.cfg.Dirs.Download
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create download directory: %v", err)
+	}
+
+	filePath := casaOSDownloadPath(downloadDir)
+
+	// 创建本地文件
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create local file: %v", err)
+	}
+	defer file.Close()
+
+	// 复制数据并按Content-Length报告进度（20%-35%），长度未知时报告已下载字节数
+	total := resp.ContentLength
+	if total < 0 {
+		total = 0
+	}
+	lastProgress, lastReported := 20, int64(0)
+	body := newProgressReader(resp.Body, total, func(read, total int64) {
+		if total > 0 {
+			progress := 20 + int(15*read/total)
+			if progress > lastProgress {
+				lastProgress = progress
+				progressCallback(progress, fmt.Sprintf("Downloading: %d/%d bytes (%d%%)", read, total, read*100/total))
+			}
+			return
+		}
+		if read-lastReported >= downloadReportStep {
+			lastReported = read
+			progressCallback(20, fmt.Sprintf("Downloading: %d bytes", read))
+		}
+	})
+
+	written, err := io.Copy(file, body)
+	if err != nil {
+		return "", fmt.Errorf("Failed to download file: %v", err)
+	}
+
+	progressCallback(35, fmt.Sprintf("Download completed, file size: %d bytes", written))
+
+	return filePath, nil
+}
+
+// downloadCasaOSPerFile 通过文件接口逐个列出并下载文件，写入与打包下载结构相同的ZIP文件
+func (s *MigrationService) downloadCasaOSPerFile(ctx context.Context, conn *models.SystemConnection, progressCallback func(int, string)) (string, error) {
+	downloadDir := s.cfg.Dirs.Download
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create download directory: %v", err)
+	}
+	filePath := casaOSDownloadPath(downloadDir)
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create local file: %v", err)
+	}
+	archive := zip.NewWriter(file)
+
+	var files, written int64
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := s.listCasaOSFolder(ctx, conn, dir)
+		if err != nil {
+			return fmt.Errorf("Failed to list %s: %v", dir, err)
+		}
+		// 目录条目保留空目录
+		if _, err := archive.Create(strings.TrimPrefix(dir, "/") + "/"); err != nil {
+			return fmt.Errorf("Failed to create ZIP entry: %v", err)
+		}
+		for _, entry := range entries {
+			entryPath := path.Join(dir, entry.Name)
+			if entry.IsDir {
+				if err := walk(entryPath); err != nil {
+					return err
+				}
+				continue
+			}
+			n, err := s.downloadCasaOSFile(ctx, conn, entryPath, archive)
+			if err != nil {
+				return err
+			}
+			files++
+			written += n
+			if files%perFileReportEvery == 0 {
+				progressCallback(20, fmt.Sprintf("Downloaded %d files (%d bytes)", files, written))
+			}
+		}
+		return nil
+	}
+
+	for _, root := range []string{casaOSAppsDir, casaOSAppDataDir} {
+		if err = walk(root); err != nil {
+			break
+		}
+	}
+	if closeErr := archive.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("Failed to write ZIP file: %v", closeErr)
+	}
+	file.Close()
+	if err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+
+	progressCallback(35, fmt.Sprintf("Download completed: %d files, %d bytes", files, written))
+	return filePath, nil
+}
+
+// downloadCasaOSFile 下载单个文件并写入ZIP条目，返回写入的字节数
+func (s *MigrationService) downloadCasaOSFile(ctx context.Context, conn *models.SystemConnection, filePath string, archive *zip.Writer) (int64, error) {
+	query := url.Values{"path": {filePath}, "token": {conn.Token}}
+	req, err := newConnRequest(ctx, conn, "GET", fmt.Sprintf("%s/v1/file?%s", connBaseURL(conn), query.Encode()), nil)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to create download request: %v", err)
+	}
+	req.Header.Set("Authorization", conn.Token)
+
+	resp, err := s.downloadClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to download %s: %v", filePath, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Failed to download %s, status code: %d", filePath, resp.StatusCode)
+	}
+
+	writer, err := archive.Create(strings.TrimPrefix(filePath, "/"))
+	if err != nil {
+		return 0, fmt.Errorf("Failed to create ZIP entry: %v", err)
+	}
+	n, err := io.Copy(writer, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("Failed to download %s: %v", filePath, err)
+	}
+	return n, nil
+}
+
+// downloadCasaOSOverSSH 通过SSH端口用rsync同步apps和AppData目录，再打包成ZIP文件
+func (s *MigrationService) downloadCasaOSOverSSH(ctx context.Context, conn *models.SystemConnection, progressCallback func(int, string)) (string, error) {
+	downloadDir := s.cfg.Dirs.Download
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create download directory: %v", err)
+	}
+	stageDir, err := os.MkdirTemp(downloadDir, "casaos_ssh_")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	sshConn := *conn
+	sshConn.Port = conn.SSHPort
+	for i, dir := range []string{casaOSAppsDir, casaOSAppDataDir} {
+		progressCallback(20+5*i, fmt.Sprintf("Syncing %s over SSH...", dir))
+		localDir := filepath.Join(stageDir, filepath.FromSlash(strings.TrimPrefix(dir, "/")))
+		if err := os.MkdirAll(localDir, 0755); err != nil {
+			return "", fmt.Errorf("Failed to create staging directory: %v", err)
+		}
+		if err := rsyncFrom(ctx, &sshConn, dir+"/", localDir+"/"); err != nil {
+			return "", err
+		}
+	}
+
+	filePath := casaOSDownloadPath(downloadDir)
+	if err := s.createZipFile(stageDir, filePath); err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("Failed to create ZIP file: %v", err)
+	}
+	progressCallback(35, fmt.Sprintf("Download completed, file size: %d bytes", s.getFileSize(filePath)))
+	return filePath, nil
+}
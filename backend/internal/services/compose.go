@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// composeDocument 保留键顺序的compose文档，用于在导入前对compose进行结构化修改
+type composeDocument struct {
+	root yaml.MapSlice
+}
+
+// composeService compose中的单个服务，修改Config后需调用SetService写回文档
+type composeService struct {
+	Name   string
+	Config yaml.MapSlice
+}
+
+// parseCompose 解析compose内容
+func parseCompose(content string) (*composeDocument, error) {
+	var root yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, fmt.Errorf("Failed to parse compose file: %v", err)
+	}
+	return &composeDocument{root: root}, nil
+}
+
+// String 序列化compose文档
+func (d *composeDocument) String() (string, error) {
+	data, err := yaml.Marshal(d.root)
+	if err != nil {
+		return "", fmt.Errorf("Failed to serialize compose file: %v", err)
+	}
+	return string(data), nil
+}
+
+// Services 返回compose中的所有服务
+func (d *composeDocument) Services() []composeService {
+	value, _ := mapGet(d.root, "services")
+	services, ok := value.(yaml.MapSlice)
+	if !ok {
+		return nil
+	}
+
+	result := make([]composeService, 0, len(services))
+	for _, item := range services {
+		config, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		result = append(result, composeService{Name: fmt.Sprint(item.Key), Config: config})
+	}
+	return result
+}
+
+// SetService 将修改后的服务配置写回文档
+func (d *composeDocument) SetService(name string, config yaml.MapSlice) {
+	value, _ := mapGet(d.root, "services")
+	services, _ := value.(yaml.MapSlice)
+	mapSet(&services, name, config)
+	mapSet(&d.root, "services", services)
+}
+
+// RewriteBindSources 使用rewrite函数改写所有服务的bind挂载源路径
+// 同时支持短格式 "host:container[:mode]" 和长格式 {type: bind, source: ...}
+func (d *composeDocument) RewriteBindSources(rewrite func(service, source string) string) {
+	for _, svc := range d.Services() {
+		value, ok := mapGet(svc.Config, "volumes")
+		if !ok {
+			continue
+		}
+		volumes, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+
+		for i, volume := range volumes {
+			switch v := volume.(type) {
+			case string:
+				parts := strings.SplitN(v, ":", 2)
+				if len(parts) == 2 && isBindSource(parts[0]) {
+					volumes[i] = rewrite(svc.Name, parts[0]) + ":" + parts[1]
+				}
+			case yaml.MapSlice:
+				source, _ := mapGet(v, "source")
+				sourceStr, ok := source.(string)
+				if !ok || !isBindSource(sourceStr) {
+					continue
+				}
+				mapSet(&v, "source", rewrite(svc.Name, sourceStr))
+				volumes[i] = v
+			}
+		}
+
+		mapSet(&svc.Config, "volumes", volumes)
+		d.SetService(svc.Name, svc.Config)
+	}
+}
+
+// BindSources 返回所有服务的bind挂载源路径
+func (d *composeDocument) BindSources() []string {
+	var sources []string
+	d.RewriteBindSources(func(service, source string) string {
+		sources = append(sources, source)
+		return source
+	})
+	return sources
+}
+
+// RewriteImages 使用rewrite函数改写所有服务的image字段
+func (d *composeDocument) RewriteImages(rewrite func(service, image string) string) {
+	for _, svc := range d.Services() {
+		value, ok := mapGet(svc.Config, "image")
+		if !ok {
+			continue
+		}
+		image, ok := value.(string)
+		if !ok || image == "" {
+			continue
+		}
+		if rewritten := rewrite(svc.Name, image); rewritten != image {
+			mapSet(&svc.Config, "image", rewritten)
+			d.SetService(svc.Name, svc.Config)
+		}
+	}
+}
+
+// RemoveNetwork 从所有服务和顶层networks中移除指定网络
+func (d *composeDocument) RemoveNetwork(name string) {
+	for _, svc := range d.Services() {
+		value, ok := mapGet(svc.Config, "networks")
+		if !ok {
+			continue
+		}
+
+		empty := false
+		switch networks := value.(type) {
+		case []interface{}:
+			kept := make([]interface{}, 0, len(networks))
+			for _, network := range networks {
+				if fmt.Sprint(network) != name {
+					kept = append(kept, network)
+				}
+			}
+			mapSet(&svc.Config, "networks", kept)
+			empty = len(kept) == 0
+		case yaml.MapSlice:
+			mapDelete(&networks, name)
+			mapSet(&svc.Config, "networks", networks)
+			empty = len(networks) == 0
+		}
+		if empty {
+			mapDelete(&svc.Config, "networks")
+		}
+		d.SetService(svc.Name, svc.Config)
+	}
+
+	value, _ := mapGet(d.root, "networks")
+	if networks, ok := value.(yaml.MapSlice); ok {
+		mapDelete(&networks, name)
+		if len(networks) == 0 {
+			mapDelete(&d.root, "networks")
+		} else {
+			mapSet(&d.root, "networks", networks)
+		}
+	}
+}
+
+// Merge 按docker compose多文件规则将覆盖文件合并到文档中：映射逐键合并，
+// 列表追加去重（command、entrypoint等命令行整体替换），标量以覆盖文件为准
+func (d *composeDocument) Merge(override *composeDocument) {
+	d.root = mergeComposeValue("", d.root, override.root).(yaml.MapSlice)
+}
+
+// composeReplaceKeys 合并时整体替换而不追加的列表字段
+var composeReplaceKeys = map[string]bool{"command": true, "entrypoint": true, "test": true}
+
+// mergeComposeValue 递归合并compose中的值
+func mergeComposeValue(key string, base, override interface{}) interface{} {
+	switch o := override.(type) {
+	case yaml.MapSlice:
+		b, ok := base.(yaml.MapSlice)
+		if !ok {
+			return o
+		}
+		for _, item := range o {
+			k := fmt.Sprint(item.Key)
+			if existing, ok := mapGet(b, k); ok {
+				mapSet(&b, k, mergeComposeValue(k, existing, item.Value))
+			} else {
+				mapSet(&b, k, item.Value)
+			}
+		}
+		return b
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok || composeReplaceKeys[key] {
+			return o
+		}
+		seen := make(map[string]bool, len(b))
+		for _, v := range b {
+			seen[fmt.Sprint(v)] = true
+		}
+		for _, v := range o {
+			if !seen[fmt.Sprint(v)] {
+				b = append(b, v)
+			}
+		}
+		return b
+	}
+	return override
+}
+
+// isBindSource 判断挂载源是否为主机路径（而非命名卷）
+func isBindSource(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") || strings.HasPrefix(source, "~")
+}
+
+// MapSlice辅助函数
+
+// mapGet 获取MapSlice中的键值
+func mapGet(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if fmt.Sprint(item.Key) == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// mapSet 设置MapSlice中的键值，不存在时追加
+func mapSet(m *yaml.MapSlice, key string, value interface{}) {
+	for i := range *m {
+		if fmt.Sprint((*m)[i].Key) == key {
+			(*m)[i].Value = value
+			return
+		}
+	}
+	*m = append(*m, yaml.MapItem{Key: key, Value: value})
+}
+
+// mapDelete 删除MapSlice中的键，返回是否存在
+func mapDelete(m *yaml.MapSlice, key string) bool {
+	for i := range *m {
+		if fmt.Sprint((*m)[i].Key) == key {
+			*m = append((*m)[:i], (*m)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// 环境变量辅助函数
+
+// envVarPattern 匹配 $$、${VAR}、${VAR:-default}、${VAR-default}、${VAR:?err} 和 $VAR
+var envVarPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?])([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// parseEnvFile 解析.env格式内容（KEY=VALUE，忽略注释和空行）
+func parseEnvFile(content string) map[string]string {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[key] = value
+	}
+	return vars
+}
+
+// interpolateEnv 使用vars替换内容中的变量引用，未定义且无默认值的变量保持原样
+func interpolateEnv(content string, vars map[string]string) string {
+	return envVarPattern.ReplaceAllStringFunc(content, func(match string) string {
+		if match == "$$" {
+			return match
+		}
+
+		groups := envVarPattern.FindStringSubmatch(match)
+		name, op, arg := groups[1], groups[2], groups[3]
+		if name == "" {
+			name = groups[4]
+		}
+
+		value, ok := vars[name]
+		switch op {
+		case ":-":
+			if !ok || value == "" {
+				return arg
+			}
+		case "-":
+			if !ok {
+				return arg
+			}
+		}
+		if !ok {
+			return match
+		}
+		return value
+	})
+}
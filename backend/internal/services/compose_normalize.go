@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ctoz/backend/internal/models"
+
+	"gopkg.in/yaml.v2"
+)
+
+// composeTopLevelKeys Compose规范中的顶层键，用于识别没有services键的v1格式
+var composeTopLevelKeys = map[string]bool{
+	"version": true, "name": true, "services": true, "networks": true,
+	"volumes": true, "configs": true, "secrets": true, "include": true,
+}
+
+// normalizeComposeForTarget 导入前规范化compose，修改内容逐条记录到任务日志
+// 解析失败时原样返回，由目标系统报告具体错误
+func (s *MigrationService) normalizeComposeForTarget(appName, composeContent, taskID string) string {
+	doc, err := parseCompose(composeContent)
+	if err != nil {
+		return composeContent
+	}
+
+	changes := doc.Normalize()
+	if len(changes) == 0 {
+		return composeContent
+	}
+
+	normalized, err := doc.String()
+	if err != nil {
+		log.Printf("[WARNING] App %s: failed to normalize compose: %v", appName, err)
+		return composeContent
+	}
+	for _, change := range changes {
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: compose normalized: %s", appName, change))
+	}
+	return normalized
+}
+
+// Normalize 将旧版compose转换为Compose规范格式，返回所做修改的说明
+// - v1格式（服务位于顶层）包装到services下
+// - 删除已废弃的顶层version字段
+// - net改写为network_mode
+// - links改写为depends_on，别名改为被链接服务的网络别名
+// - environment中的布尔值和数字转换为字符串
+func (d *composeDocument) Normalize() []string {
+	var changes []string
+
+	if _, ok := mapGet(d.root, "services"); !ok && d.isV1() {
+		services := d.root
+		d.root = yaml.MapSlice{{Key: "services", Value: services}}
+		changes = append(changes, "moved version 1 services under the services key")
+	}
+
+	if value, ok := mapGet(d.root, "version"); ok {
+		mapDelete(&d.root, "version")
+		changes = append(changes, fmt.Sprintf("removed obsolete version field (%v)", value))
+	}
+
+	aliases := make(map[string][]string)
+	for _, svc := range d.Services() {
+		changed := false
+
+		if net, ok := mapGet(svc.Config, "net"); ok {
+			mapDelete(&svc.Config, "net")
+			if _, exists := mapGet(svc.Config, "network_mode"); !exists {
+				mapSet(&svc.Config, "network_mode", net)
+			}
+			changes = append(changes, fmt.Sprintf("service %s: replaced net with network_mode", svc.Name))
+			changed = true
+		}
+
+		if value, ok := mapGet(svc.Config, "links"); ok {
+			if links, ok := value.([]interface{}); ok {
+				kept := d.convertLinks(&svc, links, aliases, &changes)
+				if len(kept) == 0 {
+					mapDelete(&svc.Config, "links")
+				} else {
+					mapSet(&svc.Config, "links", kept)
+				}
+				changed = true
+			}
+		}
+
+		if value, ok := mapGet(svc.Config, "environment"); ok {
+			if env, ok := value.(yaml.MapSlice); ok {
+				converted := 0
+				for i := range env {
+					switch v := env[i].Value.(type) {
+					case bool, int, int64, float64:
+						env[i].Value = fmt.Sprint(v)
+						converted++
+					}
+				}
+				if converted > 0 {
+					mapSet(&svc.Config, "environment", env)
+					changes = append(changes, fmt.Sprintf("service %s: quoted %d non-string environment values", svc.Name, converted))
+					changed = true
+				}
+			}
+		}
+
+		if changed {
+			d.SetService(svc.Name, svc.Config)
+		}
+	}
+
+	// 链接别名写入被链接服务的默认网络别名
+	for _, svc := range d.Services() {
+		names := aliases[svc.Name]
+		if len(names) == 0 {
+			continue
+		}
+		aliasValues := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			aliasValues = append(aliasValues, name)
+		}
+		mapSet(&svc.Config, "networks", yaml.MapSlice{{Key: "default", Value: yaml.MapSlice{{Key: "aliases", Value: aliasValues}}}})
+		d.SetService(svc.Name, svc.Config)
+	}
+	return changes
+}
+
+// convertLinks 将links转换为depends_on，返回无法转换、需要保留的链接
+// 服务已自定义网络时无法安全地添加别名，带别名的链接保持不变
+func (d *composeDocument) convertLinks(svc *composeService, links []interface{}, aliases map[string][]string, changes *[]string) []interface{} {
+	serviceConfigs := make(map[string]yaml.MapSlice)
+	for _, other := range d.Services() {
+		serviceConfigs[other.Name] = other.Config
+	}
+
+	var kept []interface{}
+	for _, link := range links {
+		parts := strings.SplitN(fmt.Sprint(link), ":", 2)
+		target := parts[0]
+		other, ok := serviceConfigs[target]
+		if !ok {
+			// 链接到项目外的容器，保持不变
+			kept = append(kept, link)
+			continue
+		}
+
+		if len(parts) == 2 && parts[1] != target {
+			alias := parts[1]
+			_, hasNetworks := mapGet(other, "networks")
+			_, hasNetworkMode := mapGet(other, "network_mode")
+			if hasNetworks || hasNetworkMode {
+				kept = append(kept, link)
+				*changes = append(*changes, fmt.Sprintf("service %s: kept link %s because %s uses custom networks", svc.Name, link, target))
+				continue
+			}
+			if !containsString(aliases[target], alias) {
+				aliases[target] = append(aliases[target], alias)
+			}
+		}
+
+		addDependsOn(&svc.Config, target)
+		*changes = append(*changes, fmt.Sprintf("service %s: replaced link %s with depends_on", svc.Name, link))
+	}
+	return kept
+}
+
+// addDependsOn 向服务的depends_on添加依赖，兼容列表和映射两种格式
+func addDependsOn(config *yaml.MapSlice, target string) {
+	value, _ := mapGet(*config, "depends_on")
+	switch deps := value.(type) {
+	case yaml.MapSlice:
+		if _, ok := mapGet(deps, target); !ok {
+			mapSet(&deps, target, yaml.MapSlice{{Key: "condition", Value: "service_started"}})
+			mapSet(config, "depends_on", deps)
+		}
+	case []interface{}:
+		for _, dep := range deps {
+			if fmt.Sprint(dep) == target {
+				return
+			}
+		}
+		mapSet(config, "depends_on", append(deps, target))
+	default:
+		mapSet(config, "depends_on", []interface{}{target})
+	}
+}
+
+// isV1 判断是否为没有services键的v1格式：所有顶层键都不是规范中的键且值为包含image或build的映射
+func (d *composeDocument) isV1() bool {
+	if len(d.root) == 0 {
+		return false
+	}
+	for _, item := range d.root {
+		if composeTopLevelKeys[fmt.Sprint(item.Key)] || strings.HasPrefix(fmt.Sprint(item.Key), "x-") {
+			return false
+		}
+		config, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return false
+		}
+		_, hasImage := mapGet(config, "image")
+		_, hasBuild := mapGet(config, "build")
+		if !hasImage && !hasBuild {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString 判断切片中是否包含字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
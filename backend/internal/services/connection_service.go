@@ -1,13 +1,21 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/logging"
 	"ctoz/backend/internal/models"
 	"ctoz/backend/internal/storage"
 
@@ -16,92 +24,367 @@ import (
 
 // ConnectionService 连接服务
 type ConnectionService struct {
-	client *http.Client
-	store  *storage.MemoryStore
+	client    *retryClient
+	store     *storage.MemoryStore
+	sshKeyDir string
 }
 
 // NewConnectionService 创建新的连接服务
-func NewConnectionService() *ConnectionService {
+func NewConnectionService(cfg *config.Config, store *storage.MemoryStore) *ConnectionService {
+	registerStoredSecrets(store)
 	return &ConnectionService{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		store: storage.NewMemoryStore(),
+		client:    newRetryClient(cfg.Timeouts.Connect, cfg.Retry),
+		store:     store,
+		sshKeyDir: cfg.SSHKeyDir,
+	}
+}
+
+// registerStoredSecrets 登记保存的连接和任务中的密码和令牌，使其不出现在日志中
+func registerStoredSecrets(store *storage.MemoryStore) {
+	conns, _ := store.GetAllConnections()
+	for _, conn := range conns {
+		registerConnectionSecrets(conn)
+	}
+	tasks, _ := store.GetAllTasks()
+	for _, task := range tasks {
+		registerTaskSecrets(task)
+	}
+}
+
+// 密码和令牌的登记归属，删除任务或连接时释放
+func taskSecretOwner(taskID string) string { return "task:" + taskID }
+
+func connectionSecretOwner(connID string) string { return "connection:" + connID }
+
+// registerTaskSecrets 以任务为归属登记源和目标连接的密码和令牌
+func registerTaskSecrets(task *models.MigrationTask) {
+	owner := taskSecretOwner(task.ID)
+	for _, conn := range []*models.SystemConnection{task.Source, task.Target} {
+		if conn != nil {
+			logging.RegisterSecret(owner, conn.Password, conn.Token)
+		}
+	}
+}
+
+// registerConnectionSecrets 以连接为归属登记保存的连接的密码和令牌，替换该连接之前登记的值
+func registerConnectionSecrets(conn *models.SystemConnection) {
+	owner := connectionSecretOwner(conn.ID)
+	logging.ReleaseSecrets(owner)
+	logging.RegisterSecret(owner, conn.Password, conn.Token)
+}
+
+// secretOwnerKey 上下文中保存密码和令牌登记归属的键
+type secretOwnerKey struct{}
+
+// secretScope 确定测试连接时登记密码和令牌的归属并保存到上下文
+// 任务中归属于任务，保存的连接归属于连接；二者都不是时返回的函数在测试结束后释放登记的值
+func secretScope(ctx context.Context, conn *models.SystemConnection) (context.Context, func()) {
+	if secretOwner(ctx) != "" {
+		return ctx, func() {}
+	}
+	if taskID := taskIDFromContext(ctx); taskID != "" {
+		return context.WithValue(ctx, secretOwnerKey{}, taskSecretOwner(taskID)), func() {}
 	}
+	if conn.ID != "" {
+		return context.WithValue(ctx, secretOwnerKey{}, connectionSecretOwner(conn.ID)), func() {}
+	}
+	owner := "test:" + uuid.New().String()
+	return context.WithValue(ctx, secretOwnerKey{}, owner), func() { logging.ReleaseSecrets(owner) }
+}
+
+// secretOwner 返回上下文中登记密码和令牌的归属
+func secretOwner(ctx context.Context) string {
+	owner, _ := ctx.Value(secretOwnerKey{}).(string)
+	return owner
 }
 
 // TestConnection 测试系统连接
-func (s *ConnectionService) TestConnection(conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
+func (s *ConnectionService) TestConnection(ctx context.Context, conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
 	if conn == nil {
 		return &models.ConnectionTestResponse{
 			Success: false,
-			Message: "连接信息不能为空",
+			Message: "Connection information is required",
 		}, nil
 	}
 
+	// 重新测试保存的连接时，未提供的密码和令牌使用保存的值
+	if conn.ID != "" && conn.Password == "" && conn.Token == "" {
+		if saved, err := s.store.GetConnection(conn.ID); err == nil {
+			conn.Password = saved.Password
+			conn.Token = saved.Token
+		}
+	}
+	ctx, release := secretScope(ctx, conn)
+	defer release()
+	logging.RegisterSecret(secretOwner(ctx), conn.Password, conn.Token)
+
 	// 验证必填字段
 	if conn.Host == "" {
 		return &models.ConnectionTestResponse{
 			Success: false,
-			Message: "主机地址不能为空",
+			Message: "Host is required",
+		}, nil
+	}
+	if err := normalizeHost(conn); err != nil {
+		return &models.ConnectionTestResponse{
+			Success: false,
+			Message: err.Error(),
 		}, nil
 	}
 
 	if conn.Port <= 0 || conn.Port > 65535 {
 		return &models.ConnectionTestResponse{
 			Success: false,
-			Message: "端口号必须在1-65535之间",
+			Message: "Port must be between 1 and 65535",
 		}, nil
 	}
 
 	if conn.Username == "" {
 		return &models.ConnectionTestResponse{
 			Success: false,
-			Message: "用户名不能为空",
+			Message: "Username is required",
+		}, nil
+	}
+	if err := s.checkSSHSettings(conn); err != nil {
+		return &models.ConnectionTestResponse{
+			Success: false,
+			Message: err.Error(),
 		}, nil
 	}
 
-	if conn.Password == "" {
+	// 基于SSH的系统可以仅使用密钥认证
+	if conn.Password == "" && !isSSHSystem(conn.Type) {
 		return &models.ConnectionTestResponse{
 			Success: false,
-			Message: "密码不能为空",
+			Message: "Password is required",
 		}, nil
 	}
 
 	// 根据系统类型进行连接测试
 	switch conn.Type {
 	case models.SystemTypeCasaOS:
-		response, err := s.testCasaOSConnection(conn)
+		response, err := s.testCasaOSConnection(ctx, conn)
 		if err == nil && response.Success {
-			// 保存连接信息
-			if conn.ID == "" {
-				conn.ID = uuid.New().String()
-			}
-			s.store.SaveConnection(conn)
+			s.detectVersion(ctx, conn, response)
+			s.saveConnection(conn, response)
 		}
 		return response, err
 	case models.SystemTypeZimaOS:
-		response, err := s.testZimaOSConnection(conn)
+		response, err := s.testZimaOSConnection(ctx, conn)
 		if err == nil && response.Success {
-			// 保存连接信息
-			if conn.ID == "" {
-				conn.ID = uuid.New().String()
-			}
-			s.store.SaveConnection(conn)
+			s.detectVersion(ctx, conn, response)
+			s.saveConnection(conn, response)
+		}
+		return response, err
+	case models.SystemTypeDocker:
+		response, err := s.testDockerConnection(ctx, conn)
+		if err == nil && response.Success {
+			s.saveConnection(conn, response)
+		}
+		return response, err
+	case models.SystemTypeRuntipi:
+		response, err := s.testRuntipiConnection(ctx, conn)
+		if err == nil && response.Success {
+			s.saveConnection(conn, response)
+		}
+		return response, err
+	case models.SystemTypeTrueNAS:
+		response, err := s.testTrueNASConnection(ctx, conn)
+		if err == nil && response.Success {
+			s.saveConnection(conn, response)
 		}
 		return response, err
 	default:
 		return &models.ConnectionTestResponse{
 			Success: false,
-			Message: fmt.Sprintf("不支持的系统类型: %s", conn.Type),
+			Message: fmt.Sprintf("Unsupported system type: %s", conn.Type),
+		}, nil
+	}
+}
+
+// GetConnection 获取测试通过后保存的连接
+func (s *ConnectionService) GetConnection(connID string) (*models.SystemConnection, error) {
+	return s.store.GetConnection(connID)
+}
+
+// saveConnection 保存测试通过的连接并在响应中返回连接ID
+// 未指定ID时，类型、主机、端口和用户名都相同的连接沿用已保存的ID，避免重复测试产生多条记录
+func (s *ConnectionService) saveConnection(conn *models.SystemConnection, response *models.ConnectionTestResponse) {
+	if conn.ID == "" {
+		if conns, err := s.store.GetAllConnections(); err == nil {
+			for _, saved := range conns {
+				if saved.Type == conn.Type && saved.Host == conn.Host && saved.Port == conn.Port && saved.Username == conn.Username {
+					conn.ID = saved.ID
+					break
+				}
+			}
+		}
+	}
+	if conn.ID == "" {
+		conn.ID = uuid.New().String()
+	}
+
+	now := time.Now()
+	conn.Verified = true
+	conn.LastTestedAt = &now
+
+	saved := *conn
+	s.store.SaveConnection(&saved)
+	registerConnectionSecrets(&saved)
+	response.ConnectionID = conn.ID
+}
+
+// ListConnections 列出保存的连接，去掉密码和令牌，最近测试的在前
+func (s *ConnectionService) ListConnections() []*models.SystemConnection {
+	conns, _ := s.store.GetAllConnections()
+	redacted := make([]*models.SystemConnection, 0, len(conns))
+	for _, conn := range conns {
+		redacted = append(redacted, RedactConnection(conn))
+	}
+	sort.Slice(redacted, func(i, j int) bool {
+		a, b := redacted[i].LastTestedAt, redacted[j].LastTestedAt
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+	return redacted
+}
+
+// UpdateConnection 修改保存的连接，未提供的密码和令牌沿用原值
+// 主机、端口、用户名、类型或凭据变化后连接需要重新测试，verified置为false
+func (s *ConnectionService) UpdateConnection(connID string, update *models.SystemConnection) (*models.SystemConnection, error) {
+	existing, err := s.store.GetConnection(connID)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := *update
+	conn.ID = existing.ID
+	if conn.Password == "" {
+		conn.Password = existing.Password
+	}
+	if conn.Token == "" {
+		conn.Token = existing.Token
+	}
+	// 返回给前端的代理地址隐藏了密码，原样提交时沿用保存的值
+	if conn.Proxy != "" && conn.Proxy == redactProxyURL(existing.Proxy) {
+		conn.Proxy = existing.Proxy
+	}
+	if err := s.ValidateConnectionConfig(&conn); err != nil {
+		return nil, err
+	}
+
+	conn.Verified = existing.Verified
+	conn.LastTestedAt = existing.LastTestedAt
+	if conn.Host != existing.Host || conn.Port != existing.Port || conn.Username != existing.Username ||
+		conn.Type != existing.Type || conn.Password != existing.Password || conn.Token != existing.Token ||
+		conn.KeyFile != existing.KeyFile || conn.SSHPort != existing.SSHPort || conn.Proxy != existing.Proxy ||
+		conn.ResolveIP != existing.ResolveIP {
+		conn.Verified = false
+	}
+
+	s.store.SaveConnection(&conn)
+	registerConnectionSecrets(&conn)
+	return RedactConnection(&conn), nil
+}
+
+// DeleteConnection 删除保存的连接，已创建的任务和定时备份保留各自的连接副本
+func (s *ConnectionService) DeleteConnection(connID string) error {
+	if err := s.store.DeleteConnection(connID); err != nil {
+		return err
+	}
+	logging.ReleaseSecrets(connectionSecretOwner(connID))
+	return nil
+}
+
+// RedactConnection 返回去掉密码和令牌的连接副本，代理地址中的密码被隐藏
+func RedactConnection(conn *models.SystemConnection) *models.SystemConnection {
+	redacted := *conn
+	redacted.Password = ""
+	redacted.Token = ""
+	redacted.Proxy = redactProxyURL(conn.Proxy)
+	return &redacted
+}
+
+// testDockerConnection 通过SSH测试通用Docker主机连接
+func (s *ConnectionService) testDockerConnection(ctx context.Context, conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
+	output, err := runSSH(ctx, conn, nil, "docker compose version --short")
+	if err != nil {
+		return &models.ConnectionTestResponse{
+			Success: false,
+			Message: fmt.Sprintf("Docker host connection failed: %v", err),
+		}, nil
+	}
+
+	composeVersion := strings.TrimSpace(string(output))
+	return &models.ConnectionTestResponse{
+		Success: true,
+		Message: "Docker host connection successful",
+		SystemInfo: map[string]interface{}{
+			"type":            "Docker",
+			"host":            conn.Host,
+			"port":            sshPort(conn),
+			"username":        conn.Username,
+			"compose_version": composeVersion,
+		},
+	}, nil
+}
+
+// testRuntipiConnection 通过SSH测试Runtipi主机连接并检查安装目录
+func (s *ConnectionService) testRuntipiConnection(ctx context.Context, conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
+	rootDir := runtipiRootDir(conn)
+	remoteCmd := fmt.Sprintf("test -d %[1]s/apps && test -d %[1]s/app-data && ls -1 %[1]s/apps | wc -l", shellQuote(rootDir))
+	output, err := runSSH(ctx, conn, nil, remoteCmd)
+	if err != nil {
+		return &models.ConnectionTestResponse{
+			Success: false,
+			Message: fmt.Sprintf("Runtipi connection failed (is %s a Runtipi installation?): %v", rootDir, err),
 		}, nil
 	}
+
+	return &models.ConnectionTestResponse{
+		Success: true,
+		Message: "Runtipi connection successful",
+		SystemInfo: map[string]interface{}{
+			"type":      "Runtipi",
+			"host":      conn.Host,
+			"port":      sshPort(conn),
+			"username":  conn.Username,
+			"root_dir":  rootDir,
+			"app_count": strings.TrimSpace(string(output)),
+		},
+	}, nil
+}
+
+// testTrueNASConnection 通过SSH测试TrueNAS SCALE主机连接并定位应用数据集
+func (s *ConnectionService) testTrueNASConnection(ctx context.Context, conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
+	layout, err := detectTrueNASLayout(ctx, conn)
+	if err != nil {
+		return &models.ConnectionTestResponse{
+			Success: false,
+			Message: fmt.Sprintf("TrueNAS connection failed: %v", err),
+		}, nil
+	}
+
+	return &models.ConnectionTestResponse{
+		Success: true,
+		Message: "TrueNAS connection successful",
+		SystemInfo: map[string]interface{}{
+			"type":      "TrueNAS SCALE",
+			"host":      conn.Host,
+			"port":      sshPort(conn),
+			"username":  conn.Username,
+			"root_dir":  layout.RootDir,
+			"apps_type": layout.Kind,
+		},
+	}, nil
 }
 
 // testCasaOSConnection 测试CasaOS连接
-func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
+func (s *ConnectionService) testCasaOSConnection(ctx context.Context, conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
 	// 构建登录API URL
-	apiURL := fmt.Sprintf("http://%s:%d/v1/users/login", conn.Host, conn.Port)
+	apiURL := connBaseURL(conn) + "/v1/users/login"
 
 	// 构建登录请求体
 	loginData := map[string]string{
@@ -118,17 +401,19 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	}
 
 	// 调试日志：记录请求信息
-	fmt.Printf("[CasaOS DEBUG] Request URL: %s\n", apiURL)
-	fmt.Printf("[CasaOS DEBUG] Request body: %s\n", string(loginJSON))
+	log.Printf("[DEBUG] CasaOS login: request URL: %s", apiURL)
+	log.Printf("[DEBUG] CasaOS login: request body: %s", string(loginJSON))
 
 	// 创建登录请求
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(string(loginJSON)))
+	req, err := newConnRequest(ctx, conn, "POST", apiURL, strings.NewReader(string(loginJSON)))
 	if err != nil {
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create login request: %v", err),
 		}, nil
 	}
+	// 登录请求重复发送没有副作用，临时故障时可以重试
+	req = markIdempotent(req)
 
 	// 设置请求头
 	req.Header.Set("Accept", "application/json, text/plain, */*")
@@ -136,17 +421,17 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Language", "en_us")
-	req.Header.Set("Origin", fmt.Sprintf("http://%s:%d", conn.Host, conn.Port))
-	req.Header.Set("Referer", fmt.Sprintf("http://%s:%d/", conn.Host, conn.Port))
+	req.Header.Set("Origin", connBaseURL(conn))
+	req.Header.Set("Referer", connBaseURL(conn)+"/")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36")
 
 	// 调试日志：记录请求头
-	fmt.Printf("[CasaOS DEBUG] Request headers: %+v\n", req.Header)
+	log.Printf("[DEBUG] CasaOS login: request headers: %+v", req.Header)
 
 	// 发送登录请求
 	resp, err := s.client.Do(req)
 	if err != nil {
-		fmt.Printf("[CasaOS DEBUG] Request failed: %v\n", err)
+		log.Printf("[DEBUG] CasaOS login: request failed: %v", err)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("CasaOS login connection failed: %v", err),
@@ -155,13 +440,13 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	defer resp.Body.Close()
 
 	// 调试日志：记录响应状态码
-	fmt.Printf("[CasaOS DEBUG] Response status: %d\n", resp.StatusCode)
-	fmt.Printf("[CasaOS DEBUG] Response headers: %+v\n", resp.Header)
+	log.Printf("[DEBUG] CasaOS login: response status: %d", resp.StatusCode)
+	log.Printf("[DEBUG] CasaOS login: response headers: %+v", resp.Header)
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Printf("[CasaOS DEBUG] Failed to read response: %v\n", err)
+		log.Printf("[DEBUG] CasaOS login: failed to read response: %v", err)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to read CasaOS login response: %v", err),
@@ -169,11 +454,11 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	}
 
 	// 调试日志：记录完整响应内容
-	fmt.Printf("[CasaOS DEBUG] Response body: %s\n", string(body))
+	log.Printf("[DEBUG] CasaOS login: response body: %s", string(body))
 
 	// 检查状态码
 	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[CasaOS DEBUG] HTTP status error: %d\n", resp.StatusCode)
+		log.Printf("[DEBUG] CasaOS login: HTTP status error: %d", resp.StatusCode)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("CasaOS login failed, status code: %d, response: %s", resp.StatusCode, string(body)),
@@ -183,7 +468,7 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	// 解析登录响应
 	var loginResponse map[string]interface{}
 	if err := json.Unmarshal(body, &loginResponse); err != nil {
-		fmt.Printf("[CasaOS DEBUG] JSON parse failed: %v\n", err)
+		log.Printf("[DEBUG] CasaOS login: JSON parse failed: %v", err)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to parse CasaOS login response: %v", err),
@@ -191,7 +476,7 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	}
 
 	// 调试日志：记录解析后的响应结构
-	fmt.Printf("[CasaOS DEBUG] Parsed response: %+v\n", loginResponse)
+	log.Printf("[DEBUG] CasaOS login: parsed response: %+v", loginResponse)
 
 	// 检查登录是否成功 - 支持数字200和布尔值true
 	var isSuccess bool
@@ -200,7 +485,7 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 	} else if successNum, ok := loginResponse["success"].(float64); ok {
 		isSuccess = successNum == 200
 	} else {
-		fmt.Printf("[CasaOS DEBUG] Unknown success field type: %T, value: %v\n", loginResponse["success"], loginResponse["success"])
+		log.Printf("[DEBUG] CasaOS login: unknown success field type: %T, value: %v", loginResponse["success"], loginResponse["success"])
 		isSuccess = false
 	}
 
@@ -223,19 +508,20 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 		if tokenData, ok := data["token"].(map[string]interface{}); ok {
 			if accessToken, ok := tokenData["access_token"].(string); ok {
 				token = accessToken
-				fmt.Printf("[CasaOS DEBUG] Extracted token: %s\n", token)
+				log.Printf("[DEBUG] CasaOS login: token received (%d characters)", len(token))
 			} else {
-				fmt.Printf("[CasaOS DEBUG] access_token missing or wrong type: %T, value: %v\n", tokenData["access_token"], tokenData["access_token"])
+				log.Printf("[DEBUG] CasaOS login: access_token missing or wrong type: %T, value: %v", tokenData["access_token"], tokenData["access_token"])
 			}
 		} else {
-			fmt.Printf("[CasaOS DEBUG] token missing or wrong type: %T, value: %v\n", data["token"], data["token"])
+			log.Printf("[DEBUG] CasaOS login: token missing or wrong type: %T, value: %v", data["token"], data["token"])
 		}
 	} else {
-		fmt.Printf("[CasaOS DEBUG] data missing or wrong type: %T, value: %v\n", loginResponse["data"], loginResponse["data"])
+		log.Printf("[DEBUG] CasaOS login: data missing or wrong type: %T, value: %v", loginResponse["data"], loginResponse["data"])
 	}
 
-	// 保存token到连接信息
+	// 保存token到连接信息，之后的日志中不再出现该令牌
 	conn.Token = token
+	logging.RegisterSecret(secretOwner(ctx), token)
 
 	return &models.ConnectionTestResponse{
 		Success: true,
@@ -251,9 +537,9 @@ func (s *ConnectionService) testCasaOSConnection(conn *models.SystemConnection)
 }
 
 // testZimaOSConnection 测试ZimaOS连接
-func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
+func (s *ConnectionService) testZimaOSConnection(ctx context.Context, conn *models.SystemConnection) (*models.ConnectionTestResponse, error) {
 	// 构建登录API URL
-	apiURL := fmt.Sprintf("http://%s:%d/v1/users/login", conn.Host, conn.Port)
+	apiURL := connBaseURL(conn) + "/v1/users/login"
 
 	// 构建登录请求体
 	loginData := map[string]string{
@@ -270,34 +556,36 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 	}
 
 	// 调试日志：记录请求信息
-	fmt.Printf("[ZimaOS DEBUG] Request URL: %s\n", apiURL)
-	fmt.Printf("[ZimaOS DEBUG] Request body: %s\n", string(loginJSON))
+	log.Printf("[DEBUG] ZimaOS login: request URL: %s", apiURL)
+	log.Printf("[DEBUG] ZimaOS login: request body: %s", string(loginJSON))
 
 	// 创建登录请求
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(string(loginJSON)))
+	req, err := newConnRequest(ctx, conn, "POST", apiURL, strings.NewReader(string(loginJSON)))
 	if err != nil {
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create login request: %v", err),
 		}, nil
 	}
+	// 登录请求重复发送没有副作用，临时故障时可以重试
+	req = markIdempotent(req)
 
 	// 设置请求头
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Origin", fmt.Sprintf("http://%s:%d", conn.Host, conn.Port))
-	req.Header.Set("Referer", fmt.Sprintf("http://%s:%d/", conn.Host, conn.Port))
+	req.Header.Set("Origin", connBaseURL(conn))
+	req.Header.Set("Referer", connBaseURL(conn)+"/")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36")
 
 	// 调试日志：记录请求头
-	fmt.Printf("[ZimaOS DEBUG] Request headers: %+v\n", req.Header)
+	log.Printf("[DEBUG] ZimaOS login: request headers: %+v", req.Header)
 
 	// 发送登录请求
 	resp, err := s.client.Do(req)
 	if err != nil {
-		fmt.Printf("[ZimaOS DEBUG] Request failed: %v\n", err)
+		log.Printf("[DEBUG] ZimaOS login: request failed: %v", err)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("ZimaOS login connection failed: %v", err),
@@ -306,13 +594,13 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 	defer resp.Body.Close()
 
 	// 调试日志：记录响应状态码
-	fmt.Printf("[ZimaOS DEBUG] Response status: %d\n", resp.StatusCode)
-	fmt.Printf("[ZimaOS DEBUG] Response headers: %+v\n", resp.Header)
+	log.Printf("[DEBUG] ZimaOS login: response status: %d", resp.StatusCode)
+	log.Printf("[DEBUG] ZimaOS login: response headers: %+v", resp.Header)
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Printf("[ZimaOS DEBUG] Failed to read response: %v\n", err)
+		log.Printf("[DEBUG] ZimaOS login: failed to read response: %v", err)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to read ZimaOS login response: %v", err),
@@ -320,11 +608,11 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 	}
 
 	// 调试日志：记录完整响应内容
-	fmt.Printf("[ZimaOS DEBUG] Response body: %s\n", string(body))
+	log.Printf("[DEBUG] ZimaOS login: response body: %s", string(body))
 
 	// 检查状态码
 	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[ZimaOS DEBUG] HTTP status error: %d\n", resp.StatusCode)
+		log.Printf("[DEBUG] ZimaOS login: HTTP status error: %d", resp.StatusCode)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("ZimaOS login failed, status code: %d, response: %s", resp.StatusCode, string(body)),
@@ -334,7 +622,7 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 	// 解析登录响应
 	var loginResponse map[string]interface{}
 	if err := json.Unmarshal(body, &loginResponse); err != nil {
-		fmt.Printf("[ZimaOS DEBUG] JSON parse failed: %v\n", err)
+		log.Printf("[DEBUG] ZimaOS login: JSON parse failed: %v", err)
 		return &models.ConnectionTestResponse{
 			Success: false,
 			Message: fmt.Sprintf("Failed to parse ZimaOS login response: %v", err),
@@ -342,7 +630,7 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 	}
 
 	// 调试日志：记录解析后的响应结构
-	fmt.Printf("[ZimaOS DEBUG] Parsed response: %+v\n", loginResponse)
+	log.Printf("[DEBUG] ZimaOS login: parsed response: %+v", loginResponse)
 
 	// 检查登录是否成功 - 支持数字200和布尔值true
 	var isSuccess bool
@@ -351,7 +639,7 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 	} else if successNum, ok := loginResponse["success"].(float64); ok {
 		isSuccess = successNum == 200
 	} else {
-		fmt.Printf("[ZimaOS DEBUG] Unknown success field type: %T, value: %v\n", loginResponse["success"], loginResponse["success"])
+		log.Printf("[DEBUG] ZimaOS login: unknown success field type: %T, value: %v", loginResponse["success"], loginResponse["success"])
 		isSuccess = false
 	}
 
@@ -374,19 +662,20 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 		if tokenData, ok := data["token"].(map[string]interface{}); ok {
 			if accessToken, ok := tokenData["access_token"].(string); ok {
 				token = accessToken
-				fmt.Printf("[ZimaOS DEBUG] Extracted token: %s\n", token)
+				log.Printf("[DEBUG] ZimaOS login: token received (%d characters)", len(token))
 			} else {
-				fmt.Printf("[ZimaOS DEBUG] access_token missing or wrong type: %T, value: %v\n", tokenData["access_token"], tokenData["access_token"])
+				log.Printf("[DEBUG] ZimaOS login: access_token missing or wrong type: %T, value: %v", tokenData["access_token"], tokenData["access_token"])
 			}
 		} else {
-			fmt.Printf("[ZimaOS DEBUG] token missing or wrong type: %T, value: %v\n", data["token"], data["token"])
+			log.Printf("[DEBUG] ZimaOS login: token missing or wrong type: %T, value: %v", data["token"], data["token"])
 		}
 	} else {
-		fmt.Printf("[ZimaOS DEBUG] data missing or wrong type: %T, value: %v\n", loginResponse["data"], loginResponse["data"])
+		log.Printf("[DEBUG] ZimaOS login: data missing or wrong type: %T, value: %v", loginResponse["data"], loginResponse["data"])
 	}
 
-	// 保存token到连接信息
+	// 保存token到连接信息，之后的日志中不再出现该令牌
 	conn.Token = token
+	logging.RegisterSecret(secretOwner(ctx), token)
 
 	return &models.ConnectionTestResponse{
 		Success: true,
@@ -402,7 +691,7 @@ func (s *ConnectionService) testZimaOSConnection(conn *models.SystemConnection)
 }
 
 // GetSystemInfo 获取系统信息
-func (s *ConnectionService) GetSystemInfo(conn *models.SystemConnection) (map[string]interface{}, error) {
+func (s *ConnectionService) GetSystemInfo(ctx context.Context, conn *models.SystemConnection) (map[string]interface{}, error) {
 	if conn == nil {
 		return nil, fmt.Errorf("connection info must not be empty")
 	}
@@ -410,17 +699,17 @@ func (s *ConnectionService) GetSystemInfo(conn *models.SystemConnection) (map[st
 	var apiURL string
 	switch conn.Type {
 	case models.SystemTypeCasaOS:
-		apiURL = fmt.Sprintf("http://%s:%d/v1/sys/info", conn.Host, conn.Port)
+		apiURL = connBaseURL(conn) + "/v1/sys/info"
 	case models.SystemTypeZimaOS:
-		apiURL = fmt.Sprintf("http://%s:%d/v2/sys/info", conn.Host, conn.Port)
+		apiURL = connBaseURL(conn) + "/v2/sys/info"
 	default:
 		return nil, fmt.Errorf("unsupported system type: %s", conn.Type)
 	}
 
 	// 创建请求
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := newConnRequest(ctx, conn, "GET", apiURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+		return nil, fmt.Errorf("Failed to create request: %v", err)
 	}
 
 	// 添加认证头
@@ -431,25 +720,25 @@ func (s *ConnectionService) GetSystemInfo(conn *models.SystemConnection) (map[st
 	// 发送请求
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+		return nil, fmt.Errorf("Request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("Failed to read response: %v", err)
 	}
 
 	// 检查状态码
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("Request failed, status code: %d, response: %s", resp.StatusCode, string(body))
 	}
 
 	// 解析响应
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+		return nil, fmt.Errorf("Failed to parse response: %v", err)
 	}
 
 	return result, nil
@@ -458,23 +747,24 @@ func (s *ConnectionService) GetSystemInfo(conn *models.SystemConnection) (map[st
 // ValidateConnectionConfig 验证连接配置
 func (s *ConnectionService) ValidateConnectionConfig(conn *models.SystemConnection) error {
 	if conn == nil {
-		return fmt.Errorf("连接信息不能为空")
+		return fmt.Errorf("Connection information is required")
 	}
-
 	if strings.TrimSpace(conn.Host) == "" {
-		return fmt.Errorf("主机地址不能为空")
+		return fmt.Errorf("Host is required")
+	}
+	if err := normalizeHost(conn); err != nil {
+		return err
 	}
 
 	if conn.Port <= 0 || conn.Port > 65535 {
-		return fmt.Errorf("端口号必须在1-65535之间")
+		return fmt.Errorf("Port must be between 1 and 65535")
 	}
 
 	if strings.TrimSpace(conn.Username) == "" {
-		return fmt.Errorf("用户名不能为空")
+		return fmt.Errorf("Username is required")
 	}
-
-	if strings.TrimSpace(conn.Password) == "" {
-		return fmt.Errorf("密码不能为空")
+	if err := s.checkSSHSettings(conn); err != nil {
+		return err
 	}
 
 	// 修复系统类型大小写问题
@@ -483,9 +773,74 @@ func (s *ConnectionService) ValidateConnectionConfig(conn *models.SystemConnecti
 		conn.Type = models.SystemTypeCasaOS
 	} else if lowerType == "zimaos" {
 		conn.Type = models.SystemTypeZimaOS
+	} else if lowerType == "docker" {
+		conn.Type = models.SystemTypeDocker
+	} else if lowerType == "runtipi" {
+		conn.Type = models.SystemTypeRuntipi
+	} else if lowerType == "truenas" {
+		conn.Type = models.SystemTypeTrueNAS
 	} else {
-		return fmt.Errorf("不支持的系统类型: %s", conn.Type)
+		return fmt.Errorf("Unsupported system type: %s", conn.Type)
+	}
+
+	if conn.Proxy != "" {
+		if err := validateProxyURL(conn.Proxy); err != nil {
+			return err
+		}
+	}
+	if conn.ResolveIP != "" && net.ParseIP(conn.ResolveIP) == nil {
+		return fmt.Errorf("Invalid resolve_ip: %s", conn.ResolveIP)
+	}
+
+	// 基于SSH的系统可以仅使用密钥认证
+	if strings.TrimSpace(conn.Password) == "" && !(isSSHSystem(conn.Type) && conn.KeyFile != "") {
+		return fmt.Errorf("Password is required")
 	}
 
 	return nil
 }
+
+// checkSSHSettings 检查用户名和主机能否安全地作为ssh/rsync参数，并将key_file解析为SSH密钥目录中的文件
+func (s *ConnectionService) checkSSHSettings(conn *models.SystemConnection) error {
+	if err := checkSSHDestination(conn); err != nil {
+		return err
+	}
+	if conn.KeyFile == "" {
+		return nil
+	}
+	keyFile, err := resolveSSHKeyFile(s.sshKeyDir, conn.KeyFile)
+	if err != nil {
+		return err
+	}
+	conn.KeyFile = keyFile
+	return nil
+}
+
+// resolveSSHKeyFile 将相对路径按SSH密钥目录解析，解析符号链接后仍须是该目录中的普通文件
+func resolveSSHKeyFile(keyDir, keyFile string) (string, error) {
+	root, err := filepath.Abs(keyDir)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", fmt.Errorf("SSH key directory is not available: %v", err)
+	}
+
+	candidate := keyFile
+	if !filepath.IsAbs(candidate) {
+		candidate = filepath.Join(root, candidate)
+	}
+	resolved, err := filepath.EvalSymlinks(candidate)
+	if err != nil || !isWithinDir(root, resolved) || resolved == root {
+		return "", fmt.Errorf("Invalid key_file %s: must be a file in the SSH key directory", keyFile)
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("Invalid key_file %s: must be a file in the SSH key directory", keyFile)
+	}
+	return resolved, nil
+}
+
+// isSSHSystem 判断系统类型是否通过SSH访问
+func isSSHSystem(systemType string) bool {
+	return systemType == models.SystemTypeDocker || systemType == models.SystemTypeRuntipi || systemType == models.SystemTypeTrueNAS
+}
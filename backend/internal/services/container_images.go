@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// transferImagesOption 迁移选项：在CasaOS源上用docker save保存应用使用的镜像，迁移后在目标上docker load
+	// 目标无法访问镜像仓库时也能启动应用
+	transferImagesOption = "transfer_images"
+	// containerImagesDir 源系统上镜像包在应用AppData目录中的位置，随AppData下载
+	containerImagesDir = ".ctoz-images"
+	// containerImagesFile 镜像包文件名
+	containerImagesFile = "images.tar"
+	// exportImagesDir 导出压缩包和解压目录中存放各应用镜像包（<app>.tar）的目录
+	exportImagesDir = "images"
+)
+
+// transferImages 迁移选项是否开启了transfer_images
+func transferImages(options map[string]interface{}) bool {
+	images, ok := options[transferImagesOption].(bool)
+	return ok && images
+}
+
+// containerImagesApp 判断压缩包条目是否位于应用AppData的镜像包目录中，返回所属应用
+func containerImagesApp(name string) (string, bool) {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+	rest := strings.TrimPrefix(name, "DATA/AppData/")
+	if rest == name {
+		return "", false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] != containerImagesDir {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// exportImagePath 应用镜像包在导出压缩包和解压目录中的相对路径
+func exportImagePath(app string) string {
+	return path.Join(exportImagesDir, app+".tar")
+}
+
+// collectContainerImages 将解压目录中随AppData下载的镜像包移到images目录，镜像包不再随AppData上传到目标
+func collectContainerImages(extractedPath string) error {
+	appDataDir := filepath.Join(extractedPath, "DATA/AppData")
+	entries, err := os.ReadDir(appDataDir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		imageDir := filepath.Join(appDataDir, entry.Name(), containerImagesDir)
+		archive := filepath.Join(imageDir, containerImagesFile)
+		if _, err := os.Stat(archive); err != nil {
+			continue
+		}
+		dest := filepath.Join(extractedPath, filepath.FromSlash(exportImagePath(entry.Name())))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("Failed to create images directory: %v", err)
+		}
+		if err := os.Rename(archive, dest); err != nil {
+			return fmt.Errorf("Failed to move container images of app %s: %v", entry.Name(), err)
+		}
+		os.RemoveAll(imageDir)
+	}
+	return nil
+}
+
+// saveSourceImages 开启transfer_images时在CasaOS源上把每个compose应用使用的镜像保存到应用的AppData目录，返回写入了镜像包的目录
+// 单个应用保存失败只记录警告，该应用在目标上仍从镜像仓库拉取
+func (s *MigrationService) saveSourceImages(ctx context.Context, taskID string, conn *models.SystemConnection) []string {
+	if conn.SSHPort <= 0 {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, "Transferring container images requires ssh_port on the source connection, skipping")
+		return nil
+	}
+	sshConn := *conn
+	sshConn.Port = conn.SSHPort
+
+	// 包括已停止的容器，stop_source_apps停止的应用也能保存
+	output, err := runSSH(ctx, &sshConn, nil, "docker ps -a --format "+shellQuote(`{{.Label "com.docker.compose.project"}}	{{.Image}}`))
+	if err != nil {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Could not list source containers, images were not saved: %v", err))
+		return nil
+	}
+
+	imagesByApp := make(map[string][]string)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		project, image := fields[0], fields[1]
+		if seen[project+"\t"+image] {
+			continue
+		}
+		seen[project+"\t"+image] = true
+		imagesByApp[project] = append(imagesByApp[project], image)
+	}
+
+	apps := make([]string, 0, len(imagesByApp))
+	for app := range imagesByApp {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	var imageDirs []string
+	for _, app := range apps {
+		if ctx.Err() != nil {
+			break
+		}
+		images := imagesByApp[app]
+		imageDir := path.Join(casaOSAppDataDir, app, containerImagesDir)
+		quoted := make([]string, 0, len(images))
+		for _, image := range images {
+			quoted = append(quoted, shellQuote(image))
+		}
+		remoteCmd := fmt.Sprintf("mkdir -p %s && docker save -o %s %s", shellQuote(imageDir), shellQuote(path.Join(imageDir, containerImagesFile)), strings.Join(quoted, " "))
+		if _, err := runSSH(ctx, &sshConn, nil, remoteCmd); err != nil {
+			log.Printf("[WARNING] Failed to save images of app %s: %v", app, err)
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to save container images, the target will pull them: %v", app, err))
+			// 删除保存了一半的镜像包
+			imageDirs = append(imageDirs, imageDir)
+			continue
+		}
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: saved %d container images", app, len(images)))
+		imageDirs = append(imageDirs, imageDir)
+	}
+	return imageDirs
+}
+
+// loadContainerImages 在导入compose前把解压目录images中的镜像包加载到目标系统
+// 只要有应用的镜像包就加载，离线导入不需要再设置选项；加载失败只记录警告，目标仍会尝试拉取镜像
+func (s *MigrationService) loadContainerImages(ctx context.Context, task *models.MigrationTask, target TargetAdapter, appStatuses []models.AppImportStatus, extractedPath string) {
+	var apps []int
+	for i := range appStatuses {
+		// 未确认的高权限应用不导入，恢复的任务中已导入的应用不再加载
+		if appStatuses[i].OverallStatus == models.AppStatusWarning || appStatuses[i].ComposeStatus == models.AppStatusSuccess {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(extractedPath, filepath.FromSlash(exportImagePath(appStatuses[i].AppName)))); err == nil {
+			apps = append(apps, i)
+		}
+	}
+	if len(apps) == 0 {
+		return
+	}
+
+	err := s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Load container images", func(ctx context.Context, progressCallback func(int, string)) error {
+		for n, i := range apps {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			appName := appStatuses[i].AppName
+			progressCallback(100*n/len(apps), fmt.Sprintf("Loading container images of %s (%d/%d)...", appName, n+1, len(apps)))
+
+			archive := filepath.Join(extractedPath, filepath.FromSlash(exportImagePath(appName)))
+			if err := target.LoadImages(ctx, appName, archive); err != nil {
+				log.Printf("[WARNING] App %s image load failed: %v", appName, err)
+				appStatuses[i].Warnings = append(appStatuses[i].Warnings, fmt.Sprintf("Container images could not be loaded on the target, they will be pulled from the registry: %v", err))
+				s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to load container images: %v", appName, err))
+				continue
+			}
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s: container images loaded ✓", appName))
+		}
+		s.saveAppImportStatuses(task.ID, appStatuses)
+		progressCallback(100, "Container images loaded")
+		return nil
+	})
+	if err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to load container images: %v", err))
+	}
+}
+
+// loadImagesOverSSH 通过SSH把本地镜像包传给目标主机上的docker load
+func loadImagesOverSSH(ctx context.Context, conn *models.SystemConnection, archive string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("Failed to open image archive: %v", err)
+	}
+	defer file.Close()
+	_, err = runSSH(ctx, conn, file, "docker load -q")
+	return err
+}
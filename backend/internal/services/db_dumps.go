@@ -0,0 +1,272 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// databaseDumpsOption 迁移选项：复制AppData前在源容器内导出数据库，导入后在目标上恢复
+	databaseDumpsOption = "database_dumps"
+	// databaseDumpDir 数据库导出文件在应用AppData目录中的位置，随AppData一起迁移
+	databaseDumpDir = ".ctoz-dumps"
+	// databaseReadyTimeout 恢复前等待目标数据库可连接的最长时间
+	databaseReadyTimeout = 2 * time.Minute
+)
+
+// databaseEngine 内置的数据库处理器，命令均在容器内通过sh -c执行，可使用容器的环境变量
+type databaseEngine struct {
+	Name   string
+	Images []string // 镜像名（不含仓库和标签）
+	// Dump 导出命令，输出写入转储文件；Restore为空时只执行刷盘，不生成转储文件
+	Dump    string
+	Ready   string
+	Restore string
+}
+
+// databaseEngines 支持的数据库，按镜像名匹配
+var databaseEngines = []databaseEngine{
+	{
+		Name:    "postgres",
+		Images:  []string{"postgres", "postgresql", "postgis"},
+		Dump:    `pg_dumpall --clean --if-exists -U "${POSTGRES_USER:-postgres}"`,
+		Ready:   `pg_isready -U "${POSTGRES_USER:-postgres}"`,
+		Restore: `psql -q -U "${POSTGRES_USER:-postgres}" -d postgres`,
+	},
+	{
+		Name:    "mariadb",
+		Images:  []string{"mariadb"},
+		Dump:    `MYSQL_PWD="${MARIADB_ROOT_PASSWORD:-$MYSQL_ROOT_PASSWORD}" $(command -v mariadb-dump || echo mysqldump) -uroot --all-databases --single-transaction --routines --events --add-drop-database`,
+		Ready:   `MYSQL_PWD="${MARIADB_ROOT_PASSWORD:-$MYSQL_ROOT_PASSWORD}" $(command -v mariadb-admin || echo mysqladmin) -uroot ping`,
+		Restore: `MYSQL_PWD="${MARIADB_ROOT_PASSWORD:-$MYSQL_ROOT_PASSWORD}" $(command -v mariadb || echo mysql) -uroot`,
+	},
+	{
+		Name:    "mysql",
+		Images:  []string{"mysql"},
+		Dump:    `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" mysqldump -uroot --all-databases --single-transaction --routines --events --add-drop-database`,
+		Ready:   `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" mysqladmin -uroot ping`,
+		Restore: `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" mysql -uroot`,
+	},
+	{
+		// Redis只需把内存数据写入数据目录中的dump.rdb，启动时自动加载
+		Name:   "redis",
+		Images: []string{"redis", "redis-stack-server"},
+		Dump:   `redis-cli ${REDIS_PASSWORD:+-a "$REDIS_PASSWORD"} SAVE`,
+	},
+}
+
+// databaseEngineFor 根据镜像匹配数据库处理器，未匹配时返回nil
+func databaseEngineFor(image string) *databaseEngine {
+	name := image
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ToLower(name)
+	for i := range databaseEngines {
+		for _, img := range databaseEngines[i].Images {
+			if name == img {
+				return &databaseEngines[i]
+			}
+		}
+	}
+	return nil
+}
+
+// databaseDumpFile 转储文件名，包含服务名和数据库类型，恢复时据此选择处理器
+func databaseDumpFile(service, engine string) string {
+	return fmt.Sprintf("%s.%s.sql", service, engine)
+}
+
+// composeServiceExec 生成在compose服务的运行中容器内执行命令的shell片段，调用方可追加重定向
+func composeServiceExec(project, service, command string) string {
+	return fmt.Sprintf(`c=$(docker ps -q --filter label=com.docker.compose.project=%s --filter label=com.docker.compose.service=%s | head -n 1); [ -n "$c" ] || { echo "no running container" >&2; exit 1; }; docker exec -i "$c" sh -c %s`,
+		shellQuote(project), shellQuote(service), shellQuote(command))
+}
+
+// dumpSourceDatabases 开启database_dumps时在CasaOS源上导出运行中的数据库容器，返回写入了转储文件的目录
+// 单个数据库导出失败只记录警告，该应用按原始文件复制
+func (s *MigrationService) dumpSourceDatabases(ctx context.Context, taskID string, conn *models.SystemConnection) []string {
+	if conn.SSHPort <= 0 {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, "Database dumps require ssh_port on the source connection, skipping")
+		return nil
+	}
+	sshConn := *conn
+	sshConn.Port = conn.SSHPort
+
+	output, err := runSSH(ctx, &sshConn, nil, "docker ps --format "+shellQuote(`{{.Label "com.docker.compose.project"}}	{{.Label "com.docker.compose.service"}}	{{.Image}}`))
+	if err != nil {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Could not list source containers, databases were not dumped: %v", err))
+		return nil
+	}
+
+	var dumpDirs []string
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) != 3 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		project, service := fields[0], fields[1]
+		engine := databaseEngineFor(fields[2])
+		if engine == nil {
+			continue
+		}
+
+		remoteCmd := composeServiceExec(project, service, engine.Dump) + " >/dev/null"
+		dumpDir := path.Join(casaOSAppDataDir, project, databaseDumpDir)
+		if engine.Restore != "" {
+			dumpPath := path.Join(dumpDir, databaseDumpFile(service, engine.Name))
+			remoteCmd = fmt.Sprintf("mkdir -p %s && %s > %s", shellQuote(dumpDir), composeServiceExec(project, service, engine.Dump), shellQuote(dumpPath))
+		}
+		if _, err := runSSH(ctx, &sshConn, nil, remoteCmd); err != nil {
+			log.Printf("[WARNING] Failed to dump %s database of app %s: %v", engine.Name, project, err)
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to dump %s database in service %s, copying raw files: %v", project, engine.Name, service, err))
+			continue
+		}
+
+		if engine.Restore == "" {
+			s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: %s data in service %s flushed to disk", project, engine.Name, service))
+			continue
+		}
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: %s database in service %s dumped", project, engine.Name, service))
+		dumpDirs = append(dumpDirs, dumpDir)
+	}
+	return dumpDirs
+}
+
+// removeSourceDumps 复制完成后删除源系统上的转储文件或镜像包所在目录
+func (s *MigrationService) removeSourceDumps(ctx context.Context, conn *models.SystemConnection, dumpDirs []string) {
+	if len(dumpDirs) == 0 {
+		return
+	}
+	sshConn := *conn
+	sshConn.Port = conn.SSHPort
+	quoted := make([]string, 0, len(dumpDirs))
+	for _, dir := range dumpDirs {
+		quoted = append(quoted, shellQuote(dir))
+	}
+	if _, err := runSSH(ctx, &sshConn, nil, "rm -rf "+strings.Join(quoted, " ")); err != nil {
+		log.Printf("[WARNING] Failed to remove database dumps on %s: %v", conn.Host, err)
+	}
+}
+
+// restoreDatabaseDumps 在目标系统上恢复随AppData迁移的数据库转储
+// 应用未运行时先启动应用，恢复失败只记录在应用状态中，转储文件保留在AppData目录中可手动恢复
+func (s *MigrationService) restoreDatabaseDumps(ctx context.Context, task *models.MigrationTask, target TargetAdapter, appStatuses []models.AppImportStatus, appDataPath string) {
+	if dumps, ok := task.Options[databaseDumpsOption].(bool); !ok || !dumps {
+		return
+	}
+
+	// 收集AppData和compose都已导入且带有转储文件的应用
+	dumpsByApp := make(map[int][]string)
+	for i := range appStatuses {
+		if appStatuses[i].AppDataStatus != models.AppStatusSuccess || appStatuses[i].ComposeStatus != models.AppStatusSuccess {
+			continue
+		}
+		files, _ := filepath.Glob(filepath.Join(appDataPath, appStatuses[i].AppName, databaseDumpDir, "*.sql"))
+		if len(files) > 0 {
+			dumpsByApp[i] = files
+		}
+	}
+	if len(dumpsByApp) == 0 {
+		return
+	}
+
+	err := s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Restore database dumps", func(ctx context.Context, progressCallback func(int, string)) error {
+		n := 0
+		for i := range appStatuses {
+			files, ok := dumpsByApp[i]
+			if !ok {
+				continue
+			}
+			appName := appStatuses[i].AppName
+			progressCallback(100*n/len(dumpsByApp), fmt.Sprintf("Restoring databases of %s (%d/%d)...", appName, n+1, len(dumpsByApp)))
+			n++
+
+			if err := s.restoreAppDatabases(ctx, task.ID, target, &appStatuses[i], files); err != nil {
+				log.Printf("[WARNING] App %s database restore failed: %v", appName, err)
+				if appStatuses[i].ErrorMessage == "" {
+					appStatuses[i].ErrorMessage = fmt.Sprintf("Database restore failed: %v", err)
+				} else {
+					appStatuses[i].ErrorMessage += fmt.Sprintf("; Database restore failed: %v", err)
+				}
+				s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: database restore failed, dumps are kept in %s: %v", appName, databaseDumpDir, err))
+			}
+			s.saveAppImportStatuses(task.ID, appStatuses)
+		}
+		progressCallback(100, "Database restore completed")
+		return nil
+	})
+	if err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to restore database dumps: %v", err))
+	}
+}
+
+// restoreAppDatabases 启动应用（如未运行）并逐个恢复其数据库转储
+func (s *MigrationService) restoreAppDatabases(ctx context.Context, taskID string, target TargetAdapter, status *models.AppImportStatus, files []string) error {
+	appName := status.AppName
+	if status.RuntimeStatus != models.AppRuntimeRunning && status.RuntimeStatus != models.AppRuntimeHealthy {
+		runtimeStatus, err := target.StartApp(ctx, appName, taskID)
+		status.RuntimeStatus = runtimeStatus
+		if err != nil {
+			status.RuntimeMessage = err.Error()
+			return fmt.Errorf("Failed to start app: %v", err)
+		}
+	}
+
+	for _, file := range files {
+		// 文件名格式: <service>.<engine>.sql
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(file), ".sql"), ".")
+		if len(parts) < 2 {
+			continue
+		}
+		service, engineName := strings.Join(parts[:len(parts)-1], "."), parts[len(parts)-1]
+		var engine *databaseEngine
+		for j := range databaseEngines {
+			if databaseEngines[j].Name == engineName {
+				engine = &databaseEngines[j]
+			}
+		}
+		if engine == nil || engine.Restore == "" {
+			continue
+		}
+
+		if err := waitForDatabase(ctx, target, appName, service, engine); err != nil {
+			return err
+		}
+		dumpFile := path.Join(databaseDumpDir, filepath.Base(file))
+		if _, err := target.ExecInService(ctx, appName, service, engine.Restore, dumpFile); err != nil {
+			return fmt.Errorf("Failed to restore %s database in service %s: %v", engine.Name, service, err)
+		}
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: %s database in service %s restored ✓", appName, engine.Name, service))
+	}
+	return nil
+}
+
+// waitForDatabase 等待目标容器中的数据库可以连接
+func waitForDatabase(ctx context.Context, target TargetAdapter, appName, service string, engine *databaseEngine) error {
+	deadline := time.Now().Add(databaseReadyTimeout)
+	for {
+		_, err := target.ExecInService(ctx, appName, service, engine.Ready, "")
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s database in service %s not ready after %s: %v", engine.Name, service, databaseReadyTimeout, err)
+		}
+		if !sleepContext(ctx, appStartPollInterval) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// demoExportPrefix 演示模式导出文件名的前缀
+	demoExportPrefix = "demo_"
+
+	// demoMarkerFile 演示数据压缩包根目录中的标记文件
+	demoMarkerFile = "DEMO_DATA_NOT_A_REAL_EXPORT.txt"
+
+	demoMarkerContent = "This archive was generated by CTOZ in demo mode because the source system could not be reached.\n" +
+		"It contains mock data only and is not a backup of any real system.\n"
+)
+
+// IsDemoExport 判断导出文件是否为演示模式生成的模拟数据
+func IsDemoExport(filePath string) bool {
+	return strings.HasPrefix(filepath.Base(filePath), demoExportPrefix)
+}
+
+// isDemoImport 判断解压后的导入文件是否为演示数据
+func isDemoImport(extractDir string) bool {
+	_, err := os.Stat(filepath.Join(extractDir, demoMarkerFile))
+	return err == nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ignoreDeviceChecksOption 迁移选项：目标缺少设备时仍然导入应用，只记录警告
+	ignoreDeviceChecksOption = "ignore_device_checks"
+	// nvidiaDevicePath NVIDIA驱动加载后存在的控制设备
+	nvidiaDevicePath = "/dev/nvidiactl"
+)
+
+// deviceRequirement compose中服务需要的主机设备
+type deviceRequirement struct {
+	Service string
+	Path    string // 主机上的设备路径
+	GPU     bool   // 通过gpus、runtime或deploy预留申请的NVIDIA GPU
+}
+
+// DeviceRequirements 扫描devices、gpus、runtime: nvidia、deploy预留的GPU以及挂载的/dev路径
+func (d *composeDocument) DeviceRequirements() []deviceRequirement {
+	var reqs []deviceRequirement
+	for _, svc := range d.Services() {
+		// devices和挂载的/dev路径都需要目标上存在对应设备
+		for _, key := range []string{"devices", "volumes"} {
+			value, _ := mapGet(svc.Config, key)
+			for _, source := range mountSources(value) {
+				if strings.HasPrefix(source, "/dev/") {
+					reqs = append(reqs, deviceRequirement{Service: svc.Name, Path: source})
+				}
+			}
+		}
+
+		gpu := false
+		if _, ok := mapGet(svc.Config, "gpus"); ok {
+			gpu = true
+		}
+		if runtime, ok := mapGet(svc.Config, "runtime"); ok && fmt.Sprint(runtime) == "nvidia" {
+			gpu = true
+		}
+		if reservedGPU(svc.Config) {
+			gpu = true
+		}
+		if gpu {
+			reqs = append(reqs, deviceRequirement{Service: svc.Name, Path: nvidiaDevicePath, GPU: true})
+		}
+	}
+	return reqs
+}
+
+// mountSources 返回devices或volumes列表中的主机路径，支持短格式和长格式
+func mountSources(value interface{}) []string {
+	items, _ := value.([]interface{})
+	sources := make([]string, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			sources = append(sources, strings.SplitN(v, ":", 2)[0])
+		case yaml.MapSlice:
+			if source, ok := mapGet(v, "source"); ok {
+				sources = append(sources, fmt.Sprint(source))
+			}
+		}
+	}
+	return sources
+}
+
+// reservedGPU 判断deploy.resources.reservations.devices中是否预留了GPU
+func reservedGPU(config yaml.MapSlice) bool {
+	value := interface{}(config)
+	for _, key := range []string{"deploy", "resources", "reservations", "devices"} {
+		m, ok := value.(yaml.MapSlice)
+		if !ok {
+			return false
+		}
+		if value, ok = mapGet(m, key); !ok {
+			return false
+		}
+	}
+
+	devices, _ := value.([]interface{})
+	for _, device := range devices {
+		m, ok := device.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		if driver, ok := mapGet(m, "driver"); ok && fmt.Sprint(driver) == "nvidia" {
+			return true
+		}
+		if caps, ok := mapGet(m, "capabilities"); ok {
+			list, _ := caps.([]interface{})
+			for _, c := range list {
+				if fmt.Sprint(c) == "gpu" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// checkAppDevices 确认应用需要的设备在目标上存在，缺少时将应用标记为警告并返回true表示跳过导入
+// 无法确认时只记录警告，开启ignore_device_checks时缺少设备也继续导入
+func (s *MigrationService) checkAppDevices(ctx context.Context, task *models.MigrationTask, target TargetAdapter, appName, composeContent string, appStatuses []models.AppImportStatus) bool {
+	doc, err := parseCompose(composeContent)
+	if err != nil {
+		return false
+	}
+	reqs := doc.DeviceRequirements()
+	if len(reqs) == 0 {
+		return false
+	}
+
+	paths := make([]string, 0, len(reqs))
+	seen := make(map[string]bool)
+	for _, req := range reqs {
+		if !seen[req.Path] {
+			seen[req.Path] = true
+			paths = append(paths, req.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	missing, err := target.MissingPaths(ctx, paths)
+	if err != nil {
+		log.Printf("[WARNING] App %s: failed to check devices on target: %v", appName, err)
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: could not verify devices on the target (%s): %v", appName, strings.Join(paths, ", "), err))
+		return false
+	}
+	missingSet := make(map[string]bool, len(missing))
+	for _, p := range missing {
+		missingSet[p] = true
+	}
+
+	var warnings []string
+	warned := make(map[string]bool)
+	for _, req := range reqs {
+		// 同一服务的同一设备只提示一次
+		if !missingSet[req.Path] || warned[req.Service+"\x00"+req.Path] {
+			continue
+		}
+		warned[req.Service+"\x00"+req.Path] = true
+		if req.GPU {
+			warnings = append(warnings, fmt.Sprintf("Service %s requests an NVIDIA GPU, but no NVIDIA driver was found on the target. Install the GPU driver or remove the GPU reservation, then import the app again.", req.Service))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("Device %s used by service %s does not exist on the target. Attach the hardware or remove the mapping from the compose file, then import the app again.", req.Path, req.Service))
+		}
+	}
+	if len(warnings) == 0 {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s: required devices found on target: %s", appName, strings.Join(paths, ", ")))
+		return false
+	}
+
+	for _, warning := range warnings {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: %s", appName, warning))
+	}
+	ignore, _ := task.Options[ignoreDeviceChecksOption].(bool)
+	for j := range appStatuses {
+		if appStatuses[j].AppName != appName {
+			continue
+		}
+		appStatuses[j].Warnings = append(appStatuses[j].Warnings, warnings...)
+		if !ignore {
+			appStatuses[j].ComposeStatus = models.AppStatusSkipped
+			appStatuses[j].OverallStatus = models.AppStatusWarning
+		}
+		break
+	}
+	s.saveAppImportStatuses(task.ID, appStatuses)
+	if ignore {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: importing despite missing devices (%s is set)", appName, ignoreDeviceChecksOption))
+		return false
+	}
+	s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: compose not imported because required devices are missing", appName))
+	return true
+}
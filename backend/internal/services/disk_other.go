@@ -0,0 +1,10 @@
+//go:build windows
+
+package services
+
+import "fmt"
+
+// localFreeSpace 当前平台不支持查询可用空间
+func localFreeSpace(path string) (int64, int64, error) {
+	return 0, 0, fmt.Errorf("Free space check is not supported on this platform")
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package services
+
+import "syscall"
+
+// localFreeSpace 返回本地路径所在文件系统的可用空间和总空间（字节）
+func localFreeSpace(path string) (int64, int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), int64(st.Blocks) * int64(st.Bsize), nil
+}
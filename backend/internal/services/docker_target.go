@@ -0,0 +1,358 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"ctoz/backend/internal/models"
+)
+
+// Docker主机目标的默认目录
+const (
+	defaultDockerComposeDir = "/opt/stacks"
+	defaultDockerAppDataDir = "/DATA/AppData"
+	casaOSAppDataDir        = "/DATA/AppData"
+)
+
+// dockerHostTarget 通用Docker主机目标适配器
+// 通过SSH/rsync复制AppData，并把compose文件写入可配置目录
+type dockerHostTarget struct {
+	s          *MigrationService
+	conn       *models.SystemConnection
+	composeDir string
+	appDataDir string
+}
+
+// newDockerHostTarget 创建Docker主机目标适配器
+func newDockerHostTarget(s *MigrationService, conn *models.SystemConnection) *dockerHostTarget {
+	composeDir := strings.TrimRight(conn.ComposeDir, "/")
+	if composeDir == "" {
+		composeDir = defaultDockerComposeDir
+	}
+	appDataDir := strings.TrimRight(conn.AppDataDir, "/")
+	if appDataDir == "" {
+		appDataDir = defaultDockerAppDataDir
+	}
+
+	return &dockerHostTarget{
+		s:          s,
+		conn:       conn,
+		composeDir: composeDir,
+		appDataDir: appDataDir,
+	}
+}
+
+// Name 目标类型名称
+func (t *dockerHostTarget) Name() string {
+	return models.SystemTypeDocker
+}
+
+// UploadAppData 通过rsync将应用数据同步到Docker主机
+func (t *dockerHostTarget) UploadAppData(ctx context.Context, appName, sourcePath, taskID string) error {
+	remoteDir := path.Join(t.appDataDir, appName)
+	log.Printf("[INFO] Start syncing data directory for app %s to %s:%s", appName, t.conn.Host, remoteDir)
+
+	// 确保远端目录存在
+	if _, err := runSSH(ctx, t.conn, nil, "mkdir -p "+shellQuote(remoteDir)); err != nil {
+		return fmt.Errorf("Failed to create remote directory %s: %v", remoteDir, err)
+	}
+
+	args := []string{
+		"-a", "-H", "--sparse", "--partial", "--numeric-ids", "--stats",
+		"--exclude", "/" + ownershipManifestName,
+		"-e", sshTransport(t.conn),
+		"--",
+		strings.TrimRight(sourcePath, "/") + "/",
+		rsyncRemote(t.conn, remoteDir+"/"),
+	}
+	uploadStart := time.Now()
+	output, err := sshExec(ctx, t.conn, "rsync", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync failed: %v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	// rsync直接同步目录，没有压缩和解压阶段
+	t.s.recordAppMetrics(taskID, appName, func(m *models.AppMetrics) {
+		m.UploadMs = time.Since(uploadStart).Milliseconds()
+		m.BytesTransferred = parseRsyncBytesSent(string(output))
+	})
+
+	// 本地非root解压时无法chown，按属主清单在远端恢复
+	if err := applyOwnershipOverSSH(ctx, t.conn, remoteDir, loadOwnership(appDataRoot(sourcePath), sourcePath)); err != nil {
+		t.s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: %v", appName, err))
+	}
+
+	t.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: AppData synced to %s", appName, remoteDir))
+	log.Printf("[INFO] App %s data sync completed", appName)
+	return nil
+}
+
+// ImportCompose 将compose文件写入Docker主机的compose目录并校验
+func (t *dockerHostTarget) ImportCompose(ctx context.Context, appName, composeContent, taskID string) error {
+	t.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Start importing app: %s", appName))
+	composeContent = t.s.normalizeComposeForTarget(appName, composeContent, taskID)
+
+	// CasaOS的数据目录映射到目标主机的AppData目录
+	if t.appDataDir != casaOSAppDataDir {
+		composeContent = strings.ReplaceAll(composeContent, casaOSAppDataDir+"/", t.appDataDir+"/")
+	}
+
+	appDir := path.Join(t.composeDir, appName)
+	composePath := path.Join(appDir, "docker-compose.yml")
+	remoteCmd := fmt.Sprintf("mkdir -p %s && cat > %s", shellQuote(appDir), shellQuote(composePath))
+	if _, err := runSSH(ctx, t.conn, strings.NewReader(composeContent), remoteCmd); err != nil {
+		errorMsg := fmt.Sprintf("App %s: Failed to write compose file: %v", appName, err)
+		t.s.taskService.AddTaskLog(taskID, models.LogLevelError, errorMsg)
+		return fmt.Errorf(errorMsg)
+	}
+
+	// 使用docker compose校验配置文件
+	if output, err := runSSH(ctx, t.conn, nil, fmt.Sprintf("docker compose -f %s config -q", shellQuote(composePath))); err != nil {
+		errorMsg := fmt.Sprintf("App %s: Compose validation failed: %v %s", appName, err, strings.TrimSpace(string(output)))
+		t.s.taskService.AddTaskLog(taskID, models.LogLevelError, errorMsg)
+		return fmt.Errorf(errorMsg)
+	}
+
+	t.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: Compose written to %s ✓", appName, composePath))
+	return nil
+}
+
+// StartApp 使用docker compose启动应用并等待容器运行
+func (t *dockerHostTarget) StartApp(ctx context.Context, appName, taskID string) (string, error) {
+	composePath := shellQuote(path.Join(t.composeDir, appName, "docker-compose.yml"))
+	if _, err := runSSH(ctx, t.conn, nil, fmt.Sprintf("docker compose -f %s up -d", composePath)); err != nil {
+		return "", fmt.Errorf("docker compose up failed: %v", err)
+	}
+	return waitForAppRunning(ctx, func() ([]appContainer, error) {
+		output, err := runSSH(ctx, t.conn, nil, fmt.Sprintf("docker compose -f %s ps -a --format json 2>/dev/null", composePath))
+		if err != nil {
+			return nil, err
+		}
+		return parseComposePS(output)
+	})
+}
+
+// UploadAppFiles 将文件写入应用的compose目录，相对路径的挂载和env_file保持有效
+func (t *dockerHostTarget) UploadAppFiles(ctx context.Context, appName, localDir string, files []string, taskID string) error {
+	return uploadFilesOverSSH(ctx, t.conn, path.Join(t.composeDir, appName), localDir, files)
+}
+
+// MissingPaths 通过SSH检查Docker主机上的路径
+func (t *dockerHostTarget) MissingPaths(ctx context.Context, paths []string) ([]string, error) {
+	return missingPathsOverSSH(ctx, t.conn, paths)
+}
+
+// ExecInService 通过SSH在应用容器内执行命令
+func (t *dockerHostTarget) ExecInService(ctx context.Context, appName, service, command, stdinFile string) ([]byte, error) {
+	remoteCmd := composeServiceExec(appName, service, command)
+	if stdinFile != "" {
+		remoteCmd += " < " + shellQuote(path.Join(t.appDataDir, appName, stdinFile))
+	}
+	return runSSH(ctx, t.conn, nil, remoteCmd)
+}
+
+// LoadImages 通过SSH在Docker主机上加载镜像包
+func (t *dockerHostTarget) LoadImages(ctx context.Context, appName, archive string) error {
+	return loadImagesOverSSH(ctx, t.conn, archive)
+}
+
+// SSH辅助函数
+
+// sshPort 返回SSH端口，未配置时默认22
+func sshPort(conn *models.SystemConnection) int {
+	if conn.Port > 0 {
+		return conn.Port
+	}
+	return 22
+}
+
+// sshOptions 返回通用的SSH选项
+func sshOptions(conn *models.SystemConnection) []string {
+	opts := []string{
+		"-p", strconv.Itoa(sshPort(conn)),
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", "ConnectTimeout=10",
+	}
+	if conn.KeyFile != "" {
+		opts = append(opts, "-i", conn.KeyFile)
+	}
+	// 主机名固定解析到指定地址，主机密钥仍按主机名记录
+	if conn.ResolveIP != "" {
+		opts = append(opts, "-o", "HostName="+conn.ResolveIP, "-o", "HostKeyAlias="+conn.Host)
+	}
+	// 未提供密码时仅允许密钥认证，避免交互式提示阻塞
+	if conn.Password == "" {
+		opts = append(opts, "-o", "BatchMode=yes")
+	}
+	return opts
+}
+
+// checkSSHDestination 拒绝以"-"开头或包含空白、控制字符的用户名和主机，防止被ssh/rsync当作选项解析
+func checkSSHDestination(conn *models.SystemConnection) error {
+	if !safeSSHArg(conn.Username) {
+		return fmt.Errorf("Invalid username: must not start with '-' or contain spaces or control characters")
+	}
+	if !safeSSHArg(conn.Host) {
+		return fmt.Errorf("Invalid host: must not start with '-' or contain spaces or control characters")
+	}
+	return nil
+}
+
+// safeSSHArg 判断值能否直接拼入ssh目标
+func safeSSHArg(value string) bool {
+	return !strings.HasPrefix(value, "-") && strings.IndexFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) < 0
+}
+
+// sshDestination 返回 user@host 形式的SSH目标
+func sshDestination(conn *models.SystemConnection) string {
+	return fmt.Sprintf("%s@%s", conn.Username, conn.Host)
+}
+
+// rsyncRemote 返回rsync使用的 user@host:path 形式的远端路径，IPv6地址需要方括号
+func rsyncRemote(conn *models.SystemConnection, remotePath string) string {
+	return fmt.Sprintf("%s@%s:%s", conn.Username, urlHost(conn.Host), remotePath)
+}
+
+// sshTransport 返回rsync -e使用的ssh命令行
+func sshTransport(conn *models.SystemConnection) string {
+	parts := []string{"ssh"}
+	for _, opt := range sshOptions(conn) {
+		parts = append(parts, shellQuote(opt))
+	}
+	return strings.Join(parts, " ")
+}
+
+// sshExec 创建外部命令，提供密码时通过sshpass传递
+func sshExec(ctx context.Context, conn *models.SystemConnection, name string, args ...string) *exec.Cmd {
+	if conn.Password != "" {
+		cmd := exec.CommandContext(ctx, "sshpass", append([]string{"-e", name}, args...)...)
+		cmd.Env = append(os.Environ(), "SSHPASS="+conn.Password)
+		return cmd
+	}
+	return exec.CommandContext(ctx, name, args...)
+}
+
+// runSSH 在远端主机执行命令并返回合并输出
+func runSSH(ctx context.Context, conn *models.SystemConnection, stdin io.Reader, remoteCmd string) ([]byte, error) {
+	if err := checkSSHDestination(conn); err != nil {
+		return nil, err
+	}
+	args := append(sshOptions(conn), "--", sshDestination(conn), remoteCmd)
+	cmd := sshExec(ctx, conn, "ssh", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return output.Bytes(), fmt.Errorf("%v: %s", err, strings.TrimSpace(output.String()))
+	}
+	return output.Bytes(), nil
+}
+
+// rsyncFrom 通过rsync从远端主机同步文件或目录到本地
+func rsyncFrom(ctx context.Context, conn *models.SystemConnection, remotePath, localPath string) error {
+	if err := checkSSHDestination(conn); err != nil {
+		return err
+	}
+	args := []string{
+		"-a", "-H", "--sparse", "--partial",
+		"-e", sshTransport(conn),
+		"--",
+		rsyncRemote(conn, remotePath),
+		localPath,
+	}
+	output, err := sshExec(ctx, conn, "rsync", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync failed: %v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// missingPathsOverSSH 在远端主机上检查路径，返回不存在的路径
+func missingPathsOverSSH(ctx context.Context, conn *models.SystemConnection, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	quoted := make([]string, 0, len(paths))
+	for _, p := range paths {
+		quoted = append(quoted, shellQuote(p))
+	}
+	output, err := runSSH(ctx, conn, nil, fmt.Sprintf(`for p in %s; do [ -e "$p" ] || echo "$p"; done`, strings.Join(quoted, " ")))
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			missing = append(missing, line)
+		}
+	}
+	return missing, nil
+}
+
+// uploadFilesOverSSH 将本地目录中的文件打包为tar流，通过SSH解压到远端目录
+func uploadFilesOverSSH(ctx context.Context, conn *models.SystemConnection, remoteDir, localDir string, files []string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, localDir, files))
+	}()
+
+	remoteCmd := fmt.Sprintf("mkdir -p %[1]s && tar -xf - -C %[1]s", shellQuote(remoteDir))
+	if _, err := runSSH(ctx, conn, pr, remoteCmd); err != nil {
+		pr.CloseWithError(err)
+		return fmt.Errorf("Failed to upload files to %s: %v", remoteDir, err)
+	}
+	return nil
+}
+
+// writeTar 将本地目录中的文件按相对路径写入tar流
+func writeTar(w io.Writer, localDir string, files []string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range files {
+		localPath := filepath.Join(localDir, filepath.FromSlash(name))
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// shellQuote 对字符串进行单引号转义，用于拼接远端shell命令
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// envFilesOption 迁移选项：应用.env文件的处理方式，可以是对所有应用生效的字符串，
+	// 也可以是应用名到处理方式的映射（"*" 为其余应用的默认值）
+	envFilesOption = "env_files"
+	// envModeInterpolate 将.env中的值替换到compose中（默认）
+	envModeInterpolate = "interpolate"
+	// envModeUpload 将.env上传到目标上compose所在目录，由docker compose在启动时读取
+	envModeUpload = "upload"
+)
+
+// envFileMode 返回应用.env文件的处理方式
+func envFileMode(options map[string]interface{}, appName string) string {
+	var mode string
+	switch v := options[envFilesOption].(type) {
+	case string:
+		mode = v
+	case map[string]interface{}:
+		if mode, _ = v[appName].(string); mode == "" {
+			mode, _ = v["*"].(string)
+		}
+	}
+	if strings.EqualFold(strings.TrimSpace(mode), envModeUpload) {
+		return envModeUpload
+	}
+	return envModeInterpolate
+}
+
+// casaOSGlobalEnvVars CasaOS和ZimaOS在导入应用时自动提供的变量，不需要.env定义
+var casaOSGlobalEnvVars = map[string]bool{"PUID": true, "PGID": true, "TZ": true, "AppID": true}
+
+// applyAppEnvFile 按迁移选项处理应用的.env文件，返回要导入的compose内容
+// uploadErr为应用目录文件的上传结果，上传失败时回退为插值；compose中仍有未定义的变量时记录警告
+func (s *MigrationService) applyAppEnvFile(task *models.MigrationTask, appName, composeContent, appDir string, uploadErr error) string {
+	envContent, err := os.ReadFile(filepath.Join(appDir, ".env"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARNING] Failed to read .env file for app %s: %v", appName, err)
+		}
+		s.warnUndefinedEnvVars(task.ID, appName, composeContent, nil)
+		return composeContent
+	}
+
+	vars := parseEnvFile(string(envContent))
+	if envFileMode(task.Options, appName) == envModeUpload {
+		if uploadErr == nil {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s: .env file uploaded with %d variables", appName, len(vars)))
+			s.warnUndefinedEnvVars(task.ID, appName, composeContent, vars)
+			return composeContent
+		}
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to upload .env file, interpolating instead: %v", appName, uploadErr))
+	}
+
+	composeContent = interpolateEnv(composeContent, vars)
+	s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s: interpolated %d variables from .env file", appName, len(vars)))
+	s.warnUndefinedEnvVars(task.ID, appName, composeContent, vars)
+	return composeContent
+}
+
+// warnUndefinedEnvVars 记录compose中引用但未定义且没有默认值的变量
+func (s *MigrationService) warnUndefinedEnvVars(taskID, appName, composeContent string, vars map[string]string) {
+	if names := undefinedEnvVars(composeContent, vars); len(names) > 0 {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: compose references undefined variables: %s", appName, strings.Join(names, ", ")))
+	}
+}
+
+// undefinedEnvVars 返回内容中引用但vars中不存在且没有默认值的变量名
+func undefinedEnvVars(content string, vars map[string]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, groups := range envVarPattern.FindAllStringSubmatch(content, -1) {
+		name, op := groups[1], groups[2]
+		if name == "" {
+			name = groups[4]
+		}
+		if name == "" || op == ":-" || op == "-" || seen[name] || casaOSGlobalEnvVars[name] {
+			continue
+		}
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
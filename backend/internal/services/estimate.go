@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// 吞吐量测量参数
+const (
+	throughputSampleBytes = 16 << 20 // 最多读取16MB
+	throughputSampleTime  = 5 * time.Second
+	throughputSampleLimit = 10 * time.Second // 查找样本文件与下载的总时限，远小于请求超时
+	throughputSampleDirs  = 20               // 查找样本文件时最多列出的目录数
+)
+
+// EstimateMigration 统计源系统每个应用的compose与AppData大小，并按实测吞吐量估算迁移耗时
+func (s *MigrationService) EstimateMigration(ctx context.Context, sourceConn *models.SystemConnection) (*models.EstimateResponse, error) {
+	testResp, err := s.connService.TestConnection(ctx, sourceConn)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to test source connection: %v", err)
+	}
+	if !testResp.Success {
+		return nil, fmt.Errorf("Source connection failed: %s", testResp.Message)
+	}
+
+	appEntries, err := s.listCasaOSFolder(ctx, sourceConn, casaOSAppsDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list apps: %v", err)
+	}
+
+	// AppData目录名大小写可能与应用名不同
+	appDataDirs := make(map[string]string)
+	if entries, err := s.listCasaOSFolder(ctx, sourceConn, casaOSAppDataDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir {
+				appDataDirs[strings.ToLower(entry.Name)] = entry.Name
+			}
+		}
+	} else {
+		log.Printf("[WARNING] Failed to list AppData directory: %v", err)
+	}
+
+	result := &models.EstimateResponse{Apps: make([]models.AppSizeEstimate, 0, len(appEntries))}
+	for _, entry := range appEntries {
+		if !entry.IsDir {
+			continue
+		}
+
+		app := models.AppSizeEstimate{AppName: entry.Name}
+		if app.ComposeBytes, err = s.getCasaOSFolderSize(ctx, sourceConn, path.Join(casaOSAppsDir, entry.Name)); err != nil {
+			log.Printf("[WARNING] Failed to get compose size for app %s: %v", entry.Name, err)
+		}
+		if dirName, ok := appDataDirs[strings.ToLower(entry.Name)]; ok {
+			if app.AppDataBytes, err = s.getCasaOSFolderSize(ctx, sourceConn, path.Join(casaOSAppDataDir, dirName)); err != nil {
+				log.Printf("[WARNING] Failed to get AppData size for app %s: %v", entry.Name, err)
+			}
+		}
+		app.TotalBytes = app.ComposeBytes + app.AppDataBytes
+
+		result.Apps = append(result.Apps, app)
+		result.TotalBytes += app.TotalBytes
+	}
+
+	throughput, err := s.measureSourceThroughput(ctx, sourceConn)
+	if err != nil {
+		log.Printf("[WARNING] Failed to measure source throughput: %v", err)
+	}
+	result.ThroughputBps = throughput
+
+	// 在线迁移需要先从源系统下载，再上传到目标系统
+	if throughput > 0 {
+		result.EstimatedSeconds = 2 * result.TotalBytes / throughput
+	}
+
+	return result, nil
+}
+
+// measureSourceThroughput 通过单文件下载接口读取一个样本文件的开头部分，测量源系统下载吞吐量（字节/秒）
+func (s *MigrationService) measureSourceThroughput(ctx context.Context, conn *models.SystemConnection) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, throughputSampleLimit)
+	defer cancel()
+
+	samplePath, err := s.findThroughputSample(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	query := url.Values{"path": {samplePath}, "token": {conn.Token}}
+	req, err := newConnRequest(ctx, conn, "GET", fmt.Sprintf("%s/v1/file?%s", connBaseURL(conn), query.Encode()), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", conn.Token)
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+
+	var read int64
+	buf := make([]byte, 32*1024)
+	for read < throughputSampleBytes && time.Since(start) < throughputSampleTime {
+		n, err := resp.Body.Read(buf)
+		read += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	elapsed := time.Since(start).Seconds()
+	if read == 0 || elapsed <= 0 {
+		return 0, fmt.Errorf("No data received")
+	}
+	return int64(float64(read) / elapsed), nil
+}
+
+// findThroughputSample 逐层列出AppData和apps目录，返回找到的最大文件路径
+// 找到不小于采样上限的文件即停止，最多列出 throughputSampleDirs 个目录
+func (s *MigrationService) findThroughputSample(ctx context.Context, conn *models.SystemConnection) (string, error) {
+	var sample casaOSFileEntry
+	queue := []string{casaOSAppDataDir, casaOSAppsDir}
+	for listed := 0; len(queue) > 0 && listed < throughputSampleDirs; listed++ {
+		dir := queue[0]
+		queue = queue[1:]
+
+		entries, err := s.listCasaOSFolder(ctx, conn, dir)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		for _, entry := range entries {
+			entryPath := entry.Path
+			if entryPath == "" {
+				entryPath = path.Join(dir, entry.Name)
+			}
+			if entry.IsDir {
+				queue = append(queue, entryPath)
+			} else if entry.Size > sample.Size {
+				sample = entry
+				sample.Path = entryPath
+			}
+		}
+		if sample.Size >= throughputSampleBytes {
+			break
+		}
+	}
+
+	if sample.Path == "" {
+		return "", fmt.Errorf("No file found to sample")
+	}
+	return sample.Path, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// exportFilterRoots 导出过滤作用的目录，其他条目（如migration_data.json）总是导出
+var exportFilterRoots = []string{"var/lib/casaos/apps/", "DATA/"}
+
+// exportFilter 导出时按包含和排除模式筛选压缩包条目
+type exportFilter struct {
+	include [][]string
+	exclude [][]string
+}
+
+// exportFileCounts 导出压缩包中写入和被过滤掉的文件数，记录在清单中
+type exportFileCounts struct {
+	Apps     int `json:"apps"`     // 应用目录中导出的文件数
+	Data     int `json:"data"`     // DATA目录中导出的文件数
+	Excluded int `json:"excluded"` // 被包含或排除模式过滤掉的文件数
+}
+
+// parseExportFilter 从导出选项的include和exclude中读取过滤模式，未设置时返回nil
+func parseExportFilter(options map[string]interface{}) (*models.ExportFilter, error) {
+	if options["include"] == nil && options["exclude"] == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"include": options["include"],
+		"exclude": options["exclude"],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Invalid export filter: %v", err)
+	}
+	var filter models.ExportFilter
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return nil, fmt.Errorf("Invalid export filter: include and exclude must be lists of patterns")
+	}
+	if _, err := newExportFilter(&filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// ValidateExportFilter 检查导出选项中的include和exclude模式
+func ValidateExportFilter(options map[string]interface{}) error {
+	_, err := parseExportFilter(options)
+	return err
+}
+
+// newExportFilter 编译过滤模式，没有任何模式时返回nil
+func newExportFilter(filter *models.ExportFilter) (*exportFilter, error) {
+	if filter == nil || (len(filter.Include) == 0 && len(filter.Exclude) == 0) {
+		return nil, nil
+	}
+
+	compile := func(patterns []string) ([][]string, error) {
+		var compiled [][]string
+		for _, pattern := range patterns {
+			cleaned := strings.Trim(strings.TrimSpace(pattern), "/")
+			if cleaned == "" {
+				return nil, fmt.Errorf("Invalid export pattern: pattern is empty")
+			}
+			segments := strings.Split(cleaned, "/")
+			for _, segment := range segments {
+				if _, err := path.Match(segment, ""); err != nil {
+					return nil, fmt.Errorf("Invalid export pattern %s: %v", pattern, err)
+				}
+			}
+			compiled = append(compiled, segments)
+		}
+		return compiled, nil
+	}
+
+	include, err := compile(filter.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compile(filter.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &exportFilter{include: include, exclude: exclude}, nil
+}
+
+// allows 判断条目是否导出，nil表示不过滤
+// 模式匹配条目本身或其上级目录时即视为匹配，排除模式优先
+func (f *exportFilter) allows(name string) bool {
+	if f == nil {
+		return true
+	}
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if exportFilterRoot(name) == "" {
+		return true
+	}
+
+	segments := strings.Split(name, "/")
+	if len(f.include) > 0 && !matchAnyPattern(f.include, segments) {
+		return false
+	}
+	return !matchAnyPattern(f.exclude, segments)
+}
+
+// exportFilterRoot 返回条目所在的过滤目录，不在其中时返回空字符串
+func exportFilterRoot(name string) string {
+	for _, root := range exportFilterRoots {
+		if strings.HasPrefix(name, root) {
+			return root
+		}
+	}
+	return ""
+}
+
+// add 统计一个ZIP条目，目录条目不计数
+func (c *exportFileCounts) add(file *zip.File, included bool) {
+	if c == nil || file.FileInfo().IsDir() {
+		return
+	}
+	if !included {
+		c.Excluded++
+		return
+	}
+	switch exportFilterRoot(strings.TrimPrefix(file.Name, "/")) {
+	case "var/lib/casaos/apps/":
+		c.Apps++
+	case "DATA/":
+		c.Data++
+	}
+}
+
+// matchAnyPattern 判断路径或其任一上级目录是否匹配其中一个模式
+func matchAnyPattern(patterns [][]string, segments []string) bool {
+	for _, pattern := range patterns {
+		for i := 1; i <= len(segments); i++ {
+			if matchGlobSegments(pattern, segments[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlobSegments 按路径段匹配模式，*和?不跨越目录，**匹配任意层目录（包括零层）
+func matchGlobSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchGlobSegments(pattern[1:], segments[1:])
+}
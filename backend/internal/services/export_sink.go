@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// ExportSink 导出文件的推送目标
+type ExportSink interface {
+	// Name 目标类型名称
+	Name() string
+	// Upload 推送本地导出文件，返回文件在目标上的位置
+	Upload(ctx context.Context, localPath string) (string, error)
+}
+
+// parseExportDestination 从导出选项中读取推送目标，未设置时返回nil
+func parseExportDestination(options map[string]interface{}) (*models.ExportDestination, error) {
+	value, ok := options["destination"]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid export destination: %v", err)
+	}
+	var dest models.ExportDestination
+	if err := json.Unmarshal(data, &dest); err != nil {
+		return nil, fmt.Errorf("Invalid export destination: %v", err)
+	}
+	return &dest, nil
+}
+
+// RedactTaskOptions 返回去掉导出推送目标和S3导入来源中密钥和密码的任务选项副本
+func RedactTaskOptions(options map[string]interface{}) map[string]interface{} {
+	dest, _ := parseExportDestination(options)
+	src, _ := parseS3Source(options)
+	if dest == nil && src == nil {
+		return options
+	}
+
+	redacted := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		redacted[k] = v
+	}
+	if dest != nil {
+		if dest.S3 != nil {
+			s3 := *dest.S3
+			s3.SecretAccessKey = ""
+			dest.S3 = &s3
+		}
+		if dest.SMB != nil {
+			smb := *dest.SMB
+			smb.Password = ""
+			dest.SMB = &smb
+		}
+		redacted["destination"] = dest
+	}
+	if src != nil {
+		srcCopy := *src
+		srcCopy.SecretAccessKey = ""
+		redacted[importS3Option] = &srcCopy
+	}
+	return redacted
+}
+
+// newExportSink 根据推送目标设置创建推送器
+func newExportSink(dest *models.ExportDestination) (ExportSink, error) {
+	switch dest.Type {
+	case models.ExportDestinationS3:
+		client, err := newS3Client(dest.S3)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Sink{client: client}, nil
+	case models.ExportDestinationSMB:
+		if dest.SMB == nil || dest.SMB.Server == "" || dest.SMB.Share == "" {
+			return nil, fmt.Errorf("SMB server and share are required")
+		}
+		if strings.ContainsAny(dest.SMB.Path, "\";") {
+			return nil, fmt.Errorf("Invalid SMB path: %s", dest.SMB.Path)
+		}
+		return &smbSink{cfg: *dest.SMB}, nil
+	case models.ExportDestinationNFS:
+		if dest.NFS == nil || dest.NFS.Server == "" || dest.NFS.Export == "" {
+			return nil, fmt.Errorf("NFS server and export path are required")
+		}
+		return &nfsSink{cfg: *dest.NFS}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported export destination: %s", dest.Type)
+	}
+}
+
+// s3Sink 推送到S3兼容对象存储
+type s3Sink struct {
+	client *s3Client
+}
+
+// Name 目标类型名称
+func (s *s3Sink) Name() string {
+	return models.ExportDestinationS3
+}
+
+// Upload 以文件名为对象键（加上前缀）上传
+func (s *s3Sink) Upload(ctx context.Context, localPath string) (string, error) {
+	key := s.client.objectKey(filepath.Base(localPath))
+	if err := s.client.PutFile(ctx, key, localPath); err != nil {
+		return "", err
+	}
+	return s.client.location(key), nil
+}
+
+// smbSink 通过smbclient推送到SMB共享
+type smbSink struct {
+	cfg models.SMBConfig
+}
+
+// Name 目标类型名称
+func (s *smbSink) Name() string {
+	return models.ExportDestinationSMB
+}
+
+// Upload 上传到共享内的目录，目录需已存在
+func (s *smbSink) Upload(ctx context.Context, localPath string) (string, error) {
+	name := filepath.Base(localPath)
+	command := fmt.Sprintf(`put "%s" "%s"`, localPath, name)
+	if dir := strings.Trim(s.cfg.Path, "/"); dir != "" {
+		command = fmt.Sprintf(`cd "%s"; %s`, dir, command)
+	}
+
+	service := fmt.Sprintf("//%s/%s", s.cfg.Server, strings.Trim(s.cfg.Share, "/"))
+	args := []string{service, "-c", command}
+	if s.cfg.Username == "" {
+		args = append(args, "-N")
+	} else {
+		args = append(args, "-U", s.cfg.Username)
+	}
+	if s.cfg.Domain != "" {
+		args = append(args, "-W", s.cfg.Domain)
+	}
+
+	// 密码通过环境变量传递，避免出现在进程列表中
+	cmd := exec.CommandContext(ctx, "smbclient", args...)
+	cmd.Env = append(os.Environ(), "PASSWD="+s.cfg.Password)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("smbclient failed: %v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	return service + "/" + path.Join(strings.Trim(s.cfg.Path, "/"), name), nil
+}
+
+// nfsSink 挂载NFS导出后复制文件，需要容器具有挂载权限
+type nfsSink struct {
+	cfg models.NFSConfig
+}
+
+// Name 目标类型名称
+func (s *nfsSink) Name() string {
+	return models.ExportDestinationNFS
+}
+
+// Upload 临时挂载NFS导出并复制文件，完成后卸载
+func (s *nfsSink) Upload(ctx context.Context, localPath string) (string, error) {
+	mountDir, err := os.MkdirTemp("", "ctoz-nfs-*")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create mount point: %v", err)
+	}
+	defer os.Remove(mountDir)
+
+	remote := fmt.Sprintf("%s:%s", s.cfg.Server, s.cfg.Export)
+	args := []string{"-t", "nfs"}
+	if s.cfg.Options != "" {
+		args = append(args, "-o", s.cfg.Options)
+	}
+	args = append(args, remote, mountDir)
+	if output, err := exec.CommandContext(ctx, "mount", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("Failed to mount %s: %v, output: %s", remote, err, strings.TrimSpace(string(output)))
+	}
+	defer func() {
+		if output, err := exec.Command("umount", mountDir).CombinedOutput(); err != nil {
+			log.Printf("[WARNING] Failed to unmount %s: %v, output: %s", mountDir, err, strings.TrimSpace(string(output)))
+		}
+	}()
+
+	targetDir := filepath.Join(mountDir, filepath.FromSlash(strings.Trim(s.cfg.Path, "/")))
+	if !isWithinDir(mountDir, targetDir) {
+		return "", fmt.Errorf("Invalid NFS path: %s", s.cfg.Path)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create directory on NFS export: %v", err)
+	}
+
+	name := filepath.Base(localPath)
+	if err := copyFileContents(localPath, filepath.Join(targetDir, name)); err != nil {
+		return "", err
+	}
+	return remote + "/" + path.Join(strings.Trim(s.cfg.Path, "/"), name), nil
+}
+
+// copyFileContents 复制文件内容
+func copyFileContents(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("Failed to create file: %s - %v", dst, err)
+	}
+	if _, err := copySparse(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("Failed to copy file: %v", err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+
+	"ctoz/backend/internal/config"
+)
+
+// extractLimiter 在解压过程中统计条目数和字节数，超过配置上限时返回错误
+type extractLimiter struct {
+	limits      config.ExtractLimits
+	archiveSize int64
+	entries     int
+	total       int64
+}
+
+// newExtractLimiter 为压缩包创建解压限制器
+func (s *MigrationService) newExtractLimiter(archivePath string) *extractLimiter {
+	l := &extractLimiter{limits: s.cfg.Extract}
+	if info, err := os.Stat(archivePath); err == nil {
+		l.archiveSize = info.Size()
+	}
+	return l
+}
+
+// checkZip 根据ZIP目录中声明的大小提前检查，避免开始解压后才失败
+// 声明的大小可能被伪造，解压时仍按实际字节数检查
+func (l *extractLimiter) checkZip(files []*zip.File) error {
+	if l.limits.MaxEntries > 0 && len(files) > l.limits.MaxEntries {
+		return fmt.Errorf("Archive exceeds extraction limit: %d entries (max %d, CTOZ_MAX_EXTRACT_ENTRIES)", len(files), l.limits.MaxEntries)
+	}
+
+	var declared int64
+	for _, f := range files {
+		size := int64(f.UncompressedSize64)
+		if l.limits.MaxFileBytes > 0 && size > l.limits.MaxFileBytes {
+			return fmt.Errorf("Archive exceeds extraction limit: %s is %d bytes (max %d, CTOZ_MAX_EXTRACT_FILE_BYTES)", f.Name, size, l.limits.MaxFileBytes)
+		}
+		declared += size
+	}
+	return l.checkTotal(declared)
+}
+
+// checkSevenZip 根据7z列表中声明的大小提前检查
+// 7-Zip直接写入磁盘，解压完成后再按实际大小检查一次
+func (l *extractLimiter) checkSevenZip(entries []sevenZipEntry) error {
+	if l.limits.MaxEntries > 0 && len(entries) > l.limits.MaxEntries {
+		return fmt.Errorf("Archive exceeds extraction limit: %d entries (max %d, CTOZ_MAX_EXTRACT_ENTRIES)", len(entries), l.limits.MaxEntries)
+	}
+
+	var declared int64
+	for _, entry := range entries {
+		if l.limits.MaxFileBytes > 0 && entry.Size > l.limits.MaxFileBytes {
+			return fmt.Errorf("Archive exceeds extraction limit: %s is %d bytes (max %d, CTOZ_MAX_EXTRACT_FILE_BYTES)", entry.Path, entry.Size, l.limits.MaxFileBytes)
+		}
+		declared += entry.Size
+	}
+	return l.checkTotal(declared)
+}
+
+// addEntry 统计一个条目
+func (l *extractLimiter) addEntry(name string) error {
+	l.entries++
+	if l.limits.MaxEntries > 0 && l.entries > l.limits.MaxEntries {
+		return fmt.Errorf("Archive exceeds extraction limit: more than %d entries (CTOZ_MAX_EXTRACT_ENTRIES)", l.limits.MaxEntries)
+	}
+	return nil
+}
+
+// checkTotal 检查解压总大小和压缩比
+func (l *extractLimiter) checkTotal(total int64) error {
+	if l.limits.MaxTotalBytes > 0 && total > l.limits.MaxTotalBytes {
+		return fmt.Errorf("Archive exceeds extraction limit: more than %d bytes decompressed (CTOZ_MAX_EXTRACT_BYTES)", l.limits.MaxTotalBytes)
+	}
+	if l.limits.MaxRatio > 0 && l.archiveSize > 0 && total/l.archiveSize > int64(l.limits.MaxRatio) {
+		return fmt.Errorf("Archive exceeds extraction limit: compression ratio above %d:1 (CTOZ_MAX_EXTRACT_RATIO)", l.limits.MaxRatio)
+	}
+	return nil
+}
+
+// reader 包装条目内容，读取超过单文件或总大小上限时返回错误
+func (l *extractLimiter) reader(name string, r io.Reader) io.Reader {
+	return &limitedEntryReader{l: l, name: name, r: r}
+}
+
+// limitedEntryReader 统计单个条目读取字节数的Reader
+type limitedEntryReader struct {
+	l    *extractLimiter
+	name string
+	r    io.Reader
+	read int64
+}
+
+// Read 读取数据并检查限制
+func (e *limitedEntryReader) Read(buf []byte) (int, error) {
+	n, err := e.r.Read(buf)
+	e.read += int64(n)
+	e.l.total += int64(n)
+
+	if max := e.l.limits.MaxFileBytes; max > 0 && e.read > max {
+		return n, fmt.Errorf("Archive exceeds extraction limit: %s is larger than %d bytes (CTOZ_MAX_EXTRACT_FILE_BYTES)", e.name, max)
+	}
+	if limitErr := e.l.checkTotal(e.l.total); limitErr != nil {
+		return n, limitErr
+	}
+	return n, err
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// heartbeatInterval 目标上的长时间操作期间推送心跳的间隔
+	heartbeatInterval = 15 * time.Second
+	// heartbeatLogEvery 每隔几次心跳写一条任务日志，避免日志被心跳刷屏
+	heartbeatLogEvery = 4
+)
+
+// 心跳对应的目标操作
+const (
+	heartbeatPhaseDecompress = "decompress"
+	heartbeatPhaseCompose    = "compose"
+)
+
+// heartbeatLogFormats 各操作心跳的任务日志格式，参数为应用名、已等待时间和目标状态
+var heartbeatLogFormats = map[string]string{
+	heartbeatPhaseDecompress: "Still decompressing data of app %s on the target (%s elapsed, status: %s)",
+	heartbeatPhaseCompose:    "Still importing compose of app %s, the target may be pulling images (%s elapsed, status: %s)",
+}
+
+// heartbeat 在等待目标完成操作期间定期推送已等待的时间和目标上的任务状态
+type heartbeat struct {
+	mu     sync.Mutex
+	status string
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// startHeartbeat 开始推送心跳，操作结束后必须调用stop
+func (s *MigrationService) startHeartbeat(taskID, appName, phase, status string) *heartbeat {
+	h := &heartbeat{status: status, done: make(chan struct{})}
+	start := time.Now()
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for beats := 1; ; beats++ {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+			}
+			elapsed := time.Since(start)
+			status := h.currentStatus()
+			s.taskService.ReportHeartbeat(taskID, appName, phase, elapsed, status)
+			if beats%heartbeatLogEvery == 0 {
+				s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf(heartbeatLogFormats[phase], appName, elapsed.Round(time.Second), status))
+			}
+		}
+	}()
+	return h
+}
+
+// setStatus 更新目标上的任务状态，下次心跳时推送
+func (h *heartbeat) setStatus(status string) {
+	h.mu.Lock()
+	h.status = status
+	h.mu.Unlock()
+}
+
+// currentStatus 返回最近一次的目标任务状态
+func (h *heartbeat) currentStatus() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// stop 停止推送心跳并等待后台goroutine退出
+func (h *heartbeat) stop() {
+	close(h.done)
+	h.wg.Wait()
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/models"
+)
+
+// retryableStatusCodes 视为临时故障的响应状态码
+var retryableStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:     true,
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// idempotentKey 请求上下文中标记非幂等方法的请求可以安全重发
+type idempotentKey struct{}
+
+// markIdempotent 标记POST等请求重复发送不会产生副作用（如覆盖上传同一文件），失败时可以和GET一样重试
+func markIdempotent(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), idempotentKey{}, true))
+}
+
+// retryClient 对CasaOS/ZimaOS接口调用按指数退避重试临时故障的HTTP客户端
+// 幂等请求在连接错误、超时和502/503/504等状态码时重试；其余请求只在连接未建立时重试，避免重复执行
+type retryClient struct {
+	client *http.Client
+	policy config.RetryConfig
+	// timeout 每次请求（包括读取响应体）的总时长，通过请求上下文限制，0表示不限制
+	timeout time.Duration
+	// onRetry 每次重试前调用，用于记录日志
+	onRetry func(req *http.Request, attempt int, delay time.Duration, reason string)
+	// onFailure 请求最终失败（出错或状态码不低于400）时调用，用于记录诊断信息
+	onFailure func(req *http.Request, resp *http.Response, err error)
+	// limiter 按主机限制并发和发起间隔，nil表示不限制
+	limiter *hostLimiter
+}
+
+// newRetryClient 创建重试客户端
+func newRetryClient(timeout time.Duration, policy config.RetryConfig) *retryClient {
+	return &retryClient{
+		client:  &http.Client{Transport: newHTTPTransport(defaultDialTimeout, 0)},
+		policy:  policy,
+		timeout: timeout,
+		onRetry: logRetryAttempt,
+	}
+}
+
+// logRetryAttempt 在服务日志中记录重试
+func logRetryAttempt(req *http.Request, attempt int, delay time.Duration, reason string) {
+	log.Printf("[WARNING] %s %s failed (%s), retrying in %s (attempt %d)", req.Method, req.URL.Path, reason, delay, attempt)
+}
+
+// Do 发送请求，临时故障时重试，返回最后一次的响应或错误
+func (c *retryClient) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.do(req)
+		if attempt > c.policy.MaxRetries {
+			c.reportFailure(req, resp, err)
+			return resp, err
+		}
+
+		reason, retry := c.shouldRetry(req, resp, err)
+		if !retry {
+			c.reportFailure(req, resp, err)
+			return resp, err
+		}
+
+		delay := c.backoff(attempt, resp)
+		if resp != nil {
+			// 读完响应体以复用连接
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, fmt.Errorf("Failed to rewind request body for retry: %v", bodyErr)
+			}
+			req.Body = body
+		}
+
+		c.onRetry(req, attempt, delay, reason)
+		if !sleepContext(req.Context(), delay) {
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// reportFailure 请求最终出错或返回错误状态码时调用onFailure，调用方取消的请求不记录
+func (c *retryClient) reportFailure(req *http.Request, resp *http.Response, err error) {
+	if c.onFailure == nil || req.Context().Err() != nil {
+		return
+	}
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		c.onFailure(req, resp, err)
+	}
+}
+
+// do 发送一次请求，设置了总时长时用带截止时间的上下文发送，截止时间在响应体关闭前一直有效
+func (c *retryClient) do(req *http.Request) (*http.Response, error) {
+	// 等待限流的时间不计入请求的总时长
+	release, err := c.limiter.wait(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if c.timeout <= 0 {
+		return c.client.Do(req)
+	}
+
+	parent := req.Context()
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		err = timeoutError(parent, ctx, c.timeout, err)
+		cancel()
+		return nil, err
+	}
+	resp.Body = &deadlineBody{ReadCloser: resp.Body, parent: parent, ctx: ctx, cancel: cancel, timeout: c.timeout}
+	return resp, nil
+}
+
+// deadlineBody 读取带截止时间的响应体，关闭时释放上下文
+type deadlineBody struct {
+	io.ReadCloser
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+}
+
+// Read 读取响应体，超过截止时间时返回注明超时时长的错误
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = timeoutError(b.parent, b.ctx, b.timeout, err)
+	}
+	return n, err
+}
+
+// Close 关闭响应体并释放上下文
+func (b *deadlineBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// timeoutError 请求超过自身的总时长时返回注明时长的错误，调用方取消等其它错误原样返回
+// 错误信息不包含URL，避免查询参数中的令牌出现在日志中
+func timeoutError(parent, ctx context.Context, timeout time.Duration, err error) error {
+	if parent.Err() != nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	return fmt.Errorf("Request timed out after %s: %v", timeout, err)
+}
+
+// shouldRetry 判断请求是否可以重试，返回重试原因
+func (c *retryClient) shouldRetry(req *http.Request, resp *http.Response, err error) (string, bool) {
+	// 请求体无法重新读取（如流式上传）时不能重发
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return "", false
+	}
+
+	if err != nil {
+		// 错误信息不包含URL，避免查询参数中的令牌出现在日志中
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		// 连接未建立时请求没有到达服务端，任何请求都可以重发
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return err.Error(), true
+		}
+		return err.Error(), isIdempotent(req)
+	}
+
+	if retryableStatusCodes[resp.StatusCode] && isIdempotent(req) {
+		return fmt.Sprintf("status code %d", resp.StatusCode), true
+	}
+	return "", false
+}
+
+// backoff 计算第attempt次重试前的等待时间，服务端返回Retry-After时优先使用
+func (c *retryClient) backoff(attempt int, resp *http.Response) time.Duration {
+	delay := c.policy.BaseDelay << uint(attempt-1)
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	if c.policy.MaxDelay > 0 && (delay > c.policy.MaxDelay || delay <= 0) {
+		delay = c.policy.MaxDelay
+	}
+	return delay
+}
+
+// isIdempotent 请求方法是否幂等，或已通过markIdempotent标记
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// logRetry 记录重试，并写入源或目标为该主机的运行中任务的日志
+func (s *MigrationService) logRetry(req *http.Request, attempt int, delay time.Duration, reason string) {
+	logRetryAttempt(req, attempt, delay, reason)
+
+	host := req.URL.Hostname()
+	for _, task := range s.taskService.ListTasks() {
+		if task.Status != string(models.TaskStatusRunning) && task.Status != string(models.TaskStatusWaiting) {
+			continue
+		}
+		if (task.Source != nil && task.Source.Host == host) || (task.Target != nil && task.Target.Host == host) {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Request to %s failed (%s), retrying in %s (attempt %d of %d)", req.URL.Path, reason, delay, attempt, s.cfg.Retry.MaxRetries))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// connBaseURL 返回系统HTTP接口的基础地址（不含结尾的/），IPv6地址加上方括号
+func connBaseURL(conn *models.SystemConnection) string {
+	return (&url.URL{Scheme: "http", Host: net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port))}).String()
+}
+
+// urlHost 返回可以放在URL或rsync目标中的主机名，IPv6地址加上方括号
+func urlHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// normalizeHost 去掉IPv6地址两侧的方括号，并校验包含冒号的主机名是合法的IPv6地址
+// 端口需填在端口字段中，host:port形式会被拒绝
+func normalizeHost(conn *models.SystemConnection) error {
+	host := strings.TrimSpace(conn.Host)
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if strings.Contains(host, ":") {
+		// 链路本地地址可以带区域，如 fe80::1%eth0
+		addr := host
+		if i := strings.Index(addr, "%"); i >= 0 {
+			addr = addr[:i]
+		}
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("Invalid IPv6 address: %s", conn.Host)
+		}
+	}
+	conn.Host = host
+	return nil
+}
+
+// connContextKey 请求上下文中保存请求所访问的系统连接
+type connContextKey struct{}
+
+// newConnRequest 创建访问某个系统的请求，连接的代理等设置随请求传给Transport
+// ctx取消时（任务取消、服务关闭或接口请求超时）中止正在进行的请求和传输
+func newConnRequest(ctx context.Context, conn *models.SystemConnection, method, rawURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	return req.WithContext(context.WithValue(req.Context(), connContextKey{}, conn)), nil
+}
+
+// requestConn 返回请求所访问的系统连接，不是通过newConnRequest创建时为nil
+func requestConn(req *http.Request) *models.SystemConnection {
+	conn, _ := req.Context().Value(connContextKey{}).(*models.SystemConnection)
+	return conn
+}
+
+// defaultDialTimeout 未单独配置时建立TCP连接的超时时间
+const defaultDialTimeout = 30 * time.Second
+
+// newHTTPTransport 创建CasaOS/ZimaOS客户端共用的Transport，按请求的连接设置选择代理和解析地址
+// dialTimeout限制建立连接的时间，headerTimeout限制发完请求后等待响应头的时间（0表示不限制）
+func newHTTPTransport(dialTimeout, headerTimeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = headerTimeout
+	transport.Proxy = proxyForRequest
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, resolveOverride(ctx, addr))
+	}
+	return transport
+}
+
+// resolveOverride 连接配置了resolve_ip且要连接的正是该连接的主机时，改为连接指定的地址
+// 经代理访问时连接的是代理地址，不受影响
+func resolveOverride(ctx context.Context, addr string) string {
+	conn, _ := ctx.Value(connContextKey{}).(*models.SystemConnection)
+	if conn == nil || conn.ResolveIP == "" {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || !strings.EqualFold(host, conn.Host) {
+		return addr
+	}
+	return net.JoinHostPort(conn.ResolveIP, port)
+}
+
+// proxyForRequest 连接配置了代理时使用该代理，否则按HTTP_PROXY/HTTPS_PROXY/NO_PROXY环境变量选择
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	if conn := requestConn(req); conn != nil && conn.Proxy != "" {
+		return url.Parse(conn.Proxy)
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// redactProxyURL 隐藏代理地址中的密码
+func redactProxyURL(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return proxy
+	}
+	return u.Redacted()
+}
+
+// validateProxyURL 校验连接的代理地址，支持http、https和socks5
+func validateProxyURL(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("Invalid proxy URL: %s", proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return nil
+	}
+	return fmt.Errorf("Unsupported proxy scheme: %s (expected http, https or socks5)", u.Scheme)
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// confirmArchMismatchOption 迁移选项：true确认全部没有目标架构镜像的应用，或确认的应用名列表
+	confirmArchMismatchOption = "confirm_arch_mismatch"
+	// dockerHubRegistryHost docker.io镜像实际所在的仓库地址
+	dockerHubRegistryHost = "registry-1.docker.io"
+)
+
+// manifestAcceptTypes 读取镜像清单时接受的格式，多架构镜像返回清单列表
+var manifestAcceptTypes = strings.Join([]string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}, ", ")
+
+// normalizeArch 把uname和Go的架构名统一为镜像仓库使用的名称
+func normalizeArch(arch string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+	switch arch {
+	case "x86_64", "x86-64", "x64":
+		return "amd64"
+	case "aarch64", "armv8", "armv8l":
+		return "arm64"
+	case "armv7l", "armv7", "armv6l", "armhf":
+		return "arm"
+	case "i386", "i686":
+		return "386"
+	}
+	return arch
+}
+
+// systemArch 获取CasaOS/ZimaOS的CPU架构，Docker主机通过SSH读取
+func (s *MigrationService) systemArch(ctx context.Context, conn *models.SystemConnection) (string, error) {
+	if conn.Type == models.SystemTypeDocker {
+		output, err := runSSH(ctx, conn, nil, "uname -m")
+		if err != nil {
+			return "", fmt.Errorf("Failed to read CPU architecture: %v", err)
+		}
+		return normalizeArch(string(output)), nil
+	}
+
+	var hardware struct {
+		Arch string `json:"arch"`
+	}
+	if err := s.casaOSGet(ctx, conn, "/v1/sys/hardware", nil, &hardware); err != nil {
+		return "", err
+	}
+	if hardware.Arch == "" {
+		return "", fmt.Errorf("System did not report its CPU architecture")
+	}
+	return normalizeArch(hardware.Arch), nil
+}
+
+// casaOSComposeImages 读取CasaOS源上每个compose应用使用的镜像
+func (s *MigrationService) casaOSComposeImages(ctx context.Context, conn *models.SystemConnection) (map[string][]string, error) {
+	var composeApps map[string]struct {
+		Compose struct {
+			Services map[string]struct {
+				Image string `json:"image"`
+			} `json:"services"`
+		} `json:"compose"`
+	}
+	if err := s.casaOSGet(ctx, conn, "/v2/app_management/compose", nil, &composeApps); err != nil {
+		return nil, fmt.Errorf("Failed to fetch compose apps: %v", err)
+	}
+
+	images := make(map[string][]string)
+	for appName, app := range composeApps {
+		seen := make(map[string]bool)
+		for _, service := range app.Compose.Services {
+			if service.Image == "" || seen[service.Image] {
+				continue
+			}
+			seen[service.Image] = true
+			images[appName] = append(images[appName], service.Image)
+		}
+		sort.Strings(images[appName])
+	}
+	return images, nil
+}
+
+// registryClient 匿名读取镜像仓库中的镜像清单，按仓库和镜像缓存令牌
+type registryClient struct {
+	client *retryClient
+	tokens map[string]string
+}
+
+// imageArchitectures 返回镜像在仓库中已有的CPU架构
+func (r *registryClient) imageArchitectures(ctx context.Context, image string) ([]string, error) {
+	ref := parseImageReference(image)
+	parts := strings.SplitN(ref.Name, "/", 2)
+	registry, repository := parts[0], parts[1]
+	host := registry
+	if host == defaultImageRegistry {
+		host = dockerHubRegistryHost
+	}
+	reference := ref.Tag
+	if ref.Digest != "" {
+		reference = ref.Digest
+	}
+
+	var manifest struct {
+		Architecture string `json:"architecture"`
+		Config       struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Manifests []struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := r.get(ctx, host, repository, "/manifests/"+reference, manifestAcceptTypes, &manifest); err != nil {
+		return nil, err
+	}
+
+	arches := make(map[string]bool)
+	switch {
+	case len(manifest.Manifests) > 0:
+		for _, m := range manifest.Manifests {
+			// 构建证明等附件的平台为unknown
+			if m.Platform.Architecture != "" && m.Platform.Architecture != "unknown" {
+				arches[normalizeArch(m.Platform.Architecture)] = true
+			}
+		}
+	case manifest.Architecture != "":
+		arches[normalizeArch(manifest.Architecture)] = true
+	case manifest.Config.Digest != "":
+		// 单架构镜像的架构记录在镜像配置中
+		var config struct {
+			Architecture string `json:"architecture"`
+		}
+		if err := r.get(ctx, host, repository, "/blobs/"+manifest.Config.Digest, "", &config); err != nil {
+			return nil, err
+		}
+		if config.Architecture != "" {
+			arches[normalizeArch(config.Architecture)] = true
+		}
+	}
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("Registry did not report the architectures of %s", image)
+	}
+
+	list := make([]string, 0, len(arches))
+	for arch := range arches {
+		list = append(list, arch)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// get 请求仓库API并解析JSON响应，返回401时按WWW-Authenticate获取匿名令牌后重试一次
+func (r *registryClient) get(ctx context.Context, host, repository, apiPath, accept string, out interface{}) error {
+	apiURL := fmt.Sprintf("https://%s/v2/%s%s", host, repository, apiPath)
+	tokenKey := host + "/" + repository
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to create request: %v", err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token := r.tokens[tokenKey]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("Registry request failed: %v", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Failed to read registry response: %v", err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			token, err := r.fetchToken(ctx, resp.Header.Get("WWW-Authenticate"))
+			if err != nil {
+				return err
+			}
+			r.tokens[tokenKey] = token
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Registry %s returned status code %d for %s", host, resp.StatusCode, repository)
+		}
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("Failed to parse registry response: %v", err)
+		}
+		return nil
+	}
+}
+
+// fetchToken 按Bearer质询向认证服务获取匿名拉取令牌
+func (r *registryClient) fetchToken(ctx context.Context, challenge string) (string, error) {
+	params := parseAuthChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("Registry requires authentication")
+	}
+	query := url.Values{}
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create request: %v", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Registry token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Registry token request failed, status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("Failed to parse registry token: %v", err)
+	}
+	if result.Token != "" {
+		return result.Token, nil
+	}
+	return result.AccessToken, nil
+}
+
+// parseAuthChallenge 解析 Bearer realm="...",service="...",scope="..." 形式的质询
+func parseAuthChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+	scheme, rest, found := strings.Cut(strings.TrimSpace(challenge), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return params
+	}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return params
+}
+
+// findArchMismatches 返回有镜像没有目标架构版本的应用，镜像先按镜像改写规则改写，即目标实际拉取的镜像
+// 无法读取架构的镜像（如私有仓库）不会被标记
+func (s *MigrationService) findArchMismatches(ctx context.Context, source *models.SystemConnection, targetArch string) ([]models.ArchMismatchApp, error) {
+	appImages, err := s.casaOSComposeImages(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	rules := sortedImageRules(s.taskService.store.GetAllImageRules())
+	registry := &registryClient{client: s.client, tokens: make(map[string]string)}
+	cache := make(map[string][]string)
+
+	apps := []models.ArchMismatchApp{}
+	for appName, images := range appImages {
+		var mismatched []models.ArchMismatchImage
+		for _, image := range images {
+			image, _ = rewriteImage(rules, nil, image)
+			arches, checked := cache[image]
+			if !checked {
+				arches, err = registry.imageArchitectures(ctx, image)
+				if err != nil {
+					log.Printf("[WARNING] Could not read architectures of image %s: %v", image, err)
+				}
+				cache[image] = arches
+			}
+			if len(arches) > 0 && !containsString(arches, targetArch) {
+				mismatched = append(mismatched, models.ArchMismatchImage{Image: image, Architectures: arches})
+			}
+		}
+		if len(mismatched) > 0 {
+			apps = append(apps, models.ArchMismatchApp{AppName: appName, Images: mismatched})
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].AppName < apps[j].AppName })
+	return apps, nil
+}
+
+// unconfirmedArchMismatches 返回迁移选项中尚未确认的应用
+func unconfirmedArchMismatches(options map[string]interface{}, apps []models.ArchMismatchApp) []models.ArchMismatchApp {
+	confirmed := make(map[string]bool)
+	switch value := options[confirmArchMismatchOption].(type) {
+	case bool:
+		if value {
+			return nil
+		}
+	case []interface{}:
+		for _, item := range value {
+			if name, ok := item.(string); ok {
+				confirmed[strings.ToLower(name)] = true
+			}
+		}
+	}
+
+	var pending []models.ArchMismatchApp
+	for _, app := range apps {
+		if !confirmed[strings.ToLower(app.AppName)] {
+			pending = append(pending, app)
+		}
+	}
+	return pending
+}
+
+// checkArchitectures 源和目标CPU架构不同时，开始迁移前检查应用镜像是否有目标架构的版本，未确认时返回 *models.ArchMismatchError
+// 只检查CasaOS源，架构或镜像列表无法读取时不阻止迁移
+func (s *MigrationService) checkArchitectures(ctx context.Context, source, target *models.SystemConnection, options map[string]interface{}) error {
+	if source.Type != models.SystemTypeCasaOS {
+		return nil
+	}
+	if confirmed, _ := options[confirmArchMismatchOption].(bool); confirmed {
+		return nil
+	}
+
+	sourceArch, err := s.systemArch(ctx, source)
+	if err != nil {
+		log.Printf("[WARNING] Skipping architecture check, source architecture unknown: %v", err)
+		return nil
+	}
+	targetArch, err := s.systemArch(ctx, target)
+	if err != nil {
+		log.Printf("[WARNING] Skipping architecture check, target architecture unknown: %v", err)
+		return nil
+	}
+	if sourceArch == targetArch {
+		return nil
+	}
+
+	apps, err := s.findArchMismatches(ctx, source, targetArch)
+	if err != nil {
+		log.Printf("[WARNING] Skipping architecture check: %v", err)
+		return nil
+	}
+	pending := unconfirmedArchMismatches(options, apps)
+	if len(pending) == 0 {
+		return nil
+	}
+	return &models.ArchMismatchError{SourceArch: sourceArch, TargetArch: targetArch, Apps: pending}
+}
+
+// preflightArchitectures 比较源和目标的CPU架构，不同时列出镜像没有目标架构版本的应用，有未确认的应用时给出警告
+func (s *MigrationService) preflightArchitectures(ctx context.Context, source, target *models.SystemConnection, options map[string]interface{}) models.PreflightCheck {
+	check := models.PreflightCheck{Name: "architecture"}
+	sourceArch, err := s.systemArch(ctx, source)
+	if err != nil {
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Could not determine source CPU architecture: %v", err)
+		return check
+	}
+	targetArch, err := s.systemArch(ctx, target)
+	if err != nil {
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Could not determine target CPU architecture: %v", err)
+		return check
+	}
+	check.Details = map[string]interface{}{"source_arch": sourceArch, "target_arch": targetArch}
+	if sourceArch == targetArch {
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("Source and target are both %s", sourceArch)
+		return check
+	}
+
+	apps, err := s.findArchMismatches(ctx, source, targetArch)
+	if err != nil {
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Could not check app images for %s: %v", targetArch, err)
+		return check
+	}
+	pending := unconfirmedArchMismatches(options, apps)
+	check.Details["apps"] = apps
+
+	switch {
+	case len(apps) == 0:
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("All app images are available for %s", targetArch)
+	case len(pending) == 0:
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("%d apps without images for %s are confirmed", len(apps), targetArch)
+	default:
+		names := make([]string, 0, len(pending))
+		for _, app := range pending {
+			names = append(names, app.AppName)
+		}
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Apps without images for %s need confirmation: %s", targetArch, strings.Join(names, ", "))
+	}
+	return check
+}
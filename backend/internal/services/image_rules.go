@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultImageRegistry 没有写明仓库地址的镜像所在的仓库
+	defaultImageRegistry = "docker.io"
+	// imageRuleMatchAll pin规则匹配所有镜像的写法
+	imageRuleMatchAll = "*"
+)
+
+// ImageRuleService 镜像引用改写规则服务，规则与任务一起保存在状态文件中
+type ImageRuleService struct {
+	taskService *TaskService
+}
+
+// NewImageRuleService 创建镜像改写规则服务
+func NewImageRuleService(taskService *TaskService) *ImageRuleService {
+	return &ImageRuleService{taskService: taskService}
+}
+
+// ListImageRules 按匹配顺序（创建时间）列出所有规则
+func (s *ImageRuleService) ListImageRules() []*models.ImageRule {
+	return sortedImageRules(s.taskService.store.GetAllImageRules())
+}
+
+// GetImageRule 获取规则
+func (s *ImageRuleService) GetImageRule(ruleID string) (*models.ImageRule, error) {
+	return s.taskService.store.GetImageRule(ruleID)
+}
+
+// CreateImageRule 创建规则，新规则排在已有规则之后
+func (s *ImageRuleService) CreateImageRule(req *models.ImageRuleRequest) (*models.ImageRule, error) {
+	if err := validateImageRule(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule := &models.ImageRule{
+		ID:          uuid.New().String(),
+		Type:        req.Type,
+		Match:       req.Match,
+		Replace:     req.Replace,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.taskService.store.SaveImageRule(rule)
+	return rule, nil
+}
+
+// UpdateImageRule 替换规则内容，保留匹配顺序
+func (s *ImageRuleService) UpdateImageRule(ruleID string, req *models.ImageRuleRequest) (*models.ImageRule, error) {
+	existing, err := s.taskService.store.GetImageRule(ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateImageRule(req); err != nil {
+		return nil, err
+	}
+
+	rule := &models.ImageRule{
+		ID:          existing.ID,
+		Type:        req.Type,
+		Match:       req.Match,
+		Replace:     req.Replace,
+		Description: req.Description,
+		CreatedAt:   existing.CreatedAt,
+		UpdatedAt:   time.Now(),
+	}
+	s.taskService.store.SaveImageRule(rule)
+	return rule, nil
+}
+
+// DeleteImageRule 删除规则，已完成的导入不受影响
+func (s *ImageRuleService) DeleteImageRule(ruleID string) error {
+	return s.taskService.store.DeleteImageRule(ruleID)
+}
+
+// validateImageRule 校验规则类型和匹配表达式
+func validateImageRule(req *models.ImageRuleRequest) error {
+	switch req.Type {
+	case models.ImageRulePrefix:
+		if strings.TrimSpace(req.Replace) == "" {
+			return fmt.Errorf("Prefix rules need a replacement prefix")
+		}
+	case models.ImageRuleRegex:
+		if _, err := regexp.Compile(req.Match); err != nil {
+			return fmt.Errorf("Invalid regular expression: %v", err)
+		}
+	case models.ImageRulePin:
+	default:
+		return fmt.Errorf("Invalid rule type: %s (expected %s, %s or %s)", req.Type, models.ImageRulePrefix, models.ImageRuleRegex, models.ImageRulePin)
+	}
+	return nil
+}
+
+// sortedImageRules 按创建时间排序规则，即匹配顺序
+func sortedImageRules(rules []*models.ImageRule) []*models.ImageRule {
+	sort.Slice(rules, func(i, j int) bool {
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// hasPinImageRules 是否存在pin规则
+func hasPinImageRules(rules []*models.ImageRule) bool {
+	for _, rule := range rules {
+		if rule.Type == models.ImageRulePin {
+			return true
+		}
+	}
+	return false
+}
+
+// imageReference 拆分后的镜像引用
+type imageReference struct {
+	Name   string // 带仓库地址的镜像名，如 docker.io/library/nginx
+	Tag    string
+	Digest string
+}
+
+// parseImageReference 拆分镜像引用并补全仓库地址，没有标签和摘要时标签为latest
+func parseImageReference(image string) imageReference {
+	var ref imageReference
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	// 第一段包含.或:或为localhost时是仓库地址
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 || (!strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost") {
+		if len(parts) == 1 {
+			name = "library/" + name
+		}
+		name = defaultImageRegistry + "/" + name
+	}
+	ref.Name = name
+	return ref
+}
+
+// String 返回完整的镜像引用
+func (r imageReference) String() string {
+	s := r.Name
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// matchImagePrefix 按引用的组成部分匹配前缀：docker.io/library/nginx 匹配 docker.io/library/nginx:1.25，不匹配 docker.io/library/nginx-proxy
+func matchImagePrefix(prefix, reference string) bool {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == imageRuleMatchAll {
+		return true
+	}
+	if !strings.HasPrefix(reference, prefix) {
+		return false
+	}
+	return len(reference) == len(prefix) || strings.ContainsRune("/:@", rune(reference[len(prefix)]))
+}
+
+// rewriteImage 先按pin规则把latest标签固定为源系统上的摘要，再用第一个匹配的prefix或regex规则改写镜像引用
+// pin规则匹配但没有该镜像的摘要时missingDigest为true；没有规则生效时原样返回
+func rewriteImage(rules []*models.ImageRule, digests map[string]string, image string) (rewritten string, missingDigest bool) {
+	rewritten = image
+	ref := parseImageReference(image)
+	if ref.Tag == "latest" && ref.Digest == "" {
+		for _, rule := range rules {
+			if rule.Type != models.ImageRulePin || !matchImagePrefix(rule.Match, ref.String()) {
+				continue
+			}
+			if digest := digests[ref.String()]; digest != "" {
+				ref.Digest = digest
+				rewritten = image + "@" + digest
+			} else {
+				missingDigest = true
+			}
+			break
+		}
+	}
+
+	reference := ref.String()
+	for _, rule := range rules {
+		switch rule.Type {
+		case models.ImageRulePrefix:
+			if matchImagePrefix(rule.Match, reference) {
+				return strings.TrimRight(rule.Replace, "/") + strings.TrimPrefix(reference, strings.TrimRight(rule.Match, "/")), missingDigest
+			}
+		case models.ImageRuleRegex:
+			re, err := regexp.Compile(rule.Match)
+			if err == nil && re.MatchString(reference) {
+				return re.ReplaceAllString(reference, rule.Replace), missingDigest
+			}
+		}
+	}
+	return rewritten, missingDigest
+}
+
+// parseImageDigests 解析docker image ls --digests的输出，返回完整镜像引用到摘要的映射
+func parseImageDigests(output []byte) map[string]string {
+	digests := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) != 3 || fields[0] == "<none>" || fields[1] == "<none>" || !strings.HasPrefix(fields[2], "sha256:") {
+			continue
+		}
+		digests[parseImageReference(fields[0]+":"+fields[1]).String()] = fields[2]
+	}
+	return digests
+}
+
+// captureSourceImageDigests 存在pin规则时读取CasaOS源上本地镜像的摘要并保存到任务中，读取失败时镜像不固定
+func (s *MigrationService) captureSourceImageDigests(ctx context.Context, taskID string, conn *models.SystemConnection) {
+	if !hasPinImageRules(s.taskService.store.GetAllImageRules()) {
+		return
+	}
+	if conn.SSHPort <= 0 {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, "Pinning images to digests requires ssh_port on the source connection, images will not be pinned")
+		return
+	}
+	sshConn := *conn
+	sshConn.Port = conn.SSHPort
+
+	output, err := runSSH(ctx, &sshConn, nil, "docker image ls --digests --format "+shellQuote("{{.Repository}}\t{{.Tag}}\t{{.Digest}}"))
+	if err != nil {
+		log.Printf("[WARNING] Failed to read image digests from %s: %v", conn.Host, err)
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Could not read source image digests, images will not be pinned: %v", err))
+		return
+	}
+	digests := parseImageDigests(output)
+	s.taskService.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		task.ImageDigests = digests
+	})
+	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Read digests of %d source images", len(digests)))
+}
+
+// applyImageRules 导入前按镜像改写规则修改compose中的镜像引用，每处改写记录到任务日志
+func (s *MigrationService) applyImageRules(taskID, appName, composeContent string) string {
+	rules := sortedImageRules(s.taskService.store.GetAllImageRules())
+	if len(rules) == 0 {
+		return composeContent
+	}
+	doc, err := parseCompose(composeContent)
+	if err != nil {
+		return composeContent
+	}
+
+	var digests map[string]string
+	if hasPinImageRules(rules) {
+		if task, err := s.taskService.GetTask(taskID); err == nil {
+			digests = task.ImageDigests
+		}
+	}
+
+	changed := false
+	doc.RewriteImages(func(service, image string) string {
+		rewritten, missingDigest := rewriteImage(rules, digests, image)
+		if missingDigest {
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: service %s image %s was not pinned, no digest was read from the source", appName, service, image))
+		}
+		if rewritten == image {
+			return image
+		}
+		changed = true
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: service %s image %s rewritten to %s", appName, service, image, rewritten))
+		return rewritten
+	})
+	if !changed {
+		return composeContent
+	}
+
+	rewrittenContent, err := doc.String()
+	if err != nil {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to apply image rules: %v", appName, err))
+		return composeContent
+	}
+	return rewrittenContent
+}
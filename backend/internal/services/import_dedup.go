@@ -0,0 +1,83 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// archiveSHA256Option 任务选项：上传的导入压缩包的SHA-256，由服务端计算
+	archiveSHA256Option = "archive_sha256"
+	// allowDuplicateOption 导入选项：确认再次导入已导入到同一目标的压缩包
+	allowDuplicateOption = "allow_duplicate"
+)
+
+// FileSHA256 计算文件的SHA-256（十六进制）
+func FileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("Failed to hash file: %v", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// CheckDuplicateImport 将压缩包的SHA-256记录到导入选项中，并检查是否已导入到同一目标
+// 旧的压缩包再次导入会用旧数据覆盖目标上较新的应用数据，未设置allow_duplicate时返回 *models.DuplicateImportError
+// 失败的导入不算在内，可以直接重试
+func (s *MigrationService) CheckDuplicateImport(req *models.DataImportRequest, checksum string) error {
+	if req.ImportOptions == nil {
+		req.ImportOptions = make(map[string]interface{})
+	}
+	req.ImportOptions[archiveSHA256Option] = checksum
+
+	options, err := applyPreset(s.taskService.store, req.Preset, req.ImportOptions)
+	if err != nil {
+		return err
+	}
+	if allowed, _ := options[allowDuplicateOption].(bool); allowed {
+		return nil
+	}
+
+	var previous *models.MigrationTask
+	for _, task := range s.taskService.ListTasks() {
+		if task.Type != models.TaskTypeImport || task.Status == string(models.TaskStatusFailed) {
+			continue
+		}
+		if sum, _ := task.Options[archiveSHA256Option].(string); sum != checksum || !sameTarget(task.Target, &req.Target) {
+			continue
+		}
+		if previous == nil || task.CreatedAt.After(previous.CreatedAt) {
+			previous = task
+		}
+	}
+	if previous == nil {
+		return nil
+	}
+	return &models.DuplicateImportError{Duplicate: &models.DuplicateImport{
+		TaskID:     previous.ID,
+		TaskName:   previous.Name,
+		Status:     previous.Status,
+		SHA256:     checksum,
+		ImportedAt: previous.CreatedAt,
+	}}
+}
+
+// sameTarget 判断两个目标连接是否指向同一系统
+func sameTarget(a, b *models.SystemConnection) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return strings.EqualFold(a.Host, b.Host) && a.Port == b.Port && a.Type == b.Type
+}
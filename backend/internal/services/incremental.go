@@ -0,0 +1,372 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// exportManifestName 导出压缩包中记录应用哈希和增量关系的清单
+	exportManifestName = "ctoz_manifest.json"
+	// exportTypeFull 完整导出
+	exportTypeFull = "full"
+	// exportTypeIncremental 增量导出，只包含相对上一个导出有变化的应用
+	exportTypeIncremental = "incremental"
+	// defaultFullBackupEvery 未设置时连续增量备份的最大次数
+	defaultFullBackupEvery = 7
+	// maxIncrementalChain 导入时增量链的最大长度，防止清单循环引用
+	maxIncrementalChain = 1000
+)
+
+// exportManifest 导出压缩包清单
+// Apps 记录导出时源系统全部应用的内容哈希，增量导出中不在其中的应用视为已删除
+type exportManifest struct {
+	Version   int               `json:"version"`
+	Type      string            `json:"type"`
+	Parent    string            `json:"parent,omitempty"` // 增量导出所基于的上一个压缩包文件名
+	Apps      map[string]string `json:"apps"`
+	Changed   []string          `json:"changed,omitempty"` // 增量导出中包含的应用
+	Include   []string          `json:"include,omitempty"` // 导出时使用的包含模式
+	Exclude   []string          `json:"exclude,omitempty"` // 导出时使用的排除模式
+	Files     *exportFileCounts `json:"files,omitempty"`   // 写入和被过滤掉的文件数
+	Images    map[string]string `json:"images,omitempty"`  // 应用名到images目录中镜像包的路径
+	CreatedAt time.Time         `json:"created_at"`
+
+	changedSet map[string]bool
+}
+
+// includes 判断压缩包条目是否写入导出文件，增量导出跳过未变化应用的条目
+func (m *exportManifest) includes(name string) bool {
+	if m == nil || m.Type != exportTypeIncremental {
+		return true
+	}
+	app, ok := entryApp(name)
+	if !ok {
+		return true
+	}
+	if m.changedSet == nil {
+		m.changedSet = make(map[string]bool, len(m.Changed))
+		for _, changed := range m.Changed {
+			m.changedSet[changed] = true
+		}
+	}
+	return m.changedSet[app]
+}
+
+// entryApp 返回压缩包条目所属的应用（compose目录或AppData目录）
+func entryApp(name string) (string, bool) {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+	for _, prefix := range []string{"var/lib/casaos/apps/", "DATA/AppData/"} {
+		if rest := strings.TrimPrefix(name, prefix); rest != name {
+			app := strings.SplitN(rest, "/", 2)[0]
+			return app, app != ""
+		}
+	}
+	return "", false
+}
+
+// appContentHashes 根据压缩包条目的名称、大小和CRC32计算每个应用的内容哈希，无需解压
+// 被导出过滤掉的条目不计入哈希
+func appContentHashes(zipPath string, filter *exportFilter) (map[string]string, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open ZIP file: %v", err)
+	}
+	defer r.Close()
+
+	entries := make(map[string][]string)
+	for _, f := range r.File {
+		app, ok := entryApp(f.Name)
+		if !ok || !filter.allows(f.Name) {
+			continue
+		}
+		entries[app] = append(entries[app], fmt.Sprintf("%s\x00%d\x00%08x\x00%o", strings.TrimPrefix(f.Name, "/"), f.UncompressedSize64, f.CRC32, f.Mode()))
+	}
+
+	hashes := make(map[string]string, len(entries))
+	for app, lines := range entries {
+		sort.Strings(lines)
+		sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+		hashes[app] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
+// changedApps 返回与上次导出相比新增或内容变化的应用
+func changedApps(current, previous map[string]string) []string {
+	var changed []string
+	for app, hash := range current {
+		if previous[app] != hash {
+			changed = append(changed, app)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// newExportManifest 创建完整导出的清单，记录导出过滤模式
+func newExportManifest(hashes map[string]string, filter *models.ExportFilter) *exportManifest {
+	manifest := &exportManifest{
+		Version:   1,
+		Type:      exportTypeFull,
+		Apps:      hashes,
+		Files:     &exportFileCounts{},
+		CreatedAt: time.Now(),
+	}
+	if filter != nil {
+		manifest.Include = filter.Include
+		manifest.Exclude = filter.Exclude
+	}
+	return manifest
+}
+
+// writeExportManifest 将清单写入导出压缩包
+func writeExportManifest(zipWriter *zip.Writer, manifest *exportManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to serialize export manifest: %v", err)
+	}
+	writer, err := zipWriter.Create(exportManifestName)
+	if err != nil {
+		return fmt.Errorf("Failed to create ZIP entry: %v", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("Failed to write export manifest: %v", err)
+	}
+	return nil
+}
+
+// readExportManifest 读取压缩包中的清单，没有清单或不是ZIP文件时返回nil
+func readExportManifest(zipPath string) (*exportManifest, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, nil
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != exportManifestName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open export manifest: %v", err)
+		}
+		defer rc.Close()
+
+		var manifest exportManifest
+		if err := json.NewDecoder(io.LimitReader(rc, 16<<20)).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("Invalid export manifest in %s: %v", filepath.Base(zipPath), err)
+		}
+		return &manifest, nil
+	}
+	return nil, nil
+}
+
+// backupParentArchives 返回导出目录中压缩包依赖的全部上级压缩包路径
+func backupParentArchives(archivePath string) []string {
+	var parents []string
+	current := archivePath
+	for i := 0; i < maxIncrementalChain; i++ {
+		manifest, err := readExportManifest(current)
+		if err != nil || manifest == nil || manifest.Type != exportTypeIncremental || manifest.Parent == "" {
+			break
+		}
+		current = filepath.Join(filepath.Dir(archivePath), filepath.Base(manifest.Parent))
+		parents = append(parents, current)
+	}
+	return parents
+}
+
+// incrementalChain 从增量导出向上查找到完整导出，返回按从旧到新排列的压缩包及其清单
+// 上级压缩包在导入文件所在目录和导出目录中按文件名查找
+func (s *MigrationService) incrementalChain(importFile string, manifest *exportManifest) ([]string, []*exportManifest, error) {
+	paths := []string{importFile}
+	manifests := []*exportManifest{manifest}
+	searchDirs := []string{filepath.Dir(importFile), s.cfg.Dirs.Export}
+
+	for manifest.Type == exportTypeIncremental {
+		if len(paths) >= maxIncrementalChain {
+			return nil, nil, fmt.Errorf("Incremental export chain is longer than %d archives", maxIncrementalChain)
+		}
+		if manifest.Parent == "" {
+			return nil, nil, fmt.Errorf("Incremental export has no base archive")
+		}
+
+		parentName := filepath.Base(manifest.Parent)
+		var parentPath string
+		for _, dir := range searchDirs {
+			candidate := filepath.Join(dir, parentName)
+			if _, err := os.Stat(candidate); err == nil {
+				parentPath = candidate
+				break
+			}
+		}
+		if parentPath == "" {
+			return nil, nil, fmt.Errorf("Base archive %s of incremental export not found", parentName)
+		}
+
+		parent, err := readExportManifest(parentPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if parent == nil {
+			return nil, nil, fmt.Errorf("Base archive %s has no export manifest", parentName)
+		}
+		paths = append([]string{parentPath}, paths...)
+		manifests = append([]*exportManifest{parent}, manifests...)
+		manifest = parent
+	}
+	return paths, manifests, nil
+}
+
+// mergeIncrementalExport 将增量导出按顺序叠加到完整导出上，结果写入extractDir
+// 每层先删除该层中已变化或已删除应用的目录，再解压该层
+func (s *MigrationService) mergeIncrementalExport(taskID, importFile, extractDir string, manifest *exportManifest, progressCallback func(int, string)) error {
+	paths, manifests, err := s.incrementalChain(importFile, manifest)
+	if err != nil {
+		return err
+	}
+
+	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Layering %d incremental exports over base %s", len(paths)-1, filepath.Base(paths[0])))
+
+	entries, err := os.ReadDir(extractDir)
+	if err != nil {
+		return fmt.Errorf("Failed to read extraction directory: %v", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(extractDir, entry.Name())); err != nil {
+			return fmt.Errorf("Failed to clean extraction directory: %v", err)
+		}
+	}
+
+	for i, layerPath := range paths {
+		progressCallback(30+20*i/len(paths), fmt.Sprintf("Extracting %s (%d/%d)...", filepath.Base(layerPath), i+1, len(paths)))
+		if i > 0 {
+			layer := manifests[i]
+			removed := append([]string{}, layer.Changed...)
+			for app := range manifests[i-1].Apps {
+				if _, ok := layer.Apps[app]; !ok {
+					removed = append(removed, app)
+				}
+			}
+			for _, app := range removed {
+				if err := removeExtractedApp(extractDir, app); err != nil {
+					return err
+				}
+			}
+		}
+		if err := s.extractZipFile(layerPath, extractDir); err != nil {
+			return fmt.Errorf("Failed to extract %s: %v", filepath.Base(layerPath), err)
+		}
+	}
+
+	// 清单只描述单个压缩包，合并后删除以免误认为仍是增量导出
+	os.Remove(filepath.Join(extractDir, exportManifestName))
+	return nil
+}
+
+// removeExtractedApp 删除解压目录中应用的compose目录、AppData目录和镜像包
+func removeExtractedApp(extractDir, app string) error {
+	for _, dir := range []string{"var/lib/casaos/apps", "DATA/AppData"} {
+		root := filepath.Join(extractDir, dir)
+		appDir := filepath.Join(root, app)
+		if app == "" || filepath.Dir(appDir) != root {
+			return fmt.Errorf("Invalid app name in export manifest: %s", app)
+		}
+		if err := os.RemoveAll(appDir); err != nil {
+			return fmt.Errorf("Failed to remove %s: %v", appDir, err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(extractDir, filepath.FromSlash(exportImagePath(app)))); err != nil {
+		return fmt.Errorf("Failed to remove container images of app %s: %v", app, err)
+	}
+	return nil
+}
+
+// createBackupArchive 下载源系统数据并生成以备份任务命名的导出压缩包
+// 开启增量备份时只包含与上次备份相比有变化的应用
+func (s *MigrationService) createBackupArchive(ctx context.Context, task *models.MigrationTask, jobID string, exportData map[string]interface{}, progressCallback func(int, string)) (string, error) {
+	var downloadedPath string
+	err := s.withSourceQuiesced(ctx, task.ID, task.Source, task.Options, func() error {
+		var err error
+		downloadedPath, err = s.downloadCasaOSFiles(ctx, task.Source, progressCallback)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to download CasaOS files: %v", err)
+	}
+	defer os.Remove(downloadedPath)
+
+	filterSpec, err := parseExportFilter(task.Options)
+	if err != nil {
+		return "", err
+	}
+	filter, err := newExportFilter(filterSpec)
+	if err != nil {
+		return "", err
+	}
+	hashes, err := appContentHashes(downloadedPath, filter)
+	if err != nil {
+		return "", err
+	}
+	manifest := newExportManifest(hashes, filterSpec)
+
+	job, err := s.taskService.store.GetBackupJob(jobID)
+	if err == nil && job.Incremental && job.LastArchive != "" && job.AppHashes != nil {
+		fullEvery := job.FullEvery
+		if fullEvery <= 0 {
+			fullEvery = defaultFullBackupEvery
+		}
+		if job.IncrementalCount < fullEvery {
+			manifest.Type = exportTypeIncremental
+			manifest.Parent = job.LastArchive
+			manifest.Changed = changedApps(hashes, job.AppHashes)
+		}
+	}
+	if manifest.Type == exportTypeIncremental {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Incremental backup: %d of %d apps changed since %s", len(manifest.Changed), len(hashes), manifest.Parent))
+	} else {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Full backup of %d apps", len(hashes)))
+	}
+
+	filePath, err := s.writeExportArchive(exportData, downloadedPath, manifest, filter)
+	if err != nil {
+		return "", err
+	}
+	if filter != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Export filter: %d app files and %d data files included, %d files excluded", manifest.Files.Apps, manifest.Files.Data, manifest.Files.Excluded))
+	}
+
+	backupPath := filepath.Join(filepath.Dir(filePath), backupArchiveName(jobID, time.Now()))
+	if err := os.Rename(filePath, backupPath); err != nil {
+		return "", fmt.Errorf("Failed to rename backup archive: %v", err)
+	}
+
+	// 记录本次备份作为下一次增量备份的基准
+	if err := s.taskService.store.UpdateBackupJob(jobID, func(j *models.BackupJob) {
+		j.LastArchive = filepath.Base(backupPath)
+		j.AppHashes = hashes
+		if manifest.Type == exportTypeIncremental {
+			j.IncrementalCount++
+		} else {
+			j.IncrementalCount = 0
+		}
+	}); err != nil {
+		log.Printf("[WARNING] Failed to record backup state of job %s: %v", jobID, err)
+	}
+	return backupPath, nil
+}
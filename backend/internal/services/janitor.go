@@ -0,0 +1,374 @@
+package services
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/logging"
+	"ctoz/backend/internal/models"
+)
+
+// minActiveCleanupAge 有任务运行时的最小清理时长
+// 运行中任务的临时目录没有记录在任务中，只能依靠修改时间区分
+const minActiveCleanupAge = time.Hour
+
+// JanitorService 清理工作目录中不再被任务引用的过期文件
+// 任务异常退出或服务重启后，下载、解压、导出产生的文件会残留在工作目录中
+type JanitorService struct {
+	cfg         *config.Config
+	taskService *TaskService
+	mutex       sync.Mutex
+}
+
+// NewJanitorService 创建临时文件清理服务
+func NewJanitorService(cfg *config.Config, taskService *TaskService) *JanitorService {
+	return &JanitorService{
+		cfg:         cfg,
+		taskService: taskService,
+	}
+}
+
+// Start 按配置的间隔在后台自动清理，未配置间隔时不启动
+// 未配置过期时长时只执行备份保留规则
+func (s *JanitorService) Start() {
+	maxAge, interval := s.cfg.Cleanup.MaxAge, s.cfg.Cleanup.Interval
+	if interval <= 0 {
+		log.Printf("[INFO] Automatic cleanup of temporary files disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			var report *models.CleanupReport
+			if maxAge > 0 {
+				report = s.Cleanup(maxAge)
+			} else {
+				report = s.PruneBackups()
+			}
+			if len(report.Removed) > 0 || len(report.Pruned) > 0 {
+				log.Printf("[INFO] Cleanup removed %d temporary entries and %d expired backups (%d bytes)", len(report.Removed), len(report.Pruned), report.FreedBytes)
+			}
+		}
+	}()
+	if maxAge > 0 {
+		log.Printf("[INFO] Automatic cleanup of temporary files older than %s enabled (every %s)", maxAge, interval)
+	} else {
+		log.Printf("[INFO] Automatic cleanup of temporary files disabled; backup retention enforced every %s", interval)
+	}
+}
+
+// DefaultMaxAge 手动清理默认使用的过期时长，未配置自动清理时为24小时
+func (s *JanitorService) DefaultMaxAge() time.Duration {
+	if s.cfg.Cleanup.MaxAge > 0 {
+		return s.cfg.Cleanup.MaxAge
+	}
+	return 24 * time.Hour
+}
+
+// Cleanup 删除工作目录中超过maxAge未修改且未被任何任务引用的文件和目录
+func (s *JanitorService) Cleanup(maxAge time.Duration) *models.CleanupReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &models.CleanupReport{Removed: []string{}}
+	s.pruneBackups(report)
+
+	referenced, active := s.referencedPaths()
+	if active && maxAge < minActiveCleanupAge {
+		maxAge = minActiveCleanupAge
+	}
+	cutoff := time.Now().Add(-maxAge)
+
+	// 工作目录可能互相嵌套（如下载目录设为工作根目录），嵌套的工作目录本身不能删除
+	dirs := s.cfg.Dirs.All()
+	var absDirs []string
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			absDirs = append(absDirs, abs)
+		}
+	}
+	// 归档目录保存用户要保留的备份，不清理，位于其它工作目录下时同样受保护
+	if abs, err := filepath.Abs(s.cfg.Dirs.Archive); err == nil && s.cfg.Dirs.Archive != "" {
+		absDirs = append(absDirs, abs)
+	}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			entryPath := filepath.Join(dir, entry.Name())
+			if containsWorkDir(entryPath, absDirs) || isReferenced(entryPath, referenced) {
+				report.Kept++
+				continue
+			}
+
+			// 目录以其中最近修改的文件为准，避免删除仍在写入的目录
+			modTime, size := entryStats(entryPath)
+			if modTime.After(cutoff) {
+				report.Kept++
+				continue
+			}
+
+			if err := os.RemoveAll(entryPath); err != nil {
+				log.Printf("[WARNING] Failed to remove temporary entry %s: %v", entryPath, err)
+				continue
+			}
+			log.Printf("[DEBUG] Removed temporary entry: %s", entryPath)
+			report.Removed = append(report.Removed, entryPath)
+			report.FreedBytes += size
+		}
+	}
+	return report
+}
+
+// DiskUsage 统计各工作目录的磁盘占用，嵌套的工作目录单独统计，不计入外层目录
+func (s *JanitorService) DiskUsage() []models.WorkDirUsage {
+	dirs := []struct{ name, path string }{
+		{"download", s.cfg.Dirs.Download},
+		{"upload", s.cfg.Dirs.Upload},
+		{"extract", s.cfg.Dirs.Extract},
+		{"compress", s.cfg.Dirs.Compress},
+		{"export", s.cfg.Dirs.Export},
+		{"package", s.cfg.Dirs.Package},
+		{"archive", s.cfg.Dirs.Archive},
+	}
+	var absDirs []string
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir.path); err == nil && dir.path != "" {
+			absDirs = append(absDirs, abs)
+		}
+	}
+
+	usage := []models.WorkDirUsage{}
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if dir.path == "" || seen[dir.path] {
+			continue
+		}
+		seen[dir.path] = true
+		entry := models.WorkDirUsage{Name: dir.name, Path: dir.path}
+		entries, _ := os.ReadDir(dir.path)
+		for _, e := range entries {
+			entryPath := filepath.Join(dir.path, e.Name())
+			if containsWorkDir(entryPath, absDirs) {
+				continue
+			}
+			_, size := entryStats(entryPath)
+			entry.Bytes += size
+			entry.Entries++
+		}
+		usage = append(usage, entry)
+	}
+	return usage
+}
+
+// PurgeAll 删除全部任务、日志、保存的连接和定时备份任务，并清空所有工作目录（包括归档目录）
+// 用于迁移完成后清除工具中的敏感数据；有任务待执行、运行或等待目标恢复时返回ErrTasksRunning
+func (s *JanitorService) PurgeAll() (*models.PurgeReport, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tasks := s.taskService.ListTasks()
+	for _, task := range tasks {
+		switch models.TaskStatus(task.Status) {
+		case models.TaskStatusPending, models.TaskStatusRunning, models.TaskStatusWaiting:
+			return nil, models.ErrTasksRunning
+		}
+	}
+	conns, _ := s.taskService.store.GetAllConnections()
+
+	report := s.taskService.store.Purge()
+	// 已删除的任务和连接的密码和令牌不再替换
+	for _, task := range tasks {
+		logging.ReleaseSecrets(taskSecretOwner(task.ID))
+	}
+	for _, conn := range conns {
+		logging.ReleaseSecrets(connectionSecretOwner(conn.ID))
+	}
+	report.RemovedFiles = []string{}
+	if err := s.taskService.store.Flush(); err != nil {
+		log.Printf("[WARNING] Failed to persist state after purge: %v", err)
+	}
+
+	// 嵌套的工作目录本身保留，其内容在遍历该目录时删除
+	dirs := append(s.cfg.Dirs.All(), s.cfg.Dirs.Archive)
+	var absDirs []string
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil && dir != "" {
+			absDirs = append(absDirs, abs)
+		}
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			entryPath := filepath.Join(dir, entry.Name())
+			if containsWorkDir(entryPath, absDirs) {
+				continue
+			}
+			_, size := entryStats(entryPath)
+			if err := os.RemoveAll(entryPath); err != nil {
+				log.Printf("[WARNING] Failed to remove %s: %v", entryPath, err)
+				continue
+			}
+			report.RemovedFiles = append(report.RemovedFiles, entryPath)
+			report.FreedBytes += size
+		}
+	}
+
+	log.Printf("[INFO] Purged %d tasks, %d connections, %d backup jobs and %d files", report.Tasks, report.Connections, report.BackupJobs, len(report.RemovedFiles))
+	return &report, nil
+}
+
+// PruneBackups 按各定时备份任务的保留规则删除导出目录中过期的备份压缩包
+func (s *JanitorService) PruneBackups() *models.CleanupReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &models.CleanupReport{Removed: []string{}}
+	s.pruneBackups(report)
+	return report
+}
+
+// pruneBackups 删除过期的备份压缩包并记录到清理结果中，已删除任务的备份不处理
+func (s *JanitorService) pruneBackups(report *models.CleanupReport) {
+	now := time.Now()
+	for _, job := range s.taskService.store.GetAllBackupJobs() {
+		retention := defaultBackupRetention
+		if job.Retention != nil {
+			retention = *job.Retention
+		}
+
+		archives := listBackupArchives(s.cfg.Dirs.Export, job.ID)
+		expired := expiredBackupArchives(archives, retention, now)
+
+		// 保留的增量备份依赖的上级备份不能删除
+		required := make(map[string]bool)
+		expiredSet := make(map[string]bool, len(expired))
+		for _, archive := range expired {
+			expiredSet[archive.path] = true
+		}
+		for _, archive := range archives {
+			if expiredSet[archive.path] {
+				continue
+			}
+			for _, parent := range backupParentArchives(archive.path) {
+				required[parent] = true
+			}
+		}
+
+		for _, archive := range expired {
+			if required[archive.path] {
+				continue
+			}
+			info, err := os.Stat(archive.path)
+			if err != nil {
+				continue
+			}
+			if err := os.Remove(archive.path); err != nil {
+				log.Printf("[WARNING] Failed to remove expired backup %s: %v", archive.path, err)
+				continue
+			}
+			log.Printf("[INFO] Removed expired backup of job %s: %s", job.ID, archive.path)
+			report.Pruned = append(report.Pruned, archive.path)
+			report.FreedBytes += info.Size()
+		}
+	}
+}
+
+// referencedPaths 收集任务仍在使用的文件路径（绝对路径），并返回是否有运行中的任务
+func (s *JanitorService) referencedPaths() ([]string, bool) {
+	var paths []string
+	var anyActive bool
+	add := func(p string) {
+		if p == "" {
+			return
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+
+	for _, task := range s.taskService.ListTasks() {
+		// 结束且不可恢复的任务不再需要导入文件
+		if importFile, ok := task.Options["import_file"].(string); ok && (!models.IsTerminalStatus(task.Status) || task.Resumable) {
+			add(importFile)
+		}
+		if exportFile, ok := task.Result["export_file"].(string); ok {
+			add(exportFile)
+		}
+		if task.Checkpoint != nil {
+			add(task.Checkpoint.DownloadPath)
+			add(task.Checkpoint.ExtractedPath)
+		}
+
+		// 导入任务固定解压到同一目录
+		active := task.Status == string(models.TaskStatusRunning) || task.Status == string(models.TaskStatusPending) || task.Status == string(models.TaskStatusWaiting)
+		if active && task.Type == models.TaskTypeImport {
+			add(importExtractDir(s.cfg.Dirs))
+		}
+		anyActive = anyActive || active
+	}
+	return paths, anyActive
+}
+
+// isReferenced 判断条目本身、其中的文件或其上级目录是否被任务引用
+func isReferenced(entryPath string, referenced []string) bool {
+	abs, err := filepath.Abs(entryPath)
+	if err != nil {
+		return true
+	}
+	for _, p := range referenced {
+		if isWithinDir(abs, p) || isWithinDir(p, abs) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWorkDir 判断条目是否为某个工作目录或包含工作目录
+func containsWorkDir(entryPath string, absDirs []string) bool {
+	abs, err := filepath.Abs(entryPath)
+	if err != nil {
+		return true
+	}
+	for _, dir := range absDirs {
+		if isWithinDir(abs, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// entryStats 返回条目内最近的修改时间和总大小
+func entryStats(entryPath string) (time.Time, int64) {
+	var newest time.Time
+	var size int64
+	filepath.Walk(entryPath, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return newest, size
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// largeAppBytesOption 迁移选项：覆盖CTOZ_LARGE_APP_BYTES的大小阈值（字节），0表示不检查
+	largeAppBytesOption = "large_app_bytes"
+	// confirmLargeAppsOption 迁移选项：true确认全部大应用，或确认的应用名列表
+	confirmLargeAppsOption = "confirm_large_apps"
+)
+
+// largeAppThreshold 返回迁移使用的AppData大小阈值
+func (s *MigrationService) largeAppThreshold(options map[string]interface{}) int64 {
+	if value, ok := options[largeAppBytesOption].(float64); ok && value >= 0 {
+		return int64(value)
+	}
+	return s.cfg.LargeAppBytes
+}
+
+// casaOSAppDataSizes 统计CasaOS源系统上每个应用AppData目录的大小
+func (s *MigrationService) casaOSAppDataSizes(ctx context.Context, conn *models.SystemConnection) (map[string]int64, error) {
+	entries, err := s.listCasaOSFolder(ctx, conn, casaOSAppDataDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list AppData directory: %v", err)
+	}
+
+	sizes := make(map[string]int64)
+	for _, entry := range entries {
+		if !entry.IsDir {
+			continue
+		}
+		size, err := s.getCasaOSFolderSize(ctx, conn, path.Join(casaOSAppDataDir, entry.Name))
+		if err != nil {
+			log.Printf("[WARNING] Failed to get AppData size for app %s: %v", entry.Name, err)
+			continue
+		}
+		sizes[entry.Name] = size
+	}
+	return sizes, nil
+}
+
+// findLargeApps 返回AppData超过阈值的应用，按大小从大到小排列
+func findLargeApps(sizes map[string]int64, threshold int64) []models.LargeApp {
+	apps := []models.LargeApp{}
+	if threshold <= 0 {
+		return apps
+	}
+	for name, size := range sizes {
+		if size > threshold {
+			apps = append(apps, models.LargeApp{AppName: name, AppDataBytes: size})
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].AppDataBytes != apps[j].AppDataBytes {
+			return apps[i].AppDataBytes > apps[j].AppDataBytes
+		}
+		return apps[i].AppName < apps[j].AppName
+	})
+	return apps
+}
+
+// unconfirmedLargeApps 返回迁移选项中尚未确认的大应用
+func unconfirmedLargeApps(options map[string]interface{}, apps []models.LargeApp) []models.LargeApp {
+	confirmed := make(map[string]bool)
+	switch value := options[confirmLargeAppsOption].(type) {
+	case bool:
+		if value {
+			return nil
+		}
+	case []interface{}:
+		for _, item := range value {
+			if name, ok := item.(string); ok {
+				confirmed[strings.ToLower(name)] = true
+			}
+		}
+	}
+
+	var pending []models.LargeApp
+	for _, app := range apps {
+		if !confirmed[strings.ToLower(app.AppName)] {
+			pending = append(pending, app)
+		}
+	}
+	return pending
+}
+
+// checkLargeApps 开始迁移前检查AppData超过阈值的应用，未确认时返回 *models.LargeAppsError
+// 只有CasaOS源可以在迁移前统计大小，统计失败时不阻止迁移
+func (s *MigrationService) checkLargeApps(ctx context.Context, source *models.SystemConnection, options map[string]interface{}) error {
+	threshold := s.largeAppThreshold(options)
+	if threshold <= 0 || source.Type != models.SystemTypeCasaOS {
+		return nil
+	}
+	if confirmed, _ := options[confirmLargeAppsOption].(bool); confirmed {
+		return nil
+	}
+
+	sizes, err := s.casaOSAppDataSizes(ctx, source)
+	if err != nil {
+		log.Printf("[WARNING] Skipping large app check: %v", err)
+		return nil
+	}
+	pending := unconfirmedLargeApps(options, findLargeApps(sizes, threshold))
+	if len(pending) == 0 {
+		return nil
+	}
+	return &models.LargeAppsError{Apps: pending, ThresholdBytes: threshold}
+}
+
+// preflightLargeApps 列出AppData超过阈值的应用，有未确认的应用时给出警告
+func (s *MigrationService) preflightLargeApps(ctx context.Context, source *models.SystemConnection, options map[string]interface{}) models.PreflightCheck {
+	check := models.PreflightCheck{Name: "large_apps"}
+	threshold := s.largeAppThreshold(options)
+	if threshold <= 0 {
+		check.Status = models.PreflightPass
+		check.Message = "Large app check is disabled"
+		return check
+	}
+
+	sizes, err := s.casaOSAppDataSizes(ctx, source)
+	if err != nil {
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Could not determine app sizes: %v", err)
+		return check
+	}
+	apps := findLargeApps(sizes, threshold)
+	pending := unconfirmedLargeApps(options, apps)
+	check.Details = map[string]interface{}{"threshold_bytes": threshold, "apps": apps}
+
+	switch {
+	case len(apps) == 0:
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("No app has more than %s of AppData", formatBytes(threshold))
+	case len(pending) == 0:
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("%d apps with more than %s of AppData are confirmed", len(apps), formatBytes(threshold))
+	default:
+		names := make([]string, 0, len(pending))
+		for _, app := range pending {
+			names = append(names, fmt.Sprintf("%s (%s)", app.AppName, formatBytes(app.AppDataBytes)))
+		}
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Apps with more than %s of AppData need confirmation: %s", formatBytes(threshold), strings.Join(names, ", "))
+	}
+	return check
+}
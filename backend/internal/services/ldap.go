@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// LDAP结果码
+	ldapResultSuccess            = 0
+	ldapResultInvalidCredentials = 49
+
+	// ldapMaxMessageSize 单条响应的最大长度，用户条目的memberOf可能较长
+	ldapMaxMessageSize = 1 << 20
+
+	// ldapMemberOfAttr 用户所属组的属性（Active Directory、OpenLDAP memberof overlay、lldap等提供）
+	ldapMemberOfAttr = "memberOf"
+)
+
+// ldapAuthenticator 通过LDAP简单绑定校验用户名和密码
+// 用户DN由模板生成，不需要服务账号；配置了允许的组时以用户自己的身份读取其条目的memberOf
+type ldapAuthenticator struct {
+	cfg     config.LDAPConfig
+	timeout time.Duration
+}
+
+// authenticate 以用户DN和密码绑定LDAP服务器，密码错误时返回ErrInvalidCredentials
+func (a *ldapAuthenticator) authenticate(username, password string) error {
+	username = strings.TrimSpace(username)
+	// 空密码的简单绑定在多数服务器上是匿名绑定，会被当作成功
+	if username == "" || password == "" {
+		return models.ErrInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return fmt.Errorf("Failed to connect to LDAP server: %v", err)
+	}
+	defer conn.Close()
+	if a.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(a.timeout))
+	}
+
+	dn := strings.ReplaceAll(a.cfg.UserDN, "%s", escapeLDAPDNValue(username))
+	if _, err := conn.Write(ldapBindRequest(1, dn, password)); err != nil {
+		return fmt.Errorf("Failed to send LDAP bind request: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	code, message, err := readLDAPBindResponse(reader)
+	if err != nil {
+		return fmt.Errorf("Failed to read LDAP bind response: %v", err)
+	}
+	switch code {
+	case ldapResultSuccess:
+	case ldapResultInvalidCredentials:
+		return models.ErrInvalidCredentials
+	default:
+		return fmt.Errorf("LDAP bind failed with result code %d: %s", code, message)
+	}
+
+	if len(a.cfg.AllowedGroups) == 0 {
+		return nil
+	}
+	if _, err := conn.Write(ldapSearchRequest(2, dn, ldapMemberOfAttr)); err != nil {
+		return fmt.Errorf("Failed to send LDAP search request: %v", err)
+	}
+	groups, err := readLDAPSearchValues(reader, ldapMemberOfAttr)
+	if err != nil {
+		return fmt.Errorf("Failed to read LDAP groups: %v", err)
+	}
+	if !ldapGroupAllowed(groups, a.cfg.AllowedGroups) {
+		return models.ErrGroupNotAllowed
+	}
+	return nil
+}
+
+// ldapGroupAllowed 判断用户所属组是否在允许列表中，允许的组可以写完整DN或组DN第一个RDN的值（如cn）
+func ldapGroupAllowed(groups, allowed []string) bool {
+	for _, group := range groups {
+		name := group
+		if rdn, _, _ := strings.Cut(group, ","); strings.Contains(rdn, "=") {
+			_, name, _ = strings.Cut(rdn, "=")
+		}
+		for _, want := range allowed {
+			if strings.EqualFold(strings.TrimSpace(want), group) || strings.EqualFold(strings.TrimSpace(want), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dial 按URL连接LDAP服务器，ldaps使用TLS
+func (a *ldapAuthenticator) dial() (net.Conn, error) {
+	u, err := url.Parse(a.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid LDAP URL: %v", err)
+	}
+	dialer := &net.Dialer{Timeout: a.timeout}
+
+	switch strings.ToLower(u.Scheme) {
+	case "ldap":
+		return dialer.Dial("tcp", hostWithDefaultPort(u, "389"))
+	case "ldaps":
+		return tls.DialWithDialer(dialer, "tcp", hostWithDefaultPort(u, "636"), &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: a.cfg.InsecureSkipVerify,
+		})
+	}
+	return nil, fmt.Errorf("Unsupported LDAP URL scheme %q, use ldap or ldaps", u.Scheme)
+}
+
+// hostWithDefaultPort 返回URL的主机和端口，未指定端口时使用默认端口
+func hostWithDefaultPort(u *url.URL, defaultPort string) string {
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// escapeLDAPDNValue 按RFC 4514转义DN属性值中的特殊字符
+func escapeLDAPDNValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == ',' || c == '+' || c == '"' || c == '\\' || c == '<' || c == '>' || c == ';' || c == '=':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == 0:
+			b.WriteString("\\00")
+		case (c == ' ' || c == '#') && i == 0, c == ' ' && i == len(value)-1:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ldapBindRequest 编码LDAPv3简单绑定请求
+func ldapBindRequest(messageID int, dn, password string) []byte {
+	bind := berEncode(0x60, // [APPLICATION 0] BindRequest
+		berEncode(0x02, []byte{3}),        // version
+		berEncode(0x04, []byte(dn)),       // name
+		berEncode(0x80, []byte(password)), // simple [0]
+	)
+	return berEncode(0x30, berEncode(0x02, berInt(messageID)), bind)
+}
+
+// readLDAPBindResponse 读取绑定响应，返回结果码和诊断信息
+func readLDAPBindResponse(r io.Reader) (int, string, error) {
+	tag, message, err := berRead(r)
+	if err != nil {
+		return 0, "", err
+	}
+	if tag != 0x30 {
+		return 0, "", fmt.Errorf("Unexpected LDAP message tag 0x%02x", tag)
+	}
+
+	elements, err := berSplit(message)
+	if err != nil || len(elements) < 2 {
+		return 0, "", fmt.Errorf("Malformed LDAP message")
+	}
+	if elements[1].tag != 0x61 { // [APPLICATION 1] BindResponse
+		return 0, "", fmt.Errorf("Unexpected LDAP response tag 0x%02x", elements[1].tag)
+	}
+
+	fields, err := berSplit(elements[1].content)
+	if err != nil || len(fields) < 3 || fields[0].tag != 0x0a {
+		return 0, "", fmt.Errorf("Malformed LDAP bind response")
+	}
+	code := 0
+	for _, b := range fields[0].content {
+		code = code<<8 | int(b)
+	}
+	return code, string(fields[2].content), nil
+}
+
+// ldapSearchRequest 编码读取单个条目属性的搜索请求：以dn为基准、base范围、(objectClass=*)过滤
+func ldapSearchRequest(messageID int, dn, attr string) []byte {
+	search := berEncode(0x63, // [APPLICATION 3] SearchRequest
+		berEncode(0x04, []byte(dn)),                    // baseObject
+		berEncode(0x0a, []byte{0}),                     // scope: baseObject
+		berEncode(0x0a, []byte{0}),                     // derefAliases: never
+		berEncode(0x02, berInt(1)),                     // sizeLimit
+		berEncode(0x02, berInt(10)),                    // timeLimit（秒）
+		berEncode(0x01, []byte{0}),                     // typesOnly: false
+		berEncode(0x87, []byte("objectClass")),         // present [7]
+		berEncode(0x30, berEncode(0x04, []byte(attr))), // attributes
+	)
+	return berEncode(0x30, berEncode(0x02, berInt(messageID)), search)
+}
+
+// readLDAPSearchValues 读取搜索响应直到SearchResultDone，返回条目中attr属性的全部值
+func readLDAPSearchValues(r io.Reader, attr string) ([]string, error) {
+	var values []string
+	for {
+		tag, message, err := berRead(r)
+		if err != nil {
+			return nil, err
+		}
+		if tag != 0x30 {
+			return nil, fmt.Errorf("Unexpected LDAP message tag 0x%02x", tag)
+		}
+		elements, err := berSplit(message)
+		if err != nil || len(elements) < 2 {
+			return nil, fmt.Errorf("Malformed LDAP message")
+		}
+
+		switch elements[1].tag {
+		case 0x64: // [APPLICATION 4] SearchResultEntry
+			fields, err := berSplit(elements[1].content)
+			if err != nil || len(fields) < 2 {
+				return nil, fmt.Errorf("Malformed LDAP search entry")
+			}
+			attributes, err := berSplit(fields[1].content)
+			if err != nil {
+				return nil, fmt.Errorf("Malformed LDAP search entry")
+			}
+			for _, attribute := range attributes {
+				parts, err := berSplit(attribute.content)
+				if err != nil || len(parts) < 2 || !strings.EqualFold(string(parts[0].content), attr) {
+					continue
+				}
+				vals, err := berSplit(parts[1].content)
+				if err != nil {
+					return nil, fmt.Errorf("Malformed LDAP search entry")
+				}
+				for _, val := range vals {
+					values = append(values, string(val.content))
+				}
+			}
+		case 0x73: // [APPLICATION 19] SearchResultReference
+		case 0x65: // [APPLICATION 5] SearchResultDone
+			fields, err := berSplit(elements[1].content)
+			if err != nil || len(fields) < 3 || fields[0].tag != 0x0a {
+				return nil, fmt.Errorf("Malformed LDAP search result")
+			}
+			code := 0
+			for _, b := range fields[0].content {
+				code = code<<8 | int(b)
+			}
+			if code != ldapResultSuccess {
+				return nil, fmt.Errorf("LDAP search failed with result code %d: %s", code, string(fields[2].content))
+			}
+			return values, nil
+		default:
+			return nil, fmt.Errorf("Unexpected LDAP response tag 0x%02x", elements[1].tag)
+		}
+	}
+}
+
+// berElement BER编码的一个元素
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+// berEncode 编码BER元素（只支持单字节标签）
+func berEncode(tag byte, contents ...[]byte) []byte {
+	var body []byte
+	for _, c := range contents {
+		body = append(body, c...)
+	}
+	out := []byte{tag}
+	if n := len(body); n < 0x80 {
+		out = append(out, byte(n))
+	} else {
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(out, 0x80|byte(len(length)))
+		out = append(out, length...)
+	}
+	return append(out, body...)
+}
+
+// berInt 编码非负整数的内容字节
+func berInt(n int) []byte {
+	out := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		out = append([]byte{byte(n)}, out...)
+	}
+	if out[0]&0x80 != 0 {
+		out = append([]byte{0}, out...)
+	}
+	return out
+}
+
+// berRead 从流中读取一个BER元素
+func berRead(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		size := length & 0x7f
+		if size == 0 || size > 4 {
+			return 0, nil, fmt.Errorf("Unsupported BER length")
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, nil, err
+		}
+		length = 0
+		for _, b := range buf {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > ldapMaxMessageSize {
+		return 0, nil, fmt.Errorf("LDAP message too large")
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return 0, nil, err
+	}
+	return header[0], content, nil
+}
+
+// berSplit 拆分构造类型内容中的各个元素
+func berSplit(data []byte) ([]berElement, error) {
+	var elements []berElement
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		tag, content, err := berRead(r)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, berElement{tag: tag, content: content})
+	}
+	return elements, nil
+}
@@ -3,8 +3,10 @@ package services
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,11 +14,17 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"ctoz/backend/internal/config"
 	"ctoz/backend/internal/models"
 
 	"gopkg.in/yaml.v2"
@@ -24,24 +32,51 @@ import (
 
 // MigrationService 迁移服务
 type MigrationService struct {
-	connService *ConnectionService
-	taskService *TaskService
-	client      *http.Client
+	cfg                 *config.Config
+	connService         *ConnectionService
+	taskService         *TaskService
+	notificationService *NotificationService
+	client              *retryClient // 文件管理等普通接口调用
+	downloadClient      *retryClient
+	uploadClient        *retryClient
+	composeClient       *retryClient
+	// limiter 所有接口调用共用的按主机限流器
+	limiter *hostLimiter
+	// waitingTasks 等待目标恢复的任务ID到唤醒通道的映射
+	waitingTasks sync.Map
+	// appMetrics 上传应用数据时记录的指标，键为 taskID/appName
+	appMetrics sync.Map
 }
 
 // NewMigrationService 创建新的迁移服务
-func NewMigrationService(connService *ConnectionService, taskService *TaskService) *MigrationService {
-	return &MigrationService{
-		connService: connService,
-		taskService: taskService,
-		client: &http.Client{
-			Timeout: 300 * time.Second, // 5分钟超时
-		},
-	}
+func NewMigrationService(cfg *config.Config, connService *ConnectionService, taskService *TaskService, notificationService *NotificationService) *MigrationService {
+	s := &MigrationService{
+		cfg:                 cfg,
+		connService:         connService,
+		taskService:         taskService,
+		notificationService: notificationService,
+		limiter:             newHostLimiter(cfg.RateLimit),
+	}
+	s.client = s.newHTTPClient(cfg.Timeouts.FileOps, 0)
+	s.downloadClient = s.newHTTPClient(cfg.Timeouts.Download, cfg.Timeouts.ResponseHeader)
+	s.uploadClient = s.newHTTPClient(cfg.Timeouts.Upload, cfg.Timeouts.ResponseHeader)
+	s.composeClient = s.newHTTPClient(cfg.Timeouts.Compose, 0)
+	return s
+}
+
+// newHTTPClient 创建重试客户端，重试记录到相关任务的日志中，失败的请求记录到任务的诊断信息中，同一主机的请求共用限流
+// timeout限制每次请求的总时长，headerTimeout限制等待响应头的时间，用于不限总时长的下载和上传
+func (s *MigrationService) newHTTPClient(timeout, headerTimeout time.Duration) *retryClient {
+	client := newRetryClient(timeout, s.cfg.Retry)
+	client.client.Transport = newHTTPTransport(s.cfg.Timeouts.Dial, headerTimeout)
+	client.onRetry = s.logRetry
+	client.onFailure = s.recordFailedCall
+	client.limiter = s.limiter
+	return client
 }
 
 // StartOnlineMigration 开始在线迁移
-func (s *MigrationService) StartOnlineMigration(req *models.OnlineMigrationRequest) (*models.MigrationTask, error) {
+func (s *MigrationService) StartOnlineMigration(ctx context.Context, req *models.OnlineMigrationRequest) (*models.MigrationTask, error) {
 	// 验证连接配置
 	if err := s.connService.ValidateConnectionConfig(&req.Source); err != nil {
 		return nil, fmt.Errorf("Invalid source connection configuration: %v", err)
@@ -50,12 +85,34 @@ func (s *MigrationService) StartOnlineMigration(req *models.OnlineMigrationReque
 		return nil, fmt.Errorf("Invalid target connection configuration: %v", err)
 	}
 
+	options, err := applyPreset(s.taskService.store, req.Preset, req.MigrationOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAppRenameOptions(options); err != nil {
+		return nil, err
+	}
+	if err := validateAppDependencyOptions(options); err != nil {
+		return nil, err
+	}
+	if err := normalizeTaskLogLevel(&req.TaskMeta); err != nil {
+		return nil, err
+	}
+	if err := s.checkLargeApps(ctx, &req.Source, options); err != nil {
+		return nil, err
+	}
+	if err := s.checkArchitectures(ctx, &req.Source, &req.Target, options); err != nil {
+		return nil, err
+	}
+
 	// 创建迁移任务
 	task := s.taskService.CreateTask(
 		models.TaskTypeOnline,
+		req.Language,
+		req.TaskMeta,
 		&req.Source,
 		&req.Target,
-		req.MigrationOptions,
+		options,
 	)
 
 	// 异步执行迁移
@@ -66,6 +123,10 @@ func (s *MigrationService) StartOnlineMigration(req *models.OnlineMigrationReque
 
 // executeOnlineMigration 执行在线迁移
 func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
+	// 任务上下文在取消任务或关闭服务时取消，中止进行中的请求和传输
+	ctx, release := s.taskService.RunContext(task.ID)
+	defer release()
+
 	// 更新任务状态为运行中
 	s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusRunning))
 
@@ -73,10 +134,15 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 	var appStatuses []models.AppImportStatus
 	var hasCriticalError bool = false
 
+	// 恢复中断的任务时沿用上次已完成的应用步骤
+	previousApps := resumeAppStatuses(task)
+
 	defer func() {
 		if r := recover(); r != nil {
-			s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusFailed))
-			s.taskService.AddTaskLog(task.ID, models.LogLevelError, fmt.Sprintf("Migration panic: %v", r))
+			s.taskService.RecordPanic(task.ID, "Migration panic: %v", r, debug.Stack())
+		} else if ctx.Err() != nil {
+			// 任务被取消或服务关闭
+			s.taskService.FinishCancelled(task.ID)
 		} else if hasCriticalError {
 			// 只有在发生关键错误时才标记任务失败
 			s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusFailed))
@@ -89,11 +155,14 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 
 		// 保存应用导入状态到任务结果
 		s.saveAppImportStatuses(task.ID, appStatuses)
+
+		// 发送任务完成通知
+		s.notifyTaskFinished(task.ID, appStatuses)
 	}()
 
 	// 步骤1: 测试源系统连接（关键步骤，失败则终止）
-	err := s.taskService.ExecuteStep(task.ID, "Test source system connection", func() error {
-		testResp, err := s.connService.TestConnection(task.Source)
+	err := s.taskService.ExecuteStep(ctx, task.ID, "Test source system connection", func(ctx context.Context) error {
+		testResp, err := s.connService.TestConnection(ctx, task.Source)
 		if err != nil {
 			return fmt.Errorf("Failed to test source connection: %v", err)
 		}
@@ -109,8 +178,8 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 	}
 
 	// 步骤2: 测试目标系统连接（关键步骤，失败则终止）
-	err = s.taskService.ExecuteStep(task.ID, "Test target system connection", func() error {
-		testResp, err := s.connService.TestConnection(task.Target)
+	err = s.taskService.ExecuteStep(ctx, task.ID, "Test target system connection", func(ctx context.Context) error {
+		testResp, err := s.connService.TestConnection(ctx, task.Target)
 		if err != nil {
 			return fmt.Errorf("Failed to test target connection: %v", err)
 		}
@@ -125,32 +194,65 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 		return
 	}
 
+	// 根据目标类型选择迁移适配器
+	target, err := s.targetAdapter(ctx, task)
+	if err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelError, err.Error())
+		hasCriticalError = true
+		return
+	}
+	// 目标连续失败时暂停任务，等待目标恢复后继续
+	target = s.withCircuitBreaker(task, target)
+
+	// 根据源类型选择源适配器
+	source, err := s.sourceAdapter(task.Source, task.Options)
+	if err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelError, err.Error())
+		hasCriticalError = true
+		return
+	}
+
 	// 步骤3: 下载和处理源系统数据（关键步骤，失败则终止）
 	var sourceData map[string]interface{}
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Download and process source data", func(progressCallback func(int, string)) error {
-		progressCallback(5, "Start download")
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Download and process source data", func(ctx context.Context, progressCallback func(int, string)) error {
+		// 恢复任务时复用上次已解压的源数据
+		snapshot := checkpointSnapshot(task.Checkpoint)
+		if snapshot != nil {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Reusing source data from %s", snapshot.ExtractedPath))
+		} else {
+			progressCallback(5, "Start download")
 
-		// 下载CasaOS文件
-		downloadPath, err := s.downloadCasaOSFiles(task.Source, progressCallback)
-		if err != nil {
-			return fmt.Errorf("Failed to download files: %v", err)
+			// 获取源系统数据快照
+			snapshot, err = source.Fetch(ctx, task.ID, progressCallback)
+			if err != nil {
+				return err
+			}
+			s.taskService.SetCheckpoint(task.ID, &models.TaskCheckpoint{
+				DownloadPath:  snapshot.DownloadPath,
+				ExtractedPath: snapshot.ExtractedPath,
+				SkippedApps:   snapshot.SkippedApps,
+			})
 		}
+		downloadPath, extractedPath := snapshot.DownloadPath, snapshot.ExtractedPath
 
-		progressCallback(40, "Download succeeded")
-		progressCallback(45, "Extracting")
+		// 随AppData下载的镜像包单独存放，不上传到目标的AppData目录
+		if err := collectContainerImages(extractedPath); err != nil {
+			return err
+		}
 
-		// 解压下载的文件
-		extractedPath, err := s.extractDownloadedFiles(downloadPath, progressCallback)
-		if err != nil {
-			return fmt.Errorf("Failed to extract files: %v", err)
+		// 按迁移选项重命名应用，之后的步骤都使用新名称
+		if err := s.renameApps(task, extractedPath); err != nil {
+			return err
 		}
 
 		progressCallback(60, "Extraction succeeded")
 		progressCallback(65, "Fetching app list")
 
-		apps, err := s.getSystemApps(task.Source)
+		// 应用列表仅用于记录，非CasaOS源或API不可用时不影响迁移
+		apps, err := s.getSystemApps(ctx, task.Source)
 		if err != nil {
-			return fmt.Errorf("Failed to fetch app list: %v", err)
+			log.Printf("[WARNING] Failed to fetch app list: %v", err)
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to fetch app list: %v", err))
 		}
 
 		progressCallback(75, "Fetching system settings")
@@ -171,6 +273,7 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 			"userData":      userData,
 			"downloadPath":  downloadPath,
 			"extractedPath": extractedPath,
+			"skippedApps":   snapshot.SkippedApps,
 		}
 
 		progressCallback(95, "Data acquisition completed")
@@ -182,7 +285,7 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 	}
 
 	// 步骤4: 预扫描应用并初始化状态（非关键步骤，失败时记录日志但继续执行）
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Scan app configuration", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Scan app configuration", func(ctx context.Context, progressCallback func(int, string)) error {
 		// 获取解压路径
 		extractedPath, ok := sourceData["extractedPath"].(string)
 		if !ok {
@@ -212,8 +315,11 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 
 		progressCallback(60, "Initializing application status...")
 
+		// 按应用之间的依赖确定导入和启动顺序
+		appOrder := s.appImportOrder(task, composeFiles)
+
 		// 初始化每个应用的状态
-		for appName := range composeFiles {
+		for _, appName := range appOrder {
 			// 检查该应用是否有AppData
 			appDataDir := filepath.Join(appDataPath, appName)
 			hasAppData := false
@@ -236,11 +342,16 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 
 		// 保存compose文件到sourceData
 		sourceData["composeFiles"] = composeFiles
+		sourceData["appOrder"] = appOrder
 		sourceData["hasGlobalAppData"] = hasGlobalAppData
 
 		progressCallback(100, fmt.Sprintf("Found %d apps", len(composeFiles)))
 		return nil
 	})
+	if ctx.Err() != nil {
+		// 任务已取消，不再执行后续步骤
+		return
+	}
 	if err != nil {
 		// 非关键步骤失败，记录错误日志但继续执行
 		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to scan app configuration: %v, continuing with next steps", err))
@@ -251,8 +362,28 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 		sourceData["hasGlobalAppData"] = false
 	}
 
+	// 源系统中无法迁移的应用记录为跳过
+	if skippedApps, ok := sourceData["skippedApps"].(map[string]string); ok {
+		for appName, reason := range skippedApps {
+			appStatuses = append(appStatuses, models.AppImportStatus{
+				AppName:       appName,
+				AppDataStatus: models.AppStatusSkipped,
+				ComposeStatus: models.AppStatusSkipped,
+				OverallStatus: models.AppStatusSkipped,
+				ErrorMessage:  reason,
+			})
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("App %s skipped: %s", appName, reason))
+		}
+	}
+
+	s.restoreAppStatuses(appStatuses, previousApps)
+
+	// 记录bind挂载供路径改写预览，并生成高权限应用报告，未确认的应用暂不迁移
+	recordBindMounts(sourceData, appStatuses)
+	s.reportPrivilegedApps(task, sourceData, appStatuses)
+
 	// 步骤5: 合并AppData目录（非关键步骤，失败时记录日志但继续执行）
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Merge AppData directory", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Merge AppData directory", func(ctx context.Context, progressCallback func(int, string)) error {
 		// 获取解压路径
 		extractedPath, ok := sourceData["extractedPath"].(string)
 		if !ok {
@@ -280,7 +411,10 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 
 		completedApps := 0
 		for i := range appStatuses {
-			if !appStatuses[i].HasAppData {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !appStatuses[i].HasAppData || appStatuses[i].OverallStatus == models.AppStatusWarning {
 				continue
 			}
 
@@ -288,19 +422,27 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 			progress := 20 + (60 * completedApps / totalAppsWithData)
 			progressCallback(progress, fmt.Sprintf("Merging %s AppData (%d/%d)...", appStatuses[i].AppName, completedApps, totalAppsWithData))
 
+			if appStatuses[i].AppDataStatus == models.AppStatusSuccess {
+				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s AppData already migrated, skipping", appStatuses[i].AppName))
+				continue
+			}
+
 			// 合并单个应用的AppData
 			appDataDir := filepath.Join(appDataPath, appStatuses[i].AppName)
-			err := s.uploadAppDataToZimaOS(task.Target, appStatuses[i].AppName, appDataDir, task.ID)
+			err := target.UploadAppData(ctx, appStatuses[i].AppName, appDataDir, task.ID)
+			s.collectAppMetrics(task.ID, &appStatuses[i])
 
 			if err != nil {
 				log.Printf("[ERROR] App %s AppData merge failed: %v", appStatuses[i].AppName, err)
 				appStatuses[i].AppDataStatus = models.AppStatusFailed
 				appStatuses[i].ErrorMessage = fmt.Sprintf("AppData merge failed: %v", err)
+				appStatuses[i].ErrorCode = appErrorCode(err)
 				s.taskService.AddTaskLog(task.ID, models.LogLevelError, fmt.Sprintf("App %s AppData merge failed: %v", appStatuses[i].AppName, err))
 			} else {
 				log.Printf("[INFO] App %s AppData merge succeeded", appStatuses[i].AppName)
 				appStatuses[i].AppDataStatus = models.AppStatusSuccess
 				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s AppData merge succeeded ✓", appStatuses[i].AppName))
+				s.taskService.CheckpointApp(task.ID, appStatuses[i].AppName, appStepAppData)
 			}
 
 			// 实时保存应用状态到任务结果
@@ -310,14 +452,23 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 		progressCallback(100, "AppData directory merge completed")
 		return nil
 	})
+	if ctx.Err() != nil {
+		// 任务已取消，不再执行后续步骤
+		return
+	}
 	if err != nil {
 		// 非关键步骤失败，记录错误日志但继续执行
 		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to merge AppData directory: %v, continuing with next steps", err))
 		log.Printf("[WARNING] Failed to merge AppData directory: %v, continuing with next steps", err)
 	}
 
+	// 可选步骤: 在导入compose前加载迁移的容器镜像，目标无法访问镜像仓库时也能启动应用
+	if extractedPath, ok := sourceData["extractedPath"].(string); ok {
+		s.loadContainerImages(ctx, task, target, appStatuses, extractedPath)
+	}
+
 	// 步骤6: 导入compose文件（非关键步骤，失败时记录日志但继续执行）
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Import application configuration", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Import application configuration", func(ctx context.Context, progressCallback func(int, string)) error {
 		composeFiles, ok := sourceData["composeFiles"].(map[string]string)
 		if !ok {
 			return fmt.Errorf("Compose file data not found")
@@ -335,13 +486,39 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 		totalCompose := len(composeFiles)
 		completedCompose := 0
 
-		for appName, composeContent := range composeFiles {
+		for _, appName := range orderedComposeApps(sourceData, composeFiles) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			composeContent := composeFiles[appName]
 			completedCompose++
 			progress := 20 + (70 * completedCompose / totalCompose)
 			progressCallback(progress, fmt.Sprintf("Import %s compose configuration (%d/%d)...", appName, completedCompose, totalCompose))
 
+			if composeImported(appStatuses, appName) {
+				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s compose already imported, skipping", appName))
+				continue
+			}
+			if appHeld(appStatuses, appName) {
+				continue
+			}
+
+			// 目标缺少应用需要的设备时不导入，应用标记为警告
+			if s.checkAppDevices(ctx, task, target, appName, composeContent, appStatuses) {
+				continue
+			}
+
+			// 上传应用目录中的配置文件，并按迁移选项处理.env
+			composeContent = s.prepareAppFiles(ctx, task, target, appName, composeContent, sourceData)
+			composeContent = s.applyPathRules(task.ID, appName, composeContent)
+			composeContent = s.applyImageRules(task.ID, appName, composeContent)
+
 			// 导入单个应用的compose
-			err := s.importComposeToZimaOS(task.Target, appName, composeContent, task.ID)
+			importStart := time.Now()
+			hb := s.startHeartbeat(task.ID, appName, heartbeatPhaseCompose, "waiting for the target")
+			err := target.ImportCompose(ctx, appName, composeContent, task.ID)
+			hb.stop()
+			recordComposeImportTime(appStatuses, appName, importStart)
 
 			if err != nil {
 				log.Printf("[ERROR] App %s compose import failed: %v", appName, err)
@@ -368,6 +545,7 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 					}
 				}
 				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s compose import succeeded ✓", appName))
+				s.taskService.CheckpointApp(task.ID, appName, appStepCompose)
 			}
 
 			// 计算整体状态
@@ -386,23 +564,32 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 		log.Printf("[INFO] All application compose imports completed")
 		return nil
 	})
+	if ctx.Err() != nil {
+		// 任务已取消，不再执行后续步骤
+		return
+	}
 	if err != nil {
 		// 非关键步骤失败，记录错误日志但继续执行
 		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to import application configuration: %v, continuing with next steps", err))
 		log.Printf("[WARNING] Failed to import application configuration: %v, continuing with next steps", err)
 	}
 
+	// 可选步骤: 启动导入的应用并记录运行状态
+	s.startImportedApps(ctx, task, target, appStatuses)
+
+	// 可选步骤: 在目标系统上恢复随AppData迁移的数据库转储
+	if extractedPath, ok := sourceData["extractedPath"].(string); ok {
+		s.restoreDatabaseDumps(ctx, task, target, appStatuses, filepath.Join(extractedPath, "DATA/AppData"))
+	}
+
 	// 步骤6: 清理本地临时文件
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Cleanup local temporary files", func(progressCallback func(int, string)) error {
+	var sourceArchive string
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Cleanup local temporary files", func(ctx context.Context, progressCallback func(int, string)) error {
 		progressCallback(50, "Cleaning up local temporary files...")
 
-		// 清理本地下载和解压的文件
-		if downloadPath, ok := sourceData["downloadPath"].(string); ok {
-			if err := os.Remove(downloadPath); err != nil {
-				log.Printf("[WARNING] Failed to remove downloaded file: %v", err)
-			} else {
-				log.Printf("[DEBUG] Downloaded file removed: %s", downloadPath)
-			}
+		// 清理本地下载和解压的文件，开启keep_source_archive时保留下载的备份
+		if downloadPath, ok := sourceData["downloadPath"].(string); ok && downloadPath != "" {
+			sourceArchive = s.cleanupDownload(task, downloadPath)
 		}
 
 		if extractedPath, ok := sourceData["extractedPath"].(string); ok {
@@ -414,6 +601,7 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 		}
 
 		progressCallback(100, "Cleanup completed")
+		s.taskService.SetCheckpoint(task.ID, nil)
 		return nil
 	})
 	if err != nil {
@@ -426,12 +614,16 @@ func (s *MigrationService) executeOnlineMigration(task *models.MigrationTask) {
 	summary := s.calculateImportSummary(appStatuses)
 
 	// 设置任务结果
-	s.taskService.SetTaskResult(task.ID, map[string]interface{}{
+	result := map[string]interface{}{
 		"apps":            appStatuses,
 		"summary":         summary,
 		"completion_time": time.Now(),
 		"status":          fmt.Sprintf("Import completed: %d succeeded, %d failed, total %d apps", summary.SuccessApps, summary.FailedApps, summary.TotalApps),
-	})
+	}
+	if sourceArchive != "" {
+		result["source_archive"] = sourceArchive
+	}
+	s.taskService.SetTaskResult(task.ID, result)
 
 	// 更新任务进度为100%
 	s.taskService.UpdateTaskProgress(task.ID, 100)
@@ -447,9 +639,28 @@ func (s *MigrationService) StartDataExport(req *models.DataExportRequest) (*mode
 		return nil, fmt.Errorf("Invalid target connection configuration: %v", err)
 	}
 
+	// 验证导出内容的过滤模式
+	if _, err := parseExportFilter(req.ExportOptions); err != nil {
+		return nil, err
+	}
+	if err := normalizeTaskLogLevel(&req.TaskMeta); err != nil {
+		return nil, err
+	}
+
+	// 验证导出文件推送目标
+	if dest, err := parseExportDestination(req.ExportOptions); err != nil {
+		return nil, err
+	} else if dest != nil {
+		if _, err := newExportSink(dest); err != nil {
+			return nil, fmt.Errorf("Invalid export destination: %v", err)
+		}
+	}
+
 	// 创建导出任务
 	task := s.taskService.CreateTask(
 		models.TaskTypeExport,
+		req.Language,
+		req.TaskMeta,
 		&req.Source,
 		nil,
 		req.ExportOptions,
@@ -463,14 +674,20 @@ func (s *MigrationService) StartDataExport(req *models.DataExportRequest) (*mode
 
 // executeDataExport 执行数据导出
 func (s *MigrationService) executeDataExport(task *models.MigrationTask) {
+	// 任务上下文在取消任务或关闭服务时取消，中止进行中的请求和传输
+	ctx, release := s.taskService.RunContext(task.ID)
+	defer release()
+
 	// 更新任务状态为运行中
 	s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusRunning))
 	var hasCriticalError bool = false
 
 	defer func() {
 		if r := recover(); r != nil {
-			s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusFailed))
-			s.taskService.AddTaskLog(task.ID, models.LogLevelError, fmt.Sprintf("Panic occurred during export: %v", r))
+			s.taskService.RecordPanic(task.ID, "Panic occurred during export: %v", r, debug.Stack())
+		} else if ctx.Err() != nil {
+			// 任务被取消或服务关闭
+			s.taskService.FinishCancelled(task.ID)
 		} else if hasCriticalError {
 			// 只有在发生关键错误时才标记任务失败
 			s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusFailed))
@@ -483,8 +700,8 @@ func (s *MigrationService) executeDataExport(task *models.MigrationTask) {
 	}()
 
 	// 步骤1: 测试源系统连接（关键步骤，失败则终止）
-	err := s.taskService.ExecuteStep(task.ID, "Test source system connection", func() error {
-		testResp, err := s.connService.TestConnection(task.Source)
+	err := s.taskService.ExecuteStep(ctx, task.ID, "Test source system connection", func(ctx context.Context) error {
+		testResp, err := s.connService.TestConnection(ctx, task.Source)
 		if err != nil {
 			return fmt.Errorf("Failed to test source connection: %v", err)
 		}
@@ -502,13 +719,13 @@ func (s *MigrationService) executeDataExport(task *models.MigrationTask) {
 	// 步骤2: 导出数据（关键步骤，失败则终止）
 	var exportData map[string]interface{}
 	var exportPath string
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Export system data", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Export system data", func(ctx context.Context, progressCallback func(int, string)) error {
 		options := task.Options
 		exportData = make(map[string]interface{})
 
 		if exportApps, ok := options["export_apps"].(bool); ok && exportApps {
 			progressCallback(20, "Export application data")
-			apps, err := s.getSystemApps(task.Source)
+			apps, err := s.getSystemApps(ctx, task.Source)
 			if err != nil {
 				return fmt.Errorf("Failed to export application data: %v", err)
 			}
@@ -534,7 +751,14 @@ func (s *MigrationService) executeDataExport(task *models.MigrationTask) {
 		}
 
 		progressCallback(90, "Generate export file")
-		filePath, err := s.createExportFile(task.ID, exportData)
+		var filePath string
+		var err error
+		if jobID, _ := options[backupJobOption].(string); jobID != "" {
+			// 定时备份导出包含应用数据的完整压缩包
+			filePath, err = s.createBackupArchive(ctx, task, jobID, exportData, progressCallback)
+		} else {
+			filePath, err = s.createExportFile(task.ID, exportData)
+		}
 		if err != nil {
 			return fmt.Errorf("Failed to generate export file: %v", err)
 		}
@@ -547,13 +771,52 @@ func (s *MigrationService) executeDataExport(task *models.MigrationTask) {
 		hasCriticalError = true
 		return
 	}
+	exportSize := s.getFileSize(exportPath)
 
-	// 设置任务结果
-	s.taskService.SetTaskResult(task.ID, map[string]interface{}{
+	// 步骤3: 推送导出文件到外部存储（设置了推送目标时，关键步骤）
+	var exportLocation string
+	dest, _ := parseExportDestination(task.Options)
+	if dest != nil {
+		err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Upload export file", func(ctx context.Context, progressCallback func(int, string)) error {
+			sink, err := newExportSink(dest)
+			if err != nil {
+				return err
+			}
+
+			progressCallback(10, fmt.Sprintf("Uploading export file to %s", sink.Name()))
+			location, err := sink.Upload(ctx, exportPath)
+			if err != nil {
+				return fmt.Errorf("Failed to upload export file: %v", err)
+			}
+			exportLocation = location
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Export file uploaded to %s", location))
+
+			if !dest.KeepLocal {
+				if err := os.Remove(exportPath); err != nil {
+					log.Printf("[WARNING] Failed to remove local export file: %v", err)
+				} else {
+					exportPath = ""
+				}
+			}
+			progressCallback(100, "Export file uploaded")
+			return nil
+		})
+		if err != nil {
+			hasCriticalError = true
+			return
+		}
+	}
+
+	result := map[string]interface{}{
 		"export_file":     exportPath,
-		"export_size":     s.getFileSize(exportPath),
+		"export_size":     exportSize,
 		"completion_time": time.Now(),
-		"download_instructions": &models.DownloadInstructions{
+	}
+	if exportLocation != "" {
+		result["export_location"] = exportLocation
+	}
+	if exportPath != "" {
+		result["download_instructions"] = &models.DownloadInstructions{
 			Message:     "Data export completed. Please download the export file from CasaOS manually.",
 			FilePath:    exportPath,
 			DownloadURL: fmt.Sprintf("/downloads/%s", filepath.Base(exportPath)),
@@ -564,8 +827,11 @@ func (s *MigrationService) executeDataExport(task *models.MigrationTask) {
 				"4. Download the file to your local machine",
 				"5. Use this file to import on the target system",
 			},
-		},
-	})
+		}
+	}
+
+	// 设置任务结果
+	s.taskService.SetTaskResult(task.ID, result)
 
 	// 更新任务进度为100%
 	s.taskService.UpdateTaskProgress(task.ID, 100)
@@ -581,12 +847,41 @@ func (s *MigrationService) StartDataImport(req *models.DataImportRequest) (*mode
 		return nil, fmt.Errorf("Invalid target connection configuration: %v", err)
 	}
 
+	options, err := applyPreset(s.taskService.store, req.Preset, req.ImportOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAppRenameOptions(options); err != nil {
+		return nil, err
+	}
+	if err := validateAppDependencyOptions(options); err != nil {
+		return nil, err
+	}
+	if err := normalizeTaskLogLevel(&req.TaskMeta); err != nil {
+		return nil, err
+	}
+
+	// 从S3导入时在任务中下载导入文件
+	if req.S3 != nil {
+		if err := validateS3Source(req.S3); err != nil {
+			return nil, fmt.Errorf("Invalid S3 import source: %v", err)
+		}
+		withS3 := make(map[string]interface{}, len(options)+1)
+		for k, v := range options {
+			withS3[k] = v
+		}
+		withS3[importS3Option] = req.S3
+		options = withS3
+	}
+
 	// 创建导入任务
 	task := s.taskService.CreateTask(
 		models.TaskTypeImport,
+		req.Language,
+		req.TaskMeta,
 		nil,
 		&req.Target,
-		req.ImportOptions,
+		options,
 	)
 
 	// 异步执行导入
@@ -597,6 +892,10 @@ func (s *MigrationService) StartDataImport(req *models.DataImportRequest) (*mode
 
 // executeDataImport 执行数据导入
 func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
+	// 任务上下文在取消任务或关闭服务时取消，中止进行中的请求和传输
+	ctx, release := s.taskService.RunContext(task.ID)
+	defer release()
+
 	// 更新任务状态为运行中
 	s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusRunning))
 
@@ -604,10 +903,15 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 	var appStatuses []models.AppImportStatus
 	var hasCriticalError bool = false
 
+	// 恢复中断的任务时沿用上次已完成的应用步骤
+	previousApps := resumeAppStatuses(task)
+
 	defer func() {
 		if r := recover(); r != nil {
-			s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusFailed))
-			s.taskService.AddTaskLog(task.ID, models.LogLevelError, fmt.Sprintf("Panic occurred during import: %v", r))
+			s.taskService.RecordPanic(task.ID, "Panic occurred during import: %v", r, debug.Stack())
+		} else if ctx.Err() != nil {
+			// 任务被取消或服务关闭
+			s.taskService.FinishCancelled(task.ID)
 		} else if hasCriticalError {
 			// 只有在发生关键错误时才标记任务失败
 			s.taskService.UpdateTaskStatus(task.ID, string(models.TaskStatusFailed))
@@ -620,11 +924,14 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 
 		// 保存应用导入状态到任务结果
 		s.saveAppImportStatuses(task.ID, appStatuses)
+
+		// 发送任务完成通知
+		s.notifyTaskFinished(task.ID, appStatuses)
 	}()
 
 	// 步骤1: 测试目标系统连接（关键步骤，失败则终止）
-	err := s.taskService.ExecuteStep(task.ID, "Test target system connection", func() error {
-		testResp, err := s.connService.TestConnection(task.Target)
+	err := s.taskService.ExecuteStep(ctx, task.ID, "Test target system connection", func(ctx context.Context) error {
+		testResp, err := s.connService.TestConnection(ctx, task.Target)
 		if err != nil {
 			return fmt.Errorf("Failed to test target connection: %v", err)
 		}
@@ -639,10 +946,36 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 		return
 	}
 
+	// 根据目标类型选择迁移适配器
+	target, err := s.targetAdapter(ctx, task)
+	if err != nil {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelError, err.Error())
+		hasCriticalError = true
+		return
+	}
+	// 目标连续失败时暂停任务，等待目标恢复后继续
+	target = s.withCircuitBreaker(task, target)
+
+	// 从S3导入时先下载导入文件（关键步骤），恢复任务时已下载的文件直接复用
+	if src, _ := parseS3Source(task.Options); src != nil && !importFileExists(task) {
+		err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Download import file", func(ctx context.Context, progressCallback func(int, string)) error {
+			importFile, err := s.downloadImportFromS3(ctx, task.ID, src, progressCallback)
+			if err != nil {
+				return fmt.Errorf("Failed to download import file: %v", err)
+			}
+			progressCallback(100, "Download completed")
+			return s.taskService.SetTaskOption(task.ID, "import_file", importFile)
+		})
+		if err != nil {
+			hasCriticalError = true
+			return
+		}
+	}
+
 	// 步骤2: 解析导入文件（关键步骤，失败则终止）
 	var sourceData map[string]interface{}
 	var extractedPath string
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Parse import file", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Parse import file", func(ctx context.Context, progressCallback func(int, string)) error {
 		// 安全获取import_file字段
 		importFileValue, exists := task.Options["import_file"]
 		if !exists {
@@ -659,7 +992,7 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 
 		// 解压导入文件
 		progressCallback(30, "Extract import file...")
-		extractDir := filepath.Join("uploads", "extracted_import")
+		extractDir := importExtractDir(s.cfg.Dirs)
 
 		// 清理之前的解压目录（如果存在）
 		if err := os.RemoveAll(extractDir); err != nil {
@@ -687,21 +1020,59 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 		log.Printf("[INFO] Detected file format: %s", actualFormat)
 
 		switch actualFormat {
-		case "gzip":
-			// 使用tar.gz解压函数
-			if err := s.extractTarGz(importFile, extractDir); err != nil {
-				return fmt.Errorf("Failed to extract tar.gz file: %v", err)
+		case "gzip", "xz", "tar":
+			// 使用tar解压函数
+			if err := s.extractTarGz(ctx, importFile, extractDir); err != nil {
+				return fmt.Errorf("Failed to extract %s file: %v", tarFormatName(actualFormat), err)
 			}
 		case "zip":
 			// 使用ZIP解压函数
 			if err := s.extractZipFile(importFile, extractDir); err != nil {
 				return fmt.Errorf("Failed to extract ZIP file: %v", err)
 			}
+		case "7z":
+			if err := s.extract7zFile(ctx, importFile, extractDir); err != nil {
+				return fmt.Errorf("Failed to extract 7z file: %v", err)
+			}
 		default:
-			return fmt.Errorf("Unsupported file format: %s, only ZIP and GZIP are supported", actualFormat)
+			return fmt.Errorf("Unsupported file format: %s, only ZIP, 7z, tar.gz, tar.xz and tar are supported", actualFormat)
 		}
 		extractedPath = extractDir
 
+		// 演示模式生成的模拟数据不是真实系统的备份
+		if isDemoImport(extractDir) {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, "Import file contains demo data generated without a source connection, not a real export")
+		}
+
+		// 增量导出需要与完整导出及之前的增量叠加
+		manifest, err := readExportManifest(importFile)
+		if err != nil {
+			return err
+		}
+		if manifest != nil && manifest.Type == exportTypeIncremental {
+			if err := s.mergeIncrementalExport(task.ID, importFile, extractDir, manifest, progressCallback); err != nil {
+				return fmt.Errorf("Failed to merge incremental export: %v", err)
+			}
+		}
+
+		// Synology Container Manager项目导出需要先转换为CasaOS结构
+		if isSynologyProjectExport(extractDir) {
+			progressCallback(50, "Converting Synology Container Manager projects...")
+			if err := s.convertSynologyProjects(task.ID, extractDir); err != nil {
+				return fmt.Errorf("Failed to convert Synology projects: %v", err)
+			}
+		}
+
+		// 旧版备份中随AppData导出的镜像包与images目录中的一样单独存放
+		if err := collectContainerImages(extractDir); err != nil {
+			return err
+		}
+
+		// 按导入选项重命名应用，之后的步骤都使用新名称
+		if err := s.renameApps(task, extractDir); err != nil {
+			return err
+		}
+
 		progressCallback(60, "Parsing CasaOS structure...")
 		// 解析CasaOS导出结构，而不是查找migration_data.json
 		sourceData = map[string]interface{}{
@@ -717,7 +1088,7 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 	}
 
 	// 步骤3: 扫描应用配置并初始化状态（非关键步骤，失败时记录日志但继续执行）
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Scan app configuration", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Scan app configuration", func(ctx context.Context, progressCallback func(int, string)) error {
 		// 获取解压路径
 		extractedPath, ok := sourceData["extractedPath"].(string)
 		if !ok {
@@ -747,8 +1118,11 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 
 		progressCallback(60, "Initializing application status...")
 
+		// 按应用之间的依赖确定导入和启动顺序
+		appOrder := s.appImportOrder(task, composeFiles)
+
 		// 初始化每个应用的状态
-		for appName := range composeFiles {
+		for _, appName := range appOrder {
 			// 检查该应用是否有AppData
 			appDataDir := filepath.Join(appDataPath, appName)
 			hasAppData := false
@@ -771,11 +1145,16 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 
 		// 保存compose文件到sourceData
 		sourceData["composeFiles"] = composeFiles
+		sourceData["appOrder"] = appOrder
 		sourceData["hasGlobalAppData"] = hasGlobalAppData
 
 		progressCallback(100, fmt.Sprintf("Found %d apps", len(composeFiles)))
 		return nil
 	})
+	if ctx.Err() != nil {
+		// 任务已取消，不再执行后续步骤
+		return
+	}
 	if err != nil {
 		// 非关键步骤失败，记录错误日志但继续执行
 		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to scan app configuration: %v, continuing with next steps", err))
@@ -786,8 +1165,14 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 		sourceData["hasGlobalAppData"] = false
 	}
 
+	s.restoreAppStatuses(appStatuses, previousApps)
+
+	// 记录bind挂载供路径改写预览，并生成高权限应用报告，未确认的应用暂不迁移
+	recordBindMounts(sourceData, appStatuses)
+	s.reportPrivilegedApps(task, sourceData, appStatuses)
+
 	// 步骤4: 合并AppData目录（非关键步骤，失败时记录日志但继续执行）
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Merge AppData directory", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Merge AppData directory", func(ctx context.Context, progressCallback func(int, string)) error {
 		// 获取解压路径
 		extractedPath, ok := sourceData["extractedPath"].(string)
 		if !ok {
@@ -815,7 +1200,10 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 
 		completedApps := 0
 		for i := range appStatuses {
-			if !appStatuses[i].HasAppData {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !appStatuses[i].HasAppData || appStatuses[i].OverallStatus == models.AppStatusWarning {
 				continue
 			}
 
@@ -823,19 +1211,27 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 			progress := 20 + (60 * completedApps / totalAppsWithData)
 			progressCallback(progress, fmt.Sprintf("Merging %s AppData (%d/%d)...", appStatuses[i].AppName, completedApps, totalAppsWithData))
 
+			if appStatuses[i].AppDataStatus == models.AppStatusSuccess {
+				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s AppData already migrated, skipping", appStatuses[i].AppName))
+				continue
+			}
+
 			// 合并单个应用的AppData
 			appDataDir := filepath.Join(appDataPath, appStatuses[i].AppName)
-			err := s.uploadAppDataToZimaOS(task.Target, appStatuses[i].AppName, appDataDir, task.ID)
+			err := target.UploadAppData(ctx, appStatuses[i].AppName, appDataDir, task.ID)
+			s.collectAppMetrics(task.ID, &appStatuses[i])
 
 			if err != nil {
 				log.Printf("[ERROR] App %s AppData merge failed: %v", appStatuses[i].AppName, err)
 				appStatuses[i].AppDataStatus = models.AppStatusFailed
 				appStatuses[i].ErrorMessage = fmt.Sprintf("AppData merge failed: %v", err)
+				appStatuses[i].ErrorCode = appErrorCode(err)
 				s.taskService.AddTaskLog(task.ID, models.LogLevelError, fmt.Sprintf("App %s AppData merge failed: %v", appStatuses[i].AppName, err))
 			} else {
 				log.Printf("[INFO] App %s AppData merge succeeded", appStatuses[i].AppName)
 				appStatuses[i].AppDataStatus = models.AppStatusSuccess
 				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s AppData merge succeeded ✓", appStatuses[i].AppName))
+				s.taskService.CheckpointApp(task.ID, appStatuses[i].AppName, appStepAppData)
 			}
 
 			// 实时保存应用状态到任务结果
@@ -845,14 +1241,23 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 		progressCallback(100, "AppData directory merge completed")
 		return nil
 	})
+	if ctx.Err() != nil {
+		// 任务已取消，不再执行后续步骤
+		return
+	}
 	if err != nil {
 		// 非关键步骤失败，记录错误日志但继续执行
 		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to merge AppData directory: %v, continuing with next steps", err))
 		log.Printf("[WARNING] Failed to merge AppData directory: %v, continuing with next steps", err)
 	}
 
+	// 可选步骤: 在导入compose前加载迁移的容器镜像，目标无法访问镜像仓库时也能启动应用
+	if extractedPath, ok := sourceData["extractedPath"].(string); ok {
+		s.loadContainerImages(ctx, task, target, appStatuses, extractedPath)
+	}
+
 	// 步骤5: 导入应用配置(Compose)（非关键步骤，失败时记录日志但继续执行）
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Import application configuration", func(progressCallback func(int, string)) error {
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Import application configuration", func(ctx context.Context, progressCallback func(int, string)) error {
 		composeFiles, ok := sourceData["composeFiles"].(map[string]string)
 		if !ok {
 			return fmt.Errorf("Compose file data not found")
@@ -870,13 +1275,39 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 		totalCompose := len(composeFiles)
 		completedCompose := 0
 
-		for appName, composeContent := range composeFiles {
+		for _, appName := range orderedComposeApps(sourceData, composeFiles) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			composeContent := composeFiles[appName]
 			completedCompose++
 			progress := 20 + (70 * completedCompose / totalCompose)
 			progressCallback(progress, fmt.Sprintf("Import %s compose configuration (%d/%d)...", appName, completedCompose, totalCompose))
 
+			if composeImported(appStatuses, appName) {
+				s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s compose already imported, skipping", appName))
+				continue
+			}
+			if appHeld(appStatuses, appName) {
+				continue
+			}
+
+			// 目标缺少应用需要的设备时不导入，应用标记为警告
+			if s.checkAppDevices(ctx, task, target, appName, composeContent, appStatuses) {
+				continue
+			}
+
+			// 上传应用目录中的配置文件，并按迁移选项处理.env
+			composeContent = s.prepareAppFiles(ctx, task, target, appName, composeContent, sourceData)
+			composeContent = s.applyPathRules(task.ID, appName, composeContent)
+			composeContent = s.applyImageRules(task.ID, appName, composeContent)
+
 			// 导入单个应用的compose
-			err := s.importComposeToZimaOS(task.Target, appName, composeContent, task.ID)
+			importStart := time.Now()
+			hb := s.startHeartbeat(task.ID, appName, heartbeatPhaseCompose, "waiting for the target")
+			err := target.ImportCompose(ctx, appName, composeContent, task.ID)
+			hb.stop()
+			recordComposeImportTime(appStatuses, appName, importStart)
 
 			// 找到对应的appStatus并更新
 			for i := range appStatuses {
@@ -893,6 +1324,7 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 						log.Printf("[INFO] App %s compose import succeeded", appName)
 						appStatuses[i].ComposeStatus = models.AppStatusSuccess
 						s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("App %s compose import succeeded ✓", appName))
+						s.taskService.CheckpointApp(task.ID, appName, appStepCompose)
 					}
 
 					// 计算整体状态
@@ -909,23 +1341,32 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 		log.Printf("[INFO] All application compose imports completed")
 		return nil
 	})
+	if ctx.Err() != nil {
+		// 任务已取消，不再执行后续步骤
+		return
+	}
 	if err != nil {
 		// 非关键步骤失败，记录错误日志但继续执行
 		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to import application configuration: %v, continuing with next steps", err))
 		log.Printf("[WARNING] Failed to import application configuration: %v, continuing with next steps", err)
 	}
 
+	// 可选步骤: 启动导入的应用并记录运行状态
+	s.startImportedApps(ctx, task, target, appStatuses)
+
+	// 可选步骤: 在目标系统上恢复随AppData迁移的数据库转储
+	if extractedPath, ok := sourceData["extractedPath"].(string); ok {
+		s.restoreDatabaseDumps(ctx, task, target, appStatuses, filepath.Join(extractedPath, "DATA/AppData"))
+	}
+
 	// 步骤6: 清理本地临时文件
-	err = s.taskService.ExecuteStepWithProgress(task.ID, "Cleanup local temporary files", func(progressCallback func(int, string)) error {
+	var sourceArchive string
+	err = s.taskService.ExecuteStepWithProgress(ctx, task.ID, "Cleanup local temporary files", func(ctx context.Context, progressCallback func(int, string)) error {
 		progressCallback(50, "Cleaning up local temporary files...")
 
-		// 清理本地下载和解压的文件
-		if downloadPath, ok := sourceData["downloadPath"].(string); ok {
-			if err := os.Remove(downloadPath); err != nil {
-				log.Printf("[WARNING] Failed to remove downloaded file: %v", err)
-			} else {
-				log.Printf("[DEBUG] Downloaded file removed: %s", downloadPath)
-			}
+		// 清理本地下载和解压的文件，开启keep_source_archive时保留下载的备份
+		if downloadPath, ok := sourceData["downloadPath"].(string); ok && downloadPath != "" {
+			sourceArchive = s.cleanupDownload(task, downloadPath)
 		}
 
 		if extractedPath, ok := sourceData["extractedPath"].(string); ok {
@@ -949,12 +1390,16 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 	summary := s.calculateImportSummary(appStatuses)
 
 	// 设置任务结果
-	s.taskService.SetTaskResult(task.ID, map[string]interface{}{
+	result := map[string]interface{}{
 		"apps":            appStatuses,
 		"summary":         summary,
 		"completion_time": time.Now(),
 		"status":          fmt.Sprintf("Import completed: %d succeeded, %d failed, total %d apps", summary.SuccessApps, summary.FailedApps, summary.TotalApps),
-	})
+	}
+	if sourceArchive != "" {
+		result["source_archive"] = sourceArchive
+	}
+	s.taskService.SetTaskResult(task.ID, result)
 
 	// 更新任务进度为100%
 	s.taskService.UpdateTaskProgress(task.ID, 100)
@@ -965,21 +1410,106 @@ func (s *MigrationService) executeDataImport(task *models.MigrationTask) {
 
 // 辅助方法
 
-// getSystemApps 获取系统应用列表
-func (s *MigrationService) getSystemApps(conn *models.SystemConnection) ([]interface{}, error) {
-	// 模拟获取应用列表
-	return []interface{}{
-		map[string]interface{}{
-			"name":    "Nextcloud",
-			"version": "25.0.0",
-			"port":    "8080",
-		},
-		map[string]interface{}{
-			"name":    "Jellyfin",
-			"version": "10.8.0",
-			"port":    "8096",
-		},
-	}, nil
+// getSystemApps 通过CasaOS应用管理API获取已安装的应用列表
+func (s *MigrationService) getSystemApps(ctx context.Context, conn *models.SystemConnection) ([]models.SourceApp, error) {
+	var grid []struct {
+		Name    string            `json:"name"`
+		Title   map[string]string `json:"title"`
+		Icon    string            `json:"icon"`
+		Port    string            `json:"port"`
+		Status  string            `json:"status"`
+		AppType string            `json:"app_type"`
+	}
+	if err := s.casaOSGet(ctx, conn, "/v2/app_management/web/appgrid", nil, &grid); err != nil {
+		return nil, fmt.Errorf("Failed to fetch app grid: %v", err)
+	}
+
+	// 端口映射来自compose应用列表，获取失败时仅缺少端口信息
+	ports := make(map[string][]string)
+	var composeApps map[string]struct {
+		Compose struct {
+			Services map[string]struct {
+				Ports []struct {
+					Published interface{} `json:"published"`
+					Target    interface{} `json:"target"`
+					Protocol  string      `json:"protocol"`
+				} `json:"ports"`
+			} `json:"services"`
+		} `json:"compose"`
+	}
+	if err := s.casaOSGet(ctx, conn, "/v2/app_management/compose", nil, &composeApps); err != nil {
+		log.Printf("[WARNING] Failed to fetch compose apps: %v", err)
+	}
+	for appName, app := range composeApps {
+		for _, service := range app.Compose.Services {
+			for _, p := range service.Ports {
+				port := fmt.Sprintf("%v:%v", p.Published, p.Target)
+				if p.Protocol != "" && p.Protocol != "tcp" {
+					port += "/" + p.Protocol
+				}
+				ports[strings.ToLower(appName)] = append(ports[strings.ToLower(appName)], port)
+			}
+		}
+	}
+
+	// AppData目录名大小写可能与应用名不同
+	appDataDirs := make(map[string]string)
+	if entries, err := s.listCasaOSFolder(ctx, conn, casaOSAppDataDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir {
+				appDataDirs[strings.ToLower(entry.Name)] = entry.Name
+			}
+		}
+	} else {
+		log.Printf("[WARNING] Failed to list AppData directory: %v", err)
+	}
+
+	apps := make([]models.SourceApp, 0, len(grid))
+	for _, item := range grid {
+		app := models.SourceApp{
+			Name:    item.Name,
+			Title:   item.Title["custom"],
+			Icon:    item.Icon,
+			Status:  item.Status,
+			AppType: item.AppType,
+			WebPort: item.Port,
+			Ports:   ports[strings.ToLower(item.Name)],
+		}
+		if app.Title == "" {
+			app.Title = item.Title["en_us"]
+		}
+		if app.Title == "" {
+			app.Title = item.Name
+		}
+		if app.Ports == nil {
+			app.Ports = []string{}
+		}
+
+		if dirName, ok := appDataDirs[strings.ToLower(item.Name)]; ok {
+			size, err := s.getCasaOSFolderSize(ctx, conn, path.Join(casaOSAppDataDir, dirName))
+			if err != nil {
+				log.Printf("[WARNING] Failed to get AppData size for app %s: %v", item.Name, err)
+			}
+			app.DataSize = size
+		}
+
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// ListSourceApps 连接源系统并返回已安装应用的预览列表
+func (s *MigrationService) ListSourceApps(ctx context.Context, sourceConn *models.SystemConnection) ([]models.SourceApp, error) {
+	testResp, err := s.connService.TestConnection(ctx, sourceConn)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to test source connection: %v", err)
+	}
+	if !testResp.Success {
+		return nil, fmt.Errorf("Source connection failed: %s", testResp.Message)
+	}
+
+	return s.getSystemApps(ctx, sourceConn)
 }
 
 // getSystemSettings 获取系统设置
@@ -1018,10 +1548,10 @@ func min(a, b int) int {
 
 // extractZipFile 解压ZIP文件到指定目录
 func (s *MigrationService) extractZipFile(src, dest string) error {
-	// 打开ZIP文件
+	// 打开ZIP文件，中央目录缺失或损坏时无法打开
 	r, err := zip.OpenReader(src)
 	if err != nil {
-		return fmt.Errorf("Failed to open ZIP file: %v", err)
+		return fmt.Errorf("Failed to open ZIP file, central directory is missing or corrupted: %v", err)
 	}
 	defer r.Close()
 
@@ -1038,8 +1568,24 @@ func (s *MigrationService) extractZipFile(src, dest string) error {
 
 	log.Printf("[DEBUG] Starting to extract ZIP file: %s -> %s", src, dest)
 
+	// 解压前按声明大小检查压缩炸弹
+	limiter := s.newExtractLimiter(src)
+	if err := limiter.checkZip(r.File); err != nil {
+		return err
+	}
+
+	// 恢复条目权限并记录属主
+	attrs := newAttrRecorder(dest)
+
 	// 解压文件
 	for _, f := range r.File {
+		if err := limiter.addEntry(f.Name); err != nil {
+			return err
+		}
+		if skipArchiveManifest(attrs, f.Name, f.Open) {
+			continue
+		}
+
 		// 构建目标文件路径
 		path := filepath.Join(dest, f.Name)
 
@@ -1049,8 +1595,11 @@ func (s *MigrationService) extractZipFile(src, dest string) error {
 		}
 
 		if f.FileInfo().IsDir() {
+			if err := prepareExtractPath(dest, path); err != nil {
+				return err
+			}
 			// 创建目录
-			err = os.MkdirAll(path, 0755) // 使用统一的权限
+			err = os.MkdirAll(path, 0755)
 			if err != nil {
 				log.Printf("[ERROR] Failed to create directory: %s, error: %v", path, err)
 				return fmt.Errorf("Failed to create directory: %s - %v", path, err)
@@ -1059,19 +1608,32 @@ func (s *MigrationService) extractZipFile(src, dest string) error {
 			if err := os.Chmod(path, 0755); err != nil {
 				log.Printf("[WARNING] Failed to set directory permissions: %s - %v", path, err)
 			}
+			recordZipAttrs(attrs, path, f)
 			log.Printf("[DEBUG] Created directory: %s", path)
 			continue
 		}
 
-		// 创建文件的父目录
-		parentDir := filepath.Dir(path)
-		if err := os.MkdirAll(parentDir, 0755); err != nil {
-			log.Printf("[ERROR] Failed to create parent directory: %s, error: %v", parentDir, err)
-			return fmt.Errorf("Failed to create parent directory: %s - %v", parentDir, err)
+		// 符号链接条目的内容为链接目标
+		if isZipSymlink(f) {
+			linkname, err := zipSymlinkTarget(f)
+			if err != nil {
+				return err
+			}
+			created, err := extractSymlink(dest, path, linkname)
+			if err != nil {
+				return err
+			}
+			if created {
+				recordZipAttrs(attrs, path, f)
+				log.Printf("[DEBUG] Created symlink: %s -> %s", path, linkname)
+			}
+			continue
 		}
-		// 设置父目录权限
-		if err := os.Chmod(parentDir, 0755); err != nil {
-			log.Printf("[WARNING] Failed to set parent directory permissions: %s - %v", parentDir, err)
+
+		// 创建文件的父目录
+		if err := prepareExtractPath(dest, path); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return err
 		}
 
 		// 打开ZIP中的文件
@@ -1089,16 +1651,21 @@ func (s *MigrationService) extractZipFile(src, dest string) error {
 		}
 
 		// 复制文件内容
-		_, err = io.Copy(outFile, rc)
+		_, err = copySparse(outFile, limiter.reader(f.Name, rc))
 		rc.Close()
 		outFile.Close()
 		if err != nil {
 			return fmt.Errorf("Failed to copy file content: %v", err)
 		}
+		recordZipAttrs(attrs, path, f)
 
 		log.Printf("[DEBUG] Extracted file: %s", path)
 	}
 
+	if err := attrs.finish(); err != nil {
+		return err
+	}
+
 	log.Printf("[DEBUG] ZIP file extraction completed: %s", src)
 	return nil
 }
@@ -1124,12 +1691,21 @@ func (s *MigrationService) calculateImportSummary(appStatuses []models.AppImport
 	}
 
 	for _, app := range appStatuses {
-		if app.OverallStatus == models.AppStatusSuccess {
+		switch app.OverallStatus {
+		case models.AppStatusSuccess:
 			summary.SuccessApps++
-		} else {
+		case models.AppStatusSkipped:
+			summary.SkippedApps++
+		case models.AppStatusWarning:
+			summary.WarningApps++
+		default:
 			summary.FailedApps++
 		}
+		if len(app.SecurityFindings) > 0 {
+			summary.FlaggedApps++
+		}
 	}
+	summary.Metrics = sumAppMetrics(appStatuses)
 
 	return summary
 }
@@ -1148,6 +1724,21 @@ func (s *MigrationService) saveAppImportStatuses(taskID string, appStatuses []mo
 	log.Printf("[INFO] Saved app import status: total %d, succeeded %d, failed %d", summary.TotalApps, summary.SuccessApps, summary.FailedApps)
 }
 
+// notifyTaskFinished 异步发送任务完成通知
+func (s *MigrationService) notifyTaskFinished(taskID string, appStatuses []models.AppImportStatus) {
+	task, err := s.taskService.GetTask(taskID)
+	if err != nil {
+		return
+	}
+
+	// 复制一份任务和应用状态，避免通知发送期间数据被修改
+	taskCopy := *task
+	statuses := make([]models.AppImportStatus, len(appStatuses))
+	copy(statuses, appStatuses)
+
+	go s.notificationService.NotifyTaskFinished(&taskCopy, statuses)
+}
+
 // CreateAppPackage 为指定应用创建包含AppData和Compose文件的压缩包
 func (s *MigrationService) CreateAppPackage(taskID, appName string) (string, error) {
 	// 获取任务信息
@@ -1164,17 +1755,17 @@ func (s *MigrationService) CreateAppPackage(taskID, appName string) (string, err
 	// 查找解压后的目录
 	var extractedPath string
 
-	// 扫描download目录，查找解压后的文件夹
-	downloadDir := "./download"
-	entries, err := os.ReadDir(downloadDir)
+	// 扫描解压目录，查找解压后的文件夹
+	extractRoot := s.cfg.Dirs.Extract
+	entries, err := os.ReadDir(extractRoot)
 	if err != nil {
-		return "", fmt.Errorf("Failed to read download directory: %v", err)
+		return "", fmt.Errorf("Failed to read extraction directory: %v", err)
 	}
 
 	// 查找最新的解压目录（不是zip文件）
 	for _, entry := range entries {
 		if entry.IsDir() && !strings.HasSuffix(entry.Name(), ".zip") {
-			testPath := filepath.Join(downloadDir, entry.Name())
+			testPath := filepath.Join(extractRoot, entry.Name())
 			// 检查是否包含DATA和var目录
 			dataPath := filepath.Join(testPath, "DATA")
 			varPath := filepath.Join(testPath, "var")
@@ -1191,8 +1782,11 @@ func (s *MigrationService) CreateAppPackage(taskID, appName string) (string, err
 		return "", fmt.Errorf("Extracted backup directory not found")
 	}
 
-	// 创建临时目录
-	tempDir, err := os.MkdirTemp("", "app_package_*")
+	// 在应用包目录下创建临时目录，应用数据可能很大
+	if err := os.MkdirAll(s.cfg.Dirs.Package, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create packages directory: %v", err)
+	}
+	tempDir, err := os.MkdirTemp(s.cfg.Dirs.Package, "app_package_*")
 	if err != nil {
 		return "", fmt.Errorf("Failed to create temporary directory: %v", err)
 	}
@@ -1233,20 +1827,20 @@ func (s *MigrationService) CreateAppPackage(taskID, appName string) (string, err
 	}
 
 	if matchedAppName == "" {
-		return "", fmt.Errorf("未找到应用 %s 的相关文件", appName)
+		return "", fmt.Errorf("No files found for app %s", appName)
 	}
 
-	// 复制Compose文件（如果存在）
-	composeSourcePath := filepath.Join(extractedPath, "var/lib/casaos/apps", matchedAppName, "docker-compose.yml")
-	composeTargetPath := filepath.Join(appPackageDir, "docker-compose.yml")
-	if _, err := os.Stat(composeSourcePath); err == nil {
-		err = s.copyFile(composeSourcePath, composeTargetPath)
+	// 复制应用目录（Compose文件及.env、覆盖文件和自定义配置）
+	appSourceDir := filepath.Join(extractedPath, "var/lib/casaos/apps", matchedAppName)
+	if _, err := os.Stat(filepath.Join(appSourceDir, composeFileName)); err != nil {
+		log.Printf("[WARNING] Compose file not found for app %s: %s", matchedAppName, filepath.Join(appSourceDir, composeFileName))
+	}
+	if _, err := os.Stat(appSourceDir); err == nil {
+		err = s.copyDir(appSourceDir, appPackageDir)
 		if err != nil {
-			return "", fmt.Errorf("Failed to copy Compose file: %v", err)
+			return "", fmt.Errorf("Failed to copy app directory: %v", err)
 		}
-		log.Printf("[INFO] Copied Compose file for app %s", matchedAppName)
-	} else {
-		log.Printf("[WARNING] Compose file not found for app %s: %s", matchedAppName, composeSourcePath)
+		log.Printf("[INFO] Copied app directory for app %s", matchedAppName)
 	}
 
 	// 复制AppData目录（如果存在）
@@ -1263,7 +1857,7 @@ func (s *MigrationService) CreateAppPackage(taskID, appName string) (string, err
 	}
 
 	// 创建应用包压缩文件
-	packagesDir := "./packages"
+	packagesDir := s.cfg.Dirs.Package
 	err = os.MkdirAll(packagesDir, 0755)
 	if err != nil {
 		return "", fmt.Errorf("Failed to create packages directory: %v", err)
@@ -1325,6 +1919,13 @@ func (s *MigrationService) copyDir(src, dst string) error {
 		if info.IsDir() {
 			// 创建目录
 			return os.MkdirAll(targetPath, info.Mode())
+		} else if isSymlink(info) {
+			// 复制符号链接本身
+			linkname, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkname, targetPath)
 		} else {
 			// 复制文件
 			return s.copyFile(path, targetPath)
@@ -1359,6 +1960,16 @@ func (s *MigrationService) createZipFile(sourceDir, targetZip string) error {
 			return err
 		}
 
+		// 符号链接保存为链接本身，不跟随
+		if isSymlink(info) {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relPath)
+			return writeZipSymlink(archive, header, path)
+		}
+
 		// 创建ZIP文件条目
 		writer, err := archive.Create(relPath)
 		if err != nil {
@@ -1405,7 +2016,7 @@ func (s *MigrationService) readComposeFiles(appsDir string) (map[string]string,
 		}
 
 		appName := entry.Name()
-		composeFilePath := filepath.Join(appsDir, appName, "docker-compose.yml")
+		composeFilePath := filepath.Join(appsDir, appName, composeFileName)
 
 		// 检查compose文件是否存在
 		if _, err := os.Stat(composeFilePath); os.IsNotExist(err) {
@@ -1420,7 +2031,14 @@ func (s *MigrationService) readComposeFiles(appsDir string) (map[string]string,
 			continue
 		}
 
-		composeFiles[appName] = string(content)
+		// 覆盖文件合并到主compose中，合并失败时只使用主compose
+		merged, err := mergeComposeOverride(filepath.Join(appsDir, appName), string(content))
+		if err != nil {
+			log.Printf("[WARNING] Failed to merge compose override for app %s: %v", appName, err)
+			merged = string(content)
+		}
+
+		composeFiles[appName] = merged
 		log.Printf("[DEBUG] Read compose file for app %s, size: %d bytes", appName, len(content))
 	}
 
@@ -1429,57 +2047,30 @@ func (s *MigrationService) readComposeFiles(appsDir string) (map[string]string,
 }
 
 // importComposeToZimaOS 导入compose文件到ZimaOS
-func (s *MigrationService) importComposeToZimaOS(target *models.SystemConnection, appName, composeContent, taskID string) error {
+func (s *MigrationService) importComposeToZimaOS(ctx context.Context, target *models.SystemConnection, appName, composeContent, taskID string) error {
 	// 记录开始导入
 	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Start importing app: %s", appName))
 
-	// 构建API URL
-	apiURL := fmt.Sprintf("http://%s:%d/v2/app_management/compose?dry_run=false&check_port_conflict=true", target.Host, target.Port)
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(composeContent))
-	if err != nil {
-		errorMsg := fmt.Sprintf("App %s: Failed to create request: %v", appName, err)
-		s.taskService.AddTaskLog(taskID, models.LogLevelError, errorMsg)
-		return fmt.Errorf(errorMsg)
-	}
-
-	// 设置请求头
-	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en-US;q=0.8,en;q=0.7")
-	req.Header.Set("Authorization", target.Token)
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Content-Type", "application/yaml")
-	req.Header.Set("Language", "en_US")
-	req.Header.Set("Origin", fmt.Sprintf("http://%s:%d", target.Host, target.Port))
-	req.Header.Set("Referer", fmt.Sprintf("http://%s:%d/modules/icewhale_app/?_t=%d", target.Host, target.Port, time.Now().Unix()))
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36")
-
 	// 发送请求
 	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: Sending import request...", appName))
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		errorMsg := fmt.Sprintf("App %s: Request failed: %v", appName, err)
-		s.taskService.AddTaskLog(taskID, models.LogLevelError, errorMsg)
-		return fmt.Errorf(errorMsg)
+	// 蓝绿导入的应用与目标上的原应用并存，端口冲突时仍然导入
+	checkPorts := true
+	if task, err := s.taskService.GetTask(taskID); err == nil && blueGreen(task.Options) {
+		checkPorts = false
 	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	statusCode, body, err := s.zimaOSClientFor(target).ImportCompose(ctx, composeContent, checkPorts)
 	if err != nil {
-		errorMsg := fmt.Sprintf("App %s: Failed to read response: %v", appName, err)
+		errorMsg := fmt.Sprintf("App %s: %v", appName, err)
 		s.taskService.AddTaskLog(taskID, models.LogLevelError, errorMsg)
 		return fmt.Errorf(errorMsg)
 	}
 
 	// 检查响应状态
-	if resp.StatusCode == 200 {
+	if statusCode == 200 {
 		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: Import succeeded ✓", appName))
 		return nil
 	} else {
-		errorMsg := fmt.Sprintf("App %s: Import failed (status code: %d): %s", appName, resp.StatusCode, string(body))
+		errorMsg := fmt.Sprintf("App %s: Import failed (status code: %d): %s", appName, statusCode, string(body))
 		s.taskService.AddTaskLog(taskID, models.LogLevelError, errorMsg)
 		return fmt.Errorf(errorMsg)
 	}
@@ -1502,7 +2093,7 @@ func (s *MigrationService) migrateUserData(target *models.SystemConnection, user
 // createExportFile 创建导出文件
 func (s *MigrationService) createExportFile(taskID string, data map[string]interface{}) (string, error) {
 	// 创建导出目录
-	exportDir := "./exports"
+	exportDir := s.cfg.Dirs.Export
 	if err := os.MkdirAll(exportDir, 0755); err != nil {
 		return "", fmt.Errorf("Failed to create export directory: %v", err)
 	}
@@ -1540,6 +2131,18 @@ func (s *MigrationService) createExportFile(taskID string, data map[string]inter
 	return filePath, nil
 }
 
+// testExportSource 测试导出源系统的连接，失败时返回携带测试结果的SourceConnectionError
+func (s *MigrationService) testExportSource(ctx context.Context, sourceConn *models.SystemConnection) error {
+	testResp, err := s.connService.TestConnection(ctx, sourceConn)
+	if err != nil {
+		testResp = &models.ConnectionTestResponse{Success: false, Message: err.Error()}
+	}
+	if !testResp.Success {
+		return &models.SourceConnectionError{Test: testResp}
+	}
+	return nil
+}
+
 // createMockDownloadFile 创建模拟的下载文件用于演示
 func (s *MigrationService) createMockDownloadFile() (string, error) {
 	// 创建临时目录
@@ -1593,6 +2196,12 @@ $CONFIG = array (
 		return "", fmt.Errorf("Failed to create config file: %v", err)
 	}
 
+	// 在压缩包根目录放置演示标记，导入时据此提示
+	err = os.WriteFile(filepath.Join(tempDir, demoMarkerFile), []byte(demoMarkerContent), 0644)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create demo marker: %v", err)
+	}
+
 	// 创建ZIP文件
 	zipPath := filepath.Join(os.TempDir(), fmt.Sprintf("mock_casaos_%d.zip", time.Now().Unix()))
 	err = s.createZipFile(tempDir, zipPath)
@@ -1608,10 +2217,36 @@ $CONFIG = array (
 	return zipPath, nil
 }
 
-// createDirectExportFile 创建包含实际文件的导出压缩包
-func (s *MigrationService) createDirectExportFile(taskID string, data map[string]interface{}, downloadedFilePath string) (string, error) {
+// createDirectExportFile 创建包含实际文件的导出压缩包，设置了导出过滤模式或包含镜像时写入清单
+func (s *MigrationService) createDirectExportFile(taskID string, data map[string]interface{}, downloadedFilePath string, filterSpec *models.ExportFilter, withImages bool) (string, error) {
+	filter, err := newExportFilter(filterSpec)
+	if err != nil {
+		return "", err
+	}
+	var manifest *exportManifest
+	if filter != nil || withImages {
+		hashes, err := appContentHashes(downloadedFilePath, filter)
+		if err != nil {
+			return "", err
+		}
+		manifest = newExportManifest(hashes, filterSpec)
+	}
+
+	filePath, err := s.writeExportArchive(data, downloadedFilePath, manifest, filter)
+	if err != nil {
+		return "", err
+	}
+	if filter != nil {
+		log.Printf("[INFO] [DirectExport] Export filter: %d app files and %d data files included, %d files excluded", manifest.Files.Apps, manifest.Files.Data, manifest.Files.Excluded)
+	}
+	return filePath, nil
+}
+
+// writeExportArchive 写入导出压缩包，manifest不为空时写入清单，增量导出只包含有变化的应用
+// filter不为空时只写入通过过滤的条目，写入和被过滤掉的文件数记录在清单中
+func (s *MigrationService) writeExportArchive(data map[string]interface{}, downloadedFilePath string, manifest *exportManifest, filter *exportFilter) (string, error) {
 	// 创建导出目录
-	exportDir := "./exports"
+	exportDir := s.cfg.Dirs.Export
 	if err := os.MkdirAll(exportDir, 0755); err != nil {
 		return "", fmt.Errorf("Failed to create export directory: %v", err)
 	}
@@ -1647,6 +2282,10 @@ func (s *MigrationService) createDirectExportFile(taskID string, data map[string
 	}
 
 	// 2. 添加下载的CasaOS文件（包含apps和appdata目录）
+	var counts *exportFileCounts
+	if manifest != nil {
+		counts = manifest.Files
+	}
 	if downloadedFilePath != "" {
 		// 打开下载的ZIP文件
 		downloadedZip, err := zip.OpenReader(downloadedFilePath)
@@ -1655,27 +2294,60 @@ func (s *MigrationService) createDirectExportFile(taskID string, data map[string
 		}
 		defer downloadedZip.Close()
 
-		// 将下载的ZIP文件内容复制到新的ZIP文件中
+		// 将下载的ZIP文件内容复制到新的ZIP文件中，重新压缩在多个CPU核上并行进行
+		parallel := newParallelZipWriter(zipWriter, s.cfg.CompressWorkers)
+		defer parallel.close()
 		for _, file := range downloadedZip.File {
+			if !manifest.includes(file.Name) {
+				continue
+			}
+			if !filter.allows(file.Name) {
+				counts.add(file, false)
+				continue
+			}
+
 			// 打开源文件
 			src, err := file.Open()
 			if err != nil {
 				return "", fmt.Errorf("Failed to open source file: %v", err)
 			}
 
-			// 在新ZIP中创建文件
-			dst, err := zipWriter.Create(file.Name)
-			if err != nil {
-				src.Close()
-				return "", fmt.Errorf("Failed to create destination file: %v", err)
+			// 在新ZIP中创建文件并复制内容，应用的镜像包写入images目录并记录在清单中
+			header := &zip.FileHeader{Name: file.Name, Modified: file.Modified}
+			header.SetMode(file.Mode())
+			if app, ok := containerImagesApp(file.Name); ok {
+				if path.Base(file.Name) != containerImagesFile {
+					src.Close()
+					continue
+				}
+				header.Name = exportImagePath(app)
+				if manifest != nil {
+					if manifest.Images == nil {
+						manifest.Images = make(map[string]string)
+					}
+					manifest.Images[app] = header.Name
+				}
+			}
+			if strings.HasSuffix(file.Name, "/") {
+				err = parallel.addEntry(header, nil)
+			} else {
+				err = parallel.addFile(header, src)
 			}
-
-			// 复制文件内容
-			_, err = io.Copy(dst, src)
 			src.Close()
 			if err != nil {
 				return "", fmt.Errorf("Failed to copy file content: %v", err)
 			}
+			counts.add(file, true)
+		}
+		if err := parallel.close(); err != nil {
+			return "", fmt.Errorf("Failed to write ZIP file: %v", err)
+		}
+	}
+
+	// 3. 清单在文件之后写入，以便记录文件数
+	if manifest != nil {
+		if err := writeExportManifest(zipWriter, manifest); err != nil {
+			return "", err
 		}
 	}
 
@@ -1690,25 +2362,18 @@ func (s *MigrationService) detectFileFormat(filePath string) (string, error) {
 	}
 	defer file.Close()
 
-	// 读取文件前10个字节用于格式检测
-	buf := make([]byte, 10)
-	n, err := file.Read(buf)
-	if err != nil {
+	// 读取文件头用于格式检测，tar的标识位于第257字节
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
 		return "", fmt.Errorf("Failed to read file header: %v", err)
 	}
 
-	log.Printf("[DEBUG] First %d bytes of file: %v", n, buf[:n])
-
-	// 检测ZIP格式 (PK signature: 0x504B)
-	if n >= 2 && buf[0] == 0x50 && buf[1] == 0x4B {
-		log.Printf("[DEBUG] Detected ZIP format, magic: %02X %02X", buf[0], buf[1])
-		return "zip", nil
-	}
+	log.Printf("[DEBUG] First %d bytes of file: %v", min(n, 10), buf[:min(n, 10)])
 
-	// 检测GZIP格式 (magic number: 0x1F8B)
-	if n >= 2 && buf[0] == 0x1F && buf[1] == 0x8B {
-		log.Printf("[DEBUG] Detected GZIP format, magic: %02X %02X", buf[0], buf[1])
-		return "gzip", nil
+	if format := DetectArchiveFormat(buf[:n]); format != "unknown" {
+		log.Printf("[DEBUG] Detected %s format", format)
+		return format, nil
 	}
 
 	// 如果都不匹配，返回详细的错误信息
@@ -1720,11 +2385,11 @@ func (s *MigrationService) detectFileFormat(filePath string) (string, error) {
 	}
 
 	log.Printf("[ERROR] Unrecognized file format, magic: %s", magicStr)
-	return "unknown", fmt.Errorf("Unsupported file format. Detected magic bytes: %s (Supported: ZIP magic 50 4B, GZIP magic 1F 8B)", magicStr)
+	return "unknown", fmt.Errorf("Unsupported file format. Detected magic bytes: %s (Supported: ZIP, 7z, GZIP, XZ and uncompressed tar)", magicStr)
 }
 
 // parseImportFile 解析导入文件
-func (s *MigrationService) parseImportFile(filePath string) (map[string]interface{}, error) {
+func (s *MigrationService) parseImportFile(ctx context.Context, filePath string) (map[string]interface{}, error) {
 	// 根据文件内容检测实际格式
 	actualFormat, err := s.detectFileFormat(filePath)
 	if err != nil {
@@ -1736,7 +2401,7 @@ func (s *MigrationService) parseImportFile(filePath string) (map[string]interfac
 	// 根据实际格式选择解析方法
 	switch actualFormat {
 	case "gzip":
-		return s.parseTarGzFile(filePath)
+		return s.parseTarGzFile(ctx, filePath)
 	case "zip":
 		return s.parseZipFile(filePath)
 	default:
@@ -1780,7 +2445,7 @@ func (s *MigrationService) parseZipFile(filePath string) (map[string]interface{}
 }
 
 // parseTarGzFile 解析tar.gz格式文件
-func (s *MigrationService) parseTarGzFile(filePath string) (map[string]interface{}, error) {
+func (s *MigrationService) parseTarGzFile(ctx context.Context, filePath string) (map[string]interface{}, error) {
 	// 打开文件
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -1832,20 +2497,23 @@ func (s *MigrationService) parseTarGzFile(filePath string) (map[string]interface
 	}
 
 	// 如果没有找到JSON文件，尝试解析目录结构
-	return s.parseCasaOSStructure(filePath)
+	return s.parseCasaOSStructure(ctx, filePath)
 }
 
 // parseCasaOSStructure 解析CasaOS目录结构
-func (s *MigrationService) parseCasaOSStructure(filePath string) (map[string]interface{}, error) {
-	// 创建临时目录
-	tempDir, err := os.MkdirTemp("", "casaos-import-*")
+func (s *MigrationService) parseCasaOSStructure(ctx context.Context, filePath string) (map[string]interface{}, error) {
+	// 在解压目录下创建临时目录
+	if err := os.MkdirAll(s.cfg.Dirs.Extract, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to create extraction directory: %v", err)
+	}
+	tempDir, err := os.MkdirTemp(s.cfg.Dirs.Extract, "casaos-import-*")
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create temporary directory: %v", err)
 	}
 	defer os.RemoveAll(tempDir)
 
 	// 解压tar.gz文件到临时目录
-	if err := s.extractTarGz(filePath, tempDir); err != nil {
+	if err := s.extractTarGz(ctx, filePath, tempDir); err != nil {
 		return nil, fmt.Errorf("Failed to extract file: %v", err)
 	}
 
@@ -1886,8 +2554,8 @@ func (s *MigrationService) parseCasaOSStructure(filePath string) (map[string]int
 	return result, nil
 }
 
-// extractTarGz 解压tar.gz文件或ZIP文件
-func (s *MigrationService) extractTarGz(src, dest string) error {
+// extractTarGz 解压tar.gz、tar.xz、tar、ZIP或7z文件
+func (s *MigrationService) extractTarGz(ctx context.Context, src, dest string) error {
 	// 检查源文件是否存在
 	fileInfo, err := os.Stat(src)
 	if err != nil {
@@ -1910,37 +2578,33 @@ func (s *MigrationService) extractTarGz(src, dest string) error {
 
 	// 根据实际格式选择解压方法
 	switch actualFormat {
-	case "gzip":
-		log.Printf("[INFO] Using GZIP extraction method")
-		return s.extractGzipFile(src, dest)
+	case "gzip", "xz", "tar":
+		log.Printf("[INFO] Using tar extraction method (%s)", tarFormatName(actualFormat))
+		return s.extractTarFile(src, dest, actualFormat)
 	case "zip":
 		log.Printf("[INFO] Detected ZIP file; using ZIP extraction method")
 		return s.extractZipFile(src, dest)
+	case "7z":
+		log.Printf("[INFO] Detected 7z file; using 7-Zip extraction method")
+		return s.extract7zFile(ctx, src, dest)
 	default:
 		// 如果是unknown格式，错误信息已经在detectFileFormat中生成
 		if actualFormat == "unknown" {
 			return err // 返回detectFileFormat的详细错误信息
 		}
-		return fmt.Errorf("Unsupported file format: %s. Please use ZIP or GZIP archives", actualFormat)
+		return fmt.Errorf("Unsupported file format: %s. Please use ZIP, 7z, tar.gz, tar.xz or tar archives", actualFormat)
 	}
 }
 
-// extractGzipFile 解压gzip格式的tar文件
-func (s *MigrationService) extractGzipFile(src, dest string) error {
-	file, err := os.Open(src)
+// extractTarFile 解压tar文件，format为gzip、xz或tar（未压缩）
+func (s *MigrationService) extractTarFile(src, dest, format string) error {
+	stream, err := openTarStream(src, format)
 	if err != nil {
-		return fmt.Errorf("Failed to open source file: %v", err)
-	}
-	defer file.Close()
-
-	// 创建gzip reader
-	gzReader, err := gzip.NewReader(file)
-	if err != nil {
-		return fmt.Errorf("Failed to create gzip reader: %v", err)
+		return err
 	}
-	defer gzReader.Close()
+	defer stream.Close()
 
-	log.Printf("[DEBUG] gzip reader created")
+	log.Printf("[DEBUG] %s reader created", tarFormatName(format))
 
 	// 确保目标目录存在且权限正确
 	if err := os.MkdirAll(dest, 0755); err != nil {
@@ -1950,7 +2614,19 @@ func (s *MigrationService) extractGzipFile(src, dest string) error {
 		log.Printf("[WARNING] Failed to set destination directory permissions: %v", err)
 	}
 
-	tarReader := tar.NewReader(gzReader)
+	// 压缩数据中不是tar包时给出明确的错误
+	buffered := bufio.NewReader(stream)
+	if format != "tar" {
+		if err := peekTarHeader(buffered, format); err != nil {
+			return err
+		}
+	}
+
+	tarReader := tar.NewReader(buffered)
+
+	// 恢复条目权限并记录属主
+	attrs := newAttrRecorder(dest)
+	limiter := s.newExtractLimiter(src)
 
 	for {
 		header, err := tarReader.Next()
@@ -1960,9 +2636,25 @@ func (s *MigrationService) extractGzipFile(src, dest string) error {
 		if err != nil {
 			return fmt.Errorf("Failed to read tar entry: %v", err)
 		}
+		if err := limiter.addEntry(header.Name); err != nil {
+			return err
+		}
+		if skipArchiveManifest(attrs, header.Name, func() (io.ReadCloser, error) {
+			if header.Typeflag != tar.TypeReg {
+				return nil, fmt.Errorf("%s is not a regular file", header.Name)
+			}
+			return io.NopCloser(tarReader), nil
+		}) {
+			continue
+		}
 
 		target := filepath.Join(dest, header.Name)
 
+		// 手动执行 tar -C <dir> . 打包时第一个条目是根目录本身
+		if target == filepath.Clean(dest) && header.Typeflag == tar.TypeDir {
+			continue
+		}
+
 		// 检查路径安全性，防止目录遍历攻击
 		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
 			return fmt.Errorf("Insecure file path: %s", header.Name)
@@ -1970,6 +2662,9 @@ func (s *MigrationService) extractGzipFile(src, dest string) error {
 
 		switch header.Typeflag {
 		case tar.TypeDir:
+			if err := prepareExtractPath(dest, target); err != nil {
+				return err
+			}
 			if err := os.MkdirAll(target, 0755); err != nil {
 				log.Printf("[ERROR] Failed to create directory: %s, error: %v", target, err)
 				return fmt.Errorf("Failed to create directory: %s - %v", target, err)
@@ -1978,33 +2673,50 @@ func (s *MigrationService) extractGzipFile(src, dest string) error {
 			if err := os.Chmod(target, 0755); err != nil {
 				log.Printf("[WARNING] Failed to set directory permissions: %s - %v", target, err)
 			}
+			attrs.record(target, header.FileInfo().Mode(), header.Uid, header.Gid, true)
 			log.Printf("[DEBUG] Created directory: %s", target)
-		case tar.TypeReg:
-			parentDir := filepath.Dir(target)
-			if err := os.MkdirAll(parentDir, 0755); err != nil {
-				log.Printf("[ERROR] Failed to create parent directory: %s, error: %v", parentDir, err)
-				return fmt.Errorf("Failed to create parent directory: %s - %v", parentDir, err)
+		case tar.TypeSymlink:
+			created, err := extractSymlink(dest, target, header.Linkname)
+			if err != nil {
+				return err
+			}
+			if created {
+				attrs.record(target, header.FileInfo().Mode(), header.Uid, header.Gid, true)
+				log.Printf("[DEBUG] Created symlink: %s -> %s", target, header.Linkname)
+			}
+		case tar.TypeLink:
+			if err := extractHardlink(dest, target, header.Linkname); err != nil {
+				log.Printf("[ERROR] %v", err)
+				return err
 			}
-			// 设置父目录权限
-			if err := os.Chmod(parentDir, 0755); err != nil {
-				log.Printf("[WARNING] Failed to set parent directory permissions: %s - %v", parentDir, err)
+			log.Printf("[DEBUG] Created hardlink: %s -> %s", target, header.Linkname)
+		case tar.TypeReg, tar.TypeGNUSparse:
+			// 稀疏文件的空洞由tar reader读出为0，写入时重新生成空洞
+			if err := prepareExtractPath(dest, target); err != nil {
+				log.Printf("[ERROR] %v", err)
+				return err
 			}
 
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, 0644) // 使用统一的文件权限
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
 			if err != nil {
 				log.Printf("[ERROR] Failed to create file: %s, error: %v", target, err)
 				return fmt.Errorf("Failed to create file: %s - %v", target, err)
 			}
-			if _, err := io.Copy(f, tarReader); err != nil {
+			if _, err := copySparse(f, limiter.reader(header.Name, tarReader)); err != nil {
 				f.Close()
 				return fmt.Errorf("Failed to copy file content: %v", err)
 			}
 			f.Close()
+			attrs.record(target, header.FileInfo().Mode(), header.Uid, header.Gid, true)
 			log.Printf("[DEBUG] Extracted file: %s", target)
 		}
 	}
 
-	log.Printf("[DEBUG] GZIP file extraction completed: %s", src)
+	if err := attrs.finish(); err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] %s file extraction completed: %s", tarFormatName(format), src)
 	return nil
 }
 
@@ -2065,58 +2777,9 @@ func (s *MigrationService) getFileSize(filePath string) int64 {
 	return 0
 }
 
-// downloadCasaOSFiles 下载CasaOS文件
-func (s *MigrationService) downloadCasaOSFiles(conn *models.SystemConnection, progressCallback func(int, string)) (string, error) {
-	// 构建下载URL
-	downloadURL := fmt.Sprintf("http://%s/v1/batch?token=%s&files=/var/lib/casaos/apps,/DATA/AppData", conn.Host, conn.Token)
-
-	progressCallback(10, "Start downloading")
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("GET", downloadURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("Failed to create download request: %v", err)
-	}
-
-	// 发送请求
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("Failed to send download request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Download failed, status code: %d", resp.StatusCode)
-	}
-
-	progressCallback(20, "Downloading file")
-
-	// 创建下载目录
-	downloadDir := "./download"
-	if err := os.MkdirAll(downloadDir, 0755); err != nil {
-		return "", fmt.Errorf("Failed to create download directory: %v", err)
-	}
-
-	// 生成文件名
-	filename := fmt.Sprintf("casaos_backup_%s.zip", time.Now().Format("20060102_150405"))
-	filePath := filepath.Join(downloadDir, filename)
-
-	// 创建本地文件
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("Failed to create local file: %v", err)
-	}
-	defer file.Close()
-
-	// 复制数据并显示进度
-	written, err := io.Copy(file, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("Failed to download file: %v", err)
-	}
-
-	progressCallback(35, fmt.Sprintf("Download completed, file size: %d bytes", written))
-
-	return filePath, nil
+// importExtractDir 离线导入文件的解压目录
+func importExtractDir(dirs config.WorkDirs) string {
+	return filepath.Join(dirs.Extract, "extracted_import")
 }
 
 // extractDownloadedFiles 解压下载的文件
@@ -2124,7 +2787,7 @@ func (s *MigrationService) extractDownloadedFiles(zipPath string, progressCallba
 	progressCallback(45, "Starting to extract file")
 
 	// 创建解压目录
-	extractDir := filepath.Join(filepath.Dir(zipPath), "extracted")
+	extractDir := filepath.Join(s.cfg.Dirs.Extract, "extracted")
 	if err := os.MkdirAll(extractDir, 0755); err != nil {
 		return "", fmt.Errorf("Failed to create extraction directory: %v", err)
 	}
@@ -2138,8 +2801,24 @@ func (s *MigrationService) extractDownloadedFiles(zipPath string, progressCallba
 
 	progressCallback(50, "Extracting file")
 
+	// 解压前按声明大小检查压缩炸弹
+	limiter := s.newExtractLimiter(zipPath)
+	if err := limiter.checkZip(zipReader.File); err != nil {
+		return "", err
+	}
+
+	// 恢复条目权限并记录属主
+	attrs := newAttrRecorder(extractDir)
+
 	// 解压文件
 	for i, file := range zipReader.File {
+		if err := limiter.addEntry(file.Name); err != nil {
+			return "", err
+		}
+		if skipArchiveManifest(attrs, file.Name, file.Open) {
+			continue
+		}
+
 		// 计算进度
 		progress := 50 + (i*10)/len(zipReader.File)
 		progressCallback(progress, fmt.Sprintf("Extracting: %s", file.Name))
@@ -2147,17 +2826,42 @@ func (s *MigrationService) extractDownloadedFiles(zipPath string, progressCallba
 		// 构建目标路径
 		targetPath := filepath.Join(extractDir, file.Name)
 
+		// 检查路径安全性，防止目录遍历攻击
+		if !strings.HasPrefix(targetPath, filepath.Clean(extractDir)+string(os.PathSeparator)) {
+			return "", fmt.Errorf("Insecure file path: %s", file.Name)
+		}
+
 		// 确保目录存在
 		if file.FileInfo().IsDir() {
-			if err := os.MkdirAll(targetPath, file.FileInfo().Mode()); err != nil {
+			if err := prepareExtractPath(extractDir, targetPath); err != nil {
+				return "", err
+			}
+			if err := os.MkdirAll(targetPath, 0755); err != nil {
 				return "", fmt.Errorf("Failed to create directory: %v", err)
 			}
+			recordZipAttrs(attrs, targetPath, file)
+			continue
+		}
+
+		// 符号链接条目的内容为链接目标
+		if isZipSymlink(file) {
+			linkname, err := zipSymlinkTarget(file)
+			if err != nil {
+				return "", err
+			}
+			created, err := extractSymlink(extractDir, targetPath, linkname)
+			if err != nil {
+				return "", err
+			}
+			if created {
+				recordZipAttrs(attrs, targetPath, file)
+			}
 			continue
 		}
 
 		// 确保父目录存在
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-			return "", fmt.Errorf("Failed to create parent directory: %v", err)
+		if err := prepareExtractPath(extractDir, targetPath); err != nil {
+			return "", err
 		}
 
 		// 打开ZIP中的文件
@@ -2174,13 +2878,18 @@ func (s *MigrationService) extractDownloadedFiles(zipPath string, progressCallba
 		}
 
 		// 复制文件内容
-		_, err = io.Copy(dst, src)
+		_, err = copySparse(dst, limiter.reader(file.Name, src))
 		src.Close()
 		dst.Close()
 
 		if err != nil {
 			return "", fmt.Errorf("Failed to copy file content: %v", err)
 		}
+		recordZipAttrs(attrs, targetPath, file)
+	}
+
+	if err := attrs.finish(); err != nil {
+		return "", err
 	}
 
 	progressCallback(60, "Extraction completed")
@@ -2189,7 +2898,7 @@ func (s *MigrationService) extractDownloadedFiles(zipPath string, progressCallba
 }
 
 // mergeAppDataToZimaOS 合并AppData目录到ZimaOS
-func (s *MigrationService) mergeAppDataToZimaOS(target *models.SystemConnection, appDataPath string, taskID string, progressCallback func(int, string)) error {
+func (s *MigrationService) mergeAppDataToZimaOS(ctx context.Context, target *models.SystemConnection, appDataPath, remoteAppDataDir string, taskID string, progressCallback func(int, string)) error {
 	log.Printf("[INFO] Start merging AppData directory: %s", appDataPath)
 
 	// 读取AppData目录下的所有应用目录
@@ -2221,7 +2930,7 @@ func (s *MigrationService) mergeAppDataToZimaOS(target *models.SystemConnection,
 		progressCallback(progress, fmt.Sprintf("Processing app data: %s (%d/%d)", appName, completedDirs, totalDirs))
 
 		// 检查ZimaOS中是否已存在该应用目录
-		exists, err := s.checkAppDataExists(target, appName)
+		exists, err := s.checkAppDataExists(ctx, target, remoteAppDataDir, appName)
 		if err != nil {
 			log.Printf("[WARNING] Failed to check app %s data directory: %v", appName, err)
 			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Failed to check app %s data directory: %v", appName, err))
@@ -2236,7 +2945,7 @@ func (s *MigrationService) mergeAppDataToZimaOS(target *models.SystemConnection,
 
 		// 上传应用数据目录到ZimaOS
 		sourcePath := filepath.Join(appDataPath, appName)
-		err = s.uploadAppDataToZimaOS(target, appName, sourcePath, taskID)
+		err = s.uploadAppDataToZimaOS(ctx, target, remoteAppDataDir, appName, sourcePath, taskID)
 		if err != nil {
 			log.Printf("[ERROR] Failed to upload data for app %s: %v", appName, err)
 			s.taskService.AddTaskLog(taskID, models.LogLevelError, fmt.Sprintf("App %s data upload failed: %v", appName, err))
@@ -2252,12 +2961,17 @@ func (s *MigrationService) mergeAppDataToZimaOS(target *models.SystemConnection,
 }
 
 // checkAppDataExists 检查ZimaOS中是否已存在应用数据目录
-func (s *MigrationService) checkAppDataExists(target *models.SystemConnection, appName string) (bool, error) {
+func (s *MigrationService) checkAppDataExists(ctx context.Context, target *models.SystemConnection, remoteAppDataDir, appName string) (bool, error) {
+	return s.zimaOSPathExists(ctx, target, path.Join(remoteAppDataDir, appName))
+}
+
+// zimaOSPathExists 通过文件信息接口检查ZimaOS上的路径是否存在
+func (s *MigrationService) zimaOSPathExists(ctx context.Context, target *models.SystemConnection, remotePath string) (bool, error) {
 	// 构建检查URL
-	checkURL := fmt.Sprintf("http://%s:%d/v1/file/info?path=/media/ZimaOS-HD/AppData/%s", target.Host, target.Port, appName)
+	checkURL := fmt.Sprintf("%s/v1/file/info?path=%s", connBaseURL(target), url.QueryEscape(remotePath))
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("GET", checkURL, nil)
+	req, err := newConnRequest(ctx, target, "GET", checkURL, nil)
 	if err != nil {
 		return false, fmt.Errorf("Failed to create check request: %v", err)
 	}
@@ -2282,12 +2996,12 @@ func (s *MigrationService) checkAppDataExists(target *models.SystemConnection, a
 	}
 }
 
-// uploadAppDataToZimaOS 上传应用数据目录到ZimaOS
-func (s *MigrationService) uploadAppDataToZimaOS(target *models.SystemConnection, appName, sourcePath, taskID string) error {
+// uploadAppDataToZimaOS 上传应用数据目录到ZimaOS上的remoteAppDataDir
+func (s *MigrationService) uploadAppDataToZimaOS(ctx context.Context, target *models.SystemConnection, remoteAppDataDir, appName, sourcePath, taskID string) error {
 	log.Printf("[INFO] Start uploading data directory for app %s: %s", appName, sourcePath)
 
 	// 创建临时压缩文件
-	tempDir := "./compress"
+	tempDir := s.cfg.Dirs.Compress
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		return fmt.Errorf("Failed to create temporary directory: %v", err)
 	}
@@ -2296,10 +3010,19 @@ func (s *MigrationService) uploadAppDataToZimaOS(target *models.SystemConnection
 	tempZipPath := filepath.Join(tempDir, fmt.Sprintf("%s_appdata_%s.zip", appName, time.Now().Format("20060102_150405")))
 
 	// 压缩应用数据目录
+	compressStart := time.Now()
 	err := s.compressDirectory(sourcePath, tempZipPath)
 	if err != nil {
 		return fmt.Errorf("Failed to compress app data: %v", err)
 	}
+	var archiveBytes int64
+	if info, err := os.Stat(tempZipPath); err == nil {
+		archiveBytes = info.Size()
+	}
+	s.recordAppMetrics(taskID, appName, func(m *models.AppMetrics) {
+		m.CompressMs = time.Since(compressStart).Milliseconds()
+		m.BytesTransferred = archiveBytes
+	})
 
 	defer func() {
 		// 清理临时压缩文件
@@ -2308,31 +3031,78 @@ func (s *MigrationService) uploadAppDataToZimaOS(target *models.SystemConnection
 		}
 	}()
 
-	// 上传压缩文件到ZimaOS，目标路径为/media/ZimaOS-HD/AppData，文件名为{appName}.zip
-	uploadURL := fmt.Sprintf("http://%s:%d/v2_1/files/file/uploadV2", target.Host, target.Port)
-	err = s.uploadFileToZimaOS(uploadURL, tempZipPath, "/media/ZimaOS-HD/AppData", fmt.Sprintf("%s.zip", appName), target.Token)
+	// 上传前检查目标空间，避免上传或解压到一半才因磁盘已满失败
+	_, dataBytes := entryStats(sourcePath)
+	if err := s.checkZimaOSFreeSpace(ctx, target, remoteAppDataDir, appName, archiveBytes, dataBytes); err != nil {
+		return err
+	}
+
+	// 上传压缩文件到ZimaOS，目标路径为remoteAppDataDir，文件名为{appName}.zip
+	client := s.zimaOSClientFor(target)
+	remoteZipPath := path.Join(remoteAppDataDir, fmt.Sprintf("%s.zip", appName))
+	uploadStart := time.Now()
+	err = client.Upload(ctx, tempZipPath, remoteAppDataDir, fmt.Sprintf("%s.zip", appName), func(transferred, total int64) {
+		s.taskService.ReportTransferProgress(taskID, appName, transferred, total)
+	})
 	if err != nil {
 		return fmt.Errorf("Failed to upload archive: %v", err)
 	}
+	s.recordAppMetrics(taskID, appName, func(m *models.AppMetrics) {
+		m.UploadMs = time.Since(uploadStart).Milliseconds()
+	})
 
 	// 在ZimaOS上解压文件
-	unzipURL := fmt.Sprintf("http://%s:%d/v2_1/files/task/decompress", target.Host, target.Port)
-	err = s.extractFileOnZimaOS(unzipURL, fmt.Sprintf("/media/ZimaOS-HD/AppData/%s.zip", appName), "/media/ZimaOS-HD/AppData", target.Token)
+	decompressStart := time.Now()
+	hb := s.startHeartbeat(taskID, appName, heartbeatPhaseDecompress, "submitted")
+	err = client.Decompress(ctx, remoteZipPath, remoteAppDataDir, func(progress int, status string) {
+		hb.setStatus(fmt.Sprintf("%s %d%%", status, progress))
+		s.taskService.ReportDecompressProgress(taskID, appName, progress)
+	})
+	hb.stop()
+	s.recordAppMetrics(taskID, appName, func(m *models.AppMetrics) {
+		m.DecompressMs = time.Since(decompressStart).Milliseconds()
+	})
 	if err != nil {
 		return fmt.Errorf("Failed to decompress file on ZimaOS: %v", err)
 	}
 
 	// 删除ZimaOS上的临时压缩文件
-	deleteURL := fmt.Sprintf("http://%s:%d/v2_1/files/file", target.Host, target.Port)
-	err = s.deleteFileOnZimaOS(deleteURL, fmt.Sprintf("/media/ZimaOS-HD/AppData/%s.zip", appName), target.Token)
+	err = client.Delete(ctx, remoteZipPath)
 	if err != nil {
 		log.Printf("[WARNING] Failed to delete temporary archive on ZimaOS: %v", err)
 	}
 
+	// 解压API不保留属主，配置了SSH端口时通过SSH恢复
+	s.restoreZimaOSOwnership(ctx, target, remoteAppDataDir, appName, sourcePath, taskID)
+
 	log.Printf("[INFO] App %s data upload completed", appName)
 	return nil
 }
 
+// restoreZimaOSOwnership 通过SSH在ZimaOS上恢复应用数据的属主和权限
+// 未配置SSH时属主清单保留在应用AppData目录中，可手动应用
+func (s *MigrationService) restoreZimaOSOwnership(ctx context.Context, target *models.SystemConnection, remoteAppDataDir, appName, sourcePath, taskID string) {
+	entries := loadOwnership(appDataRoot(sourcePath), sourcePath)
+	if len(entries) == 0 {
+		return
+	}
+
+	remoteDir := path.Join(remoteAppDataDir, appName)
+	if target.SSHPort <= 0 {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: file ownership not restored (no ssh_port configured), see %s/%s", appName, remoteDir, ownershipManifestName))
+		return
+	}
+
+	sshConn := *target
+	sshConn.Port = target.SSHPort
+	if err := applyOwnershipOverSSH(ctx, &sshConn, remoteDir, entries); err != nil {
+		log.Printf("[WARNING] Failed to restore ownership for app %s: %v", appName, err)
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: %v", appName, err))
+		return
+	}
+	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: restored ownership of %d files", appName, len(entries)))
+}
+
 // compressDirectory 压缩目录
 func (s *MigrationService) compressDirectory(sourceDir, zipPath string) error {
 	// 创建ZIP文件
@@ -2345,6 +3115,10 @@ func (s *MigrationService) compressDirectory(sourceDir, zipPath string) error {
 	zipWriter := zip.NewWriter(zipFile)
 	defer zipWriter.Close()
 
+	// 文件内容按块在多个CPU核上并行压缩
+	parallel := newParallelZipWriter(zipWriter, s.cfg.CompressWorkers)
+	defer parallel.close()
+
 	// 遍历源目录
 	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -2357,12 +3131,12 @@ func (s *MigrationService) compressDirectory(sourceDir, zipPath string) error {
 			return err
 		}
 
-		// 跳过根目录
-		if relPath == "." {
+		// 跳过根目录和上级解压留下的属主清单
+		if relPath == "." || relPath == ownershipManifestName {
 			return nil
 		}
 
-		// 创建ZIP条目
+		// 创建ZIP条目（FileInfoHeader会保留权限位）
 		header, err := zip.FileInfoHeader(info)
 		if err != nil {
 			return err
@@ -2372,47 +3146,62 @@ func (s *MigrationService) compressDirectory(sourceDir, zipPath string) error {
 
 		if info.IsDir() {
 			header.Name += "/"
+			return parallel.addEntry(header, nil)
 		}
 
-		writer, err := zipWriter.CreateHeader(header)
-		if err != nil {
-			return err
-		}
-
-		// 如果是文件，复制内容
-		if !info.IsDir() {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
-
-			_, err = io.Copy(writer, file)
+		// 符号链接保存为链接本身，不跟随
+		if isSymlink(info) {
+			linkname, err := os.Readlink(path)
 			if err != nil {
 				return err
 			}
+			header.Method = zip.Store
+			return parallel.addEntry(header, []byte(linkname))
 		}
 
-		return nil
+		// 压缩存储，稀疏文件的空洞和重复的硬链接内容不会按原大小占用空间
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return parallel.addFile(header, file)
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	// ZIP无法可靠保存uid/gid，将属主清单写入压缩包根目录
+	if entries := loadOwnership(appDataRoot(sourceDir), sourceDir); len(entries) > 0 {
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("Failed to serialize ownership manifest: %v", err)
+		}
+		header := &zip.FileHeader{Name: ownershipManifestName, Method: zip.Deflate}
+		if err := parallel.addEntry(header, data); err != nil {
+			return err
+		}
+	}
+
+	if err := parallel.close(); err != nil {
+		return fmt.Errorf("Failed to write ZIP file: %v", err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("Failed to write ZIP file: %v", err)
+	}
+	return nil
 }
 
 // uploadFileToZimaOS 上传文件到ZimaOS
-func (s *MigrationService) uploadFileToZimaOS(uploadURL, filePath, targetPath, filename, token string) error {
+// onProgress 可为nil，用于报告已发送/总字节数
+func (s *MigrationService) uploadFileToZimaOS(ctx context.Context, conn *models.SystemConnection, uploadURL, filePath string, fields map[string]string, filename string, onProgress func(transferred, total int64)) error {
 	// 获取文件信息
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		return fmt.Errorf("Failed to get file info: %v", err)
 	}
 
-	log.Printf("[DEBUG] ========== File Upload Debug ==========")
-	log.Printf("[DEBUG] Local file path: %s", filePath)
-	log.Printf("[DEBUG] File size: %d bytes", fileInfo.Size())
-	log.Printf("[DEBUG] File exists: %t", !os.IsNotExist(err))
-	log.Printf("[DEBUG] Target path: %s", targetPath)
-	log.Printf("[DEBUG] Upload URL: %s", uploadURL)
+	s.debugf(ctx, "Uploading %s (%d bytes) as %s, form fields: %v", filePath, fileInfo.Size(), filename, fields)
 
 	// 打开文件
 	file, err := os.Open(filePath)
@@ -2425,11 +3214,15 @@ func (s *MigrationService) uploadFileToZimaOS(uploadURL, filePath, targetPath, f
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	// 添加path字段
-	writer.WriteField("path", targetPath)
-
-	// 添加rename字段（空字符串）
-	writer.WriteField("rename", "")
+	// 按名称顺序添加表单字段，不同版本的上传接口字段不同
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writer.WriteField(name, fields[name])
+	}
 
 	// 手动创建文件字段以确保正确的Content-Disposition和Content-Type
 	// 使用传入的filename参数而不是原始文件名
@@ -2449,56 +3242,36 @@ func (s *MigrationService) uploadFileToZimaOS(uploadURL, filePath, targetPath, f
 
 	writer.Close()
 
-	// 打印multipart表单信息
-	log.Printf("[DEBUG] Multipart Content-Type: %s", writer.FormDataContentType())
-	log.Printf("[DEBUG] Request body size: %d bytes", body.Len())
-	log.Printf("[DEBUG] Form fields: path=%s, rename=\"\", file=%s", targetPath, filename)
-	log.Printf("[DEBUG] File field Content-Disposition: form-data; name=\"file\"; filename=\"%s\"", filename)
-	log.Printf("[DEBUG] File field Content-Type: application/zip")
-
-	// 创建HTTP请求 - 使用bytes.NewReader
-	req, err := http.NewRequest("POST", uploadURL, bytes.NewReader(body.Bytes()))
+	// 创建HTTP请求 - 使用bytes.NewReader，并统计已发送字节数
+	bodyLen := int64(body.Len())
+	req, err := newConnRequest(ctx, conn, "POST", uploadURL, newProgressReader(bytes.NewReader(body.Bytes()), bodyLen, onProgress))
 	if err != nil {
 		return fmt.Errorf("Failed to create upload request: %v", err)
 	}
+	req.ContentLength = bodyLen
+	// 重试时重新读取请求体，覆盖上传同一文件可以安全重发
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(newProgressReader(bytes.NewReader(body.Bytes()), bodyLen, onProgress)), nil
+	}
+	req = markIdempotent(req)
 
 	// 设置请求头
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", token)
+	req.Header.Set("Authorization", conn.Token)
 
-	// 打印请求头信息
-	log.Printf("[DEBUG] ========== Request Headers ==========")
-	for key, values := range req.Header {
-		for _, value := range values {
-			log.Printf("[DEBUG] %s: %s", key, value)
-		}
-	}
+	// 记录请求，认证头不输出
+	s.debugf(ctx, "Upload request: POST %s, body %d bytes, headers: %s", redactURL(req.URL), bodyLen, formatHeaders(req.Header))
 
 	// 发送请求
-	log.Printf("[DEBUG] Sending HTTP request...")
-	resp, err := s.client.Do(req)
+	resp, err := s.uploadClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("Failed to send upload request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// 打印响应头信息
-	log.Printf("[DEBUG] ========== Response Info ==========")
-	log.Printf("[DEBUG] Status Code: %d", resp.StatusCode)
-	log.Printf("[DEBUG] Status: %s", resp.Status)
-	log.Printf("[DEBUG] ========== Response Headers ==========")
-	for key, values := range resp.Header {
-		for _, value := range values {
-			log.Printf("[DEBUG] %s: %s", key, value)
-		}
-	}
-
 	// 读取响应体以获取详细错误信息
 	respBody, _ := io.ReadAll(resp.Body)
-	log.Printf("[DEBUG] ========== Response Body ==========")
-	log.Printf("[DEBUG] Body: %s", string(respBody))
-	log.Printf("[DEBUG] Body Length: %d bytes", len(respBody))
-	log.Printf("[DEBUG] ========================================")
+	s.debugf(ctx, "Upload response: %s, headers: %s, body (%d bytes): %s", resp.Status, formatHeaders(resp.Header), len(respBody), redactExcerpt(respBody))
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("Upload failed, status code: %d, response: %s", resp.StatusCode, string(respBody))
@@ -2507,8 +3280,9 @@ func (s *MigrationService) uploadFileToZimaOS(uploadURL, filePath, targetPath, f
 	return nil
 }
 
-// extractFileOnZimaOS 在ZimaOS上解压文件
-func (s *MigrationService) extractFileOnZimaOS(extractURL, zipPath, targetDir, token string) error {
+// extractFileOnZimaOS 在ZimaOS上提交解压任务，返回文件任务ID，响应中没有ID时返回空字符串
+// 解压是异步执行的，调用方需要等待任务完成
+func (s *MigrationService) extractFileOnZimaOS(ctx context.Context, conn *models.SystemConnection, extractURL, zipPath, targetDir string) (string, error) {
 	// 构建请求体 - 使用新的API格式
 	requestBody := map[string]interface{}{
 		"src":             []string{zipPath},
@@ -2519,35 +3293,42 @@ func (s *MigrationService) extractFileOnZimaOS(extractURL, zipPath, targetDir, t
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return fmt.Errorf("Failed to serialize request data: %v", err)
+		return "", fmt.Errorf("Failed to serialize request data: %v", err)
 	}
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", extractURL, strings.NewReader(string(jsonData)))
+	req, err := newConnRequest(ctx, conn, "POST", extractURL, strings.NewReader(string(jsonData)))
 	if err != nil {
-		return fmt.Errorf("Failed to create decompression request: %v", err)
+		return "", fmt.Errorf("Failed to create decompression request: %v", err)
 	}
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", token)
+	req.Header.Set("Authorization", conn.Token)
 
 	// 发送请求
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("Failed to send decompression request: %v", err)
+		return "", fmt.Errorf("Failed to send decompression request: %v", err)
 	}
 	defer resp.Body.Close()
 
+	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Decompression failed, status code: %d", resp.StatusCode)
+		return "", fmt.Errorf("Decompression failed, status code: %d, response: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	var result struct {
+		Data interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", nil
+	}
+	return fileTaskID(result.Data), nil
 }
 
 // deleteFileOnZimaOS 删除ZimaOS上的文件
-func (s *MigrationService) deleteFileOnZimaOS(deleteURL, filePath, token string) error {
+func (s *MigrationService) deleteFileOnZimaOS(ctx context.Context, conn *models.SystemConnection, deleteURL, filePath string) error {
 	// 构建请求体 - 使用新的API格式，支持批量删除
 	requestBody := []string{filePath}
 
@@ -2556,19 +3337,17 @@ func (s *MigrationService) deleteFileOnZimaOS(deleteURL, filePath, token string)
 		return fmt.Errorf("Failed to serialize request data: %v", err)
 	}
 
-	log.Printf("[DEBUG] Delete file on ZimaOS:")
-	log.Printf("[DEBUG] URL: %s", deleteURL)
-	log.Printf("[DEBUG] Body: %s", string(jsonData))
+	s.debugf(ctx, "Delete request: DELETE %s, body: %s", deleteURL, string(jsonData))
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("DELETE", deleteURL, strings.NewReader(string(jsonData)))
+	req, err := newConnRequest(ctx, conn, "DELETE", deleteURL, strings.NewReader(string(jsonData)))
 	if err != nil {
 		return fmt.Errorf("Failed to create delete request: %v", err)
 	}
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", token)
+	req.Header.Set("Authorization", conn.Token)
 
 	// 发送请求
 	resp, err := s.client.Do(req)
@@ -2578,8 +3357,7 @@ func (s *MigrationService) deleteFileOnZimaOS(deleteURL, filePath, token string)
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
-	log.Printf("[DEBUG] Delete response status: %d", resp.StatusCode)
-	log.Printf("[DEBUG] Delete response body: %s", string(body))
+	s.debugf(ctx, "Delete response: %s, body: %s", resp.Status, redactExcerpt(body))
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("Delete failed, status code: %d, response: %s", resp.StatusCode, string(body))
@@ -2588,15 +3366,25 @@ func (s *MigrationService) deleteFileOnZimaOS(deleteURL, filePath, token string)
 	return nil
 }
 
-// CreateDirectExport 直接创建导出压缩包文件
-func (s *MigrationService) CreateDirectExport(sourceConn *models.SystemConnection) (string, error) {
+// CreateDirectExport 直接创建导出压缩包文件，options中的include和exclude用于筛选导出内容
+func (s *MigrationService) CreateDirectExport(ctx context.Context, sourceConn *models.SystemConnection, options map[string]interface{}) (string, error) {
+	filter, err := parseExportFilter(options)
+	if err != nil {
+		return "", err
+	}
+
 	// 测试源系统连接
-	testResp, err := s.connService.TestConnection(sourceConn)
+	err = s.testExportSource(ctx, sourceConn)
 	var downloadedFilePath string
+	demo := false
 
-	if err != nil || !testResp.Success {
-		// 连接失败时使用模拟数据进行演示
-		log.Printf("[DirectExport] Connection failed; using mock data: %v", err)
+	if err != nil {
+		// 只有明确开启演示模式时才使用模拟数据，否则导出失败
+		if !s.cfg.DemoMode {
+			return "", err
+		}
+		log.Printf("[WARNING] [DirectExport] %v; demo mode is enabled, exporting mock data", err)
+		demo = true
 		// 创建一个模拟的下载文件
 		downloadedFilePath, err = s.createMockDownloadFile()
 		if err != nil {
@@ -2608,16 +3396,22 @@ func (s *MigrationService) CreateDirectExport(sourceConn *models.SystemConnectio
 			log.Printf("[DirectExport] %d%% - %s", progress, message)
 		}
 
-		downloadedFilePath, err = s.downloadCasaOSFiles(sourceConn, progressCallback)
+		// 开启transfer_images时先在源上保存镜像，随AppData下载后写入压缩包的images目录
+		if transferImages(options) {
+			imageDirs := s.saveSourceImages(ctx, "", sourceConn)
+			defer s.removeSourceDumps(ctx, sourceConn, imageDirs)
+		}
+
+		downloadedFilePath, err = s.downloadCasaOSFiles(ctx, sourceConn, progressCallback)
 		if err != nil {
 			return "", fmt.Errorf("Failed to download CasaOS files: %v", err)
 		}
 	}
 
-	// 导出应用数据（用于metadata）
-	apps, err := s.getSystemApps(sourceConn)
+	// 导出应用数据（用于metadata），获取失败时仅缺少应用元数据
+	apps, err := s.getSystemApps(ctx, sourceConn)
 	if err != nil {
-		return "", fmt.Errorf("Failed to export application data: %v", err)
+		log.Printf("[DirectExport] Failed to fetch app list: %v", err)
 	}
 
 	// 导出系统设置
@@ -2639,14 +3433,27 @@ func (s *MigrationService) CreateDirectExport(sourceConn *models.SystemConnectio
 		"userData":  userData,
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
+	if demo {
+		exportData["demo"] = true
+	}
 
 	// 创建包含实际文件的导出压缩包
 	taskID := fmt.Sprintf("direct_%d", time.Now().Unix())
-	filePath, err := s.createDirectExportFile(taskID, exportData, downloadedFilePath)
+	filePath, err := s.createDirectExportFile(taskID, exportData, downloadedFilePath, filter, transferImages(options) && !demo)
 	if err != nil {
 		return "", fmt.Errorf("Failed to create export file: %v", err)
 	}
 
+	// 演示数据的文件名加上前缀，避免被当作真实导出
+	if demo {
+		demoPath := filepath.Join(filepath.Dir(filePath), demoExportPrefix+filepath.Base(filePath))
+		if err := os.Rename(filePath, demoPath); err != nil {
+			os.Remove(filePath)
+			return "", fmt.Errorf("Failed to create export file: %v", err)
+		}
+		filePath = demoPath
+	}
+
 	// 清理临时下载文件
 	os.Remove(downloadedFilePath)
 
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/models"
+)
+
+// Notifier 通知渠道接口
+type Notifier interface {
+	// Name 渠道名称，用于日志
+	Name() string
+	// Send 发送任务完成通知
+	Send(n *TaskNotification) error
+}
+
+// TaskNotification 任务完成通知内容
+type TaskNotification struct {
+	TaskID      string
+	TaskName    string
+	TaskType    string
+	Status      string
+	Summary     models.ImportSummary
+	FailedApps  []string
+	Duration    time.Duration
+	TaskURL     string
+	CompletedAt time.Time
+}
+
+// Subject 通知标题
+func (n *TaskNotification) Subject() string {
+	return fmt.Sprintf("[CtoZ] %s migration %s (%d/%d apps succeeded)", n.TaskType, n.Status, n.Summary.SuccessApps, n.Summary.TotalApps)
+}
+
+// Body 通知正文（纯文本）
+func (n *TaskNotification) Body() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Task: %s\n", n.TaskID)
+	if n.TaskName != "" {
+		fmt.Fprintf(&b, "Name: %s\n", n.TaskName)
+	}
+	fmt.Fprintf(&b, "Type: %s\n", n.TaskType)
+	fmt.Fprintf(&b, "Status: %s\n", n.Status)
+	fmt.Fprintf(&b, "Duration: %s\n", n.Duration.Round(time.Second))
+	fmt.Fprintf(&b, "Apps: %d total, %d succeeded, %d failed, %d skipped\n", n.Summary.TotalApps, n.Summary.SuccessApps, n.Summary.FailedApps, n.Summary.SkippedApps)
+	if n.Summary.WarningApps > 0 {
+		fmt.Fprintf(&b, "Apps needing attention: %d\n", n.Summary.WarningApps)
+	}
+	if len(n.FailedApps) > 0 {
+		fmt.Fprintf(&b, "Failed apps: %s\n", strings.Join(n.FailedApps, ", "))
+	}
+	fmt.Fprintf(&b, "Details: %s\n", n.TaskURL)
+	return b.String()
+}
+
+// NotificationService 通知服务
+type NotificationService struct {
+	notifiers []Notifier
+	publicURL string
+}
+
+// NewNotificationService 创建新的通知服务，按配置启用可用的通知渠道
+func NewNotificationService(cfg *config.Config) *NotificationService {
+	s := &NotificationService{
+		publicURL: cfg.PublicURL,
+	}
+
+	if cfg.SMTP.Enabled() {
+		s.notifiers = append(s.notifiers, NewEmailNotifier(cfg.SMTP))
+		log.Printf("[INFO] Email notifications enabled via %s:%d", cfg.SMTP.Host, cfg.SMTP.Port)
+	}
+
+	if cfg.TelegramBotToken != "" && cfg.TelegramChatID != "" {
+		s.notifiers = append(s.notifiers, NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID))
+		log.Printf("[INFO] Telegram notifications enabled")
+	}
+
+	if cfg.DiscordWebhookURL != "" {
+		s.notifiers = append(s.notifiers, NewDiscordNotifier(cfg.DiscordWebhookURL))
+		log.Printf("[INFO] Discord notifications enabled")
+	}
+
+	return s
+}
+
+// NotifyTaskFinished 任务结束时向所有已启用的渠道发送摘要通知
+func (s *NotificationService) NotifyTaskFinished(task *models.MigrationTask, appStatuses []models.AppImportStatus) {
+	if s == nil || len(s.notifiers) == 0 || task == nil {
+		return
+	}
+
+	notification := &TaskNotification{
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		TaskType:    task.Type,
+		Status:      task.Status,
+		Duration:    time.Since(task.CreatedAt),
+		TaskURL:     fmt.Sprintf("%s/status/%s", s.publicURL, task.ID),
+		CompletedAt: time.Now(),
+	}
+
+	notification.Summary.TotalApps = len(appStatuses)
+	for _, app := range appStatuses {
+		if app.OverallStatus == models.AppStatusSuccess {
+			notification.Summary.SuccessApps++
+		} else if app.OverallStatus == models.AppStatusSkipped {
+			notification.Summary.SkippedApps++
+		} else if app.OverallStatus == models.AppStatusWarning {
+			notification.Summary.WarningApps++
+		} else {
+			notification.Summary.FailedApps++
+			notification.FailedApps = append(notification.FailedApps, app.AppName)
+		}
+	}
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.Send(notification); err != nil {
+			log.Printf("[WARNING] Failed to send %s notification for task %s: %v", notifier.Name(), task.ID, err)
+			continue
+		}
+		log.Printf("[INFO] Sent %s notification for task %s", notifier.Name(), task.ID)
+	}
+}
+
+// EmailNotifier SMTP邮件通知渠道
+type EmailNotifier struct {
+	cfg config.SMTPConfig
+}
+
+// NewEmailNotifier 创建邮件通知渠道
+func NewEmailNotifier(cfg config.SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg}
+}
+
+// Name 渠道名称
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Send 发送邮件通知
+func (e *EmailNotifier) Send(n *TaskNotification) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.CompletedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Body(), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+
+	// 465端口使用隐式TLS，其余端口由smtp.SendMail自动协商STARTTLS
+	if e.cfg.Port == 465 {
+		return e.sendImplicitTLS(addr, auth, []byte(msg.String()))
+	}
+	return smtp.SendMail(addr, auth, e.cfg.From, e.cfg.To, []byte(msg.String()))
+}
+
+// sendImplicitTLS 通过隐式TLS连接发送邮件
+func (e *EmailNotifier) sendImplicitTLS(addr string, auth smtp.Auth, msg []byte) error {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: e.cfg.Host})
+	if err != nil {
+		return fmt.Errorf("Failed to connect to SMTP server: %v", err)
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Failed to create SMTP client: %v", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+	if err := client.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("SMTP MAIL command failed: %v", err)
+	}
+	for _, to := range e.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT command failed for %s: %v", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA command failed: %v", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return fmt.Errorf("Failed to write email body: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("Failed to finish email body: %v", err)
+	}
+	return client.Quit()
+}
+
+// TelegramNotifier Telegram机器人通知渠道
+type TelegramNotifier struct {
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+// NewTelegramNotifier 创建Telegram通知渠道
+func NewTelegramNotifier(botToken, chatID string) *TelegramNotifier {
+	return &TelegramNotifier{
+		botToken: botToken,
+		chatID:   chatID,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 渠道名称
+func (t *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Send 通过Bot API发送消息
+func (t *TelegramNotifier) Send(n *TaskNotification) error {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.botToken)
+	payload := map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     n.Subject() + "\n\n" + n.Body(),
+		"disable_web_page_preview": true,
+	}
+	return postJSON(t.client, apiURL, payload)
+}
+
+// DiscordNotifier Discord Webhook通知渠道
+type DiscordNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewDiscordNotifier 创建Discord通知渠道
+func NewDiscordNotifier(webhookURL string) *DiscordNotifier {
+	return &DiscordNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 渠道名称
+func (d *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// Send 通过Webhook发送嵌入消息
+func (d *DiscordNotifier) Send(n *TaskNotification) error {
+	// 成功为绿色，失败为红色
+	color := 0x2ECC71
+	if n.Status != string(models.TaskStatusCompleted) || n.Summary.FailedApps > 0 {
+		color = 0xE74C3C
+	}
+
+	payload := map[string]interface{}{
+		"username": "CtoZ",
+		"embeds": []map[string]interface{}{
+			{
+				"title":       n.Subject(),
+				"description": n.Body(),
+				"url":         n.TaskURL,
+				"color":       color,
+				"timestamp":   n.CompletedAt.Format(time.RFC3339),
+			},
+		},
+	}
+	return postJSON(d.client, d.webhookURL, payload)
+}
+
+// postJSON 发送JSON请求并检查响应状态
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize payload: %v", err)
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Unexpected status code: %d, response: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512" // 注册SHA-384/SHA-512
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/config"
+)
+
+const (
+	// oidcMetadataTTL 发现文档和签名公钥的缓存时间
+	oidcMetadataTTL = time.Hour
+	// oidcClockSkew 校验令牌有效期时允许的时钟偏差
+	oidcClockSkew = time.Minute
+)
+
+// oidcLogin 进行中的OIDC登录，state对应的nonce和PKCE校验码
+type oidcLogin struct {
+	nonce    string
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// oidcDiscovery OIDC发现文档中用到的字段
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider 通用OIDC身份提供方，使用授权码模式（PKCE）并校验ID令牌的签名
+type oidcProvider struct {
+	cfg         config.OIDCConfig
+	redirectURL string
+	scopes      []string
+	client      *retryClient
+
+	mu           sync.Mutex
+	discovery    *oidcDiscovery
+	keys         map[string]crypto.PublicKey
+	discoveredAt time.Time
+}
+
+// newOIDCProvider 创建OIDC身份提供方，未配置回调地址时使用 PublicURL/api/auth/oidc/callback
+func newOIDCProvider(cfg config.OIDCConfig, publicURL string, client *retryClient) *oidcProvider {
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" {
+		redirectURL = publicURL + "/api/auth/oidc/callback"
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	return &oidcProvider{cfg: cfg, redirectURL: redirectURL, scopes: scopes, client: client}
+}
+
+// authCodeURL 生成跳转到身份提供方的授权地址
+func (p *oidcProvider) authCodeURL(state, nonce, verifier string) (string, error) {
+	discovery, err := p.metadata()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {strings.Join(p.scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// exchange 用授权码换取令牌，校验ID令牌后返回用户名
+func (p *oidcProvider) exchange(code, nonce, verifier string) (string, error) {
+	discovery, err := p.metadata()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest("POST", discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("Failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.getJSON(req, &token); err != nil {
+		return "", fmt.Errorf("Token request failed: %v", err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("Token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("Token response contains no ID token")
+	}
+
+	claims, err := p.verifyIDToken(token.IDToken, nonce)
+	if err != nil {
+		return "", err
+	}
+	if len(p.cfg.AllowedGroups) > 0 {
+		groups := claimStrings(claims["groups"])
+		if groups == nil && token.AccessToken != "" && discovery.UserinfoEndpoint != "" {
+			// 部分身份提供方只在userinfo中返回groups
+			groups = p.userinfoGroups(discovery.UserinfoEndpoint, token.AccessToken)
+		}
+		if !containsAny(groups, p.cfg.AllowedGroups) {
+			return "", fmt.Errorf("User is not a member of an allowed group")
+		}
+	}
+
+	for _, claim := range []string{p.cfg.UsernameClaim, "preferred_username", "email", "sub"} {
+		if name, _ := claims[claim].(string); claim != "" && name != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("ID token contains no user name")
+}
+
+// verifyIDToken 校验ID令牌的签名、签发方、受众、有效期和nonce，返回令牌中的声明
+func (p *oidcProvider) verifyIDToken(rawToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("Malformed ID token header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Malformed ID token signature: %v", err)
+	}
+
+	key, err := p.signingKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("Malformed ID token claims: %v", err)
+	}
+
+	discovery, err := p.metadata()
+	if err != nil {
+		return nil, err
+	}
+	if iss, _ := claims["iss"].(string); iss != discovery.Issuer {
+		return nil, fmt.Errorf("ID token issuer %q does not match %q", iss, discovery.Issuer)
+	}
+	if !containsAny(claimStrings(claims["aud"]), []string{p.cfg.ClientID}) {
+		return nil, fmt.Errorf("ID token was not issued for this client")
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Add(-oidcClockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("ID token has expired")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("ID token nonce does not match")
+	}
+	return claims, nil
+}
+
+// metadata 返回缓存的发现文档，过期或尚未获取时重新获取发现文档和签名公钥
+func (p *oidcProvider) metadata() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.discoveredAt) < oidcMetadataTTL {
+		return p.discovery, nil
+	}
+	if err := p.refreshLocked(); err != nil {
+		return nil, err
+	}
+	return p.discovery, nil
+}
+
+// signingKey 按kid查找签名公钥，找不到时重新获取一次（身份提供方可能轮换了密钥）
+func (p *oidcProvider) signingKey(kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lookup := func() crypto.PublicKey {
+		if key, ok := p.keys[kid]; ok {
+			return key
+		}
+		// 令牌未指定kid且只有一个公钥时使用该公钥
+		if kid == "" && len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key
+			}
+		}
+		return nil
+	}
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	if err := p.refreshLocked(); err != nil {
+		return nil, err
+	}
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("No signing key found for ID token (kid %q)", kid)
+}
+
+// refreshLocked 获取发现文档和JWKS，调用时需持有锁
+func (p *oidcProvider) refreshLocked() error {
+	req, err := http.NewRequest("GET", p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create discovery request: %v", err)
+	}
+	var discovery oidcDiscovery
+	if err := p.getJSON(req, &discovery); err != nil {
+		return fmt.Errorf("OIDC discovery failed: %v", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return fmt.Errorf("OIDC discovery document is missing required endpoints")
+	}
+
+	req, err = http.NewRequest("GET", discovery.JWKSURI, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create JWKS request: %v", err)
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(req, &jwks); err != nil {
+		return fmt.Errorf("Failed to get OIDC signing keys: %v", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("OIDC provider published no usable signing keys")
+	}
+
+	p.discovery = &discovery
+	p.keys = keys
+	p.discoveredAt = time.Now()
+	return nil
+}
+
+// userinfoGroups 从userinfo接口读取用户组，失败时返回nil
+func (p *oidcProvider) userinfoGroups(endpoint, accessToken string) []string {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	var info map[string]interface{}
+	if err := p.getJSON(req, &info); err != nil {
+		return nil
+	}
+	return claimStrings(info["groups"])
+}
+
+// getJSON 发送请求并解析JSON响应，令牌接口的错误响应也按JSON解析
+func (p *oidcProvider) getJSON(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("Invalid JSON response (HTTP %d): %v", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// jsonWebKey JWKS中的公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey 将JWK转换为RSA或ECDSA公钥
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("Unsupported key type %s", k.Kty)
+}
+
+// verifyJWTSignature 按算法校验JWT签名，支持RS256/384/512和ES256/384/512
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("Unsupported ID token algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("Unsupported ID token algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Signing key does not match algorithm %s", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+			return fmt.Errorf("Invalid ID token signature")
+		}
+		return nil
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Signing key does not match algorithm %s", alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("Invalid ID token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("Invalid ID token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported ID token algorithm %q", alg)
+}
+
+// decodeJWTPart 解码JWT的头部或载荷
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimStrings 将字符串或字符串数组形式的声明统一为数组
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// containsAny 判断values中是否包含wanted中的任意一个
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// ownershipManifestName 记录文件属主和权限的附属清单，解压时写入解压根目录，压缩时写入应用压缩包根目录
+const ownershipManifestName = ".ctoz-ownership.json"
+
+// maxOwnershipManifestBytes 压缩包中属主清单的最大读取大小
+const maxOwnershipManifestBytes = 64 << 20
+
+// ZIP中与Unix属性相关的常量
+const (
+	zipCreatorUnix = 3      // 由Unix系统创建（外部属性高16位为st_mode）
+	zipUnixExtraID = 0x7875 // Info-ZIP "New Unix" 扩展字段（保存uid/gid）
+)
+
+// fileAttr 单个文件的属主和权限
+type fileAttr struct {
+	Path string `json:"path"` // 相对路径，使用/分隔
+	UID  int    `json:"uid"`
+	GID  int    `json:"gid"`
+	Mode uint32 `json:"mode"` // Unix权限位（含setuid/setgid/sticky）
+}
+
+// attrRecorder 在解压过程中恢复权限并记录属主
+// 目录权限在全部解压完成后再设置，避免只读目录导致后续文件无法写入
+type attrRecorder struct {
+	dest     string
+	entries  []fileAttr
+	dirModes map[string]os.FileMode
+	isRoot   bool
+}
+
+// newAttrRecorder 创建属主记录器
+func newAttrRecorder(dest string) *attrRecorder {
+	return &attrRecorder{
+		dest:     dest,
+		dirModes: make(map[string]os.FileMode),
+		isRoot:   os.Geteuid() == 0,
+	}
+}
+
+// record 恢复文件权限，有属主信息时记录并在root运行时直接chown
+func (r *attrRecorder) record(target string, mode os.FileMode, uid, gid int, hasOwner bool) {
+	isLink := mode&os.ModeSymlink != 0
+	if mode.IsDir() {
+		r.dirModes[target] = mode
+	} else if !isLink {
+		if err := os.Chmod(target, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			log.Printf("[WARNING] Failed to set file permissions: %s - %v", target, err)
+		}
+	}
+
+	if !hasOwner {
+		return
+	}
+	if r.isRoot {
+		if err := os.Lchown(target, uid, gid); err != nil {
+			log.Printf("[WARNING] Failed to set file owner: %s - %v", target, err)
+		}
+	}
+
+	relPath, err := filepath.Rel(r.dest, target)
+	if err != nil {
+		return
+	}
+	r.entries = append(r.entries, fileAttr{
+		Path: filepath.ToSlash(relPath),
+		UID:  uid,
+		GID:  gid,
+		Mode: unixMode(mode),
+	})
+}
+
+// recordZipAttrs 记录ZIP条目的权限和属主
+// 非Unix创建的ZIP没有可靠的权限位，此时仅在存在属主扩展字段时记录
+func recordZipAttrs(r *attrRecorder, target string, f *zip.File) {
+	uid, gid, hasOwner := zipUnixOwner(f.Extra)
+	if f.CreatorVersion>>8 != zipCreatorUnix && !hasOwner {
+		return
+	}
+	r.record(target, f.Mode(), uid, gid, hasOwner)
+}
+
+// finish 设置目录权限并保存属主清单
+func (r *attrRecorder) finish() error {
+	// 先处理最深的目录
+	dirs := make([]string, 0, len(r.dirModes))
+	for dir := range r.dirModes {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, dir := range dirs {
+		if err := os.Chmod(dir, r.dirModes[dir]&(os.ModePerm|os.ModeSetgid|os.ModeSticky)); err != nil {
+			log.Printf("[WARNING] Failed to set directory permissions: %s - %v", dir, err)
+		}
+	}
+
+	if len(r.entries) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(r.entries)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize ownership manifest: %v", err)
+	}
+	return os.WriteFile(filepath.Join(r.dest, ownershipManifestName), data, 0600)
+}
+
+// adoptManifest 接收压缩包根目录中随导出一起打包的属主清单（ZIP无法保存uid/gid），
+// 只保留路径合法的条目，与解压时记录的属主一起由finish写入；子目录中的清单直接丢弃
+func (r *attrRecorder) adoptManifest(data []byte) {
+	var entries []fileAttr
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("[WARNING] Ignoring invalid ownership manifest in archive: %v", err)
+		return
+	}
+	// 放在解压时记录的条目之前，同一路径以解压时记录的属主为准
+	r.entries = append(sanitizeOwnership(entries), r.entries...)
+}
+
+// skipArchiveManifest 压缩包中的属主清单不按普通文件解压，只有解压器能写入属主清单：
+// 根目录的清单交给attrs合并，子目录中的清单直接丢弃；返回true表示条目已处理
+func skipArchiveManifest(attrs *attrRecorder, name string, open func() (io.ReadCloser, error)) bool {
+	manifest, root := isOwnershipManifest(name)
+	if !manifest {
+		return false
+	}
+	if !root {
+		log.Printf("[WARNING] Skipping ownership manifest in archive: %s", name)
+		return true
+	}
+	rc, err := open()
+	if err != nil {
+		log.Printf("[WARNING] Failed to read ownership manifest in archive: %v", err)
+		return true
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxOwnershipManifestBytes))
+	if err != nil {
+		log.Printf("[WARNING] Failed to read ownership manifest in archive: %v", err)
+		return true
+	}
+	attrs.adoptManifest(data)
+	return true
+}
+
+// sanitizeExtractedManifests 处理由外部工具（7z）解压出的属主清单：删除子目录中的清单，
+// 根目录的清单只保留路径合法的条目
+func sanitizeExtractedManifests(dest string) error {
+	return filepath.Walk(dest, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() != ownershipManifestName {
+			return nil
+		}
+		if filepath.Dir(p) != filepath.Clean(dest) || !info.Mode().IsRegular() {
+			log.Printf("[WARNING] Removing ownership manifest from archive: %s", p)
+			return os.Remove(p)
+		}
+		attrs := newAttrRecorder(dest)
+		if !skipArchiveManifest(attrs, ownershipManifestName, func() (io.ReadCloser, error) { return os.Open(p) }) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		return attrs.finish()
+	})
+}
+
+// isOwnershipManifest 压缩包条目是否为属主清单，root为true时表示位于压缩包根目录
+func isOwnershipManifest(name string) (manifest, root bool) {
+	cleaned := path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "./"))
+	return path.Base(cleaned) == ownershipManifestName, cleaned == ownershipManifestName
+}
+
+// validOwnershipPath 属主清单中的路径必须是不含..的相对路径，避免chown/chmod作用到应用目录之外
+func validOwnershipPath(p string) bool {
+	if p == "" || path.IsAbs(filepath.ToSlash(p)) {
+		return false
+	}
+	for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeOwnership 去掉路径不合法的属主条目
+func sanitizeOwnership(entries []fileAttr) []fileAttr {
+	result := make([]fileAttr, 0, len(entries))
+	for _, entry := range entries {
+		if !validOwnershipPath(entry.Path) {
+			log.Printf("[WARNING] Ignoring ownership entry with unsafe path: %s", entry.Path)
+			continue
+		}
+		entry.Path = path.Clean(entry.Path)
+		result = append(result, entry)
+	}
+	return result
+}
+
+// appDataRoot 返回应用数据目录所在的解压根目录，应用数据按CasaOS布局位于<root>/DATA/AppData/<app>，
+// 不符合该布局时返回目录本身
+func appDataRoot(appDir string) string {
+	parent := filepath.Dir(appDir)
+	if filepath.Base(parent) == "AppData" && filepath.Base(filepath.Dir(parent)) == "DATA" {
+		return filepath.Dir(filepath.Dir(parent))
+	}
+	return appDir
+}
+
+// loadOwnership 在dir到解压根目录root之间查找属主清单，返回dir内文件的属主（路径相对于dir）
+// 不会查找root之外的目录，路径不合法的条目被忽略
+func loadOwnership(root, dir string) []fileAttr {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil || !isWithinDir(absRoot, absDir) {
+		return nil
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		data, err := os.ReadFile(filepath.Join(current, ownershipManifestName))
+		if err == nil {
+			var entries []fileAttr
+			if err := json.Unmarshal(data, &entries); err != nil {
+				log.Printf("[WARNING] Invalid ownership manifest in %s: %v", current, err)
+				return nil
+			}
+			entries = sanitizeOwnership(entries)
+
+			prefix, err := filepath.Rel(current, absDir)
+			if err != nil {
+				return nil
+			}
+			prefix = filepath.ToSlash(prefix)
+
+			var result []fileAttr
+			for _, entry := range entries {
+				if prefix == "." {
+					result = append(result, entry)
+				} else if entry.Path == prefix {
+					entry.Path = "."
+					result = append(result, entry)
+				} else if strings.HasPrefix(entry.Path, prefix+"/") {
+					entry.Path = strings.TrimPrefix(entry.Path, prefix+"/")
+					result = append(result, entry)
+				}
+			}
+			return result
+		}
+
+		if current == absRoot {
+			return nil
+		}
+	}
+}
+
+// applyOwnershipOverSSH 通过SSH在远端目录上恢复文件属主和权限
+// 每个条目的父目录先在远端解析符号链接，解析后不在baseDir之内的条目跳过，防止经由压缩包中的符号链接修改目录之外的文件
+func applyOwnershipOverSSH(ctx context.Context, conn *models.SystemConnection, baseDir string, entries []fileAttr) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// 非root用户通过sudo执行
+	shell := "sh"
+	if conn.Username != "root" {
+		shell = "sudo -n sh"
+	}
+	output, err := runSSH(ctx, conn, strings.NewReader(ownershipScript(baseDir, entries)), shell)
+	if err != nil {
+		return fmt.Errorf("Failed to apply ownership: %v", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if skipped := strings.TrimPrefix(line, "skipped "); skipped != line {
+			log.Printf("[WARNING] Ownership of %s not applied: path leads outside %s through a symlink", skipped, baseDir)
+		}
+	}
+	return nil
+}
+
+// ownershipScript 生成在远端恢复属主和权限的shell脚本，跳过的条目输出为 "skipped <path>"
+func ownershipScript(baseDir string, entries []fileAttr) string {
+	// cd -P/pwd -P 在没有realpath -e 的精简系统上同样可用
+	var script strings.Builder
+	fmt.Fprintf(&script, "base=$(cd -P -- %s && pwd -P) || exit 1\n", shellQuote(baseDir))
+	script.WriteString(`apply() {
+	dir=$(cd -P -- "$base/$3" 2>/dev/null && pwd -P) || return 0
+	case "$dir/" in "$base"/*) ;; *) echo "skipped $3/$4"; return 0 ;; esac
+	chown -h "$1" "$dir/$4"
+	[ -L "$dir/$4" ] || chmod "$2" "$dir/$4"
+}
+`)
+	for _, entry := range sanitizeOwnership(entries) {
+		owner := fmt.Sprintf("%d:%d", entry.UID, entry.GID)
+		if entry.Path == "." {
+			fmt.Fprintf(&script, "chown -h %s \"$base\"\nchmod %04o \"$base\"\n", owner, entry.Mode)
+			continue
+		}
+		fmt.Fprintf(&script, "apply %s %04o %s %s\n", owner, entry.Mode, shellQuote(path.Dir(entry.Path)), shellQuote(path.Base(entry.Path)))
+	}
+	return script.String()
+}
+
+// unixMode 将Go的FileMode转换为Unix权限位
+func unixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
+
+// zipUnixOwner 从ZIP扩展字段中读取uid/gid
+func zipUnixOwner(extra []byte) (int, int, bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return 0, 0, false
+		}
+		field := extra[4 : 4+size]
+		extra = extra[4+size:]
+
+		// 格式: version(1) uidSize(1) uid gidSize(1) gid
+		if id != zipUnixExtraID || len(field) < 2 || field[0] != 1 {
+			continue
+		}
+		uid, rest, ok := readZipUnixID(field[1:])
+		if !ok {
+			return 0, 0, false
+		}
+		gid, _, ok := readZipUnixID(rest)
+		if !ok {
+			return 0, 0, false
+		}
+		return uid, gid, true
+	}
+	return 0, 0, false
+}
+
+// readZipUnixID 读取变长的小端序ID
+func readZipUnixID(b []byte) (int, []byte, bool) {
+	if len(b) < 1 {
+		return 0, nil, false
+	}
+	size := int(b[0])
+	if size > 8 || len(b) < 1+size {
+		return 0, nil, false
+	}
+	var id uint64
+	for i := size - 1; i >= 0; i-- {
+		id = id<<8 | uint64(b[1+i])
+	}
+	return int(id), b[1+size:], true
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
+	"io"
+	"math"
+	"sync"
+)
+
+const (
+	// parallelZipChunkSize 并行压缩时每个数据块的大小
+	parallelZipChunkSize = 1 << 20
+	// parallelZipLevel 压缩级别，与archive/zip默认的deflate级别一致
+	parallelZipLevel = 5
+	// zipDataDescriptorFlag ZIP通用标志位3：CRC和大小写在条目数据之后的数据描述符中
+	zipDataDescriptorFlag = 0x8
+	// zipVersion45 使用ZIP64扩展的条目所需的解压版本
+	zipVersion45 = 45
+)
+
+// deflateFinalBlock 空的最终deflate块（固定哈夫曼编码，只有结束码）
+// 各数据块以sync flush结束、按字节对齐，拼接后再接上该块即为完整的deflate流
+var deflateFinalBlock = []byte{0x03, 0x00}
+
+// flateWriterPool 复用deflate压缩器，避免每个数据块重新分配
+var flateWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, parallelZipLevel)
+		return w
+	},
+}
+
+// parallelZipWriter 多核并行压缩的ZIP写入器
+// 文件内容按块分给多个worker独立压缩，与pgzip的做法相同；写入协程按添加顺序写入条目和数据块，
+// 因此压缩包内容与顺序和单线程压缩一致，只是每块之间不共享字典，压缩率略低
+type parallelZipWriter struct {
+	zw    *zip.Writer
+	sem   chan struct{}     // 限制同时压缩的数据块数
+	queue chan zipQueueItem // 按顺序等待写入的条目和数据块
+	done  chan struct{}     // 写入协程退出时关闭
+	once  sync.Once
+	mutex sync.Mutex
+	err   error
+}
+
+// zipQueueItem 写入队列中的一项：新条目、压缩后的数据块或文件结束
+type zipQueueItem struct {
+	header  *zip.FileHeader
+	content []byte      // 内容已知的条目（目录、符号链接、清单）直接写入
+	raw     bool        // 条目数据为后续的压缩数据块
+	chunk   chan []byte // 压缩后的数据块，worker完成后发送
+	end     bool        // 文件结束，crc32和size为未压缩数据的校验和与大小
+	crc32   uint32
+	size    uint64
+}
+
+// newParallelZipWriter 创建并行压缩写入器，workers为同时压缩的数据块数
+func newParallelZipWriter(zw *zip.Writer, workers int) *parallelZipWriter {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &parallelZipWriter{
+		zw:    zw,
+		sem:   make(chan struct{}, workers),
+		queue: make(chan zipQueueItem, 2*workers),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// run 按顺序写入队列中的条目，出错后丢弃剩余的项
+func (p *parallelZipWriter) run() {
+	defer close(p.done)
+
+	var current *zip.FileHeader
+	var writer io.Writer
+	var written uint64
+	for item := range p.queue {
+		if p.error() != nil {
+			continue
+		}
+
+		var err error
+		switch {
+		case item.chunk != nil:
+			data := <-item.chunk
+			_, err = writer.Write(data)
+			written += uint64(len(data))
+		case item.end:
+			_, err = writer.Write(deflateFinalBlock)
+			written += uint64(len(deflateFinalBlock))
+			setZipEntrySizes(current, item.crc32, written, item.size)
+		case item.raw:
+			current, written = item.header, 0
+			writer, err = p.zw.CreateRaw(item.header)
+		default:
+			var w io.Writer
+			if w, err = p.zw.CreateHeader(item.header); err == nil {
+				_, err = w.Write(item.content)
+			}
+		}
+		if err != nil {
+			p.setError(err)
+		}
+	}
+}
+
+// addEntry 添加内容已知的条目，如目录、符号链接和清单文件
+func (p *parallelZipWriter) addEntry(header *zip.FileHeader, content []byte) error {
+	if err := p.error(); err != nil {
+		return err
+	}
+	p.queue <- zipQueueItem{header: header, content: content}
+	return nil
+}
+
+// addFile 读取r的全部内容，按块并行压缩后写入条目
+// CRC32在读取时顺序计算，大小在写入后记录到数据描述符中
+func (p *parallelZipWriter) addFile(header *zip.FileHeader, r io.Reader) error {
+	if err := p.error(); err != nil {
+		return err
+	}
+	header.Method = zip.Deflate
+	header.Flags |= zipDataDescriptorFlag
+	p.queue <- zipQueueItem{header: header, raw: true}
+
+	checksum := crc32.NewIEEE()
+	var size uint64
+	for {
+		if err := p.error(); err != nil {
+			return err
+		}
+		buf := make([]byte, parallelZipChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			data := buf[:n]
+			checksum.Write(data)
+			size += uint64(n)
+
+			result := make(chan []byte, 1)
+			p.sem <- struct{}{}
+			go func() {
+				defer func() { <-p.sem }()
+				result <- deflateChunk(data)
+			}()
+			p.queue <- zipQueueItem{chunk: result}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	p.queue <- zipQueueItem{end: true, crc32: checksum.Sum32(), size: size}
+	return nil
+}
+
+// close 等待全部条目写入，返回写入过程中的第一个错误；之后仍需关闭zip.Writer
+func (p *parallelZipWriter) close() error {
+	p.once.Do(func() { close(p.queue) })
+	<-p.done
+	return p.error()
+}
+
+// error 返回写入协程的第一个错误
+func (p *parallelZipWriter) error() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.err
+}
+
+// setError 记录第一个错误
+func (p *parallelZipWriter) setError(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// deflateChunk 独立压缩一个数据块，以sync flush结束而不是最终块，便于拼接
+func deflateChunk(data []byte) []byte {
+	var buf bytes.Buffer
+	fw := flateWriterPool.Get().(*flate.Writer)
+	fw.Reset(&buf)
+	fw.Write(data)
+	fw.Flush()
+	flateWriterPool.Put(fw)
+	return buf.Bytes()
+}
+
+// setZipEntrySizes 在条目数据写完后设置CRC32和大小，写入数据描述符和中央目录时使用
+func setZipEntrySizes(header *zip.FileHeader, checksum uint32, compressed, uncompressed uint64) {
+	header.CRC32 = checksum
+	header.CompressedSize64 = compressed
+	header.UncompressedSize64 = uncompressed
+	if compressed > math.MaxUint32 || uncompressed > math.MaxUint32 {
+		header.CompressedSize = math.MaxUint32
+		header.UncompressedSize = math.MaxUint32
+		header.ReaderVersion = zipVersion45
+	} else {
+		header.CompressedSize = uint32(compressed)
+		header.UncompressedSize = uint32(uncompressed)
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PathRuleService bind挂载路径改写规则服务，规则与任务一起保存在状态文件中
+type PathRuleService struct {
+	taskService *TaskService
+}
+
+// NewPathRuleService 创建路径改写规则服务
+func NewPathRuleService(taskService *TaskService) *PathRuleService {
+	return &PathRuleService{taskService: taskService}
+}
+
+// ListPathRules 按匹配顺序（创建时间）列出所有规则
+func (s *PathRuleService) ListPathRules() []*models.PathRule {
+	return sortedPathRules(s.taskService.store.GetAllPathRules())
+}
+
+// GetPathRule 获取规则
+func (s *PathRuleService) GetPathRule(ruleID string) (*models.PathRule, error) {
+	return s.taskService.store.GetPathRule(ruleID)
+}
+
+// CreatePathRule 创建规则，新规则排在已有规则之后
+func (s *PathRuleService) CreatePathRule(req *models.PathRuleRequest) (*models.PathRule, error) {
+	if err := validatePathRule(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule := &models.PathRule{
+		ID:          uuid.New().String(),
+		Type:        req.Type,
+		Match:       req.Match,
+		Replace:     req.Replace,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.taskService.store.SavePathRule(rule)
+	return rule, nil
+}
+
+// UpdatePathRule 替换规则内容，保留匹配顺序
+func (s *PathRuleService) UpdatePathRule(ruleID string, req *models.PathRuleRequest) (*models.PathRule, error) {
+	existing, err := s.taskService.store.GetPathRule(ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePathRule(req); err != nil {
+		return nil, err
+	}
+
+	rule := &models.PathRule{
+		ID:          existing.ID,
+		Type:        req.Type,
+		Match:       req.Match,
+		Replace:     req.Replace,
+		Description: req.Description,
+		CreatedAt:   existing.CreatedAt,
+		UpdatedAt:   time.Now(),
+	}
+	s.taskService.store.SavePathRule(rule)
+	return rule, nil
+}
+
+// DeletePathRule 删除规则，已完成的导入不受影响
+func (s *PathRuleService) DeletePathRule(ruleID string) error {
+	return s.taskService.store.DeletePathRule(ruleID)
+}
+
+// PreviewTaskBindMounts 按当前规则预览任务中每个应用的bind挂载改写结果
+// 挂载在任务扫描应用配置时记录，任务尚未扫描时返回空列表
+func (s *PathRuleService) PreviewTaskBindMounts(taskID string) ([]models.AppBindMounts, error) {
+	task, err := s.taskService.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 任务结果可能是内存中的结构体，也可能是从状态文件加载的map
+	var apps []models.AppImportStatus
+	if value, ok := task.Result["apps"]; ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read task result: %v", err)
+		}
+		if err := json.Unmarshal(data, &apps); err != nil {
+			return nil, fmt.Errorf("Failed to read task result: %v", err)
+		}
+	}
+
+	rules := s.ListPathRules()
+	previews := make([]models.AppBindMounts, 0, len(apps))
+	for _, app := range apps {
+		if len(app.BindMounts) == 0 {
+			continue
+		}
+		mounts := make([]models.BindMount, 0, len(app.BindMounts))
+		for _, mount := range app.BindMounts {
+			mount.Rewritten, mount.RuleID = rewritePath(rules, mount.Source)
+			mounts = append(mounts, mount)
+		}
+		previews = append(previews, models.AppBindMounts{AppName: app.AppName, Mounts: mounts})
+	}
+	sort.Slice(previews, func(i, j int) bool { return previews[i].AppName < previews[j].AppName })
+	return previews, nil
+}
+
+// validatePathRule 校验规则类型和匹配表达式
+func validatePathRule(req *models.PathRuleRequest) error {
+	switch req.Type {
+	case models.PathRulePrefix:
+		if !strings.HasPrefix(req.Match, "/") || !strings.HasPrefix(req.Replace, "/") {
+			return fmt.Errorf("Prefix rules must map an absolute path to an absolute path")
+		}
+	case models.PathRuleRegex:
+		if _, err := regexp.Compile(req.Match); err != nil {
+			return fmt.Errorf("Invalid regular expression: %v", err)
+		}
+	default:
+		return fmt.Errorf("Invalid rule type: %s (expected %s or %s)", req.Type, models.PathRulePrefix, models.PathRuleRegex)
+	}
+	return nil
+}
+
+// sortedPathRules 按创建时间排序规则，即匹配顺序
+func sortedPathRules(rules []*models.PathRule) []*models.PathRule {
+	sort.Slice(rules, func(i, j int) bool {
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// rewritePath 使用第一个匹配的规则改写路径，返回改写后的路径和规则ID，没有匹配时原样返回
+// 前缀规则按路径段匹配：/mnt/data 匹配 /mnt/data 和 /mnt/data/x，不匹配 /mnt/database
+func rewritePath(rules []*models.PathRule, source string) (string, string) {
+	for _, rule := range rules {
+		switch rule.Type {
+		case models.PathRulePrefix:
+			prefix := strings.TrimRight(rule.Match, "/")
+			if source == prefix || strings.HasPrefix(source, prefix+"/") || prefix == "" {
+				return path.Clean(rule.Replace + "/" + strings.TrimPrefix(source, prefix)), rule.ID
+			}
+		case models.PathRuleRegex:
+			re, err := regexp.Compile(rule.Match)
+			if err == nil && re.MatchString(source) {
+				return re.ReplaceAllString(source, rule.Replace), rule.ID
+			}
+		}
+	}
+	return source, ""
+}
+
+// recordBindMounts 扫描应用配置时记录每个应用的bind挂载，供预览接口使用
+func recordBindMounts(sourceData map[string]interface{}, appStatuses []models.AppImportStatus) {
+	composeFiles, _ := sourceData["composeFiles"].(map[string]string)
+	for i := range appStatuses {
+		doc, err := parseCompose(composeFiles[appStatuses[i].AppName])
+		if err != nil {
+			continue
+		}
+		var mounts []models.BindMount
+		doc.RewriteBindSources(func(service, source string) string {
+			mounts = append(mounts, models.BindMount{Service: service, Source: source, Rewritten: source})
+			return source
+		})
+		appStatuses[i].BindMounts = mounts
+	}
+}
+
+// applyPathRules 导入前按改写规则修改compose中的bind挂载源路径，每处改写记录到任务日志
+func (s *MigrationService) applyPathRules(taskID, appName, composeContent string) string {
+	rules := sortedPathRules(s.taskService.store.GetAllPathRules())
+	if len(rules) == 0 {
+		return composeContent
+	}
+	doc, err := parseCompose(composeContent)
+	if err != nil {
+		return composeContent
+	}
+
+	changed := false
+	doc.RewriteBindSources(func(service, source string) string {
+		rewritten, ruleID := rewritePath(rules, source)
+		if ruleID == "" || rewritten == source {
+			return source
+		}
+		changed = true
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s: service %s bind mount %s rewritten to %s", appName, service, source, rewritten))
+		return rewritten
+	})
+	if !changed {
+		return composeContent
+	}
+
+	rewrittenContent, err := doc.String()
+	if err != nil {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s: failed to apply path rules: %v", appName, err))
+		return composeContent
+	}
+	return rewrittenContent
+}
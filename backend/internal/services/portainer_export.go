@@ -0,0 +1,231 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// 导出格式常量
+const (
+	ExportFormatCasaOS    = "casaos"
+	ExportFormatPortainer = "portainer"
+)
+
+// PortainerStack Portainer栈导出清单中的单个栈
+type PortainerStack struct {
+	Name          string   `json:"name"`
+	ComposeFile   string   `json:"compose_file"`
+	DataDir       string   `json:"data_dir,omitempty"`
+	ExternalBinds []string `json:"external_binds,omitempty"` // 未能转换为相对路径的主机挂载
+}
+
+// CreatePortainerExport 从CasaOS源系统导出Portainer兼容的栈压缩包
+// 每个应用一个栈目录，AppData放在栈目录下的data/中，compose中的挂载改写为相对路径
+func (s *MigrationService) CreatePortainerExport(ctx context.Context, sourceConn *models.SystemConnection) (string, error) {
+	if err := s.testExportSource(ctx, sourceConn); err != nil {
+		return "", err
+	}
+
+	progressCallback := func(progress int, message string) {
+		log.Printf("[PortainerExport] %d%% - %s", progress, message)
+	}
+
+	downloadPath, err := s.downloadCasaOSFiles(ctx, sourceConn, progressCallback)
+	if err != nil {
+		return "", fmt.Errorf("Failed to download CasaOS files: %v", err)
+	}
+	defer os.Remove(downloadPath)
+
+	extractedPath, err := s.extractDownloadedFiles(downloadPath, progressCallback)
+	if err != nil {
+		return "", fmt.Errorf("Failed to extract files: %v", err)
+	}
+	defer os.RemoveAll(extractedPath)
+
+	return s.createPortainerExportFile(extractedPath)
+}
+
+// createPortainerExportFile 根据解压后的CasaOS目录生成Portainer栈压缩包
+func (s *MigrationService) createPortainerExportFile(extractedPath string) (string, error) {
+	composeFiles, err := s.readComposeFiles(filepath.Join(extractedPath, "var/lib/casaos/apps"))
+	if err != nil {
+		return "", err
+	}
+
+	exportDir := s.cfg.Dirs.Export
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create export directory: %v", err)
+	}
+
+	filePath := filepath.Join(exportDir, fmt.Sprintf("portainer_stacks_%s.zip", time.Now().Format("20060102_150405")))
+	zipFile, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create ZIP file: %v", err)
+	}
+	defer zipFile.Close()
+
+	zipWriter := zip.NewWriter(zipFile)
+
+	appNames := make([]string, 0, len(composeFiles))
+	for appName := range composeFiles {
+		appNames = append(appNames, appName)
+	}
+	sort.Strings(appNames)
+
+	appDataRoot := filepath.Join(extractedPath, "DATA/AppData")
+	stacks := make([]PortainerStack, 0, len(appNames))
+
+	for _, appName := range appNames {
+		stack := PortainerStack{
+			Name:        appName,
+			ComposeFile: path.Join(appName, "docker-compose.yml"),
+		}
+
+		// 查找应用的AppData目录（CasaOS中目录名大小写可能与应用名不同）
+		appDataName := findEntryFold(appDataRoot, appName)
+		appDataPrefix := casaOSAppDataDir + "/" + appDataName
+
+		doc, err := parseCompose(composeFiles[appName])
+		if err != nil {
+			return "", fmt.Errorf("App %s: %v", appName, err)
+		}
+		doc.RewriteBindSources(func(service, source string) string {
+			if appDataName != "" && (source == appDataPrefix || strings.HasPrefix(source, appDataPrefix+"/")) {
+				return "./data" + strings.TrimPrefix(source, appDataPrefix)
+			}
+			if strings.HasPrefix(source, "/") {
+				stack.ExternalBinds = append(stack.ExternalBinds, source)
+			}
+			return source
+		})
+		content, err := doc.String()
+		if err != nil {
+			return "", fmt.Errorf("App %s: %v", appName, err)
+		}
+
+		writer, err := zipWriter.Create(stack.ComposeFile)
+		if err != nil {
+			return "", fmt.Errorf("Failed to create ZIP entry: %v", err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			return "", fmt.Errorf("Failed to write compose file: %v", err)
+		}
+
+		// .env和自定义配置文件与compose放在同一目录，相对路径保持有效
+		appDir := filepath.Join(extractedPath, "var/lib/casaos/apps", appName)
+		if files, err := listAppFiles(appDir); err == nil {
+			for _, name := range files {
+				if err := addDirToZip(zipWriter, filepath.Join(appDir, filepath.FromSlash(name)), path.Join(appName, name)); err != nil {
+					return "", fmt.Errorf("App %s: Failed to add %s: %v", appName, name, err)
+				}
+			}
+		}
+
+		if appDataName != "" {
+			stack.DataDir = path.Join(appName, "data")
+			if err := addDirToZip(zipWriter, filepath.Join(appDataRoot, appDataName), stack.DataDir); err != nil {
+				return "", fmt.Errorf("App %s: Failed to add AppData: %v", appName, err)
+			}
+		}
+
+		if len(stack.ExternalBinds) > 0 {
+			log.Printf("[WARNING] App %s keeps %d absolute bind mounts in Portainer export", appName, len(stack.ExternalBinds))
+		}
+		stacks = append(stacks, stack)
+	}
+
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"format":     ExportFormatPortainer,
+		"created_at": time.Now().Format(time.RFC3339),
+		"stacks":     stacks,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Failed to serialize manifest: %v", err)
+	}
+	writer, err := zipWriter.Create("stacks.json")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create ZIP entry: %v", err)
+	}
+	if _, err := writer.Write(manifest); err != nil {
+		return "", fmt.Errorf("Failed to write manifest: %v", err)
+	}
+	// 关闭时才写入ZIP的中央目录，失败时压缩包不完整
+	if err := zipWriter.Close(); err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("Failed to write ZIP file: %v", err)
+	}
+
+	log.Printf("[INFO] Created Portainer export with %d stacks: %s", len(stacks), filePath)
+	return filePath, nil
+}
+
+// findEntryFold 在目录中查找与名称大小写不敏感匹配的条目，未找到返回空字符串
+func findEntryFold(dir, name string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+			return entry.Name()
+		}
+	}
+	return ""
+}
+
+// addDirToZip 将本地目录递归写入ZIP中的指定前缀下
+func addDirToZip(zipWriter *zip.Writer, sourceDir, prefix string) error {
+	return filepath.Walk(sourceDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(sourceDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(relPath))
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		if isSymlink(info) {
+			return writeZipSymlink(zipWriter, header, filePath)
+		}
+
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(writer, file)
+		return err
+	})
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// Preflight 在开始迁移前检查源和目标系统：连接、版本、待迁移数据量以及源、目标和本机的可用空间
+func (s *MigrationService) Preflight(ctx context.Context, req *models.PreflightRequest) *models.PreflightReport {
+	report := &models.PreflightReport{Checks: []models.PreflightCheck{}}
+	add := func(check models.PreflightCheck) {
+		report.Checks = append(report.Checks, check)
+	}
+
+	sourceOK := s.preflightConnection(ctx, &req.Source, "source_connection", add)
+	targetOK := s.preflightConnection(ctx, &req.Target, "target_connection", add)
+
+	if sourceOK {
+		add(s.preflightVersion(ctx, &req.Source, "source_version"))
+	} else {
+		add(skippedCheck("source_version"))
+	}
+	if targetOK {
+		add(s.preflightVersion(ctx, &req.Target, "target_version"))
+	} else {
+		add(skippedCheck("target_version"))
+	}
+
+	// 只有CasaOS源可以在迁移前统计数据量
+	var sourceBytes int64
+	if sourceOK && req.Source.Type == models.SystemTypeCasaOS {
+		check := models.PreflightCheck{Name: "source_size"}
+		size, err := s.casaOSDataSize(ctx, &req.Source)
+		if err != nil {
+			check.Status = models.PreflightWarning
+			check.Message = fmt.Sprintf("Could not determine source data size: %v", err)
+		} else {
+			sourceBytes = size
+			check.Status = models.PreflightPass
+			check.Message = fmt.Sprintf("Source data: %s", formatBytes(size))
+			check.Details = map[string]interface{}{"bytes": size}
+		}
+		add(check)
+	}
+	report.SourceBytes = sourceBytes
+
+	// 列出AppData很大的应用，避免迁移耗时出乎意料
+	if sourceOK && req.Source.Type == models.SystemTypeCasaOS {
+		add(s.preflightLargeApps(ctx, &req.Source, req.Options))
+	}
+
+	// 源和目标CPU架构不同时，列出镜像没有目标架构版本的应用
+	if sourceOK && targetOK && req.Source.Type == models.SystemTypeCasaOS {
+		add(s.preflightArchitectures(ctx, &req.Source, &req.Target, req.Options))
+	} else {
+		add(skippedCheck("architecture"))
+	}
+
+	if sourceOK {
+		check := models.PreflightCheck{Name: "source_disk"}
+		if avail, total, err := s.remoteFreeSpace(ctx, &req.Source, sourceDataDir(&req.Source)); err != nil {
+			check.Status = models.PreflightWarning
+			check.Message = fmt.Sprintf("Could not determine free space: %v", err)
+		} else {
+			check.Status = models.PreflightPass
+			check.Message = fmt.Sprintf("%s free of %s", formatBytes(avail), formatBytes(total))
+			check.Details = map[string]interface{}{"free_bytes": avail, "total_bytes": total}
+		}
+		add(check)
+	} else {
+		add(skippedCheck("source_disk"))
+	}
+
+	// ZimaOS目标先上传压缩包再解压，需要约两倍的空间
+	if targetOK {
+		required := sourceBytes
+		dataDir := targetDataDir(&req.Target)
+		override, err := targetAppDataDirOverride(req.Options)
+		if override != "" {
+			dataDir = override
+		}
+		if req.Target.Type == models.SystemTypeZimaOS {
+			required = 2 * sourceBytes
+			switch {
+			case err != nil:
+			case override != "":
+				err = s.validateZimaOSAppDataDir(ctx, &req.Target, override)
+			default:
+				dataDir, err = s.zimaOSTargetAppDataDir(ctx, &req.Target, req.Options)
+			}
+		}
+		if err != nil {
+			add(models.PreflightCheck{Name: "target_disk", Status: models.PreflightFail, Message: err.Error()})
+		} else {
+			avail, total, err := s.remoteFreeSpace(ctx, &req.Target, dataDir)
+			add(spaceCheck("target_disk", avail, total, sourceBytes, required, err))
+		}
+	} else {
+		add(skippedCheck("target_disk"))
+	}
+
+	// 本机需要保存下载的压缩包和解压后的数据
+	avail, total, err := localFreeSpace(existingParent(s.cfg.Dirs.Download))
+	add(spaceCheck("local_disk", avail, total, 2*sourceBytes, 2*sourceBytes, err))
+
+	report.Ready = true
+	for _, check := range report.Checks {
+		if check.Status == models.PreflightFail {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// preflightConnection 测试连接并记录耗时
+func (s *MigrationService) preflightConnection(ctx context.Context, conn *models.SystemConnection, name string, add func(models.PreflightCheck)) bool {
+	check := models.PreflightCheck{Name: name}
+	if err := s.connService.ValidateConnectionConfig(conn); err != nil {
+		check.Status = models.PreflightFail
+		check.Message = err.Error()
+		add(check)
+		return false
+	}
+
+	start := time.Now()
+	resp, err := s.connService.TestConnection(ctx, conn)
+	elapsed := time.Since(start).Milliseconds()
+	switch {
+	case err != nil:
+		check.Status = models.PreflightFail
+		check.Message = fmt.Sprintf("Connection test failed: %v", err)
+	case !resp.Success:
+		check.Status = models.PreflightFail
+		check.Message = resp.Message
+	default:
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("Connected in %d ms", elapsed)
+		check.Details = map[string]interface{}{"latency_ms": elapsed, "connection_id": resp.ConnectionID}
+	}
+	add(check)
+	return check.Status == models.PreflightPass
+}
+
+// preflightVersion 检查系统版本，无法识别版本时只给出警告
+func (s *MigrationService) preflightVersion(ctx context.Context, conn *models.SystemConnection, name string) models.PreflightCheck {
+	check := models.PreflightCheck{Name: name}
+	version, err := s.systemVersion(ctx, conn)
+	if err != nil || version == "" {
+		if err != nil {
+			log.Printf("[WARNING] Failed to detect version of %s: %v", conn.Host, err)
+		}
+		check.Status = models.PreflightWarning
+		check.Message = "Could not determine version"
+		return check
+	}
+	check.Status = models.PreflightPass
+	check.Message = fmt.Sprintf("Version %s", version)
+	check.Details = map[string]interface{}{"version": version}
+	return check
+}
+
+// systemVersion 获取系统版本：CasaOS/ZimaOS读取系统信息，基于SSH的系统返回Docker版本
+func (s *MigrationService) systemVersion(ctx context.Context, conn *models.SystemConnection) (string, error) {
+	if isSSHSystem(conn.Type) {
+		output, err := runSSH(ctx, conn, nil, "docker version --format '{{.Server.Version}}'")
+		if err != nil {
+			return "", err
+		}
+		return "Docker " + strings.TrimSpace(string(output)), nil
+	}
+
+	info, err := s.connService.GetSystemInfo(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	return findVersion(info), nil
+}
+
+// findVersion 在系统信息中查找版本字段
+func findVersion(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"version", "current_version", "Version"} {
+			if str, ok := v[key].(string); ok && str != "" {
+				return str
+			}
+		}
+		for _, child := range v {
+			if version := findVersion(child); version != "" {
+				return version
+			}
+		}
+	}
+	return ""
+}
+
+// casaOSDataSize 统计CasaOS应用配置和AppData的总大小
+func (s *MigrationService) casaOSDataSize(ctx context.Context, conn *models.SystemConnection) (int64, error) {
+	appsSize, err := s.getCasaOSFolderSize(ctx, conn, casaOSAppsDir)
+	if err != nil {
+		return 0, err
+	}
+	appDataSize, err := s.getCasaOSFolderSize(ctx, conn, casaOSAppDataDir)
+	if err != nil {
+		return 0, err
+	}
+	return appsSize + appDataSize, nil
+}
+
+// remoteFreeSpace 获取远端系统的可用空间和总空间
+// ZimaOS优先使用dir所在存储卷的信息，CasaOS/ZimaOS使用系统资源接口，基于SSH的系统对dir执行df
+func (s *MigrationService) remoteFreeSpace(ctx context.Context, conn *models.SystemConnection, dir string) (int64, int64, error) {
+	if conn.Type == models.SystemTypeZimaOS {
+		if volumes, err := s.ListTargetStorage(ctx, conn); err == nil {
+			for _, v := range volumes {
+				if v.Size > 0 && strings.HasPrefix(dir+"/", v.MountPoint+"/") {
+					return v.Avail, v.Size, nil
+				}
+			}
+		}
+	}
+	if !isSSHSystem(conn.Type) {
+		var utilization struct {
+			Disk struct {
+				Size  int64 `json:"size"`
+				Avail int64 `json:"avail"`
+			} `json:"disk"`
+		}
+		if err := s.casaOSGet(ctx, conn, "/v1/sys/utilization", nil, &utilization); err != nil {
+			return 0, 0, err
+		}
+		if utilization.Disk.Size == 0 {
+			return 0, 0, fmt.Errorf("Disk information not available")
+		}
+		return utilization.Disk.Avail, utilization.Disk.Size, nil
+	}
+
+	// 目录可能还不存在，向上查找已存在的目录
+	script := fmt.Sprintf(`p=%s; while [ ! -e "$p" ]; do p=$(dirname "$p"); done; df -Pk "$p"`, shellQuote(dir))
+	output, err := runSSH(ctx, conn, nil, script)
+	if err != nil {
+		return 0, 0, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, 0, fmt.Errorf("Unexpected df output: %s", strings.TrimSpace(string(output)))
+	}
+	total, err1 := strconv.ParseInt(fields[1], 10, 64)
+	avail, err2 := strconv.ParseInt(fields[3], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("Unexpected df output: %s", strings.TrimSpace(string(output)))
+	}
+	return avail * 1024, total * 1024, nil
+}
+
+// sourceDataDir 源系统上应用数据所在目录
+func sourceDataDir(conn *models.SystemConnection) string {
+	switch conn.Type {
+	case models.SystemTypeRuntipi:
+		return runtipiRootDir(conn)
+	case models.SystemTypeTrueNAS:
+		if conn.RootDir != "" {
+			return conn.RootDir
+		}
+		return "/mnt"
+	case models.SystemTypeDocker:
+		if conn.AppDataDir != "" {
+			return conn.AppDataDir
+		}
+		return defaultDockerAppDataDir
+	}
+	return casaOSAppDataDir
+}
+
+// targetDataDir Docker主机目标上AppData写入的目录
+func targetDataDir(conn *models.SystemConnection) string {
+	if conn.AppDataDir != "" {
+		return conn.AppDataDir
+	}
+	return defaultDockerAppDataDir
+}
+
+// spaceCheck 根据可用空间和所需空间生成检查结果：不足needed为失败，不足recommended为警告
+func spaceCheck(name string, avail, total, needed, recommended int64, err error) models.PreflightCheck {
+	check := models.PreflightCheck{Name: name}
+	if err != nil {
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Could not determine free space: %v", err)
+		return check
+	}
+
+	check.Details = map[string]interface{}{"free_bytes": avail, "total_bytes": total, "required_bytes": recommended}
+	switch {
+	case avail < needed:
+		check.Status = models.PreflightFail
+		check.Message = fmt.Sprintf("Not enough free space: %s required, %s free", formatBytes(needed), formatBytes(avail))
+	case avail < recommended:
+		check.Status = models.PreflightWarning
+		check.Message = fmt.Sprintf("Free space is tight: %s recommended, %s free", formatBytes(recommended), formatBytes(avail))
+	default:
+		check.Status = models.PreflightPass
+		check.Message = fmt.Sprintf("%s free of %s", formatBytes(avail), formatBytes(total))
+	}
+	return check
+}
+
+// skippedCheck 因连接失败而跳过的检查项
+func skippedCheck(name string) models.PreflightCheck {
+	return models.PreflightCheck{
+		Name:    name,
+		Status:  models.PreflightSkipped,
+		Message: "Skipped because the connection failed",
+	}
+}
+
+// existingParent 返回路径本身或最近的已存在上级目录
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// formatBytes 将字节数格式化为便于阅读的形式
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for value := n / unit; value >= unit; value /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+	"ctoz/backend/internal/storage"
+)
+
+// presetNamePattern 预设名称只允许字母、数字和 . _ -
+var presetNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// presetReservedOptions 由任务自身决定、不能保存在预设中的选项
+var presetReservedOptions = []string{"import_file", importS3Option, backupJobOption}
+
+// PresetService 迁移选项预设服务，预设与任务一起保存在状态文件中
+type PresetService struct {
+	taskService *TaskService
+}
+
+// NewPresetService 创建迁移选项预设服务
+func NewPresetService(taskService *TaskService) *PresetService {
+	return &PresetService{taskService: taskService}
+}
+
+// ListPresets 列出所有预设，按名称排序
+func (s *PresetService) ListPresets() []*models.OptionPreset {
+	presets := s.taskService.store.GetAllPresets()
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+// GetPreset 获取预设
+func (s *PresetService) GetPreset(name string) (*models.OptionPreset, error) {
+	return s.taskService.store.GetPreset(name)
+}
+
+// CreatePreset 创建预设，名称已存在时返回ErrPresetExists
+func (s *PresetService) CreatePreset(req *models.OptionPresetRequest) (*models.OptionPreset, error) {
+	name := strings.TrimSpace(req.Name)
+	if !presetNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("Invalid preset name: %s", req.Name)
+	}
+	if err := validatePresetOptions(req.Options); err != nil {
+		return nil, err
+	}
+	if _, err := s.taskService.store.GetPreset(name); err == nil {
+		return nil, models.ErrPresetExists
+	}
+
+	now := time.Now()
+	preset := &models.OptionPreset{
+		Name:        name,
+		Description: req.Description,
+		Options:     req.Options,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.taskService.store.SavePreset(preset)
+	return preset, nil
+}
+
+// UpdatePreset 替换预设的说明和选项
+func (s *PresetService) UpdatePreset(name string, req *models.OptionPresetRequest) (*models.OptionPreset, error) {
+	existing, err := s.taskService.store.GetPreset(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePresetOptions(req.Options); err != nil {
+		return nil, err
+	}
+
+	preset := &models.OptionPreset{
+		Name:        existing.Name,
+		Description: req.Description,
+		Options:     req.Options,
+		CreatedAt:   existing.CreatedAt,
+		UpdatedAt:   time.Now(),
+	}
+	s.taskService.store.SavePreset(preset)
+	return preset, nil
+}
+
+// DeletePreset 删除预设，已创建的任务不受影响
+func (s *PresetService) DeletePreset(name string) error {
+	return s.taskService.store.DeletePreset(name)
+}
+
+// validatePresetOptions 校验预设选项
+func validatePresetOptions(options map[string]interface{}) error {
+	for _, key := range presetReservedOptions {
+		if _, ok := options[key]; ok {
+			return fmt.Errorf("Option %s cannot be stored in a preset", key)
+		}
+	}
+	return nil
+}
+
+// applyPreset 以预设的选项为默认值合并请求中的选项，未指定预设时原样返回
+func applyPreset(store *storage.MemoryStore, name string, options map[string]interface{}) (map[string]interface{}, error) {
+	if name == "" {
+		return options, nil
+	}
+	preset, err := store.GetPreset(name)
+	if err != nil {
+		return nil, fmt.Errorf("Preset %s not found", name)
+	}
+
+	merged := make(map[string]interface{}, len(preset.Options)+len(options))
+	for k, v := range preset.Options {
+		merged[k] = v
+	}
+	for k, v := range options {
+		merged[k] = v
+	}
+	return merged, nil
+}
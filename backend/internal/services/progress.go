@@ -0,0 +1,43 @@
+package services
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	// progressReportInterval 字节级进度的最小上报间隔，避免WebSocket消息过多
+	progressReportInterval = 500 * time.Millisecond
+	// downloadReportStep 下载长度未知时每下载多少字节报告一次
+	downloadReportStep = 50 << 20
+)
+
+// progressReader 统计读取字节数并定期回调的Reader
+type progressReader struct {
+	r          io.Reader
+	total      int64 // 总字节数，未知时为0
+	read       int64
+	onProgress func(read, total int64)
+	lastReport time.Time
+}
+
+// newProgressReader 创建进度统计Reader，onProgress为nil时直接返回原Reader
+func newProgressReader(r io.Reader, total int64, onProgress func(read, total int64)) io.Reader {
+	if onProgress == nil {
+		return r
+	}
+	return &progressReader{r: r, total: total, onProgress: onProgress}
+}
+
+// Read 读取数据并按间隔上报进度，读取结束时总会上报一次
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.read += int64(n)
+
+	done := err == io.EOF || (p.total > 0 && p.read >= p.total)
+	if done || time.Since(p.lastReport) >= progressReportInterval {
+		p.lastReport = time.Now()
+		p.onProgress(p.read, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"ctoz/backend/internal/models"
+)
+
+// planStep 迁移计划中的一个步骤及其在总进度中的权重
+type planStep struct {
+	Name   string
+	Weight int
+}
+
+// migrationPlans 各类任务的步骤及权重，按执行顺序排列
+// 未执行的可选步骤（如未开启auto_start）在后续步骤开始时计为已完成
+var migrationPlans = map[string][]planStep{
+	string(models.TaskTypeOnline): {
+		{"Test source system connection", 2},
+		{"Test target system connection", 2},
+		{"Download and process source data", 30},
+		{"Scan app configuration", 3},
+		{"Merge AppData directory", 35},
+		{"Load container images", 5},
+		{"Import application configuration", 10},
+		{"Start imported apps", 5},
+		{"Restore database dumps", 5},
+		{"Cleanup local temporary files", 3},
+	},
+	string(models.TaskTypeExport): {
+		{"Test source system connection", 5},
+		{"Export system data", 75},
+		{"Upload export file", 20},
+	},
+	string(models.TaskTypeImport): {
+		{"Test target system connection", 2},
+		{"Download import file", 10},
+		{"Parse import file", 15},
+		{"Scan app configuration", 3},
+		{"Merge AppData directory", 40},
+		{"Load container images", 5},
+		{"Import application configuration", 12},
+		{"Start imported apps", 5},
+		{"Restore database dumps", 5},
+		{"Cleanup local temporary files", 3},
+	},
+}
+
+// planProgress 根据步骤内进度计算任务总进度，步骤不在计划中时ok为false
+func planProgress(taskType, step string, stepProgress int) (progress int, ok bool) {
+	plan := migrationPlans[taskType]
+	total, base, weight := 0, 0, -1
+	for _, p := range plan {
+		if p.Name == step && weight < 0 {
+			base, weight = total, p.Weight
+		}
+		total += p.Weight
+	}
+	if weight < 0 || total == 0 {
+		return 0, false
+	}
+
+	if stepProgress < 0 {
+		stepProgress = 0
+	} else if stepProgress > 100 {
+		stepProgress = 100
+	}
+	return (base*100 + weight*stepProgress) / total, true
+}
+
+// advanceProgress 按步骤权重更新任务总进度并返回，总进度只增不减
+func (s *TaskService) advanceProgress(taskID, step string, stepProgress int) int {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return stepProgress
+	}
+
+	progress, ok := planProgress(task.Type, step, stepProgress)
+	if !ok || progress <= task.Progress {
+		return task.Progress
+	}
+	s.store.UpdateTaskProgress(taskID, progress)
+	return progress
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/config"
+)
+
+// hostLimiter 按主机限制CasaOS/ZimaOS接口调用的并发数和发起间隔，避免请求突发压垮低功耗设备
+// 请求从发出到收到响应头期间占用名额，读取响应体（如下载）不占用
+type hostLimiter struct {
+	cfg   config.RateLimitConfig
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots 单个主机的并发名额和下次允许发起请求的时间
+type hostSlots struct {
+	sem  chan struct{}
+	next time.Time
+}
+
+// newHostLimiter 创建主机限流器，并发数和间隔都为0时返回nil表示不限制
+func newHostLimiter(cfg config.RateLimitConfig) *hostLimiter {
+	if cfg.MaxInFlight <= 0 && cfg.Interval <= 0 {
+		return nil
+	}
+	return &hostLimiter{cfg: cfg, hosts: make(map[string]*hostSlots)}
+}
+
+// wait 等待主机的并发名额和发起间隔，返回释放名额的函数；上下文取消时返回错误
+func (l *hostLimiter) wait(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = &hostSlots{}
+		if l.cfg.MaxInFlight > 0 {
+			slots.sem = make(chan struct{}, l.cfg.MaxInFlight)
+		}
+		l.hosts[host] = slots
+	}
+	l.mu.Unlock()
+
+	release := func() {}
+	if slots.sem != nil {
+		select {
+		case slots.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-slots.sem }
+	}
+
+	if l.cfg.Interval > 0 {
+		// 预约下一个发起时间，并发的请求依次顺延
+		l.mu.Lock()
+		now := time.Now()
+		start := slots.next
+		if start.Before(now) {
+			start = now
+		}
+		slots.next = start.Add(l.cfg.Interval)
+		l.mu.Unlock()
+
+		if !sleepContext(ctx, start.Sub(now)) {
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
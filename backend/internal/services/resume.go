@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"ctoz/backend/internal/models"
+)
+
+// ResumeTask 恢复因服务重启而中断的任务，或让等待目标恢复的任务立即重新探测目标
+// 在线迁移复用断点中已解压的源数据，离线导入重新解压导入文件；已成功的应用步骤不会重复执行
+func (s *MigrationService) ResumeTask(taskID string) (*models.MigrationTask, error) {
+	// 等待目标恢复的任务立即重新探测目标
+	if s.wakeWaitingTask(taskID) {
+		return s.taskService.GetTask(taskID)
+	}
+
+	task, err := s.taskService.PrepareResume(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, "Resuming interrupted task")
+	log.Printf("[INFO] Resuming task %s (%s)", task.ID, task.Type)
+
+	switch task.Type {
+	case models.TaskTypeOnline:
+		go s.executeOnlineMigration(task)
+	case models.TaskTypeImport:
+		go s.executeDataImport(task)
+	default:
+		return nil, fmt.Errorf("Task type %s cannot be resumed", task.Type)
+	}
+	return task, nil
+}
+
+// RetryTask 重新执行失败的任务，中断或等待中的任务按ResumeTask处理
+// 在线迁移复用断点中尚未清理的源数据，已成功的应用步骤不会重复执行
+func (s *MigrationService) RetryTask(taskID string) (*models.MigrationTask, error) {
+	task, err := s.taskService.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != string(models.TaskStatusFailed) {
+		return s.ResumeTask(taskID)
+	}
+
+	var run func(*models.MigrationTask)
+	switch task.Type {
+	case models.TaskTypeOnline:
+		run = s.executeOnlineMigration
+	case models.TaskTypeImport:
+		run = s.executeDataImport
+	case models.TaskTypeExport:
+		run = s.executeDataExport
+	default:
+		return nil, fmt.Errorf("Task type %s cannot be retried", task.Type)
+	}
+
+	task, err = s.taskService.PrepareRetry(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, "Retrying failed task")
+	log.Printf("[INFO] Retrying task %s (%s)", task.ID, task.Type)
+	go run(task)
+	return task, nil
+}
+
+// checkpointSnapshot 由断点还原源数据快照，解压目录已不存在时返回nil
+func checkpointSnapshot(checkpoint *models.TaskCheckpoint) *SourceSnapshot {
+	if checkpoint == nil || checkpoint.ExtractedPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(checkpoint.ExtractedPath); err != nil {
+		return nil
+	}
+	return &SourceSnapshot{
+		DownloadPath:  checkpoint.DownloadPath,
+		ExtractedPath: checkpoint.ExtractedPath,
+		SkippedApps:   checkpoint.SkippedApps,
+	}
+}
+
+// previousAppStatuses 读取任务结果中上次保存的应用状态
+// 从状态文件加载的任务结果是通用JSON结构，需要重新解析
+func previousAppStatuses(task *models.MigrationTask) []models.AppImportStatus {
+	if task.Result == nil {
+		return nil
+	}
+	apps, ok := task.Result["apps"]
+	if !ok {
+		return nil
+	}
+	if statuses, ok := apps.([]models.AppImportStatus); ok {
+		return statuses
+	}
+
+	data, err := json.Marshal(apps)
+	if err != nil {
+		return nil
+	}
+	var statuses []models.AppImportStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil
+	}
+	return statuses
+}
+
+// 应用断点记录的步骤
+const (
+	appStepAppData = "appdata"
+	appStepCompose = "compose"
+)
+
+// resumeAppStatuses 返回上次保存的应用状态，并按应用断点记录补全已完成的步骤
+// 任务结果只定期写入状态文件，断点记录在每个应用的步骤完成后立即写入，崩溃时不会丢失
+func resumeAppStatuses(task *models.MigrationTask) []models.AppImportStatus {
+	previous := previousAppStatuses(task)
+	if task.Checkpoint == nil || len(task.Checkpoint.Apps) == 0 {
+		return previous
+	}
+
+	index := make(map[string]int, len(previous))
+	for i, status := range previous {
+		index[status.AppName] = i
+	}
+	for appName, record := range task.Checkpoint.Apps {
+		i, ok := index[appName]
+		if !ok {
+			previous = append(previous, models.AppImportStatus{AppName: appName})
+			i = len(previous) - 1
+			index[appName] = i
+		}
+		if record.AppData {
+			previous[i].AppDataStatus = models.AppStatusSuccess
+		}
+		if record.Compose {
+			previous[i].ComposeStatus = models.AppStatusSuccess
+		}
+	}
+	return previous
+}
+
+// restoreAppStatuses 将上次已成功的AppData和compose步骤应用到新扫描的应用状态
+func (s *MigrationService) restoreAppStatuses(appStatuses, previous []models.AppImportStatus) {
+	if len(previous) == 0 {
+		return
+	}
+
+	byName := make(map[string]models.AppImportStatus, len(previous))
+	for _, status := range previous {
+		byName[status.AppName] = status
+	}
+
+	for i := range appStatuses {
+		prev, ok := byName[appStatuses[i].AppName]
+		if !ok {
+			continue
+		}
+		if appStatuses[i].HasAppData && prev.AppDataStatus == models.AppStatusSuccess {
+			appStatuses[i].AppDataStatus = models.AppStatusSuccess
+		}
+		// 已完成步骤的指标沿用上次的记录
+		appStatuses[i].Metrics = prev.Metrics
+		if prev.ComposeStatus == models.AppStatusSuccess {
+			appStatuses[i].ComposeStatus = models.AppStatusSuccess
+			appStatuses[i].OverallStatus = s.calculateOverallStatus(appStatuses[i])
+		}
+	}
+}
+
+// composeImported 判断应用的compose是否已导入成功
+func composeImported(appStatuses []models.AppImportStatus, appName string) bool {
+	for _, status := range appStatuses {
+		if status.AppName == appName {
+			return status.ComposeStatus == models.AppStatusSuccess
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// Runtipi相关常量
+const (
+	defaultRuntipiRootDir = "runtipi"           // 相对于SSH用户主目录
+	runtipiMainNetwork    = "tipi_main_network" // Runtipi为所有应用创建的外部网络
+)
+
+// runtipiSource Runtipi源适配器
+// 通过rsync拉取 <root>/apps 与 <root>/app-data，并转换为CasaOS布局：
+// apps/<app>/docker-compose.yml -> var/lib/casaos/apps/<app>/docker-compose.yml
+// app-data/<app>               -> DATA/AppData/<app>
+type runtipiSource struct {
+	s    *MigrationService
+	conn *models.SystemConnection
+}
+
+// runtipiApp 本地拉取的Runtipi应用
+type runtipiApp struct {
+	Name       string
+	AppDir     string // 包含docker-compose.yml的目录
+	AppDataDir string
+}
+
+// Name 源类型名称
+func (r *runtipiSource) Name() string {
+	return models.SystemTypeRuntipi
+}
+
+// Fetch 拉取Runtipi应用并转换为统一快照
+func (r *runtipiSource) Fetch(ctx context.Context, taskID string, progressCallback func(int, string)) (*SourceSnapshot, error) {
+	rootDir := runtipiRootDir(r.conn)
+	extractDir := filepath.Join(r.s.cfg.Dirs.Extract, fmt.Sprintf("runtipi_%s", time.Now().Format("20060102_150405")))
+	rawDir := filepath.Join(extractDir, ".runtipi")
+	if err := os.MkdirAll(rawDir, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to create download directory: %v", err)
+	}
+
+	progressCallback(10, "Syncing Runtipi apps")
+	for _, dir := range []string{"apps", "app-data"} {
+		if err := rsyncFrom(ctx, r.conn, path.Join(rootDir, dir)+"/", filepath.Join(rawDir, dir)); err != nil {
+			os.RemoveAll(extractDir)
+			return nil, fmt.Errorf("Failed to sync %s: %v", dir, err)
+		}
+		progressCallback(20, fmt.Sprintf("Synced %s", dir))
+	}
+
+	// 根目录的.env包含ROOT_FOLDER_HOST等全局变量，不存在时忽略
+	globalVars := make(map[string]string)
+	if err := rsyncFrom(ctx, r.conn, path.Join(rootDir, ".env"), filepath.Join(rawDir, ".env")); err == nil {
+		if content, err := os.ReadFile(filepath.Join(rawDir, ".env")); err == nil {
+			globalVars = parseEnvFile(string(content))
+		}
+	}
+
+	progressCallback(40, "Download succeeded")
+	progressCallback(45, "Converting Runtipi apps")
+
+	apps, err := findRuntipiApps(rawDir)
+	if err != nil {
+		os.RemoveAll(extractDir)
+		return nil, err
+	}
+
+	appsDir := filepath.Join(extractDir, "var/lib/casaos/apps")
+	appDataRoot := filepath.Join(extractDir, "DATA/AppData")
+	for _, dir := range []string{appsDir, appDataRoot} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			os.RemoveAll(extractDir)
+			return nil, fmt.Errorf("Failed to create directory: %v", err)
+		}
+	}
+
+	for i, app := range apps {
+		progressCallback(45+(i*15)/len(apps), fmt.Sprintf("Converting: %s", app.Name))
+
+		if err := r.convertApp(app, globalVars, appsDir, appDataRoot); err != nil {
+			r.s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Runtipi app %s skipped: %v", app.Name, err))
+			continue
+		}
+		r.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Runtipi app %s converted", app.Name))
+	}
+
+	if err := os.RemoveAll(rawDir); err != nil {
+		log.Printf("[WARNING] Failed to remove Runtipi raw directory: %v", err)
+	}
+
+	progressCallback(60, "Extraction succeeded")
+	return &SourceSnapshot{ExtractedPath: extractDir}, nil
+}
+
+// convertApp 转换单个Runtipi应用的compose和数据目录
+func (r *runtipiSource) convertApp(app runtipiApp, globalVars map[string]string, appsDir, appDataRoot string) error {
+	content, err := os.ReadFile(filepath.Join(app.AppDir, "docker-compose.yml"))
+	if err != nil {
+		return fmt.Errorf("Failed to read compose file: %v", err)
+	}
+
+	// 变量优先级：应用app.env > 全局.env，APP_DATA_DIR指向迁移后的AppData目录
+	vars := make(map[string]string)
+	for k, v := range globalVars {
+		vars[k] = v
+	}
+	if envContent, err := os.ReadFile(filepath.Join(app.AppDataDir, "app.env")); err == nil {
+		for k, v := range parseEnvFile(string(envContent)) {
+			vars[k] = v
+		}
+	}
+	vars["APP_DATA_DIR"] = path.Join(casaOSAppDataDir, app.Name)
+
+	doc, err := parseCompose(interpolateEnv(string(content), vars))
+	if err != nil {
+		return err
+	}
+	doc.RemoveNetwork(runtipiMainNetwork)
+	composeContent, err := doc.String()
+	if err != nil {
+		return err
+	}
+
+	return writeSourceApp(appsDir, appDataRoot, app.Name, composeContent, app.AppDataDir)
+}
+
+// findRuntipiApps 查找本地拉取目录中的应用
+// 兼容旧布局 apps/<app> 与按应用商店分组的新布局 apps/<store>/<app>
+func findRuntipiApps(rawDir string) ([]runtipiApp, error) {
+	appsDir := filepath.Join(rawDir, "apps")
+	entries, err := os.ReadDir(appsDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read Runtipi apps directory: %v", err)
+	}
+
+	var apps []runtipiApp
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(appsDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "docker-compose.yml")); err == nil {
+			apps = append(apps, runtipiApp{
+				Name:       entry.Name(),
+				AppDir:     dir,
+				AppDataDir: filepath.Join(rawDir, "app-data", entry.Name()),
+			})
+			continue
+		}
+
+		subEntries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, sub := range subEntries {
+			subDir := filepath.Join(dir, sub.Name())
+			if _, err := os.Stat(filepath.Join(subDir, "docker-compose.yml")); sub.IsDir() && err == nil {
+				apps = append(apps, runtipiApp{
+					Name:       sub.Name(),
+					AppDir:     subDir,
+					AppDataDir: filepath.Join(rawDir, "app-data", entry.Name(), sub.Name()),
+				})
+			}
+		}
+	}
+
+	log.Printf("[INFO] Found %d Runtipi apps", len(apps))
+	return apps, nil
+}
+
+// runtipiRootDir 返回Runtipi安装目录，相对路径基于SSH用户主目录
+func runtipiRootDir(conn *models.SystemConnection) string {
+	rootDir := strings.TrimRight(strings.TrimPrefix(conn.RootDir, "~/"), "/")
+	if rootDir == "" {
+		return defaultRuntipiRootDir
+	}
+	return rootDir
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// s3DefaultRegion 未指定区域时使用的区域
+	s3DefaultRegion = "us-east-1"
+	// s3MinPartSize 分片上传的最小分片大小，小于该大小的文件直接上传
+	s3MinPartSize = 64 << 20
+	// s3MaxParts S3允许的最大分片数
+	s3MaxParts = 10000
+	// s3UnsignedPayload 不对请求体计算签名，避免大文件读取两遍
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3EmptyPayloadHash 空请求体的SHA256
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// s3Client 使用AWS Signature V4访问S3兼容对象存储的简单客户端
+type s3Client struct {
+	cfg      models.S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// newS3Client 创建S3客户端并校验设置
+func newS3Client(cfg *models.S3Config) (*s3Client, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("S3 settings are required")
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key and secret key are required")
+	}
+
+	c := &s3Client{cfg: *cfg, client: &http.Client{}}
+	if c.cfg.Region == "" {
+		c.cfg.Region = s3DefaultRegion
+	}
+
+	endpoint := c.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.cfg.Region)
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid S3 endpoint: %s", c.cfg.Endpoint)
+	}
+	c.endpoint = u
+	return c, nil
+}
+
+// objectKey 在前缀下拼接对象键
+func (c *s3Client) objectKey(name string) string {
+	prefix := strings.Trim(c.cfg.Prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// location 返回对象的s3://描述
+func (c *s3Client) location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", c.cfg.Bucket, key)
+}
+
+// objectURL 返回对象的访问地址
+func (c *s3Client) objectURL(key string, query url.Values) *url.URL {
+	u := *c.endpoint
+	if c.cfg.PathStyle {
+		u.Path = u.Path + "/" + c.cfg.Bucket + "/" + key
+	} else {
+		u.Host = c.cfg.Bucket + "." + u.Host
+		u.Path = u.Path + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = s3CanonicalQuery(query)
+	return &u
+}
+
+// newRequest 创建已签名的请求
+func (c *s3Client) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Request, error) {
+	u := c.objectURL(key, query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	c.sign(req, u, payloadHash, time.Now().UTC())
+	return req, nil
+}
+
+// sign 按Signature V4为请求签名
+func (c *s3Client) sign(req *http.Request, u *url.URL, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, c.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// do 发送请求，非2xx响应转换为错误
+func (c *s3Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %v", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &s3Err) == nil && s3Err.Code != "" {
+		return nil, fmt.Errorf("S3 %s failed: %s (%s)", req.Method, s3Err.Message, s3Err.Code)
+	}
+	return nil, fmt.Errorf("S3 %s failed: HTTP %d", req.Method, resp.StatusCode)
+}
+
+// PutFile 上传本地文件，大文件使用分片上传
+func (c *s3Client) PutFile(ctx context.Context, key, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to read file info: %v", err)
+	}
+	if info.Size() <= s3MinPartSize {
+		req, err := c.newRequest(ctx, http.MethodPut, key, nil, file, info.Size(), s3UnsignedPayload)
+		if err != nil {
+			return err
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	return c.putMultipart(ctx, key, file, info.Size())
+}
+
+// GetFile 下载对象到本地文件
+func (c *s3Client) GetFile(ctx context.Context, key, localPath string, onProgress func(read, total int64)) error {
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil, nil, 0, s3EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to create local file: %v", err)
+	}
+	total := resp.ContentLength
+	if total < 0 {
+		total = 0
+	}
+	if _, err := io.Copy(file, newProgressReader(resp.Body, total, onProgress)); err != nil {
+		file.Close()
+		os.Remove(localPath)
+		return fmt.Errorf("Failed to download s3://%s/%s: %v", c.cfg.Bucket, key, err)
+	}
+	return file.Close()
+}
+
+// putMultipart 分片上传，失败时放弃上传以释放已上传的分片
+func (c *s3Client) putMultipart(ctx context.Context, key string, file *os.File, size int64) error {
+	partSize := int64(s3MinPartSize)
+	for size/partSize >= s3MaxParts {
+		partSize *= 2
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, s3EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	var initResult struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initResult)
+	resp.Body.Close()
+	if err != nil || initResult.UploadID == "" {
+		return fmt.Errorf("Failed to start S3 multipart upload: %v", err)
+	}
+
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+	uploadErr := func() error {
+		for offset, number := int64(0), 1; offset < size; offset, number = offset+partSize, number+1 {
+			length := partSize
+			if size-offset < length {
+				length = size - offset
+			}
+			query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initResult.UploadID}}
+			req, err := c.newRequest(ctx, http.MethodPut, key, query, io.NewSectionReader(file, offset, length), length, s3UnsignedPayload)
+			if err != nil {
+				return err
+			}
+			resp, err := c.do(req)
+			if err != nil {
+				return fmt.Errorf("Failed to upload part %d: %v", number, err)
+			}
+			resp.Body.Close()
+			parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		}
+
+		body, err := xml.Marshal(struct {
+			XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+			Parts   []completedPart `xml:"Part"`
+		}{Parts: parts})
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(body)
+		req, err := c.newRequest(ctx, http.MethodPost, key, url.Values{"uploadId": {initResult.UploadID}}, bytes.NewReader(body), int64(len(body)), hex.EncodeToString(hash[:]))
+		if err != nil {
+			return err
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}()
+	if uploadErr == nil {
+		return nil
+	}
+
+	if req, err := c.newRequest(ctx, http.MethodDelete, key, url.Values{"uploadId": {initResult.UploadID}}, nil, 0, s3EmptyPayloadHash); err == nil {
+		if resp, err := c.do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+	return uploadErr
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape 按RFC 3986对字符串进行URI编码
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (ch == '/' && !encodeSlash) {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// s3EscapePath 编码对象路径，保留路径分隔符
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return s3Escape(p, false)
+}
+
+// s3CanonicalQuery 按签名要求排序并编码查询参数
+func s3CanonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
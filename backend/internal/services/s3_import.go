@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// importS3Option 任务选项中保存S3导入来源的键
+const importS3Option = "s3_source"
+
+// parseS3Source 从任务选项中读取S3导入来源，未设置时返回nil
+func parseS3Source(options map[string]interface{}) (*models.S3Object, error) {
+	value, ok := options[importS3Option]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	if src, ok := value.(*models.S3Object); ok {
+		return src, nil
+	}
+
+	// 从状态文件加载的选项是通用JSON结构
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid S3 import source: %v", err)
+	}
+	var src models.S3Object
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, fmt.Errorf("Invalid S3 import source: %v", err)
+	}
+	return &src, nil
+}
+
+// validateS3Source 校验S3导入来源
+func validateS3Source(src *models.S3Object) error {
+	if src.Key == "" {
+		return fmt.Errorf("S3 object key is required")
+	}
+	_, err := newS3Client(&src.S3Config)
+	return err
+}
+
+// importFileExists 判断导入任务的本地导入文件是否存在
+func importFileExists(task *models.MigrationTask) bool {
+	importFile, _ := task.Options["import_file"].(string)
+	if importFile == "" {
+		return false
+	}
+	_, err := os.Stat(importFile)
+	return err == nil
+}
+
+// downloadImportFromS3 把S3中的导入文件下载到上传目录，返回本地路径
+func (s *MigrationService) downloadImportFromS3(ctx context.Context, taskID string, src *models.S3Object, progressCallback func(int, string)) (string, error) {
+	client, err := newS3Client(&src.S3Config)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(s.cfg.Dirs.Upload, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create upload directory: %v", err)
+	}
+
+	localPath := filepath.Join(s.cfg.Dirs.Upload, fmt.Sprintf("import_%s_%s", time.Now().Format("20060102_150405"), path.Base(src.Key)))
+	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Downloading import file from %s", client.location(src.Key)))
+
+	lastProgress, lastReported := 0, int64(0)
+	err = client.GetFile(ctx, src.Key, localPath, func(read, total int64) {
+		if total > 0 {
+			if progress := int(95 * read / total); progress > lastProgress {
+				lastProgress = progress
+				progressCallback(progress, fmt.Sprintf("Downloading: %d/%d bytes (%d%%)", read, total, read*100/total))
+			}
+			return
+		}
+		if read-lastReported >= downloadReportStep {
+			lastReported = read
+			progressCallback(50, fmt.Sprintf("Downloading: %d bytes", read))
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return localPath, nil
+}
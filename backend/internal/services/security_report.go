@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// allowPrivilegedAppsOption 迁移选项：确认迁移使用特权模式、主机网络、额外权限或docker.sock的应用
+const allowPrivilegedAppsOption = "allow_privileged_apps"
+
+// 高权限配置类型
+const (
+	findingPrivileged   = "privileged"
+	findingHostNetwork  = "host_network"
+	findingCapAdd       = "cap_add"
+	findingDockerSocket = "docker_socket"
+)
+
+// dockerSocketPaths 主机上Docker守护进程的套接字路径
+var dockerSocketPaths = map[string]bool{"/var/run/docker.sock": true, "/run/docker.sock": true}
+
+// SecurityFindings 扫描服务的privileged、network_mode: host、cap_add和docker.sock挂载
+func (d *composeDocument) SecurityFindings() []models.SecurityFinding {
+	var findings []models.SecurityFinding
+	for _, svc := range d.Services() {
+		if value, ok := mapGet(svc.Config, "privileged"); ok && fmt.Sprint(value) == "true" {
+			findings = append(findings, models.SecurityFinding{Service: svc.Name, Kind: findingPrivileged})
+		}
+		if value, ok := mapGet(svc.Config, "network_mode"); ok && fmt.Sprint(value) == "host" {
+			findings = append(findings, models.SecurityFinding{Service: svc.Name, Kind: findingHostNetwork})
+		}
+		if value, ok := mapGet(svc.Config, "cap_add"); ok {
+			caps, _ := value.([]interface{})
+			names := make([]string, 0, len(caps))
+			for _, c := range caps {
+				names = append(names, fmt.Sprint(c))
+			}
+			if len(names) > 0 {
+				findings = append(findings, models.SecurityFinding{Service: svc.Name, Kind: findingCapAdd, Detail: strings.Join(names, ", ")})
+			}
+		}
+		value, _ := mapGet(svc.Config, "volumes")
+		for _, source := range mountSources(value) {
+			if dockerSocketPaths[source] {
+				findings = append(findings, models.SecurityFinding{Service: svc.Name, Kind: findingDockerSocket, Detail: source})
+			}
+		}
+	}
+	return findings
+}
+
+// reportPrivilegedApps 生成高权限应用报告并写入应用状态
+// 未设置allow_privileged_apps时这些应用不迁移数据也不导入compose，标记为警告等待确认
+func (s *MigrationService) reportPrivilegedApps(task *models.MigrationTask, sourceData map[string]interface{}, appStatuses []models.AppImportStatus) {
+	composeFiles, _ := sourceData["composeFiles"].(map[string]string)
+	allowed, _ := task.Options[allowPrivilegedAppsOption].(bool)
+
+	var flagged []string
+	for i := range appStatuses {
+		appName := appStatuses[i].AppName
+		doc, err := parseCompose(composeFiles[appName])
+		if err != nil {
+			continue
+		}
+		findings := doc.SecurityFindings()
+		if len(findings) == 0 {
+			continue
+		}
+
+		appStatuses[i].SecurityFindings = findings
+		flagged = append(flagged, fmt.Sprintf("%s (%s)", appName, describeFindings(findings)))
+
+		// 之前已导入的应用（断点续传）不再拦截
+		if allowed || appStatuses[i].ComposeStatus == models.AppStatusSuccess {
+			continue
+		}
+		appStatuses[i].AppDataStatus = models.AppStatusSkipped
+		appStatuses[i].ComposeStatus = models.AppStatusSkipped
+		appStatuses[i].OverallStatus = models.AppStatusWarning
+		appStatuses[i].Warnings = append(appStatuses[i].Warnings, fmt.Sprintf("App uses %s. Review its compose file and set %s to migrate it.", describeFindings(findings), allowPrivilegedAppsOption))
+	}
+	if len(flagged) == 0 {
+		return
+	}
+
+	sort.Strings(flagged)
+	s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Security report: %d apps use privileged or host-level access: %s", len(flagged), strings.Join(flagged, "; ")))
+	if allowed {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Migrating flagged apps because %s is set", allowPrivilegedAppsOption))
+	} else {
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Flagged apps are not migrated until %s is set", allowPrivilegedAppsOption))
+	}
+	s.saveAppImportStatuses(task.ID, appStatuses)
+}
+
+// describeFindings 生成高权限配置的简短说明，如 "privileged in app, cap_add NET_ADMIN in app"
+func describeFindings(findings []models.SecurityFinding) string {
+	parts := make([]string, 0, len(findings))
+	for _, f := range findings {
+		kind := f.Kind
+		if f.Detail != "" {
+			kind += " " + f.Detail
+		}
+		parts = append(parts, fmt.Sprintf("%s in %s", kind, f.Service))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// appHeld 判断应用是否因等待确认而暂不迁移
+func appHeld(appStatuses []models.AppImportStatus, appName string) bool {
+	for _, status := range appStatuses {
+		if status.AppName == appName {
+			return status.OverallStatus == models.AppStatusWarning
+		}
+	}
+	return false
+}
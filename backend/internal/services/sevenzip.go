@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sevenZipCommands 按顺序查找的7-Zip命令，7zz为官方Linux版本，7z/7za为p7zip
+var sevenZipCommands = []string{"7zz", "7z", "7za"}
+
+// sevenZipEntry 7z压缩包中的一个条目
+type sevenZipEntry struct {
+	Path      string
+	Size      int64
+	Dir       bool
+	Symlink   bool
+	Encrypted bool
+}
+
+// findSevenZip 查找可用的7-Zip命令
+func findSevenZip() (string, error) {
+	for _, name := range sevenZipCommands {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("7-Zip command not found (7zz, 7z or 7za), it is required to import .7z archives")
+}
+
+// extract7zFile 解压7z文件
+// 7z格式没有标准库支持，先列出条目检查路径和大小，再调用7-Zip解压，最后检查解压出的符号链接和实际大小
+func (s *MigrationService) extract7zFile(ctx context.Context, src, dest string) error {
+	bin, err := findSevenZip()
+	if err != nil {
+		return err
+	}
+
+	entries, err := listSevenZip(bin, src)
+	if err != nil {
+		return err
+	}
+	if err := checkSevenZipEntries(dest, entries); err != nil {
+		return err
+	}
+
+	// 解压前按声明大小检查压缩炸弹
+	limiter := s.newExtractLimiter(src)
+	if err := limiter.checkSevenZip(entries); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("Failed to create destination directory: %v", err)
+	}
+
+	log.Printf("[DEBUG] Starting to extract 7z file: %s -> %s (%d entries)", src, dest, len(entries))
+
+	// 标准输入为空，加密的压缩包不会等待输入密码
+	cmd := exec.CommandContext(ctx, bin, "x", "-y", "-bd", "-o"+dest, src)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Failed to extract 7z file: %v - %s", err, lastLines(string(output), 5))
+	}
+
+	if err := sanitizeExtractedTree(dest, limiter); err != nil {
+		return err
+	}
+	if err := sanitizeExtractedManifests(dest); err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] 7z file extraction completed: %s", src)
+	return nil
+}
+
+// listSevenZip 列出7z压缩包中的条目
+func listSevenZip(bin, src string) ([]sevenZipEntry, error) {
+	output, err := exec.Command(bin, "l", "-slt", "-bd", src).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list 7z file: %v - %s", err, lastLines(string(output), 5))
+	}
+	return parseSevenZipListing(output), nil
+}
+
+// parseSevenZipListing 解析 7z l -slt 的输出
+// 条目位于分隔线 ---------- 之后，每个条目是若干 "键 = 值" 行，以空行分隔
+func parseSevenZipListing(output []byte) []sevenZipEntry {
+	var entries []sevenZipEntry
+	var current *sevenZipEntry
+	inEntries := false
+
+	flush := func() {
+		if current != nil && current.Path != "" {
+			entries = append(entries, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !inEntries {
+			inEntries = line == "----------"
+			continue
+		}
+		if line == "" {
+			flush()
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		if current == nil {
+			current = &sevenZipEntry{}
+		}
+		switch key {
+		case "Path":
+			current.Path = value
+		case "Size":
+			current.Size, _ = strconv.ParseInt(value, 10, 64)
+		case "Folder":
+			current.Dir = value == "+"
+		case "Encrypted":
+			current.Encrypted = value == "+"
+		case "Attributes":
+			// 形如 "D_ drwxr-xr-x" 或 "A_ lrwxrwxrwx"，前半部分为Windows属性，后半部分为Unix权限
+			winAttrs, unixMode, _ := strings.Cut(value, " ")
+			if strings.Contains(winAttrs, "D") || strings.HasPrefix(unixMode, "d") {
+				current.Dir = true
+			}
+			if strings.HasPrefix(unixMode, "l") {
+				current.Symlink = true
+			}
+		}
+	}
+	flush()
+	return entries
+}
+
+// checkSevenZipEntries 检查条目路径，拒绝加密条目、目录遍历和经由符号链接写入的条目
+func checkSevenZipEntries(dest string, entries []sevenZipEntry) error {
+	var links []string
+	for _, entry := range entries {
+		if entry.Symlink {
+			links = append(links, filepath.Join(dest, entry.Path))
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Encrypted {
+			return fmt.Errorf("Encrypted 7z archives are not supported: %s", entry.Path)
+		}
+		target := filepath.Join(dest, entry.Path)
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("Insecure file path: %s", entry.Path)
+		}
+		for _, link := range links {
+			if strings.HasPrefix(target, link+string(os.PathSeparator)) {
+				return fmt.Errorf("Insecure file path (through symlink): %s", entry.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// sanitizeExtractedTree 删除指向解压目录之外的相对符号链接，并按实际大小检查解压限制
+// 与tar/ZIP解压时的规则一致，绝对路径的符号链接保留，导入后在目标系统上解析
+func sanitizeExtractedTree(dest string, limiter *extractLimiter) error {
+	var total int64
+	return filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			linkname, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("Failed to read symlink: %s - %v", path, err)
+			}
+			if !filepath.IsAbs(linkname) && !isWithinDir(dest, filepath.Join(filepath.Dir(path), linkname)) {
+				log.Printf("[WARNING] Removing symlink %s -> %s: target escapes extraction directory", path, linkname)
+				return os.Remove(path)
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if max := limiter.limits.MaxFileBytes; max > 0 && info.Size() > max {
+			return fmt.Errorf("Archive exceeds extraction limit: %s is larger than %d bytes (CTOZ_MAX_EXTRACT_FILE_BYTES)", path, max)
+		}
+		total += info.Size()
+		return limiter.checkTotal(total)
+	})
+}
+
+// lastLines 返回命令输出的最后几行，用于错误信息
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.TrimSpace(strings.Join(lines, " "))
+}
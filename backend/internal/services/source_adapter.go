@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ctoz/backend/internal/models"
+)
+
+// SourceSnapshot 源系统数据的本地快照
+// ExtractedPath 按CasaOS布局组织：var/lib/casaos/apps/<app>/docker-compose.yml 与 DATA/AppData/<app>
+type SourceSnapshot struct {
+	DownloadPath  string // 下载的原始文件，可为空
+	ExtractedPath string
+	SkippedApps   map[string]string // 无法迁移的应用及原因
+}
+
+// SourceAdapter 迁移源适配器，把不同源系统的数据转换为统一的本地快照
+type SourceAdapter interface {
+	// Name 源类型名称
+	Name() string
+	// Fetch 获取源系统的应用配置和数据
+	Fetch(ctx context.Context, taskID string, progressCallback func(int, string)) (*SourceSnapshot, error)
+}
+
+// sourceAdapter 根据源连接类型选择适配器
+func (s *MigrationService) sourceAdapter(source *models.SystemConnection, options map[string]interface{}) (SourceAdapter, error) {
+	if source == nil {
+		return nil, fmt.Errorf("Source connection is required")
+	}
+
+	switch source.Type {
+	case models.SystemTypeRuntipi:
+		return &runtipiSource{s: s, conn: source}, nil
+	case models.SystemTypeTrueNAS:
+		return &trueNASSource{s: s, conn: source}, nil
+	default:
+		return &casaOSSource{s: s, conn: source, options: options}, nil
+	}
+}
+
+// casaOSSource CasaOS源适配器，通过批量下载API获取apps和AppData
+type casaOSSource struct {
+	s       *MigrationService
+	conn    *models.SystemConnection
+	options map[string]interface{}
+}
+
+// Name 源类型名称
+func (c *casaOSSource) Name() string {
+	return models.SystemTypeCasaOS
+}
+
+// Fetch 下载并解压CasaOS文件
+func (c *casaOSSource) Fetch(ctx context.Context, taskID string, progressCallback func(int, string)) (*SourceSnapshot, error) {
+	// 存在pin镜像规则时先读取源上镜像的摘要
+	c.s.captureSourceImageDigests(ctx, taskID, c.conn)
+
+	// 下载CasaOS文件，下载前按迁移选项导出数据库、停止源应用
+	var downloadPath string
+	err := c.s.withSourceQuiesced(ctx, taskID, c.conn, c.options, func() error {
+		var err error
+		downloadPath, err = c.s.downloadCasaOSFiles(ctx, c.conn, progressCallback)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download files: %v", err)
+	}
+
+	progressCallback(40, "Download succeeded")
+	progressCallback(45, "Extracting")
+
+	// 解压下载的文件
+	extractedPath, err := c.s.extractDownloadedFiles(downloadPath, progressCallback)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to extract files: %v", err)
+	}
+
+	return &SourceSnapshot{DownloadPath: downloadPath, ExtractedPath: extractedPath}, nil
+}
+
+// writeSourceApp 将转换后的应用写入CasaOS布局的快照目录
+func writeSourceApp(appsDir, appDataRoot, appName, composeContent, dataDir string) error {
+	appDir := filepath.Join(appsDir, appName)
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		return fmt.Errorf("Failed to create app directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(composeContent), 0644); err != nil {
+		return fmt.Errorf("Failed to write compose file: %v", err)
+	}
+
+	if dataDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(dataDir); err == nil {
+		if err := os.Rename(dataDir, filepath.Join(appDataRoot, appName)); err != nil {
+			return fmt.Errorf("Failed to move app data: %v", err)
+		}
+	}
+	return nil
+}
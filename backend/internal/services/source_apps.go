@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"ctoz/backend/internal/models"
+)
+
+// stopSourceAppsOption 迁移选项：复制数据前停止源系统上运行中的应用，复制完成后重新启动
+const stopSourceAppsOption = "stop_source_apps"
+
+// withSourceQuiesced 按迁移选项在fn复制数据前导出CasaOS上的数据库（database_dumps）、保存应用镜像（transfer_images）并停止运行中的compose应用（stop_source_apps），
+// 结束后重新启动应用并删除源系统上的转储文件和镜像包；这些操作失败只记录警告，不影响数据复制
+func (s *MigrationService) withSourceQuiesced(ctx context.Context, taskID string, conn *models.SystemConnection, options map[string]interface{}, fn func() error) error {
+	if dumps, ok := options[databaseDumpsOption].(bool); ok && dumps {
+		dumpDirs := s.dumpSourceDatabases(ctx, taskID, conn)
+		defer s.removeSourceDumps(ctx, conn, dumpDirs)
+	}
+	if transferImages(options) {
+		imageDirs := s.saveSourceImages(ctx, taskID, conn)
+		defer s.removeSourceDumps(ctx, conn, imageDirs)
+	}
+	if stop, ok := options[stopSourceAppsOption].(bool); ok && stop {
+		stopped := s.stopSourceApps(ctx, taskID, conn)
+		defer s.restartSourceApps(ctx, taskID, conn, stopped)
+	}
+	return fn()
+}
+
+// stopSourceApps 停止源系统上运行中的compose应用，返回已停止的应用
+func (s *MigrationService) stopSourceApps(ctx context.Context, taskID string, conn *models.SystemConnection) []string {
+	apps, err := s.getSystemApps(ctx, conn)
+	if err != nil {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Could not list source apps, apps were not stopped: %v", err))
+		return nil
+	}
+
+	var stopped []string
+	for _, app := range apps {
+		if app.Status != "running" {
+			continue
+		}
+		// 旧版单容器应用无法通过compose接口控制
+		if app.AppType != "" && app.AppType != "v2app" {
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("App %s is not a compose app and was not stopped", app.Name))
+			continue
+		}
+		if err := s.setComposeStatus(ctx, conn, app.Name, "stopped"); err != nil {
+			log.Printf("[WARNING] Failed to stop app %s on %s: %v", app.Name, conn.Host, err)
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Failed to stop app %s on source: %v", app.Name, err))
+			continue
+		}
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s stopped on source", app.Name))
+		stopped = append(stopped, app.Name)
+	}
+	return stopped
+}
+
+// restartSourceApps 重新启动之前停止的应用
+func (s *MigrationService) restartSourceApps(ctx context.Context, taskID string, conn *models.SystemConnection, apps []string) {
+	for _, appName := range apps {
+		if err := s.setComposeStatus(ctx, conn, appName, "running"); err != nil {
+			log.Printf("[WARNING] Failed to restart app %s on %s: %v", appName, conn.Host, err)
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Failed to restart app %s on source: %v", appName, err))
+			continue
+		}
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("App %s restarted on source", appName))
+	}
+}
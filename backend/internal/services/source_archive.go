@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// keepSourceArchiveOption 迁移选项：保留从源系统下载的备份，清理步骤将其移动到归档目录而不是删除
+const keepSourceArchiveOption = "keep_source_archive"
+
+// cleanupDownload 清理下载的源系统备份，开启keep_source_archive时移动到归档目录
+// 返回保留的归档路径，未保留时为空
+func (s *MigrationService) cleanupDownload(task *models.MigrationTask, downloadPath string) string {
+	if keep, ok := task.Options[keepSourceArchiveOption].(bool); ok && keep {
+		archivePath, err := s.keepSourceArchive(task, downloadPath)
+		if err == nil {
+			s.taskService.AddTaskLog(task.ID, models.LogLevelInfo, fmt.Sprintf("Source archive kept at %s", archivePath))
+			return archivePath
+		}
+		// 保留失败时不删除下载的文件，由用户或定期清理处理
+		log.Printf("[WARNING] Failed to keep source archive: %v", err)
+		s.taskService.AddTaskLog(task.ID, models.LogLevelWarning, fmt.Sprintf("Failed to keep source archive, leaving it at %s: %v", downloadPath, err))
+		return ""
+	}
+
+	if err := os.Remove(downloadPath); err != nil {
+		log.Printf("[WARNING] Failed to remove downloaded file: %v", err)
+	} else {
+		log.Printf("[DEBUG] Downloaded file removed: %s", downloadPath)
+	}
+	return ""
+}
+
+// keepSourceArchive 将下载的备份移动到归档目录，文件名包含源系统类型、地址和时间
+func (s *MigrationService) keepSourceArchive(task *models.MigrationTask, downloadPath string) (string, error) {
+	archiveDir := s.cfg.Dirs.Archive
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create archive directory: %v", err)
+	}
+
+	archivePath := filepath.Join(archiveDir, sourceArchiveName(task.Source, filepath.Ext(downloadPath), time.Now()))
+	if err := os.Rename(downloadPath, archivePath); err != nil {
+		// 归档目录可能在其它文件系统上，改为复制后删除
+		if err := copyFileContents(downloadPath, archivePath); err != nil {
+			os.Remove(archivePath)
+			return "", err
+		}
+		if err := os.Remove(downloadPath); err != nil {
+			log.Printf("[WARNING] Failed to remove downloaded file: %v", err)
+		}
+	}
+	return archivePath, nil
+}
+
+// sourceArchiveName 生成归档文件名，如 casaos_192.168.1.10_20240102_150405.zip
+func sourceArchiveName(source *models.SystemConnection, ext string, t time.Time) string {
+	sourceType, host := "source", "unknown"
+	if source != nil {
+		if source.Type != "" {
+			sourceType = source.Type
+		}
+		if source.Host != "" {
+			host = source.Host
+		}
+	}
+	// 地址中可能包含端口或IPv6冒号，替换为文件名安全的字符
+	host = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, host)
+	return fmt.Sprintf("%s_%s_%s%s", sourceType, host, t.Format("20060102_150405"), ext)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"io"
+	"os"
+)
+
+// sparseBlockSize 检测空洞的块大小
+const sparseBlockSize = 32 * 1024
+
+// copySparse 将内容写入文件，全零块通过Seek跳过以保留稀疏文件的空洞
+// 返回写入的逻辑字节数
+func copySparse(f *os.File, r io.Reader) (int64, error) {
+	buf := make([]byte, sparseBlockSize)
+	var written int64
+	hole := false
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if isZeroBlock(buf[:n]) {
+				if _, serr := f.Seek(int64(n), io.SeekCurrent); serr != nil {
+					return written, serr
+				}
+				hole = true
+			} else {
+				if _, werr := f.Write(buf[:n]); werr != nil {
+					return written, werr
+				}
+				hole = false
+			}
+			written += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+
+	// 以空洞结尾时需要扩展文件到实际大小
+	if hole {
+		if err := f.Truncate(written); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// isZeroBlock 判断数据块是否全为0
+func isZeroBlock(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
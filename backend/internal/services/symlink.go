@@ -0,0 +1,171 @@
+package services
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinkTargetLen ZIP符号链接条目内容（链接目标）的最大长度
+const maxSymlinkTargetLen = 4096
+
+// isSymlink 判断文件是否为符号链接
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// isWithinDir 判断path是否位于dir之内（两者需为同一种形式的路径）
+func isWithinDir(dir, path string) bool {
+	dir = filepath.Clean(dir)
+	path = filepath.Clean(path)
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// prepareExtractPath 为解压条目创建父目录，并确保写入不会经由已解压的符号链接逃出解压目录
+func prepareExtractPath(root, target string) error {
+	parentDir := filepath.Dir(target)
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return fmt.Errorf("Failed to create parent directory: %s - %v", parentDir, err)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve destination directory: %v", err)
+	}
+	realParent, err := filepath.EvalSymlinks(parentDir)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve parent directory: %s - %v", parentDir, err)
+	}
+	if !isWithinDir(realRoot, realParent) {
+		return fmt.Errorf("Insecure file path (through symlink): %s", target)
+	}
+
+	// 已存在的同名符号链接先删除，避免写入时跟随链接
+	if info, err := os.Lstat(target); err == nil && isSymlink(info) {
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("Failed to replace symlink: %s - %v", target, err)
+		}
+	}
+	return nil
+}
+
+// extractSymlink 在解压目录中创建符号链接
+// 相对链接的目标必须位于解压目录内，否则跳过；绝对链接指向目标系统上的路径，原样保留
+func extractSymlink(root, target, linkname string) (bool, error) {
+	if linkname == "" || strings.ContainsRune(linkname, 0) {
+		log.Printf("[WARNING] Skipping symlink with invalid target: %s", target)
+		return false, nil
+	}
+	if !filepath.IsAbs(linkname) && !isWithinDir(root, filepath.Join(filepath.Dir(target), linkname)) {
+		log.Printf("[WARNING] Skipping symlink %s -> %s: target escapes extraction directory", target, linkname)
+		return false, nil
+	}
+
+	if err := prepareExtractPath(root, target); err != nil {
+		return false, err
+	}
+	if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+		if err := os.Remove(target); err != nil {
+			return false, fmt.Errorf("Failed to replace file with symlink: %s - %v", target, err)
+		}
+	}
+	if err := os.Symlink(linkname, target); err != nil {
+		return false, fmt.Errorf("Failed to create symlink: %s - %v", target, err)
+	}
+	return true, nil
+}
+
+// zipSymlinkTarget 读取ZIP符号链接条目中保存的链接目标
+func zipSymlinkTarget(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("Failed to open file inside ZIP: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTargetLen+1))
+	if err != nil {
+		return "", fmt.Errorf("Failed to read symlink target: %v", err)
+	}
+	if len(data) > maxSymlinkTargetLen {
+		return "", fmt.Errorf("Symlink target too long: %s", f.Name)
+	}
+	return string(data), nil
+}
+
+// isZipSymlink 判断ZIP条目是否为Unix符号链接
+func isZipSymlink(f *zip.File) bool {
+	return f.CreatorVersion>>8 == zipCreatorUnix && f.Mode()&os.ModeSymlink != 0
+}
+
+// writeZipSymlink 将符号链接以链接目标为内容写入ZIP（Info-ZIP约定），不跟随链接
+func writeZipSymlink(zipWriter *zip.Writer, header *zip.FileHeader, linkPath string) error {
+	linkname, err := os.Readlink(linkPath)
+	if err != nil {
+		return err
+	}
+
+	header.Method = zip.Store
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, linkname)
+	return err
+}
+
+// extractHardlink 为tar硬链接条目创建硬链接，链接源必须是解压目录内已解压的普通文件
+// 无法创建硬链接时（如跨文件系统）退化为复制
+func extractHardlink(root, target, linkname string) error {
+	source := filepath.Join(root, linkname)
+	if !isWithinDir(root, source) || source == filepath.Clean(root) {
+		return fmt.Errorf("Insecure hardlink target: %s -> %s", target, linkname)
+	}
+	// 链接源的上级目录不能经由符号链接指向解压目录之外
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve destination directory: %v", err)
+	}
+	realSourceDir, err := filepath.EvalSymlinks(filepath.Dir(source))
+	if err != nil || !isWithinDir(realRoot, realSourceDir) {
+		return fmt.Errorf("Insecure hardlink target: %s -> %s", target, linkname)
+	}
+	info, err := os.Lstat(source)
+	if err != nil {
+		return fmt.Errorf("Hardlink target not found: %s -> %s", target, linkname)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("Hardlink target is not a regular file: %s -> %s", target, linkname)
+	}
+
+	if err := prepareExtractPath(root, target); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("Failed to replace file with hardlink: %s - %v", target, err)
+		}
+	}
+	if err := os.Link(source, target); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("Failed to open hardlink target: %v", err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("Failed to create file: %s - %v", target, err)
+	}
+	defer dst.Close()
+	if _, err := copySparse(dst, src); err != nil {
+		return fmt.Errorf("Failed to copy hardlink target: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// synologyComposeNames Container Manager项目支持的compose文件名
+var synologyComposeNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// synologyVolumePattern 匹配Synology存储卷路径（/volume1、/volume2 ...）
+var synologyVolumePattern = regexp.MustCompile(`^/?volume\d+(/|$)`)
+
+// synologyDefaultProjectRoot Container Manager默认的项目共享文件夹
+const synologyDefaultProjectRoot = "/volume1/docker"
+
+// synologyProject 导入包中的Container Manager项目
+type synologyProject struct {
+	Name        string
+	Dir         string // 本地解压目录
+	ComposeFile string
+	OriginPath  string // 项目在Synology上的绝对路径
+}
+
+// isSynologyProjectExport 判断解压目录是否为Synology Container Manager项目导出
+// 不含CasaOS结构、且包含volumeN目录或直接包含项目compose文件时视为Synology导出
+func isSynologyProjectExport(extractDir string) bool {
+	if _, err := os.Stat(filepath.Join(extractDir, "var/lib/casaos/apps")); err == nil {
+		return false
+	}
+
+	projects, err := findSynologyProjects(extractDir)
+	return err == nil && len(projects) > 0
+}
+
+// findSynologyProjects 查找所有包含compose文件的项目目录，项目目录内部不再继续查找
+func findSynologyProjects(extractDir string) ([]synologyProject, error) {
+	var projects []synologyProject
+	err := filepath.Walk(extractDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || filePath == extractDir {
+			return err
+		}
+
+		for _, name := range synologyComposeNames {
+			composeFile := filepath.Join(filePath, name)
+			if _, err := os.Stat(composeFile); err != nil {
+				continue
+			}
+
+			relPath, err := filepath.Rel(extractDir, filePath)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+
+			// 导出包保留了volumeN路径时可得到原始位置，否则按默认项目目录推断
+			originPath := path.Join(synologyDefaultProjectRoot, info.Name())
+			if synologyVolumePattern.MatchString(relPath) {
+				originPath = "/" + relPath
+			}
+
+			projects = append(projects, synologyProject{
+				Name:        info.Name(),
+				Dir:         filePath,
+				ComposeFile: composeFile,
+				OriginPath:  originPath,
+			})
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return projects, err
+}
+
+// convertSynologyProjects 将Container Manager项目转换为CasaOS导入结构
+// 项目目录作为应用AppData，其它/volumeN共享文件夹路径映射到ZimaOS数据根目录
+func (s *MigrationService) convertSynologyProjects(taskID, extractDir string) error {
+	projects, err := findSynologyProjects(extractDir)
+	if err != nil {
+		return fmt.Errorf("Failed to scan Synology projects: %v", err)
+	}
+
+	appsDir := filepath.Join(extractDir, "var/lib/casaos/apps")
+	appDataRoot := filepath.Join(extractDir, "DATA/AppData")
+	for _, dir := range []string{appsDir, appDataRoot} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("Failed to create directory: %v", err)
+		}
+	}
+
+	dataRoot := s.cfg.ZimaOSDataRoot
+	for _, project := range projects {
+		content, err := os.ReadFile(project.ComposeFile)
+		if err != nil {
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Synology project %s skipped: %v", project.Name, err))
+			continue
+		}
+
+		// Container Manager支持项目目录下的.env
+		vars := make(map[string]string)
+		if envContent, err := os.ReadFile(filepath.Join(project.Dir, ".env")); err == nil {
+			vars = parseEnvFile(string(envContent))
+		}
+
+		doc, err := parseCompose(interpolateEnv(string(content), vars))
+		if err != nil {
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Synology project %s skipped: %v", project.Name, err))
+			continue
+		}
+
+		appDataPrefix := path.Join(casaOSAppDataDir, project.Name)
+		sharePaths := make(map[string]bool)
+		doc.RewriteBindSources(func(service, source string) string {
+			if !strings.HasPrefix(source, "/") {
+				source = path.Join(project.OriginPath, source)
+			}
+			if source == project.OriginPath || strings.HasPrefix(source, project.OriginPath+"/") {
+				return appDataPrefix + strings.TrimPrefix(source, project.OriginPath)
+			}
+			if loc := synologyVolumePattern.FindStringIndex(source); loc != nil {
+				mapped := path.Join(dataRoot, source[loc[1]:])
+				sharePaths[mapped] = true
+				return mapped
+			}
+			return source
+		})
+
+		composeContent, err := doc.String()
+		if err != nil {
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Synology project %s skipped: %v", project.Name, err))
+			continue
+		}
+
+		// 先写compose再移动项目目录，compose文件本身不作为AppData保留
+		if err := writeSourceApp(appsDir, appDataRoot, project.Name, composeContent, ""); err != nil {
+			return err
+		}
+		if err := os.Remove(project.ComposeFile); err != nil {
+			log.Printf("[WARNING] Failed to remove original compose file: %v", err)
+		}
+		if err := os.Rename(project.Dir, filepath.Join(appDataRoot, project.Name)); err != nil {
+			return fmt.Errorf("Failed to move project %s data: %v", project.Name, err)
+		}
+
+		s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Synology project %s converted", project.Name))
+		for sharePath := range sharePaths {
+			s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Project %s mounts shared folder %s; make sure its contents exist on the target", project.Name, sharePath))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// TargetAdapter 迁移目标适配器，屏蔽不同目标系统的数据上传和应用导入差异
+type TargetAdapter interface {
+	// Name 目标类型名称
+	Name() string
+	// UploadAppData 将本地应用数据目录上传到目标系统
+	UploadAppData(ctx context.Context, appName, sourcePath, taskID string) error
+	// ImportCompose 将应用的compose配置导入目标系统
+	ImportCompose(ctx context.Context, appName, composeContent, taskID string) error
+	// StartApp 启动已导入的应用并等待容器运行，返回最终的运行状态
+	StartApp(ctx context.Context, appName, taskID string) (string, error)
+	// UploadAppFiles 将本地应用目录中的文件（.env和自定义配置等）写入目标上compose所在目录，需在ImportCompose之前调用
+	UploadAppFiles(ctx context.Context, appName, localDir string, files []string, taskID string) error
+	// MissingPaths 返回目标主机上不存在的路径，用于检查设备映射
+	MissingPaths(ctx context.Context, paths []string) ([]string, error)
+	// ExecInService 在应用某个compose服务的运行中容器内执行命令，stdinFile为应用AppData目录下的相对路径，不为空时作为命令输入
+	ExecInService(ctx context.Context, appName, service, command, stdinFile string) ([]byte, error)
+	// LoadImages 在目标系统上加载本地的镜像包（docker save格式）
+	LoadImages(ctx context.Context, appName, archive string) error
+}
+
+// targetAppDataDirOption 迁移选项：目标系统上AppData的基础目录，优先于存储卷选择和连接配置
+const targetAppDataDirOption = "target_appdata_dir"
+
+// targetAdapter 根据任务的目标连接类型和迁移选项选择适配器
+// 指定了AppData基础目录时在第一次上传前到目标系统上校验
+func (s *MigrationService) targetAdapter(ctx context.Context, task *models.MigrationTask) (TargetAdapter, error) {
+	target, options := task.Target, task.Options
+	if target == nil {
+		return nil, fmt.Errorf("Target connection is required")
+	}
+
+	appDataDir, err := targetAppDataDirOverride(options)
+	if err != nil {
+		return nil, err
+	}
+
+	switch target.Type {
+	case models.SystemTypeZimaOS:
+		if appDataDir == "" {
+			if appDataDir, err = s.zimaOSTargetAppDataDir(ctx, target, options); err != nil {
+				return nil, err
+			}
+		} else if err := s.validateZimaOSAppDataDir(ctx, target, appDataDir); err != nil {
+			return nil, err
+		}
+		s.logZimaOSClient(task.ID, target)
+		return &zimaOSTarget{s: s, conn: target, appDataDir: appDataDir}, nil
+	case models.SystemTypeDocker:
+		adapter := newDockerHostTarget(s, target)
+		if appDataDir != "" {
+			// 与上传时一样按需创建目录，同时确认SSH用户有写权限
+			if _, err := runSSH(ctx, target, nil, fmt.Sprintf("mkdir -p %[1]s && test -w %[1]s", shellQuote(appDataDir))); err != nil {
+				return nil, fmt.Errorf("Target AppData directory %s is not writable: %v", appDataDir, err)
+			}
+			adapter.appDataDir = appDataDir
+		}
+		return adapter, nil
+	default:
+		return nil, fmt.Errorf("Unsupported target system type: %s", target.Type)
+	}
+}
+
+// zimaOSTarget ZimaOS目标适配器，通过ZimaOS文件与应用管理API完成迁移
+type zimaOSTarget struct {
+	s          *MigrationService
+	conn       *models.SystemConnection
+	appDataDir string // 应用数据写入的目录，位于所选存储卷上
+}
+
+// Name 目标类型名称
+func (t *zimaOSTarget) Name() string {
+	return models.SystemTypeZimaOS
+}
+
+// UploadAppData 上传应用数据到ZimaOS
+func (t *zimaOSTarget) UploadAppData(ctx context.Context, appName, sourcePath, taskID string) error {
+	return t.s.uploadAppDataToZimaOS(ctx, t.conn, t.appDataDir, appName, sourcePath, taskID)
+}
+
+// ImportCompose 规范化compose后导入ZimaOS应用管理，AppData路径指向所选存储卷
+func (t *zimaOSTarget) ImportCompose(ctx context.Context, appName, composeContent, taskID string) error {
+	composeContent = t.s.normalizeComposeForTarget(appName, composeContent, taskID)
+	composeContent = rewriteZimaOSAppDataPaths(composeContent, t.appDataDir)
+	return t.s.importComposeToZimaOS(ctx, t.conn, appName, composeContent, taskID)
+}
+
+// UploadAppFiles 通过SSH将文件写入ZimaOS应用管理的应用目录，需要配置ssh_port
+func (t *zimaOSTarget) UploadAppFiles(ctx context.Context, appName, localDir string, files []string, taskID string) error {
+	if t.conn.SSHPort <= 0 {
+		return fmt.Errorf("ssh_port is not configured for the target")
+	}
+	sshConn := *t.conn
+	sshConn.Port = t.conn.SSHPort
+	return uploadFilesOverSSH(ctx, &sshConn, path.Join(casaOSAppsDir, appName), localDir, files)
+}
+
+// MissingPaths 检查ZimaOS上的路径，配置了ssh_port时通过SSH检查，否则使用文件信息API
+func (t *zimaOSTarget) MissingPaths(ctx context.Context, paths []string) ([]string, error) {
+	if t.conn.SSHPort > 0 {
+		sshConn := *t.conn
+		sshConn.Port = t.conn.SSHPort
+		return missingPathsOverSSH(ctx, &sshConn, paths)
+	}
+
+	var missing []string
+	for _, p := range paths {
+		exists, err := t.s.zimaOSPathExists(ctx, t.conn, p)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// ExecInService 通过SSH在ZimaOS上的应用容器内执行命令，需要配置ssh_port
+func (t *zimaOSTarget) ExecInService(ctx context.Context, appName, service, command, stdinFile string) ([]byte, error) {
+	if t.conn.SSHPort <= 0 {
+		return nil, fmt.Errorf("ssh_port is not configured for the target")
+	}
+	sshConn := *t.conn
+	sshConn.Port = t.conn.SSHPort
+	remoteCmd := composeServiceExec(appName, service, command)
+	if stdinFile != "" {
+		remoteCmd += " < " + shellQuote(path.Join(t.appDataDir, appName, stdinFile))
+	}
+	return runSSH(ctx, &sshConn, nil, remoteCmd)
+}
+
+// LoadImages 通过SSH在ZimaOS上加载镜像包，需要配置ssh_port
+func (t *zimaOSTarget) LoadImages(ctx context.Context, appName, archive string) error {
+	if t.conn.SSHPort <= 0 {
+		return fmt.Errorf("ssh_port is not configured for the target")
+	}
+	sshConn := *t.conn
+	sshConn.Port = t.conn.SSHPort
+	return loadImagesOverSSH(ctx, &sshConn, archive)
+}
+
+// targetAppDataDirOverride 返回迁移选项中指定的AppData基础目录，未指定时返回空字符串
+func targetAppDataDirOverride(options map[string]interface{}) (string, error) {
+	dir, _ := options[targetAppDataDirOption].(string)
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(dir, "/") {
+		return "", fmt.Errorf("Target AppData directory must be an absolute path: %s", dir)
+	}
+	dir = path.Clean(dir)
+	if dir == "/" {
+		return "", fmt.Errorf("Target AppData directory cannot be the root directory")
+	}
+	return dir, nil
+}
+
+// validateZimaOSAppDataDir 校验ZimaOS上的AppData基础目录：必须位于数据目录下，且目录本身或其上级目录已存在
+func (s *MigrationService) validateZimaOSAppDataDir(ctx context.Context, target *models.SystemConnection, dir string) error {
+	if !strings.HasPrefix(dir, zimaOSMediaDir) && !strings.HasPrefix(dir, "/DATA/") {
+		return fmt.Errorf("Target AppData directory must be under /media or /DATA: %s", dir)
+	}
+	for _, p := range []string{dir, path.Dir(dir)} {
+		exists, err := s.zimaOSPathExists(ctx, target, p)
+		if err != nil {
+			return fmt.Errorf("Failed to check target AppData directory %s: %v", dir, err)
+		}
+		if exists {
+			return nil
+		}
+	}
+	return fmt.Errorf("Target AppData directory %s does not exist on ZimaOS", dir)
+}
+
+// StartApp 通过应用管理接口启动应用并等待容器运行
+func (t *zimaOSTarget) StartApp(ctx context.Context, appName, taskID string) (string, error) {
+	client := t.s.zimaOSClientFor(t.conn)
+	if err := client.StartCompose(ctx, appName); err != nil {
+		return "", err
+	}
+	return waitForAppRunning(ctx, func() ([]appContainer, error) {
+		return client.ComposeContainers(ctx, appName)
+	})
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// breakerTarget 目标适配器的熔断包装：连续失败达到阈值时先探测目标，
+// 目标不可用则暂停任务（状态为waiting），定期探测直到目标恢复后继续，避免剩余应用全部失败
+// 超过最长等待时间后不再访问目标，剩余操作直接失败
+type breakerTarget struct {
+	TargetAdapter
+	s           *MigrationService
+	task        *models.MigrationTask
+	failures    int  // 连续失败次数
+	unavailable bool // 已放弃等待
+}
+
+// withCircuitBreaker 为任务的目标适配器加上熔断，阈值为0时不启用
+func (s *MigrationService) withCircuitBreaker(task *models.MigrationTask, target TargetAdapter) TargetAdapter {
+	if s.cfg.TargetBreaker.Threshold <= 0 {
+		return target
+	}
+	return &breakerTarget{TargetAdapter: target, s: s, task: task}
+}
+
+// UploadAppData 目标可用时上传应用数据
+func (b *breakerTarget) UploadAppData(ctx context.Context, appName, sourcePath, taskID string) error {
+	return b.guard(ctx, func() error {
+		return b.TargetAdapter.UploadAppData(ctx, appName, sourcePath, taskID)
+	})
+}
+
+// ImportCompose 目标可用时导入compose
+func (b *breakerTarget) ImportCompose(ctx context.Context, appName, composeContent, taskID string) error {
+	return b.guard(ctx, func() error {
+		return b.TargetAdapter.ImportCompose(ctx, appName, composeContent, taskID)
+	})
+}
+
+// UploadAppFiles 目标可用时上传应用目录中的文件
+func (b *breakerTarget) UploadAppFiles(ctx context.Context, appName, localDir string, files []string, taskID string) error {
+	return b.guard(ctx, func() error {
+		return b.TargetAdapter.UploadAppFiles(ctx, appName, localDir, files, taskID)
+	})
+}
+
+// StartApp 目标可用时启动应用
+func (b *breakerTarget) StartApp(ctx context.Context, appName, taskID string) (string, error) {
+	var status string
+	err := b.guard(ctx, func() error {
+		var err error
+		status, err = b.TargetAdapter.StartApp(ctx, appName, taskID)
+		return err
+	})
+	return status, err
+}
+
+// LoadImages 目标可用时加载镜像包
+func (b *breakerTarget) LoadImages(ctx context.Context, appName, archive string) error {
+	return b.guard(ctx, func() error {
+		return b.TargetAdapter.LoadImages(ctx, appName, archive)
+	})
+}
+
+// guard 等待目标可用后执行操作，并记录连续失败次数
+func (b *breakerTarget) guard(ctx context.Context, fn func() error) error {
+	if err := b.waitForTarget(ctx); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		b.failures++
+		return err
+	}
+	b.failures = 0
+	return nil
+}
+
+// waitForTarget 连续失败未达到阈值时直接返回；达到阈值时探测目标，不可用则暂停任务直到目标恢复
+func (b *breakerTarget) waitForTarget(ctx context.Context) error {
+	cfg := b.s.cfg.TargetBreaker
+	if b.unavailable {
+		return fmt.Errorf("Target unavailable, operation skipped")
+	}
+	if b.failures < cfg.Threshold {
+		return nil
+	}
+
+	// 失败可能是应用本身的问题，目标连接正常时继续
+	if b.probe(ctx) {
+		b.failures = 0
+		return nil
+	}
+
+	taskID := b.task.ID
+	log.Printf("[WARNING] Task %s: target unavailable after %d consecutive failures, waiting", taskID, b.failures)
+	b.s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("Target unavailable after %d consecutive failures; pausing until it recovers (checking every %s)", b.failures, cfg.ProbeInterval))
+	b.s.taskService.UpdateTaskStatus(taskID, string(models.TaskStatusWaiting))
+
+	wake := b.s.registerWaitingTask(taskID)
+	defer b.s.unregisterWaitingTask(taskID)
+
+	started := time.Now()
+	for {
+		select {
+		case <-time.After(cfg.ProbeInterval):
+		case <-wake:
+			b.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, "Checking target availability now")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if b.probe(ctx) {
+			b.failures = 0
+			b.s.taskService.UpdateTaskStatus(taskID, string(models.TaskStatusRunning))
+			b.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Target available again after %s, continuing", time.Since(started).Round(time.Second)))
+			return nil
+		}
+
+		if cfg.MaxWait > 0 && time.Since(started) >= cfg.MaxWait {
+			b.unavailable = true
+			b.s.taskService.UpdateTaskStatus(taskID, string(models.TaskStatusRunning))
+			b.s.taskService.AddTaskLog(taskID, models.LogLevelError, fmt.Sprintf("Target still unavailable after %s; remaining operations will fail", cfg.MaxWait))
+			return fmt.Errorf("Target unavailable, operation skipped")
+		}
+	}
+}
+
+// probe 测试目标连接是否可用
+func (b *breakerTarget) probe(ctx context.Context) bool {
+	resp, err := b.s.connService.TestConnection(ctx, b.task.Target)
+	return err == nil && resp != nil && resp.Success
+}
+
+// registerWaitingTask 登记等待目标恢复的任务，返回用于立即重新探测的通道
+func (s *MigrationService) registerWaitingTask(taskID string) chan struct{} {
+	wake := make(chan struct{}, 1)
+	s.waitingTasks.Store(taskID, wake)
+	return wake
+}
+
+// unregisterWaitingTask 取消登记
+func (s *MigrationService) unregisterWaitingTask(taskID string) {
+	s.waitingTasks.Delete(taskID)
+}
+
+// wakeWaitingTask 让等待目标恢复的任务立即重新探测，任务不在等待时返回false
+func (s *MigrationService) wakeWaitingTask(taskID string) bool {
+	value, ok := s.waitingTasks.Load(taskID)
+	if !ok {
+		return false
+	}
+	select {
+	case value.(chan struct{}) <- struct{}{}:
+	default:
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/logging"
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// failedCallExcerptBytes 失败请求的请求体和响应体最多记录的字节数
+	failedCallExcerptBytes = 2048
+	// maxFailedCalls 每个任务保留的失败请求数，超出时丢弃最早的记录
+	maxFailedCalls = 20
+)
+
+// recordFailedCall 将任务上下文中失败的请求摘录记录到任务中，不属于任务的请求不记录
+func (s *MigrationService) recordFailedCall(req *http.Request, resp *http.Response, err error) {
+	taskID := taskIDFromContext(req.Context())
+	if taskID == "" {
+		return
+	}
+
+	call := models.FailedCall{
+		Time:   time.Now(),
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+	}
+	if err != nil {
+		// 错误信息不包含URL，避免查询参数中的令牌写入诊断包
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		call.Error = logging.Redact(err.Error())
+	} else {
+		call.StatusCode = resp.StatusCode
+		call.Response = redactExcerpt(peekResponseBody(resp, failedCallExcerptBytes+1))
+	}
+	if req.GetBody != nil && isTextContent(req.Header.Get("Content-Type")) {
+		if body, bodyErr := req.GetBody(); bodyErr == nil {
+			data, _ := io.ReadAll(io.LimitReader(body, failedCallExcerptBytes+1))
+			body.Close()
+			call.Request = redactExcerpt(data)
+		}
+	}
+
+	s.taskService.RecordFailedCall(taskID, call)
+}
+
+// RecordFailedCall 在任务中记录失败的请求，只保留最近的maxFailedCalls条
+func (s *TaskService) RecordFailedCall(taskID string, call models.FailedCall) {
+	s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		task.FailedCalls = append(task.FailedCalls, call)
+		if len(task.FailedCalls) > maxFailedCalls {
+			task.FailedCalls = task.FailedCalls[len(task.FailedCalls)-maxFailedCalls:]
+		}
+	})
+}
+
+// peekResponseBody 读取响应体开头最多n个字节，响应体仍可从头完整读取
+func peekResponseBody(resp *http.Response, n int64) []byte {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(data), resp.Body), Closer: resp.Body}
+	return data
+}
+
+// peekedBody 先返回已读取的开头再继续读取原响应体
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// isTextContent 判断请求体是否为可以摘录的文本（JSON、表单等），上传的文件内容不记录
+func isTextContent(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, kind := range []string{"json", "x-www-form-urlencoded", "text/", "xml", "yaml"} {
+		if strings.Contains(contentType, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL 返回去掉用户信息、隐藏敏感查询参数值的URL
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	query := redacted.Query()
+	for name := range query {
+		if logging.SensitiveName(name) {
+			query.Set(name, logging.Redacted)
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// redactExcerpt 截取内容开头并隐藏其中的密码和令牌
+func redactExcerpt(data []byte) string {
+	truncated := len(data) > failedCallExcerptBytes
+	if truncated {
+		data = data[:failedCallExcerptBytes]
+	}
+	text := logging.Redact(strings.ToValidUTF8(string(data), "?"))
+	if truncated {
+		text += "..."
+	}
+	return text
+}
+
+// BuildTaskDiagnostics 生成任务的诊断包（ZIP）：去掉密码的任务记录、完整日志、步骤时间线、
+// 失败的CasaOS/ZimaOS请求摘录和工具版本信息，用于附在问题反馈中
+func (s *MigrationService) BuildTaskDiagnostics(taskID string) ([]byte, error) {
+	task, err := s.taskService.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	steps, _ := s.taskService.GetTaskSteps(taskID)
+	logs, _, _ := s.taskService.QueryTaskLogs(taskID, models.LogQuery{})
+
+	taskCopy := RedactTask(task)
+	// 日志和失败请求单独写入文件
+	taskCopy.Logs = nil
+	taskCopy.FailedCalls = nil
+
+	failedCalls := task.FailedCalls
+	if failedCalls == nil {
+		failedCalls = []models.FailedCall{}
+	}
+
+	var logText strings.Builder
+	for _, l := range logs {
+		// 早于启用日志脱敏的日志在导出时再处理一次
+		fmt.Fprintf(&logText, "%s [%s] %s\n", l.Timestamp.Format(time.RFC3339Nano), strings.ToUpper(l.Level), logging.Redact(l.Message))
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"task.json", taskCopy},
+		{"steps.json", steps},
+		{"failed_calls.json", failedCalls},
+		{"version.json", struct {
+			*models.BuildInfo
+			GeneratedAt time.Time `json:"generated_at"`
+		}{CurrentBuild(), time.Now()}},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		// 不转义URL中的&等字符，便于阅读
+		var data bytes.Buffer
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("Failed to encode %s: %v", file.name, err)
+		}
+		if err := writeZipFile(zw, file.name, data.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeZipFile(zw, "logs.log", []byte(logText.String())); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("Failed to create diagnostics archive: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeZipFile 在压缩包中写入一个文件
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("Failed to create diagnostics archive: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("Failed to create diagnostics archive: %v", err)
+	}
+	return nil
+}
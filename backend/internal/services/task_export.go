@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/logging"
+	"ctoz/backend/internal/models"
+)
+
+// 任务历史导出格式
+const (
+	TaskExportCSV    = "csv"
+	TaskExportNDJSON = "ndjson"
+)
+
+// taskExportColumns CSV导出的列，连接和选项只导出主机和类型
+var taskExportColumns = []string{
+	"id", "name", "type", "status", "progress",
+	"source_type", "source_host", "target_type", "target_host",
+	"created_at", "finished_at", "duration_ms",
+	"total_apps", "success_apps", "warning_apps", "failed_apps", "skipped_apps", "bytes_transferred",
+	"labels", "notes", "error", "version",
+}
+
+// TaskHistory 按创建时间顺序返回任务历史记录，status和taskType为空时不过滤
+func (s *MigrationService) TaskHistory(status, taskType string) []models.TaskHistoryRecord {
+	tasks := s.taskService.ListTasks()
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+
+	now := time.Now()
+	records := []models.TaskHistoryRecord{}
+	for _, task := range tasks {
+		if (status != "" && task.Status != status) || (taskType != "" && task.Type != taskType) {
+			continue
+		}
+		records = append(records, s.taskHistoryRecord(task, now))
+	}
+	return records
+}
+
+// taskHistoryRecord 生成单个任务的历史记录，备注和错误信息中的密码和令牌也会被隐藏
+func (s *MigrationService) taskHistoryRecord(task *models.MigrationTask, now time.Time) models.TaskHistoryRecord {
+	record := models.TaskHistoryRecord{
+		ID:        task.ID,
+		Name:      task.Name,
+		Type:      task.Type,
+		Status:    task.Status,
+		Progress:  task.Progress,
+		Labels:    task.Labels,
+		Notes:     logging.Redact(task.Notes),
+		Options:   RedactTaskOptions(task.Options),
+		Summary:   s.calculateImportSummary(previousAppStatuses(task)),
+		CreatedAt: task.CreatedAt,
+	}
+	if task.Source != nil {
+		record.Source = RedactConnection(task.Source)
+	}
+	if task.Target != nil {
+		record.Target = RedactConnection(task.Target)
+	}
+	if task.Build != nil {
+		record.Version = task.Build.Version
+	}
+	record.FinishedAt, record.DurationMs = taskTiming(task, now)
+
+	var failures []string
+	for _, step := range task.Steps {
+		if step.Status == models.StepStatusFailed && step.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", step.Name, step.Error))
+		}
+	}
+	record.Error = logging.Redact(strings.Join(failures, "; "))
+	return record
+}
+
+// WriteTaskHistory 以CSV（带表头）或NDJSON（每行一个JSON记录）格式写出任务历史
+func WriteTaskHistory(w io.Writer, format string, records []models.TaskHistoryRecord) error {
+	switch format {
+	case TaskExportNDJSON:
+		encoder := json.NewEncoder(w)
+		for i := range records {
+			if err := encoder.Encode(&records[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case TaskExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(taskExportColumns); err != nil {
+			return err
+		}
+		for i := range records {
+			if err := writer.Write(taskHistoryRow(&records[i])); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf("Invalid export format %s: must be csv or ndjson", format)
+}
+
+// taskHistoryRow 按taskExportColumns的顺序生成CSV行
+func taskHistoryRow(record *models.TaskHistoryRecord) []string {
+	var sourceType, sourceHost, targetType, targetHost string
+	if record.Source != nil {
+		sourceType, sourceHost = record.Source.Type, record.Source.Host
+	}
+	if record.Target != nil {
+		targetType, targetHost = record.Target.Type, record.Target.Host
+	}
+	finishedAt := ""
+	if record.FinishedAt != nil {
+		finishedAt = record.FinishedAt.Format(time.RFC3339)
+	}
+	var bytesTransferred int64
+	if record.Summary.Metrics != nil {
+		bytesTransferred = record.Summary.Metrics.BytesTransferred
+	}
+
+	// 标签按键排序，写作key=value并以分号分隔
+	labels := make([]string, 0, len(record.Labels))
+	for key, value := range record.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+
+	return []string{
+		record.ID, csvSafe(record.Name), record.Type, record.Status, strconv.Itoa(record.Progress),
+		sourceType, csvSafe(sourceHost), targetType, csvSafe(targetHost),
+		record.CreatedAt.Format(time.RFC3339), finishedAt, strconv.FormatInt(record.DurationMs, 10),
+		strconv.Itoa(record.Summary.TotalApps), strconv.Itoa(record.Summary.SuccessApps), strconv.Itoa(record.Summary.WarningApps),
+		strconv.Itoa(record.Summary.FailedApps), strconv.Itoa(record.Summary.SkippedApps), strconv.FormatInt(bytesTransferred, 10),
+		csvSafe(strings.Join(labels, ";")), csvSafe(record.Notes), csvSafe(record.Error), record.Version,
+	}
+}
+
+// csvSafe 在以公式字符开头的单元格前加单引号，防止表格软件将用户输入当作公式执行
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"ctoz/backend/internal/logging"
+	"ctoz/backend/internal/models"
+)
+
+// logLevelRanks 日志级别的先后，低于任务日志级别的日志不记录
+var logLevelRanks = map[string]int{
+	models.LogLevelDebug:   0,
+	models.LogLevelInfo:    1,
+	models.LogLevelWarning: 2,
+	models.LogLevelError:   3,
+}
+
+// normalizeTaskLogLevel 检查并规范创建任务请求中的日志级别，warn等同于warning，空表示默认的info
+func normalizeTaskLogLevel(meta *models.TaskMeta) error {
+	level := strings.ToLower(strings.TrimSpace(meta.LogLevel))
+	if level == "warn" {
+		level = models.LogLevelWarning
+	}
+	if _, ok := logLevelRanks[level]; level != "" && (!ok || level == models.LogLevelError) {
+		return fmt.Errorf("Invalid log_level %s: must be debug, info or warn", meta.LogLevel)
+	}
+	meta.LogLevel = level
+	return nil
+}
+
+// taskLogsLevel 判断任务是否记录该级别的日志，未设置日志级别的任务按info处理
+func taskLogsLevel(task *models.MigrationTask, level string) bool {
+	threshold := task.LogLevel
+	if threshold == "" {
+		threshold = models.LogLevelInfo
+	}
+	rank, ok := logLevelRanks[level]
+	return !ok || rank >= logLevelRanks[threshold]
+}
+
+// debugf 输出接口调用的请求和响应，服务日志开启了http_dumps时写入服务日志，
+// 上下文所属任务的日志级别为debug时同时写入任务日志
+func (s *MigrationService) debugf(ctx context.Context, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if logging.HTTPDumps() {
+		log.Printf("[DEBUG] %s", message)
+	}
+	if taskID := taskIDFromContext(ctx); taskID != "" {
+		s.taskService.AddTaskLog(taskID, models.LogLevelDebug, message)
+	}
+}
+
+// formatHeaders 按名称顺序输出HTTP头，认证和Cookie等敏感头的值替换为REDACTED
+func formatHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if logging.SensitiveName(name) {
+			value = logging.Redacted
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
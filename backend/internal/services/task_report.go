@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"ctoz/backend/internal/i18n"
+	"ctoz/backend/internal/models"
+)
+
+// reportTemplate 迁移总结报告，样式内联，便于离线保存或在浏览器中打印为PDF
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatReportDuration,
+	"bytes":    formatBytes,
+	// metricsTime 应用各阶段耗时的合计
+	"metricsTime": func(m *models.AppMetrics) int64 {
+		return m.CompressMs + m.UploadMs + m.DecompressMs + m.ComposeImportMs
+	},
+	"time": func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04:05")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{call .T "Migration report"}} - {{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, "Noto Sans SC", sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+h2 { margin-top: 1.6em; border-bottom: 1px solid #ddd; padding-bottom: 0.2em; }
+table { border-collapse: collapse; width: 100%; margin-top: 0.5em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; font-size: 0.9em; }
+th { background: #f5f5f5; }
+.success, .done { color: #1a7f37; }
+.failed { color: #cf222e; }
+.warning, .skipped { color: #9a6700; }
+code { font-size: 0.9em; }
+@media print { body { margin: 0; } h2 { page-break-after: avoid; } tr { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{call .T "Migration report"}}</h1>
+<p>{{.Title}}</p>
+<table>
+<tr><th>{{call .T "Task ID"}}</th><td><code>{{.Task.ID}}</code></td></tr>
+<tr><th>{{call .T "Type"}}</th><td>{{.Task.Type}}</td></tr>
+<tr><th>{{call .T "Status"}}</th><td class="{{.Task.Status}}">{{.Task.Status}}</td></tr>
+{{if .Source}}<tr><th>{{call .T "Source"}}</th><td>{{.Source}}</td></tr>{{end}}
+{{if .Target}}<tr><th>{{call .T "Target"}}</th><td>{{.Target}}</td></tr>{{end}}
+<tr><th>{{call .T "Started"}}</th><td>{{time .Started}}</td></tr>
+<tr><th>{{call .T "Finished"}}</th><td>{{time .Finished}}</td></tr>
+<tr><th>{{call .T "Total duration"}}</th><td>{{duration .DurationMs}}</td></tr>
+{{if .Task.Notes}}<tr><th>{{call .T "Notes"}}</th><td>{{.Task.Notes}}</td></tr>{{end}}
+</table>
+
+{{if .Apps}}
+<h2>{{call .T "Summary"}}</h2>
+<table>
+<tr><th>{{call .T "Total apps"}}</th><th>{{call .T "Succeeded"}}</th><th>{{call .T "Warnings"}}</th><th>{{call .T "Failed"}}</th><th>{{call .T "Skipped"}}</th>{{if .Summary.Metrics}}<th>{{call .T "Transferred"}}</th>{{end}}</tr>
+<tr><td>{{.Summary.TotalApps}}</td><td>{{.Summary.SuccessApps}}</td><td>{{.Summary.WarningApps}}</td><td>{{.Summary.FailedApps}}</td><td>{{.Summary.SkippedApps}}</td>{{with .Summary.Metrics}}<td>{{bytes .BytesTransferred}}</td>{{end}}</tr>
+</table>
+
+<h2>{{call .T "Apps"}}</h2>
+<table>
+<tr><th>{{call .T "App"}}</th><th>{{call .T "Status"}}</th><th>AppData</th><th>Compose</th><th>{{call .T "Runtime"}}</th><th>{{call .T "Transferred"}}</th><th>{{call .T "Duration"}}</th><th>{{call .T "Message"}}</th></tr>
+{{range .Apps}}<tr><td>{{.AppName}}</td><td class="{{.OverallStatus}}">{{.OverallStatus}}</td><td>{{.AppDataStatus}}</td><td>{{.ComposeStatus}}</td><td>{{.RuntimeStatus}}</td>{{if .Metrics}}<td>{{bytes .Metrics.BytesTransferred}}</td><td>{{duration (metricsTime .Metrics)}}</td>{{else}}<td>-</td><td>-</td>{{end}}<td>{{.ErrorMessage}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{if .Rewrites}}
+<h2>{{call .T "Rewritten paths"}}</h2>
+<table>
+<tr><th>{{call .T "App"}}</th><th>{{call .T "Service"}}</th><th>{{call .T "Source path"}}</th><th>{{call .T "Target path"}}</th></tr>
+{{range .Rewrites}}<tr><td>{{.App}}</td><td>{{.Mount.Service}}</td><td><code>{{.Mount.Source}}</code></td><td><code>{{.Mount.Rewritten}}</code></td></tr>
+{{end}}</table>
+{{end}}
+
+{{if .Failures}}
+<h2>{{call .T "Failures"}}</h2>
+<ul>
+{{range .Failures}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}
+
+{{if .Warnings}}
+<h2>{{call .T "Warnings"}}</h2>
+<ul>
+{{range .Warnings}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}
+
+{{if .Steps}}
+<h2>{{call .T "Steps"}}</h2>
+<table>
+<tr><th>{{call .T "Step"}}</th><th>{{call .T "Status"}}</th><th>{{call .T "Duration"}}</th><th>{{call .T "Message"}}</th></tr>
+{{range .Steps}}<tr><td>{{call $.T .Name}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{duration .DurationMs}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+{{end}}
+
+<p><small>{{call .T "Generated at"}} {{time .GeneratedAt}}</small></p>
+</body>
+</html>
+`))
+
+// reportRewrite 报告中的一条路径改写
+type reportRewrite struct {
+	App   string
+	Mount models.BindMount
+}
+
+// reportData 报告模板数据
+type reportData struct {
+	Lang        string
+	T           func(string) string
+	Title       string
+	Task        *models.MigrationTask
+	Source      string
+	Target      string
+	Started     *time.Time
+	Finished    *time.Time
+	DurationMs  int64
+	GeneratedAt *time.Time
+	Summary     models.ImportSummary
+	Apps        []models.AppImportStatus
+	Rewrites    []reportRewrite
+	Failures    []string
+	Warnings    []string
+	Steps       []models.StepRecord
+}
+
+// BuildTaskReport 生成任务的HTML总结报告：迁移的应用及其传输量和耗时、改写的路径、警告、失败原因和总耗时
+func (s *MigrationService) BuildTaskReport(taskID string) ([]byte, error) {
+	task, err := s.taskService.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	steps, _ := s.taskService.GetTaskSteps(taskID)
+	logs, _ := s.taskService.GetTaskLogs(taskID)
+
+	lang := s.taskService.taskLanguage(taskID)
+	now := time.Now()
+	data := reportData{
+		Lang:        lang,
+		T:           func(message string) string { return i18n.T(lang, message) },
+		Title:       task.Name,
+		Task:        task,
+		Started:     &task.CreatedAt,
+		GeneratedAt: &now,
+		Apps:        previousAppStatuses(task),
+		Steps:       steps,
+	}
+	if data.Title == "" {
+		data.Title = task.ID
+	}
+	if task.Source != nil {
+		data.Source = fmt.Sprintf("%s (%s)", task.Source.Host, task.Source.Type)
+	}
+	if task.Target != nil {
+		data.Target = fmt.Sprintf("%s (%s)", task.Target.Host, task.Target.Type)
+	}
+
+	data.Finished, data.DurationMs = taskTiming(task, now)
+
+	data.Summary = s.calculateImportSummary(data.Apps)
+	for _, app := range data.Apps {
+		if app.OverallStatus == models.AppStatusFailed {
+			reason := app.ErrorMessage
+			if reason == "" {
+				reason = i18n.T(lang, "No reason recorded")
+			}
+			data.Failures = append(data.Failures, fmt.Sprintf("%s: %s", app.AppName, reason))
+		}
+		for _, warning := range app.Warnings {
+			data.Warnings = append(data.Warnings, fmt.Sprintf("%s: %s", app.AppName, warning))
+		}
+		for _, mount := range app.BindMounts {
+			if mount.Rewritten != "" && mount.Rewritten != mount.Source {
+				data.Rewrites = append(data.Rewrites, reportRewrite{App: app.AppName, Mount: mount})
+			}
+		}
+	}
+	for _, step := range steps {
+		if step.Status == models.StepStatusFailed {
+			data.Failures = append(data.Failures, fmt.Sprintf("%s: %s", i18n.T(lang, step.Name), step.Error))
+		}
+	}
+	// 应用的警告通常也记录在任务日志中，去掉重复的内容
+	seen := make(map[string]bool, len(data.Warnings))
+	for _, warning := range data.Warnings {
+		seen[warning] = true
+	}
+	for _, l := range logs {
+		if l.Level == models.LogLevelWarning && !seen[l.Message] {
+			seen[l.Message] = true
+			data.Warnings = append(data.Warnings, l.Message)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("Failed to render report: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// taskTiming 返回任务的结束时间和总耗时（毫秒）
+// 任务结束时以最后一次更新为结束时间，否则统计到now，结束时间为nil
+func taskTiming(task *models.MigrationTask, now time.Time) (*time.Time, int64) {
+	if models.IsTerminalStatus(task.Status) {
+		finished := task.UpdatedAt
+		return &finished, finished.Sub(task.CreatedAt).Milliseconds()
+	}
+	return nil, now.Sub(task.CreatedAt).Milliseconds()
+}
+
+// formatReportDuration 将毫秒数格式化为可读的时长
+func formatReportDuration(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	d := time.Duration(ms) * time.Millisecond
+	if d < time.Second {
+		return d.String()
+	}
+	return d.Round(time.Second).String()
+}
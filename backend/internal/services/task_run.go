@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+var (
+	// errTaskCancelled 任务被取消后步骤返回的错误
+	errTaskCancelled = errors.New("Task cancelled")
+	// errServerShutdown 服务关闭时步骤返回的错误
+	errServerShutdown = errors.New("Server is shutting down")
+)
+
+// taskIDKey 任务上下文中保存任务ID的键
+type taskIDKey struct{}
+
+// taskIDFromContext 返回上下文所属的任务ID，不是任务上下文时返回空字符串
+func taskIDFromContext(ctx context.Context) string {
+	taskID, _ := ctx.Value(taskIDKey{}).(string)
+	return taskID
+}
+
+// taskRuns 运行中任务的上下文，取消时中止任务正在进行的下载、上传、外部命令和等待
+type taskRuns struct {
+	ctx     context.Context // 服务的生命周期，关闭服务时取消
+	stop    context.CancelFunc
+	mutex   sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// newTaskRuns 创建任务上下文登记表
+func newTaskRuns() *taskRuns {
+	ctx, stop := context.WithCancel(context.Background())
+	return &taskRuns{ctx: ctx, stop: stop, cancels: make(map[string]context.CancelFunc)}
+}
+
+// RunContext 为执行中的任务创建上下文，任务结束时调用返回的函数释放
+// 上下文在取消任务或关闭服务时取消
+func (s *TaskService) RunContext(taskID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(s.runs.ctx)
+	ctx = context.WithValue(ctx, taskIDKey{}, taskID)
+
+	s.runs.mutex.Lock()
+	s.runs.cancels[taskID] = cancel
+	s.runs.wg.Add(1)
+	s.runs.mutex.Unlock()
+
+	return ctx, func() {
+		s.runs.mutex.Lock()
+		delete(s.runs.cancels, taskID)
+		s.runs.mutex.Unlock()
+		cancel()
+		s.runs.wg.Done()
+	}
+}
+
+// CancelTask 取消正在执行的任务，任务不在执行时返回 models.ErrInvalidTaskStatus
+func (s *TaskService) CancelTask(taskID string) error {
+	if _, err := s.store.GetTask(taskID); err != nil {
+		return err
+	}
+
+	s.runs.mutex.Lock()
+	cancel, ok := s.runs.cancels[taskID]
+	s.runs.mutex.Unlock()
+	if !ok {
+		return models.ErrInvalidTaskStatus
+	}
+
+	s.AddTaskLog(taskID, models.LogLevelWarning, "Cancelling task")
+	log.Printf("[INFO] Cancelling task %s", taskID)
+	cancel()
+	return nil
+}
+
+// Shutdown 关闭服务时取消所有运行中的任务，最多等待timeout让任务保存状态
+func (s *TaskService) Shutdown(timeout time.Duration) {
+	s.runs.stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("[WARNING] Tasks did not stop within %s", timeout)
+	}
+	s.store.Flush()
+}
+
+// contextError 任务上下文已取消时返回取消原因，否则返回nil
+func (s *TaskService) contextError(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if s.runs.ctx.Err() != nil {
+		return errServerShutdown
+	}
+	return errTaskCancelled
+}
+
+// FinishCancelled 结束被取消的任务：服务关闭时标记为已中断以便之后恢复，否则标记为失败
+func (s *TaskService) FinishCancelled(taskID string) {
+	if s.runs.ctx.Err() == nil {
+		s.UpdateTaskStatus(taskID, string(models.TaskStatusFailed))
+		s.AddTaskLog(taskID, models.LogLevelError, "Task cancelled")
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return
+	}
+	resumable := isTaskResumable(task)
+	s.store.UpdateTask(taskID, func(t *models.MigrationTask) {
+		t.Status = string(models.TaskStatusInterrupted)
+		t.Resumable = resumable
+		failRunningSteps(t, "Task interrupted by server shutdown")
+	})
+
+	message := "Task interrupted by server shutdown"
+	if resumable {
+		message = "Task interrupted by server shutdown; it can be resumed"
+	}
+	s.AddTaskLog(taskID, models.LogLevelWarning, message)
+	log.Printf("[WARNING] Task %s was interrupted by shutdown (resumable: %v)", taskID, resumable)
+}
+
+// sleepContext 等待d或直到上下文取消，取消时返回false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
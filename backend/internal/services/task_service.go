@@ -1,9 +1,14 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"os"
 	"time"
 
+	"ctoz/backend/internal/i18n"
+	"ctoz/backend/internal/logging"
 	"ctoz/backend/internal/models"
 	"ctoz/backend/internal/storage"
 	"ctoz/backend/internal/websocket"
@@ -15,19 +20,216 @@ import (
 type TaskService struct {
 	store     *storage.MemoryStore
 	wsManager *websocket.Manager
+	runs      *taskRuns
 }
 
-// NewTaskService 创建新的任务服务
-func NewTaskService(wsManager *websocket.Manager) *TaskService {
-	return &TaskService{
-		store:     storage.NewMemoryStore(),
+// NewTaskService 创建新的任务服务，使用与其他服务共享的存储
+func NewTaskService(store *storage.MemoryStore, wsManager *websocket.Manager) *TaskService {
+	s := &TaskService{
+		store:     store,
 		wsManager: wsManager,
+		runs:      newTaskRuns(),
 	}
+	if wsManager != nil {
+		wsManager.SetRecorder(s.recordEvent)
+	}
+	return s
+}
+
+// recordEvent 把发给任务的WebSocket消息保存为任务事件，上传频道等不属于任务的消息不保存
+func (s *TaskService) recordEvent(channel string, message models.WSMessage) {
+	if _, err := s.store.GetTask(channel); err != nil {
+		return
+	}
+	s.store.AddEvent(channel, message)
+}
+
+// GetTaskEvents 获取任务序号大于since的事件，用于在任务结束后重建完整的进度时间线
+func (s *TaskService) GetTaskEvents(taskID string, since int64) (*models.TaskEventPage, error) {
+	if _, err := s.store.GetTask(taskID); err != nil {
+		return nil, err
+	}
+	events, lastSeq, truncated := s.store.GetEvents(taskID, since)
+	return &models.TaskEventPage{
+		TaskID:    taskID,
+		Events:    events,
+		LastSeq:   lastSeq,
+		Truncated: truncated,
+	}, nil
+}
+
+// RecoverInterruptedTasks 将上次运行时未结束的任务标记为已中断
+// 断点中的工作目录仍然存在，或导入文件仍在时，任务可以恢复
+func (s *TaskService) RecoverInterruptedTasks() {
+	tasks, err := s.store.GetAllTasks()
+	if err != nil {
+		return
+	}
+
+	for _, task := range tasks {
+		if task.Status != string(models.TaskStatusRunning) && task.Status != string(models.TaskStatusPending) && task.Status != string(models.TaskStatusWaiting) {
+			continue
+		}
+
+		resumable := isTaskResumable(task)
+		s.store.UpdateTask(task.ID, func(t *models.MigrationTask) {
+			t.Status = string(models.TaskStatusInterrupted)
+			t.Resumable = resumable
+			failRunningSteps(t, "Task interrupted by server restart")
+		})
+
+		message := "Task interrupted by server restart"
+		if resumable {
+			message = "Task interrupted by server restart; it can be resumed"
+		}
+		s.AddTaskLog(task.ID, models.LogLevelWarning, message)
+		log.Printf("[WARNING] Task %s was interrupted (resumable: %v)", task.ID, resumable)
+	}
+	s.store.Flush()
+}
+
+// isTaskResumable 判断中断的任务是否还有可恢复的数据
+func isTaskResumable(task *models.MigrationTask) bool {
+	switch task.Type {
+	case models.TaskTypeOnline:
+		if task.Checkpoint == nil {
+			return false
+		}
+		// 解压目录已删除时重新下载源数据，已完成的应用不再上传
+		if len(task.Checkpoint.Apps) > 0 {
+			return true
+		}
+		if task.Checkpoint.ExtractedPath == "" {
+			return false
+		}
+		_, err := os.Stat(task.Checkpoint.ExtractedPath)
+		return err == nil
+	case models.TaskTypeImport:
+		// 从S3导入的任务可以重新下载导入文件
+		if _, ok := task.Options[importS3Option]; ok {
+			return true
+		}
+		return importFileExists(task)
+	}
+	return false
+}
+
+// SetTaskOption 设置任务选项
+func (s *TaskService) SetTaskOption(taskID, key string, value interface{}) error {
+	return s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		options := make(map[string]interface{}, len(task.Options)+1)
+		for k, v := range task.Options {
+			options[k] = v
+		}
+		options[key] = value
+		task.Options = options
+	})
+}
+
+// SetCheckpoint 保存任务断点，未指定应用断点记录时保留已有的记录
+func (s *TaskService) SetCheckpoint(taskID string, checkpoint *models.TaskCheckpoint) error {
+	return s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		if checkpoint != nil && checkpoint.Apps == nil && task.Checkpoint != nil {
+			checkpoint.Apps = task.Checkpoint.Apps
+		}
+		task.Checkpoint = checkpoint
+	})
+}
+
+// CheckpointApp 记录应用已完成的步骤（appStepAppData或appStepCompose）并立即写入状态文件，
+// 服务崩溃后恢复任务时跳过该步骤
+func (s *TaskService) CheckpointApp(taskID, appName, step string) error {
+	err := s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		checkpoint := &models.TaskCheckpoint{}
+		if task.Checkpoint != nil {
+			copied := *task.Checkpoint
+			checkpoint = &copied
+		}
+		apps := make(map[string]*models.AppCheckpoint, len(checkpoint.Apps)+1)
+		for name, record := range checkpoint.Apps {
+			apps[name] = record
+		}
+		record := &models.AppCheckpoint{}
+		if existing, ok := apps[appName]; ok {
+			copied := *existing
+			record = &copied
+		}
+		switch step {
+		case appStepAppData:
+			record.AppData = true
+		case appStepCompose:
+			record.Compose = true
+		}
+		record.UpdatedAt = time.Now()
+		apps[appName] = record
+		checkpoint.Apps = apps
+		task.Checkpoint = checkpoint
+	})
+	if err != nil {
+		return err
+	}
+	return s.store.Flush()
+}
+
+// PrepareResume 将可恢复的中断任务重置为待执行状态
+func (s *TaskService) PrepareResume(taskID string) (*models.MigrationTask, error) {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != string(models.TaskStatusInterrupted) {
+		return nil, models.ErrInvalidTaskStatus
+	}
+	if !isTaskResumable(task) {
+		return nil, fmt.Errorf("Task %s has no resumable data left", taskID)
+	}
+
+	s.store.UpdateTask(taskID, func(t *models.MigrationTask) {
+		t.Status = string(models.TaskStatusPending)
+		t.Resumable = false
+	})
+	return task, nil
+}
+
+// PrepareRetry 将失败的任务重置为待执行状态以便重新执行
+// 断点和已成功的应用步骤保留，重新执行时跳过；总进度从0重新计算
+func (s *TaskService) PrepareRetry(taskID string) (*models.MigrationTask, error) {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != string(models.TaskStatusFailed) {
+		return nil, models.ErrInvalidTaskStatus
+	}
+	// 离线导入需要上传的文件仍在，或可以从S3重新下载
+	if task.Type == models.TaskTypeImport && !isTaskResumable(task) {
+		return nil, fmt.Errorf("Import file of task %s no longer exists", taskID)
+	}
+
+	// 检查和重置在同一次更新中完成，同一任务不会被同时重试两次
+	reset := false
+	err = s.store.UpdateTask(taskID, func(t *models.MigrationTask) {
+		if t.Status != string(models.TaskStatusFailed) {
+			return
+		}
+		t.Status = string(models.TaskStatusPending)
+		t.Progress = 0
+		t.Resumable = false
+		reset = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !reset {
+		return nil, models.ErrInvalidTaskStatus
+	}
+	return s.store.GetTask(taskID)
 }
 
-// CreateTask 创建新任务
-func (s *TaskService) CreateTask(taskType string, source, target *models.SystemConnection, options map[string]interface{}) *models.MigrationTask {
+// CreateTask 创建新任务，language为任务日志和推送消息使用的语言（空则使用默认语言）
+func (s *TaskService) CreateTask(taskType, language string, meta models.TaskMeta, source, target *models.SystemConnection, options map[string]interface{}) *models.MigrationTask {
 	task := &models.MigrationTask{
+		TaskMeta:  meta,
 		ID:        uuid.New().String(),
 		Type:      taskType,
 		Status:    string(models.TaskStatusPending),
@@ -35,11 +237,14 @@ func (s *TaskService) CreateTask(taskType string, source, target *models.SystemC
 		Source:    source,
 		Target:    target,
 		Options:   options,
+		Language:  language,
+		Build:     CurrentBuild(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	s.store.SaveTask(task)
+	registerTaskSecrets(task)
 	return task
 }
 
@@ -48,6 +253,19 @@ func (s *TaskService) GetTask(taskID string) (*models.MigrationTask, error) {
 	return s.store.GetTask(taskID)
 }
 
+// RedactTask 返回不含连接密码、令牌和任务选项中密钥的任务副本，用于接口响应和诊断包
+func RedactTask(task *models.MigrationTask) *models.MigrationTask {
+	taskCopy := *task
+	if taskCopy.Source != nil {
+		taskCopy.Source = RedactConnection(taskCopy.Source)
+	}
+	if taskCopy.Target != nil {
+		taskCopy.Target = RedactConnection(taskCopy.Target)
+	}
+	taskCopy.Options = RedactTaskOptions(taskCopy.Options)
+	return &taskCopy
+}
+
 // UpdateTaskStatus 更新任务状态
 func (s *TaskService) UpdateTaskStatus(taskID string, status string) error {
 	err := s.store.UpdateTaskStatus(taskID, status)
@@ -57,13 +275,16 @@ func (s *TaskService) UpdateTaskStatus(taskID string, status string) error {
 
 	// 发送WebSocket消息
 	if s.wsManager != nil {
+		lang := s.taskLanguage(taskID)
 		switch status {
 		case string(models.TaskStatusRunning):
-			s.wsManager.SendTaskStatus(taskID, models.TaskStatusRunning, "Task started")
+			s.wsManager.SendTaskStatus(taskID, models.TaskStatusRunning, i18n.T(lang, "Task started"))
+		case string(models.TaskStatusWaiting):
+			s.wsManager.SendTaskStatus(taskID, models.TaskStatusWaiting, i18n.T(lang, "Target unavailable, waiting for it to recover"))
 		case string(models.TaskStatusCompleted):
-			s.wsManager.SendTaskStatus(taskID, models.TaskStatusCompleted, "Task completed")
+			s.wsManager.SendTaskStatus(taskID, models.TaskStatusCompleted, i18n.T(lang, "Task completed"))
 		case string(models.TaskStatusFailed):
-			s.wsManager.SendTaskStatus(taskID, models.TaskStatusFailed, "Task failed")
+			s.wsManager.SendTaskStatus(taskID, models.TaskStatusFailed, i18n.T(lang, "Task failed"))
 		}
 	}
 
@@ -85,16 +306,24 @@ func (s *TaskService) UpdateTaskProgress(taskID string, progress int) error {
 	return nil
 }
 
-// AddTaskLog 添加任务日志
+// AddTaskLog 添加任务日志，消息按任务语言翻译
+// 低于任务日志级别的日志不记录也不推送，消息中的密码和令牌被隐藏
 func (s *TaskService) AddTaskLog(taskID string, level string, message string) error {
+	lang := ""
+	if task, err := s.store.GetTask(taskID); err == nil {
+		if !taskLogsLevel(task, level) {
+			return nil
+		}
+		lang = task.Language
+	}
+	message = logging.Redact(i18n.T(lang, message))
 	log := &models.MigrationLog{
 		Level:     level,
 		Message:   message,
 		Timestamp: time.Now(),
 	}
 
-	err := s.store.AddLog(taskID, log)
-	if err != nil {
+	if err := s.store.AddLog(taskID, log); err != nil {
 		return err
 	}
 
@@ -106,6 +335,15 @@ func (s *TaskService) AddTaskLog(taskID string, level string, message string) er
 	return nil
 }
 
+// taskLanguage 返回任务的语言，任务不存在或未指定时为空（使用默认语言）
+func (s *TaskService) taskLanguage(taskID string) string {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return ""
+	}
+	return task.Language
+}
+
 // SetTaskResult 设置任务结果
 func (s *TaskService) SetTaskResult(taskID string, result interface{}) error {
 	return s.store.SetTaskResult(taskID, result)
@@ -120,9 +358,20 @@ func (s *TaskService) ListTasks() []*models.MigrationTask {
 	return allTasks
 }
 
-// DeleteTask 删除任务
+// DeleteTask 删除任务，运行中或等待目标恢复的任务不能删除，返回ErrInvalidTaskStatus
 func (s *TaskService) DeleteTask(taskID string) error {
-	return s.store.DeleteTask(taskID)
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status == string(models.TaskStatusRunning) || task.Status == string(models.TaskStatusWaiting) {
+		return models.ErrInvalidTaskStatus
+	}
+	if err := s.store.DeleteTask(taskID); err != nil {
+		return err
+	}
+	logging.ReleaseSecrets(taskSecretOwner(taskID))
+	return nil
 }
 
 // GetTaskLogs 获取任务日志
@@ -130,63 +379,148 @@ func (s *TaskService) GetTaskLogs(taskID string) ([]*models.MigrationLog, error)
 	return s.store.GetLogs(taskID)
 }
 
+// QueryTaskLogs 按时间和分页条件查询任务日志，返回当前页日志及过滤后的总数
+func (s *TaskService) QueryTaskLogs(taskID string, query models.LogQuery) ([]*models.MigrationLog, int, error) {
+	logs, err := s.store.GetLogs(taskID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 按时间过滤
+	filtered := logs
+	if !query.Since.IsZero() {
+		filtered = make([]*models.MigrationLog, 0, len(logs))
+		for _, l := range logs {
+			if l.Timestamp.After(query.Since) {
+				filtered = append(filtered, l)
+			}
+		}
+	}
+
+	// 分页
+	total := len(filtered)
+	start := query.Offset
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+
+	page := make([]*models.MigrationLog, end-start)
+	copy(page, filtered[start:end])
+	return page, total, nil
+}
+
 // CleanupExpiredTasks 清理过期任务
 func (s *TaskService) CleanupExpiredTasks(expireDuration time.Duration) error {
 	return s.store.CleanupExpiredTasks(expireDuration)
 }
 
 // GetStats 获取任务统计信息
-func (s *TaskService) GetStats() map[string]interface{} {
+func (s *TaskService) GetStats() models.StoreStats {
 	return s.store.GetStats()
 }
 
+// ReportTransferProgress 推送应用数据传输的字节级进度
+func (s *TaskService) ReportTransferProgress(taskID, appName string, transferred, total int64) {
+	s.wsManager.SendTransferProgress(taskID, appName, transferred, total)
+}
+
+// ReportDecompressProgress 推送目标系统上解压应用数据的进度
+func (s *TaskService) ReportDecompressProgress(taskID, appName string, progress int) {
+	s.wsManager.SendDecompressProgress(taskID, appName, progress)
+}
+
+// ReportHeartbeat 推送目标上长时间操作的心跳
+func (s *TaskService) ReportHeartbeat(taskID, appName, phase string, elapsed time.Duration, status string) {
+	s.wsManager.SendHeartbeat(taskID, appName, phase, elapsed, status)
+}
+
+// reportStepProgress 在task_progress中推送按步骤权重计算的任务总进度
+func (s *TaskService) reportStepProgress(taskID, step string, progress int, message string) {
+	s.wsManager.SendProgress(taskID, s.advanceProgress(taskID, step, progress), step, message)
+}
+
 // ExecuteStep 执行步骤并发送WebSocket消息
-func (s *TaskService) ExecuteStep(taskID, step string, fn func() error) error {
+// 任务已取消时不再执行，步骤因任务取消而失败时返回取消原因
+func (s *TaskService) ExecuteStep(ctx context.Context, taskID, step string, fn func(ctx context.Context) error) error {
+	if err := s.contextError(ctx); err != nil {
+		return err
+	}
+	lang := s.taskLanguage(taskID)
+
 	// Send step start message
-	s.wsManager.SendStepStart(taskID, step, "Step started")
+	s.wsManager.SendStepStart(taskID, step, i18n.T(lang, "Step started"))
 	s.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Step started: %s", step))
+	s.beginStep(taskID, step)
+	s.reportStepProgress(taskID, step, 0, "")
 
 	// 执行步骤
-	err := fn()
+	err := fn(ctx)
 	if err != nil {
+		if ctxErr := s.contextError(ctx); ctxErr != nil {
+			err = ctxErr
+		}
 		// Send step error message
-		s.wsManager.SendStepError(taskID, step, "Step failed", err.Error())
+		s.wsManager.SendStepError(taskID, step, i18n.T(lang, "Step failed"), err.Error())
 		s.AddTaskLog(taskID, models.LogLevelError, fmt.Sprintf("Step failed: %s - %v", step, err))
+		s.endStep(taskID, step, err)
 		return err
 	}
 
 	// Send step completion message
-	s.wsManager.SendStepComplete(taskID, step, "Step completed")
+	s.endStep(taskID, step, nil)
+	s.reportStepProgress(taskID, step, 100, "")
+	s.wsManager.SendStepComplete(taskID, step, i18n.T(lang, "Step completed"))
 	s.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Step completed: %s", step))
 	return nil
 }
 
 // ExecuteStepWithProgress 执行带进度的步骤
-func (s *TaskService) ExecuteStepWithProgress(taskID, step string, fn func(progressCallback func(int, string)) error) error {
+func (s *TaskService) ExecuteStepWithProgress(ctx context.Context, taskID, step string, fn func(ctx context.Context, progressCallback func(int, string)) error) error {
+	if err := s.contextError(ctx); err != nil {
+		return err
+	}
+	lang := s.taskLanguage(taskID)
+
 	// Send step start message
-	s.wsManager.SendStepStart(taskID, step, "Step started")
+	s.wsManager.SendStepStart(taskID, step, i18n.T(lang, "Step started"))
 	s.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Step started: %s", step))
+	s.beginStep(taskID, step)
+	s.reportStepProgress(taskID, step, 0, "")
 
 	// 进度回调函数
 	progressCallback := func(progress int, message string) {
-		s.wsManager.SendProgress(taskID, progress, step, message)
-		s.UpdateTaskProgress(taskID, progress)
+		// 步骤内进度通过step消息推送，task_progress中为任务总进度
+		s.wsManager.SendStepProgress(taskID, step, i18n.T(lang, message), progress)
+		s.reportStepProgress(taskID, step, progress, i18n.T(lang, message))
 		if message != "" {
 			s.AddTaskLog(taskID, models.LogLevelInfo, message)
 		}
 	}
 
 	// 执行步骤
-	err := fn(progressCallback)
+	err := fn(ctx, progressCallback)
 	if err != nil {
+		if ctxErr := s.contextError(ctx); ctxErr != nil {
+			err = ctxErr
+		}
 		// Send step error message
-		s.wsManager.SendStepError(taskID, step, "Step failed", err.Error())
+		s.wsManager.SendStepError(taskID, step, i18n.T(lang, "Step failed"), err.Error())
 		s.AddTaskLog(taskID, models.LogLevelError, fmt.Sprintf("Step failed: %s - %v", step, err))
+		s.endStep(taskID, step, err)
 		return err
 	}
 
 	// Send step completion message
-	s.wsManager.SendStepComplete(taskID, step, "Step completed")
+	s.endStep(taskID, step, nil)
+	s.reportStepProgress(taskID, step, 100, "")
+	s.wsManager.SendStepComplete(taskID, step, i18n.T(lang, "Step completed"))
 	s.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("Step completed: %s", step))
 	return nil
 }
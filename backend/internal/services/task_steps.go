@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// beginStep 在任务中记录步骤开始，恢复任务时重新执行的步骤覆盖之前的记录
+func (s *TaskService) beginStep(taskID, step string) {
+	now := time.Now()
+	s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		for i := range task.Steps {
+			if task.Steps[i].Name == step {
+				task.Steps = append(task.Steps[:i], task.Steps[i+1:]...)
+				break
+			}
+		}
+		task.Steps = append(task.Steps, models.StepRecord{Name: step, Status: models.StepStatusRunning, StartedAt: &now})
+	})
+}
+
+// endStep 在任务中记录步骤结束，err不为nil时步骤为失败
+func (s *TaskService) endStep(taskID, step string, err error) {
+	s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		for i := range task.Steps {
+			if task.Steps[i].Name == step && task.Steps[i].Status == models.StepStatusRunning {
+				finishStep(&task.Steps[i], err)
+				return
+			}
+		}
+	})
+}
+
+// failRunningSteps 将任务中仍在运行的步骤标记为失败，用于服务重启后中断的任务
+func failRunningSteps(task *models.MigrationTask, reason string) {
+	for i := range task.Steps {
+		if task.Steps[i].Status == models.StepStatusRunning {
+			finishStep(&task.Steps[i], errors.New(reason))
+		}
+	}
+}
+
+// finishStep 设置步骤的结束时间、耗时和结果
+func finishStep(record *models.StepRecord, err error) {
+	now := time.Now()
+	record.FinishedAt = &now
+	if record.StartedAt != nil {
+		record.DurationMs = now.Sub(*record.StartedAt).Milliseconds()
+	}
+	record.Status = models.StepStatusDone
+	record.Error = ""
+	if err != nil {
+		record.Status = models.StepStatusFailed
+		record.Error = err.Error()
+	}
+}
+
+// GetTaskSteps 返回任务的步骤清单：计划中的步骤按顺序排列，未执行的步骤为pending，
+// 后续步骤已开始或任务已完成时为skipped；不在计划中的步骤按开始顺序追加在后面
+func (s *TaskService) GetTaskSteps(taskID string) ([]models.StepRecord, error) {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return buildStepList(migrationPlans[task.Type], task.Steps, task.Status), nil
+}
+
+// buildStepList 合并计划步骤和已执行步骤的记录
+func buildStepList(plan []planStep, records []models.StepRecord, taskStatus string) []models.StepRecord {
+	byName := make(map[string]models.StepRecord, len(records))
+	for _, record := range records {
+		byName[record.Name] = record
+	}
+
+	steps := make([]models.StepRecord, 0, len(plan)+len(records))
+	planned := make(map[string]bool, len(plan))
+	lastStarted := -1
+	for i, p := range plan {
+		planned[p.Name] = true
+		record, ok := byName[p.Name]
+		if !ok {
+			record = models.StepRecord{Name: p.Name, Status: models.StepStatusPending}
+		} else {
+			lastStarted = i
+		}
+		steps = append(steps, record)
+	}
+	for _, record := range records {
+		if !planned[record.Name] {
+			steps = append(steps, record)
+		}
+	}
+
+	now := time.Now()
+	for i := range steps {
+		step := &steps[i]
+		switch {
+		case step.Status == models.StepStatusRunning && step.StartedAt != nil:
+			// 运行中的步骤返回已用时间
+			step.DurationMs = now.Sub(*step.StartedAt).Milliseconds()
+		case step.Status == models.StepStatusPending && (i < lastStarted || taskStatus == string(models.TaskStatusCompleted)):
+			step.Status = models.StepStatusSkipped
+		}
+	}
+	return steps
+}
+
+// RecordPanic 记录步骤中发生的异常：任务标记为失败，异常信息和协程堆栈写入任务日志和任务结果的panic
+// message为带%v的日志消息，stack为recover时runtime/debug.Stack()的结果
+func (s *TaskService) RecordPanic(taskID, message string, value interface{}, stack []byte) {
+	log.Printf("[ERROR] Task %s panicked: %v\n%s", taskID, value, stack)
+
+	reason := fmt.Sprintf(message, value)
+	s.store.UpdateTask(taskID, func(task *models.MigrationTask) {
+		details := map[string]interface{}{
+			"message": fmt.Sprint(value),
+			"stack":   string(stack),
+		}
+		for _, step := range task.Steps {
+			if step.Status == models.StepStatusRunning {
+				details["step"] = step.Name
+			}
+		}
+		failRunningSteps(task, reason)
+		if task.Result == nil {
+			task.Result = make(map[string]interface{})
+		}
+		task.Result["panic"] = details
+	})
+
+	s.UpdateTaskStatus(taskID, string(models.TaskStatusFailed))
+	s.AddTaskLog(taskID, models.LogLevelError, reason)
+	s.AddTaskLog(taskID, models.LogLevelError, fmt.Sprintf("Stack trace:\n%s", stack))
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"ctoz/backend/internal/models"
+)
+
+// TrueNAS SCALE应用布局
+const (
+	trueNASLayoutDocker = "docker" // 24.10及以后：/mnt/.ix-apps，应用为docker compose
+	trueNASLayoutK3s    = "k3s"    // 24.04及以前：<pool>/ix-applications，应用为Helm chart
+	trueNASDockerRoot   = "/mnt/.ix-apps"
+	trueNASCustomChart  = "ix-chart" // 自定义Docker镜像应用使用的chart
+)
+
+// trueNASLayout 远端TrueNAS应用数据集位置
+type trueNASLayout struct {
+	Kind    string
+	RootDir string
+}
+
+// trueNASSource TrueNAS SCALE源适配器
+// Docker版直接使用渲染后的compose文件；k3s版仅转换ix-chart自定义应用，其它Helm应用记录为跳过
+type trueNASSource struct {
+	s    *MigrationService
+	conn *models.SystemConnection
+}
+
+// ixChartValues ix-chart自定义应用的values（仅包含可转换为compose的字段）
+type ixChartValues struct {
+	Image struct {
+		Repository string `yaml:"repository"`
+		Tag        string `yaml:"tag"`
+	} `yaml:"image"`
+	ContainerCommand              []string `yaml:"containerCommand"`
+	ContainerArgs                 []string `yaml:"containerArgs"`
+	ContainerEnvironmentVariables []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"containerEnvironmentVariables"`
+	PortForwardingList []struct {
+		ContainerPort int    `yaml:"containerPort"`
+		NodePort      int    `yaml:"nodePort"`
+		Protocol      string `yaml:"protocol"`
+	} `yaml:"portForwardingList"`
+	HostPathVolumes []struct {
+		HostPath  string `yaml:"hostPath"`
+		MountPath string `yaml:"mountPath"`
+		ReadOnly  bool   `yaml:"readOnly"`
+	} `yaml:"hostPathVolumes"`
+	Volumes []struct {
+		DatasetName string `yaml:"datasetName"`
+		MountPath   string `yaml:"mountPath"`
+	} `yaml:"volumes"`
+	HostNetwork     bool `yaml:"hostNetwork"`
+	SecurityContext struct {
+		Privileged bool `yaml:"privileged"`
+	} `yaml:"securityContext"`
+}
+
+// Name 源类型名称
+func (t *trueNASSource) Name() string {
+	return models.SystemTypeTrueNAS
+}
+
+// Fetch 拉取TrueNAS应用并转换为统一快照
+func (t *trueNASSource) Fetch(ctx context.Context, taskID string, progressCallback func(int, string)) (*SourceSnapshot, error) {
+	layout, err := detectTrueNASLayout(ctx, t.conn)
+	if err != nil {
+		return nil, err
+	}
+	t.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("TrueNAS apps found at %s (%s)", layout.RootDir, layout.Kind))
+
+	extractDir := filepath.Join(t.s.cfg.Dirs.Extract, fmt.Sprintf("truenas_%s", time.Now().Format("20060102_150405")))
+	rawDir := filepath.Join(extractDir, ".truenas")
+	appsDir := filepath.Join(extractDir, "var/lib/casaos/apps")
+	appDataRoot := filepath.Join(extractDir, "DATA/AppData")
+	for _, dir := range []string{rawDir, appsDir, appDataRoot} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("Failed to create download directory: %v", err)
+		}
+	}
+
+	// Docker版需要应用配置和挂载数据，k3s版的releases目录同时包含chart和ix_volumes
+	remoteDirs := []string{"releases"}
+	if layout.Kind == trueNASLayoutDocker {
+		remoteDirs = []string{"app_configs", "app_mounts"}
+	}
+
+	progressCallback(10, "Syncing TrueNAS apps")
+	for _, dir := range remoteDirs {
+		if err := rsyncFrom(ctx, t.conn, path.Join(layout.RootDir, dir)+"/", filepath.Join(rawDir, dir)); err != nil {
+			os.RemoveAll(extractDir)
+			return nil, fmt.Errorf("Failed to sync %s: %v", dir, err)
+		}
+		progressCallback(20, fmt.Sprintf("Synced %s", dir))
+	}
+
+	progressCallback(40, "Download succeeded")
+	progressCallback(45, "Converting TrueNAS apps")
+
+	configDir := filepath.Join(rawDir, remoteDirs[0])
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		os.RemoveAll(extractDir)
+		return nil, fmt.Errorf("Failed to read TrueNAS apps directory: %v", err)
+	}
+
+	skipped := make(map[string]string)
+	for i, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		appName := entry.Name()
+		progressCallback(45+(i*15)/len(entries), fmt.Sprintf("Converting: %s", appName))
+
+		var composeContent, dataDir string
+		if layout.Kind == trueNASLayoutDocker {
+			composeContent, dataDir, err = t.convertDockerApp(rawDir, layout.RootDir, appName)
+		} else {
+			composeContent, dataDir, err = t.convertChartRelease(filepath.Join(configDir, appName), appName)
+		}
+		if err != nil {
+			skipped[appName] = err.Error()
+			continue
+		}
+
+		if err := writeSourceApp(appsDir, appDataRoot, appName, composeContent, dataDir); err != nil {
+			skipped[appName] = err.Error()
+			continue
+		}
+		t.s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("TrueNAS app %s converted", appName))
+	}
+
+	if err := os.RemoveAll(rawDir); err != nil {
+		log.Printf("[WARNING] Failed to remove TrueNAS raw directory: %v", err)
+	}
+
+	progressCallback(60, "Extraction succeeded")
+	return &SourceSnapshot{ExtractedPath: extractDir, SkippedApps: skipped}, nil
+}
+
+// convertDockerApp 转换Docker版TrueNAS应用：使用最新版本的渲染compose，并把app_mounts映射到AppData
+func (t *trueNASSource) convertDockerApp(rawDir, rootDir, appName string) (string, string, error) {
+	versionsDir := filepath.Join(rawDir, "app_configs", appName, "versions")
+	version, err := latestVersionDir(versionsDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	content, err := os.ReadFile(filepath.Join(versionsDir, version, "templates/rendered/docker-compose.yaml"))
+	if err != nil {
+		return "", "", fmt.Errorf("Rendered compose file not found: %v", err)
+	}
+
+	doc, err := parseCompose(string(content))
+	if err != nil {
+		return "", "", err
+	}
+
+	mountPrefix := path.Join(rootDir, "app_mounts", appName)
+	appDataPrefix := path.Join(casaOSAppDataDir, appName)
+	doc.RewriteBindSources(func(service, source string) string {
+		if source == mountPrefix || strings.HasPrefix(source, mountPrefix+"/") {
+			return appDataPrefix + strings.TrimPrefix(source, mountPrefix)
+		}
+		return source
+	})
+
+	composeContent, err := doc.String()
+	if err != nil {
+		return "", "", err
+	}
+	return composeContent, filepath.Join(rawDir, "app_mounts", appName), nil
+}
+
+// convertChartRelease 将k3s版的ix-chart自定义应用转换为compose，ix_volumes作为AppData
+func (t *trueNASSource) convertChartRelease(releaseDir, appName string) (string, string, error) {
+	chartsDir := filepath.Join(releaseDir, "charts")
+	version, err := latestVersionDir(chartsDir)
+	if err != nil {
+		return "", "", err
+	}
+	chartDir := filepath.Join(chartsDir, version)
+
+	var chart struct {
+		Name string `yaml:"name"`
+	}
+	chartContent, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		return "", "", fmt.Errorf("Chart.yaml not found: %v", err)
+	}
+	if err := yaml.Unmarshal(chartContent, &chart); err != nil {
+		return "", "", fmt.Errorf("Failed to parse Chart.yaml: %v", err)
+	}
+	if chart.Name != trueNASCustomChart {
+		return "", "", fmt.Errorf("Helm-only app (chart %s) cannot be converted to compose", chart.Name)
+	}
+
+	// 依次叠加values文件，后者覆盖前者
+	var values ixChartValues
+	found := false
+	for _, name := range []string{"values.yaml", "ix_values.yaml", "user_values.yaml"} {
+		content, err := os.ReadFile(filepath.Join(chartDir, name))
+		if err != nil {
+			continue
+		}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return "", "", fmt.Errorf("Failed to parse %s: %v", name, err)
+		}
+		found = true
+	}
+	if !found || values.Image.Repository == "" {
+		return "", "", fmt.Errorf("Chart values do not define an image")
+	}
+
+	composeContent, err := ixChartToCompose(appName, &values)
+	if err != nil {
+		return "", "", err
+	}
+	return composeContent, filepath.Join(releaseDir, "volumes/ix_volumes"), nil
+}
+
+// ixChartToCompose 根据ix-chart values生成compose内容
+func ixChartToCompose(appName string, values *ixChartValues) (string, error) {
+	image := values.Image.Repository
+	if values.Image.Tag != "" {
+		image += ":" + values.Image.Tag
+	}
+
+	service := yaml.MapSlice{
+		{Key: "image", Value: image},
+		{Key: "container_name", Value: appName},
+		{Key: "restart", Value: "unless-stopped"},
+	}
+	if len(values.ContainerCommand) > 0 {
+		service = append(service, yaml.MapItem{Key: "entrypoint", Value: values.ContainerCommand})
+	}
+	if len(values.ContainerArgs) > 0 {
+		service = append(service, yaml.MapItem{Key: "command", Value: values.ContainerArgs})
+	}
+	if len(values.ContainerEnvironmentVariables) > 0 {
+		env := yaml.MapSlice{}
+		for _, v := range values.ContainerEnvironmentVariables {
+			env = append(env, yaml.MapItem{Key: v.Name, Value: v.Value})
+		}
+		service = append(service, yaml.MapItem{Key: "environment", Value: env})
+	}
+	if values.HostNetwork {
+		service = append(service, yaml.MapItem{Key: "network_mode", Value: "host"})
+	} else if len(values.PortForwardingList) > 0 {
+		var ports []string
+		for _, p := range values.PortForwardingList {
+			port := fmt.Sprintf("%d:%d", p.NodePort, p.ContainerPort)
+			if protocol := strings.ToLower(p.Protocol); protocol != "" && protocol != "tcp" {
+				port += "/" + protocol
+			}
+			ports = append(ports, port)
+		}
+		service = append(service, yaml.MapItem{Key: "ports", Value: ports})
+	}
+
+	var volumes []string
+	for _, v := range values.Volumes {
+		volumes = append(volumes, fmt.Sprintf("%s:%s", path.Join(casaOSAppDataDir, appName, v.DatasetName), v.MountPath))
+	}
+	for _, v := range values.HostPathVolumes {
+		volume := fmt.Sprintf("%s:%s", v.HostPath, v.MountPath)
+		if v.ReadOnly {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+	if len(volumes) > 0 {
+		service = append(service, yaml.MapItem{Key: "volumes", Value: volumes})
+	}
+	if values.SecurityContext.Privileged {
+		service = append(service, yaml.MapItem{Key: "privileged", Value: true})
+	}
+
+	doc := &composeDocument{root: yaml.MapSlice{
+		{Key: "name", Value: appName},
+		{Key: "services", Value: yaml.MapSlice{{Key: appName, Value: service}}},
+	}}
+	return doc.String()
+}
+
+// detectTrueNASLayout 探测远端TrueNAS应用数据集位置，优先使用连接中配置的root_dir
+func detectTrueNASLayout(ctx context.Context, conn *models.SystemConnection) (*trueNASLayout, error) {
+	script := fmt.Sprintf(`if [ -d %[1]s/app_configs ]; then echo %[2]s %[1]s; else d=$(ls -d /mnt/*/ix-applications 2>/dev/null | head -n1); [ -n "$d" ] && echo %[3]s "$d"; fi`,
+		shellQuote(trueNASDockerRoot), trueNASLayoutDocker, trueNASLayoutK3s)
+	if conn.RootDir != "" {
+		root := shellQuote(strings.TrimRight(conn.RootDir, "/"))
+		script = fmt.Sprintf(`if [ -d %[1]s/app_configs ]; then echo %[2]s %[1]s; elif [ -d %[1]s/releases ]; then echo %[3]s %[1]s; fi`,
+			root, trueNASLayoutDocker, trueNASLayoutK3s)
+	}
+
+	output, err := runSSH(ctx, conn, nil, script)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(string(output)), " ", 2)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("No TrueNAS SCALE apps dataset found (set root_dir to the ix-applications or .ix-apps path)")
+	}
+	return &trueNASLayout{Kind: fields[0], RootDir: fields[1]}, nil
+}
+
+// latestVersionDir 返回目录下版本号最高的子目录名
+func latestVersionDir(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read versions directory: %v", err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("No versions found in %s", dir)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions[len(versions)-1], nil
+}
+
+// compareVersions 按点分数字比较版本号，非数字段按字符串比较
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var sa, sb string
+		if i < len(pa) {
+			sa = pa[i]
+		}
+		if i < len(pb) {
+			sb = pb[i]
+		}
+
+		na, errA := strconv.Atoi(sa)
+		nb, errB := strconv.Atoi(sb)
+		if errA == nil && errB == nil {
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
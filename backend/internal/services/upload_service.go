@@ -0,0 +1,301 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ctoz/backend/internal/config"
+	"ctoz/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// uploadDirPrefix 每个断点续传上传在上传目录中的子目录前缀
+	uploadDirPrefix = "tus_"
+	uploadInfoFile  = "info.json"
+	uploadDataFile  = "data"
+	uploadHashFile  = "sha256.json"
+)
+
+// uploadHashState 已接收数据的SHA-256中间状态，每次追加数据后更新，完成时不必重新读取整个文件
+type uploadHashState struct {
+	Offset int64  `json:"offset"` // 已计入哈希的字节数
+	State  []byte `json:"state"`  // sha256的MarshalBinary结果
+}
+
+// UploadService 导入文件的断点续传上传（tus协议）
+// 每个上传保存为上传目录下的一个子目录，服务重启或页面刷新后可以从已接收的位置继续
+// 长时间未续传的上传由临时文件清理删除
+type UploadService struct {
+	cfg   *config.Config
+	mutex sync.Mutex
+	busy  map[string]bool // 正在写入的上传，同一上传同时只允许一个写入请求
+}
+
+// NewUploadService 创建上传服务
+func NewUploadService(cfg *config.Config) *UploadService {
+	return &UploadService{
+		cfg:  cfg,
+		busy: make(map[string]bool),
+	}
+}
+
+// MaxSize 导入文件的最大字节数，0表示不限制
+func (s *UploadService) MaxSize() int64 {
+	return s.cfg.MaxUploadBytes
+}
+
+// CheckSize 检查导入文件大小是否超过上限
+func (s *UploadService) CheckSize(size int64) error {
+	if limit := s.MaxSize(); limit > 0 && size > limit {
+		return fmt.Errorf("File size exceeds limit (%s)", formatBytes(limit))
+	}
+	return nil
+}
+
+// CreateUpload 创建上传，length为文件总大小
+func (s *UploadService) CreateUpload(filename string, length int64, metadata map[string]string) (*models.Upload, error) {
+	upload := &models.Upload{
+		ID:        uuid.New().String(),
+		Filename:  filepath.Base(filename),
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+
+	dir := s.uploadDir(upload.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to create upload directory: %v", err)
+	}
+	info, err := json.Marshal(upload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize upload info: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, uploadInfoFile), info, 0644); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("Failed to save upload info: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, uploadDataFile), nil, 0644); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("Failed to create upload file: %v", err)
+	}
+	return upload, nil
+}
+
+// GetUpload 获取上传及已接收的字节数
+func (s *UploadService) GetUpload(uploadID string) (*models.Upload, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return nil, models.ErrUploadNotFound
+	}
+	dir := s.uploadDir(uploadID)
+
+	data, err := os.ReadFile(filepath.Join(dir, uploadInfoFile))
+	if err != nil {
+		return nil, models.ErrUploadNotFound
+	}
+	var upload models.Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("Failed to parse upload info: %v", err)
+	}
+
+	stat, err := os.Stat(filepath.Join(dir, uploadDataFile))
+	if err != nil {
+		return nil, models.ErrUploadNotFound
+	}
+	upload.Offset = stat.Size()
+	return &upload, nil
+}
+
+// WriteChunk 从offset处追加数据，offset必须等于已接收的字节数，返回新的偏移
+// 连接中断时已写入的数据保留，客户端可以查询偏移后继续
+func (s *UploadService) WriteChunk(uploadID string, offset int64, r io.Reader) (int64, error) {
+	if !s.acquire(uploadID) {
+		return 0, models.ErrUploadBusy
+	}
+	defer s.release(uploadID)
+
+	upload, err := s.GetUpload(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if offset != upload.Offset {
+		return upload.Offset, models.ErrUploadOffsetMismatch
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.uploadDir(uploadID), uploadDataFile), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return offset, fmt.Errorf("Failed to open upload file: %v", err)
+	}
+	defer file.Close()
+
+	// 中间状态与已接收的数据不一致时不再增量计算，完成时重新读取文件
+	hasher := s.loadHash(uploadID, offset)
+	var w io.Writer = file
+	if hasher != nil {
+		w = &hashingWriter{w: file, hash: hasher}
+	}
+
+	// 多读一个字节，用于判断数据是否超过声明的总大小
+	remaining := upload.Length - offset
+	written, err := io.Copy(w, io.LimitReader(r, remaining+1))
+	if written > remaining {
+		file.Truncate(upload.Length)
+		return upload.Length, models.ErrUploadTooLarge
+	}
+	if hasher != nil {
+		s.saveHash(uploadID, offset+written, hasher)
+	}
+	if err != nil {
+		return offset + written, fmt.Errorf("Failed to write upload data: %v", err)
+	}
+	return offset + written, nil
+}
+
+// Checksum 返回接收完成的上传的SHA-256（十六进制）
+func (s *UploadService) Checksum(uploadID string) (string, error) {
+	upload, err := s.GetUpload(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if upload.Offset != upload.Length {
+		return "", models.ErrUploadIncomplete
+	}
+	if hasher := s.loadHash(uploadID, upload.Offset); hasher != nil {
+		return hex.EncodeToString(hasher.Sum(nil)), nil
+	}
+	return FileSHA256(filepath.Join(s.uploadDir(uploadID), uploadDataFile))
+}
+
+// hashingWriter 只把成功写入文件的字节计入哈希，写入中断时状态仍与文件一致
+type hashingWriter struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	h.hash.Write(p[:n])
+	return n, err
+}
+
+// loadHash 读取哈希中间状态，offset为0时从头开始，状态缺失或与offset不一致时返回nil
+func (s *UploadService) loadHash(uploadID string, offset int64) hash.Hash {
+	hasher := sha256.New()
+	if offset == 0 {
+		return hasher
+	}
+	data, err := os.ReadFile(filepath.Join(s.uploadDir(uploadID), uploadHashFile))
+	if err != nil {
+		return nil
+	}
+	var state uploadHashState
+	if err := json.Unmarshal(data, &state); err != nil || state.Offset != offset {
+		return nil
+	}
+	if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(state.State); err != nil {
+		return nil
+	}
+	return hasher
+}
+
+// saveHash 保存哈希中间状态
+func (s *UploadService) saveHash(uploadID string, offset int64, hasher hash.Hash) {
+	path := filepath.Join(s.uploadDir(uploadID), uploadHashFile)
+	state, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(uploadHashState{Offset: offset, State: state}); err == nil {
+			err = os.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		// 没有可用的中间状态时完成后重新计算
+		os.Remove(path)
+	}
+}
+
+// DeleteUpload 取消上传并删除已接收的数据
+func (s *UploadService) DeleteUpload(uploadID string) error {
+	if _, err := s.GetUpload(uploadID); err != nil {
+		return err
+	}
+	if !s.acquire(uploadID) {
+		return models.ErrUploadBusy
+	}
+	defer s.release(uploadID)
+
+	if err := os.RemoveAll(s.uploadDir(uploadID)); err != nil {
+		return fmt.Errorf("Failed to delete upload: %v", err)
+	}
+	return nil
+}
+
+// CompleteUpload 将接收完成的上传移动为导入文件，返回导入文件路径和上传信息
+func (s *UploadService) CompleteUpload(uploadID string) (string, *models.Upload, error) {
+	if !s.acquire(uploadID) {
+		return "", nil, models.ErrUploadBusy
+	}
+	defer s.release(uploadID)
+
+	upload, err := s.GetUpload(uploadID)
+	if err != nil {
+		return "", nil, err
+	}
+	if upload.Offset != upload.Length {
+		return "", nil, models.ErrUploadIncomplete
+	}
+
+	// 与普通上传使用相同的命名，清理时由任务引用保护
+	dir := s.uploadDir(uploadID)
+	importFile := filepath.Join(s.cfg.Dirs.Upload, fmt.Sprintf("import_%s_%s%s", time.Now().Format("20060102_150405"), uploadID[:8], uploadFileExt(upload.Filename)))
+	if err := os.Rename(filepath.Join(dir, uploadDataFile), importFile); err != nil {
+		return "", nil, fmt.Errorf("Failed to move uploaded file: %v", err)
+	}
+	os.RemoveAll(dir)
+	return importFile, upload, nil
+}
+
+// uploadDir 上传的保存目录
+func (s *UploadService) uploadDir(uploadID string) string {
+	return filepath.Join(s.cfg.Dirs.Upload, uploadDirPrefix+uploadID)
+}
+
+// acquire 标记上传正在写入，已在写入时返回false
+func (s *UploadService) acquire(uploadID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.busy[uploadID] {
+		return false
+	}
+	s.busy[uploadID] = true
+	return true
+}
+
+// release 取消上传的写入标记
+func (s *UploadService) release(uploadID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.busy, uploadID)
+}
+
+// uploadFileExt 返回导入文件的扩展名，.tar.gz 等复合扩展名作为整体保留
+func uploadFileExt(filename string) string {
+	name := strings.ToLower(filename)
+	for _, suffix := range importFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return suffix
+		}
+	}
+	return strings.ToLower(filepath.Ext(filename))
+}
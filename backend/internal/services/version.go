@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+// detectVersion 登录成功后检测CasaOS/ZimaOS的版本，写入连接信息和测试结果，检测失败时版本为空
+func (s *ConnectionService) detectVersion(ctx context.Context, conn *models.SystemConnection, response *models.ConnectionTestResponse) {
+	version, err := s.fetchVersion(ctx, conn)
+	if err != nil {
+		log.Printf("[WARNING] Failed to detect version of %s: %v", conn.Host, err)
+	}
+	conn.Version = version
+	if version != "" && response.SystemInfo != nil {
+		response.SystemInfo["version"] = version
+	}
+}
+
+// fetchVersion 先读取版本接口，不可用时读取系统信息
+func (s *ConnectionService) fetchVersion(ctx context.Context, conn *models.SystemConnection) (string, error) {
+	apiURL := connBaseURL(conn) + "/v1/sys/version"
+	req, err := newConnRequest(ctx, conn, "GET", apiURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", conn.Token)
+
+	if resp, err := s.client.Do(req); err == nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+
+		var result map[string]interface{}
+		if resp.StatusCode == http.StatusOK && json.Unmarshal(body, &result) == nil {
+			if version := findVersion(result); version != "" {
+				return normalizeVersion(version), nil
+			}
+		}
+	}
+
+	info, err := s.GetSystemInfo(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	return normalizeVersion(findVersion(info)), nil
+}
+
+// normalizeVersion 去掉版本号前的v和空白
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// zimaOSFilesV2MinVersion 提供 /v2_1/files 文件接口的最低ZimaOS版本
+	zimaOSFilesV2MinVersion = "1.2"
+	// zimaOSTaskPollInterval 轮询文件任务状态的间隔
+	zimaOSTaskPollInterval = 2 * time.Second
+	// zimaOSTaskTimeout 等待单个文件任务完成的最长时间
+	zimaOSTaskTimeout = 6 * time.Hour
+	// zimaOSTaskMaxErrors 连续查询失败多少次后放弃等待
+	zimaOSTaskMaxErrors = 5
+)
+
+// zimaOSClient ZimaOS文件与应用管理接口，不同版本的路径和参数不同
+type zimaOSClient interface {
+	// Name 接口版本名称，记录在任务日志中
+	Name() string
+	// Upload 上传本地文件到远端目录
+	Upload(ctx context.Context, localPath, remoteDir, filename string, onProgress func(transferred, total int64)) error
+	// Decompress 在远端将压缩包解压到目录，等待解压完成后返回，进度（0-100）和目标上的任务状态通过onProgress报告
+	Decompress(ctx context.Context, archivePath, remoteDir string, onProgress func(progress int, status string)) error
+	// Delete 删除远端文件
+	Delete(ctx context.Context, remotePath string) error
+	// ImportCompose 通过应用管理接口安装compose应用，返回响应状态码和内容；checkPorts为false时不检查端口冲突
+	ImportCompose(ctx context.Context, composeContent string, checkPorts bool) (int, []byte, error)
+	// UninstallCompose 卸载compose应用，deleteConfig为true时同时删除应用目录，AppData不受影响
+	UninstallCompose(ctx context.Context, appName string, deleteConfig bool) error
+	// StartCompose 启动已安装的compose应用
+	StartCompose(ctx context.Context, appName string) error
+	// ComposeContainers 获取compose应用的容器状态
+	ComposeContainers(ctx context.Context, appName string) ([]appContainer, error)
+}
+
+// zimaOSClientFor 根据连接测试时检测到的版本选择接口实现，版本未知时使用当前接口
+func (s *MigrationService) zimaOSClientFor(conn *models.SystemConnection) zimaOSClient {
+	current := &zimaOSV2Client{s: s, conn: conn}
+	if conn.Version != "" && compareVersions(conn.Version, zimaOSFilesV2MinVersion) < 0 {
+		return &zimaOSLegacyClient{zimaOSV2Client: current}
+	}
+	return current
+}
+
+// logZimaOSClient 在任务日志中记录检测到的ZimaOS版本和使用的接口版本
+func (s *MigrationService) logZimaOSClient(taskID string, conn *models.SystemConnection) {
+	client := s.zimaOSClientFor(conn)
+	if conn.Version == "" {
+		s.taskService.AddTaskLog(taskID, models.LogLevelWarning, fmt.Sprintf("ZimaOS version unknown, using %s file API", client.Name()))
+		return
+	}
+	s.taskService.AddTaskLog(taskID, models.LogLevelInfo, fmt.Sprintf("ZimaOS %s detected, using %s file API", conn.Version, client.Name()))
+}
+
+// zimaOSV2Client ZimaOS 1.2及以上版本：/v2_1/files 文件接口和 /v2/app_management 应用接口
+type zimaOSV2Client struct {
+	s    *MigrationService
+	conn *models.SystemConnection
+}
+
+// Name 接口版本名称
+func (c *zimaOSV2Client) Name() string {
+	return "v2.1"
+}
+
+// url 构建接口地址
+func (c *zimaOSV2Client) url(apiPath string) string {
+	return connBaseURL(c.conn) + apiPath
+}
+
+// Upload 使用uploadV2接口上传文件
+func (c *zimaOSV2Client) Upload(ctx context.Context, localPath, remoteDir, filename string, onProgress func(transferred, total int64)) error {
+	fields := map[string]string{"path": remoteDir, "rename": ""}
+	return c.s.uploadFileToZimaOS(ctx, c.conn, c.url("/v2_1/files/file/uploadV2"), localPath, fields, filename, onProgress)
+}
+
+// Decompress 提交解压任务并轮询任务状态直到完成
+func (c *zimaOSV2Client) Decompress(ctx context.Context, archivePath, remoteDir string, onProgress func(progress int, status string)) error {
+	taskID, err := c.s.extractFileOnZimaOS(ctx, c.conn, c.url("/v2_1/files/task/decompress"), archivePath, remoteDir)
+	if err != nil {
+		return err
+	}
+	if taskID == "" {
+		log.Printf("[WARNING] ZimaOS returned no task ID for decompressing %s, cannot wait for completion", archivePath)
+		return nil
+	}
+	return c.waitFileTask(ctx, taskID, onProgress)
+}
+
+// waitFileTask 轮询文件任务直到完成或失败
+// 任务结束后可能被立即移除，查询返回404时视为已完成
+func (c *zimaOSV2Client) waitFileTask(ctx context.Context, taskID string, onProgress func(progress int, status string)) error {
+	deadline := time.Now().Add(zimaOSTaskTimeout)
+	lastProgress, lastStatus, failures := -1, "", 0
+	for {
+		state, found, err := c.queryFileTask(ctx, taskID)
+		switch {
+		case err != nil:
+			failures++
+			if failures >= zimaOSTaskMaxErrors {
+				return fmt.Errorf("Failed to query decompression task %s: %v", taskID, err)
+			}
+		case !found:
+			onProgress(100, "finished")
+			return nil
+		default:
+			failures = 0
+			if state.progress != lastProgress || state.status != lastStatus {
+				lastProgress, lastStatus = state.progress, state.status
+				onProgress(state.progress, state.status)
+			}
+			switch state.status {
+			case "finished", "finish", "completed", "complete", "success", "succeeded", "done":
+				onProgress(100, state.status)
+				return nil
+			case "failed", "fail", "error", "canceled", "cancelled":
+				return fmt.Errorf("Decompression task %s %s: %s", taskID, state.status, state.message)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timed out waiting for decompression task %s", taskID)
+		}
+		if !sleepContext(ctx, zimaOSTaskPollInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// fileTaskState 文件任务状态
+type fileTaskState struct {
+	status   string
+	progress int
+	message  string
+}
+
+// queryFileTask 查询文件任务状态，任务不存在时found为false
+func (c *zimaOSV2Client) queryFileTask(ctx context.Context, taskID string) (state fileTaskState, found bool, err error) {
+	req, err := newConnRequest(ctx, c.conn, "GET", c.url("/v2_1/files/task/"+url.PathEscape(taskID)), nil)
+	if err != nil {
+		return state, false, fmt.Errorf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", c.conn.Token)
+
+	resp, err := c.s.client.Do(req)
+	if err != nil {
+		return state, false, fmt.Errorf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusNotFound {
+		return state, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return state, false, fmt.Errorf("Status code: %d, response: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return state, false, fmt.Errorf("Failed to parse response: %v", err)
+	}
+	for _, key := range []string{"status", "state"} {
+		if status, ok := result.Data[key].(string); ok && status != "" {
+			state.status = strings.ToLower(status)
+			break
+		}
+	}
+	for _, key := range []string{"progress", "percent"} {
+		if value, ok := result.Data[key]; ok {
+			state.progress = int(jsonInt64(value))
+			break
+		}
+	}
+	for _, key := range []string{"message", "error", "err_msg"} {
+		if message, ok := result.Data[key].(string); ok && message != "" {
+			state.message = message
+			break
+		}
+	}
+	return state, true, nil
+}
+
+// fileTaskID 从文件任务接口的响应数据中取出任务ID，兼容字符串和对象两种格式
+func fileTaskID(data interface{}) string {
+	switch v := data.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	case map[string]interface{}:
+		for _, key := range []string{"id", "task_id", "uuid"} {
+			if id := fileTaskID(v[key]); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// Delete 使用批量删除接口删除文件
+func (c *zimaOSV2Client) Delete(ctx context.Context, remotePath string) error {
+	return c.s.deleteFileOnZimaOS(ctx, c.conn, c.url("/v2_1/files/file"), remotePath)
+}
+
+// ImportCompose 提交compose到应用管理接口
+func (c *zimaOSV2Client) ImportCompose(ctx context.Context, composeContent string, checkPorts bool) (int, []byte, error) {
+	apiPath := fmt.Sprintf("/v2/app_management/compose?dry_run=false&check_port_conflict=%t", checkPorts)
+	req, err := newConnRequest(ctx, c.conn, "POST", c.url(apiPath), strings.NewReader(composeContent))
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to create request: %v", err)
+	}
+
+	// 设置请求头
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en-US;q=0.8,en;q=0.7")
+	req.Header.Set("Authorization", c.conn.Token)
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Content-Type", "application/yaml")
+	req.Header.Set("Language", "en_US")
+	req.Header.Set("Origin", connBaseURL(c.conn))
+	req.Header.Set("Referer", fmt.Sprintf("%s/modules/icewhale_app/?_t=%d", connBaseURL(c.conn), time.Now().Unix()))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36")
+
+	resp, err := c.s.composeClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("Failed to read response: %v", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// StartCompose 将应用状态设置为running
+func (c *zimaOSV2Client) StartCompose(ctx context.Context, appName string) error {
+	if err := c.s.setComposeStatus(ctx, c.conn, appName, "running"); err != nil {
+		return fmt.Errorf("Failed to start app: %v", err)
+	}
+	return nil
+}
+
+// UninstallCompose 通过应用管理接口卸载应用
+func (c *zimaOSV2Client) UninstallCompose(ctx context.Context, appName string, deleteConfig bool) error {
+	apiPath := fmt.Sprintf("/v2/app_management/compose/%s?delete_config_folder=%t", url.PathEscape(appName), deleteConfig)
+	req, err := newConnRequest(ctx, c.conn, "DELETE", c.url(apiPath), nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", c.conn.Token)
+
+	resp, err := c.s.composeClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Failed to uninstall app %s, status code: %d, response: %s", appName, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// ComposeContainers 读取应用的容器列表，健康状态从Docker状态描述中解析
+func (c *zimaOSV2Client) ComposeContainers(ctx context.Context, appName string) ([]appContainer, error) {
+	var result struct {
+		Containers map[string]struct {
+			State  string `json:"State"`
+			Status string `json:"Status"`
+		} `json:"containers"`
+	}
+	if err := c.s.casaOSGet(ctx, c.conn, "/v2/app_management/compose/"+url.PathEscape(appName)+"/containers", nil, &result); err != nil {
+		return nil, err
+	}
+
+	containers := make([]appContainer, 0, len(result.Containers))
+	for service, container := range result.Containers {
+		containers = append(containers, appContainer{
+			Name:   service,
+			State:  container.State,
+			Health: healthFromStatus(container.Status),
+		})
+	}
+	return containers, nil
+}
+
+// zimaOSLegacyClient 1.2之前的ZimaOS沿用CasaOS的 /v1/file 接口，没有解压接口
+// 应用接口与当前版本相同
+type zimaOSLegacyClient struct {
+	*zimaOSV2Client
+}
+
+// Name 接口版本名称
+func (c *zimaOSLegacyClient) Name() string {
+	return "v1"
+}
+
+// Upload 使用v1上传接口，整个文件作为单个分块上传
+func (c *zimaOSLegacyClient) Upload(ctx context.Context, localPath, remoteDir, filename string, onProgress func(transferred, total int64)) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to get file info: %v", err)
+	}
+	size := strconv.FormatInt(info.Size(), 10)
+	fields := map[string]string{
+		"path":             remoteDir,
+		"filename":         filename,
+		"relativePath":     filename,
+		"identifier":       fmt.Sprintf("%s-%s", size, filename),
+		"chunkNumber":      "1",
+		"totalChunks":      "1",
+		"chunkSize":        size,
+		"currentChunkSize": size,
+		"totalSize":        size,
+	}
+	return c.s.uploadFileToZimaOS(ctx, c.conn, c.url("/v1/file/upload"), localPath, fields, filename, onProgress)
+}
+
+// Decompress 旧版本没有解压接口，通过SSH同步解压，未配置SSH端口时无法完成
+func (c *zimaOSLegacyClient) Decompress(ctx context.Context, archivePath, remoteDir string, onProgress func(progress int, status string)) error {
+	if c.conn.SSHPort <= 0 {
+		return fmt.Errorf("ZimaOS %s has no decompression API, configure ssh_port to extract over SSH", c.conn.Version)
+	}
+	sshConn := *c.conn
+	sshConn.Port = c.conn.SSHPort
+	cmd := fmt.Sprintf("unzip -o -q %s -d %s", shellQuote(archivePath), shellQuote(remoteDir))
+	if output, err := runSSH(ctx, &sshConn, nil, cmd); err != nil {
+		return fmt.Errorf("Decompression over SSH failed: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	onProgress(100, "finished")
+	return nil
+}
+
+// Delete 使用v1删除接口
+func (c *zimaOSLegacyClient) Delete(ctx context.Context, remotePath string) error {
+	return c.s.deleteFileOnZimaOS(ctx, c.conn, c.url("/v1/file/delete"), path.Clean(remotePath))
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"ctoz/backend/internal/models"
+)
+
+const (
+	// targetVolumeOption 迁移选项：ZimaOS上存放AppData的存储卷挂载点
+	targetVolumeOption = "target_volume"
+	// zimaOSDataRoot ZimaOS系统数据盘，未选择存储卷时AppData写入该卷
+	zimaOSDataRoot = "/media/ZimaOS-HD"
+	// zimaOSMediaDir ZimaOS挂载数据盘和存储池的目录
+	zimaOSMediaDir = "/media/"
+)
+
+// ListTargetStorage 列出ZimaOS目标上可用于存放AppData的磁盘和存储池，系统数据盘始终排在第一位
+func (s *MigrationService) ListTargetStorage(ctx context.Context, conn *models.SystemConnection) ([]models.TargetVolume, error) {
+	if conn.Type != models.SystemTypeZimaOS {
+		return nil, fmt.Errorf("Storage selection is only supported for ZimaOS targets")
+	}
+
+	var disks []struct {
+		DiskName string `json:"disk_name"`
+		Children []struct {
+			Label      string      `json:"label"`
+			MountPoint string      `json:"mount_point"`
+			Type       string      `json:"type"`
+			Size       interface{} `json:"size"`
+			Avail      interface{} `json:"avail"`
+		} `json:"children"`
+	}
+	if err := s.casaOSGet(ctx, conn, "/v1/storage", nil, &disks); err != nil {
+		return nil, err
+	}
+
+	volumes := []models.TargetVolume{{
+		Name:       path.Base(zimaOSDataRoot),
+		MountPoint: zimaOSDataRoot,
+		AppDataDir: zimaOSAppDataDir(zimaOSDataRoot),
+		Default:    true,
+	}}
+	seen := map[string]bool{zimaOSDataRoot: true}
+	for _, disk := range disks {
+		for _, part := range disk.Children {
+			// 只有挂载在/media下的分区和存储池可以存放应用数据
+			mountPoint := path.Clean(part.MountPoint)
+			if part.MountPoint == "" || !strings.HasPrefix(mountPoint, zimaOSMediaDir) {
+				continue
+			}
+			size, avail := jsonInt64(part.Size), jsonInt64(part.Avail)
+			if mountPoint == zimaOSDataRoot {
+				volumes[0].Type, volumes[0].Size, volumes[0].Avail = part.Type, size, avail
+				continue
+			}
+			if seen[mountPoint] {
+				continue
+			}
+			seen[mountPoint] = true
+
+			name := part.Label
+			if name == "" {
+				name = path.Base(mountPoint)
+			}
+			volumes = append(volumes, models.TargetVolume{
+				Name:       name,
+				MountPoint: mountPoint,
+				Type:       part.Type,
+				Size:       size,
+				Avail:      avail,
+				AppDataDir: zimaOSAppDataDir(mountPoint),
+			})
+		}
+	}
+	return volumes, nil
+}
+
+// zimaOSTargetAppDataDir 根据迁移选项确定ZimaOS上的AppData目录，选择的存储卷必须存在于目标系统
+func (s *MigrationService) zimaOSTargetAppDataDir(ctx context.Context, conn *models.SystemConnection, options map[string]interface{}) (string, error) {
+	volume := selectedTargetVolume(options)
+	if volume == "" || volume == zimaOSDataRoot {
+		return zimaOSAppDataDir(zimaOSDataRoot), nil
+	}
+
+	volumes, err := s.ListTargetStorage(ctx, conn)
+	if err != nil {
+		return "", fmt.Errorf("Failed to list target storage: %v", err)
+	}
+	for _, v := range volumes {
+		if v.MountPoint == volume || v.Name == volume {
+			return v.AppDataDir, nil
+		}
+	}
+	return "", fmt.Errorf("Target volume %s not found on ZimaOS", volume)
+}
+
+// selectedTargetVolume 返回迁移选项中选择的存储卷，未选择时返回空字符串
+func selectedTargetVolume(options map[string]interface{}) string {
+	volume, _ := options[targetVolumeOption].(string)
+	volume = strings.TrimSpace(volume)
+	if volume == "" {
+		return ""
+	}
+	if strings.HasPrefix(volume, "/") {
+		return path.Clean(volume)
+	}
+	return volume
+}
+
+// zimaOSAppDataDir 存储卷上的AppData目录
+func zimaOSAppDataDir(volume string) string {
+	return path.Join(volume, "AppData")
+}
+
+// rewriteZimaOSAppDataPaths 将compose中指向默认AppData目录的路径改写为所选存储卷上的目录
+func rewriteZimaOSAppDataPaths(composeContent, appDataDir string) string {
+	defaultDir := zimaOSAppDataDir(zimaOSDataRoot)
+	if appDataDir == defaultDir {
+		return composeContent
+	}
+	// ZimaOS上/DATA指向系统数据盘，两种写法都需要改写
+	for _, dir := range []string{casaOSAppDataDir, defaultDir} {
+		composeContent = strings.ReplaceAll(composeContent, dir+"/", appDataDir+"/")
+	}
+	return composeContent
+}
+
+// jsonInt64 解析接口返回的数字，兼容数字和字符串两种格式
+func jsonInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n
+	}
+	return 0
+}
+
+// targetDiskFullError 目标存储卷的可用空间不足以容纳应用的压缩包和解压后的数据
+type targetDiskFullError struct {
+	dir       string
+	required  int64
+	available int64
+}
+
+func (e *targetDiskFullError) Error() string {
+	return fmt.Sprintf("Not enough free space on the target for %s: %s required, %s available", e.dir, formatBytes(e.required), formatBytes(e.available))
+}
+
+// appErrorCode 返回已知失败原因的代码，其他错误返回空字符串
+func appErrorCode(err error) string {
+	var diskFull *targetDiskFullError
+	if errors.As(err, &diskFull) {
+		return models.AppErrorTargetDiskFull
+	}
+	return ""
+}
+
+// checkZimaOSFreeSpace 上传应用数据前检查目标存储卷的可用空间
+// 解压时压缩包和解压后的数据同时存在，需要的空间为两者之和；无法获取可用空间时只记录警告，不阻止上传
+func (s *MigrationService) checkZimaOSFreeSpace(ctx context.Context, target *models.SystemConnection, remoteAppDataDir, appName string, archiveBytes, dataBytes int64) error {
+	avail, _, err := s.remoteFreeSpace(ctx, target, remoteAppDataDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("[WARNING] Could not check free space on target for app %s: %v", appName, err)
+		return nil
+	}
+
+	required := archiveBytes + dataBytes
+	if avail < required {
+		return &targetDiskFullError{dir: remoteAppDataDir, required: required, available: avail}
+	}
+	return nil
+}
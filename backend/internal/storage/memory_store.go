@@ -21,9 +21,33 @@ type MemoryStore struct {
 	logs map[string][]*models.MigrationLog
 	logsMutex sync.RWMutex
 
+	// 任务事件存储，按任务保存发出的WebSocket消息
+	events map[string][]*models.TaskEvent
+	eventsMutex sync.RWMutex
+
 	// 下载指令存储
 	downloadInstructions map[string]*models.DownloadInstructions
 	downloadMutex sync.RWMutex
+
+	// 定时备份任务存储
+	backupJobs map[string]*models.BackupJob
+	backupJobsMutex sync.RWMutex
+
+	// 迁移选项预设存储，以名称为键
+	presets map[string]*models.OptionPreset
+	presetsMutex sync.RWMutex
+
+	// bind挂载路径改写规则存储
+	pathRules map[string]*models.PathRule
+	pathRulesMutex sync.RWMutex
+
+	// 镜像引用改写规则存储
+	imageRules map[string]*models.ImageRule
+	imageRulesMutex sync.RWMutex
+
+	// 持久化状态文件，为空时仅保存在内存中
+	statePath string
+	dirty int32
 }
 
 // NewMemoryStore 创建新的内存存储管理器
@@ -32,7 +56,12 @@ func NewMemoryStore() *MemoryStore {
 		tasks:                make(map[string]*models.MigrationTask),
 		connections:          make(map[string]*models.SystemConnection),
 		logs:                 make(map[string][]*models.MigrationLog),
+		events:               make(map[string][]*models.TaskEvent),
 		downloadInstructions: make(map[string]*models.DownloadInstructions),
+		backupJobs:           make(map[string]*models.BackupJob),
+		presets:              make(map[string]*models.OptionPreset),
+		pathRules:            make(map[string]*models.PathRule),
+		imageRules:           make(map[string]*models.ImageRule),
 	}
 }
 
@@ -42,6 +71,7 @@ func NewMemoryStore() *MemoryStore {
 func (ms *MemoryStore) SaveTask(task *models.MigrationTask) error {
 	ms.tasksMutex.Lock()
 	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
 
 	ms.tasks[task.ID] = task
 	return nil
@@ -75,16 +105,20 @@ func (ms *MemoryStore) GetAllTasks() ([]*models.MigrationTask, error) {
 func (ms *MemoryStore) DeleteTask(taskID string) error {
 	ms.tasksMutex.Lock()
 	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
 
 	if _, exists := ms.tasks[taskID]; !exists {
 		return models.ErrTaskNotFound
 	}
 
 	delete(ms.tasks, taskID)
-	// 同时删除相关日志
+	// 同时删除相关日志和事件
 	ms.logsMutex.Lock()
 	delete(ms.logs, taskID)
 	ms.logsMutex.Unlock()
+	ms.eventsMutex.Lock()
+	delete(ms.events, taskID)
+	ms.eventsMutex.Unlock()
 
 	return nil
 }
@@ -93,6 +127,7 @@ func (ms *MemoryStore) DeleteTask(taskID string) error {
 func (ms *MemoryStore) UpdateTaskStatus(taskID string, status string) error {
 	ms.tasksMutex.Lock()
 	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
 
 	task, exists := ms.tasks[taskID]
 	if !exists {
@@ -108,6 +143,7 @@ func (ms *MemoryStore) UpdateTaskStatus(taskID string, status string) error {
 func (ms *MemoryStore) UpdateTaskProgress(taskID string, progress int) error {
 	ms.tasksMutex.Lock()
 	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
 
 	task, exists := ms.tasks[taskID]
 	if !exists {
@@ -123,6 +159,7 @@ func (ms *MemoryStore) UpdateTaskProgress(taskID string, progress int) error {
 func (ms *MemoryStore) SetTaskResult(taskID string, result interface{}) error {
 	ms.tasksMutex.Lock()
 	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
 
 	task, exists := ms.tasks[taskID]
 	if !exists {
@@ -142,6 +179,22 @@ func (ms *MemoryStore) SetTaskResult(taskID string, result interface{}) error {
 	return nil
 }
 
+// UpdateTask 在锁内修改任务
+func (ms *MemoryStore) UpdateTask(taskID string, update func(task *models.MigrationTask)) error {
+	ms.tasksMutex.Lock()
+	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
+
+	task, exists := ms.tasks[taskID]
+	if !exists {
+		return models.ErrTaskNotFound
+	}
+
+	update(task)
+	task.UpdatedAt = time.Now()
+	return nil
+}
+
 // Connection 相关方法
 
 // SaveConnection 保存系统连接
@@ -196,6 +249,7 @@ func (ms *MemoryStore) DeleteConnection(connID string) error {
 func (ms *MemoryStore) AddLog(taskID string, log *models.MigrationLog) error {
 	ms.logsMutex.Lock()
 	defer ms.logsMutex.Unlock()
+	defer ms.markDirty()
 
 	if ms.logs[taskID] == nil {
 		ms.logs[taskID] = make([]*models.MigrationLog, 0)
@@ -221,11 +275,277 @@ func (ms *MemoryStore) GetLogs(taskID string) ([]*models.MigrationLog, error) {
 func (ms *MemoryStore) ClearLogs(taskID string) error {
 	ms.logsMutex.Lock()
 	defer ms.logsMutex.Unlock()
+	defer ms.markDirty()
 
 	delete(ms.logs, taskID)
 	return nil
 }
 
+// Event 相关方法
+
+// maxTaskEvents 每个任务保留的最多事件数，超出后丢弃最旧的事件
+const maxTaskEvents = 5000
+
+// AddEvent 追加任务事件并分配序号
+func (ms *MemoryStore) AddEvent(taskID string, message models.WSMessage) *models.TaskEvent {
+	ms.eventsMutex.Lock()
+	defer ms.eventsMutex.Unlock()
+	defer ms.markDirty()
+
+	events := ms.events[taskID]
+	event := &models.TaskEvent{Seq: 1, WSMessage: message}
+	if len(events) > 0 {
+		event.Seq = events[len(events)-1].Seq + 1
+	}
+	events = append(events, event)
+	// 超出上限时一次丢弃十分之一，避免每条事件都复制切片
+	if len(events) > maxTaskEvents {
+		keep := maxTaskEvents * 9 / 10
+		events = append([]*models.TaskEvent(nil), events[len(events)-keep:]...)
+	}
+	ms.events[taskID] = events
+	return event
+}
+
+// GetEvents 获取序号大于since的任务事件，同时返回最新事件的序号，
+// 以及since之后的事件是否有一部分已被丢弃
+func (ms *MemoryStore) GetEvents(taskID string, since int64) ([]*models.TaskEvent, int64, bool) {
+	ms.eventsMutex.RLock()
+	defer ms.eventsMutex.RUnlock()
+
+	events := ms.events[taskID]
+	if len(events) == 0 {
+		return []*models.TaskEvent{}, 0, false
+	}
+	// 序号连续递增，可以直接定位
+	first := events[0].Seq
+	start := since - first + 1
+	if start < 0 {
+		start = 0
+	}
+	result := []*models.TaskEvent{}
+	if start < int64(len(events)) {
+		result = append(result, events[start:]...)
+	}
+	return result, events[len(events)-1].Seq, since+1 < first
+}
+
+// BackupJob 相关方法
+
+// SaveBackupJob 保存定时备份任务
+func (ms *MemoryStore) SaveBackupJob(job *models.BackupJob) error {
+	ms.backupJobsMutex.Lock()
+	defer ms.backupJobsMutex.Unlock()
+	defer ms.markDirty()
+
+	ms.backupJobs[job.ID] = job
+	return nil
+}
+
+// GetBackupJob 获取定时备份任务
+func (ms *MemoryStore) GetBackupJob(jobID string) (*models.BackupJob, error) {
+	ms.backupJobsMutex.RLock()
+	defer ms.backupJobsMutex.RUnlock()
+
+	job, exists := ms.backupJobs[jobID]
+	if !exists {
+		return nil, models.ErrBackupJobNotFound
+	}
+	return job, nil
+}
+
+// GetAllBackupJobs 获取所有定时备份任务
+func (ms *MemoryStore) GetAllBackupJobs() []*models.BackupJob {
+	ms.backupJobsMutex.RLock()
+	defer ms.backupJobsMutex.RUnlock()
+
+	jobs := make([]*models.BackupJob, 0, len(ms.backupJobs))
+	for _, job := range ms.backupJobs {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// UpdateBackupJob 在锁内修改定时备份任务
+func (ms *MemoryStore) UpdateBackupJob(jobID string, update func(job *models.BackupJob)) error {
+	ms.backupJobsMutex.Lock()
+	defer ms.backupJobsMutex.Unlock()
+	defer ms.markDirty()
+
+	job, exists := ms.backupJobs[jobID]
+	if !exists {
+		return models.ErrBackupJobNotFound
+	}
+
+	update(job)
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+// DeleteBackupJob 删除定时备份任务
+func (ms *MemoryStore) DeleteBackupJob(jobID string) error {
+	ms.backupJobsMutex.Lock()
+	defer ms.backupJobsMutex.Unlock()
+	defer ms.markDirty()
+
+	if _, exists := ms.backupJobs[jobID]; !exists {
+		return models.ErrBackupJobNotFound
+	}
+
+	delete(ms.backupJobs, jobID)
+	return nil
+}
+
+// OptionPreset 相关方法
+
+// SavePreset 保存迁移选项预设，同名预设会被覆盖
+func (ms *MemoryStore) SavePreset(preset *models.OptionPreset) error {
+	ms.presetsMutex.Lock()
+	defer ms.presetsMutex.Unlock()
+	defer ms.markDirty()
+
+	ms.presets[preset.Name] = preset
+	return nil
+}
+
+// GetPreset 获取迁移选项预设
+func (ms *MemoryStore) GetPreset(name string) (*models.OptionPreset, error) {
+	ms.presetsMutex.RLock()
+	defer ms.presetsMutex.RUnlock()
+
+	preset, exists := ms.presets[name]
+	if !exists {
+		return nil, models.ErrPresetNotFound
+	}
+	return preset, nil
+}
+
+// GetAllPresets 获取所有迁移选项预设
+func (ms *MemoryStore) GetAllPresets() []*models.OptionPreset {
+	ms.presetsMutex.RLock()
+	defer ms.presetsMutex.RUnlock()
+
+	presets := make([]*models.OptionPreset, 0, len(ms.presets))
+	for _, preset := range ms.presets {
+		presets = append(presets, preset)
+	}
+	return presets
+}
+
+// DeletePreset 删除迁移选项预设
+func (ms *MemoryStore) DeletePreset(name string) error {
+	ms.presetsMutex.Lock()
+	defer ms.presetsMutex.Unlock()
+	defer ms.markDirty()
+
+	if _, exists := ms.presets[name]; !exists {
+		return models.ErrPresetNotFound
+	}
+
+	delete(ms.presets, name)
+	return nil
+}
+
+// PathRule 相关方法
+
+// SavePathRule 保存路径改写规则
+func (ms *MemoryStore) SavePathRule(rule *models.PathRule) error {
+	ms.pathRulesMutex.Lock()
+	defer ms.pathRulesMutex.Unlock()
+	defer ms.markDirty()
+
+	ms.pathRules[rule.ID] = rule
+	return nil
+}
+
+// GetPathRule 获取路径改写规则
+func (ms *MemoryStore) GetPathRule(ruleID string) (*models.PathRule, error) {
+	ms.pathRulesMutex.RLock()
+	defer ms.pathRulesMutex.RUnlock()
+
+	rule, exists := ms.pathRules[ruleID]
+	if !exists {
+		return nil, models.ErrPathRuleNotFound
+	}
+	return rule, nil
+}
+
+// GetAllPathRules 获取所有路径改写规则
+func (ms *MemoryStore) GetAllPathRules() []*models.PathRule {
+	ms.pathRulesMutex.RLock()
+	defer ms.pathRulesMutex.RUnlock()
+
+	rules := make([]*models.PathRule, 0, len(ms.pathRules))
+	for _, rule := range ms.pathRules {
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// DeletePathRule 删除路径改写规则
+func (ms *MemoryStore) DeletePathRule(ruleID string) error {
+	ms.pathRulesMutex.Lock()
+	defer ms.pathRulesMutex.Unlock()
+	defer ms.markDirty()
+
+	if _, exists := ms.pathRules[ruleID]; !exists {
+		return models.ErrPathRuleNotFound
+	}
+
+	delete(ms.pathRules, ruleID)
+	return nil
+}
+
+// ImageRule 相关方法
+
+// SaveImageRule 保存镜像改写规则
+func (ms *MemoryStore) SaveImageRule(rule *models.ImageRule) error {
+	ms.imageRulesMutex.Lock()
+	defer ms.imageRulesMutex.Unlock()
+	defer ms.markDirty()
+
+	ms.imageRules[rule.ID] = rule
+	return nil
+}
+
+// GetImageRule 获取镜像改写规则
+func (ms *MemoryStore) GetImageRule(ruleID string) (*models.ImageRule, error) {
+	ms.imageRulesMutex.RLock()
+	defer ms.imageRulesMutex.RUnlock()
+
+	rule, exists := ms.imageRules[ruleID]
+	if !exists {
+		return nil, models.ErrImageRuleNotFound
+	}
+	return rule, nil
+}
+
+// GetAllImageRules 获取所有镜像改写规则
+func (ms *MemoryStore) GetAllImageRules() []*models.ImageRule {
+	ms.imageRulesMutex.RLock()
+	defer ms.imageRulesMutex.RUnlock()
+
+	rules := make([]*models.ImageRule, 0, len(ms.imageRules))
+	for _, rule := range ms.imageRules {
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// DeleteImageRule 删除镜像改写规则
+func (ms *MemoryStore) DeleteImageRule(ruleID string) error {
+	ms.imageRulesMutex.Lock()
+	defer ms.imageRulesMutex.Unlock()
+	defer ms.markDirty()
+
+	if _, exists := ms.imageRules[ruleID]; !exists {
+		return models.ErrImageRuleNotFound
+	}
+
+	delete(ms.imageRules, ruleID)
+	return nil
+}
+
 // DownloadInstructions 相关方法
 
 // SaveDownloadInstructions 保存下载指令
@@ -264,6 +584,7 @@ func (ms *MemoryStore) DeleteDownloadInstructions(taskID string) error {
 func (ms *MemoryStore) CleanupExpiredTasks(expireDuration time.Duration) error {
 	ms.tasksMutex.Lock()
 	defer ms.tasksMutex.Unlock()
+	defer ms.markDirty()
 
 	now := time.Now()
 	expiredTasks := make([]string, 0)
@@ -277,11 +598,15 @@ func (ms *MemoryStore) CleanupExpiredTasks(expireDuration time.Duration) error {
 	// 删除过期任务
 	for _, taskID := range expiredTasks {
 		delete(ms.tasks, taskID)
-		// 同时删除相关日志和下载指令
+		// 同时删除相关日志、事件和下载指令
 		ms.logsMutex.Lock()
 		delete(ms.logs, taskID)
 		ms.logsMutex.Unlock()
 
+		ms.eventsMutex.Lock()
+		delete(ms.events, taskID)
+		ms.eventsMutex.Unlock()
+
 		ms.downloadMutex.Lock()
 		delete(ms.downloadInstructions, taskID)
 		ms.downloadMutex.Unlock()
@@ -290,8 +615,43 @@ func (ms *MemoryStore) CleanupExpiredTasks(expireDuration time.Duration) error {
 	return nil
 }
 
-// GetStats 获取存储统计信息
-func (ms *MemoryStore) GetStats() map[string]interface{} {
+// Purge 删除全部任务、日志、下载指令、保存的连接和定时备份任务，返回删除的数量
+// 预设和改写规则不包含凭据，予以保留
+func (ms *MemoryStore) Purge() models.PurgeReport {
+	ms.tasksMutex.Lock()
+	ms.connectionsMutex.Lock()
+	ms.logsMutex.Lock()
+	ms.eventsMutex.Lock()
+	ms.downloadMutex.Lock()
+	ms.backupJobsMutex.Lock()
+	defer ms.tasksMutex.Unlock()
+	defer ms.connectionsMutex.Unlock()
+	defer ms.logsMutex.Unlock()
+	defer ms.eventsMutex.Unlock()
+	defer ms.downloadMutex.Unlock()
+	defer ms.backupJobsMutex.Unlock()
+	defer ms.markDirty()
+
+	report := models.PurgeReport{
+		Tasks:       len(ms.tasks),
+		Connections: len(ms.connections),
+		BackupJobs:  len(ms.backupJobs),
+	}
+	for _, logs := range ms.logs {
+		report.Logs += len(logs)
+	}
+
+	ms.tasks = make(map[string]*models.MigrationTask)
+	ms.connections = make(map[string]*models.SystemConnection)
+	ms.logs = make(map[string][]*models.MigrationLog)
+	ms.events = make(map[string][]*models.TaskEvent)
+	ms.downloadInstructions = make(map[string]*models.DownloadInstructions)
+	ms.backupJobs = make(map[string]*models.BackupJob)
+	return report
+}
+
+// GetStats 获取存储统计信息，任务按状态和类型分别计数
+func (ms *MemoryStore) GetStats() models.StoreStats {
 	ms.tasksMutex.RLock()
 	ms.connectionsMutex.RLock()
 	ms.logsMutex.RLock()
@@ -302,15 +662,19 @@ func (ms *MemoryStore) GetStats() map[string]interface{} {
 	defer ms.logsMutex.RUnlock()
 	defer ms.downloadMutex.RUnlock()
 
-	totalLogs := 0
-	for _, logs := range ms.logs {
-		totalLogs += len(logs)
+	stats := models.StoreStats{
+		Tasks:                len(ms.tasks),
+		TasksByStatus:        make(map[string]int),
+		TasksByType:          make(map[string]int),
+		Connections:          len(ms.connections),
+		DownloadInstructions: len(ms.downloadInstructions),
 	}
-
-	return map[string]interface{}{
-		"tasks":                len(ms.tasks),
-		"connections":          len(ms.connections),
-		"total_logs":           totalLogs,
-		"download_instructions": len(ms.downloadInstructions),
+	for _, task := range ms.tasks {
+		stats.TasksByStatus[task.Status]++
+		stats.TasksByType[task.Type]++
+	}
+	for _, logs := range ms.logs {
+		stats.TotalLogs += len(logs)
 	}
-}
\ No newline at end of file
+	return stats
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"ctoz/backend/internal/models"
+)
+
+// persistInterval 状态文件的最长写入间隔
+const persistInterval = 2 * time.Second
+
+// persistedState 写入状态文件的内容
+type persistedState struct {
+	Tasks      []*models.MigrationTask           `json:"tasks"`
+	Logs       map[string][]*models.MigrationLog `json:"logs"`
+	Events     map[string][]*models.TaskEvent    `json:"events,omitempty"`
+	BackupJobs []*models.BackupJob               `json:"backup_jobs,omitempty"`
+	Presets    []*models.OptionPreset            `json:"presets,omitempty"`
+	PathRules  []*models.PathRule                `json:"path_rules,omitempty"`
+	ImageRules []*models.ImageRule               `json:"image_rules,omitempty"`
+}
+
+// EnablePersistence 从状态文件加载任务和日志，并在之后定期把变更写回该文件
+// 文件中包含连接凭据，以0600权限保存
+func (ms *MemoryStore) EnablePersistence(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("Failed to create state directory: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to read state file: %v", err)
+	}
+	if err == nil {
+		var state persistedState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("Failed to parse state file %s: %v", path, err)
+		}
+
+		ms.tasksMutex.Lock()
+		for _, task := range state.Tasks {
+			ms.tasks[task.ID] = task
+		}
+		ms.tasksMutex.Unlock()
+
+		ms.logsMutex.Lock()
+		for taskID, logs := range state.Logs {
+			ms.logs[taskID] = logs
+		}
+		ms.logsMutex.Unlock()
+
+		ms.eventsMutex.Lock()
+		for taskID, events := range state.Events {
+			ms.events[taskID] = events
+		}
+		ms.eventsMutex.Unlock()
+
+		ms.backupJobsMutex.Lock()
+		for _, job := range state.BackupJobs {
+			ms.backupJobs[job.ID] = job
+		}
+		ms.backupJobsMutex.Unlock()
+
+		ms.presetsMutex.Lock()
+		for _, preset := range state.Presets {
+			ms.presets[preset.Name] = preset
+		}
+		ms.presetsMutex.Unlock()
+
+		ms.pathRulesMutex.Lock()
+		for _, rule := range state.PathRules {
+			ms.pathRules[rule.ID] = rule
+		}
+		ms.pathRulesMutex.Unlock()
+
+		ms.imageRulesMutex.Lock()
+		for _, rule := range state.ImageRules {
+			ms.imageRules[rule.ID] = rule
+		}
+		ms.imageRulesMutex.Unlock()
+
+		log.Printf("[INFO] Loaded %d tasks from %s", len(state.Tasks), path)
+	}
+
+	ms.statePath = path
+	go ms.persistLoop()
+	return nil
+}
+
+// markDirty 标记存储内容已变更
+func (ms *MemoryStore) markDirty() {
+	atomic.StoreInt32(&ms.dirty, 1)
+}
+
+// persistLoop 定期写入有变更的状态
+func (ms *MemoryStore) persistLoop() {
+	ticker := time.NewTicker(persistInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := ms.Flush(); err != nil {
+			log.Printf("[WARNING] Failed to persist state: %v", err)
+		}
+	}
+}
+
+// Flush 立即把有变更的状态写入状态文件，未启用持久化时不做任何事
+func (ms *MemoryStore) Flush() error {
+	if ms.statePath == "" || !atomic.CompareAndSwapInt32(&ms.dirty, 1, 0) {
+		return nil
+	}
+
+	state := persistedState{
+		Logs:   make(map[string][]*models.MigrationLog),
+		Events: make(map[string][]*models.TaskEvent),
+	}
+
+	ms.tasksMutex.RLock()
+	for _, task := range ms.tasks {
+		state.Tasks = append(state.Tasks, task)
+	}
+	ms.logsMutex.RLock()
+	for taskID, logs := range ms.logs {
+		state.Logs[taskID] = logs
+	}
+	ms.eventsMutex.RLock()
+	for taskID, events := range ms.events {
+		state.Events[taskID] = events
+	}
+	ms.backupJobsMutex.RLock()
+	for _, job := range ms.backupJobs {
+		state.BackupJobs = append(state.BackupJobs, job)
+	}
+	ms.presetsMutex.RLock()
+	for _, preset := range ms.presets {
+		state.Presets = append(state.Presets, preset)
+	}
+	ms.pathRulesMutex.RLock()
+	for _, rule := range ms.pathRules {
+		state.PathRules = append(state.PathRules, rule)
+	}
+	ms.imageRulesMutex.RLock()
+	for _, rule := range ms.imageRules {
+		state.ImageRules = append(state.ImageRules, rule)
+	}
+	data, err := json.Marshal(state)
+	ms.imageRulesMutex.RUnlock()
+	ms.pathRulesMutex.RUnlock()
+	ms.presetsMutex.RUnlock()
+	ms.backupJobsMutex.RUnlock()
+	ms.eventsMutex.RUnlock()
+	ms.logsMutex.RUnlock()
+	ms.tasksMutex.RUnlock()
+	if err != nil {
+		ms.markDirty()
+		return fmt.Errorf("Failed to serialize state: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免进程退出时留下不完整的文件
+	tmpPath := ms.statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		ms.markDirty()
+		return fmt.Errorf("Failed to write state file: %v", err)
+	}
+	if err := os.Rename(tmpPath, ms.statePath); err != nil {
+		ms.markDirty()
+		return fmt.Errorf("Failed to replace state file: %v", err)
+	}
+	return nil
+}
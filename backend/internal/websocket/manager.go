@@ -12,18 +12,25 @@ import (
 	"ctoz/backend/internal/models"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// 允许所有来源，生产环境中应该更严格
-		return true
-	},
-}
+// 上传处理阶段
+const (
+	UploadPhaseReceiving  = "receiving"  // 正在接收文件
+	UploadPhaseValidating = "validating" // 正在校验文件格式和完整性
+	UploadPhaseStarting   = "starting"   // 正在创建导入任务
+	UploadPhaseCompleted  = "completed"  // 导入任务已创建
+	UploadPhaseFailed     = "failed"     // 上传或校验失败
+)
+
+// closeGracePeriod 关闭服务时等待关闭帧写出的最长时间
+const closeGracePeriod = 5 * time.Second
 
 // Client WebSocket客户端
 type Client struct {
 	Conn   *websocket.Conn
 	Send   chan models.WSMessage
 	TaskID string
+	// closeFrame Send关闭后写出的关闭帧，为空时以正常关闭（1000）结束连接
+	closeFrame []byte
 }
 
 // Manager WebSocket管理器
@@ -33,6 +40,13 @@ type Manager struct {
 	Register   chan *Client
 	Unregister chan *Client
 	mu         sync.RWMutex
+
+	recorder func(channel string, message models.WSMessage) // 记录发出的消息，用于回放任务事件
+	upgrader websocket.Upgrader                             // 升级连接，CheckOrigin由SetOriginCheck设置
+
+	shutdown chan chan int  // 关闭请求，回复已通知的客户端数
+	closing  bool           // 正在关闭，不再接受新连接
+	writers  sync.WaitGroup // 运行中的writePump
 }
 
 // BroadcastMessage 广播消息
@@ -48,6 +62,11 @@ func NewManager() *Manager {
 		Broadcast:  make(chan BroadcastMessage),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		shutdown:   make(chan chan int),
+		upgrader: websocket.Upgrader{
+			// 未设置来源检查时允许所有来源
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
 	}
 }
 
@@ -58,6 +77,13 @@ func (m *Manager) Run() {
 		case client := <-m.Register:
 			log.Printf("[DEBUG] 注册WebSocket客户端 - TaskID: %s", client.TaskID)
 			m.mu.Lock()
+			if m.closing {
+				// 关闭过程中连上的客户端直接收到关闭帧
+				client.closeFrame = websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+				close(client.Send)
+				m.mu.Unlock()
+				continue
+			}
 			if m.Clients[client.TaskID] == nil {
 				m.Clients[client.TaskID] = make(map[*Client]bool)
 			}
@@ -112,19 +138,93 @@ func (m *Manager) Run() {
 					m.mu.Unlock()
 				}
 			}
+
+		case done := <-m.shutdown:
+			done <- m.closeAll()
+		}
+	}
+}
+
+// closeAll 向所有客户端发送server_restarting消息，之后以1012（服务重启）关闭帧结束连接，
+// 只在Run中调用，避免与广播同时写入已关闭的Send
+func (m *Manager) closeAll() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closing = true
+	frame := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	count := 0
+	for taskID, clients := range m.Clients {
+		for client := range clients {
+			message := models.WSMessage{
+				Type: models.WSMsgTypeServerRestarting,
+				Data: map[string]interface{}{
+					"task_id":   taskID,
+					"message":   "Server is shutting down, reconnect when it is back",
+					"reconnect": true,
+				},
+				Timestamp: time.Now(),
+			}
+			// 发送缓冲区已满时只发送关闭帧
+			select {
+			case client.Send <- message:
+			default:
+			}
+			client.closeFrame = frame
+			close(client.Send)
+			count++
 		}
+		delete(m.Clients, taskID)
 	}
+	return count
+}
+
+// Shutdown 通知所有客户端服务即将关闭或重启并发送关闭帧，前端据此显示重连提示而不是连接意外中断；
+// 最多等待closeGracePeriod让消息写出，之后的新连接直接被关闭
+func (m *Manager) Shutdown() {
+	done := make(chan int)
+	m.shutdown <- done
+	count := <-done
+	log.Printf("[INFO] Closing %d WebSocket connections", count)
+
+	finished := make(chan struct{})
+	go func() {
+		m.writers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(closeGracePeriod):
+		log.Printf("[WARNING] Timed out waiting for WebSocket connections to close")
+	}
+}
+
+// isClosing 是否正在关闭
+func (m *Manager) isClosing() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.closing
 }
 
 // HandleWebSocket 处理WebSocket连接
 func (m *Manager) HandleWebSocket(c *gin.Context) {
 	taskID := c.Query("task_id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "任务ID不能为空"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task ID is required"})
+		return
+	}
+
+	m.HandleChannel(c, taskID)
+}
+
+// HandleChannel 将WebSocket连接订阅到指定频道，频道为任务ID或上传的临时频道
+func (m *Manager) HandleChannel(c *gin.Context, taskID string) {
+	if m.isClosing() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
 		return
 	}
 
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := m.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket升级失败: %v", err)
 		return
@@ -141,6 +241,7 @@ func (m *Manager) HandleWebSocket(c *gin.Context) {
 	m.Register <- client
 
 	// 启动goroutines处理读写
+	m.writers.Add(1)
 	go m.writePump(client)
 	go m.readPump(client)
 }
@@ -179,6 +280,7 @@ func (m *Manager) writePump(client *Client) {
 	defer func() {
 		ticker.Stop()
 		client.Conn.Close()
+		m.writers.Done()
 	}()
 
 	for {
@@ -186,7 +288,11 @@ func (m *Manager) writePump(client *Client) {
 		case message, ok := <-client.Send:
 			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				client.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				frame := client.closeFrame
+				if frame == nil {
+					frame = websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				}
+				client.Conn.WriteMessage(websocket.CloseMessage, frame)
 				return
 			}
 
@@ -210,10 +316,38 @@ func (m *Manager) writePump(client *Client) {
 	}
 }
 
+// Stats 返回已连接的客户端数和有客户端连接的任务数
+func (m *Manager) Stats() (clients, tasks int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, taskClients := range m.Clients {
+		if len(taskClients) > 0 {
+			clients += len(taskClients)
+			tasks++
+		}
+	}
+	return clients, tasks
+}
+
+// SetOriginCheck 设置升级连接时的来源检查，启用登录时拒绝其它站点的页面携带会话Cookie连接，
+// 需要在接受连接之前设置
+func (m *Manager) SetOriginCheck(check func(r *http.Request) bool) {
+	m.upgrader.CheckOrigin = check
+}
+
+// SetRecorder 设置消息记录函数，每条通过SendMessage发出的消息（无论是否有客户端连接）都会传给它，
+// 需要在Run之前设置
+func (m *Manager) SetRecorder(recorder func(channel string, message models.WSMessage)) {
+	m.recorder = recorder
+}
+
 // SendMessage 发送消息到指定任务的所有客户端
 func (m *Manager) SendMessage(taskID string, message models.WSMessage) {
 	message.Timestamp = time.Now()
 	log.Printf("[DEBUG] SendMessage - TaskID: %s, Type: %s", taskID, message.Type)
+	if m.recorder != nil {
+		m.recorder(taskID, message)
+	}
 	m.Broadcast <- BroadcastMessage{
 		TaskID:  taskID,
 		Message: message,
@@ -249,6 +383,59 @@ func (m *Manager) SendProgress(taskID string, progress int, step, message string
 	m.SendMessage(taskID, wsMessage)
 }
 
+// SendTransferProgress 发送应用数据传输的字节级进度，
+// 使用单独的消息类型，避免覆盖任务的整体进度
+func (m *Manager) SendTransferProgress(taskID, appName string, transferred, total int64) {
+	progress := 0
+	if total > 0 {
+		progress = int(transferred * 100 / total)
+	}
+	wsMessage := models.WSMessage{
+		Type: models.WSMsgTypeAppProgress,
+		Data: map[string]interface{}{
+			"task_id":           taskID,
+			"app_name":          appName,
+			"app_progress":      progress,
+			"transferred_bytes": transferred,
+			"total_bytes":       total,
+		},
+		Timestamp: time.Now(),
+	}
+	m.SendMessage(taskID, wsMessage)
+}
+
+// SendDecompressProgress 发送目标系统上解压应用数据的进度
+func (m *Manager) SendDecompressProgress(taskID, appName string, progress int) {
+	wsMessage := models.WSMessage{
+		Type: models.WSMsgTypeAppProgress,
+		Data: map[string]interface{}{
+			"task_id":      taskID,
+			"app_name":     appName,
+			"phase":        "decompress",
+			"app_progress": progress,
+		},
+		Timestamp: time.Now(),
+	}
+	m.SendMessage(taskID, wsMessage)
+}
+
+// SendHeartbeat 目标上的长时间操作（解压、导入compose时拉取镜像）期间定期发送心跳，
+// 包含已等待的秒数和目标上的任务状态，保持连接活跃并表明任务没有卡住
+func (m *Manager) SendHeartbeat(taskID, appName, phase string, elapsed time.Duration, status string) {
+	wsMessage := models.WSMessage{
+		Type: "task_heartbeat",
+		Data: map[string]interface{}{
+			"task_id":         taskID,
+			"app_name":        appName,
+			"phase":           phase,
+			"elapsed_seconds": int64(elapsed.Seconds()),
+			"target_status":   status,
+		},
+		Timestamp: time.Now(),
+	}
+	m.SendMessage(taskID, wsMessage)
+}
+
 // SendLog 发送任务日志
 func (m *Manager) SendLog(taskID, level, message string) {
 	log.Printf("[DEBUG] SendLog - TaskID: %s, Level: %s, Message: %s", taskID, level, message)
@@ -325,4 +512,46 @@ func (m *Manager) SendStepError(taskID, step, message, errorMsg string) {
 		Timestamp: time.Now(),
 	}
 	m.SendMessage(taskID, wsMessage)
-}
\ No newline at end of file
+}
+
+// UploadChannel 上传进度使用的临时频道，与任务ID区分
+func UploadChannel(uploadID string) string {
+	return "upload:" + uploadID
+}
+
+// SendUploadProgress 发送导入文件上传的接收进度
+func (m *Manager) SendUploadProgress(uploadID string, received, total int64) {
+	progress := 0
+	if total > 0 {
+		progress = int(received * 100 / total)
+	}
+	wsMessage := models.WSMessage{
+		Type: "upload_progress",
+		Data: map[string]interface{}{
+			"upload_id":      uploadID,
+			"phase":          UploadPhaseReceiving,
+			"progress":       progress,
+			"received_bytes": received,
+			"total_bytes":    total,
+		},
+		Timestamp: time.Now(),
+	}
+	m.SendMessage(UploadChannel(uploadID), wsMessage)
+}
+
+// SendUploadPhase 发送上传处理阶段的变化，data为阶段的附加信息（如创建的任务ID）
+func (m *Manager) SendUploadPhase(uploadID, phase string, data map[string]interface{}) {
+	payload := map[string]interface{}{
+		"upload_id": uploadID,
+		"phase":     phase,
+	}
+	for key, value := range data {
+		payload[key] = value
+	}
+	wsMessage := models.WSMessage{
+		Type:      "upload_progress",
+		Data:      payload,
+		Timestamp: time.Now(),
+	}
+	m.SendMessage(UploadChannel(uploadID), wsMessage)
+}
@@ -4,6 +4,7 @@ import HomePage from './pages/HomePage'
 import OnlineMigrationPage from './pages/OnlineMigrationPage'
 import OfflineMigrationPage from './pages/OfflineMigrationPage'
 import StatusPage from './pages/StatusPage'
+import LoginPage from './pages/LoginPage'
 import Layout from './components/Layout'
 
 function App() {
@@ -16,6 +17,7 @@ function App() {
             <Route path="/online-migration" element={<OnlineMigrationPage />} />
             <Route path="/offline-migration" element={<OfflineMigrationPage />} />
             <Route path="/status/:taskId" element={<StatusPage />} />
+            <Route path="/login" element={<LoginPage />} />
           </Routes>
         </Layout>
         <Toaster position="top-right" />
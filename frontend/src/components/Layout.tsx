@@ -6,9 +6,10 @@
  * @FilePath: /CtoZ/frontend/src/components/Layout.tsx
  * @Description: 这是默认设置,请设置`customMade`, 打开koroFileHeader查看配置 进行设置: https://github.com/OBKoro1/koro1FileHeader/wiki/%E9%85%8D%E7%BD%AE
  */
-import React from 'react'
+import React, { useEffect, useState } from 'react'
 import { Link, useLocation } from 'react-router-dom'
-import { Home, ArrowRightLeft, Download, Activity } from 'lucide-react'
+import { Home, ArrowRightLeft, Download, Activity, LogOut } from 'lucide-react'
+import { apiClient } from '../utils/api'
 
 interface LayoutProps {
   children: React.ReactNode
@@ -16,6 +17,22 @@ interface LayoutProps {
 
 const Layout: React.FC<LayoutProps> = ({ children }) => {
   const location = useLocation()
+  const [user, setUser] = useState<string | null>(null)
+
+  // 启用登录时显示当前用户和退出按钮
+  useEffect(() => {
+    apiClient.getAuthStatus()
+      .then((response) => setUser(response.data?.user || null))
+      .catch(() => setUser(null))
+  }, [location.pathname])
+
+  const handleLogout = async () => {
+    try {
+      await apiClient.logout()
+    } finally {
+      window.location.assign('/login')
+    }
+  }
 
   const navigation = [
     { name: 'Home', href: '/', icon: Home },
@@ -54,6 +71,16 @@ const Layout: React.FC<LayoutProps> = ({ children }) => {
                   </Link>
                 )
               })}
+              {user && (
+                <button
+                  onClick={handleLogout}
+                  className="flex items-center px-3 py-2 rounded-md text-sm font-medium text-gray-600 hover:text-gray-900 hover:bg-gray-100 transition-colors duration-200"
+                  title={`Signed in as ${user}`}
+                >
+                  <LogOut className="h-4 w-4 mr-2" />
+                  Sign out ({user})
+                </button>
+              )}
             </nav>
           </div>
         </div>
@@ -0,0 +1,124 @@
+import React, { useEffect, useState } from 'react'
+import { useNavigate, useSearchParams } from 'react-router-dom'
+import { LogIn, Loader } from 'lucide-react'
+import { toast } from 'sonner'
+import { AuthStatus } from '../types'
+import { apiClient } from '../utils/api'
+
+const LoginPage: React.FC = () => {
+  const navigate = useNavigate()
+  const [searchParams] = useSearchParams()
+  const [status, setStatus] = useState<AuthStatus | null>(null)
+  const [username, setUsername] = useState('')
+  const [password, setPassword] = useState('')
+  const [isSubmitting, setIsSubmitting] = useState(false)
+
+  // 只接受站内路径，避免登录后跳转到其它站点
+  const requested = searchParams.get('return_to') || '/'
+  const returnTo = requested.startsWith('/') && !requested.startsWith('//') ? requested : '/'
+  const authError = searchParams.get('auth_error')
+
+  useEffect(() => {
+    loadStatus()
+  }, [])
+
+  const loadStatus = async () => {
+    try {
+      const response = await apiClient.getAuthStatus()
+      if (response.success && response.data) {
+        // 未启用登录或已登录时直接返回原页面
+        if (!response.data.enabled || response.data.user) {
+          navigate(returnTo, { replace: true })
+          return
+        }
+        setStatus(response.data)
+      }
+    } catch (error) {
+      console.error('Failed to load auth status:', error)
+      toast.error('Failed to load login options')
+    }
+  }
+
+  const handleLDAPLogin = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      setIsSubmitting(true)
+      await apiClient.ldapLogin(username, password)
+      // 整页跳转，重新建立WebSocket连接并加载数据
+      window.location.assign(returnTo)
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : 'Login failed')
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
+  if (!status) {
+    return (
+      <div className="flex justify-center py-16">
+        <Loader className="h-6 w-6 text-blue-600 animate-spin" />
+      </div>
+    )
+  }
+
+  return (
+    <div className="max-w-md mx-auto">
+      <div className="card space-y-6">
+        <div className="flex items-center">
+          <LogIn className="h-6 w-6 text-blue-600" />
+          <h2 className="ml-3 text-xl font-semibold text-gray-900">Sign in</h2>
+        </div>
+
+        {authError && (
+          <div className="rounded-md bg-red-50 p-3 text-sm text-red-700">
+            {authError}
+          </div>
+        )}
+
+        {status.providers.includes('oidc') && (
+          <a href={apiClient.getOIDCLoginUrl(returnTo)} className="btn-primary w-full flex justify-center">
+            Sign in with SSO
+          </a>
+        )}
+
+        {status.providers.includes('ldap') && (
+          <form onSubmit={handleLDAPLogin} className="space-y-4">
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                Username
+              </label>
+              <input
+                type="text"
+                value={username}
+                onChange={(e) => setUsername(e.target.value)}
+                autoComplete="username"
+                className="input-field"
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                Password
+              </label>
+              <input
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                autoComplete="current-password"
+                className="input-field"
+              />
+            </div>
+            <button
+              type="submit"
+              disabled={isSubmitting || !username || !password}
+              className="btn-primary w-full flex justify-center"
+            >
+              {isSubmitting ? <Loader className="h-4 w-4 animate-spin" /> : 'Sign in'}
+            </button>
+          </form>
+        )}
+      </div>
+    </div>
+  )
+}
+
+export default LoginPage
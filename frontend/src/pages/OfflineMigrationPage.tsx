@@ -1,14 +1,22 @@
-import React, { useState } from 'react'
+import React, { useEffect, useState } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { Download, Upload, Server, FileDown, FileUp } from 'lucide-react'
 import { SystemConnection } from '../types'
-import { apiClient } from '../utils/api'
+import { apiClient, redirectToLogin } from '../utils/api'
 import { useStore } from '../hooks/useStore'
 import { toast } from 'sonner'
 import ConnectionForm from '../components/ConnectionForm'
 
 type MigrationStep = 'export' | 'import'
 
+// 格式化文件大小
+const formatSize = (bytes: number) => {
+  if (bytes >= 1024 * 1024 * 1024) {
+    return `${(bytes / (1024 * 1024 * 1024)).toFixed(1)} GB`
+  }
+  return `${(bytes / (1024 * 1024)).toFixed(0)} MB`
+}
+
 const OfflineMigrationPage: React.FC = () => {
   const navigate = useNavigate()
   const { setLoading } = useStore()
@@ -46,6 +54,14 @@ const OfflineMigrationPage: React.FC = () => {
   const [uploadProgress, setUploadProgress] = useState<number>(0)
   const [uploadStatus, setUploadStatus] = useState<'idle' | 'uploading' | 'success' | 'error'>('idle')
   const [isDragOver, setIsDragOver] = useState(false)
+  // 服务器允许的上传大小，0表示不限制
+  const [maxUploadBytes, setMaxUploadBytes] = useState<number>(0)
+
+  useEffect(() => {
+    apiClient.getSystemInfo()
+      .then(response => setMaxUploadBytes(response.data?.max_upload_bytes ?? 0))
+      .catch(() => setMaxUploadBytes(0))
+  }, [])
 
   const testConnection = async (connection: SystemConnection, isSource: boolean) => {
     const setTesting = isSource ? setIsTestingSource : setIsTestingTarget
@@ -99,6 +115,11 @@ const OfflineMigrationPage: React.FC = () => {
           source_connection: sourceConnection
         })
       })
+
+      if (response.status === 401) {
+        redirectToLogin()
+        return
+      }
       
       if (response.ok) {
         setDownloadProgress('Generating compressed package...')
@@ -151,7 +172,7 @@ const OfflineMigrationPage: React.FC = () => {
     }
   }
 
-  const startImport = async () => {
+  const startImport = async (allowDuplicate = false) => {
     if (!targetTestResult?.success) {
       toast.error('Please test and ensure the target system connection is successful')
       return
@@ -172,12 +193,20 @@ const OfflineMigrationPage: React.FC = () => {
       const formData = new FormData()
       formData.append('file', uploadedFile)
       formData.append('target_connection', JSON.stringify(targetConnection))
+      if (allowDuplicate) {
+        formData.append('allow_duplicate', 'true')
+      }
       
       // 使用fetch进行文件上传，支持进度监控
       const response = await fetch('/api/data-import-upload', {
         method: 'POST',
         body: formData,
       })
+
+      if (response.status === 401) {
+        redirectToLogin()
+        return
+      }
       
       if (response.ok) {
         const result = await response.json()
@@ -190,6 +219,13 @@ const OfflineMigrationPage: React.FC = () => {
           setUploadStatus('error')
           toast.error(result.message || 'Failed to start import task')
         }
+      } else if (response.status === 409) {
+        // 同一压缩包已导入到该目标，确认后重新上传并导入
+        const errorData = await response.json()
+        setUploadStatus('error')
+        if (confirm(`${errorData.message}. Importing it again overwrites the app data on the target with the data in this archive. Import anyway?`)) {
+          await startImport(true)
+        }
       } else {
         const errorData = await response.json()
         setUploadStatus('error')
@@ -214,20 +250,19 @@ const OfflineMigrationPage: React.FC = () => {
 
   // 文件处理函数
   const handleFileSelect = (file: File) => {
-    // 验证文件类型 - 支持 .tar.gz 和 .zip 格式
-    const allowedTypes = ['.tar.gz', '.zip']
+    // 验证文件类型 - 支持 .tar.gz、.tar.xz、.tar、.zip 和 .7z 格式
+    const allowedTypes = ['.tar.gz', '.tar.xz', '.txz', '.tar', '.zip', '.7z']
     const fileName = file.name.toLowerCase()
     const isValidType = allowedTypes.some(type => fileName.endsWith(type))
     
     if (!isValidType) {
-      toast.error('Please select a .tar.gz or .zip format file')
+      toast.error('Please select a .tar.gz, .tar.xz, .tar, .zip or .7z format file')
       return
     }
     
-    // 验证文件大小（限制为500MB）
-    const maxSize = 500 * 1024 * 1024 // 500MB
-    if (file.size > maxSize) {
-      toast.error('File size cannot exceed 500MB')
+    // 验证文件大小（上限由服务器配置）
+    if (maxUploadBytes > 0 && file.size > maxUploadBytes) {
+      toast.error(`File size cannot exceed ${formatSize(maxUploadBytes)}`)
       return
     }
     
@@ -437,7 +472,7 @@ const OfflineMigrationPage: React.FC = () => {
                   type="file"
                   id="file-upload"
                   className="hidden"
-                  accept=".tar.gz"
+                  accept=".tar.gz,.tar.xz,.txz,.tar,.zip,.7z"
                   onChange={handleFileChange}
                 />
                 
@@ -479,7 +514,7 @@ const OfflineMigrationPage: React.FC = () => {
                         </label>
                       </p>
                       <p className="text-xs text-gray-500 mt-1">
-                        Supports .tar.gz format, max 500MB
+                        Supports .tar.gz, .tar.xz, .tar, .zip and .7z formats{maxUploadBytes > 0 ? `, max ${formatSize(maxUploadBytes)}` : ''}
                       </p>
                     </div>
                   </div>
@@ -546,7 +581,7 @@ const OfflineMigrationPage: React.FC = () => {
 
           <div className="text-center">
             <button
-              onClick={startImport}
+              onClick={() => startImport()}
               disabled={!targetTestResult?.success || !uploadedFile || isImporting || uploadStatus === 'uploading'}
               className={`inline-flex items-center px-6 py-3 text-lg font-medium rounded-lg transition-colors duration-200 ${
                 targetTestResult?.success && uploadedFile && !isImporting && uploadStatus !== 'uploading'
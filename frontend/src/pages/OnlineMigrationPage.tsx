@@ -2,7 +2,7 @@ import React, { useState } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { ArrowRightLeft, Server, Play } from 'lucide-react'
 import { SystemConnection, ConnectionTestRequest, OnlineMigrationRequest } from '../types'
-import { apiClient } from '../utils/api'
+import { apiClient, ApiError } from '../utils/api'
 import { useStore } from '../hooks/useStore'
 import { toast } from 'sonner'
 import ConnectionForm from '../components/ConnectionForm'
@@ -61,7 +61,7 @@ const OnlineMigrationPage: React.FC = () => {
     }
   }
 
-  const startMigration = async () => {
+  const startMigration = async (confirmLargeApps = false) => {
     if (!sourceTestResult?.success || !targetTestResult?.success) {
       toast.error('Please test and ensure both connections succeed first')
       return
@@ -77,7 +77,8 @@ const OnlineMigrationPage: React.FC = () => {
         migrationOptions: {
           includeApps: true,
           includeSettings: true,
-          includeUserData: true
+          includeUserData: true,
+          ...(confirmLargeApps ? { confirm_large_apps: true } : {})
         }
       }
       
@@ -90,6 +91,15 @@ const OnlineMigrationPage: React.FC = () => {
         toast.error(response.message || 'Failed to start migration task')
       }
     } catch (error) {
+      if (error instanceof ApiError && error.status === 409) {
+        // AppData很大的应用需要确认后再迁移
+        const apps: { app_name: string; appdata_bytes: number }[] = error.data?.data?.apps || []
+        const list = apps.map(app => `${app.app_name} (${(app.appdata_bytes / 1073741824).toFixed(1)} GiB)`).join('\n')
+        if (confirm(`These apps have very large AppData and may take many hours to transfer:\n${list}\n\nMigrate them anyway?`)) {
+          await startMigration(true)
+        }
+        return
+      }
       const message = error instanceof Error ? error.message : 'Failed to start migration task'
       toast.error(message)
     } finally {
@@ -175,7 +185,7 @@ const OnlineMigrationPage: React.FC = () => {
       {/* Start Migration */}
       <div className="text-center">
         <button
-          onClick={startMigration}
+          onClick={() => startMigration()}
           disabled={!canStartMigration}
           className={`inline-flex items-center px-6 py-3 text-lg font-medium rounded-lg transition-colors duration-200 ${
             canStartMigration
@@ -50,7 +50,7 @@ export interface MigrationLog {
 
 // WebSocket消息
 export interface WSMessage {
-  type: 'task_status' | 'task_progress' | 'task_log' | 'step'
+  type: 'task_status' | 'task_progress' | 'app_progress' | 'task_log' | 'step'
   data: any
   timestamp: string
 }
@@ -87,6 +87,7 @@ export interface OnlineMigrationRequest {
     includeApps?: boolean
     includeSettings?: boolean
     includeUserData?: boolean
+    confirm_large_apps?: boolean
   }
 }
 
@@ -122,6 +123,7 @@ export interface SystemInfo {
   build_time: string
   go_version: string
   os: string
+  max_upload_bytes?: number // 导入文件上传上限，0表示不限制
 }
 
 // 应用导入状态
@@ -149,4 +151,11 @@ export interface ImportStatusResponse {
   progress: number
   apps: AppImportStatus[]
   summary: ImportSummary
-}
\ No newline at end of file
+}
+
+// 登录状态
+export interface AuthStatus {
+  enabled: boolean
+  providers: Array<'oidc' | 'ldap'>
+  user?: string
+}
@@ -9,11 +9,31 @@ import {
   ExportDataResponse,
   MigrationTask,
   SystemInfo,
-  ImportStatusResponse
+  ImportStatusResponse,
+  AuthStatus
 } from '../types'
 
 const API_BASE_URL = '/api'
 
+// redirectToLogin 未登录或会话失效时跳转到登录页，登录后返回当前页面
+export function redirectToLogin(): void {
+  if (window.location.pathname === '/login') return
+  const returnTo = window.location.pathname + window.location.search
+  window.location.assign(`/login?return_to=${encodeURIComponent(returnTo)}`)
+}
+
+// ApiError 请求失败时携带HTTP状态码和响应内容
+export class ApiError extends Error {
+  status: number
+  data: any
+
+  constructor(message: string, status: number, data: any) {
+    super(message)
+    this.status = status
+    this.data = data
+  }
+}
+
 class ApiClient {
   private async request<T>(
     endpoint: string,
@@ -32,9 +52,14 @@ class ApiClient {
     try {
       const response = await fetch(url, config)
       const data = await response.json()
+
+      // 登录接口自己处理401（用户名或密码错误）
+      if (response.status === 401 && !endpoint.startsWith('/auth/')) {
+        redirectToLogin()
+      }
       
       if (!response.ok) {
-        throw new Error(data.error || `HTTP error! status: ${response.status}`)
+        throw new ApiError(data.message || data.error || `HTTP error! status: ${response.status}`, response.status, data)
       }
       
       return data
@@ -107,7 +132,12 @@ class ApiClient {
 
   // 获取系统信息
   async getSystemInfo(): Promise<APIResponse<SystemInfo>> {
-    return this.request<SystemInfo>('/info')
+    // /info 不在 /api 下
+    const response = await fetch('/info')
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`)
+    }
+    return response.json()
   }
 
   // 健康检查
@@ -120,6 +150,31 @@ class ApiClient {
     return this.request<ImportStatusResponse>(`/tasks/${taskId}/import-status`)
   }
 
+  // 获取登录状态
+  async getAuthStatus(): Promise<APIResponse<AuthStatus>> {
+    return this.request<AuthStatus>('/auth/status')
+  }
+
+  // LDAP登录
+  async ldapLogin(username: string, password: string): Promise<APIResponse<void>> {
+    return this.request<void>('/auth/ldap/login', {
+      method: 'POST',
+      body: JSON.stringify({ username, password }),
+    })
+  }
+
+  // 退出登录
+  async logout(): Promise<APIResponse<void>> {
+    return this.request<void>('/auth/logout', {
+      method: 'POST',
+    })
+  }
+
+  // OIDC登录地址，登录后返回returnTo
+  getOIDCLoginUrl(returnTo: string): string {
+    return `${API_BASE_URL}/auth/oidc/login?return_to=${encodeURIComponent(returnTo)}`
+  }
+
   // 生成应用下载链接
   getAppDownloadUrl(taskId: string, appName: string): string {
     return `${API_BASE_URL}/tasks/${taskId}/download/${appName}`